@@ -1,18 +1,27 @@
 package main
 
 import (
+	"context"
+	"expense-management-system/internal/domain"
 	"expense-management-system/internal/handler"
+	"expense-management-system/internal/jwtkeys"
 	"expense-management-system/internal/middleware"
+	"expense-management-system/internal/notifier"
+	"expense-management-system/internal/ocr"
+	"expense-management-system/internal/oidc"
 	"expense-management-system/internal/repository"
+	"expense-management-system/internal/storage"
 	"expense-management-system/internal/usecase"
 	"expense-management-system/internal/worker"
 	"expense-management-system/pkg/config"
 	"expense-management-system/pkg/database"
 	"expense-management-system/pkg/logger"
+	"expvar"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
@@ -24,6 +33,9 @@ func main() {
 	logger.Init()
 
 	cfg := config.Load()
+	if err := cfg.Validate(); err != nil {
+		logger.ErrorLogger.Fatalf("Invalid configuration: %v", err)
+	}
 
 	db, err := database.NewPostgresDB(cfg)
 	if err != nil {
@@ -35,22 +47,142 @@ func main() {
 
 	paymentChan := make(chan usecase.PaymentJob, 100)
 
-	userRepo := repository.NewUserRepository(db)
+	userRepo := repository.NewCachedUserRepository(repository.NewUserRepository(db), time.Duration(cfg.UserCacheTTLSeconds)*time.Second)
 	expenseRepo := repository.NewExpenseRepository(db)
 	approvalRepo := repository.NewApprovalRepository(db)
 	auditRepo := repository.NewAuditLogRepository(db)
+	paymentRunRepo := repository.NewPaymentRunRepository(db)
+	refundRepo := repository.NewRefundRepository(db)
+	cashAdvanceRepo := repository.NewCashAdvanceRepository(db)
+	budgetRepo := repository.NewBudgetRepository(db)
+	journalRepo := repository.NewJournalRepository(db)
+	analyticsRepo := repository.NewAnalyticsRepository(db)
+	reportRepo := repository.NewReportRepository(db)
+	receiptRepo := repository.NewReceiptRepository(db)
+	policyRuleRepo := repository.NewPolicyRuleRepository(db)
+	settingRepo := repository.NewSettingRepository(db)
+	tokenRepo := repository.NewTokenRepository(db)
+	loginAttemptRepo := repository.NewLoginAttemptRepository(db)
+	twoFactorRepo := repository.NewTwoFactorRepository(db)
+	identityRepo := repository.NewIdentityRepository(db)
+	roleRepo := repository.NewRoleRepository(db)
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	signingKeyRepo := repository.NewSigningKeyRepository(db)
+
+	// Access tokens are signed with rotating key pairs published as a JWKS,
+	// unless JWT_SIGNING_ALGORITHM=HS256 keeps the shared secret.
+	var tokenSigner domain.TokenSigner
+	var keyRotationScheduler *worker.KeyRotationScheduler
+	if cfg.JWTSigningAlgorithm == jwtkeys.AlgorithmHS256 {
+		tokenSigner = jwtkeys.NewHMACSigner(cfg.JWTSecret)
+	} else {
+		keyRing, err := jwtkeys.NewKeyRing(signingKeyRepo, jwtkeys.Config{
+			Algorithm:        cfg.JWTSigningAlgorithm,
+			RotationInterval: time.Duration(cfg.JWTKeyRotationDays) * 24 * time.Hour,
+			PublishLead:      time.Duration(cfg.JWTKeyPublishLeadMinutes) * time.Minute,
+			// Tokens signed just before a key retires must stay valid.
+			VerifyGrace:      time.Duration(cfg.AccessTokenTTLMinutes)*time.Minute + time.Hour,
+			EncryptionSecret: cfg.JWTSecret,
+		})
+		if err != nil {
+			logger.ErrorLogger.Fatalf("Failed to set up token signing: %v", err)
+		}
+		if err := keyRing.Rotate(context.Background()); err != nil {
+			logger.ErrorLogger.Fatalf("Failed to prepare signing keys: %v", err)
+		}
+		tokenSigner = keyRing
+		keyRotationScheduler = worker.NewKeyRotationScheduler(keyRing, time.Minute)
+		keyRotationScheduler.Start()
+	}
+
+	// Messages to users, such as password reset and report links, go to
+	// the log until a mail provider is configured.
+	mailer := notifier.NewLogNotifier()
 
-	authUsecase := usecase.NewAuthUsecase(userRepo, cfg)
-	expenseUsecase := usecase.NewExpenseUsecase(expenseRepo, approvalRepo, auditRepo, userRepo, paymentChan)
+	twoFactorUsecase := usecase.NewTwoFactorUsecase(twoFactorRepo, userRepo, cfg)
+	roleUsecase := usecase.NewRoleUsecase(roleRepo, auditRepo)
+	apiKeyUsecase := usecase.NewAPIKeyUsecase(apiKeyRepo, userRepo, auditRepo, cfg)
+	authUsecase := usecase.NewAuthUsecase(userRepo, tokenRepo, loginAttemptRepo, twoFactorUsecase, mailer, tokenSigner, cfg)
 
-	paymentService := worker.NewPaymentService(cfg, expenseRepo, auditRepo)
+	var identityProvider domain.IdentityProvider
+	if cfg.OIDCIssuerURL != "" {
+		identityProvider = oidc.NewProvider(oidc.Config{
+			IssuerURL:    cfg.OIDCIssuerURL,
+			ClientID:     cfg.OIDCClientID,
+			ClientSecret: cfg.OIDCClientSecret,
+			RedirectURL:  cfg.OIDCRedirectURL,
+			Scopes:       cfg.OIDCScopes,
+			GroupsClaim:  cfg.OIDCGroupsClaim,
+		})
+		logger.InfoLogger.Printf("Single sign-on enabled with issuer %s", cfg.OIDCIssuerURL)
+	}
+	ssoUsecase := usecase.NewSSOUsecase(identityProvider, identityRepo, userRepo, auditRepo, authUsecase, cfg)
+	paymentRunUsecase := usecase.NewPaymentRunUsecase(paymentRunRepo, expenseRepo, auditRepo, paymentChan, cfg.PaymentRunCutoff)
+
+	// In batched mode approved expenses wait in a payment run for finance to
+	// release; otherwise each one is paid as soon as it is approved.
+	var batchedPayments domain.PaymentRunUsecase
+	if cfg.PaymentMode == config.PaymentModeBatched {
+		batchedPayments = paymentRunUsecase
+	}
+	budgetUsecase := usecase.NewBudgetUsecase(budgetRepo, userRepo)
+	journalUsecase := usecase.NewJournalUsecase(journalRepo)
+	analyticsUsecase := usecase.NewAnalyticsUsecase(analyticsRepo)
+
+	reportStore, err := storage.NewLocalStore(cfg.ReportStorageDir)
+	if err != nil {
+		logger.ErrorLogger.Fatalf("Failed to set up report storage: %v", err)
+	}
+	reportUsecase := usecase.NewReportUsecase(reportRepo, expenseRepo, analyticsRepo, reportStore, mailer, cfg)
+
+	receiptStore, err := storage.NewLocalStore(cfg.ReceiptStorageDir)
+	if err != nil {
+		logger.ErrorLogger.Fatalf("Failed to set up receipt storage: %v", err)
+	}
+	var receiptExtractor domain.ReceiptExtractor
+	if cfg.ReceiptOCREnabled {
+		receiptExtractor = ocr.NewTesseract(cfg.TesseractPath, cfg.TesseractLanguages)
+		logger.InfoLogger.Printf("Reading receipts with %s (%s)", cfg.TesseractPath, cfg.TesseractLanguages)
+	}
+	receiptUsecase := usecase.NewReceiptUsecase(receiptRepo, receiptStore, receiptExtractor, cfg)
+	policyUsecase := usecase.NewPolicyUsecase(policyRuleRepo, userRepo, auditRepo)
+	settingsUsecase := usecase.NewSettingsUsecase(settingRepo, cfg)
+	expenseUsecase := usecase.NewExpenseUsecase(expenseRepo, approvalRepo, auditRepo, userRepo, cashAdvanceRepo, paymentChan, batchedPayments, budgetUsecase, twoFactorUsecase, receiptUsecase, policyUsecase, settingsUsecase)
+	cashAdvanceUsecase := usecase.NewCashAdvanceUsecase(cashAdvanceRepo, auditRepo, userRepo, paymentChan, settingsUsecase)
+	userAdminUsecase := usecase.NewUserAdminUsecase(userRepo, auditRepo, roleRepo, tokenRepo, loginAttemptRepo, cfg)
+
+	paymentService := worker.NewPaymentService(cfg, expenseRepo, auditRepo, paymentRunRepo, cashAdvanceRepo)
 	workerPool := worker.NewWorkerPool(paymentChan, paymentService, cfg.WorkerPoolSize, cfg.WorkerMaxRetries)
 	workerPool.Start()
 
+	refundUsecase := usecase.NewRefundUsecase(refundRepo, expenseRepo, auditRepo, userRepo, paymentService)
+
+	paymentRunScheduler := worker.NewPaymentRunScheduler(paymentRunUsecase, time.Minute)
+	paymentRunScheduler.Start()
+
+	reportScheduler := worker.NewReportScheduler(reportUsecase, time.Minute)
+	reportScheduler.Start()
+
 	authHandler := handler.NewAuthHandler(authUsecase)
+	twoFactorHandler := handler.NewTwoFactorHandler(twoFactorUsecase)
+	ssoHandler := handler.NewSSOHandler(ssoUsecase)
 	expenseHandler := handler.NewExpenseHandler(expenseUsecase)
+	receiptHandler := handler.NewReceiptHandler(receiptUsecase)
 	healthHandler := handler.NewHealthHandler()
+	jwksHandler := handler.NewJWKSHandler(tokenSigner)
 	docsHandler := handler.NewDocsHandler()
+	paymentRunHandler := handler.NewPaymentRunHandler(paymentRunUsecase)
+	refundHandler := handler.NewRefundHandler(refundUsecase)
+	cashAdvanceHandler := handler.NewCashAdvanceHandler(cashAdvanceUsecase)
+	budgetHandler := handler.NewBudgetHandler(budgetUsecase)
+	journalHandler := handler.NewJournalHandler(journalUsecase)
+	analyticsHandler := handler.NewAnalyticsHandler(analyticsUsecase)
+	reportHandler := handler.NewReportHandler(reportUsecase)
+	userAdminHandler := handler.NewUserAdminHandler(userAdminUsecase)
+	roleHandler := handler.NewRoleHandler(roleUsecase)
+	policyHandler := handler.NewPolicyHandler(policyUsecase)
+	settingsHandler := handler.NewSettingsHandler(settingsUsecase)
+	serviceAccountHandler := handler.NewServiceAccountHandler(apiKeyUsecase)
 
 	router := mux.NewRouter()
 
@@ -65,26 +197,159 @@ func main() {
 	router.HandleFunc("/docs/openapi.yaml", docsHandler.ServeOpenAPISpec).Methods("GET")
 
 	router.HandleFunc("/api/health", healthHandler.Health).Methods("GET")
+
+	// Public keys for verifying our access tokens (empty with HS256)
+	router.HandleFunc("/.well-known/jwks.json", jwksHandler.JWKS).Methods("GET")
+
 	router.HandleFunc("/api/auth/login", authHandler.Login).Methods("POST")
+	router.HandleFunc("/api/auth/refresh", authHandler.Refresh).Methods("POST")
+	router.HandleFunc("/api/auth/password/forgot", authHandler.ForgotPassword).Methods("POST")
+	router.HandleFunc("/api/auth/password/reset", authHandler.ResetPassword).Methods("POST")
+	// Second login step, authenticated by the challenge token from /auth/login
+	router.HandleFunc("/api/auth/2fa/challenge/setup", authHandler.ChallengeSetup).Methods("POST")
+	router.HandleFunc("/api/auth/2fa/challenge/verify", authHandler.ChallengeVerify).Methods("POST")
+	// OpenID Connect single sign-on (404 unless OIDC_ISSUER_URL is set)
+	router.HandleFunc("/api/auth/oidc/authorize", ssoHandler.Authorize).Methods("GET")
+	router.HandleFunc("/api/auth/oidc/callback", ssoHandler.Callback).Methods("POST")
 
 	apiRouter := router.PathPrefix("/api").Subrouter()
-	apiRouter.Use(middleware.AuthMiddleware(authUsecase))
+	apiRouter.Use(middleware.AuthMiddleware(authUsecase, apiKeyUsecase))
+	apiRouter.Use(middleware.PermissionMiddleware(roleUsecase))
+
+	// can guards a route with a permission from the caller's role, or from
+	// the scopes of the API key the request was made with
+	can := func(permission string, h http.HandlerFunc) http.Handler {
+		return middleware.RequirePermission(permission)(h)
+	}
+
+	apiRouter.HandleFunc("/auth/me", authHandler.Me).Methods("GET")
+	apiRouter.HandleFunc("/auth/logout", authHandler.Logout).Methods("POST")
+	apiRouter.HandleFunc("/auth/logout-all", authHandler.LogoutAll).Methods("POST")
+	apiRouter.HandleFunc("/auth/password", authHandler.ChangePassword).Methods("POST")
+
+	apiRouter.HandleFunc("/auth/2fa", twoFactorHandler.Status).Methods("GET")
+	apiRouter.HandleFunc("/auth/2fa/setup", twoFactorHandler.Setup).Methods("POST")
+	apiRouter.HandleFunc("/auth/2fa/enable", twoFactorHandler.Enable).Methods("POST")
+	apiRouter.HandleFunc("/auth/2fa/disable", twoFactorHandler.Disable).Methods("POST")
+	apiRouter.HandleFunc("/auth/2fa/recovery-codes", twoFactorHandler.RegenerateRecoveryCodes).Methods("POST")
 
-	apiRouter.HandleFunc("/expenses", expenseHandler.Submit).Methods("POST")
+	// Everyone lists, exports and views their own expenses; expense:read_all
+	// widens that to every employee
+	apiRouter.Handle("/expenses", can(domain.PermExpenseSubmit, expenseHandler.Submit)).Methods("POST")
 	apiRouter.HandleFunc("/expenses", expenseHandler.List).Methods("GET")
+	apiRouter.HandleFunc("/expenses/export", expenseHandler.Export).Methods("GET")
 
-	// Manager-only routes - MUST be before /{id} route to avoid conflicts
-	apiRouter.Handle("/expenses/pending", middleware.ManagerOnly(http.HandlerFunc(expenseHandler.GetPendingApprovals))).Methods("GET")
-	apiRouter.Handle("/expenses/{id}/approve", middleware.ManagerOnly(http.HandlerFunc(expenseHandler.Approve))).Methods("PUT")
-	apiRouter.Handle("/expenses/{id}/reject", middleware.ManagerOnly(http.HandlerFunc(expenseHandler.Reject))).Methods("PUT")
+	// Approval routes - MUST be before /{id} route to avoid conflicts
+	apiRouter.Handle("/expenses/pending", can(domain.PermExpenseApprove, expenseHandler.GetPendingApprovals)).Methods("GET")
+	apiRouter.Handle("/expenses/{id}/approve", can(domain.PermExpenseApprove, expenseHandler.Approve)).Methods("PUT")
+	apiRouter.Handle("/expenses/{id}/reject", can(domain.PermExpenseApprove, expenseHandler.Reject)).Methods("PUT")
+
+	// Refunds: recorded with refund:create, visible to the submitter and
+	// anyone with expense:read_all
+	apiRouter.Handle("/expenses/{id}/refunds", can(domain.PermRefundCreate, refundHandler.Create)).Methods("POST")
+	apiRouter.HandleFunc("/expenses/{id}/refunds", refundHandler.List).Methods("GET")
 
 	// Generic /{id} route must be last
 	apiRouter.HandleFunc("/expenses/{id}", expenseHandler.GetByID).Methods("GET")
 
+	// Receipts are uploaded before the expense citing them is submitted
+	apiRouter.Handle("/receipts", can(domain.PermExpenseSubmit, receiptHandler.Upload)).Methods("POST")
+	apiRouter.HandleFunc("/receipts/{id}/file", receiptHandler.Download).Methods("GET")
+
+	// Payment run review and release
+	apiRouter.Handle("/payment-runs", can(domain.PermPaymentRead, paymentRunHandler.List)).Methods("GET")
+	apiRouter.Handle("/payment-runs/{id}", can(domain.PermPaymentRead, paymentRunHandler.GetByID)).Methods("GET")
+	apiRouter.Handle("/payment-runs/{id}/release", can(domain.PermPaymentRelease, paymentRunHandler.Release)).Methods("POST")
+	apiRouter.Handle("/payment-runs/{id}/payouts/{payoutID}/retry", can(domain.PermPaymentRetry, paymentRunHandler.RetryPayout)).Methods("POST")
+
+	// Cash advances - static and action routes before the generic /{id} route
+	apiRouter.Handle("/advances", can(domain.PermAdvanceRequest, cashAdvanceHandler.Request)).Methods("POST")
+	apiRouter.HandleFunc("/advances", cashAdvanceHandler.List).Methods("GET")
+	apiRouter.HandleFunc("/advances/balance", cashAdvanceHandler.Balance).Methods("GET")
+	apiRouter.Handle("/advances/{id}/approve", can(domain.PermAdvanceApprove, cashAdvanceHandler.Approve)).Methods("PUT")
+	apiRouter.Handle("/advances/{id}/reject", can(domain.PermAdvanceApprove, cashAdvanceHandler.Reject)).Methods("PUT")
+	apiRouter.Handle("/advances/{id}/close", can(domain.PermAdvanceClose, cashAdvanceHandler.Close)).Methods("POST")
+	apiRouter.HandleFunc("/advances/{id}", cashAdvanceHandler.GetByID).Methods("GET")
+
+	// Budgets: everyone can check their own status
+	apiRouter.HandleFunc("/budgets/status", budgetHandler.Status).Methods("GET")
+	apiRouter.Handle("/budgets", can(domain.PermBudgetManage, budgetHandler.Create)).Methods("POST")
+	apiRouter.Handle("/budgets", can(domain.PermBudgetReadAll, budgetHandler.List)).Methods("GET")
+	apiRouter.Handle("/budgets/{id}", can(domain.PermBudgetManage, budgetHandler.Delete)).Methods("DELETE")
+
+	// Analytics cover the expenses the caller may list
+	apiRouter.HandleFunc("/analytics/summary", analyticsHandler.Summary).Methods("GET")
+	apiRouter.HandleFunc("/analytics/breakdown", analyticsHandler.Breakdown).Methods("GET")
+	apiRouter.HandleFunc("/analytics/top-spenders", analyticsHandler.TopSpenders).Methods("GET")
+
+	// Scheduled reports and their past runs
+	apiRouter.Handle("/reports/schedules", can(domain.PermReportManage, reportHandler.CreateSchedule)).Methods("POST")
+	apiRouter.Handle("/reports/schedules", can(domain.PermReportRead, reportHandler.ListSchedules)).Methods("GET")
+	apiRouter.Handle("/reports/schedules/{id}", can(domain.PermReportRead, reportHandler.GetSchedule)).Methods("GET")
+	apiRouter.Handle("/reports/schedules/{id}", can(domain.PermReportManage, reportHandler.UpdateSchedule)).Methods("PUT")
+	apiRouter.Handle("/reports/schedules/{id}", can(domain.PermReportManage, reportHandler.DeleteSchedule)).Methods("DELETE")
+	apiRouter.Handle("/reports/schedules/{id}/run", can(domain.PermReportManage, reportHandler.Run)).Methods("POST")
+	apiRouter.Handle("/reports/runs", can(domain.PermReportRead, reportHandler.ListRuns)).Methods("GET")
+	apiRouter.Handle("/reports/runs/{id}/download", can(domain.PermReportRead, reportHandler.Download)).Methods("GET")
+
+	// Ledger journal export of paid expenses
+	apiRouter.Handle("/journal/accounts", can(domain.PermJournalExport, journalHandler.ListAccounts)).Methods("GET")
+	apiRouter.Handle("/journal/accounts/{key}", can(domain.PermJournalManage, journalHandler.UpdateAccount)).Methods("PUT")
+	apiRouter.Handle("/journal/batches", can(domain.PermJournalExport, journalHandler.CreateBatch)).Methods("POST")
+	apiRouter.Handle("/journal/batches", can(domain.PermJournalExport, journalHandler.List)).Methods("GET")
+	apiRouter.Handle("/journal/batches/{id}", can(domain.PermJournalExport, journalHandler.Download)).Methods("GET")
+
+	// Runtime metrics, including payment gateway circuit breaker state;
+	// they also expose memory stats and the command line
+	apiRouter.Handle("/admin/debug/vars", can(domain.PermSettingsManage, expvar.Handler().ServeHTTP)).Methods("GET")
+
+	// User administration
+	apiRouter.Handle("/admin/users", can(domain.PermUserManage, userAdminHandler.Create)).Methods("POST")
+	apiRouter.Handle("/admin/users", can(domain.PermUserRead, userAdminHandler.List)).Methods("GET")
+	apiRouter.Handle("/admin/users/{id}/role", can(domain.PermUserManage, userAdminHandler.ChangeRole)).Methods("PUT")
+	apiRouter.Handle("/admin/users/{id}/deactivate", can(domain.PermUserManage, userAdminHandler.Deactivate)).Methods("POST")
+	apiRouter.Handle("/admin/users/{id}/reactivate", can(domain.PermUserManage, userAdminHandler.Reactivate)).Methods("POST")
+	apiRouter.Handle("/admin/users/{id}/lockout", can(domain.PermUserRead, userAdminHandler.LockoutStatus)).Methods("GET")
+	apiRouter.Handle("/admin/users/{id}/unlock", can(domain.PermUserManage, userAdminHandler.Unlock)).Methods("POST")
+	apiRouter.Handle("/admin/users/{id}/audit-logs", can(domain.PermAuditRead, userAdminHandler.AuditLogs)).Methods("GET")
+	apiRouter.Handle("/admin/users/{id}", can(domain.PermUserRead, userAdminHandler.GetByID)).Methods("GET")
+	apiRouter.Handle("/admin/users/{id}", can(domain.PermUserManage, userAdminHandler.Update)).Methods("PATCH")
+
+	// Role definitions
+	apiRouter.Handle("/admin/permissions", can(domain.PermUserRead, roleHandler.Permissions)).Methods("GET")
+	apiRouter.Handle("/admin/roles", can(domain.PermUserRead, roleHandler.List)).Methods("GET")
+	apiRouter.Handle("/admin/roles", can(domain.PermRoleManage, roleHandler.Create)).Methods("POST")
+	apiRouter.Handle("/admin/roles/{name}", can(domain.PermUserRead, roleHandler.GetByName)).Methods("GET")
+	apiRouter.Handle("/admin/roles/{name}", can(domain.PermRoleManage, roleHandler.Update)).Methods("PUT")
+	apiRouter.Handle("/admin/roles/{name}", can(domain.PermRoleManage, roleHandler.Delete)).Methods("DELETE")
+
+	// Policy rules checked on submission
+	apiRouter.Handle("/admin/policy-rules", can(domain.PermPolicyManage, policyHandler.List)).Methods("GET")
+	apiRouter.Handle("/admin/policy-rules", can(domain.PermPolicyManage, policyHandler.Create)).Methods("POST")
+	apiRouter.Handle("/admin/policy-rules/{id}", can(domain.PermPolicyManage, policyHandler.GetByID)).Methods("GET")
+	apiRouter.Handle("/admin/policy-rules/{id}", can(domain.PermPolicyManage, policyHandler.Update)).Methods("PUT")
+	apiRouter.Handle("/admin/policy-rules/{id}", can(domain.PermPolicyManage, policyHandler.Delete)).Methods("DELETE")
+
+	// Runtime settings
+	apiRouter.HandleFunc("/settings/expense-limits", settingsHandler.ExpenseLimits).Methods("GET")
+	apiRouter.Handle("/admin/settings", can(domain.PermSettingsManage, settingsHandler.List)).Methods("GET")
+	apiRouter.Handle("/admin/settings/history", can(domain.PermSettingsManage, settingsHandler.History)).Methods("GET")
+	apiRouter.Handle("/admin/settings/{key}", can(domain.PermSettingsManage, settingsHandler.Update)).Methods("PUT")
+	apiRouter.Handle("/admin/settings/{key}", can(domain.PermSettingsManage, settingsHandler.Reset)).Methods("DELETE")
+
+	// Service accounts and their API keys
+	apiRouter.Handle("/admin/service-accounts", can(domain.PermServiceAccountManage, serviceAccountHandler.Create)).Methods("POST")
+	apiRouter.Handle("/admin/service-accounts", can(domain.PermServiceAccountManage, serviceAccountHandler.List)).Methods("GET")
+	apiRouter.Handle("/admin/service-accounts/{id}/keys", can(domain.PermServiceAccountManage, serviceAccountHandler.CreateKey)).Methods("POST")
+	apiRouter.Handle("/admin/service-accounts/{id}/keys", can(domain.PermServiceAccountManage, serviceAccountHandler.ListKeys)).Methods("GET")
+	apiRouter.Handle("/admin/service-accounts/{id}/keys/{keyID}/rotate", can(domain.PermServiceAccountManage, serviceAccountHandler.RotateKey)).Methods("POST")
+	apiRouter.Handle("/admin/service-accounts/{id}/keys/{keyID}", can(domain.PermServiceAccountManage, serviceAccountHandler.RevokeKey)).Methods("DELETE")
+
 	c := cors.New(cors.Options{
 		AllowedOrigins:   []string{"http://localhost:3000", "http://frontend:3000"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Authorization", "Content-Type"},
+		ExposedHeaders:   []string{"Content-Disposition"},
 		AllowCredentials: true,
 	})
 
@@ -107,6 +372,11 @@ func main() {
 	<-quit
 
 	logger.InfoLogger.Println("Shutting down server...")
+	paymentRunScheduler.Stop()
+	reportScheduler.Stop()
+	if keyRotationScheduler != nil {
+		keyRotationScheduler.Stop()
+	}
 	workerPool.Stop()
 
 	if err := server.Close(); err != nil {
@@ -0,0 +1,30 @@
+// Command mock-oidc runs a mock OpenID Connect issuer for trying single
+// sign-on locally. Its login page accepts any email, name and groups.
+package main
+
+import (
+	"expense-management-system/internal/oidc/oidctest"
+	"log"
+	"net/http"
+	"os"
+)
+
+func main() {
+	addr := getEnv("MOCK_OIDC_ADDR", ":9000")
+	issuerURL := getEnv("MOCK_OIDC_ISSUER", "http://localhost:9000")
+
+	issuer, err := oidctest.New(issuerURL, getEnv("OIDC_CLIENT_ID", "expense-app"), getEnv("OIDC_CLIENT_SECRET", "expense-secret"))
+	if err != nil {
+		log.Fatalf("Failed to create issuer: %v", err)
+	}
+
+	log.Printf("Mock OIDC issuer %s listening on %s", issuerURL, addr)
+	log.Fatal(http.ListenAndServe(addr, issuer))
+}
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -1,21 +1,220 @@
 package domain
 
+// DefaultExpenseLimits apply when nothing else sets the limits.
+var DefaultExpenseLimits = ExpenseLimits{
+	MinAmountIDR:         10000,
+	MaxAmountIDR:         50000000,
+	ApprovalThresholdIDR: 1000000,
+}
+
+// Runtime settings admins can change without a deploy. Values are whole
+// numbers; until set, each comes from the environment.
+const (
+	SettingMinExpenseAmount  = "min_expense_amount_idr"
+	SettingMaxExpenseAmount  = "max_expense_amount_idr"
+	SettingApprovalThreshold = "approval_threshold_idr"
+)
+
+// SettingDefinitions lists every runtime setting with what it controls.
+var SettingDefinitions = []SettingDefinition{
+	{SettingMinExpenseAmount, "Smallest amount in IDR an expense or cash advance may have"},
+	{SettingMaxExpenseAmount, "Largest amount in IDR an expense or cash advance may have"},
+	{SettingApprovalThreshold, "Amount in IDR from which expenses and cash advances need manager approval"},
+}
+
+func IsValidSetting(key string) bool {
+	for _, d := range SettingDefinitions {
+		if d.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// MaxAmountIDR is the largest amount the amount_idr columns hold.
+const MaxAmountIDR = 2147483647
+
+// CurrencyIDR is the currency expenses are claimed in; receipts read in
+// others are not compared with the claimed amount.
+const CurrencyIDR = "IDR"
+
+const (
+	SettingSourceDatabase    = "database"
+	SettingSourceEnvironment = "environment"
+)
+
 const (
-	MinExpenseAmount  = 10000
-	MaxExpenseAmount  = 50000000
-	ApprovalThreshold = 1000000
+	CategoryGeneral   = "general"
+	CategoryTravel    = "travel"
+	CategoryLodging   = "lodging"
+	CategoryMeals     = "meals"
+	CategoryTransport = "transport"
+	CategoryOffice    = "office"
+	CategoryTraining  = "training"
 )
 
+// ExpenseCategories lists every category an expense may be filed under.
+var ExpenseCategories = []string{
+	CategoryGeneral,
+	CategoryTravel,
+	CategoryLodging,
+	CategoryMeals,
+	CategoryTransport,
+	CategoryOffice,
+	CategoryTraining,
+}
+
+func IsValidCategory(category string) bool {
+	for _, c := range ExpenseCategories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+// Built-in roles. Admins can define more; what each role may do is stored
+// in the roles table as a list of permissions.
 const (
 	RoleEmployee = "employee"
 	RoleManager  = "manager"
+	RoleFinance  = "finance"
+	RoleAdmin    = "admin"
+	RoleAuditor  = "auditor"
+	// RoleService is held by service accounts. It grants nothing: what a
+	// service account may do is set by the scopes of its API keys.
+	RoleService = "service"
+)
+
+func IsBuiltInRole(role string) bool {
+	switch role {
+	case RoleEmployee, RoleManager, RoleFinance, RoleAdmin, RoleAuditor, RoleService:
+		return true
+	}
+	return false
+}
+
+const (
+	PermExpenseSubmit  = "expense:submit"
+	PermExpenseReadAll = "expense:read_all"
+	PermExpenseApprove = "expense:approve"
+
+	PermRefundCreate = "refund:create"
+
+	PermPaymentRead    = "payment:read"
+	PermPaymentRelease = "payment:release"
+	PermPaymentRetry   = "payment:retry"
+
+	PermAdvanceRequest = "advance:request"
+	PermAdvanceReadAll = "advance:read_all"
+	PermAdvanceApprove = "advance:approve"
+	PermAdvanceClose   = "advance:close"
+
+	PermBudgetReadAll = "budget:read_all"
+	PermBudgetManage  = "budget:manage"
+
+	PermUserRead   = "user:read"
+	PermUserManage = "user:manage"
+	PermRoleManage = "role:manage"
+	PermAuditRead  = "audit:read"
+
+	PermServiceAccountManage = "service_account:manage"
+
+	PermJournalExport = "journal:export"
+	PermJournalManage = "journal:manage"
+
+	PermReportRead   = "report:read"
+	PermReportManage = "report:manage"
+
+	PermPolicyManage = "policy:manage"
+
+	PermSettingsManage = "settings:manage"
+)
+
+// Permissions lists every permission with what it allows.
+var Permissions = []Permission{
+	{PermExpenseSubmit, "Submit expenses"},
+	{PermExpenseReadAll, "View every employee's expenses and refunds"},
+	{PermExpenseApprove, "Approve and reject expenses"},
+	{PermRefundCreate, "Record refunds and reversals"},
+	{PermPaymentRead, "View payment runs"},
+	{PermPaymentRelease, "Release payment runs for payout"},
+	{PermPaymentRetry, "Retry failed payouts"},
+	{PermAdvanceRequest, "Request cash advances"},
+	{PermAdvanceReadAll, "View every employee's cash advances and balances"},
+	{PermAdvanceApprove, "Approve and reject cash advances"},
+	{PermAdvanceClose, "Close cash advances"},
+	{PermBudgetReadAll, "View budgets and every employee's budget status"},
+	{PermBudgetManage, "Create and delete budgets"},
+	{PermUserRead, "View user accounts, roles and lockout status"},
+	{PermUserManage, "Create, change, deactivate and unlock user accounts"},
+	{PermRoleManage, "Define roles and their permissions"},
+	{PermAuditRead, "Read audit logs"},
+	{PermServiceAccountManage, "Create service accounts and issue, rotate and revoke their API keys"},
+	{PermJournalExport, "Export paid expenses as ledger journal batches and download them"},
+	{PermJournalManage, "Change the ledger accounts journal lines post to"},
+	{PermReportRead, "View scheduled reports and download their runs"},
+	{PermReportManage, "Create, change, delete and run scheduled reports"},
+	{PermPolicyManage, "Define the policy rules checked when expenses are submitted"},
+	{PermSettingsManage, "Change runtime settings such as expense limits and the approval threshold"},
+}
+
+func IsValidPermission(permission string) bool {
+	for _, p := range Permissions {
+		if p.Name == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidScope reports whether an API key may be granted permission. Keys
+// cannot manage roles or API keys, so a leaked key cannot widen itself.
+func IsValidScope(permission string) bool {
+	switch permission {
+	case PermRoleManage, PermServiceAccountManage:
+		return false
+	}
+	return IsValidPermission(permission)
+}
+
+const (
+	StatusAwaitingApproval  = "awaiting_approval"
+	StatusApproved          = "approved"
+	StatusRejected          = "rejected"
+	StatusCompleted         = "completed"
+	StatusPartiallyRefunded = "partially_refunded"
+	StatusRefunded          = "refunded"
 )
 
+func IsValidStatus(status string) bool {
+	switch status {
+	case StatusAwaitingApproval, StatusApproved, StatusRejected, StatusCompleted, StatusPartiallyRefunded, StatusRefunded:
+		return true
+	}
+	return false
+}
+
+// Fields expense lists can be sorted by.
+const (
+	ExpenseSortSubmittedAt = "submitted_at"
+	ExpenseSortProcessedAt = "processed_at"
+	ExpenseSortAmount      = "amount_idr"
+	ExpenseSortStatus      = "status"
+	ExpenseSortID          = "id"
+)
+
+func IsValidExpenseSort(field string) bool {
+	switch field {
+	case ExpenseSortSubmittedAt, ExpenseSortProcessedAt, ExpenseSortAmount, ExpenseSortStatus, ExpenseSortID:
+		return true
+	}
+	return false
+}
+
 const (
-	StatusAwaitingApproval = "awaiting_approval"
-	StatusApproved         = "approved"
-	StatusRejected         = "rejected"
-	StatusCompleted        = "completed"
+	SortAsc  = "asc"
+	SortDesc = "desc"
 )
 
 const (
@@ -23,4 +222,178 @@ const (
 	ActionApprove  = "approve"
 	ActionReject   = "reject"
 	ActionComplete = "complete"
+	ActionRelease  = "release"
+	ActionRefund   = "refund"
+	ActionSettle   = "settle"
+	// ActionPayoutRetry records finance sending a failed payout again.
+	ActionPayoutRetry = "payout_retry"
+	// ActionSubmitBlocked records a submission that policy rules refused;
+	// its subject is the submitter since no expense was created.
+	ActionSubmitBlocked = "submit_blocked"
+
+	ActionUserCreate     = "user_create"
+	ActionUserUpdate     = "user_update"
+	ActionRoleChange     = "role_change"
+	ActionUserDeactivate = "user_deactivate"
+	ActionUserReactivate = "user_reactivate"
+	ActionUserUnlock     = "user_unlock"
+	ActionUserProvision  = "user_provision"
+	ActionIdentityLink   = "identity_link"
+
+	ActionRoleCreate = "role_create"
+	ActionRoleUpdate = "role_update"
+	ActionRoleDelete = "role_delete"
+
+	ActionPolicyRuleCreate = "policy_rule_create"
+	ActionPolicyRuleUpdate = "policy_rule_update"
+	ActionPolicyRuleDelete = "policy_rule_delete"
+
+	ActionServiceAccountCreate = "service_account_create"
+	ActionAPIKeyCreate         = "api_key_create"
+	ActionAPIKeyRotate         = "api_key_rotate"
+	ActionAPIKeyRevoke         = "api_key_revoke"
 )
+
+const (
+	AdvanceStatusAwaitingApproval = "awaiting_approval"
+	AdvanceStatusApproved         = "approved"
+	AdvanceStatusRejected         = "rejected"
+	AdvanceStatusPaid             = "paid"
+	AdvanceStatusSettled          = "settled"
+)
+
+const (
+	// RefundTypeRefund records money the employee returned to the company.
+	RefundTypeRefund = "refund"
+	// RefundTypeReversal pulls the original payout back through the gateway.
+	RefundTypeReversal = "reversal"
+)
+
+const (
+	RefundStatusPending   = "pending"
+	RefundStatusCompleted = "completed"
+	RefundStatusFailed    = "failed"
+)
+
+const (
+	PaymentRunStatusOpen          = "open"
+	PaymentRunStatusPendingReview = "pending_review"
+	PaymentRunStatusReleased      = "released"
+	PaymentRunStatusCompleted     = "completed"
+	PaymentRunStatusFailed        = "failed"
+)
+
+const (
+	PayoutStatusPending   = "pending"
+	PayoutStatusCompleted = "completed"
+	PayoutStatusFailed    = "failed"
+)
+
+const (
+	BudgetPeriodMonthly   = "monthly"
+	BudgetPeriodQuarterly = "quarterly"
+	BudgetPeriodYearly    = "yearly"
+)
+
+const (
+	// BudgetActionRequireApproval sends expenses that would breach the hard
+	// limit to a manager even when they are below the approval threshold.
+	BudgetActionRequireApproval = "require_approval"
+	// BudgetActionBlock rejects such expenses outright.
+	BudgetActionBlock = "block"
+)
+
+const (
+	// JournalAccountCash is credited with what was paid out to employees.
+	JournalAccountCash = "cash"
+	// JournalAccountAdvances is credited with what cash advances covered.
+	JournalAccountAdvances = "employee_advances"
+)
+
+// JournalExpenseAccount is the key of the account expenses of category
+// are debited to.
+func JournalExpenseAccount(category string) string {
+	return "expense:" + category
+}
+
+// JournalAccountKeys lists every account a journal line can post to.
+func JournalAccountKeys() []string {
+	keys := make([]string, 0, len(ExpenseCategories)+2)
+	for _, category := range ExpenseCategories {
+		keys = append(keys, JournalExpenseAccount(category))
+	}
+	return append(keys, JournalAccountCash, JournalAccountAdvances)
+}
+
+func IsValidJournalAccount(key string) bool {
+	for _, k := range JournalAccountKeys() {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Dimensions an expense breakdown can group by.
+const (
+	AnalyticsByMonth    = "month"
+	AnalyticsByStatus   = "status"
+	AnalyticsByCategory = "category"
+	AnalyticsByEmployee = "employee"
+	AnalyticsByApprover = "approver"
+)
+
+func IsValidAnalyticsDimension(dimension string) bool {
+	switch dimension {
+	case AnalyticsByMonth, AnalyticsByStatus, AnalyticsByCategory, AnalyticsByEmployee, AnalyticsByApprover:
+		return true
+	}
+	return false
+}
+
+// Periods a scheduled report covers, ending where the day, week (from
+// Monday) or month of the run starts.
+const (
+	ReportPeriodDay   = "previous_day"
+	ReportPeriodWeek  = "previous_week"
+	ReportPeriodMonth = "previous_month"
+	ReportPeriodAll   = "all_time"
+)
+
+func IsValidReportPeriod(period string) bool {
+	switch period {
+	case ReportPeriodDay, ReportPeriodWeek, ReportPeriodMonth, ReportPeriodAll:
+		return true
+	}
+	return false
+}
+
+const (
+	ReportRunRunning   = "running"
+	ReportRunSucceeded = "succeeded"
+	ReportRunFailed    = "failed"
+)
+
+const (
+	ReportTriggerSchedule = "schedule"
+	ReportTriggerManual   = "manual"
+)
+
+// What a policy rule does to an expense matching its condition.
+const (
+	// PolicyActionBlock refuses the submission.
+	PolicyActionBlock = "block"
+	// PolicyActionWarn accepts it with a warning.
+	PolicyActionWarn = "warn"
+	// PolicyActionRequireApproval sends it to a manager even below the
+	// approval threshold.
+	PolicyActionRequireApproval = "require_approval"
+)
+
+func IsValidPolicyAction(action string) bool {
+	switch action {
+	case PolicyActionBlock, PolicyActionWarn, PolicyActionRequireApproval:
+		return true
+	}
+	return false
+}
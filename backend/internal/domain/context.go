@@ -0,0 +1,17 @@
+package domain
+
+import "context"
+
+type apiKeyContextKey struct{}
+
+// WithAPIKeyID marks ctx as belonging to a request made with an API key, so
+// that audit entries written on its behalf record the key.
+func WithAPIKeyID(ctx context.Context, keyID int) context.Context {
+	return context.WithValue(ctx, apiKeyContextKey{}, keyID)
+}
+
+// APIKeyIDFromContext returns the API key the request was made with, if any.
+func APIKeyIDFromContext(ctx context.Context) (int, bool) {
+	keyID, ok := ctx.Value(apiKeyContextKey{}).(int)
+	return keyID, ok
+}
@@ -1,15 +1,124 @@
 package domain
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type User struct {
-	ID           int       `json:"id"`
-	Email        string    `json:"email"`
-	PasswordHash string    `json:"-"`
-	Name         string    `json:"name"`
-	Role         string    `json:"role"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID             int       `json:"id"`
+	Email          string    `json:"email"`
+	PasswordHash   string    `json:"-"`
+	Name           string    `json:"name"`
+	Role           string    `json:"role"`
+	TeamID         *int      `json:"team_id,omitempty"`
+	Active         bool      `json:"active"`
+	ServiceAccount bool      `json:"service_account"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+
+	FailedLoginAttempts int        `json:"-"`
+	LastFailedLoginAt   *time.Time `json:"-"`
+	LockedUntil         *time.Time `json:"-"`
+}
+
+// LoginAttempt is one password login, successful or not. UserID is nil when
+// the email did not match an account.
+type LoginAttempt struct {
+	ID        int       `json:"id"`
+	Email     string    `json:"email"`
+	UserID    *int      `json:"user_id,omitempty"`
+	IPAddress string    `json:"ip_address"`
+	Success   bool      `json:"success"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// APIKey lets a service account call the API. Service accounts are users
+// with ServiceAccount set; they have no password. Only a hash of the key is
+// stored: the key itself is shown once, and Prefix identifies it in lists
+// and logs.
+type APIKey struct {
+	ID               int        `json:"id"`
+	ServiceAccountID int        `json:"service_account_id"`
+	Name             string     `json:"name"`
+	Prefix           string     `json:"prefix"`
+	KeyHash          string     `json:"-"`
+	Scopes           []string   `json:"scopes"`
+	ExpiresAt        *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt       *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP       *string    `json:"last_used_ip,omitempty"`
+	CreatedBy        int        `json:"created_by"`
+	CreatedAt        time.Time  `json:"created_at"`
+	RevokedAt        *time.Time `json:"revoked_at,omitempty"`
+	ReplacedByID     *int       `json:"replaced_by_id,omitempty"`
+}
+
+// Role is a named set of permissions assigned to users.
+type Role struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Permissions []string  `json:"permissions"`
+	BuiltIn     bool      `json:"built_in"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type Permission struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type SettingDefinition struct {
+	Key         string `json:"key"`
+	Description string `json:"description"`
+}
+
+// Setting is the value a runtime setting has now. Source is "database" once
+// an admin set it and "environment" while the configured default applies.
+type Setting struct {
+	Key          string     `json:"key"`
+	Description  string     `json:"description,omitempty"`
+	Value        int        `json:"value"`
+	DefaultValue int        `json:"default_value"`
+	Source       string     `json:"source"`
+	UpdatedBy    *int       `json:"updated_by,omitempty"`
+	UpdatedAt    *time.Time `json:"updated_at,omitempty"`
+}
+
+// SettingChange records one change of a runtime setting. OldValue is nil
+// when the default applied before, NewValue when it was reset to it.
+type SettingChange struct {
+	ID        int       `json:"id"`
+	Key       string    `json:"key"`
+	OldValue  *int      `json:"old_value"`
+	NewValue  *int      `json:"new_value"`
+	ChangedBy int       `json:"changed_by"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// ExpenseLimits are the amount rules expenses and cash advances are checked
+// against when submitted.
+type ExpenseLimits struct {
+	MinAmountIDR         int `json:"min_amount_idr"`
+	MaxAmountIDR         int `json:"max_amount_idr"`
+	ApprovalThresholdIDR int `json:"approval_threshold_idr"`
+}
+
+type LockoutStatus struct {
+	UserID            int             `json:"user_id"`
+	Locked            bool            `json:"locked"`
+	LockedUntil       *time.Time      `json:"locked_until,omitempty"`
+	FailedAttempts    int             `json:"failed_attempts"`
+	LastFailedLoginAt *time.Time      `json:"last_failed_login_at,omitempty"`
+	RecentAttempts    []*LoginAttempt `json:"recent_attempts"`
+}
+
+// UserUpdate holds the profile fields an admin may change; nil fields are
+// left as they are.
+type UserUpdate struct {
+	Email  *string `json:"email,omitempty"`
+	Name   *string `json:"name,omitempty"`
+	TeamID *int    `json:"team_id,omitempty"`
 }
 
 type Expense struct {
@@ -17,16 +126,93 @@ type Expense struct {
 	UserID            int        `json:"user_id"`
 	AmountIDR         int        `json:"amount_idr"`
 	Description       string     `json:"description"`
+	Category          string     `json:"category"`
 	ReceiptURL        *string    `json:"receipt_url,omitempty"`
+	ReceiptID         *int       `json:"receipt_id,omitempty"`
 	Status            string     `json:"status"`
 	AutoApproved      bool       `json:"auto_approved"`
 	SubmittedAt       time.Time  `json:"submitted_at"`
 	ProcessedAt       *time.Time `json:"processed_at,omitempty"`
 	PaymentID         *string    `json:"payment_id,omitempty"`
 	PaymentExternalID *string    `json:"payment_external_id,omitempty"`
-	CreatedAt         time.Time  `json:"created_at"`
-	UpdatedAt         time.Time  `json:"updated_at"`
-	Approval          *Approval  `json:"approval,omitempty"`
+	PaymentRunID      *int       `json:"payment_run_id,omitempty"`
+	CashAdvanceID     *int       `json:"cash_advance_id,omitempty"`
+	AdvanceSettledIDR int        `json:"advance_settled_idr,omitempty"`
+	JournalBatchID    *int       `json:"journal_batch_id,omitempty"`
+	// PossibleDuplicateOf is the earlier expense this one likely repeats;
+	// flagged expenses always wait for a manager.
+	PossibleDuplicateOf *int      `json:"possible_duplicate_of,omitempty"`
+	CreatedAt           time.Time `json:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at"`
+	Approval            *Approval `json:"approval,omitempty"`
+	BudgetWarnings      []string  `json:"budget_warnings,omitempty"`
+	// PolicyViolations are the warn and require_approval rules the expense
+	// matched when it was submitted.
+	PolicyViolations []PolicyViolation `json:"policy_violations,omitempty"`
+	// ReceiptExtraction is what was read off the receipt when it was
+	// uploaded. ReceiptAmountMismatch is set when its total, in IDR, is not
+	// the amount claimed.
+	ReceiptExtraction     *ReceiptExtraction `json:"receipt_extraction,omitempty"`
+	ReceiptAmountMismatch bool               `json:"receipt_amount_mismatch,omitempty"`
+}
+
+// DuplicateCandidate is an earlier expense a new one may repeat, with the
+// content hash of its receipt if it has an uploaded one.
+type DuplicateCandidate struct {
+	Expense       *Expense
+	ReceiptSHA256 *string
+}
+
+// ExpenseFilter narrows and orders an expense list; zero fields do not
+// filter. From bounds are inclusive and To bounds exclusive.
+type ExpenseFilter struct {
+	SubmitterID   *int
+	ApproverID    *int
+	Statuses      []string
+	SubmittedFrom *time.Time
+	SubmittedTo   *time.Time
+	ProcessedFrom *time.Time
+	ProcessedTo   *time.Time
+	MinAmountIDR  *int
+	MaxAmountIDR  *int
+	AutoApproved  *bool
+	// Search matches words of the description (Postgres full-text search).
+	Search string
+	// SortBy is one of the ExpenseSort fields, newest submitted first by
+	// default.
+	SortBy    string
+	SortOrder string
+}
+
+// PageRequest selects a page of a list: the rows after Cursor when it is
+// set, otherwise page Page (from 1). Counting all matching rows costs a
+// query of its own, so it is only done with CountTotal.
+type PageRequest struct {
+	Page       int
+	Limit      int
+	Cursor     string
+	CountTotal bool
+}
+
+// ExpenseExportRow is an expense with what an export shows next to it: who
+// submitted it and, unless it was auto-approved, who decided on it and
+// their notes.
+type ExpenseExportRow struct {
+	Expense
+	SubmitterName  string
+	SubmitterEmail string
+	ApproverName   *string
+	ApproverEmail  *string
+	ApprovalNotes  *string
+	DecidedAt      *time.Time
+}
+
+// ExpensePage is one page of expenses. NextCursor continues after its last
+// expense and is empty on the last page; Total is nil unless counted.
+type ExpensePage struct {
+	Expenses   []*Expense
+	Total      *int
+	NextCursor string
 }
 
 type Approval struct {
@@ -39,12 +225,427 @@ type Approval struct {
 }
 
 type AuditLog struct {
-	ID        int                    `json:"id"`
-	ExpenseID int                    `json:"expense_id"`
-	UserID    *int                   `json:"user_id,omitempty"`
-	Action    string                 `json:"action"`
-	OldStatus *string                `json:"old_status,omitempty"`
-	NewStatus *string                `json:"new_status,omitempty"`
-	Metadata  map[string]interface{} `json:"metadata,omitempty"`
-	CreatedAt time.Time              `json:"created_at"`
+	ID            int                    `json:"id"`
+	ExpenseID     int                    `json:"expense_id,omitempty"`
+	CashAdvanceID *int                   `json:"cash_advance_id,omitempty"`
+	SubjectUserID *int                   `json:"subject_user_id,omitempty"`
+	UserID        *int                   `json:"user_id,omitempty"`
+	Action        string                 `json:"action"`
+	OldStatus     *string                `json:"old_status,omitempty"`
+	NewStatus     *string                `json:"new_status,omitempty"`
+	Metadata      map[string]interface{} `json:"metadata,omitempty"`
+	APIKeyID      *int                   `json:"api_key_id,omitempty"`
+	CreatedAt     time.Time              `json:"created_at"`
+}
+
+// AuditLogPage is one page of audit logs, as ExpensePage.
+type AuditLogPage struct {
+	AuditLogs  []*AuditLog
+	NextCursor string
+}
+
+type PaymentRun struct {
+	ID             int        `json:"id"`
+	CutoffAt       time.Time  `json:"cutoff_at"`
+	Status         string     `json:"status"`
+	TotalAmountIDR int        `json:"total_amount_idr"`
+	ExpenseCount   int        `json:"expense_count"`
+	ReleasedBy     *int       `json:"released_by,omitempty"`
+	ReleasedAt     *time.Time `json:"released_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	Payouts        []*Payout  `json:"payouts,omitempty"`
+	Expenses       []*Expense `json:"expenses,omitempty"`
+}
+
+// Payout is a single gateway transfer to one employee covering all of their
+// expenses in a payment run.
+type Payout struct {
+	ID                int       `json:"id"`
+	PaymentRunID      int       `json:"payment_run_id"`
+	UserID            int       `json:"user_id"`
+	AmountIDR         int       `json:"amount_idr"`
+	ExpenseCount      int       `json:"expense_count"`
+	Status            string    `json:"status"`
+	PaymentID         *string   `json:"payment_id,omitempty"`
+	PaymentExternalID string    `json:"payment_external_id"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+type Refund struct {
+	ID               int       `json:"id"`
+	ExpenseID        int       `json:"expense_id"`
+	PaymentID        string    `json:"payment_id"`
+	Type             string    `json:"type"`
+	AmountIDR        int       `json:"amount_idr"`
+	Reason           string    `json:"reason"`
+	Status           string    `json:"status"`
+	ExternalID       string    `json:"external_id"`
+	ProviderRefundID *string   `json:"provider_refund_id,omitempty"`
+	InitiatedBy      int       `json:"initiated_by"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// CashAdvance is money paid to an employee up front. Once paid, later expenses
+// linked to it are settled against OutstandingIDR before anything is paid out.
+type CashAdvance struct {
+	ID                int        `json:"id"`
+	UserID            int        `json:"user_id"`
+	AmountIDR         int        `json:"amount_idr"`
+	Purpose           string     `json:"purpose"`
+	Status            string     `json:"status"`
+	OutstandingIDR    int        `json:"outstanding_idr"`
+	RecoveredIDR      int        `json:"recovered_idr"`
+	ApproverID        *int       `json:"approver_id,omitempty"`
+	ApprovalNotes     *string    `json:"approval_notes,omitempty"`
+	SubmittedAt       time.Time  `json:"submitted_at"`
+	ProcessedAt       *time.Time `json:"processed_at,omitempty"`
+	PaymentID         *string    `json:"payment_id,omitempty"`
+	PaymentExternalID string     `json:"payment_external_id"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+type AdvanceBalance struct {
+	UserID         int            `json:"user_id"`
+	OutstandingIDR int            `json:"outstanding_idr"`
+	Advances       []*CashAdvance `json:"advances"`
+}
+
+// Budget caps spending for one period. It applies to a single employee
+// (UserID), a team (TeamID) or everyone, optionally narrowed to one Category.
+type Budget struct {
+	ID              int       `json:"id"`
+	UserID          *int      `json:"user_id,omitempty"`
+	TeamID          *int      `json:"team_id,omitempty"`
+	Category        *string   `json:"category,omitempty"`
+	Period          string    `json:"period"`
+	SoftLimitIDR    *int      `json:"soft_limit_idr,omitempty"`
+	HardLimitIDR    *int      `json:"hard_limit_idr,omitempty"`
+	HardLimitAction string    `json:"hard_limit_action"`
+	CreatedBy       int       `json:"created_by"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// BudgetStatus is a budget's consumption for the current period. ConsumedIDR
+// counts approved and completed expenses submitted within the period.
+type BudgetStatus struct {
+	Budget            *Budget   `json:"budget"`
+	PeriodStart       time.Time `json:"period_start"`
+	PeriodEnd         time.Time `json:"period_end"`
+	ConsumedIDR       int       `json:"consumed_idr"`
+	SoftLimitExceeded bool      `json:"soft_limit_exceeded"`
+	HardLimitExceeded bool      `json:"hard_limit_exceeded"`
+}
+
+// AnalyticsSummary aggregates the expenses matching a filter. Turnaround
+// runs from submission to the manager's decision and is only known for
+// expenses a manager decided on.
+type AnalyticsSummary struct {
+	ExpenseCount       int      `json:"expense_count"`
+	TotalAmountIDR     int      `json:"total_amount_idr"`
+	AverageAmountIDR   int      `json:"average_amount_idr"`
+	AutoApprovedCount  int      `json:"auto_approved_count"`
+	AutoApprovalRate   float64  `json:"auto_approval_rate"`
+	DecidedCount       int      `json:"decided_count"`
+	AvgTurnaroundHours *float64 `json:"avg_approval_turnaround_hours,omitempty"`
+}
+
+// AnalyticsGroup is one row of a breakdown: the expenses sharing a month,
+// status, category, employee or approver. Label names the employee or
+// approver whose ID is the key.
+type AnalyticsGroup struct {
+	Key                string   `json:"key"`
+	Label              string   `json:"label,omitempty"`
+	ExpenseCount       int      `json:"expense_count"`
+	TotalAmountIDR     int      `json:"total_amount_idr"`
+	AvgTurnaroundHours *float64 `json:"avg_approval_turnaround_hours,omitempty"`
+}
+
+// ReportFilters narrow the expenses a report covers like the filters of
+// the expense list; the dates come from the report's period.
+type ReportFilters struct {
+	Statuses     []string `json:"status,omitempty"`
+	SubmitterID  *int     `json:"submitter_id,omitempty"`
+	ApproverID   *int     `json:"approver_id,omitempty"`
+	MinAmountIDR *int     `json:"min_amount,omitempty"`
+	MaxAmountIDR *int     `json:"max_amount,omitempty"`
+	AutoApproved *bool    `json:"auto_approved,omitempty"`
+	Search       string   `json:"q,omitempty"`
+}
+
+// ReportSchedule is a report definition run on a cron schedule. Each run
+// covers the expenses submitted in the period before it, listed one by one
+// or, with GroupBy, as an analytics breakdown. NextRunAt is nil while the
+// schedule is disabled.
+type ReportSchedule struct {
+	ID         int           `json:"id"`
+	Name       string        `json:"name"`
+	Cron       string        `json:"cron"`
+	Period     string        `json:"period"`
+	GroupBy    string        `json:"group_by,omitempty"`
+	Format     string        `json:"format"`
+	Filters    ReportFilters `json:"filters"`
+	Recipients []string      `json:"recipients"`
+	Enabled    bool          `json:"enabled"`
+	NextRunAt  *time.Time    `json:"next_run_at,omitempty"`
+	LastRunAt  *time.Time    `json:"last_run_at,omitempty"`
+	CreatedBy  int           `json:"created_by"`
+	CreatedAt  time.Time     `json:"created_at"`
+	UpdatedAt  time.Time     `json:"updated_at"`
+}
+
+// ReportRun is one generation of a report. The run keeps the report's name
+// and its file when the schedule is deleted.
+type ReportRun struct {
+	ID          int        `json:"id"`
+	ScheduleID  *int       `json:"schedule_id,omitempty"`
+	ReportName  string     `json:"report_name"`
+	Trigger     string     `json:"trigger"`
+	TriggeredBy *int       `json:"triggered_by,omitempty"`
+	Status      string     `json:"status"`
+	Format      string     `json:"format"`
+	PeriodStart *time.Time `json:"period_start,omitempty"`
+	PeriodEnd   *time.Time `json:"period_end,omitempty"`
+	RowCount    int        `json:"row_count"`
+	FileName    *string    `json:"-"`
+	Error       *string    `json:"error,omitempty"`
+	StartedAt   time.Time  `json:"started_at"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+}
+
+// Receipt is an uploaded receipt file. SHA256 is the hex digest of its
+// content, which finds the same receipt submitted twice.
+type Receipt struct {
+	ID          int       `json:"id"`
+	UserID      int       `json:"user_id"`
+	URL         string    `json:"url"`
+	FileName    string    `json:"-"`
+	ContentType string    `json:"content_type"`
+	SizeBytes   int64     `json:"size_bytes"`
+	SHA256      string    `json:"sha256"`
+	CreatedAt   time.Time `json:"created_at"`
+	// Extraction is nil when the receipt could not be read.
+	Extraction *ReceiptExtraction `json:"extraction,omitempty"`
+}
+
+// ReceiptExtraction holds the fields read off a receipt by OCR; those that
+// could not be read are nil. Date is YYYY-MM-DD and Currency an ISO 4217
+// code.
+type ReceiptExtraction struct {
+	Total    *float64 `json:"total,omitempty"`
+	Currency *string  `json:"currency,omitempty"`
+	Date     *string  `json:"date,omitempty"`
+	Merchant *string  `json:"merchant,omitempty"`
+}
+
+// PolicyRule is an admin-defined check of submitted expenses. Condition
+// is a JSON expression (see package policy); expenses matching it are
+// blocked, warned about or sent for approval, as Action says, with Message
+// shown to the submitter.
+type PolicyRule struct {
+	ID        int             `json:"id"`
+	Name      string          `json:"name"`
+	Message   string          `json:"message"`
+	Action    string          `json:"action"`
+	Condition json.RawMessage `json:"condition"`
+	Enabled   bool            `json:"enabled"`
+	CreatedBy int             `json:"created_by"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// PolicyViolation is a rule an expense matched.
+type PolicyViolation struct {
+	RuleID   int    `json:"rule_id"`
+	RuleName string `json:"rule_name"`
+	Action   string `json:"action"`
+	Message  string `json:"message"`
+}
+
+// JournalAccount is the ledger account journal lines post to for one
+// purpose; see the JournalAccount keys.
+type JournalAccount struct {
+	Key       string    `json:"key"`
+	Code      string    `json:"code"`
+	Name      string    `json:"name"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// JournalBatch is one export of paid expenses to the ledger. Each expense
+// is in at most one batch, and the lines are stored as they were exported
+// so a batch downloads the same way every time.
+type JournalBatch struct {
+	ID                int            `json:"id"`
+	PaidBefore        time.Time      `json:"paid_before"`
+	ExpenseCount      int            `json:"expense_count"`
+	TotalAmountIDR    int            `json:"total_amount_idr"`
+	RefundCount       int            `json:"refund_count"`
+	RefundedAmountIDR int            `json:"refunded_amount_idr"`
+	CreatedBy         int            `json:"created_by"`
+	CreatedAt         time.Time      `json:"created_at"`
+	Lines             []*JournalLine `json:"lines,omitempty"`
+}
+
+// JournalLine debits or credits one account. The lines of an expense, or of
+// a refund of it (RefundID set), form one balanced entry.
+type JournalLine struct {
+	ExpenseID   int       `json:"expense_id"`
+	RefundID    *int      `json:"refund_id,omitempty"`
+	EntryDate   time.Time `json:"entry_date"`
+	AccountCode string    `json:"account_code"`
+	AccountName string    `json:"account_name"`
+	DebitIDR    int       `json:"debit_idr"`
+	CreditIDR   int       `json:"credit_idr"`
+	Description string    `json:"description"`
+}
+
+// JournalRefund is a completed refund waiting to be exported, with the
+// category of its expense the refunded amount is credited back to.
+type JournalRefund struct {
+	ID          int
+	ExpenseID   int
+	AmountIDR   int
+	Reason      string
+	Category    string
+	CompletedAt time.Time
+}
+
+// RefreshToken is a server-side login session. Only a hash of the token is
+// stored. Each refresh rotates it within the same FamilyID, and AccessJTI is
+// the most recent access token issued for the session.
+type RefreshToken struct {
+	ID        int        `json:"id"`
+	UserID    int        `json:"user_id"`
+	TokenHash string     `json:"-"`
+	FamilyID  string     `json:"family_id"`
+	AccessJTI string     `json:"-"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// PasswordResetToken is a single-use token mailed to a user who forgot their
+// password. As with refresh tokens, only a hash is stored.
+type PasswordResetToken struct {
+	ID        int        `json:"id"`
+	UserID    int        `json:"user_id"`
+	TokenHash string     `json:"-"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// TwoFactorSecret is a user's TOTP enrolment. It is pending until EnabledAt is
+// set by verifying a first code. LastUsedStep stops a code from being
+// replayed within its 30 second window.
+type TwoFactorSecret struct {
+	UserID       int        `json:"user_id"`
+	Secret       string     `json:"-"`
+	EnabledAt    *time.Time `json:"enabled_at,omitempty"`
+	LastUsedStep int64      `json:"-"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+type TwoFactorSetup struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type TwoFactorStatus struct {
+	Enabled                bool `json:"enabled"`
+	Required               bool `json:"required"`
+	RecoveryCodesRemaining int  `json:"recovery_codes_remaining"`
+}
+
+// MFAChallenge is handed out instead of tokens when a password login still
+// needs a second factor. EnrollmentRequired means the user's role requires
+// 2FA but they have not set it up yet.
+type MFAChallenge struct {
+	Token              string `json:"challenge_token"`
+	EnrollmentRequired bool   `json:"enrollment_required"`
+	ExpiresIn          int    `json:"expires_in"`
+}
+
+// LoginResult carries either Tokens or, when a second factor is needed, a
+// Challenge to complete with a TOTP or recovery code.
+type LoginResult struct {
+	Tokens    *TokenPair
+	User      *User
+	Challenge *MFAChallenge
+}
+
+// IdentityClaims is what an external identity provider asserts about a user
+// after a successful single sign-on.
+type IdentityClaims struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Groups        []string
+}
+
+// ExternalIdentity links a user to an account at an identity provider. The
+// issuer and subject pair is stable even if the email changes there.
+type ExternalIdentity struct {
+	ID          int        `json:"id"`
+	UserID      int        `json:"user_id"`
+	Issuer      string     `json:"issuer"`
+	Subject     string     `json:"subject"`
+	Email       string     `json:"email"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+}
+
+// SSOAuthRequest is a single sign-on login in progress, between the redirect
+// to the identity provider and the callback. Only the hash of the state is
+// stored.
+type SSOAuthRequest struct {
+	ID           int
+	StateHash    string
+	Nonce        string
+	CodeVerifier string
+	ExpiresAt    time.Time
+	CreatedAt    time.Time
+}
+
+// SigningKey is a key pair access tokens are signed with. A key is
+// published in the JWKS from creation, signs tokens between ActivatesAt and
+// RetiresAt, and verifies them until ExpiresAt. PrivateKey is encrypted.
+type SigningKey struct {
+	KID         string
+	Algorithm   string
+	PrivateKey  []byte
+	ActivatesAt time.Time
+	RetiresAt   time.Time
+	ExpiresAt   time.Time
+	CreatedAt   time.Time
+}
+
+// JSONWebKey is a public key in JWK format (RFC 7517).
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA keys
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519 keys
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
 }
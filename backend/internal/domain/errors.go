@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	// ErrAccountLocked is returned by Login while an account is locked after
+	// too many failed attempts.
+	ErrAccountLocked = errors.New("account is temporarily locked due to too many failed login attempts")
+	// ErrTooManyLoginAttempts is returned by Login when the client IP has too
+	// many recent failed attempts across all accounts.
+	ErrTooManyLoginAttempts = errors.New("too many failed login attempts, try again later")
+
+	ErrInvalidTwoFactorCode = errors.New("invalid two-factor code")
+	// ErrStepUpRequired is returned when an action needs a fresh two-factor
+	// code and none was given.
+	ErrStepUpRequired = errors.New("two-factor verification required for this amount")
+
+	ErrSSONotConfigured = errors.New("single sign-on is not configured")
+
+	// ErrInvalidFilter wraps errors about list filters a client sent.
+	ErrInvalidFilter = errors.New("invalid filter")
+
+	// ErrInvalidReceipt wraps errors about receipt files a client uploaded.
+	ErrInvalidReceipt = errors.New("invalid receipt")
+
+	// ErrNothingToExport is returned when no paid expenses or completed
+	// refunds are left to put in a journal batch.
+	ErrNothingToExport = errors.New("no paid expenses or refunds left to export")
+	// ErrJournalConflict is returned when expenses or refunds of a new
+	// journal batch were exported by another request in the meantime.
+	ErrJournalConflict = errors.New("expenses were exported concurrently, try again")
+
+	// ErrRefundExceedsBalance is returned when a refund is larger than what
+	// is left of the expense after its earlier refunds.
+	ErrRefundExceedsBalance = errors.New("refund exceeds the remaining balance")
+
+	// ErrRoleNotGrantable is returned when a role carries permissions the
+	// caller granting it does not hold.
+	ErrRoleNotGrantable = errors.New("cannot grant a role with permissions you do not have")
+	// ErrScopeNotGrantable is returned when an API key would get scopes the
+	// caller issuing it does not hold.
+	ErrScopeNotGrantable = errors.New("cannot grant scopes you do not have")
+)
+
+// PolicyViolationError is returned by Submit when policy rules block an
+// expense; Violations are the blocking rules.
+type PolicyViolationError struct {
+	Violations []PolicyViolation
+}
+
+func (e *PolicyViolationError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, violation := range e.Violations {
+		messages[i] = violation.Message
+	}
+	return "expense violates policy: " + strings.Join(messages, "; ")
+}
@@ -1,22 +1,135 @@
 package domain
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type UserRepository interface {
 	Create(ctx context.Context, user *User) error
 	GetByEmail(ctx context.Context, email string) (*User, error)
 	GetByID(ctx context.Context, id int) (*User, error)
+	// List returns users filtered by role ("" for all); inactive users are
+	// only included when includeInactive is set.
+	List(ctx context.Context, role string, includeInactive bool, limit, offset int) ([]*User, int, error)
+	Update(ctx context.Context, user *User) error
+	UpdateRole(ctx context.Context, id int, role string) error
+	UpdatePassword(ctx context.Context, id int, passwordHash string) error
+	SetActive(ctx context.Context, id int, active bool) error
+	// RecordLoginFailure counts a failed login and locks the account until
+	// lockUntil once maxAttempts is reached. The count starts over when the
+	// previous failure is older than windowStart or an earlier lock expired.
+	RecordLoginFailure(ctx context.Context, id int, maxAttempts int, windowStart, lockUntil time.Time) (*User, error)
+	ResetLoginFailures(ctx context.Context, id int) error
+}
+
+type RoleRepository interface {
+	List(ctx context.Context) ([]*Role, error)
+	GetByName(ctx context.Context, name string) (*Role, error)
+	Create(ctx context.Context, role *Role) error
+	// Update replaces the description and permissions of a role.
+	Update(ctx context.Context, role *Role) error
+	Delete(ctx context.Context, name string) error
+	CountUsers(ctx context.Context, name string) (int, error)
+}
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *APIKey) error
+	GetByID(ctx context.Context, id int) (*APIKey, error)
+	GetByPrefix(ctx context.Context, prefix string) (*APIKey, error)
+	ListByServiceAccount(ctx context.Context, serviceAccountID int) ([]*APIKey, error)
+	// Rotate stores newKey and makes the old key expire at oldExpiresAt,
+	// unless it already expires earlier.
+	Rotate(ctx context.Context, oldID int, newKey *APIKey, oldExpiresAt time.Time) error
+	// Revoke reports false if the key was already revoked.
+	Revoke(ctx context.Context, id int) (bool, error)
+	// TouchLastUsed records a use of the key. Uses within a minute of the
+	// last recorded one are skipped to save writes.
+	TouchLastUsed(ctx context.Context, id int, ipAddress string) error
+}
+
+type SigningKeyRepository interface {
+	// ListUnexpired returns keys that have not expired at now.
+	ListUnexpired(ctx context.Context, now time.Time) ([]*SigningKey, error)
+	Create(ctx context.Context, key *SigningKey) error
+	DeleteExpired(ctx context.Context, now time.Time) (int, error)
+}
+
+type TwoFactorRepository interface {
+	Get(ctx context.Context, userID int) (*TwoFactorSecret, error)
+	// SavePending stores a new secret that is not enabled yet, replacing any
+	// earlier pending one.
+	SavePending(ctx context.Context, userID int, secret string) error
+	Enable(ctx context.Context, userID int) error
+	Delete(ctx context.Context, userID int) error
+	// UseStep records the time step of an accepted code. It reports false if
+	// that step or a later one was already used.
+	UseStep(ctx context.Context, userID int, step int64) (bool, error)
+	ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error
+	// UseRecoveryCode reports false if the code does not exist or was used.
+	UseRecoveryCode(ctx context.Context, userID int, codeHash string) (bool, error)
+	CountRecoveryCodes(ctx context.Context, userID int) (int, error)
+}
+
+type IdentityRepository interface {
+	GetByIssuerSubject(ctx context.Context, issuer, subject string) (*ExternalIdentity, error)
+	Create(ctx context.Context, identity *ExternalIdentity) error
+	TouchLogin(ctx context.Context, id int) error
+
+	CreateAuthRequest(ctx context.Context, request *SSOAuthRequest) error
+	// ConsumeAuthRequest deletes and returns the request, so each state can
+	// be redeemed once.
+	ConsumeAuthRequest(ctx context.Context, stateHash string) (*SSOAuthRequest, error)
+}
+
+type LoginAttemptRepository interface {
+	Create(ctx context.Context, attempt *LoginAttempt) error
+	CountFailuresByIP(ctx context.Context, ipAddress string, since time.Time) (int, error)
+	ListByUser(ctx context.Context, userID int, limit int) ([]*LoginAttempt, error)
+}
+
+type TokenRepository interface {
+	CreateRefreshToken(ctx context.Context, token *RefreshToken) error
+	GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*RefreshToken, error)
+	// RevokeRefreshToken reports false if the token was already revoked, so
+	// only one of two concurrent refreshes can rotate it.
+	RevokeRefreshToken(ctx context.Context, id int) (bool, error)
+	// RevokeRefreshFamily and RevokeUserRefreshTokens return the access token
+	// JTIs of the sessions they ended.
+	RevokeRefreshFamily(ctx context.Context, familyID string) ([]string, error)
+	RevokeUserRefreshTokens(ctx context.Context, userID int) ([]string, error)
+	RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error
+	IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error)
+
+	CreatePasswordReset(ctx context.Context, token *PasswordResetToken) error
+	GetPasswordResetByHash(ctx context.Context, tokenHash string) (*PasswordResetToken, error)
+	// UsePasswordReset reports false if the token was already used, so a
+	// reset token can be redeemed only once.
+	UsePasswordReset(ctx context.Context, id int) (bool, error)
+	// InvalidatePasswordResets marks every unused reset token of the user as used.
+	InvalidatePasswordResets(ctx context.Context, userID int) error
 }
 
 type ExpenseRepository interface {
 	Create(ctx context.Context, expense *Expense) error
 	GetByID(ctx context.Context, id int) (*Expense, error)
-	GetByUserID(ctx context.Context, userID int, status string, limit, offset int) ([]*Expense, int, error)
-	GetAll(ctx context.Context, status string, limit, offset int) ([]*Expense, int, error)
+	// Search returns a page of the expenses matching filter. A cursor only
+	// continues a search with the same filter.
+	Search(ctx context.Context, filter ExpenseFilter, page PageRequest) (*ExpensePage, error)
+	// Export calls fn for each expense matching filter, in its order, as
+	// the rows are read, and stops at the first error fn returns.
+	Export(ctx context.Context, filter ExpenseFilter, fn func(*ExpenseExportRow) error) error
 	GetPendingApprovals(ctx context.Context, limit, offset int) ([]*Expense, int, error)
 	Update(ctx context.Context, expense *Expense) error
 	UpdateStatus(ctx context.Context, id int, status string, processedAt *string) error
 	UpdatePaymentInfo(ctx context.Context, id int, paymentID, externalID string) error
+	AssignPaymentRun(ctx context.Context, id int, paymentRunID int) error
+	GetByPaymentRunID(ctx context.Context, paymentRunID int) ([]*Expense, error)
+	// FindDuplicateCandidates returns the user's expenses of the same
+	// amount submitted since the given time, and every expense whose
+	// receipt has the given content hash, oldest first. Rejected expenses
+	// are left out.
+	FindDuplicateCandidates(ctx context.Context, userID, amountIDR int, since time.Time, receiptSHA256 *string) ([]*DuplicateCandidate, error)
 }
 
 type ApprovalRepository interface {
@@ -27,4 +140,133 @@ type ApprovalRepository interface {
 type AuditLogRepository interface {
 	Create(ctx context.Context, log *AuditLog) error
 	GetByExpenseID(ctx context.Context, expenseID int) ([]*AuditLog, error)
+	// GetBySubjectUserID returns the logs about a user, oldest first; all of
+	// them when page.Limit is 0.
+	GetBySubjectUserID(ctx context.Context, userID int, page PageRequest) (*AuditLogPage, error)
+}
+
+type PaymentRunRepository interface {
+	GetOrCreateOpen(ctx context.Context, cutoffAt time.Time) (*PaymentRun, error)
+	GetByID(ctx context.Context, id int) (*PaymentRun, error)
+	List(ctx context.Context, status string, limit, offset int) ([]*PaymentRun, int, error)
+	CloseDue(ctx context.Context, now time.Time) ([]*PaymentRun, error)
+	Release(ctx context.Context, id int, releasedBy int) ([]*Payout, error)
+	GetPayouts(ctx context.Context, paymentRunID int) ([]*Payout, error)
+	GetPayoutByID(ctx context.Context, id int) (*Payout, error)
+	UpdatePayoutStatus(ctx context.Context, id int, status string, paymentID *string) error
+	RefreshStatus(ctx context.Context, id int) error
+	// ClaimStalePayouts returns the pending payouts of released runs that
+	// were last touched before staleBefore, touching them so that another
+	// sweep does not claim them again right away.
+	ClaimStalePayouts(ctx context.Context, staleBefore time.Time) ([]*Payout, error)
+	// RetryPayout puts a failed payout of the run back to pending, and the
+	// run back to released.
+	RetryPayout(ctx context.Context, runID, payoutID int) (*Payout, error)
+}
+
+type RefundRepository interface {
+	// Create stores the refund if the expense has enough left after its
+	// refunds that have not failed, and returns what was left before it.
+	// Otherwise it returns what is left and ErrRefundExceedsBalance.
+	Create(ctx context.Context, refund *Refund) (int, error)
+	UpdateStatus(ctx context.Context, id int, status string, providerRefundID *string) error
+	// Complete marks the refund completed and sets its expense to refunded
+	// or partially_refunded from the total of its completed refunds. It
+	// returns the new expense status.
+	Complete(ctx context.Context, id int, providerRefundID *string) (string, error)
+	GetByExpenseID(ctx context.Context, expenseID int) ([]*Refund, error)
+}
+
+type CashAdvanceRepository interface {
+	Create(ctx context.Context, advance *CashAdvance) error
+	GetByID(ctx context.Context, id int) (*CashAdvance, error)
+	List(ctx context.Context, userID int, status string, limit, offset int) ([]*CashAdvance, int, error)
+	UpdateApproval(ctx context.Context, id int, status string, approverID int, notes *string) error
+	MarkPaid(ctx context.Context, id int, paymentID string) error
+	ApplySettlement(ctx context.Context, id int, expenseID int, amountIDR int) (int, error)
+	Close(ctx context.Context, id int) (int, error)
+}
+
+type BudgetRepository interface {
+	Create(ctx context.Context, budget *Budget) error
+	GetByID(ctx context.Context, id int) (*Budget, error)
+	List(ctx context.Context) ([]*Budget, error)
+	Delete(ctx context.Context, id int) error
+	// GetApplicable returns the budgets covering an employee. A nil category
+	// returns budgets for every category.
+	GetApplicable(ctx context.Context, userID int, teamID *int, category *string) ([]*Budget, error)
+	GetConsumption(ctx context.Context, budget *Budget, from, to time.Time) (int, error)
+}
+
+type JournalRepository interface {
+	ListAccounts(ctx context.Context) ([]*JournalAccount, error)
+	UpdateAccount(ctx context.Context, account *JournalAccount) error
+	// GetUnexported returns the paid expenses not in any batch yet that
+	// were paid before paidBefore, oldest first.
+	GetUnexported(ctx context.Context, paidBefore time.Time) ([]*Expense, error)
+	// GetUnexportedRefunds returns the completed refunds not in any batch
+	// yet that completed before completedBefore, oldest first.
+	GetUnexportedRefunds(ctx context.Context, completedBefore time.Time) ([]*JournalRefund, error)
+	// CreateBatch stores batch with its lines and marks expenseIDs and
+	// refundIDs as exported in it. It fails with ErrJournalConflict,
+	// storing nothing, if any of them is already in a batch.
+	CreateBatch(ctx context.Context, batch *JournalBatch, expenseIDs, refundIDs []int) error
+	List(ctx context.Context, limit, offset int) ([]*JournalBatch, int, error)
+	// GetByID returns the batch with its lines.
+	GetByID(ctx context.Context, id int) (*JournalBatch, error)
+}
+
+type AnalyticsRepository interface {
+	Summary(ctx context.Context, filter ExpenseFilter) (*AnalyticsSummary, error)
+	// Breakdown groups the expenses matching filter by dimension: months in
+	// order, other groups by total amount, largest first. Limit 0 returns
+	// every group.
+	Breakdown(ctx context.Context, filter ExpenseFilter, dimension string, limit int) ([]*AnalyticsGroup, error)
+}
+
+type ReportRepository interface {
+	CreateSchedule(ctx context.Context, schedule *ReportSchedule) error
+	UpdateSchedule(ctx context.Context, schedule *ReportSchedule) error
+	DeleteSchedule(ctx context.Context, id int) error
+	GetSchedule(ctx context.Context, id int) (*ReportSchedule, error)
+	ListSchedules(ctx context.Context) ([]*ReportSchedule, error)
+	// GetDue returns the enabled schedules whose next run is not after now.
+	GetDue(ctx context.Context, now time.Time) ([]*ReportSchedule, error)
+	// ClaimRun moves the next run of a schedule from due to next (nil if it
+	// never runs again). It reports false if another instance claimed the
+	// run first.
+	ClaimRun(ctx context.Context, id int, due time.Time, next *time.Time) (bool, error)
+	CreateRun(ctx context.Context, run *ReportRun) error
+	FinishRun(ctx context.Context, run *ReportRun) error
+	// ListRuns returns runs newest first, of one schedule unless scheduleID
+	// is nil.
+	ListRuns(ctx context.Context, scheduleID *int, limit, offset int) ([]*ReportRun, int, error)
+	GetRun(ctx context.Context, id int) (*ReportRun, error)
+}
+
+type ReceiptRepository interface {
+	Create(ctx context.Context, receipt *Receipt) error
+	GetByID(ctx context.Context, id int) (*Receipt, error)
+}
+
+type PolicyRuleRepository interface {
+	Create(ctx context.Context, rule *PolicyRule) error
+	Update(ctx context.Context, rule *PolicyRule) error
+	Delete(ctx context.Context, id int) error
+	GetByID(ctx context.Context, id int) (*PolicyRule, error)
+	// List returns rules in the order they were created; only enabled ones
+	// with enabledOnly.
+	List(ctx context.Context, enabledOnly bool) ([]*PolicyRule, error)
+}
+
+type SettingRepository interface {
+	// List returns the settings admins have set; the others are not stored.
+	List(ctx context.Context) ([]*Setting, error)
+	// Set stores a value and records the change in one transaction.
+	Set(ctx context.Context, key string, value int, changedBy int) error
+	// Reset removes a stored value, recording the change if there was one.
+	Reset(ctx context.Context, key string, changedBy int) error
+	// History returns changes newest first, of one setting unless key is
+	// empty.
+	History(ctx context.Context, key string, limit int) ([]*SettingChange, error)
 }
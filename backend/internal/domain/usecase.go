@@ -1,21 +1,273 @@
 package domain
 
-import "context"
+import (
+	"context"
+	"io"
+	"time"
+)
 
 type AuthUsecase interface {
-	Login(ctx context.Context, email, password string) (string, *User, error)
+	Login(ctx context.Context, email, password, clientIP string) (*LoginResult, error)
+	// ChallengeSetup starts TOTP enrolment for a user whose login challenge
+	// requires it.
+	ChallengeSetup(ctx context.Context, challengeToken string) (*TwoFactorSetup, error)
+	// CompleteLogin finishes a challenged login with a TOTP or recovery code.
+	// Recovery codes are returned when the code also completed enrolment.
+	CompleteLogin(ctx context.Context, challengeToken, code string) (*TokenPair, *User, []string, error)
+	Refresh(ctx context.Context, refreshToken string) (*TokenPair, *User, error)
+	Logout(ctx context.Context, accessToken, refreshToken string) error
+	LogoutAll(ctx context.Context, userID int, accessToken string) error
 	ValidateToken(ctx context.Context, token string) (*User, error)
+	// ValidateTokenClaims may skip the user lookup and take the user from
+	// the token's claims; for read-only requests.
+	ValidateTokenClaims(ctx context.Context, token string) (*User, error)
+	// StartSession logs in a user who was authenticated elsewhere, e.g. by
+	// single sign-on. Two-factor rules apply as for Login.
+	StartSession(ctx context.Context, user *User) (*LoginResult, error)
+	// ChangePassword ends every session of the user and returns a fresh
+	// token pair for the caller.
+	ChangePassword(ctx context.Context, userID int, currentPassword, newPassword string) (*TokenPair, error)
+	RequestPasswordReset(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, newPassword string) error
+}
+
+type TwoFactorUsecase interface {
+	Status(ctx context.Context, user *User) (*TwoFactorStatus, error)
+	IsEnabled(ctx context.Context, userID int) (bool, error)
+	IsRequired(user *User) bool
+	Setup(ctx context.Context, user *User) (*TwoFactorSetup, error)
+	// Enable confirms a pending setup with a first code and returns the
+	// recovery codes, which are shown only this once.
+	Enable(ctx context.Context, user *User, code string) ([]string, error)
+	Disable(ctx context.Context, user *User, code string) error
+	RegenerateRecoveryCodes(ctx context.Context, user *User, code string) ([]string, error)
+	// Verify accepts a TOTP code or, if allowRecovery is set, a recovery code.
+	Verify(ctx context.Context, userID int, code string, allowRecovery bool) error
+	// VerifyStepUp demands a fresh TOTP code when approving amountIDR is above
+	// the step-up threshold.
+	VerifyStepUp(ctx context.Context, userID int, amountIDR int, code string) error
+}
+
+type SSOUsecase interface {
+	// Begin returns the identity provider URL to send the browser to.
+	Begin(ctx context.Context) (string, error)
+	// Complete handles the provider's callback and logs the user in,
+	// provisioning or linking the account as needed.
+	Complete(ctx context.Context, code, state string) (*LoginResult, error)
+}
+
+// TokenSigner signs the access tokens we issue and verifies them.
+type TokenSigner interface {
+	Sign(ctx context.Context, claims map[string]interface{}) (string, error)
+	// Verify checks the signature and expiry and returns the claims.
+	Verify(ctx context.Context, token string) (map[string]interface{}, error)
+	// PublicKeys returns the keys other services can verify our tokens
+	// with. It is empty when tokens are signed with a shared secret.
+	PublicKeys(ctx context.Context) (*JSONWebKeySet, error)
+}
+
+// IdentityProvider is an OpenID Connect provider using the authorization
+// code flow with PKCE.
+type IdentityProvider interface {
+	AuthCodeURL(ctx context.Context, state, nonce, codeVerifier string) (string, error)
+	// Exchange redeems an authorization code and returns the verified claims
+	// of its ID token.
+	Exchange(ctx context.Context, code, codeVerifier, nonce string) (*IdentityClaims, error)
+}
+
+// Notifier delivers messages to users, e.g. by email.
+type Notifier interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// FileStore keeps files, such as the output of report runs and uploaded
+// receipts, by name.
+type FileStore interface {
+	Create(name string) (io.WriteCloser, error)
+	Open(name string) (io.ReadCloser, error)
+	Remove(name string) error
+}
+
+// ReceiptExtractor reads the total, date, merchant and currency off an
+// uploaded receipt. It returns nil when it could read none of them.
+type ReceiptExtractor interface {
+	Extract(ctx context.Context, contentType string, file io.Reader) (*ReceiptExtraction, error)
+}
+
+// UserAdminUsecase takes the permissions of the caller (the role's, or the
+// scopes of an API key) where users are given a role, so nobody can grant
+// more than they hold.
+type UserAdminUsecase interface {
+	Create(ctx context.Context, adminID int, granted []string, user *User, password string) (*User, error)
+	List(ctx context.Context, role string, includeInactive bool, page, limit int) ([]*User, int, error)
+	GetByID(ctx context.Context, id int) (*User, error)
+	Update(ctx context.Context, adminID, id int, update *UserUpdate) (*User, error)
+	ChangeRole(ctx context.Context, adminID int, granted []string, id int, role string) (*User, error)
+	Deactivate(ctx context.Context, adminID, id int) (*User, error)
+	Reactivate(ctx context.Context, adminID, id int) (*User, error)
+	// GetAuditLogs returns every log about the user when page.Limit is 0.
+	GetAuditLogs(ctx context.Context, id int, page PageRequest) (*AuditLogPage, error)
+	GetLockoutStatus(ctx context.Context, id int) (*LockoutStatus, error)
+	Unlock(ctx context.Context, adminID, id int) (*LockoutStatus, error)
+}
+
+type RoleUsecase interface {
+	List(ctx context.Context) ([]*Role, error)
+	GetByName(ctx context.Context, name string) (*Role, error)
+	Create(ctx context.Context, adminID int, role *Role) (*Role, error)
+	// Update changes the description and/or permissions; nil leaves them as
+	// they are.
+	Update(ctx context.Context, adminID int, name string, description *string, permissions []string) (*Role, error)
+	Delete(ctx context.Context, adminID int, name string) error
+	// Permissions returns the permissions a role grants. Unknown roles grant
+	// none.
+	Permissions(ctx context.Context, role string) (map[string]bool, error)
+}
+
+type APIKeyUsecase interface {
+	CreateServiceAccount(ctx context.Context, adminID int, name string) (*User, error)
+	ListServiceAccounts(ctx context.Context) ([]*User, error)
+	// CreateKey returns the new key and its plaintext, which is not stored
+	// and cannot be retrieved later. A nil expiresAt uses the default
+	// lifetime. The scopes must be among the caller's granted permissions.
+	CreateKey(ctx context.Context, adminID int, granted []string, serviceAccountID int, name string, scopes []string, expiresAt *time.Time) (*APIKey, string, error)
+	ListKeys(ctx context.Context, serviceAccountID int) ([]*APIKey, error)
+	// RotateKey issues a replacement with the same name and scopes. The old
+	// key keeps working for a grace period so integrations can switch over.
+	// Like CreateKey, the caller must hold every scope of the key.
+	RotateKey(ctx context.Context, adminID int, granted []string, serviceAccountID, keyID int) (*APIKey, string, error)
+	RevokeKey(ctx context.Context, adminID, serviceAccountID, keyID int) error
+	// Authenticate checks a plaintext key and returns the service account
+	// it belongs to.
+	Authenticate(ctx context.Context, rawKey, ipAddress string) (*User, *APIKey, error)
 }
 
 type ExpenseUsecase interface {
-	Submit(ctx context.Context, userID int, amountIDR int, description string, receiptURL *string) (*Expense, error)
-	GetByID(ctx context.Context, userID int, expenseID int, isManager bool) (*Expense, error)
-	GetUserExpenses(ctx context.Context, userID int, status string, page, limit int, isManager bool) ([]*Expense, int, error)
+	// Submit records an expense. A receiptID refers to an uploaded receipt
+	// of the user and replaces receiptURL.
+	Submit(ctx context.Context, userID int, amountIDR int, description string, category string, receiptURL *string, receiptID *int, cashAdvanceID *int) (*Expense, error)
+	GetByID(ctx context.Context, userID int, expenseID int, canViewAll bool) (*Expense, error)
+	// GetUserExpenses lists expenses matching filter; without canViewAll
+	// only the user's own.
+	GetUserExpenses(ctx context.Context, userID int, filter ExpenseFilter, page PageRequest, canViewAll bool) (*ExpensePage, error)
+	// ExportExpenses streams the expenses GetUserExpenses would list to fn.
+	ExportExpenses(ctx context.Context, userID int, filter ExpenseFilter, canViewAll bool, fn func(*ExpenseExportRow) error) error
 	GetPendingApprovals(ctx context.Context, page, limit int) ([]*Expense, int, error)
-	Approve(ctx context.Context, managerID, expenseID int, notes *string) error
+	// Approve needs totpCode when the amount is above the step-up threshold.
+	Approve(ctx context.Context, managerID, expenseID int, notes *string, totpCode string) error
 	Reject(ctx context.Context, managerID, expenseID int, notes *string) error
 }
 
 type PaymentService interface {
 	ProcessPayment(ctx context.Context, expenseID int, amount int, externalID string) (string, error)
+	ProcessRefund(ctx context.Context, paymentID string, amount int, externalID string) (string, error)
+}
+
+type PaymentRunUsecase interface {
+	AddExpense(ctx context.Context, expense *Expense) error
+	CloseDueRuns(ctx context.Context, now time.Time) error
+	List(ctx context.Context, status string, page, limit int) ([]*PaymentRun, int, error)
+	GetByID(ctx context.Context, id int) (*PaymentRun, error)
+	Release(ctx context.Context, userID, runID int) (*PaymentRun, error)
+	// RequeueStalePayouts queues the pending payouts of released runs again
+	// that no worker finished, e.g. because the queue was full or the
+	// server stopped.
+	RequeueStalePayouts(ctx context.Context, now time.Time) error
+	RetryPayout(ctx context.Context, userID, runID, payoutID int) (*PaymentRun, error)
+}
+
+type RefundUsecase interface {
+	Initiate(ctx context.Context, userID, expenseID int, refundType string, amountIDR int, reason string) (*Refund, error)
+	GetByExpenseID(ctx context.Context, userID, expenseID int, canViewAll bool) ([]*Refund, error)
+}
+
+type CashAdvanceUsecase interface {
+	Request(ctx context.Context, userID int, amountIDR int, purpose string) (*CashAdvance, error)
+	GetByID(ctx context.Context, userID int, advanceID int, canViewAll bool) (*CashAdvance, error)
+	List(ctx context.Context, userID int, status string, page, limit int, canViewAll bool) ([]*CashAdvance, int, error)
+	Approve(ctx context.Context, managerID, advanceID int, notes *string) error
+	Reject(ctx context.Context, managerID, advanceID int, notes *string) error
+	GetBalance(ctx context.Context, userID int) (*AdvanceBalance, error)
+	Close(ctx context.Context, userID, advanceID int) (*CashAdvance, error)
+}
+
+type BudgetUsecase interface {
+	Create(ctx context.Context, userID int, budget *Budget) (*Budget, error)
+	List(ctx context.Context) ([]*Budget, error)
+	Delete(ctx context.Context, id int) error
+	GetStatus(ctx context.Context, userID int) ([]*BudgetStatus, error)
+	// Evaluate reports every budget covering the employee and category as it
+	// would stand if amountIDR were added to it.
+	Evaluate(ctx context.Context, userID int, category string, amountIDR int) ([]*BudgetStatus, error)
+}
+
+type JournalUsecase interface {
+	ListAccounts(ctx context.Context) ([]*JournalAccount, error)
+	UpdateAccount(ctx context.Context, userID int, key, code, name string) (*JournalAccount, error)
+	// CreateBatch exports every paid expense not exported yet that was paid
+	// before paidBefore.
+	CreateBatch(ctx context.Context, userID int, paidBefore time.Time) (*JournalBatch, error)
+	List(ctx context.Context, page, limit int) ([]*JournalBatch, int, error)
+	GetByID(ctx context.Context, id int) (*JournalBatch, error)
+}
+
+// AnalyticsUsecase aggregates the expenses a user may list: without
+// canViewAll only their own.
+type AnalyticsUsecase interface {
+	Summary(ctx context.Context, userID int, filter ExpenseFilter, canViewAll bool) (*AnalyticsSummary, error)
+	Breakdown(ctx context.Context, userID int, filter ExpenseFilter, dimension string, limit int, canViewAll bool) ([]*AnalyticsGroup, error)
+	TopSpenders(ctx context.Context, userID int, filter ExpenseFilter, limit int, canViewAll bool) ([]*AnalyticsGroup, error)
+}
+
+// ReportUsecase manages scheduled reports. Reports cover every employee's
+// expenses; runs are stored as files and their download links sent to the
+// recipients.
+type ReportUsecase interface {
+	CreateSchedule(ctx context.Context, userID int, schedule *ReportSchedule) (*ReportSchedule, error)
+	UpdateSchedule(ctx context.Context, userID, id int, schedule *ReportSchedule) (*ReportSchedule, error)
+	DeleteSchedule(ctx context.Context, userID, id int) error
+	GetSchedule(ctx context.Context, id int) (*ReportSchedule, error)
+	ListSchedules(ctx context.Context) ([]*ReportSchedule, error)
+	// RunNow runs a report right away, outside its schedule.
+	RunNow(ctx context.Context, userID, id int) (*ReportRun, error)
+	// RunDue runs every enabled report whose next run is not after now.
+	RunDue(ctx context.Context, now time.Time) error
+	ListRuns(ctx context.Context, scheduleID *int, page, limit int) ([]*ReportRun, int, error)
+	// OpenRun returns a successful run with its file, which the caller
+	// must close.
+	OpenRun(ctx context.Context, id int) (*ReportRun, io.ReadCloser, error)
+}
+
+// ReceiptUsecase stores uploaded receipt files.
+type ReceiptUsecase interface {
+	// Upload stores a receipt of the user; it must be a JPEG, PNG, GIF,
+	// WebP or PDF file of at most 5 MB.
+	Upload(ctx context.Context, userID int, file io.Reader) (*Receipt, error)
+	// Get returns a receipt; without canViewAll only the user's own.
+	Get(ctx context.Context, userID, id int, canViewAll bool) (*Receipt, error)
+	// Open is Get with the receipt's file, which the caller must close.
+	Open(ctx context.Context, userID, id int, canViewAll bool) (*Receipt, io.ReadCloser, error)
+}
+
+// PolicyUsecase manages policy rules and checks submitted expenses against
+// them.
+type PolicyUsecase interface {
+	Create(ctx context.Context, adminID int, rule *PolicyRule) (*PolicyRule, error)
+	Update(ctx context.Context, adminID, id int, rule *PolicyRule) (*PolicyRule, error)
+	Delete(ctx context.Context, adminID, id int) error
+	GetByID(ctx context.Context, id int) (*PolicyRule, error)
+	List(ctx context.Context) ([]*PolicyRule, error)
+	// Evaluate returns the enabled rules an expense the user is submitting
+	// matches.
+	Evaluate(ctx context.Context, userID int, expense *Expense) ([]PolicyViolation, error)
+}
+
+type SettingsUsecase interface {
+	// ExpenseLimits is read on every submission, so it is cached briefly.
+	ExpenseLimits(ctx context.Context) (ExpenseLimits, error)
+	List(ctx context.Context) ([]*Setting, error)
+	Update(ctx context.Context, adminID int, key string, value int) (*Setting, error)
+	// Reset goes back to the value from the environment.
+	Reset(ctx context.Context, adminID int, key string) (*Setting, error)
+	History(ctx context.Context, key string, limit int) ([]*SettingChange, error)
 }
@@ -0,0 +1,47 @@
+package export
+
+import (
+	"expense-management-system/internal/domain"
+	"time"
+)
+
+// ExpenseColumns are the columns of an expense export.
+var ExpenseColumns = []string{
+	"ID", "Submitted At", "Submitter", "Submitter Email", "Description", "Category",
+	"Amount (IDR)", "Status", "Auto Approved", "Approver", "Approver Email", "Approval Notes",
+	"Processed At", "Payment ID", "Payment External ID", "Payment Run ID",
+	"Cash Advance ID", "Advance Settled (IDR)",
+}
+
+// ExpenseCells is row as the cells of ExpenseColumns.
+func ExpenseCells(row *domain.ExpenseExportRow) []interface{} {
+	return []interface{}{
+		row.ID, row.SubmittedAt, row.SubmitterName, row.SubmitterEmail, row.Description, row.Category,
+		row.AmountIDR, row.Status, row.AutoApproved, Value(row.ApproverName), Value(row.ApproverEmail), Value(row.ApprovalNotes),
+		Value(row.ProcessedAt), Value(row.PaymentID), Value(row.PaymentExternalID), Value(row.PaymentRunID),
+		Value(row.CashAdvanceID), row.AdvanceSettledIDR,
+	}
+}
+
+// Value turns a nil pointer into an empty cell.
+func Value(v interface{}) interface{} {
+	switch p := v.(type) {
+	case *string:
+		if p != nil {
+			return *p
+		}
+	case *int:
+		if p != nil {
+			return *p
+		}
+	case *float64:
+		if p != nil {
+			return *p
+		}
+	case *time.Time:
+		if p != nil {
+			return *p
+		}
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+// Package export writes tables as CSV or Excel files one row at a time, so
+// a table of any length can be sent while it is read from the database,
+// or as PDF documents for reading and printing, which are laid out once the
+// whole table is in.
+package export
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+	FormatPDF  = "pdf"
+)
+
+// TimeLayout is how times appear in CSV files; Excel files hold them as
+// dates.
+const TimeLayout = "2006-01-02 15:04:05"
+
+// Writer writes a table: the header first, then the rows. Cells may be
+// strings, ints, float64s, bools, times or nil for an empty cell. Close must be
+// called to finish the file.
+type Writer interface {
+	WriteHeader(columns []string) error
+	WriteRow(cells []interface{}) error
+	Close() error
+}
+
+// Format is a file format a table can be exported as.
+type Format struct {
+	Name        string
+	ContentType string
+	newWriter   func(w io.Writer) Writer
+}
+
+var (
+	csvFormat = &Format{
+		Name:        FormatCSV,
+		ContentType: "text/csv; charset=utf-8",
+		newWriter:   newCSVWriter,
+	}
+	xlsxFormat = &Format{
+		Name:        FormatXLSX,
+		ContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		newWriter:   newXLSXWriter,
+	}
+	pdfFormat = &Format{
+		Name:        FormatPDF,
+		ContentType: "application/pdf",
+		newWriter:   newPDFWriter,
+	}
+)
+
+// formats are written row by row. A PDF is laid out only once every row is
+// in, so it is kept to documentFormats, for tables that are in memory
+// anyway.
+var (
+	formats = map[string]*Format{
+		FormatCSV:  csvFormat,
+		FormatXLSX: xlsxFormat,
+	}
+	documentFormats = map[string]*Format{
+		FormatCSV:  csvFormat,
+		FormatXLSX: xlsxFormat,
+		FormatPDF:  pdfFormat,
+	}
+)
+
+// LookupFormat returns the format called name, which must be one that is
+// written while the rows are read.
+func LookupFormat(name string) (*Format, error) {
+	return lookup(formats, name)
+}
+
+// LookupDocumentFormat is LookupFormat for tables of bounded size, such as
+// reports and journal batches, which may also be PDF documents.
+func LookupDocumentFormat(name string) (*Format, error) {
+	return lookup(documentFormats, name)
+}
+
+// FormatNames lists the names of the formats LookupFormat knows.
+func FormatNames() []string {
+	return formatNames(formats)
+}
+
+func lookup(known map[string]*Format, name string) (*Format, error) {
+	format, ok := known[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown export format %q, use %s", name, strings.Join(formatNames(known), ", "))
+	}
+	return format, nil
+}
+
+func formatNames(known map[string]*Format) []string {
+	names := make([]string, 0, len(known))
+	for name := range known {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewWriter starts a file of this format on w.
+func (f *Format) NewWriter(w io.Writer) Writer {
+	return f.newWriter(w)
+}
+
+// Filename names a file of this format after base and the day of t.
+func (f *Format) Filename(base string, t time.Time) string {
+	return base + "-" + t.Format("20060102") + "." + f.Name
+}
+
+// cellText is how a cell reads as text.
+func cellText(cell interface{}) string {
+	switch v := cell.(type) {
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.Format(TimeLayout)
+	default:
+		return ""
+	}
+}
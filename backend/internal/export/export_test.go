@@ -0,0 +1,177 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testRows = [][]interface{}{
+	{1, "=HYPERLINK(\"http://evil\")", 500000, true, time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC), nil},
+	{2, "Taxi <airport> & \"hotel\"\x00", 75000, false, nil, "ok"},
+}
+
+func writeTable(t *testing.T, format string) []byte {
+	t.Helper()
+	f, err := LookupDocumentFormat(format)
+	if err != nil {
+		t.Fatalf("LookupDocumentFormat(%q) unexpected error = %v", format, err)
+	}
+
+	var buf bytes.Buffer
+	w := f.NewWriter(&buf)
+	if err := w.WriteHeader([]string{"ID", "Description", "Amount", "Auto", "Processed", "Notes"}); err != nil {
+		t.Fatalf("WriteHeader() unexpected error = %v", err)
+	}
+	for _, row := range testRows {
+		if err := w.WriteRow(row); err != nil {
+			t.Fatalf("WriteRow() unexpected error = %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() unexpected error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestCSVWriter(t *testing.T) {
+	got := string(writeTable(t, FormatCSV))
+	want := "ID,Description,Amount,Auto,Processed,Notes\n" +
+		"1,\"'=HYPERLINK(\"\"http://evil\"\")\",500000,true,2024-01-31 12:00:00,\n" +
+		"2,\"Taxi <airport> & \"\"hotel\"\"\x00\",75000,false,,ok\n"
+	if got != want {
+		t.Errorf("CSV =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestXLSXWriter(t *testing.T) {
+	data := writeTable(t, FormatXLSX)
+
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("Expected a zip archive, got error = %v", err)
+	}
+
+	var sheet string
+	for _, file := range archive.File {
+		rc, err := file.Open()
+		if err != nil {
+			t.Fatalf("Open(%s) unexpected error = %v", file.Name, err)
+		}
+		content, _ := io.ReadAll(rc)
+		rc.Close()
+
+		decoder := xml.NewDecoder(bytes.NewReader(content))
+		for {
+			if _, err := decoder.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%s is not well-formed XML: %v", file.Name, err)
+			}
+		}
+		if file.Name == "xl/worksheets/sheet1.xml" {
+			sheet = string(content)
+		}
+	}
+	if len(archive.File) != len(xlsxParts)+1 {
+		t.Errorf("Expected %d parts, got %d", len(xlsxParts)+1, len(archive.File))
+	}
+
+	for _, want := range []string{
+		`<c r="A1" s="2" t="inlineStr"><is><t xml:space="preserve">ID</t></is></c>`,
+		`<c r="C2"><v>500000</v></c>`,
+		`<c r="D2" t="b"><v>1</v></c>`,
+		`<c r="E2" s="1"><v>45322.5</v></c>`,
+		`Taxi &lt;airport&gt; &amp; &#34;hotel&#34;`,
+		`<c r="F3" t="inlineStr">`,
+	} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("Expected the sheet to contain %s", want)
+		}
+	}
+	if strings.Contains(sheet, `r="F2"`) {
+		t.Error("Expected no cell for nil")
+	}
+}
+
+func TestXLSXColumn(t *testing.T) {
+	for i, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+		if got := xlsxColumn(i); got != want {
+			t.Errorf("xlsxColumn(%d) = %s, want %s", i, got, want)
+		}
+	}
+}
+
+func TestLookupFormat(t *testing.T) {
+	if _, err := LookupFormat("ods"); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+	if _, err := LookupFormat(FormatPDF); err == nil {
+		t.Error("Expected PDF not to be offered for streamed exports")
+	}
+	if f, err := LookupDocumentFormat(FormatPDF); err != nil || f.ContentType != "application/pdf" {
+		t.Errorf("LookupDocumentFormat(pdf) = %v, %v", f, err)
+	}
+	f, _ := LookupFormat(FormatXLSX)
+	if got := f.Filename("expenses", time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)); got != "expenses-20240305.xlsx" {
+		t.Errorf("Filename() = %s", got)
+	}
+}
+
+func TestPDFWriter(t *testing.T) {
+	data := string(writeTable(t, FormatPDF))
+
+	if !strings.HasPrefix(data, "%PDF-1.4\n") || !strings.HasSuffix(data, "%%EOF\n") {
+		t.Fatal("Expected a PDF document")
+	}
+	for _, want := range []string{
+		`(ID  Description`,
+		`=HYPERLINK\("http://evil"\)`,
+		`Taxi <airport> & "hotel"`,
+		`(Page 1 of 1)`,
+	} {
+		if !strings.Contains(data, want) {
+			t.Errorf("Expected the document to contain %s", want)
+		}
+	}
+
+	// Every entry of the cross-reference table points at its object.
+	xref := regexp.MustCompile(`(\d{10}) 00000 n`).FindAllStringSubmatch(data, -1)
+	if len(xref) != 6 {
+		t.Fatalf("Expected 6 objects, got %d", len(xref))
+	}
+	for i, entry := range xref {
+		offset, _ := strconv.Atoi(entry[1])
+		if want := fmt.Sprintf("%d 0 obj", i+1); !strings.HasPrefix(data[offset:], want) {
+			t.Errorf("Offset of object %d points at %q", i+1, data[offset:offset+10])
+		}
+	}
+}
+
+func TestPDFWriterPages(t *testing.T) {
+	f, _ := LookupDocumentFormat(FormatPDF)
+	var buf bytes.Buffer
+	w := f.NewWriter(&buf)
+	w.WriteHeader([]string{"ID", "Amount"})
+	for i := 0; i < 200; i++ {
+		w.WriteRow([]interface{}{i, 1.5})
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() unexpected error = %v", err)
+	}
+
+	data := buf.String()
+	if !strings.Contains(data, "/Count 5 ") || !strings.Contains(data, "(Page 5 of 5)") {
+		t.Error("Expected 41 rows on a page")
+	}
+	if !strings.Contains(data, "(199     1.5)") {
+		t.Error("Expected numbers aligned to the right")
+	}
+}
@@ -0,0 +1,204 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Page layout in points: A4 landscape with half-inch margins. Text is set
+// in Courier, whose characters are all 0.6 of the font size wide, so
+// columns can be laid out by counting characters.
+const (
+	pdfPageWidth      = 842
+	pdfPageHeight     = 595
+	pdfMargin         = 36
+	pdfCharWidth      = 0.6
+	pdfMaxFontSize    = 9.0
+	pdfMinFontSize    = 4.0
+	pdfLineSpacing    = 1.3
+	pdfColumnGap      = 2
+	pdfMaxColumnChars = 40
+)
+
+// pdfWriter lays out the table once every row is known, to size the
+// columns and count the pages, so unlike the other formats it keeps the
+// whole table in memory.
+type pdfWriter struct {
+	w       io.Writer
+	header  []string
+	rows    [][]string
+	numeric []bool
+}
+
+func newPDFWriter(w io.Writer) Writer {
+	return &pdfWriter{w: w}
+}
+
+func (p *pdfWriter) WriteHeader(columns []string) error {
+	p.header = columns
+	p.numeric = make([]bool, len(columns))
+	for i := range p.numeric {
+		p.numeric[i] = true
+	}
+	return nil
+}
+
+func (p *pdfWriter) WriteRow(cells []interface{}) error {
+	row := make([]string, len(cells))
+	for i, cell := range cells {
+		row[i] = cellText(cell)
+		if i < len(p.numeric) && !isNumber(cell) && cell != nil {
+			p.numeric[i] = false
+		}
+	}
+	p.rows = append(p.rows, row)
+	return nil
+}
+
+func isNumber(cell interface{}) bool {
+	switch cell.(type) {
+	case int, float64:
+		return true
+	}
+	return false
+}
+
+func (p *pdfWriter) Close() error {
+	widths := make([]int, len(p.header))
+	for i, column := range p.header {
+		widths[i] = utf8.RuneCountInString(column)
+	}
+	for _, row := range p.rows {
+		for i, text := range row {
+			if i < len(widths) {
+				widths[i] = max(widths[i], utf8.RuneCountInString(text))
+			}
+		}
+	}
+	lineChars := 0
+	for i := range widths {
+		widths[i] = min(widths[i], pdfMaxColumnChars)
+		lineChars += widths[i] + pdfColumnGap
+	}
+
+	fontSize := pdfMaxFontSize
+	if lineChars > 0 {
+		fontSize = float64(pdfPageWidth-2*pdfMargin) / (float64(lineChars) * pdfCharWidth)
+		fontSize = max(min(fontSize, pdfMaxFontSize), pdfMinFontSize)
+	}
+	leading := fontSize * pdfLineSpacing
+	// Each page has the header and a footer line besides the rows.
+	rowsPerPage := max(int(float64(pdfPageHeight-2*pdfMargin)/leading)-3, 1)
+
+	var pages []string
+	for start := 0; start == 0 || start < len(p.rows); start += rowsPerPage {
+		end := min(start+rowsPerPage, len(p.rows))
+
+		var content strings.Builder
+		y := float64(pdfPageHeight-pdfMargin) - fontSize
+		line := func(font string, text string) {
+			fmt.Fprintf(&content, "BT /%s %.2f Tf %d %.2f Td (%s) Tj ET\n", font, fontSize, pdfMargin, y, pdfString(text))
+			y -= leading
+		}
+
+		line("F2", p.layout(p.header, widths, false))
+		y -= leading / 2
+		for _, row := range p.rows[start:end] {
+			line("F1", p.layout(row, widths, true))
+		}
+		pages = append(pages, content.String())
+	}
+
+	for i := range pages {
+		footer := fmt.Sprintf("Page %d of %d", i+1, len(pages))
+		pages[i] += fmt.Sprintf("BT /F1 %.2f Tf %d %d Td (%s) Tj ET\n", fontSize, pdfMargin, pdfMargin/2, pdfString(footer))
+	}
+
+	return writePDF(p.w, pages)
+}
+
+// layout pads or cuts the cells of a row to the column widths, aligning
+// numbers to the right.
+func (p *pdfWriter) layout(cells []string, widths []int, alignNumbers bool) string {
+	var b strings.Builder
+	for i, width := range widths {
+		text := ""
+		if i < len(cells) {
+			text = strings.Join(strings.Fields(cells[i]), " ")
+		}
+		runes := []rune(text)
+		if len(runes) > width {
+			runes = append(runes[:width-1], '~')
+		}
+		padding := strings.Repeat(" ", width-len(runes)+pdfColumnGap)
+		if alignNumbers && p.numeric[i] {
+			b.WriteString(padding[pdfColumnGap:] + string(runes) + padding[:pdfColumnGap])
+		} else {
+			b.WriteString(string(runes) + padding)
+		}
+	}
+	return strings.TrimRight(b.String(), " ")
+}
+
+// pdfString escapes text for a PDF string in WinAnsiEncoding, which for
+// Latin-1 characters matches their code points; others become ?.
+func pdfString(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 0x20 && r < 0x7f:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			b.WriteByte(byte(r))
+		case r < 0x20:
+			b.WriteByte(' ')
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// writePDF writes a document with a page for each content stream.
+func writePDF(w io.Writer, pages []string) error {
+	out := bufio.NewWriter(w)
+	var offsets []int
+	written := 0
+	object := func(body string) {
+		offsets = append(offsets, written)
+		n, _ := fmt.Fprintf(out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+		written += n
+	}
+
+	n, _ := out.WriteString("%PDF-1.4\n")
+	written += n
+
+	// Objects 1 to 4 are the catalog, the page tree and the two fonts;
+	// every page is followed by its content stream.
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Courier-Bold /Encoding /WinAnsiEncoding >>")
+	for i, content := range pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			pdfPageWidth, pdfPageHeight, 6+2*i))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", len(content), content))
+	}
+
+	fmt.Fprintf(out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, written)
+	return out.Flush()
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"expense-management-system/internal/domain"
+	"expense-management-system/internal/middleware"
+	"net/http"
+	"strconv"
+)
+
+type AnalyticsHandler struct {
+	analyticsUsecase domain.AnalyticsUsecase
+}
+
+func NewAnalyticsHandler(analyticsUsecase domain.AnalyticsUsecase) *AnalyticsHandler {
+	return &AnalyticsHandler{analyticsUsecase: analyticsUsecase}
+}
+
+// Every analytics endpoint takes the filters of the expense list and covers
+// only the caller's own expenses without expense:read_all.
+
+func (h *AnalyticsHandler) Summary(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	filter, err := parseExpenseFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	canViewAll := middleware.HasPermission(r.Context(), domain.PermExpenseReadAll)
+	summary, err := h.analyticsUsecase.Summary(r.Context(), user.ID, filter, canViewAll)
+	if err != nil {
+		http.Error(w, err.Error(), analyticsErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summary)
+}
+
+// Breakdown groups by ?by=month|status|category|employee|approver.
+func (h *AnalyticsHandler) Breakdown(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	filter, err := parseExpenseFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	dimension := r.URL.Query().Get("by")
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	canViewAll := middleware.HasPermission(r.Context(), domain.PermExpenseReadAll)
+	groups, err := h.analyticsUsecase.Breakdown(r.Context(), user.ID, filter, dimension, limit, canViewAll)
+	if err != nil {
+		http.Error(w, err.Error(), analyticsErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"by": dimension, "groups": groups})
+}
+
+func (h *AnalyticsHandler) TopSpenders(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	filter, err := parseExpenseFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	canViewAll := middleware.HasPermission(r.Context(), domain.PermExpenseReadAll)
+	spenders, err := h.analyticsUsecase.TopSpenders(r.Context(), user.ID, filter, limit, canViewAll)
+	if err != nil {
+		http.Error(w, err.Error(), analyticsErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"top_spenders": spenders})
+}
+
+func analyticsErrorStatus(err error) int {
+	if errors.Is(err, domain.ErrInvalidFilter) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
@@ -2,7 +2,9 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"expense-management-system/internal/domain"
+	"expense-management-system/internal/middleware"
 	"net/http"
 )
 
@@ -19,9 +21,58 @@ type LoginRequest struct {
 	Password string `json:"password"`
 }
 
+// LoginResponse keeps Token (the access token) for existing clients.
 type LoginResponse struct {
-	Token string       `json:"token"`
-	User  *domain.User `json:"user"`
+	Token        string       `json:"token"`
+	RefreshToken string       `json:"refresh_token"`
+	ExpiresIn    int          `json:"expires_in"`
+	User         *domain.User `json:"user"`
+}
+
+// MFAChallengeResponse is returned by Login instead of tokens when a second
+// factor is needed.
+type MFAChallengeResponse struct {
+	MFARequired bool `json:"mfa_required"`
+	*domain.MFAChallenge
+}
+
+type ChallengeRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code,omitempty"`
+}
+
+// CompleteLoginResponse includes the recovery codes when the login also
+// completed a required enrolment.
+type CompleteLoginResponse struct {
+	LoginResponse
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+func newLoginResponse(pair *domain.TokenPair, user *domain.User) LoginResponse {
+	return LoginResponse{
+		Token:        pair.AccessToken,
+		RefreshToken: pair.RefreshToken,
+		ExpiresIn:    pair.ExpiresIn,
+		User:         user,
+	}
 }
 
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
@@ -36,17 +87,242 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, user, err := h.authUsecase.Login(r.Context(), req.Email, req.Password)
+	result, err := h.authUsecase.Login(r.Context(), req.Email, req.Password, middleware.ClientIP(r))
+	if errors.Is(err, domain.ErrTooManyLoginAttempts) {
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	}
+	if errors.Is(err, domain.ErrAccountLocked) {
+		http.Error(w, err.Error(), http.StatusLocked)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	writeLoginResult(w, result)
+}
+
+// writeLoginResult sends either the tokens or the two-factor challenge.
+func writeLoginResult(w http.ResponseWriter, result *domain.LoginResult) {
+	w.Header().Set("Content-Type", "application/json")
+	if result.Challenge != nil {
+		json.NewEncoder(w).Encode(MFAChallengeResponse{MFARequired: true, MFAChallenge: result.Challenge})
+		return
+	}
+	json.NewEncoder(w).Encode(newLoginResponse(result.Tokens, result.User))
+}
+
+// ChallengeSetup returns a TOTP secret for a challenged login whose role
+// requires two-factor authentication that is not set up yet.
+func (h *AuthHandler) ChallengeSetup(w http.ResponseWriter, r *http.Request) {
+	var req ChallengeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ChallengeToken == "" {
+		http.Error(w, "Challenge token is required", http.StatusBadRequest)
+		return
+	}
+
+	setup, err := h.authUsecase.ChallengeSetup(r.Context(), req.ChallengeToken)
+	if err != nil {
+		http.Error(w, err.Error(), challengeErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(setup)
+}
+
+// ChallengeVerify completes a challenged login with a TOTP or recovery code.
+func (h *AuthHandler) ChallengeVerify(w http.ResponseWriter, r *http.Request) {
+	var req ChallengeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.ChallengeToken == "" || req.Code == "" {
+		http.Error(w, "Challenge token and code are required", http.StatusBadRequest)
+		return
+	}
+
+	pair, user, recoveryCodes, err := h.authUsecase.CompleteLogin(r.Context(), req.ChallengeToken, req.Code)
+	if err != nil {
+		http.Error(w, err.Error(), challengeErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(CompleteLoginResponse{
+		LoginResponse: newLoginResponse(pair, user),
+		RecoveryCodes: recoveryCodes,
+	})
+}
+
+func challengeErrorStatus(err error) int {
+	if errors.Is(err, domain.ErrAccountLocked) {
+		return http.StatusLocked
+	}
+	if errors.Is(err, domain.ErrInvalidTwoFactorCode) || err.Error() == "invalid or expired challenge" {
+		return http.StatusUnauthorized
+	}
+	return http.StatusBadRequest
+}
+
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.RefreshToken == "" {
+		http.Error(w, "Refresh token is required", http.StatusBadRequest)
+		return
+	}
+
+	pair, user, err := h.authUsecase.Refresh(r.Context(), req.RefreshToken)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusUnauthorized)
 		return
 	}
 
-	resp := LoginResponse{
-		Token: token,
-		User:  user,
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newLoginResponse(pair, user))
+}
+
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	token, ok := middleware.GetTokenFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// The refresh token is optional; without it only the access token is revoked.
+	var req RefreshRequest
+	json.NewDecoder(r.Body).Decode(&req)
+
+	if err := h.authUsecase.Logout(r.Context(), token, req.RefreshToken); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Logged out successfully"})
+}
+
+func (h *AuthHandler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	token, _ := middleware.GetTokenFromContext(r.Context())
+	if err := h.authUsecase.LogoutAll(r.Context(), user.ID, token); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "All sessions logged out"})
+}
+
+// Me returns the authenticated user with the permissions of their role.
+func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	// The request may have been authorized from the token's claims alone;
+	// the response has the stored user.
+	if token, ok := middleware.GetTokenFromContext(r.Context()); ok {
+		stored, err := h.authUsecase.ValidateToken(r.Context(), token)
+		if err != nil {
+			http.Error(w, "Invalid token", http.StatusUnauthorized)
+			return
+		}
+		user = stored
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"user":        user,
+		"permissions": middleware.GetPermissionsFromContext(r.Context()),
+	})
+}
+
+// ChangePassword sets a new password and ends every other session; the
+// response carries a fresh token pair for the caller.
+func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.CurrentPassword == "" || req.NewPassword == "" {
+		http.Error(w, "Current and new password are required", http.StatusBadRequest)
+		return
+	}
+
+	pair, err := h.authUsecase.ChangePassword(r.Context(), user.ID, req.CurrentPassword, req.NewPassword)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newLoginResponse(pair, user))
+}
+
+func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Email == "" {
+		http.Error(w, "Email is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.authUsecase.RequestPasswordReset(r.Context(), req.Email); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Same response whether or not the account exists.
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"message": "If the account exists, a reset link has been sent"})
+}
+
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Token == "" || req.NewPassword == "" {
+		http.Error(w, "Token and new password are required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.authUsecase.ResetPassword(r.Context(), req.Token, req.NewPassword); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(resp)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Password has been reset"})
 }
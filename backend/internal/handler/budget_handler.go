@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"encoding/json"
+	"expense-management-system/internal/domain"
+	"expense-management-system/internal/middleware"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type BudgetHandler struct {
+	budgetUsecase domain.BudgetUsecase
+}
+
+func NewBudgetHandler(budgetUsecase domain.BudgetUsecase) *BudgetHandler {
+	return &BudgetHandler{budgetUsecase: budgetUsecase}
+}
+
+type CreateBudgetRequest struct {
+	UserID          *int    `json:"user_id,omitempty"`
+	TeamID          *int    `json:"team_id,omitempty"`
+	Category        *string `json:"category,omitempty"`
+	Period          string  `json:"period"`
+	SoftLimitIDR    *int    `json:"soft_limit_idr,omitempty"`
+	HardLimitIDR    *int    `json:"hard_limit_idr,omitempty"`
+	HardLimitAction string  `json:"hard_limit_action,omitempty"`
+}
+
+func (h *BudgetHandler) Create(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req CreateBudgetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	budget, err := h.budgetUsecase.Create(r.Context(), user.ID, &domain.Budget{
+		UserID:          req.UserID,
+		TeamID:          req.TeamID,
+		Category:        req.Category,
+		Period:          req.Period,
+		SoftLimitIDR:    req.SoftLimitIDR,
+		HardLimitIDR:    req.HardLimitIDR,
+		HardLimitAction: req.HardLimitAction,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(budget)
+}
+
+func (h *BudgetHandler) List(w http.ResponseWriter, r *http.Request) {
+	budgets, err := h.budgetUsecase.List(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"budgets": budgets})
+}
+
+func (h *BudgetHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	budgetID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid budget ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.budgetUsecase.Delete(r.Context(), budgetID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Status reports consumption of every budget covering the caller. Users with
+// budget:read_all may look up another employee with ?user_id=.
+func (h *BudgetHandler) Status(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userID := user.ID
+	if raw := r.URL.Query().Get("user_id"); raw != "" {
+		requested, err := strconv.Atoi(raw)
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+		if requested != user.ID && !middleware.HasPermission(r.Context(), domain.PermBudgetReadAll) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		userID = requested
+	}
+
+	statuses, err := h.budgetUsecase.GetStatus(r.Context(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"user_id": userID, "budgets": statuses})
+}
@@ -0,0 +1,228 @@
+package handler
+
+import (
+	"encoding/json"
+	"expense-management-system/internal/domain"
+	"expense-management-system/internal/middleware"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type CashAdvanceHandler struct {
+	cashAdvanceUsecase domain.CashAdvanceUsecase
+}
+
+func NewCashAdvanceHandler(cashAdvanceUsecase domain.CashAdvanceUsecase) *CashAdvanceHandler {
+	return &CashAdvanceHandler{cashAdvanceUsecase: cashAdvanceUsecase}
+}
+
+type RequestCashAdvanceRequest struct {
+	AmountIDR int    `json:"amount_idr"`
+	Purpose   string `json:"purpose"`
+}
+
+type ListCashAdvancesResponse struct {
+	CashAdvances []*domain.CashAdvance `json:"cash_advances"`
+	Total        int                   `json:"total"`
+	Page         int                   `json:"page"`
+	Limit        int                   `json:"limit"`
+}
+
+// canViewAllAdvances reports whether the caller may see other employees' advances.
+func canViewAllAdvances(r *http.Request) bool {
+	return middleware.HasPermission(r.Context(), domain.PermAdvanceReadAll)
+}
+
+func (h *CashAdvanceHandler) Request(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req RequestCashAdvanceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	advance, err := h.cashAdvanceUsecase.Request(r.Context(), user.ID, req.AmountIDR, req.Purpose)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(advance)
+}
+
+func (h *CashAdvanceHandler) List(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 20
+	}
+
+	advances, total, err := h.cashAdvanceUsecase.List(r.Context(), user.ID, status, page, limit, canViewAllAdvances(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := ListCashAdvancesResponse{
+		CashAdvances: advances,
+		Total:        total,
+		Page:         page,
+		Limit:        limit,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (h *CashAdvanceHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	vars := mux.Vars(r)
+	advanceID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid cash advance ID", http.StatusBadRequest)
+		return
+	}
+
+	advance, err := h.cashAdvanceUsecase.GetByID(r.Context(), user.ID, advanceID, canViewAllAdvances(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(advance)
+}
+
+// Balance returns the caller's outstanding advance balance. Users with
+// advance:read_all may look up another employee with ?user_id=.
+func (h *CashAdvanceHandler) Balance(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userID := user.ID
+	if raw := r.URL.Query().Get("user_id"); raw != "" {
+		requested, err := strconv.Atoi(raw)
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+		if requested != user.ID && !canViewAllAdvances(r) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		userID = requested
+	}
+
+	balance, err := h.cashAdvanceUsecase.GetBalance(r.Context(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(balance)
+}
+
+func (h *CashAdvanceHandler) Approve(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	vars := mux.Vars(r)
+	advanceID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid cash advance ID", http.StatusBadRequest)
+		return
+	}
+
+	var req ApprovalRequest
+	json.NewDecoder(r.Body).Decode(&req)
+
+	if err := h.cashAdvanceUsecase.Approve(r.Context(), user.ID, advanceID, req.Notes); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Cash advance approved successfully"})
+}
+
+func (h *CashAdvanceHandler) Reject(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	vars := mux.Vars(r)
+	advanceID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid cash advance ID", http.StatusBadRequest)
+		return
+	}
+
+	var req ApprovalRequest
+	json.NewDecoder(r.Body).Decode(&req)
+
+	if err := h.cashAdvanceUsecase.Reject(r.Context(), user.ID, advanceID, req.Notes); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Cash advance rejected successfully"})
+}
+
+func (h *CashAdvanceHandler) Close(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	vars := mux.Vars(r)
+	advanceID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid cash advance ID", http.StatusBadRequest)
+		return
+	}
+
+	advance, err := h.cashAdvanceUsecase.Close(r.Context(), user.ID, advanceID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(advance)
+}
@@ -2,10 +2,17 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"expense-management-system/internal/domain"
+	"expense-management-system/internal/export"
 	"expense-management-system/internal/middleware"
+	"expense-management-system/pkg/logger"
+	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 )
@@ -19,20 +26,36 @@ func NewExpenseHandler(expenseUsecase domain.ExpenseUsecase) *ExpenseHandler {
 }
 
 type SubmitExpenseRequest struct {
-	AmountIDR   int     `json:"amount_idr"`
-	Description string  `json:"description"`
-	ReceiptURL  *string `json:"receipt_url,omitempty"`
+	AmountIDR     int     `json:"amount_idr"`
+	Description   string  `json:"description"`
+	Category      string  `json:"category,omitempty"`
+	ReceiptURL    *string `json:"receipt_url,omitempty"`
+	ReceiptID     *int    `json:"receipt_id,omitempty"`
+	CashAdvanceID *int    `json:"cash_advance_id,omitempty"`
 }
 
 type SubmitExpenseResponse struct {
-	ID               int     `json:"id"`
-	AmountIDR        int     `json:"amount_idr"`
-	Description      string  `json:"description"`
-	Status           string  `json:"status"`
-	RequiresApproval bool    `json:"requires_approval"`
-	AutoApproved     bool    `json:"auto_approved"`
-	CreatedAt        string  `json:"created_at"`
-	ReceiptURL       *string `json:"receipt_url,omitempty"`
+	ID               int      `json:"id"`
+	AmountIDR        int      `json:"amount_idr"`
+	Description      string   `json:"description"`
+	Category         string   `json:"category"`
+	Status           string   `json:"status"`
+	RequiresApproval bool     `json:"requires_approval"`
+	AutoApproved     bool     `json:"auto_approved"`
+	CreatedAt        string   `json:"created_at"`
+	ReceiptURL       *string  `json:"receipt_url,omitempty"`
+	ReceiptID        *int     `json:"receipt_id,omitempty"`
+	CashAdvanceID    *int     `json:"cash_advance_id,omitempty"`
+	BudgetWarnings   []string `json:"budget_warnings,omitempty"`
+	// PossibleDuplicateOf is set when the expense looks like a repeat of an
+	// earlier one; it then waits for a manager whatever its amount.
+	PossibleDuplicateOf *int `json:"possible_duplicate_of,omitempty"`
+	// PolicyViolations lists the warn and require_approval rules the
+	// expense matched; a blocking rule rejects it with 422 instead.
+	PolicyViolations []domain.PolicyViolation `json:"policy_violations,omitempty"`
+	// ReceiptAmountMismatch is set when the total read off the receipt is
+	// not the amount claimed; approvers are shown both.
+	ReceiptAmountMismatch bool `json:"receipt_amount_mismatch,omitempty"`
 }
 
 func (h *ExpenseHandler) Submit(w http.ResponseWriter, r *http.Request) {
@@ -48,21 +71,36 @@ func (h *ExpenseHandler) Submit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	expense, err := h.expenseUsecase.Submit(r.Context(), user.ID, req.AmountIDR, req.Description, req.ReceiptURL)
+	expense, err := h.expenseUsecase.Submit(r.Context(), user.ID, req.AmountIDR, req.Description, req.Category, req.ReceiptURL, req.ReceiptID, req.CashAdvanceID)
+	var policyErr *domain.PolicyViolationError
+	if errors.As(err, &policyErr) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": err.Error(), "violations": policyErr.Violations})
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
 	resp := SubmitExpenseResponse{
-		ID:               expense.ID,
-		AmountIDR:        expense.AmountIDR,
-		Description:      expense.Description,
-		Status:           expense.Status,
-		RequiresApproval: expense.AmountIDR >= domain.ApprovalThreshold,
-		AutoApproved:     expense.AutoApproved,
-		CreatedAt:        expense.SubmittedAt.Format("2006-01-02T15:04:05Z"),
-		ReceiptURL:       expense.ReceiptURL,
+		ID:                  expense.ID,
+		AmountIDR:           expense.AmountIDR,
+		Description:         expense.Description,
+		Category:            expense.Category,
+		Status:              expense.Status,
+		RequiresApproval:    expense.Status == domain.StatusAwaitingApproval,
+		AutoApproved:        expense.AutoApproved,
+		CreatedAt:           expense.SubmittedAt.Format("2006-01-02T15:04:05Z"),
+		ReceiptURL:          expense.ReceiptURL,
+		ReceiptID:           expense.ReceiptID,
+		CashAdvanceID:       expense.CashAdvanceID,
+		BudgetWarnings:      expense.BudgetWarnings,
+		PossibleDuplicateOf: expense.PossibleDuplicateOf,
+		PolicyViolations:    expense.PolicyViolations,
+
+		ReceiptAmountMismatch: expense.ReceiptAmountMismatch,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -70,11 +108,14 @@ func (h *ExpenseHandler) Submit(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(resp)
 }
 
+// ListExpensesResponse has Page only in offset mode and Total only when it
+// was counted. NextCursor is set while there are more expenses.
 type ListExpensesResponse struct {
-	Expenses []*domain.Expense `json:"expenses"`
-	Total    int               `json:"total"`
-	Page     int               `json:"page"`
-	Limit    int               `json:"limit"`
+	Expenses   []*domain.Expense `json:"expenses"`
+	Total      *int              `json:"total,omitempty"`
+	Page       int               `json:"page,omitempty"`
+	Limit      int               `json:"limit"`
+	NextCursor string            `json:"next_cursor,omitempty"`
 }
 
 func (h *ExpenseHandler) List(w http.ResponseWriter, r *http.Request) {
@@ -84,9 +125,48 @@ func (h *ExpenseHandler) List(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	status := r.URL.Query().Get("status")
-	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
-	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	filter, err := parseExpenseFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	pageRequest, err := parsePageRequest(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	canViewAll := middleware.HasPermission(r.Context(), domain.PermExpenseReadAll)
+	result, err := h.expenseUsecase.GetUserExpenses(r.Context(), user.ID, filter, pageRequest, canViewAll)
+	if errors.Is(err, domain.ErrInvalidFilter) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := ListExpensesResponse{
+		Expenses:   result.Expenses,
+		Total:      result.Total,
+		Page:       pageRequest.Page,
+		Limit:      pageRequest.Limit,
+		NextCursor: result.NextCursor,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// parsePageRequest reads page and limit, or cursor for cursor pagination:
+// an empty cursor asks for the first page. The total is counted in offset
+// mode unless include_total=false, and in cursor mode only with
+// include_total=true.
+func parsePageRequest(query url.Values) (domain.PageRequest, error) {
+	page, _ := strconv.Atoi(query.Get("page"))
+	limit, _ := strconv.Atoi(query.Get("limit"))
 
 	if page < 1 {
 		page = 1
@@ -95,22 +175,179 @@ func (h *ExpenseHandler) List(w http.ResponseWriter, r *http.Request) {
 		limit = 20
 	}
 
-	isManager := user.Role == "manager"
-	expenses, total, err := h.expenseUsecase.GetUserExpenses(r.Context(), user.ID, status, page, limit, isManager)
+	request := domain.PageRequest{Page: page, Limit: limit, CountTotal: true}
+	if query.Has("cursor") {
+		request.Page = 0
+		request.Cursor = query.Get("cursor")
+		request.CountTotal = false
+	}
+	if value := query.Get("include_total"); value != "" {
+		countTotal, err := strconv.ParseBool(value)
+		if err != nil {
+			return request, errors.New("include_total must be true or false")
+		}
+		request.CountTotal = countTotal
+	}
+	return request, nil
+}
+
+// parseExpenseFilter reads the list filters from the query string. status
+// may be repeated or comma-separated. Dates are RFC 3339 times or
+// YYYY-MM-DD days; a day as the upper bound includes the whole day.
+func parseExpenseFilter(query url.Values) (domain.ExpenseFilter, error) {
+	filter := domain.ExpenseFilter{
+		Search:    strings.TrimSpace(query.Get("q")),
+		SortBy:    query.Get("sort"),
+		SortOrder: strings.ToLower(query.Get("order")),
+	}
+
+	for _, value := range query["status"] {
+		for _, status := range strings.Split(value, ",") {
+			if status = strings.TrimSpace(status); status != "" {
+				filter.Statuses = append(filter.Statuses, status)
+			}
+		}
+	}
+
+	var err error
+	ints := []struct {
+		name string
+		dest **int
+	}{
+		{"submitter_id", &filter.SubmitterID},
+		{"approver_id", &filter.ApproverID},
+		{"min_amount", &filter.MinAmountIDR},
+		{"max_amount", &filter.MaxAmountIDR},
+	}
+	for _, param := range ints {
+		if *param.dest, err = parseIntParam(query, param.name); err != nil {
+			return filter, err
+		}
+	}
+
+	dates := []struct {
+		name       string
+		dest       **time.Time
+		upperBound bool
+	}{
+		{"submitted_from", &filter.SubmittedFrom, false},
+		{"submitted_to", &filter.SubmittedTo, true},
+		{"processed_from", &filter.ProcessedFrom, false},
+		{"processed_to", &filter.ProcessedTo, true},
+	}
+	for _, param := range dates {
+		if *param.dest, err = parseTimeParam(query, param.name, param.upperBound); err != nil {
+			return filter, err
+		}
+	}
+
+	if value := query.Get("auto_approved"); value != "" {
+		autoApproved, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, errors.New("auto_approved must be true or false")
+		}
+		filter.AutoApproved = &autoApproved
+	}
+
+	return filter, nil
+}
+
+func parseIntParam(query url.Values, name string) (*int, error) {
+	value := query.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(value)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, fmt.Errorf("%s must be a whole number", name)
+	}
+	return &n, nil
+}
+
+func parseTimeParam(query url.Values, name string, upperBound bool) (*time.Time, error) {
+	value := query.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	day, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be a date (YYYY-MM-DD) or an RFC 3339 time", name)
+	}
+	if upperBound {
+		day = day.AddDate(0, 0, 1)
+	}
+	return &day, nil
+}
+
+// Export sends the expenses matching the list filters as a file, written
+// while they are read. format is csv (default) or xlsx; PDF is left to
+// scheduled reports, as it is laid out only once every row is read.
+func (h *ExpenseHandler) Export(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	resp := ListExpensesResponse{
-		Expenses: expenses,
-		Total:    total,
-		Page:     page,
-		Limit:    limit,
+	formatName := r.URL.Query().Get("format")
+	if formatName == "" {
+		formatName = export.FormatCSV
+	}
+	format, err := export.LookupFormat(formatName)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(resp)
+	filter, err := parseExpenseFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// The response starts with the first row, so a filter the usecase
+	// rejects or a failing query can still get an error status.
+	var out export.Writer
+	begin := func() error {
+		if out != nil {
+			return nil
+		}
+		w.Header().Set("Content-Type", format.ContentType)
+		w.Header().Set("Content-Disposition", `attachment; filename="`+format.Filename("expenses", time.Now())+`"`)
+		out = format.NewWriter(w)
+		return out.WriteHeader(export.ExpenseColumns)
+	}
+
+	canViewAll := middleware.HasPermission(r.Context(), domain.PermExpenseReadAll)
+	err = h.expenseUsecase.ExportExpenses(r.Context(), user.ID, filter, canViewAll, func(row *domain.ExpenseExportRow) error {
+		if err := begin(); err != nil {
+			return err
+		}
+		return out.WriteRow(export.ExpenseCells(row))
+	})
+	if err == nil {
+		if err = begin(); err == nil {
+			err = out.Close()
+		}
+	}
+
+	if err != nil && out == nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, domain.ErrInvalidFilter) {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	if err != nil {
+		logger.ErrorLogger.Printf("Expense export for user %d failed: %v", user.ID, err)
+		// The file is partly sent; dropping the connection keeps the client
+		// from taking it for a complete one.
+		panic(http.ErrAbortHandler)
+	}
 }
 
 func (h *ExpenseHandler) GetByID(w http.ResponseWriter, r *http.Request) {
@@ -127,8 +364,8 @@ func (h *ExpenseHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	isManager := user.Role == domain.RoleManager
-	expense, err := h.expenseUsecase.GetByID(r.Context(), user.ID, expenseID, isManager)
+	canViewAll := middleware.HasPermission(r.Context(), domain.PermExpenseReadAll)
+	expense, err := h.expenseUsecase.GetByID(r.Context(), user.ID, expenseID, canViewAll)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
@@ -157,7 +394,7 @@ func (h *ExpenseHandler) GetPendingApprovals(w http.ResponseWriter, r *http.Requ
 
 	resp := ListExpensesResponse{
 		Expenses: expenses,
-		Total:    total,
+		Total:    &total,
 		Page:     page,
 		Limit:    limit,
 	}
@@ -168,6 +405,8 @@ func (h *ExpenseHandler) GetPendingApprovals(w http.ResponseWriter, r *http.Requ
 
 type ApprovalRequest struct {
 	Notes *string `json:"notes,omitempty"`
+	// TOTPCode is needed to approve amounts above the step-up threshold.
+	TOTPCode string `json:"totp_code,omitempty"`
 }
 
 func (h *ExpenseHandler) Approve(w http.ResponseWriter, r *http.Request) {
@@ -187,7 +426,16 @@ func (h *ExpenseHandler) Approve(w http.ResponseWriter, r *http.Request) {
 	var req ApprovalRequest
 	json.NewDecoder(r.Body).Decode(&req)
 
-	if err := h.expenseUsecase.Approve(r.Context(), user.ID, expenseID, req.Notes); err != nil {
+	err = h.expenseUsecase.Approve(r.Context(), user.ID, expenseID, req.Notes, req.TOTPCode)
+	if errors.Is(err, domain.ErrStepUpRequired) || errors.Is(err, domain.ErrInvalidTwoFactorCode) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, domain.ErrAccountLocked) {
+		http.Error(w, err.Error(), http.StatusLocked)
+		return
+	}
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -0,0 +1,210 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"expense-management-system/internal/domain"
+	"expense-management-system/internal/export"
+	"expense-management-system/internal/middleware"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type JournalHandler struct {
+	journalUsecase domain.JournalUsecase
+}
+
+func NewJournalHandler(journalUsecase domain.JournalUsecase) *JournalHandler {
+	return &JournalHandler{journalUsecase: journalUsecase}
+}
+
+func (h *JournalHandler) ListAccounts(w http.ResponseWriter, r *http.Request) {
+	accounts, err := h.journalUsecase.ListAccounts(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"accounts": accounts})
+}
+
+type UpdateJournalAccountRequest struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+func (h *JournalHandler) UpdateAccount(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req UpdateJournalAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	account, err := h.journalUsecase.UpdateAccount(r.Context(), user.ID, mux.Vars(r)["key"], req.Code, req.Name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(account)
+}
+
+// CreateJournalBatchRequest takes the day up to which (exclusive) paid
+// expenses are exported; without it, everything paid so far.
+type CreateJournalBatchRequest struct {
+	PaidBefore string `json:"paid_before,omitempty"`
+}
+
+func (h *JournalHandler) CreateBatch(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req CreateJournalBatchRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var paidBefore time.Time
+	if req.PaidBefore != "" {
+		day, err := time.Parse("2006-01-02", req.PaidBefore)
+		if err != nil {
+			http.Error(w, "paid_before must be a date in YYYY-MM-DD format", http.StatusBadRequest)
+			return
+		}
+		paidBefore = day
+	}
+
+	batch, err := h.journalUsecase.CreateBatch(r.Context(), user.ID, paidBefore)
+	if errors.Is(err, domain.ErrNothingToExport) || errors.Is(err, domain.ErrJournalConflict) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// The lines are downloaded separately.
+	batch.Lines = nil
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(batch)
+}
+
+type ListJournalBatchesResponse struct {
+	Batches []*domain.JournalBatch `json:"batches"`
+	Total   int                    `json:"total"`
+	Page    int                    `json:"page"`
+	Limit   int                    `json:"limit"`
+}
+
+func (h *JournalHandler) List(w http.ResponseWriter, r *http.Request) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 20
+	}
+
+	batches, total, err := h.journalUsecase.List(r.Context(), page, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := ListJournalBatchesResponse{
+		Batches: batches,
+		Total:   total,
+		Page:    page,
+		Limit:   limit,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+var journalLineColumns = []string{
+	"Batch", "Entry Date", "Reference", "Account Code", "Account Name", "Debit (IDR)", "Credit (IDR)", "Description",
+}
+
+// Download sends a batch with its lines as JSON (default) or as a csv,
+// xlsx or pdf file for import into accounting software. It can be repeated; the
+// lines are the same every time.
+func (h *JournalHandler) Download(w http.ResponseWriter, r *http.Request) {
+	batchID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid journal batch ID", http.StatusBadRequest)
+		return
+	}
+
+	formatName := r.URL.Query().Get("format")
+	var format *export.Format
+	if formatName != "" && formatName != "json" {
+		if format, err = export.LookupDocumentFormat(formatName); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	batch, err := h.journalUsecase.GetByID(r.Context(), batchID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if format == nil {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(batch)
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="journal-batch-%d.%s"`, batch.ID, format.Name))
+
+	out := format.NewWriter(w)
+	out.WriteHeader(journalLineColumns)
+	for _, line := range batch.Lines {
+		out.WriteRow([]interface{}{
+			batch.ID,
+			line.EntryDate.Format("2006-01-02"),
+			journalReference(line),
+			line.AccountCode,
+			line.AccountName,
+			line.DebitIDR,
+			line.CreditIDR,
+			line.Description,
+		})
+	}
+	out.Close()
+}
+
+// journalReference is EXP-<id> for the lines of an expense and REF-<id> for
+// those of a refund of it.
+func journalReference(line *domain.JournalLine) string {
+	if line.RefundID != nil {
+		return "REF-" + strconv.Itoa(*line.RefundID)
+	}
+	return "EXP-" + strconv.Itoa(line.ExpenseID)
+}
@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"encoding/json"
+	"expense-management-system/internal/domain"
+	"expense-management-system/pkg/logger"
+	"net/http"
+)
+
+// jwksMaxAge lets clients cache the key set. New keys are published
+// JWT_KEY_PUBLISH_LEAD_MINUTES (at least 10) before they sign, which leaves
+// room for this.
+const jwksMaxAge = "public, max-age=300"
+
+type JWKSHandler struct {
+	signer domain.TokenSigner
+}
+
+func NewJWKSHandler(signer domain.TokenSigner) *JWKSHandler {
+	return &JWKSHandler{signer: signer}
+}
+
+// JWKS serves the public keys access tokens are signed with, so other
+// services can verify them.
+func (h *JWKSHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.signer.PublicKeys(r.Context())
+	if err != nil {
+		logger.ErrorLogger.Printf("Failed to load signing keys: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", jwksMaxAge)
+	json.NewEncoder(w).Encode(keys)
+}
@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"encoding/json"
+	"expense-management-system/internal/domain"
+	"expense-management-system/internal/middleware"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type PaymentRunHandler struct {
+	paymentRunUsecase domain.PaymentRunUsecase
+}
+
+func NewPaymentRunHandler(paymentRunUsecase domain.PaymentRunUsecase) *PaymentRunHandler {
+	return &PaymentRunHandler{paymentRunUsecase: paymentRunUsecase}
+}
+
+type ListPaymentRunsResponse struct {
+	PaymentRuns []*domain.PaymentRun `json:"payment_runs"`
+	Total       int                  `json:"total"`
+	Page        int                  `json:"page"`
+	Limit       int                  `json:"limit"`
+}
+
+func (h *PaymentRunHandler) List(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 20
+	}
+
+	runs, total, err := h.paymentRunUsecase.List(r.Context(), status, page, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := ListPaymentRunsResponse{
+		PaymentRuns: runs,
+		Total:       total,
+		Page:        page,
+		Limit:       limit,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (h *PaymentRunHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	runID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid payment run ID", http.StatusBadRequest)
+		return
+	}
+
+	run, err := h.paymentRunUsecase.GetByID(r.Context(), runID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(run)
+}
+
+func (h *PaymentRunHandler) Release(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	vars := mux.Vars(r)
+	runID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid payment run ID", http.StatusBadRequest)
+		return
+	}
+
+	run, err := h.paymentRunUsecase.Release(r.Context(), user.ID, runID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(run)
+}
+
+func (h *PaymentRunHandler) RetryPayout(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	vars := mux.Vars(r)
+	runID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid payment run ID", http.StatusBadRequest)
+		return
+	}
+	payoutID, err := strconv.Atoi(vars["payoutID"])
+	if err != nil {
+		http.Error(w, "Invalid payout ID", http.StatusBadRequest)
+		return
+	}
+
+	run, err := h.paymentRunUsecase.RetryPayout(r.Context(), user.ID, runID, payoutID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(run)
+}
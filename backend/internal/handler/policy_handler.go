@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"encoding/json"
+	"expense-management-system/internal/domain"
+	"expense-management-system/internal/middleware"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+type PolicyHandler struct {
+	policyUsecase domain.PolicyUsecase
+}
+
+func NewPolicyHandler(policyUsecase domain.PolicyUsecase) *PolicyHandler {
+	return &PolicyHandler{policyUsecase: policyUsecase}
+}
+
+// PolicyRuleRequest defines a rule; rules are enabled unless enabled is
+// false.
+type PolicyRuleRequest struct {
+	Name      string          `json:"name"`
+	Message   string          `json:"message"`
+	Action    string          `json:"action"`
+	Condition json.RawMessage `json:"condition"`
+	Enabled   *bool           `json:"enabled"`
+}
+
+func (req *PolicyRuleRequest) rule() *domain.PolicyRule {
+	return &domain.PolicyRule{
+		Name:      req.Name,
+		Message:   req.Message,
+		Action:    req.Action,
+		Condition: req.Condition,
+		Enabled:   req.Enabled == nil || *req.Enabled,
+	}
+}
+
+func (h *PolicyHandler) Create(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req PolicyRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	rule, err := h.policyUsecase.Create(r.Context(), user.ID, req.rule())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(rule)
+}
+
+func (h *PolicyHandler) List(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.policyUsecase.List(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"rules": rules})
+}
+
+func (h *PolicyHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	ruleID, ok := policyRuleID(w, r)
+	if !ok {
+		return
+	}
+
+	rule, err := h.policyUsecase.GetByID(r.Context(), ruleID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rule)
+}
+
+// Update replaces a rule; expenses submitted before keep what it said then.
+func (h *PolicyHandler) Update(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ruleID, ok := policyRuleID(w, r)
+	if !ok {
+		return
+	}
+
+	var req PolicyRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	rule, err := h.policyUsecase.Update(r.Context(), user.ID, ruleID, req.rule())
+	if err != nil {
+		status := http.StatusBadRequest
+		if strings.HasSuffix(err.Error(), "not found") {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rule)
+}
+
+func (h *PolicyHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ruleID, ok := policyRuleID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.policyUsecase.Delete(r.Context(), user.ID, ruleID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func policyRuleID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid policy rule ID", http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"expense-management-system/internal/domain"
+	"expense-management-system/internal/middleware"
+	"expense-management-system/pkg/logger"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// maxReceiptUploadBytes bounds a whole upload request: the 5 MB file and
+// its multipart framing.
+const maxReceiptUploadBytes = 6 << 20
+
+type ReceiptHandler struct {
+	receiptUsecase domain.ReceiptUsecase
+}
+
+func NewReceiptHandler(receiptUsecase domain.ReceiptUsecase) *ReceiptHandler {
+	return &ReceiptHandler{receiptUsecase: receiptUsecase}
+}
+
+// Upload stores the receipt sent as the "file" field of a multipart form.
+// Expenses refer to it by its ID.
+func (h *ReceiptHandler) Upload(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxReceiptUploadBytes)
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "Receipt must be at most 5 MB", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Expected a multipart form with a file field", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	receipt, err := h.receiptUsecase.Upload(r.Context(), user.ID, file)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, domain.ErrInvalidReceipt) {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(receipt)
+}
+
+// Download sends a receipt file to its uploader or to anyone with
+// expense:read_all.
+func (h *ReceiptHandler) Download(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	receiptID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid receipt ID", http.StatusBadRequest)
+		return
+	}
+
+	canViewAll := middleware.HasPermission(r.Context(), domain.PermExpenseReadAll)
+	receipt, file, err := h.receiptUsecase.Open(r.Context(), user.ID, receiptID, canViewAll)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", receipt.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(receipt.SizeBytes, 10))
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="%s"`, receipt.FileName))
+	// The content type was sniffed on upload; browsers must not guess again.
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	if _, err := io.Copy(w, file); err != nil {
+		logger.ErrorLogger.Printf("Failed to send receipt %d: %v", receipt.ID, err)
+	}
+}
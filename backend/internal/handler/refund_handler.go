@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"encoding/json"
+	"expense-management-system/internal/domain"
+	"expense-management-system/internal/middleware"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type RefundHandler struct {
+	refundUsecase domain.RefundUsecase
+}
+
+func NewRefundHandler(refundUsecase domain.RefundUsecase) *RefundHandler {
+	return &RefundHandler{refundUsecase: refundUsecase}
+}
+
+type RefundRequest struct {
+	Type      string `json:"type"`
+	AmountIDR int    `json:"amount_idr"`
+	Reason    string `json:"reason"`
+}
+
+func (h *RefundHandler) Create(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	vars := mux.Vars(r)
+	expenseID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid expense ID", http.StatusBadRequest)
+		return
+	}
+
+	var req RefundRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	refund, err := h.refundUsecase.Initiate(r.Context(), user.ID, expenseID, req.Type, req.AmountIDR, req.Reason)
+	if err != nil {
+		// A failed reversal is still recorded, so return it alongside the error.
+		if refund != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadGateway)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": err.Error(), "refund": refund})
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(refund)
+}
+
+func (h *RefundHandler) List(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	vars := mux.Vars(r)
+	expenseID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid expense ID", http.StatusBadRequest)
+		return
+	}
+
+	refunds, err := h.refundUsecase.GetByExpenseID(r.Context(), user.ID, expenseID, middleware.HasPermission(r.Context(), domain.PermExpenseReadAll))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"refunds": refunds})
+}
@@ -0,0 +1,261 @@
+package handler
+
+import (
+	"encoding/json"
+	"expense-management-system/internal/domain"
+	"expense-management-system/internal/export"
+	"expense-management-system/internal/middleware"
+	"expense-management-system/pkg/logger"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+type ReportHandler struct {
+	reportUsecase domain.ReportUsecase
+}
+
+func NewReportHandler(reportUsecase domain.ReportUsecase) *ReportHandler {
+	return &ReportHandler{reportUsecase: reportUsecase}
+}
+
+// ReportScheduleRequest defines a report; schedules are enabled unless
+// enabled is false.
+type ReportScheduleRequest struct {
+	Name       string               `json:"name"`
+	Cron       string               `json:"cron"`
+	Period     string               `json:"period"`
+	GroupBy    string               `json:"group_by"`
+	Format     string               `json:"format"`
+	Filters    domain.ReportFilters `json:"filters"`
+	Recipients []string             `json:"recipients"`
+	Enabled    *bool                `json:"enabled"`
+}
+
+func (req *ReportScheduleRequest) schedule() *domain.ReportSchedule {
+	return &domain.ReportSchedule{
+		Name:       req.Name,
+		Cron:       req.Cron,
+		Period:     req.Period,
+		GroupBy:    req.GroupBy,
+		Format:     req.Format,
+		Filters:    req.Filters,
+		Recipients: req.Recipients,
+		Enabled:    req.Enabled == nil || *req.Enabled,
+	}
+}
+
+func (h *ReportHandler) CreateSchedule(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req ReportScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	schedule, err := h.reportUsecase.CreateSchedule(r.Context(), user.ID, req.schedule())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(schedule)
+}
+
+func (h *ReportHandler) ListSchedules(w http.ResponseWriter, r *http.Request) {
+	schedules, err := h.reportUsecase.ListSchedules(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"schedules": schedules})
+}
+
+func (h *ReportHandler) GetSchedule(w http.ResponseWriter, r *http.Request) {
+	scheduleID, ok := reportScheduleID(w, r)
+	if !ok {
+		return
+	}
+
+	schedule, err := h.reportUsecase.GetSchedule(r.Context(), scheduleID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(schedule)
+}
+
+// UpdateSchedule replaces the definition of a report; its run history is
+// kept.
+func (h *ReportHandler) UpdateSchedule(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	scheduleID, ok := reportScheduleID(w, r)
+	if !ok {
+		return
+	}
+
+	var req ReportScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	schedule, err := h.reportUsecase.UpdateSchedule(r.Context(), user.ID, scheduleID, req.schedule())
+	if err != nil {
+		http.Error(w, err.Error(), reportErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(schedule)
+}
+
+func (h *ReportHandler) DeleteSchedule(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	scheduleID, ok := reportScheduleID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.reportUsecase.DeleteSchedule(r.Context(), user.ID, scheduleID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Run generates a report now and sends it to the recipients. A report that
+// fails to generate still returns its run, with the error.
+func (h *ReportHandler) Run(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	scheduleID, ok := reportScheduleID(w, r)
+	if !ok {
+		return
+	}
+
+	run, err := h.reportUsecase.RunNow(r.Context(), user.ID, scheduleID)
+	if err != nil {
+		http.Error(w, err.Error(), reportErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(run)
+}
+
+type ListReportRunsResponse struct {
+	Runs  []*domain.ReportRun `json:"runs"`
+	Total int                 `json:"total"`
+	Page  int                 `json:"page"`
+	Limit int                 `json:"limit"`
+}
+
+// ListRuns lists past runs, newest first, of every report or of the one
+// given with ?schedule_id=.
+func (h *ReportHandler) ListRuns(w http.ResponseWriter, r *http.Request) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 20
+	}
+
+	scheduleID, err := parseIntParam(r.URL.Query(), "schedule_id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	runs, total, err := h.reportUsecase.ListRuns(r.Context(), scheduleID, page, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := ListReportRunsResponse{
+		Runs:  runs,
+		Total: total,
+		Page:  page,
+		Limit: limit,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (h *ReportHandler) Download(w http.ResponseWriter, r *http.Request) {
+	runID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid report run ID", http.StatusBadRequest)
+		return
+	}
+
+	run, file, err := h.reportUsecase.OpenRun(r.Context(), runID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	defer file.Close()
+
+	contentType := "application/octet-stream"
+	if format, err := export.LookupDocumentFormat(run.Format); err == nil {
+		contentType = format.ContentType
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="report-%d-%s.%s"`, run.ID, run.StartedAt.Format("20060102"), run.Format))
+
+	if _, err := io.Copy(w, file); err != nil {
+		logger.ErrorLogger.Printf("Failed to send report run %d: %v", run.ID, err)
+	}
+}
+
+func reportScheduleID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid report schedule ID", http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+func reportErrorStatus(err error) int {
+	if strings.HasSuffix(err.Error(), "not found") {
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
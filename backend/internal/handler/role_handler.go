@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"encoding/json"
+	"expense-management-system/internal/domain"
+	"expense-management-system/internal/middleware"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+type RoleHandler struct {
+	roleUsecase domain.RoleUsecase
+}
+
+func NewRoleHandler(roleUsecase domain.RoleUsecase) *RoleHandler {
+	return &RoleHandler{roleUsecase: roleUsecase}
+}
+
+type CreateRoleRequest struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+// UpdateRoleRequest leaves omitted fields unchanged.
+type UpdateRoleRequest struct {
+	Description *string  `json:"description,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+}
+
+// Permissions lists every permission a role can grant.
+func (h *RoleHandler) Permissions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"permissions": domain.Permissions})
+}
+
+func (h *RoleHandler) List(w http.ResponseWriter, r *http.Request) {
+	roles, err := h.roleUsecase.List(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"roles": roles})
+}
+
+func (h *RoleHandler) GetByName(w http.ResponseWriter, r *http.Request) {
+	role, err := h.roleUsecase.GetByName(r.Context(), mux.Vars(r)["name"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(role)
+}
+
+func (h *RoleHandler) Create(w http.ResponseWriter, r *http.Request) {
+	admin, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req CreateRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	role, err := h.roleUsecase.Create(r.Context(), admin.ID, &domain.Role{
+		Name:        req.Name,
+		Description: req.Description,
+		Permissions: req.Permissions,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), roleErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(role)
+}
+
+func (h *RoleHandler) Update(w http.ResponseWriter, r *http.Request) {
+	admin, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req UpdateRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	role, err := h.roleUsecase.Update(r.Context(), admin.ID, mux.Vars(r)["name"], req.Description, req.Permissions)
+	if err != nil {
+		http.Error(w, err.Error(), roleErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(role)
+}
+
+func (h *RoleHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	admin, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.roleUsecase.Delete(r.Context(), admin.ID, mux.Vars(r)["name"]); err != nil {
+		http.Error(w, err.Error(), roleErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func roleErrorStatus(err error) int {
+	switch {
+	case err.Error() == "role not found":
+		return http.StatusNotFound
+	case strings.Contains(err.Error(), "already exists"), strings.Contains(err.Error(), "assigned to"):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
@@ -0,0 +1,200 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"expense-management-system/internal/domain"
+	"expense-management-system/internal/middleware"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type ServiceAccountHandler struct {
+	apiKeyUsecase domain.APIKeyUsecase
+}
+
+func NewServiceAccountHandler(apiKeyUsecase domain.APIKeyUsecase) *ServiceAccountHandler {
+	return &ServiceAccountHandler{apiKeyUsecase: apiKeyUsecase}
+}
+
+type CreateServiceAccountRequest struct {
+	Name string `json:"name"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	// ExpiresAt defaults to API_KEY_TTL_DAYS from now.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// APIKeyResponse carries the plaintext key, which is only ever returned
+// here.
+type APIKeyResponse struct {
+	Key    string         `json:"key"`
+	APIKey *domain.APIKey `json:"api_key"`
+}
+
+func (h *ServiceAccountHandler) Create(w http.ResponseWriter, r *http.Request) {
+	admin, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req CreateServiceAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	account, err := h.apiKeyUsecase.CreateServiceAccount(r.Context(), admin.ID, req.Name)
+	if err != nil {
+		http.Error(w, err.Error(), serviceAccountErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(account)
+}
+
+func (h *ServiceAccountHandler) List(w http.ResponseWriter, r *http.Request) {
+	accounts, err := h.apiKeyUsecase.ListServiceAccounts(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"service_accounts": accounts})
+}
+
+func (h *ServiceAccountHandler) CreateKey(w http.ResponseWriter, r *http.Request) {
+	admin, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	accountID, ok := serviceAccountIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var req CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	granted := middleware.GetPermissionsFromContext(r.Context())
+	key, rawKey, err := h.apiKeyUsecase.CreateKey(r.Context(), admin.ID, granted, accountID, req.Name, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		http.Error(w, err.Error(), serviceAccountErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(APIKeyResponse{Key: rawKey, APIKey: key})
+}
+
+func (h *ServiceAccountHandler) ListKeys(w http.ResponseWriter, r *http.Request) {
+	accountID, ok := serviceAccountIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	keys, err := h.apiKeyUsecase.ListKeys(r.Context(), accountID)
+	if err != nil {
+		http.Error(w, err.Error(), serviceAccountErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"api_keys": keys})
+}
+
+func (h *ServiceAccountHandler) RotateKey(w http.ResponseWriter, r *http.Request) {
+	admin, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	accountID, keyID, ok := apiKeyIDsFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	granted := middleware.GetPermissionsFromContext(r.Context())
+	key, rawKey, err := h.apiKeyUsecase.RotateKey(r.Context(), admin.ID, granted, accountID, keyID)
+	if err != nil {
+		http.Error(w, err.Error(), serviceAccountErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(APIKeyResponse{Key: rawKey, APIKey: key})
+}
+
+func (h *ServiceAccountHandler) RevokeKey(w http.ResponseWriter, r *http.Request) {
+	admin, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	accountID, keyID, ok := apiKeyIDsFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.apiKeyUsecase.RevokeKey(r.Context(), admin.ID, accountID, keyID); err != nil {
+		http.Error(w, err.Error(), serviceAccountErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func serviceAccountIDFromPath(w http.ResponseWriter, r *http.Request) (int, bool) {
+	accountID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid service account ID", http.StatusBadRequest)
+		return 0, false
+	}
+	return accountID, true
+}
+
+func apiKeyIDsFromPath(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	accountID, ok := serviceAccountIDFromPath(w, r)
+	if !ok {
+		return 0, 0, false
+	}
+	keyID, err := strconv.Atoi(mux.Vars(r)["keyID"])
+	if err != nil {
+		http.Error(w, "Invalid API key ID", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	return accountID, keyID, true
+}
+
+func serviceAccountErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrScopeNotGrantable):
+		return http.StatusForbidden
+	case strings.HasSuffix(err.Error(), "not found"):
+		return http.StatusNotFound
+	case strings.Contains(err.Error(), "already"):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
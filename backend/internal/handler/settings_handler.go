@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"encoding/json"
+	"expense-management-system/internal/domain"
+	"expense-management-system/internal/middleware"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+type SettingsHandler struct {
+	settingsUsecase domain.SettingsUsecase
+}
+
+func NewSettingsHandler(settingsUsecase domain.SettingsUsecase) *SettingsHandler {
+	return &SettingsHandler{settingsUsecase: settingsUsecase}
+}
+
+type UpdateSettingRequest struct {
+	Value *int `json:"value"`
+}
+
+// ExpenseLimits lets any signed-in user check amounts before submitting.
+func (h *SettingsHandler) ExpenseLimits(w http.ResponseWriter, r *http.Request) {
+	limits, err := h.settingsUsecase.ExpenseLimits(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(limits)
+}
+
+func (h *SettingsHandler) List(w http.ResponseWriter, r *http.Request) {
+	settings, err := h.settingsUsecase.List(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"settings": settings})
+}
+
+func (h *SettingsHandler) Update(w http.ResponseWriter, r *http.Request) {
+	admin, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req UpdateSettingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Value == nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	setting, err := h.settingsUsecase.Update(r.Context(), admin.ID, mux.Vars(r)["key"], *req.Value)
+	if err != nil {
+		http.Error(w, err.Error(), settingErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(setting)
+}
+
+// Reset drops the stored value so the one from the environment applies.
+func (h *SettingsHandler) Reset(w http.ResponseWriter, r *http.Request) {
+	admin, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	setting, err := h.settingsUsecase.Reset(r.Context(), admin.ID, mux.Vars(r)["key"])
+	if err != nil {
+		http.Error(w, err.Error(), settingErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(setting)
+}
+
+// History lists changes newest first, of one setting with ?key=.
+func (h *SettingsHandler) History(w http.ResponseWriter, r *http.Request) {
+	limit := 0
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	changes, err := h.settingsUsecase.History(r.Context(), r.URL.Query().Get("key"), limit)
+	if err != nil {
+		http.Error(w, err.Error(), settingErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"changes": changes})
+}
+
+func settingErrorStatus(err error) int {
+	if strings.HasSuffix(err.Error(), "not found") {
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"expense-management-system/internal/domain"
+	"net/http"
+)
+
+type SSOHandler struct {
+	ssoUsecase domain.SSOUsecase
+}
+
+func NewSSOHandler(ssoUsecase domain.SSOUsecase) *SSOHandler {
+	return &SSOHandler{ssoUsecase: ssoUsecase}
+}
+
+type SSOCallbackRequest struct {
+	Code  string `json:"code"`
+	State string `json:"state"`
+}
+
+// Authorize returns the identity provider URL the browser should be sent to.
+func (h *SSOHandler) Authorize(w http.ResponseWriter, r *http.Request) {
+	authURL, err := h.ssoUsecase.Begin(r.Context())
+	if errors.Is(err, domain.ErrSSONotConfigured) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"authorization_url": authURL})
+}
+
+// Callback takes the code and state the identity provider sent back to the
+// frontend and logs the user in.
+func (h *SSOHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	var req SSOCallbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Code == "" || req.State == "" {
+		http.Error(w, "Code and state are required", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.ssoUsecase.Complete(r.Context(), req.Code, req.State)
+	if errors.Is(err, domain.ErrSSONotConfigured) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	writeLoginResult(w, result)
+}
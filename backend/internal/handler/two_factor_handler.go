@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"expense-management-system/internal/domain"
+	"expense-management-system/internal/middleware"
+	"net/http"
+)
+
+type TwoFactorHandler struct {
+	twoFactorUsecase domain.TwoFactorUsecase
+}
+
+func NewTwoFactorHandler(twoFactorUsecase domain.TwoFactorUsecase) *TwoFactorHandler {
+	return &TwoFactorHandler{twoFactorUsecase: twoFactorUsecase}
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+func (h *TwoFactorHandler) Status(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	status, err := h.twoFactorUsecase.Status(r.Context(), user)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// Setup starts enrolment; the provisioning URI is meant to be shown as a QR
+// code.
+func (h *TwoFactorHandler) Setup(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	setup, err := h.twoFactorUsecase.Setup(r.Context(), user)
+	if err != nil {
+		http.Error(w, err.Error(), twoFactorErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(setup)
+}
+
+func (h *TwoFactorHandler) Enable(w http.ResponseWriter, r *http.Request) {
+	user, req, ok := h.codeRequest(w, r)
+	if !ok {
+		return
+	}
+
+	codes, err := h.twoFactorUsecase.Enable(r.Context(), user, req.Code)
+	if err != nil {
+		http.Error(w, err.Error(), twoFactorErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+func (h *TwoFactorHandler) Disable(w http.ResponseWriter, r *http.Request) {
+	user, req, ok := h.codeRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.twoFactorUsecase.Disable(r.Context(), user, req.Code); err != nil {
+		http.Error(w, err.Error(), twoFactorErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Two-factor authentication disabled"})
+}
+
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	user, req, ok := h.codeRequest(w, r)
+	if !ok {
+		return
+	}
+
+	codes, err := h.twoFactorUsecase.RegenerateRecoveryCodes(r.Context(), user, req.Code)
+	if err != nil {
+		http.Error(w, err.Error(), twoFactorErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+func (h *TwoFactorHandler) codeRequest(w http.ResponseWriter, r *http.Request) (*domain.User, *TwoFactorCodeRequest, bool) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, nil, false
+	}
+
+	var req TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return nil, nil, false
+	}
+	if req.Code == "" {
+		http.Error(w, "Code is required", http.StatusBadRequest)
+		return nil, nil, false
+	}
+
+	return user, &req, true
+}
+
+func twoFactorErrorStatus(err error) int {
+	if errors.Is(err, domain.ErrInvalidTwoFactorCode) {
+		return http.StatusUnauthorized
+	}
+	if err.Error() == "two-factor authentication is already enabled" {
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
@@ -0,0 +1,300 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expense-management-system/internal/domain"
+	"expense-management-system/internal/middleware"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// defaultAuditLogPage is the page size of audit logs read with a cursor but
+// no limit.
+const defaultAuditLogPage = 100
+
+type UserAdminHandler struct {
+	userAdminUsecase domain.UserAdminUsecase
+}
+
+func NewUserAdminHandler(userAdminUsecase domain.UserAdminUsecase) *UserAdminHandler {
+	return &UserAdminHandler{userAdminUsecase: userAdminUsecase}
+}
+
+type CreateUserRequest struct {
+	Email    string `json:"email"`
+	Name     string `json:"name"`
+	Password string `json:"password"`
+	Role     string `json:"role,omitempty"`
+	TeamID   *int   `json:"team_id,omitempty"`
+}
+
+type ChangeRoleRequest struct {
+	Role string `json:"role"`
+}
+
+type ListUsersResponse struct {
+	Users []*domain.User `json:"users"`
+	Total int            `json:"total"`
+	Page  int            `json:"page"`
+	Limit int            `json:"limit"`
+}
+
+func (h *UserAdminHandler) Create(w http.ResponseWriter, r *http.Request) {
+	admin, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req CreateUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	granted := middleware.GetPermissionsFromContext(r.Context())
+	user, err := h.userAdminUsecase.Create(r.Context(), admin.ID, granted, &domain.User{
+		Email:  req.Email,
+		Name:   req.Name,
+		Role:   req.Role,
+		TeamID: req.TeamID,
+	}, req.Password)
+	if err != nil {
+		http.Error(w, err.Error(), userAdminErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(user)
+}
+
+func (h *UserAdminHandler) List(w http.ResponseWriter, r *http.Request) {
+	role := r.URL.Query().Get("role")
+	includeInactive := r.URL.Query().Get("include_inactive") == "true"
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 20
+	}
+
+	users, total, err := h.userAdminUsecase.List(r.Context(), role, includeInactive, page, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp := ListUsersResponse{
+		Users: users,
+		Total: total,
+		Page:  page,
+		Limit: limit,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (h *UserAdminHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := h.userAdminUsecase.GetByID(r.Context(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+func (h *UserAdminHandler) Update(w http.ResponseWriter, r *http.Request) {
+	admin, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userID, ok := userIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var req domain.UserUpdate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.userAdminUsecase.Update(r.Context(), admin.ID, userID, &req)
+	if err != nil {
+		http.Error(w, err.Error(), userAdminErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+func (h *UserAdminHandler) ChangeRole(w http.ResponseWriter, r *http.Request) {
+	admin, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userID, ok := userIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var req ChangeRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	granted := middleware.GetPermissionsFromContext(r.Context())
+	user, err := h.userAdminUsecase.ChangeRole(r.Context(), admin.ID, granted, userID, req.Role)
+	if err != nil {
+		http.Error(w, err.Error(), userAdminErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+func (h *UserAdminHandler) Deactivate(w http.ResponseWriter, r *http.Request) {
+	h.setActive(w, r, h.userAdminUsecase.Deactivate)
+}
+
+func (h *UserAdminHandler) Reactivate(w http.ResponseWriter, r *http.Request) {
+	h.setActive(w, r, h.userAdminUsecase.Reactivate)
+}
+
+func (h *UserAdminHandler) AuditLogs(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	// Without a limit every log is returned, as before pagination existed.
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	page := domain.PageRequest{Limit: limit, Cursor: r.URL.Query().Get("cursor")}
+	if page.Cursor != "" && page.Limit < 1 {
+		page.Limit = defaultAuditLogPage
+	}
+
+	result, err := h.userAdminUsecase.GetAuditLogs(r.Context(), userID, page)
+	if errors.Is(err, domain.ErrInvalidFilter) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), userAdminErrorStatus(err))
+		return
+	}
+
+	resp := map[string]interface{}{"audit_logs": result.AuditLogs}
+	if result.NextCursor != "" {
+		resp["next_cursor"] = result.NextCursor
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (h *UserAdminHandler) LockoutStatus(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	status, err := h.userAdminUsecase.GetLockoutStatus(r.Context(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), userAdminErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+func (h *UserAdminHandler) Unlock(w http.ResponseWriter, r *http.Request) {
+	admin, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userID, ok := userIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	status, err := h.userAdminUsecase.Unlock(r.Context(), admin.ID, userID)
+	if err != nil {
+		http.Error(w, err.Error(), userAdminErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+func (h *UserAdminHandler) setActive(w http.ResponseWriter, r *http.Request, apply func(ctx context.Context, adminID, id int) (*domain.User, error)) {
+	admin, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userID, ok := userIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := apply(r.Context(), admin.ID, userID)
+	if err != nil {
+		http.Error(w, err.Error(), userAdminErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+func userIDFromPath(w http.ResponseWriter, r *http.Request) (int, bool) {
+	userID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return 0, false
+	}
+	return userID, true
+}
+
+func userAdminErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrRoleNotGrantable):
+		return http.StatusForbidden
+	case err.Error() == "user not found":
+		return http.StatusNotFound
+	case strings.Contains(err.Error(), "already"):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
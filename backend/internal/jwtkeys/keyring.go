@@ -0,0 +1,285 @@
+package jwtkeys
+
+import (
+	"context"
+	"crypto/cipher"
+	"errors"
+	"expense-management-system/internal/domain"
+	"expense-management-system/pkg/logger"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// reloadInterval is how long keys are cached. Other instances see a new key
+// within this time, so it must be shorter than Config.PublishLead.
+const reloadInterval = time.Minute
+
+type Config struct {
+	// Algorithm is RS256 or EdDSA.
+	Algorithm string
+	// RotationInterval is how long each key signs tokens.
+	RotationInterval time.Duration
+	// PublishLead is how long a new key is in the JWKS before it signs, so
+	// services that cache the JWKS know it before they see tokens from it.
+	PublishLead time.Duration
+	// VerifyGrace is how long a key is still accepted after it stopped
+	// signing; at least the access token lifetime.
+	VerifyGrace time.Duration
+	// EncryptionSecret encrypts the private keys stored in the database.
+	EncryptionSecret string
+}
+
+// KeyRing signs tokens with asymmetric keys kept in the database, so every
+// API instance uses the same keys. Each token names its key in the kid
+// header. Rotate adds keys ahead of time and drops expired ones.
+type KeyRing struct {
+	repo   domain.SigningKeyRepository
+	cfg    Config
+	method jwt.SigningMethod
+	aead   cipher.AEAD
+	now    func() time.Time
+
+	mu       sync.RWMutex
+	keys     []*loadedKey
+	loadedAt time.Time
+}
+
+func NewKeyRing(repo domain.SigningKeyRepository, cfg Config) (*KeyRing, error) {
+	method, err := signingMethod(cfg.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.RotationInterval <= 0 || cfg.PublishLead < 0 || cfg.VerifyGrace < 0 {
+		return nil, errors.New("invalid key rotation intervals")
+	}
+	aead, err := newAEAD(cfg.EncryptionSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	return &KeyRing{
+		repo:   repo,
+		cfg:    cfg,
+		method: method,
+		aead:   aead,
+		now:    time.Now,
+	}, nil
+}
+
+func (k *KeyRing) Sign(ctx context.Context, claims map[string]interface{}) (string, error) {
+	key, err := k.signingKey(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(key.method, jwt.MapClaims(claims))
+	token.Header["kid"] = key.KID
+	return token.SignedString(key.private)
+}
+
+// Verify only accepts tokens signed by a known key with that key's
+// algorithm, so a token cannot pick how it is checked.
+func (k *KeyRing) Verify(ctx context.Context, tokenString string) (map[string]interface{}, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, err := k.verificationKey(ctx, kid)
+		if err != nil {
+			return nil, err
+		}
+		if token.Method.Alg() != key.Algorithm {
+			return nil, errors.New("algorithm does not match key")
+		}
+		return key.private.Public(), nil
+	}, jwt.WithValidMethods([]string{AlgorithmRS256, AlgorithmEdDSA}), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, errInvalidToken
+	}
+	return claims, nil
+}
+
+// PublicKeys includes keys that do not sign yet and keys that retired but
+// still verify.
+func (k *KeyRing) PublicKeys(ctx context.Context) (*domain.JSONWebKeySet, error) {
+	keys, err := k.current(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	set := &domain.JSONWebKeySet{Keys: []domain.JSONWebKey{}}
+	now := k.now()
+	for _, key := range keys {
+		if key.ExpiresAt.After(now) {
+			set.Keys = append(set.Keys, key.jwk())
+		}
+	}
+	return set, nil
+}
+
+// Rotate makes sure a key signs now and that its successor is published
+// PublishLead before it takes over. It is safe to run on every instance;
+// at worst two instances add a key at the same time and both are used.
+func (k *KeyRing) Rotate(ctx context.Context) error {
+	now := k.now()
+	if deleted, err := k.repo.DeleteExpired(ctx, now); err != nil {
+		return err
+	} else if deleted > 0 {
+		logger.InfoLogger.Printf("Deleted %d expired signing key(s)", deleted)
+	}
+
+	keys, err := k.current(ctx, true)
+	if err != nil {
+		return err
+	}
+
+	var latest *loadedKey
+	for _, key := range keys {
+		if key.Algorithm == k.cfg.Algorithm && (latest == nil || key.RetiresAt.After(latest.RetiresAt)) {
+			latest = key
+		}
+	}
+
+	switch {
+	case latest == nil || !latest.RetiresAt.After(now):
+		return k.addKey(ctx, now)
+	case latest.RetiresAt.Sub(now) <= k.cfg.PublishLead:
+		return k.addKey(ctx, latest.RetiresAt)
+	}
+	return nil
+}
+
+func (k *KeyRing) addKey(ctx context.Context, activatesAt time.Time) error {
+	private, err := generatePrivateKey(k.cfg.Algorithm)
+	if err != nil {
+		return err
+	}
+	kid, err := newKID()
+	if err != nil {
+		return err
+	}
+	sealed, err := sealPrivateKey(k.aead, kid, private)
+	if err != nil {
+		return err
+	}
+
+	retiresAt := activatesAt.Add(k.cfg.RotationInterval)
+	key := &domain.SigningKey{
+		KID:         kid,
+		Algorithm:   k.cfg.Algorithm,
+		PrivateKey:  sealed,
+		ActivatesAt: activatesAt,
+		RetiresAt:   retiresAt,
+		ExpiresAt:   retiresAt.Add(k.cfg.VerifyGrace),
+	}
+	if err := k.repo.Create(ctx, key); err != nil {
+		return err
+	}
+	logger.InfoLogger.Printf("[SECURITY] Added %s signing key %s, signing from %s", key.Algorithm, key.KID, activatesAt.Format(time.RFC3339))
+
+	_, err = k.current(ctx, true)
+	return err
+}
+
+// signingKey is the most recently activated key of the configured
+// algorithm that has not retired.
+func (k *KeyRing) signingKey(ctx context.Context) (*loadedKey, error) {
+	for _, reload := range []bool{false, true} {
+		keys, err := k.current(ctx, reload)
+		if err != nil {
+			return nil, err
+		}
+
+		now := k.now()
+		for i := len(keys) - 1; i >= 0; i-- {
+			key := keys[i]
+			if key.Algorithm == k.cfg.Algorithm && !key.ActivatesAt.After(now) && key.RetiresAt.After(now) {
+				return key, nil
+			}
+		}
+	}
+	return nil, errors.New("no active signing key")
+}
+
+func (k *KeyRing) verificationKey(ctx context.Context, kid string) (*loadedKey, error) {
+	find := func(keys []*loadedKey) *loadedKey {
+		for _, key := range keys {
+			if key.KID == kid && key.ExpiresAt.After(k.now()) {
+				return key
+			}
+		}
+		return nil
+	}
+
+	keys, err := k.current(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	if key := find(keys); key != nil {
+		return key, nil
+	}
+
+	// Another instance may have just added the key. Unknown key IDs reload
+	// at most once per reloadInterval/2, so forged tokens cannot hammer the
+	// database.
+	k.mu.RLock()
+	recent := k.now().Sub(k.loadedAt) < reloadInterval/2
+	k.mu.RUnlock()
+	if !recent {
+		if keys, err = k.current(ctx, true); err != nil {
+			return nil, err
+		}
+		if key := find(keys); key != nil {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// current returns the cached keys ordered by activation, loading them when
+// the cache is stale or reload is set.
+func (k *KeyRing) current(ctx context.Context, reload bool) ([]*loadedKey, error) {
+	k.mu.RLock()
+	if !reload && k.keys != nil && k.now().Sub(k.loadedAt) < reloadInterval {
+		keys := k.keys
+		k.mu.RUnlock()
+		return keys, nil
+	}
+	k.mu.RUnlock()
+
+	stored, err := k.repo.ListUnexpired(ctx, k.now())
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]*loadedKey, 0, len(stored))
+	for _, key := range stored {
+		private, err := openPrivateKey(k.aead, key.KID, key.PrivateKey)
+		if err != nil {
+			logger.ErrorLogger.Printf("[SECURITY] Skipping signing key %s: %v", key.KID, err)
+			continue
+		}
+		method, err := signingMethod(key.Algorithm)
+		if err != nil {
+			logger.ErrorLogger.Printf("[SECURITY] Skipping signing key %s: %v", key.KID, err)
+			continue
+		}
+		keys = append(keys, &loadedKey{SigningKey: key, private: private, method: method})
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		if keys[i].ActivatesAt.Equal(keys[j].ActivatesAt) {
+			return keys[i].KID < keys[j].KID
+		}
+		return keys[i].ActivatesAt.Before(keys[j].ActivatesAt)
+	})
+
+	k.mu.Lock()
+	k.keys = keys
+	k.loadedAt = k.now()
+	k.mu.Unlock()
+
+	return keys, nil
+}
@@ -0,0 +1,218 @@
+package jwtkeys
+
+import (
+	"context"
+	"expense-management-system/internal/domain"
+	"expense-management-system/pkg/logger"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func init() {
+	logger.Init()
+}
+
+type memoryKeyRepo struct {
+	keys map[string]*domain.SigningKey
+}
+
+func newMemoryKeyRepo() *memoryKeyRepo {
+	return &memoryKeyRepo{keys: map[string]*domain.SigningKey{}}
+}
+
+func (m *memoryKeyRepo) ListUnexpired(ctx context.Context, now time.Time) ([]*domain.SigningKey, error) {
+	keys := []*domain.SigningKey{}
+	for _, key := range m.keys {
+		if key.ExpiresAt.After(now) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (m *memoryKeyRepo) Create(ctx context.Context, key *domain.SigningKey) error {
+	key.CreatedAt = time.Now()
+	m.keys[key.KID] = key
+	return nil
+}
+
+func (m *memoryKeyRepo) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	deleted := 0
+	for kid, key := range m.keys {
+		if !key.ExpiresAt.After(now) {
+			delete(m.keys, kid)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func newTestKeyRing(t *testing.T, repo *memoryKeyRepo, algorithm string, now *time.Time) *KeyRing {
+	t.Helper()
+	ring, err := NewKeyRing(repo, Config{
+		Algorithm:        algorithm,
+		RotationInterval: 24 * time.Hour,
+		PublishLead:      time.Hour,
+		VerifyGrace:      2 * time.Hour,
+		EncryptionSecret: "test-secret-key",
+	})
+	if err != nil {
+		t.Fatalf("NewKeyRing() unexpected error = %v", err)
+	}
+	ring.now = func() time.Time { return *now }
+	return ring
+}
+
+func testClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"user_id": 1,
+		"exp":     time.Now().Add(15 * time.Minute).Unix(),
+	}
+}
+
+func tokenKID(t *testing.T, token string) string {
+	t.Helper()
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+	if err != nil {
+		t.Fatalf("ParseUnverified() error = %v", err)
+	}
+	kid, _ := parsed.Header["kid"].(string)
+	return kid
+}
+
+func TestKeyRing_SignAndVerify(t *testing.T) {
+	for _, algorithm := range []string{AlgorithmRS256, AlgorithmEdDSA} {
+		t.Run(algorithm, func(t *testing.T) {
+			ctx := context.Background()
+			now := time.Now()
+			ring := newTestKeyRing(t, newMemoryKeyRepo(), algorithm, &now)
+
+			if _, err := ring.Sign(ctx, testClaims()); err == nil {
+				t.Fatal("Expected signing to fail before the first rotation")
+			}
+			if err := ring.Rotate(ctx); err != nil {
+				t.Fatalf("Rotate() unexpected error = %v", err)
+			}
+
+			token, err := ring.Sign(ctx, testClaims())
+			if err != nil {
+				t.Fatalf("Sign() unexpected error = %v", err)
+			}
+			claims, err := ring.Verify(ctx, token)
+			if err != nil {
+				t.Fatalf("Verify() unexpected error = %v", err)
+			}
+			if claims["user_id"].(float64) != 1 {
+				t.Errorf("Verify() claims = %v", claims)
+			}
+
+			set, _ := ring.PublicKeys(ctx)
+			if len(set.Keys) != 1 || set.Keys[0].Kid != tokenKID(t, token) || set.Keys[0].Alg != algorithm {
+				t.Errorf("PublicKeys() = %+v", set)
+			}
+		})
+	}
+}
+
+func TestKeyRing_Rotation(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemoryKeyRepo()
+	now := time.Now()
+	ring := newTestKeyRing(t, repo, AlgorithmEdDSA, &now)
+
+	ring.Rotate(ctx)
+	oldToken, _ := ring.Sign(ctx, testClaims())
+	oldKID := tokenKID(t, oldToken)
+
+	// Nothing to do while the key has long to live.
+	ring.Rotate(ctx)
+	if len(repo.keys) != 1 {
+		t.Fatalf("Expected one key, got %d", len(repo.keys))
+	}
+
+	// Within the publish lead the successor is published but not used yet.
+	now = now.Add(23*time.Hour + 30*time.Minute)
+	ring.Rotate(ctx)
+	if set, _ := ring.PublicKeys(ctx); len(set.Keys) != 2 {
+		t.Fatalf("Expected the next key to be published, got %+v", set)
+	}
+	if token, _ := ring.Sign(ctx, testClaims()); tokenKID(t, token) != oldKID {
+		t.Error("Expected the current key to keep signing until it retires")
+	}
+
+	// Once the old key retires the successor signs, and tokens from the old
+	// key still verify during the grace period.
+	now = now.Add(time.Hour)
+	newToken, _ := ring.Sign(ctx, testClaims())
+	if tokenKID(t, newToken) == oldKID {
+		t.Error("Expected the new key to sign after the old one retired")
+	}
+	if _, err := ring.Verify(ctx, oldToken); err != nil {
+		t.Errorf("Expected tokens of the retired key to verify, got %v", err)
+	}
+
+	// After the grace period the old key is gone.
+	now = now.Add(3 * time.Hour)
+	ring.Rotate(ctx)
+	if _, ok := repo.keys[oldKID]; ok {
+		t.Error("Expected the expired key to be deleted")
+	}
+	if _, err := ring.Verify(ctx, oldToken); err == nil {
+		t.Error("Expected tokens of an expired key to be rejected")
+	}
+	if _, err := ring.Verify(ctx, newToken); err != nil {
+		t.Errorf("Verify() new token unexpected error = %v", err)
+	}
+}
+
+func TestKeyRing_RejectsForeignTokens(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemoryKeyRepo()
+	now := time.Now()
+	ring := newTestKeyRing(t, repo, AlgorithmRS256, &now)
+	ring.Rotate(ctx)
+
+	// An HS256 token must not be checked with a public key as the secret.
+	hmacToken, _ := NewHMACSigner("test-secret-key").Sign(ctx, testClaims())
+	if _, err := ring.Verify(ctx, hmacToken); err == nil {
+		t.Error("Expected an HS256 token to be rejected")
+	}
+
+	// A token from a key ring with other keys.
+	other := newTestKeyRing(t, newMemoryKeyRepo(), AlgorithmRS256, &now)
+	other.Rotate(ctx)
+	foreign, _ := other.Sign(ctx, testClaims())
+	if _, err := ring.Verify(ctx, foreign); err == nil {
+		t.Error("Expected a token signed with an unknown key to be rejected")
+	}
+
+	// Keys stored with another secret cannot be used.
+	for _, key := range repo.keys {
+		key.PrivateKey[len(key.PrivateKey)-1] ^= 0xff
+	}
+	tampered := newTestKeyRing(t, repo, AlgorithmRS256, &now)
+	if set, _ := tampered.PublicKeys(ctx); len(set.Keys) != 0 {
+		t.Errorf("Expected undecryptable keys to be skipped, got %+v", set)
+	}
+}
+
+func TestHMACSigner(t *testing.T) {
+	ctx := context.Background()
+	signer := NewHMACSigner("test-secret-key")
+
+	token, err := signer.Sign(ctx, testClaims())
+	if err != nil {
+		t.Fatalf("Sign() unexpected error = %v", err)
+	}
+	if _, err := signer.Verify(ctx, token); err != nil {
+		t.Errorf("Verify() unexpected error = %v", err)
+	}
+	if _, err := NewHMACSigner("another-secret").Verify(ctx, token); err == nil {
+		t.Error("Expected a token signed with another secret to be rejected")
+	}
+	if set, _ := signer.PublicKeys(ctx); len(set.Keys) != 0 {
+		t.Errorf("Expected no public keys, got %+v", set)
+	}
+}
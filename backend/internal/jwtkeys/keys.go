@@ -0,0 +1,119 @@
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"expense-management-system/internal/domain"
+	"fmt"
+	"math/big"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const rsaKeyBits = 2048
+
+// loadedKey is a stored signing key with its private key decrypted.
+type loadedKey struct {
+	*domain.SigningKey
+	private crypto.Signer
+	method  jwt.SigningMethod
+}
+
+func signingMethod(algorithm string) (jwt.SigningMethod, error) {
+	switch algorithm {
+	case AlgorithmRS256:
+		return jwt.SigningMethodRS256, nil
+	case AlgorithmEdDSA:
+		return jwt.SigningMethodEdDSA, nil
+	}
+	return nil, fmt.Errorf("unsupported signing algorithm %q", algorithm)
+}
+
+func generatePrivateKey(algorithm string) (crypto.Signer, error) {
+	switch algorithm {
+	case AlgorithmRS256:
+		return rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case AlgorithmEdDSA:
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		return private, err
+	}
+	return nil, fmt.Errorf("unsupported signing algorithm %q", algorithm)
+}
+
+func newKID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// newAEAD derives the key that encrypts private keys at rest from secret.
+func newAEAD(secret string) (cipher.AEAD, error) {
+	key := sha256.Sum256([]byte("jwt-signing-keys\x00" + secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealPrivateKey returns the PKCS #8 encoding of private, encrypted and
+// prefixed with its nonce.
+func sealPrivateKey(aead cipher.AEAD, kid string, private crypto.Signer) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, der, []byte(kid)), nil
+}
+
+func openPrivateKey(aead cipher.AEAD, kid string, sealed []byte) (crypto.Signer, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("encrypted key is too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	der, err := aead.Open(nil, nonce, ciphertext, []byte(kid))
+	if err != nil {
+		return nil, errors.New("cannot decrypt key; was JWT_SECRET changed?")
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+	private, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("unsupported private key type")
+	}
+	return private, nil
+}
+
+// jwk describes the public half of a signing key.
+func (k *loadedKey) jwk() domain.JSONWebKey {
+	key := domain.JSONWebKey{Kid: k.KID, Use: "sig", Alg: k.Algorithm}
+	switch public := k.private.Public().(type) {
+	case *rsa.PublicKey:
+		key.Kty = "RSA"
+		key.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		key.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		key.Kty = "OKP"
+		key.Crv = "Ed25519"
+		key.X = base64.RawURLEncoding.EncodeToString(public)
+	}
+	return key
+}
@@ -3,15 +3,24 @@ package middleware
 import (
 	"context"
 	"expense-management-system/internal/domain"
+	"expense-management-system/pkg/logger"
 	"net/http"
+	"sort"
 	"strings"
 )
 
 type contextKey string
 
-const UserContextKey contextKey = "user"
+const (
+	UserContextKey        contextKey = "user"
+	TokenContextKey       contextKey = "token"
+	PermissionsContextKey contextKey = "permissions"
+	APIKeyContextKey      contextKey = "api_key"
+)
 
-func AuthMiddleware(authUsecase domain.AuthUsecase) func(http.Handler) http.Handler {
+// AuthMiddleware accepts either a user's access token ("Bearer <token>")
+// or a service account's API key ("ApiKey <key>").
+func AuthMiddleware(authUsecase domain.AuthUsecase, apiKeyUsecase domain.APIKeyUsecase) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			authHeader := r.Header.Get("Authorization")
@@ -21,35 +30,132 @@ func AuthMiddleware(authUsecase domain.AuthUsecase) func(http.Handler) http.Hand
 			}
 
 			parts := strings.Split(authHeader, " ")
-			if len(parts) != 2 || parts[0] != "Bearer" {
+			if len(parts) != 2 || (parts[0] != "Bearer" && parts[0] != "ApiKey") {
 				http.Error(w, "Invalid authorization header", http.StatusUnauthorized)
 				return
 			}
 
-			user, err := authUsecase.ValidateToken(r.Context(), parts[1])
+			if parts[0] == "ApiKey" {
+				user, key, err := apiKeyUsecase.Authenticate(r.Context(), parts[1], ClientIP(r))
+				if err != nil {
+					http.Error(w, "Invalid API key", http.StatusUnauthorized)
+					return
+				}
+
+				ctx := context.WithValue(r.Context(), UserContextKey, user)
+				ctx = context.WithValue(ctx, APIKeyContextKey, key)
+				ctx = domain.WithAPIKeyID(ctx, key.ID)
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
+			validate := authUsecase.ValidateToken
+			if isReadOnly(r.Method) {
+				validate = authUsecase.ValidateTokenClaims
+			}
+			user, err := validate(r.Context(), parts[1])
 			if err != nil {
 				http.Error(w, "Invalid token", http.StatusUnauthorized)
 				return
 			}
 
 			ctx := context.WithValue(r.Context(), UserContextKey, user)
+			ctx = context.WithValue(ctx, TokenContextKey, parts[1])
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
-func ManagerOnly(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		user, ok := r.Context().Value(UserContextKey).(*domain.User)
-		if !ok || user.Role != domain.RoleManager {
-			http.Error(w, "Forbidden: Manager access required", http.StatusForbidden)
-			return
+// isReadOnly reports whether a request cannot change anything, so that it
+// may be authorized from the token's claims alone.
+func isReadOnly(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// PermissionMiddleware loads what the authenticated user's role may do, or
+// for API key requests what the key's scopes allow. It runs after
+// AuthMiddleware.
+func PermissionMiddleware(roleUsecase domain.RoleUsecase) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, ok := GetUserFromContext(r.Context())
+			if !ok {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			if key, ok := GetAPIKeyFromContext(r.Context()); ok {
+				permissions := make(map[string]bool, len(key.Scopes))
+				for _, scope := range key.Scopes {
+					permissions[scope] = true
+				}
+				ctx := context.WithValue(r.Context(), PermissionsContextKey, permissions)
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
+			permissions, err := roleUsecase.Permissions(r.Context(), user.Role)
+			if err != nil {
+				logger.ErrorLogger.Printf("Failed to load permissions of role %s: %v", user.Role, err)
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), PermissionsContextKey, permissions)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequirePermission only lets requests through that were granted permission.
+func RequirePermission(permission string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !HasPermission(r.Context(), permission) {
+				http.Error(w, "Forbidden: "+permission+" permission required", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// HasPermission reports whether the request was granted permission, by the
+// user's role or the API key's scopes.
+func HasPermission(ctx context.Context, permission string) bool {
+	permissions, _ := ctx.Value(PermissionsContextKey).(map[string]bool)
+	return permissions[permission]
+}
+
+// GetPermissionsFromContext returns the authenticated user's permissions,
+// sorted.
+func GetPermissionsFromContext(ctx context.Context) []string {
+	permissions, _ := ctx.Value(PermissionsContextKey).(map[string]bool)
+	list := make([]string, 0, len(permissions))
+	for permission, granted := range permissions {
+		if granted {
+			list = append(list, permission)
 		}
-		next.ServeHTTP(w, r)
-	})
+	}
+	sort.Strings(list)
+	return list
 }
 
 func GetUserFromContext(ctx context.Context) (*domain.User, bool) {
 	user, ok := ctx.Value(UserContextKey).(*domain.User)
 	return user, ok
 }
+
+// GetTokenFromContext returns the raw access token the request was
+// authenticated with.
+func GetTokenFromContext(ctx context.Context) (string, bool) {
+	token, ok := ctx.Value(TokenContextKey).(string)
+	return token, ok
+}
+
+// GetAPIKeyFromContext returns the API key the request was authenticated
+// with, if it was not made by a signed-in user.
+func GetAPIKeyFromContext(ctx context.Context) (*domain.APIKey, bool) {
+	key, ok := ctx.Value(APIKeyContextKey).(*domain.APIKey)
+	return key, ok
+}
@@ -0,0 +1,21 @@
+package notifier
+
+import (
+	"context"
+	"expense-management-system/internal/domain"
+	"expense-management-system/pkg/logger"
+)
+
+// LogNotifier writes messages to the application log instead of sending
+// them. It is the default until a mail provider is configured; message bodies
+// may contain secrets such as reset links, so it is not meant for production.
+type LogNotifier struct{}
+
+func NewLogNotifier() domain.Notifier {
+	return &LogNotifier{}
+}
+
+func (n *LogNotifier) Send(ctx context.Context, to, subject, body string) error {
+	logger.InfoLogger.Printf("[EMAIL] To: %s | Subject: %s | %s", to, subject, body)
+	return nil
+}
@@ -0,0 +1,25 @@
+// Package ocrtest is a receipt extractor for tests that returns a fixed
+// result instead of reading the file.
+package ocrtest
+
+import (
+	"context"
+	"expense-management-system/internal/domain"
+	"io"
+)
+
+type Extractor struct {
+	Extraction *domain.ReceiptExtraction
+	Err        error
+
+	// ContentTypes records the content type of every receipt extracted.
+	ContentTypes []string
+}
+
+func (e *Extractor) Extract(ctx context.Context, contentType string, file io.Reader) (*domain.ReceiptExtraction, error) {
+	e.ContentTypes = append(e.ContentTypes, contentType)
+	if _, err := io.Copy(io.Discard, file); err != nil {
+		return nil, err
+	}
+	return e.Extraction, e.Err
+}
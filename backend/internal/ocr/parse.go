@@ -0,0 +1,241 @@
+// Package ocr reads receipts: Tesseract turns the image into text and Parse
+// picks the total, date, merchant and currency out of it.
+package ocr
+
+import (
+	"expense-management-system/internal/domain"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// totalKeywords are the labels of a receipt's total, most specific first.
+// Receipts repeat "total" on subtotal, tax and change lines, so the first
+// keyword found wins and its last occurrence is taken.
+var totalKeywords = []string{
+	"grand total",
+	"total bayar",
+	"total belanja",
+	"total tagihan",
+	"amount due",
+	"total due",
+	"jumlah",
+	"total",
+}
+
+// notTotal marks lines that carry the word total but not the total.
+var notTotal = regexp.MustCompile(`(?i)sub\s*-?\s*total|(total|jumlah)\s+(item|qty|barang|disc|diskon|hemat|tax|pajak|ppn)`)
+
+// amountPattern matches amounts written with thousands separators
+// (150.000, 1,250,000.50) or without (150000, 12.50).
+var amountPattern = regexp.MustCompile(`\d{1,3}(?:[.,]\d{3})+(?:[.,]\d{1,2})?|\d+(?:[.,]\d{1,2})?`)
+
+// currencyMarkers are checked in order, on the total line first and then
+// on the whole receipt.
+var currencyMarkers = []struct {
+	pattern  *regexp.Regexp
+	currency string
+}{
+	{regexp.MustCompile(`(?i)\b(rp|idr)\b|\brp\.?\s?\d`), domain.CurrencyIDR},
+	{regexp.MustCompile(`(?i)\bsgd\b|s\$`), "SGD"},
+	{regexp.MustCompile(`(?i)\bmyr\b|\brm\s?\d`), "MYR"},
+	{regexp.MustCompile(`(?i)\beur\b|€`), "EUR"},
+	{regexp.MustCompile(`(?i)\busd\b|us\$|\$`), "USD"},
+}
+
+var (
+	isoDate     = regexp.MustCompile(`\b(\d{4})-(\d{1,2})-(\d{1,2})\b`)
+	numericDate = regexp.MustCompile(`\b(\d{1,2})[/.-](\d{1,2})[/.-](\d{2}|\d{4})\b`)
+	namedDate   = regexp.MustCompile(`(?i)\b(\d{1,2})[\s-]+([a-z]{3})[a-z]*\.?[\s-]+(\d{4})\b`)
+)
+
+// months maps English and Indonesian month abbreviations.
+var months = map[string]time.Month{
+	"jan": time.January, "feb": time.February, "mar": time.March, "apr": time.April,
+	"may": time.May, "mei": time.May, "jun": time.June, "jul": time.July,
+	"aug": time.August, "agu": time.August, "agt": time.August, "sep": time.September,
+	"oct": time.October, "okt": time.October, "nov": time.November, "dec": time.December,
+	"des": time.December,
+}
+
+// merchantSkip marks header lines that are not the shop's name.
+var merchantSkip = regexp.MustCompile(`(?i)^(struk|receipt|invoice|nota|faktur|kwitansi|tax invoice|welcome|selamat datang)\b`)
+
+// Parse picks the fields out of a receipt's text. It returns nil when it
+// finds none.
+func Parse(text string) *domain.ReceiptExtraction {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	extraction := &domain.ReceiptExtraction{}
+	totalLine := ""
+	if total, line, ok := findTotal(lines); ok {
+		extraction.Total = &total
+		totalLine = line
+	}
+	if currency, ok := findCurrency(totalLine, text); ok {
+		extraction.Currency = &currency
+	}
+	if date, ok := findDate(text); ok {
+		extraction.Date = &date
+	}
+	if merchant, ok := findMerchant(lines); ok {
+		extraction.Merchant = &merchant
+	}
+
+	if extraction.Total == nil && extraction.Date == nil && extraction.Merchant == nil {
+		return nil
+	}
+	return extraction
+}
+
+func findTotal(lines []string) (float64, string, bool) {
+	for _, keyword := range totalKeywords {
+		for i := len(lines) - 1; i >= 0; i-- {
+			line := strings.ToLower(lines[i])
+			if !strings.Contains(line, keyword) || notTotal.MatchString(line) {
+				continue
+			}
+			// The amount is usually on the same line, sometimes on the
+			// next.
+			for _, candidate := range []string{line[strings.LastIndex(line, keyword)+len(keyword):], nextLine(lines, i)} {
+				if amount, ok := lastAmount(candidate); ok {
+					return amount, lines[i], true
+				}
+			}
+		}
+	}
+	return 0, "", false
+}
+
+func nextLine(lines []string, i int) string {
+	if i+1 < len(lines) {
+		return lines[i+1]
+	}
+	return ""
+}
+
+func lastAmount(s string) (float64, bool) {
+	matches := amountPattern.FindAllString(s, -1)
+	if len(matches) == 0 {
+		return 0, false
+	}
+	amount, err := parseAmount(matches[len(matches)-1])
+	if err != nil || amount <= 0 {
+		return 0, false
+	}
+	return amount, true
+}
+
+// parseAmount reads an amount in either the Indonesian (1.250.000,50) or
+// the English (1,250,000.50) style. A separator followed by exactly three
+// digits groups thousands; one followed by one or two digits at the end
+// starts the decimals.
+func parseAmount(s string) (float64, error) {
+	decimals := ""
+	if i := strings.LastIndexAny(s, ".,"); i >= 0 && len(s)-i-1 <= 2 {
+		decimals = s[i+1:]
+		s = s[:i]
+	}
+	digits := strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, s)
+	if decimals != "" {
+		digits += "." + decimals
+	}
+	return strconv.ParseFloat(digits, 64)
+}
+
+func findCurrency(totalLine, text string) (string, bool) {
+	for _, s := range []string{totalLine, text} {
+		for _, marker := range currencyMarkers {
+			if marker.pattern.MatchString(s) {
+				return marker.currency, true
+			}
+		}
+	}
+	return "", false
+}
+
+// findDate returns the first date on the receipt. Numeric dates are read
+// day first, as on Indonesian receipts.
+func findDate(text string) (string, bool) {
+	type candidate struct {
+		index   int
+		year    int
+		month   time.Month
+		day     int
+		matched bool
+	}
+	var best candidate
+
+	consider := func(index, year int, month time.Month, day int) {
+		if year < 100 {
+			year += 2000
+		}
+		date := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+		if date.Year() != year || date.Month() != month || date.Day() != day {
+			return
+		}
+		if !best.matched || index < best.index {
+			best = candidate{index: index, year: year, month: month, day: day, matched: true}
+		}
+	}
+
+	for _, m := range isoDate.FindAllStringSubmatchIndex(text, -1) {
+		consider(m[0], atoi(text[m[2]:m[3]]), time.Month(atoi(text[m[4]:m[5]])), atoi(text[m[6]:m[7]]))
+	}
+	for _, m := range numericDate.FindAllStringSubmatchIndex(text, -1) {
+		consider(m[0], atoi(text[m[6]:m[7]]), time.Month(atoi(text[m[4]:m[5]])), atoi(text[m[2]:m[3]]))
+	}
+	for _, m := range namedDate.FindAllStringSubmatchIndex(text, -1) {
+		if month, ok := months[strings.ToLower(text[m[4]:m[5]])]; ok {
+			consider(m[0], atoi(text[m[6]:m[7]]), month, atoi(text[m[2]:m[3]]))
+		}
+	}
+
+	if !best.matched {
+		return "", false
+	}
+	return time.Date(best.year, best.month, best.day, 0, 0, 0, 0, time.UTC).Format("2006-01-02"), true
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// findMerchant takes the first of the top lines that is mostly letters;
+// receipts start with the shop's name.
+func findMerchant(lines []string) (string, bool) {
+	for i, line := range lines {
+		if i >= 5 {
+			break
+		}
+		if merchantSkip.MatchString(line) {
+			continue
+		}
+		letters, others := 0, 0
+		for _, r := range line {
+			switch {
+			case unicode.IsLetter(r):
+				letters++
+			case !unicode.IsSpace(r):
+				others++
+			}
+		}
+		if letters >= 3 && letters > 2*others {
+			return strings.Join(strings.Fields(line), " "), true
+		}
+	}
+	return "", false
+}
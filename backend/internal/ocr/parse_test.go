@@ -0,0 +1,126 @@
+package ocr
+
+import (
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name         string
+		text         string
+		wantTotal    float64
+		wantCurrency string
+		wantDate     string
+		wantMerchant string
+	}{
+		{
+			name: "Indonesian minimarket",
+			text: `
+				STRUK BELANJA
+				INDOMARET SUDIRMAN
+				Jl. Jend. Sudirman No. 5, Jakarta
+				15.03.2025 14:30  ksr: ANI
+				AQUA 600ML     2 x 3.500     7.000
+				ROTI TAWAR              18.500
+				Sub Total              25.500
+				PPN                     2.805
+				TOTAL BELANJA          28.305
+				TUNAI                  50.000
+				KEMBALI                21.695`,
+			wantTotal:    28305,
+			wantCurrency: "",
+			wantDate:     "2025-03-15",
+			wantMerchant: "INDOMARET SUDIRMAN",
+		},
+		{
+			name: "Restaurant with rupiah marks",
+			text: `
+				Warteg Bahari
+				Tanggal: 11 Mei 2025
+				Nasi rames x2     Rp 50.000
+				Es teh x2         Rp 10.000
+				Total Item 4
+				Total             Rp 60.000`,
+			wantTotal:    60000,
+			wantCurrency: "IDR",
+			wantDate:     "2025-05-11",
+			wantMerchant: "Warteg Bahari",
+		},
+		{
+			name: "Amount on the line after the label",
+			text: `
+				PT KERETA API INDONESIA
+				2025-01-09
+				JUMLAH
+				IDR 1.250.000,00`,
+			wantTotal:    1250000,
+			wantCurrency: "IDR",
+			wantDate:     "2025-01-09",
+			wantMerchant: "PT KERETA API INDONESIA",
+		},
+		{
+			name: "Hotel abroad",
+			text: `
+				Marina Bay Hotel
+				Date: 03/02/2025
+				Room charge    SGD 320.00
+				Grand Total    S$ 348.80`,
+			wantTotal:    348.80,
+			wantCurrency: "SGD",
+			wantDate:     "2025-02-03",
+			wantMerchant: "Marina Bay Hotel",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Parse(tt.text)
+			if got == nil {
+				t.Fatal("Parse() = nil")
+			}
+			if got.Total == nil || *got.Total != tt.wantTotal {
+				t.Errorf("Total = %v, want %v", got.Total, tt.wantTotal)
+			}
+			if value(got.Currency) != tt.wantCurrency {
+				t.Errorf("Currency = %q, want %q", value(got.Currency), tt.wantCurrency)
+			}
+			if value(got.Date) != tt.wantDate {
+				t.Errorf("Date = %q, want %q", value(got.Date), tt.wantDate)
+			}
+			if value(got.Merchant) != tt.wantMerchant {
+				t.Errorf("Merchant = %q, want %q", value(got.Merchant), tt.wantMerchant)
+			}
+		})
+	}
+}
+
+func TestParseNothing(t *testing.T) {
+	for _, text := range []string{"", "   \n\n", "1234 5678\n--- ***"} {
+		if got := Parse(text); got != nil {
+			t.Errorf("Parse(%q) = %+v, want nil", text, got)
+		}
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	tests := map[string]float64{
+		"150.000":      150000,
+		"150,000":      150000,
+		"1.250.000,50": 1250000.50,
+		"1,250,000.50": 1250000.50,
+		"12.50":        12.50,
+		"75000":        75000,
+	}
+	for s, want := range tests {
+		if got, err := parseAmount(s); err != nil || got != want {
+			t.Errorf("parseAmount(%q) = %v, %v, want %v", s, got, err, want)
+		}
+	}
+}
+
+func value(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
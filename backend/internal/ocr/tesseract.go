@@ -0,0 +1,44 @@
+package ocr
+
+import (
+	"bytes"
+	"context"
+	"expense-management-system/internal/domain"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// Tesseract reads receipt images with the tesseract command line tool,
+// which must be installed with the trained data for Languages (e.g.
+// "eng+ind").
+type Tesseract struct {
+	Path      string
+	Languages string
+}
+
+func NewTesseract(path, languages string) *Tesseract {
+	return &Tesseract{Path: path, Languages: languages}
+}
+
+// Extract runs tesseract on an image. PDFs are not read; they would need
+// rendering to images first.
+func (t *Tesseract) Extract(ctx context.Context, contentType string, file io.Reader) (*domain.ReceiptExtraction, error) {
+	if !strings.HasPrefix(contentType, "image/") {
+		return nil, nil
+	}
+
+	// "stdin" and "stdout" make tesseract read the image from its input
+	// and print the text.
+	cmd := exec.CommandContext(ctx, t.Path, "stdin", "stdout", "-l", t.Languages)
+	cmd.Stdin = file
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("tesseract: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return Parse(stdout.String()), nil
+}
@@ -0,0 +1,327 @@
+// Package oidctest is a mock OpenID Connect issuer for tests and local
+// development. It signs ID tokens with a throwaway RSA key and lets the user
+// type any identity into a login form, or uses a fixed one set by a test.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"expense-management-system/internal/oidc"
+	"html/template"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const keyID = "mock-key-1"
+
+// Identity is the user the issuer vouches for.
+type Identity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Groups        []string
+}
+
+type authorization struct {
+	identity      Identity
+	clientID      string
+	redirectURI   string
+	nonce         string
+	codeChallenge string
+}
+
+type Issuer struct {
+	URL          string
+	ClientID     string
+	ClientSecret string
+
+	// TamperClaims, when set, can change ID token claims before signing, to
+	// test how invalid tokens are handled.
+	TamperClaims func(claims jwt.MapClaims)
+
+	key *rsa.PrivateKey
+	mux *http.ServeMux
+
+	mu       sync.Mutex
+	identity *Identity
+	codes    map[string]*authorization
+}
+
+func New(issuerURL, clientID, clientSecret string) (*Issuer, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+
+	i := &Issuer{
+		URL:          strings.TrimSuffix(issuerURL, "/"),
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		key:          key,
+		mux:          http.NewServeMux(),
+		codes:        map[string]*authorization{},
+	}
+	i.mux.HandleFunc("/.well-known/openid-configuration", i.discovery)
+	i.mux.HandleFunc("/jwks", i.jwks)
+	i.mux.HandleFunc("/authorize", i.authorize)
+	i.mux.HandleFunc("/token", i.token)
+
+	return i, nil
+}
+
+// NewTestServer starts an issuer on a local port; close the server when done.
+func NewTestServer(clientID, clientSecret string) (*Issuer, *httptest.Server, error) {
+	server := httptest.NewUnstartedServer(nil)
+	issuer, err := New("http://"+server.Listener.Addr().String(), clientID, clientSecret)
+	if err != nil {
+		return nil, nil, err
+	}
+	server.Config.Handler = issuer
+	server.Start()
+	return issuer, server, nil
+}
+
+func (i *Issuer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	i.mux.ServeHTTP(w, r)
+}
+
+// SetIdentity makes every authorization request log in as identity without
+// showing the login form. Pass nil to show the form again.
+func (i *Issuer) SetIdentity(identity *Identity) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.identity = identity
+}
+
+// Authorize follows an authorization URL like a browser would and returns
+// the code and state from the redirect back to the client.
+func (i *Issuer) Authorize(authURL string) (code, state string, err error) {
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+
+	location, err := resp.Location()
+	if err != nil {
+		return "", "", err
+	}
+	return location.Query().Get("code"), location.Query().Get("state"), nil
+}
+
+func (i *Issuer) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                i.URL,
+		"authorization_endpoint":                i.URL + "/authorize",
+		"token_endpoint":                        i.URL + "/token",
+		"jwks_uri":                              i.URL + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"scopes_supported":                      []string{"openid", "email", "profile", "groups"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (i *Issuer) jwks(w http.ResponseWriter, r *http.Request) {
+	pub := i.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": keyID,
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+var loginForm = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html><head><title>Mock OIDC login</title></head>
+<body style="font-family: sans-serif; max-width: 28rem; margin: 3rem auto">
+<h1>Mock identity provider</h1>
+<form method="get" action="/authorize">
+{{range $name, $value := .Params}}<input type="hidden" name="{{$name}}" value="{{$value}}">
+{{end}}
+<p><label>Email<br><input name="login_email" value="manager@example.com" size="40"></label></p>
+<p><label>Name<br><input name="login_name" value="Mock User" size="40"></label></p>
+<p><label>Groups (comma separated)<br><input name="login_groups" value="" size="40"></label></p>
+<p><button type="submit">Sign in</button></p>
+</form>
+</body></html>`))
+
+func (i *Issuer) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("response_type") != "code" || q.Get("client_id") != i.ClientID || q.Get("redirect_uri") == "" {
+		http.Error(w, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+	if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		http.Error(w, "PKCE with S256 is required", http.StatusBadRequest)
+		return
+	}
+
+	i.mu.Lock()
+	identity := i.identity
+	i.mu.Unlock()
+
+	if identity == nil && q.Get("login_email") != "" {
+		email := strings.TrimSpace(q.Get("login_email"))
+		identity = &Identity{
+			Subject:       "mock|" + strings.ToLower(email),
+			Email:         email,
+			EmailVerified: true,
+			Name:          q.Get("login_name"),
+			Groups:        splitGroups(q.Get("login_groups")),
+		}
+	}
+
+	if identity == nil {
+		params := map[string]string{}
+		for name := range q {
+			params[name] = q.Get(name)
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		loginForm.Execute(w, map[string]interface{}{"Params": params})
+		return
+	}
+
+	code := randomString()
+	i.mu.Lock()
+	i.codes[code] = &authorization{
+		identity:      *identity,
+		clientID:      q.Get("client_id"),
+		redirectURI:   q.Get("redirect_uri"),
+		nonce:         q.Get("nonce"),
+		codeChallenge: q.Get("code_challenge"),
+	}
+	i.mu.Unlock()
+
+	redirect, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	params := redirect.Query()
+	params.Set("code", code)
+	params.Set("state", q.Get("state"))
+	redirect.RawQuery = params.Encode()
+
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (i *Issuer) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, "invalid_request")
+		return
+	}
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if ok {
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	} else {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != i.ClientID || clientSecret != i.ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		tokenError(w, "unsupported_grant_type")
+		return
+	}
+
+	// Codes are single use, even when the exchange fails.
+	code := r.PostForm.Get("code")
+	i.mu.Lock()
+	auth, found := i.codes[code]
+	delete(i.codes, code)
+	i.mu.Unlock()
+
+	if !found || auth.clientID != clientID || auth.redirectURI != r.PostForm.Get("redirect_uri") {
+		tokenError(w, "invalid_grant")
+		return
+	}
+	if oidc.CodeChallenge(r.PostForm.Get("code_verifier")) != auth.codeChallenge {
+		tokenError(w, "invalid_grant")
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":            i.URL,
+		"sub":            auth.identity.Subject,
+		"aud":            clientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"nonce":          auth.nonce,
+		"email":          auth.identity.Email,
+		"email_verified": auth.identity.EmailVerified,
+		"name":           auth.identity.Name,
+		"groups":         auth.identity.Groups,
+	}
+	if i.TamperClaims != nil {
+		i.TamperClaims(claims)
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = keyID
+	idToken, err := token.SignedString(i.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+func tokenError(w http.ResponseWriter, code string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func randomString() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func splitGroups(value string) []string {
+	var groups []string
+	for _, group := range strings.Split(value, ",") {
+		if group = strings.TrimSpace(group); group != "" {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
@@ -0,0 +1,132 @@
+package oidc_test
+
+import (
+	"context"
+	"expense-management-system/internal/oidc"
+	"expense-management-system/internal/oidc/oidctest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newTestProvider(t *testing.T) (*oidctest.Issuer, *oidc.Provider) {
+	t.Helper()
+
+	issuer, server, err := oidctest.NewTestServer("expense-app", "expense-secret")
+	if err != nil {
+		t.Fatalf("NewTestServer() unexpected error = %v", err)
+	}
+	t.Cleanup(server.Close)
+
+	provider := oidc.NewProvider(oidc.Config{
+		IssuerURL:    issuer.URL,
+		ClientID:     "expense-app",
+		ClientSecret: "expense-secret",
+		RedirectURL:  "http://localhost:3000/auth/callback",
+	}).(*oidc.Provider)
+
+	issuer.SetIdentity(&oidctest.Identity{
+		Subject:       "user-123",
+		Email:         "manager@example.com",
+		EmailVerified: true,
+		Name:          "Manager",
+		Groups:        []string{"expense-managers", "staff"},
+	})
+
+	return issuer, provider
+}
+
+func login(t *testing.T, issuer *oidctest.Issuer, provider *oidc.Provider, nonce, verifier string) string {
+	t.Helper()
+
+	authURL, err := provider.AuthCodeURL(context.Background(), "state-1", nonce, verifier)
+	if err != nil {
+		t.Fatalf("AuthCodeURL() unexpected error = %v", err)
+	}
+	if !strings.Contains(authURL, "code_challenge="+oidc.CodeChallenge(verifier)) {
+		t.Errorf("Expected a PKCE challenge in %s", authURL)
+	}
+
+	code, state, err := issuer.Authorize(authURL)
+	if err != nil {
+		t.Fatalf("Authorize() unexpected error = %v", err)
+	}
+	if state != "state-1" {
+		t.Errorf("Authorize() state = %v, want state-1", state)
+	}
+	return code
+}
+
+func TestProvider_Exchange(t *testing.T) {
+	issuer, provider := newTestProvider(t)
+	code := login(t, issuer, provider, "nonce-1", "verifier-1")
+
+	claims, err := provider.Exchange(context.Background(), code, "verifier-1", "nonce-1")
+	if err != nil {
+		t.Fatalf("Exchange() unexpected error = %v", err)
+	}
+
+	if claims.Issuer != issuer.URL || claims.Subject != "user-123" || claims.Email != "manager@example.com" || !claims.EmailVerified {
+		t.Errorf("Unexpected claims %+v", claims)
+	}
+	if len(claims.Groups) != 2 || claims.Groups[0] != "expense-managers" {
+		t.Errorf("Exchange() groups = %v", claims.Groups)
+	}
+
+	if _, err := provider.Exchange(context.Background(), code, "verifier-1", "nonce-1"); err == nil {
+		t.Error("Expected a used code to be rejected")
+	}
+}
+
+func TestProvider_ExchangeRejectsInvalidTokens(t *testing.T) {
+	tests := []struct {
+		name     string
+		tamper   func(claims jwt.MapClaims)
+		nonce    string
+		verifier string
+	}{
+		{name: "Wrong nonce", nonce: "other-nonce"},
+		{name: "Wrong PKCE verifier", verifier: "other-verifier"},
+		{name: "Wrong audience", tamper: func(c jwt.MapClaims) { c["aud"] = "another-app" }},
+		{name: "Wrong issuer", tamper: func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }},
+		{name: "Expired", tamper: func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() }},
+		{name: "Missing subject", tamper: func(c jwt.MapClaims) { delete(c, "sub") }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issuer, provider := newTestProvider(t)
+			issuer.TamperClaims = tt.tamper
+
+			code := login(t, issuer, provider, "nonce-1", "verifier-1")
+
+			nonce, verifier := "nonce-1", "verifier-1"
+			if tt.nonce != "" {
+				nonce = tt.nonce
+			}
+			if tt.verifier != "" {
+				verifier = tt.verifier
+			}
+
+			if _, err := provider.Exchange(context.Background(), code, verifier, nonce); err == nil {
+				t.Error("Expected Exchange() to fail")
+			}
+		})
+	}
+}
+
+func TestProvider_DiscoveryIssuerMismatch(t *testing.T) {
+	issuer, _ := newTestProvider(t)
+
+	// Same server under another name: the metadata names a different issuer.
+	provider := oidc.NewProvider(oidc.Config{
+		IssuerURL: strings.Replace(issuer.URL, "127.0.0.1", "localhost", 1),
+		ClientID:  "expense-app",
+	})
+	_, err := provider.AuthCodeURL(context.Background(), "s", "n", "v")
+	if err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("Expected an issuer mismatch error, got %v", err)
+	}
+}
@@ -0,0 +1,335 @@
+// Package policy parses the JSON conditions of expense policy rules and
+// checks them against a submitted expense.
+//
+// A condition is a comparison of one expense field with a value,
+//
+//	{"field": "amount_idr", "op": "gt", "value": 500000}
+//
+// or a combination of conditions:
+//
+//	{"all": [...]}, {"any": [...]}, {"not": {...}}
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxDepth bounds how deeply conditions nest.
+const maxDepth = 8
+
+// Facts are what conditions can test about an expense being submitted.
+type Facts struct {
+	AmountIDR   int
+	Category    string
+	Description string
+	HasReceipt  bool
+	CashAdvance bool
+	// Role is the submitter's role.
+	Role string
+	// SubmittedAt gives the weekday, weekend and hour fields, in the
+	// server's time zone.
+	SubmittedAt time.Time
+}
+
+type fieldType int
+
+const (
+	numberField fieldType = iota
+	stringField
+	boolField
+)
+
+// fields maps each field name to its type and how it is read from Facts.
+var fields = map[string]struct {
+	typ   fieldType
+	value func(f *Facts) interface{}
+}{
+	"amount_idr":   {numberField, func(f *Facts) interface{} { return float64(f.AmountIDR) }},
+	"category":     {stringField, func(f *Facts) interface{} { return f.Category }},
+	"description":  {stringField, func(f *Facts) interface{} { return f.Description }},
+	"has_receipt":  {boolField, func(f *Facts) interface{} { return f.HasReceipt }},
+	"cash_advance": {boolField, func(f *Facts) interface{} { return f.CashAdvance }},
+	"role":         {stringField, func(f *Facts) interface{} { return f.Role }},
+	"weekday":      {stringField, func(f *Facts) interface{} { return strings.ToLower(f.SubmittedAt.Weekday().String()) }},
+	"weekend": {boolField, func(f *Facts) interface{} {
+		day := f.SubmittedAt.Weekday()
+		return day == time.Saturday || day == time.Sunday
+	}},
+	"hour": {numberField, func(f *Facts) interface{} { return float64(f.SubmittedAt.Hour()) }},
+}
+
+// operators lists the operators each field type supports.
+var operators = map[fieldType][]string{
+	numberField: {"eq", "ne", "gt", "gte", "lt", "lte", "in", "not_in"},
+	stringField: {"eq", "ne", "in", "not_in", "contains", "matches"},
+	boolField:   {"eq", "ne"},
+}
+
+// FieldNames returns the fields conditions can test, sorted.
+func FieldNames() []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Condition is a parsed condition.
+type Condition struct {
+	root node
+}
+
+type node interface {
+	match(f *Facts) bool
+}
+
+// Parse parses and checks a condition: fields must exist, operators must
+// suit the field and values must have its type.
+func Parse(expr []byte) (*Condition, error) {
+	root, err := parseNode(expr, 1)
+	if err != nil {
+		return nil, err
+	}
+	return &Condition{root: root}, nil
+}
+
+// Match reports whether the facts satisfy the condition.
+func (c *Condition) Match(f Facts) bool {
+	return c.root.match(&f)
+}
+
+type rawNode struct {
+	All   []json.RawMessage `json:"all"`
+	Any   []json.RawMessage `json:"any"`
+	Not   json.RawMessage   `json:"not"`
+	Field string            `json:"field"`
+	Op    string            `json:"op"`
+	Value json.RawMessage   `json:"value"`
+}
+
+func parseNode(expr []byte, depth int) (node, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("conditions nest more than %d deep", maxDepth)
+	}
+
+	var raw rawNode
+	decoder := json.NewDecoder(bytes.NewReader(expr))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("invalid condition: %v", err)
+	}
+
+	kinds := 0
+	for _, set := range []bool{raw.All != nil, raw.Any != nil, raw.Not != nil, raw.Field != ""} {
+		if set {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return nil, errors.New(`a condition needs exactly one of "all", "any", "not" or "field"`)
+	}
+
+	switch {
+	case raw.All != nil, raw.Any != nil:
+		children := raw.All
+		if raw.Any != nil {
+			children = raw.Any
+		}
+		if len(children) == 0 {
+			return nil, errors.New(`"all" and "any" need at least one condition`)
+		}
+		nodes := make([]node, len(children))
+		for i, child := range children {
+			n, err := parseNode(child, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			nodes[i] = n
+		}
+		if raw.All != nil {
+			return allOf(nodes), nil
+		}
+		return anyOf(nodes), nil
+
+	case raw.Not != nil:
+		n, err := parseNode(raw.Not, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		return notNode{n}, nil
+	}
+
+	return parseComparison(&raw)
+}
+
+type allOf []node
+
+func (n allOf) match(f *Facts) bool {
+	for _, child := range n {
+		if !child.match(f) {
+			return false
+		}
+	}
+	return true
+}
+
+type anyOf []node
+
+func (n anyOf) match(f *Facts) bool {
+	for _, child := range n {
+		if child.match(f) {
+			return true
+		}
+	}
+	return false
+}
+
+type notNode struct {
+	node
+}
+
+func (n notNode) match(f *Facts) bool {
+	return !n.node.match(f)
+}
+
+// comparison tests one field. Value holds a float64, string or bool, or a
+// slice of one of them for in and not_in.
+type comparison struct {
+	field   string
+	op      string
+	value   interface{}
+	pattern *regexp.Regexp
+}
+
+func parseComparison(raw *rawNode) (node, error) {
+	field, ok := fields[raw.Field]
+	if !ok {
+		return nil, fmt.Errorf("unknown field %q, use one of: %s", raw.Field, strings.Join(FieldNames(), ", "))
+	}
+	supported := false
+	for _, op := range operators[field.typ] {
+		supported = supported || op == raw.Op
+	}
+	if !supported {
+		return nil, fmt.Errorf("field %q supports the operators %s, not %q", raw.Field, strings.Join(operators[field.typ], ", "), raw.Op)
+	}
+	if raw.Value == nil {
+		return nil, fmt.Errorf("comparison of %q needs a value", raw.Field)
+	}
+
+	c := &comparison{field: raw.Field, op: raw.Op}
+	var err error
+	switch {
+	case raw.Op == "in" || raw.Op == "not_in":
+		c.value, err = parseList(raw.Value, field.typ)
+	default:
+		c.value, err = parseValue(raw.Value, field.typ)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("value for %q: %v", raw.Field, err)
+	}
+
+	if raw.Op == "matches" {
+		if c.pattern, err = regexp.Compile(c.value.(string)); err != nil {
+			return nil, fmt.Errorf("invalid pattern for %q: %v", raw.Field, err)
+		}
+	}
+	return c, nil
+}
+
+func parseValue(data json.RawMessage, typ fieldType) (interface{}, error) {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	switch v := value.(type) {
+	case float64:
+		if typ == numberField && !math.IsInf(v, 0) {
+			return v, nil
+		}
+	case string:
+		if typ == stringField {
+			return v, nil
+		}
+	case bool:
+		if typ == boolField {
+			return v, nil
+		}
+	}
+	return nil, fmt.Errorf("must be a %s", typ)
+}
+
+func parseList(data json.RawMessage, typ fieldType) (interface{}, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil || len(items) == 0 {
+		return nil, fmt.Errorf("must be a non-empty list of %ss", typ)
+	}
+	values := make([]interface{}, len(items))
+	for i, item := range items {
+		value, err := parseValue(item, typ)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+func (t fieldType) String() string {
+	switch t {
+	case numberField:
+		return "number"
+	case stringField:
+		return "string"
+	}
+	return "boolean"
+}
+
+func (c *comparison) match(f *Facts) bool {
+	actual := fields[c.field].value(f)
+
+	switch c.op {
+	case "eq":
+		return equal(actual, c.value)
+	case "ne":
+		return !equal(actual, c.value)
+	case "in", "not_in":
+		found := false
+		for _, value := range c.value.([]interface{}) {
+			found = found || equal(actual, value)
+		}
+		return found == (c.op == "in")
+	case "contains":
+		return strings.Contains(strings.ToLower(actual.(string)), strings.ToLower(c.value.(string)))
+	case "matches":
+		return c.pattern.MatchString(actual.(string))
+	}
+
+	a, b := actual.(float64), c.value.(float64)
+	switch c.op {
+	case "gt":
+		return a > b
+	case "gte":
+		return a >= b
+	case "lt":
+		return a < b
+	}
+	return a <= b
+}
+
+// equal compares strings without regard to case, and other values exactly.
+func equal(a, b interface{}) bool {
+	if s, ok := a.(string); ok {
+		return strings.EqualFold(s, b.(string))
+	}
+	return a == b
+}
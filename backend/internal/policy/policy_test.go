@@ -0,0 +1,87 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		wantErr string
+	}{
+		{"not JSON", `{"field":`, "invalid condition"},
+		{"unknown key", `{"field": "amount_idr", "op": "gt", "value": 1, "extra": 1}`, "invalid condition"},
+		{"empty", `{}`, "exactly one of"},
+		{"two kinds", `{"not": {"field": "weekend", "op": "eq", "value": true}, "field": "weekend"}`, "exactly one of"},
+		{"empty all", `{"all": []}`, "at least one"},
+		{"unknown field", `{"field": "merchant", "op": "eq", "value": "x"}`, "unknown field"},
+		{"operator for another type", `{"field": "weekend", "op": "gt", "value": true}`, "supports the operators"},
+		{"missing value", `{"field": "amount_idr", "op": "gt"}`, "needs a value"},
+		{"wrong value type", `{"field": "amount_idr", "op": "gt", "value": "500000"}`, "must be a number"},
+		{"in without list", `{"field": "category", "op": "in", "value": "meals"}`, "non-empty list"},
+		{"wrong list item", `{"field": "category", "op": "in", "value": ["meals", 3]}`, "must be a string"},
+		{"bad pattern", `{"field": "description", "op": "matches", "value": "PRJ-("}`, "invalid pattern"},
+		{"nested error", `{"all": [{"field": "weekend", "op": "eq", "value": true}, {"any": [{"field": "x", "op": "eq", "value": 1}]}]}`, "unknown field"},
+		{"too deep", strings.Repeat(`{"not": `, maxDepth) + `{"field": "weekend", "op": "eq", "value": true}` + strings.Repeat(`}`, maxDepth), "nest more than"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.expr))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Parse() error = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConditionMatch(t *testing.T) {
+	// A Saturday afternoon
+	saturday := time.Date(2025, 3, 15, 14, 30, 0, 0, time.Local)
+	tuesday := time.Date(2025, 3, 11, 9, 0, 0, 0, time.Local)
+	meal := Facts{AmountIDR: 750000, Category: "meals", Description: "Client dinner PRJ-0042", Role: "employee", SubmittedAt: tuesday}
+
+	mealsWithoutReceipt := `{"all": [
+		{"field": "category", "op": "eq", "value": "meals"},
+		{"field": "amount_idr", "op": "gt", "value": 500000},
+		{"field": "has_receipt", "op": "eq", "value": false}
+	]}`
+	missingProjectCode := `{"not": {"field": "description", "op": "matches", "value": "\\bPRJ-[0-9]{4}\\b"}}`
+
+	tests := []struct {
+		name  string
+		expr  string
+		facts func(f Facts) Facts
+		want  bool
+	}{
+		{"meal over 500k without receipt", mealsWithoutReceipt, func(f Facts) Facts { return f }, true},
+		{"meal over 500k with receipt", mealsWithoutReceipt, func(f Facts) Facts { f.HasReceipt = true; return f }, false},
+		{"meal at 500k", mealsWithoutReceipt, func(f Facts) Facts { f.AmountIDR = 500000; return f }, false},
+		{"category compares without case", mealsWithoutReceipt, func(f Facts) Facts { f.Category = "Meals"; return f }, true},
+		{"weekend", `{"field": "weekend", "op": "eq", "value": true}`, func(f Facts) Facts { f.SubmittedAt = saturday; return f }, true},
+		{"weekday", `{"field": "weekend", "op": "eq", "value": true}`, func(f Facts) Facts { return f }, false},
+		{"weekday name", `{"field": "weekday", "op": "in", "value": ["saturday", "sunday"]}`, func(f Facts) Facts { f.SubmittedAt = saturday; return f }, true},
+		{"hour", `{"field": "hour", "op": "lt", "value": 10}`, func(f Facts) Facts { return f }, true},
+		{"project code present", missingProjectCode, func(f Facts) Facts { return f }, false},
+		{"project code missing", missingProjectCode, func(f Facts) Facts { f.Description = "Client dinner"; return f }, true},
+		{"contains ignores case", `{"field": "description", "op": "contains", "value": "client"}`, func(f Facts) Facts { return f }, true},
+		{"not_in", `{"field": "role", "op": "not_in", "value": ["manager", "finance"]}`, func(f Facts) Facts { return f }, true},
+		{"any", `{"any": [{"field": "cash_advance", "op": "eq", "value": true}, {"field": "amount_idr", "op": "gte", "value": 750000}]}`, func(f Facts) Facts { return f }, true},
+		{"ne", `{"field": "amount_idr", "op": "ne", "value": 750000}`, func(f Facts) Facts { return f }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			condition, err := Parse([]byte(tt.expr))
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if got := condition.Match(tt.facts(meal)); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"expense-management-system/internal/domain"
+	"fmt"
+)
+
+type analyticsRepository struct {
+	db *sql.DB
+}
+
+func NewAnalyticsRepository(db *sql.DB) domain.AnalyticsRepository {
+	return &analyticsRepository{db: db}
+}
+
+// analyticsSource selects the expenses matching filter as e, each with the
+// manager's decision on it, if any, as a. The filter applies inside the
+// subquery, where its conditions refer to expenses alone.
+func analyticsSource(filter domain.ExpenseFilter) (string, []interface{}) {
+	q := buildExpenseQuery(filter)
+	source := `
+		FROM (
+			SELECT id, user_id, amount_idr, category, status, auto_approved, submitted_at
+			FROM expenses
+			` + q.whereClause() + `
+		) e
+		LEFT JOIN LATERAL (
+			SELECT approver_id, created_at
+			FROM approvals
+			WHERE expense_id = e.id
+			ORDER BY created_at DESC
+			LIMIT 1
+		) a ON TRUE`
+	return source, q.args
+}
+
+// turnaroundHours averages the time to a decision; NULL without decisions.
+const turnaroundHours = `AVG(EXTRACT(EPOCH FROM a.created_at - e.submitted_at)) / 3600`
+
+func (r *analyticsRepository) Summary(ctx context.Context, filter domain.ExpenseFilter) (*domain.AnalyticsSummary, error) {
+	source, args := analyticsSource(filter)
+	query := `
+		SELECT COUNT(*),
+		       COALESCE(SUM(e.amount_idr), 0),
+		       COALESCE(ROUND(AVG(e.amount_idr)), 0),
+		       COUNT(*) FILTER (WHERE e.auto_approved),
+		       COUNT(a.created_at),
+		       ` + turnaroundHours + source
+
+	summary := &domain.AnalyticsSummary{}
+	var turnaround sql.NullFloat64
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(
+		&summary.ExpenseCount,
+		&summary.TotalAmountIDR,
+		&summary.AverageAmountIDR,
+		&summary.AutoApprovedCount,
+		&summary.DecidedCount,
+		&turnaround,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if summary.ExpenseCount > 0 {
+		summary.AutoApprovalRate = float64(summary.AutoApprovedCount) / float64(summary.ExpenseCount)
+	}
+	if turnaround.Valid {
+		summary.AvgTurnaroundHours = &turnaround.Float64
+	}
+
+	return summary, nil
+}
+
+// analyticsDimension is how a breakdown groups: the key expression, the
+// user whose name labels a group, if any, and the order of the groups.
+type analyticsDimension struct {
+	key     string
+	labelOf string
+	orderBy string
+}
+
+// analyticsDimensions are the only groupings that reach the SQL.
+var analyticsDimensions = map[string]analyticsDimension{
+	domain.AnalyticsByMonth:    {key: "to_char(date_trunc('month', e.submitted_at), 'YYYY-MM')", orderBy: "key"},
+	domain.AnalyticsByStatus:   {key: "e.status", orderBy: "total DESC, key"},
+	domain.AnalyticsByCategory: {key: "e.category", orderBy: "total DESC, key"},
+	domain.AnalyticsByEmployee: {key: "e.user_id::text", labelOf: "e.user_id", orderBy: "total DESC, key"},
+	domain.AnalyticsByApprover: {key: "a.approver_id::text", labelOf: "a.approver_id", orderBy: "total DESC, key"},
+}
+
+func (r *analyticsRepository) Breakdown(ctx context.Context, filter domain.ExpenseFilter, dimension string, limit int) ([]*domain.AnalyticsGroup, error) {
+	d, ok := analyticsDimensions[dimension]
+	if !ok {
+		return nil, fmt.Errorf("%w: cannot group by %q", domain.ErrInvalidFilter, dimension)
+	}
+
+	source, args := analyticsSource(filter)
+	label := "''"
+	if d.labelOf != "" {
+		label = "COALESCE(MAX(u.name), '')"
+		source += `
+		LEFT JOIN users u ON u.id = ` + d.labelOf
+	}
+	if dimension == domain.AnalyticsByApprover {
+		// Auto-approved expenses have no approver.
+		source += `
+		WHERE a.approver_id IS NOT NULL`
+	}
+
+	query := `
+		SELECT ` + d.key + ` AS key, ` + label + `, COUNT(*), COALESCE(SUM(e.amount_idr), 0) AS total, ` + turnaroundHours +
+		source + `
+		GROUP BY 1
+		ORDER BY ` + d.orderBy
+	if limit > 0 {
+		args = append(args, limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := []*domain.AnalyticsGroup{}
+	for rows.Next() {
+		group := &domain.AnalyticsGroup{}
+		var turnaround sql.NullFloat64
+		if err := rows.Scan(&group.Key, &group.Label, &group.ExpenseCount, &group.TotalAmountIDR, &turnaround); err != nil {
+			return nil, err
+		}
+		if turnaround.Valid {
+			group.AvgTurnaroundHours = &turnaround.Float64
+		}
+		groups = append(groups, group)
+	}
+
+	return groups, rows.Err()
+}
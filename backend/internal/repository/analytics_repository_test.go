@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"expense-management-system/internal/domain"
+	"strings"
+	"testing"
+)
+
+func TestAnalyticsSource(t *testing.T) {
+	submitter := 4
+	source, args := analyticsSource(domain.ExpenseFilter{
+		SubmitterID: &submitter,
+		Statuses:    []string{domain.StatusCompleted},
+		SortBy:      domain.ExpenseSortAmount,
+	})
+
+	// The filter must apply to expenses before approvals are joined, where
+	// status and id would be ambiguous.
+	inner := source[:strings.Index(source, ") e")]
+	if !strings.Contains(inner, "WHERE user_id = $1 AND status = ANY($2)") {
+		t.Errorf("Expected the filter inside the subquery, got %s", source)
+	}
+	if len(args) != 2 {
+		t.Errorf("Expected 2 arguments, got %v", args)
+	}
+	if strings.Contains(source, "ORDER BY amount_idr") {
+		t.Error("Expected the sort of the filter to be ignored")
+	}
+}
+
+func TestAnalyticsRepository_BreakdownUnknownDimension(t *testing.T) {
+	repo := NewAnalyticsRepository(nil)
+	_, err := repo.Breakdown(context.Background(), domain.ExpenseFilter{}, "e.user_id; DROP TABLE expenses", 10)
+	if !errors.Is(err, domain.ErrInvalidFilter) {
+		t.Errorf("Breakdown() error = %v, want ErrInvalidFilter", err)
+	}
+}
@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"expense-management-system/internal/domain"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type apiKeyRepository struct {
+	db *sql.DB
+}
+
+func NewAPIKeyRepository(db *sql.DB) domain.APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+const apiKeyColumns = `id, service_account_id, name, prefix, key_hash, scopes, expires_at,
+	last_used_at, last_used_ip, created_by, created_at, revoked_at, replaced_by_id`
+
+func scanAPIKey(row rowScanner) (*domain.APIKey, error) {
+	key := &domain.APIKey{}
+	err := row.Scan(
+		&key.ID,
+		&key.ServiceAccountID,
+		&key.Name,
+		&key.Prefix,
+		&key.KeyHash,
+		pq.Array(&key.Scopes),
+		&key.ExpiresAt,
+		&key.LastUsedAt,
+		&key.LastUsedIP,
+		&key.CreatedBy,
+		&key.CreatedAt,
+		&key.RevokedAt,
+		&key.ReplacedByID,
+	)
+	if key.Scopes == nil {
+		key.Scopes = []string{}
+	}
+	return key, err
+}
+
+func (r *apiKeyRepository) Create(ctx context.Context, key *domain.APIKey) error {
+	return createAPIKey(ctx, r.db.QueryRowContext, key)
+}
+
+func (r *apiKeyRepository) GetByID(ctx context.Context, id int) (*domain.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE id = $1`
+
+	key, err := scanAPIKey(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, errors.New("api key not found")
+	}
+
+	return key, err
+}
+
+func (r *apiKeyRepository) GetByPrefix(ctx context.Context, prefix string) (*domain.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE prefix = $1`
+
+	key, err := scanAPIKey(r.db.QueryRowContext(ctx, query, prefix))
+	if err == sql.ErrNoRows {
+		return nil, errors.New("api key not found")
+	}
+
+	return key, err
+}
+
+func (r *apiKeyRepository) ListByServiceAccount(ctx context.Context, serviceAccountID int) ([]*domain.APIKey, error) {
+	query := `
+		SELECT ` + apiKeyColumns + `
+		FROM api_keys
+		WHERE service_account_id = $1
+		ORDER BY created_at DESC, id DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, serviceAccountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []*domain.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+func (r *apiKeyRepository) Rotate(ctx context.Context, oldID int, newKey *domain.APIKey, oldExpiresAt time.Time) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := createAPIKey(ctx, tx.QueryRowContext, newKey); err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE api_keys
+		SET replaced_by_id = $2,
+		    expires_at = LEAST(COALESCE(expires_at, $3), $3)
+		WHERE id = $1 AND revoked_at IS NULL AND replaced_by_id IS NULL`, oldID, newKey.ID, oldExpiresAt)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return errors.New("api key was already rotated or revoked")
+	}
+
+	return tx.Commit()
+}
+
+func (r *apiKeyRepository) Revoke(ctx context.Context, id int) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE api_keys
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND revoked_at IS NULL`, id)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+func (r *apiKeyRepository) TouchLastUsed(ctx context.Context, id int, ipAddress string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE api_keys
+		SET last_used_at = CURRENT_TIMESTAMP, last_used_ip = $2
+		WHERE id = $1
+		  AND (last_used_at IS NULL
+		       OR last_used_at < CURRENT_TIMESTAMP - INTERVAL '1 minute'
+		       OR last_used_ip IS DISTINCT FROM $2)`, id, ipAddress)
+	return err
+}
+
+func createAPIKey(ctx context.Context, queryRow func(context.Context, string, ...interface{}) *sql.Row, key *domain.APIKey) error {
+	query := `
+		INSERT INTO api_keys (service_account_id, name, prefix, key_hash, scopes, expires_at, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at`
+
+	return queryRow(ctx, query,
+		key.ServiceAccountID,
+		key.Name,
+		key.Prefix,
+		key.KeyHash,
+		pq.Array(key.Scopes),
+		key.ExpiresAt,
+		key.CreatedBy,
+	).Scan(&key.ID, &key.CreatedAt)
+}
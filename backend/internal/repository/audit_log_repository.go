@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"expense-management-system/internal/domain"
+	"fmt"
 )
 
 type auditLogRepository struct {
@@ -26,31 +27,95 @@ func (r *auditLogRepository) Create(ctx context.Context, log *domain.AuditLog) e
 		}
 	}
 
+	if log.APIKeyID == nil {
+		if keyID, ok := domain.APIKeyIDFromContext(ctx); ok {
+			log.APIKeyID = &keyID
+		}
+	}
+
 	query := `
-		INSERT INTO audit_logs (expense_id, user_id, action, old_status, new_status, metadata)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO audit_logs (expense_id, cash_advance_id, subject_user_id, user_id, action, old_status, new_status, metadata, api_key_id)
+		VALUES (NULLIF($1, 0), $2, $3, $4, $5, $6, $7, $8, $9)
 		RETURNING id, created_at`
 
 	err = r.db.QueryRowContext(ctx, query,
 		log.ExpenseID,
+		log.CashAdvanceID,
+		log.SubjectUserID,
 		log.UserID,
 		log.Action,
 		log.OldStatus,
 		log.NewStatus,
 		metadataJSON,
+		log.APIKeyID,
 	).Scan(&log.ID, &log.CreatedAt)
 
 	return err
 }
 
 func (r *auditLogRepository) GetByExpenseID(ctx context.Context, expenseID int) ([]*domain.AuditLog, error) {
+	return r.query(ctx, "expense_id = $1", 0, expenseID)
+}
+
+// auditLogSort names the order audit log cursors are made for.
+const auditLogSort = "created_at"
+
+func (r *auditLogRepository) GetBySubjectUserID(ctx context.Context, userID int, page domain.PageRequest) (*domain.AuditLogPage, error) {
+	if page.Limit < 1 {
+		logs, err := r.query(ctx, "subject_user_id = $1", 0, userID)
+		if err != nil {
+			return nil, err
+		}
+		return &domain.AuditLogPage{AuditLogs: logs}, nil
+	}
+
+	where := "subject_user_id = $1"
+	args := []interface{}{userID}
+	if page.Cursor != "" {
+		cursor, err := decodeCursor(page.Cursor, auditLogSort, domain.SortAsc)
+		if err != nil {
+			return nil, err
+		}
+		if cursor.Value == nil {
+			return nil, fmt.Errorf("%w: invalid cursor", domain.ErrInvalidFilter)
+		}
+		where += " AND (created_at, id) > ($2::timestamp, $3)"
+		args = append(args, *cursor.Value, cursor.ID)
+	}
+
+	logs, err := r.query(ctx, where, page.Limit+1, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &domain.AuditLogPage{AuditLogs: logs}
+	if len(logs) > page.Limit {
+		result.AuditLogs = logs[:page.Limit]
+		last := result.AuditLogs[page.Limit-1]
+		result.NextCursor = encodeCursor(pageCursor{
+			Sort:  auditLogSort,
+			Order: domain.SortAsc,
+			Value: cursorTime(last.CreatedAt),
+			ID:    last.ID,
+		})
+	}
+	return result, nil
+}
+
+// query lists the logs matching where, oldest first; at most limit of them
+// unless it is 0.
+func (r *auditLogRepository) query(ctx context.Context, where string, limit int, args ...interface{}) ([]*domain.AuditLog, error) {
 	query := `
-		SELECT id, expense_id, user_id, action, old_status, new_status, metadata, created_at
+		SELECT id, COALESCE(expense_id, 0), cash_advance_id, subject_user_id, user_id, action, old_status, new_status, metadata, api_key_id, created_at
 		FROM audit_logs
-		WHERE expense_id = $1
-		ORDER BY created_at ASC`
+		WHERE ` + where + `
+		ORDER BY created_at ASC, id ASC`
+	if limit > 0 {
+		args = append(args, limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
 
-	rows, err := r.db.QueryContext(ctx, query, expenseID)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -65,11 +130,14 @@ func (r *auditLogRepository) GetByExpenseID(ctx context.Context, expenseID int)
 		err := rows.Scan(
 			&log.ID,
 			&log.ExpenseID,
+			&log.CashAdvanceID,
+			&log.SubjectUserID,
 			&log.UserID,
 			&log.Action,
 			&log.OldStatus,
 			&log.NewStatus,
 			&metadataJSON,
+			&log.APIKeyID,
 			&log.CreatedAt,
 		)
 		if err != nil {
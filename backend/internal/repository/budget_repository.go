@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"expense-management-system/internal/domain"
+	"time"
+)
+
+type budgetRepository struct {
+	db *sql.DB
+}
+
+func NewBudgetRepository(db *sql.DB) domain.BudgetRepository {
+	return &budgetRepository{db: db}
+}
+
+const budgetColumns = `id, user_id, team_id, category, period, soft_limit_idr, hard_limit_idr,
+		       hard_limit_action, created_by, created_at, updated_at`
+
+func scanBudget(row rowScanner) (*domain.Budget, error) {
+	budget := &domain.Budget{}
+	err := row.Scan(
+		&budget.ID,
+		&budget.UserID,
+		&budget.TeamID,
+		&budget.Category,
+		&budget.Period,
+		&budget.SoftLimitIDR,
+		&budget.HardLimitIDR,
+		&budget.HardLimitAction,
+		&budget.CreatedBy,
+		&budget.CreatedAt,
+		&budget.UpdatedAt,
+	)
+	return budget, err
+}
+
+func (r *budgetRepository) Create(ctx context.Context, budget *domain.Budget) error {
+	query := `
+		INSERT INTO budgets (user_id, team_id, category, period, soft_limit_idr, hard_limit_idr, hard_limit_action, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at, updated_at`
+
+	err := r.db.QueryRowContext(ctx, query,
+		budget.UserID,
+		budget.TeamID,
+		budget.Category,
+		budget.Period,
+		budget.SoftLimitIDR,
+		budget.HardLimitIDR,
+		budget.HardLimitAction,
+		budget.CreatedBy,
+	).Scan(&budget.ID, &budget.CreatedAt, &budget.UpdatedAt)
+
+	return err
+}
+
+func (r *budgetRepository) GetByID(ctx context.Context, id int) (*domain.Budget, error) {
+	query := `
+		SELECT ` + budgetColumns + `
+		FROM budgets
+		WHERE id = $1`
+
+	budget, err := scanBudget(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, errors.New("budget not found")
+	}
+
+	return budget, err
+}
+
+func (r *budgetRepository) List(ctx context.Context) ([]*domain.Budget, error) {
+	query := `
+		SELECT ` + budgetColumns + `
+		FROM budgets
+		ORDER BY id`
+
+	return r.query(ctx, query)
+}
+
+func (r *budgetRepository) Delete(ctx context.Context, id int) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM budgets WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errors.New("budget not found")
+	}
+
+	return nil
+}
+
+func (r *budgetRepository) GetApplicable(ctx context.Context, userID int, teamID *int, category *string) ([]*domain.Budget, error) {
+	query := `
+		SELECT ` + budgetColumns + `
+		FROM budgets
+		WHERE (user_id IS NULL OR user_id = $1)
+		  AND (team_id IS NULL OR team_id = $2)
+		  AND (category IS NULL OR $3::text IS NULL OR category = $3)
+		ORDER BY id`
+
+	return r.query(ctx, query, userID, teamID, category)
+}
+
+// GetConsumption sums approved, completed and partially refunded expenses in
+// [from, to) that fall under the budget's user, team and category. Completed
+// refunds are taken off what an expense consumes.
+func (r *budgetRepository) GetConsumption(ctx context.Context, budget *domain.Budget, from, to time.Time) (int, error) {
+	query := `
+		SELECT COALESCE(SUM(e.amount_idr - COALESCE(rf.refunded_idr, 0)), 0)
+		FROM expenses e
+		JOIN users u ON u.id = e.user_id
+		LEFT JOIN (
+			SELECT expense_id, SUM(amount_idr) AS refunded_idr
+			FROM refunds
+			WHERE status = $4
+			GROUP BY expense_id
+		) rf ON rf.expense_id = e.id
+		WHERE e.status IN ($1, $2, $3)
+		  AND e.submitted_at >= $5 AND e.submitted_at < $6
+		  AND ($7::int IS NULL OR e.user_id = $7)
+		  AND ($8::int IS NULL OR u.team_id = $8)
+		  AND ($9::text IS NULL OR e.category = $9)`
+
+	var consumed int
+	err := r.db.QueryRowContext(ctx, query,
+		domain.StatusApproved,
+		domain.StatusCompleted,
+		domain.StatusPartiallyRefunded,
+		domain.RefundStatusCompleted,
+		from,
+		to,
+		budget.UserID,
+		budget.TeamID,
+		budget.Category,
+	).Scan(&consumed)
+
+	return consumed, err
+}
+
+func (r *budgetRepository) query(ctx context.Context, query string, args ...interface{}) ([]*domain.Budget, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var budgets []*domain.Budget
+	for rows.Next() {
+		budget, err := scanBudget(rows)
+		if err != nil {
+			return nil, err
+		}
+		budgets = append(budgets, budget)
+	}
+
+	return budgets, nil
+}
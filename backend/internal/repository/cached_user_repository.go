@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"context"
+	"expense-management-system/internal/domain"
+	"sync"
+	"time"
+)
+
+// maxCachedUsers bounds the cache; when it is full, expired entries are
+// dropped and, if that is not enough, the whole cache starts over.
+const maxCachedUsers = 10000
+
+// cachedUserRepository keeps users looked up by ID in memory, since every
+// authenticated request loads its user. Writes through the repository drop
+// the user from the cache; changes made by other API instances show up
+// after the TTL at the latest.
+type cachedUserRepository struct {
+	domain.UserRepository
+	ttl time.Duration
+	now func() time.Time
+
+	mu         sync.Mutex
+	entries    map[int]cachedUser
+	loading    map[int]*userLoad
+	generation uint64
+}
+
+type cachedUser struct {
+	user      domain.User
+	expiresAt time.Time
+}
+
+// userLoad is a lookup in progress that concurrent requests for the same
+// user wait for instead of querying the database themselves.
+type userLoad struct {
+	done chan struct{}
+	user *domain.User
+	err  error
+}
+
+// NewCachedUserRepository caches GetByID results of repo for ttl. A ttl of
+// zero or less turns the cache off.
+func NewCachedUserRepository(repo domain.UserRepository, ttl time.Duration) domain.UserRepository {
+	if ttl <= 0 {
+		return repo
+	}
+	return &cachedUserRepository{
+		UserRepository: repo,
+		ttl:            ttl,
+		now:            time.Now,
+		entries:        make(map[int]cachedUser),
+		loading:        make(map[int]*userLoad),
+	}
+}
+
+// GetByID returns a copy, so callers may change the user they get.
+func (r *cachedUserRepository) GetByID(ctx context.Context, id int) (*domain.User, error) {
+	r.mu.Lock()
+	if entry, ok := r.entries[id]; ok && r.now().Before(entry.expiresAt) {
+		r.mu.Unlock()
+		return copyUser(&entry.user), nil
+	}
+	if load, ok := r.loading[id]; ok {
+		r.mu.Unlock()
+		select {
+		case <-load.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if load.err != nil {
+			return nil, load.err
+		}
+		return copyUser(load.user), nil
+	}
+	load := &userLoad{done: make(chan struct{})}
+	r.loading[id] = load
+	generation := r.generation
+	r.mu.Unlock()
+
+	load.user, load.err = r.UserRepository.GetByID(ctx, id)
+
+	r.mu.Lock()
+	delete(r.loading, id)
+	// A write during the lookup may have changed the user after it was
+	// read, so the result is only kept if nothing was invalidated since.
+	if load.err == nil && generation == r.generation {
+		r.store(load.user)
+	}
+	r.mu.Unlock()
+	close(load.done)
+
+	if load.err != nil {
+		return nil, load.err
+	}
+	return copyUser(load.user), nil
+}
+
+func (r *cachedUserRepository) Update(ctx context.Context, user *domain.User) error {
+	defer r.invalidate(user.ID)
+	return r.UserRepository.Update(ctx, user)
+}
+
+func (r *cachedUserRepository) UpdateRole(ctx context.Context, id int, role string) error {
+	defer r.invalidate(id)
+	return r.UserRepository.UpdateRole(ctx, id, role)
+}
+
+func (r *cachedUserRepository) UpdatePassword(ctx context.Context, id int, passwordHash string) error {
+	defer r.invalidate(id)
+	return r.UserRepository.UpdatePassword(ctx, id, passwordHash)
+}
+
+func (r *cachedUserRepository) SetActive(ctx context.Context, id int, active bool) error {
+	defer r.invalidate(id)
+	return r.UserRepository.SetActive(ctx, id, active)
+}
+
+func (r *cachedUserRepository) RecordLoginFailure(ctx context.Context, id int, maxAttempts int, windowStart, lockUntil time.Time) (*domain.User, error) {
+	defer r.invalidate(id)
+	return r.UserRepository.RecordLoginFailure(ctx, id, maxAttempts, windowStart, lockUntil)
+}
+
+func (r *cachedUserRepository) ResetLoginFailures(ctx context.Context, id int) error {
+	defer r.invalidate(id)
+	return r.UserRepository.ResetLoginFailures(ctx, id)
+}
+
+func (r *cachedUserRepository) invalidate(id int) {
+	r.mu.Lock()
+	delete(r.entries, id)
+	r.generation++
+	r.mu.Unlock()
+}
+
+// store must be called with mu held.
+func (r *cachedUserRepository) store(user *domain.User) {
+	now := r.now()
+	if len(r.entries) >= maxCachedUsers {
+		for id, entry := range r.entries {
+			if !now.Before(entry.expiresAt) {
+				delete(r.entries, id)
+			}
+		}
+		if len(r.entries) >= maxCachedUsers {
+			r.entries = make(map[int]cachedUser)
+		}
+	}
+	r.entries[user.ID] = cachedUser{user: *copyUser(user), expiresAt: now.Add(r.ttl)}
+}
+
+// copyUser also copies the pointer fields, so no two callers share them.
+func copyUser(user *domain.User) *domain.User {
+	c := *user
+	if user.TeamID != nil {
+		teamID := *user.TeamID
+		c.TeamID = &teamID
+	}
+	if user.LastFailedLoginAt != nil {
+		t := *user.LastFailedLoginAt
+		c.LastFailedLoginAt = &t
+	}
+	if user.LockedUntil != nil {
+		t := *user.LockedUntil
+		c.LockedUntil = &t
+	}
+	return &c
+}
@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"expense-management-system/internal/domain"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingUserRepo stands in for the database and counts its lookups.
+type countingUserRepo struct {
+	domain.UserRepository
+	lookups int64
+	delay   time.Duration
+
+	mu    sync.Mutex
+	users map[int]*domain.User
+}
+
+func newCountingUserRepo() *countingUserRepo {
+	teamID := 7
+	return &countingUserRepo{users: map[int]*domain.User{
+		1: {ID: 1, Email: "user@example.com", Role: domain.RoleEmployee, TeamID: &teamID, Active: true},
+	}}
+}
+
+func (r *countingUserRepo) GetByID(ctx context.Context, id int) (*domain.User, error) {
+	atomic.AddInt64(&r.lookups, 1)
+	time.Sleep(r.delay)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user, ok := r.users[id]
+	if !ok {
+		return nil, errors.New("user not found")
+	}
+	c := *user
+	return &c, nil
+}
+
+func (r *countingUserRepo) SetActive(ctx context.Context, id int, active bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.users[id].Active = active
+	return nil
+}
+
+func (r *countingUserRepo) UpdateRole(ctx context.Context, id int, role string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.users[id].Role = role
+	return nil
+}
+
+func TestCachedUserRepository_GetByID(t *testing.T) {
+	ctx := context.Background()
+	inner := newCountingUserRepo()
+	repo := NewCachedUserRepository(inner, time.Minute).(*cachedUserRepository)
+	now := time.Now()
+	repo.now = func() time.Time { return now }
+
+	first, err := repo.GetByID(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetByID() unexpected error = %v", err)
+	}
+	// Callers get their own copy to change.
+	first.Role = domain.RoleAdmin
+	*first.TeamID = 8
+
+	second, _ := repo.GetByID(ctx, 1)
+	if second.Role != domain.RoleEmployee || *second.TeamID != 7 {
+		t.Errorf("Expected the cached user to be unchanged, got role %s team %d", second.Role, *second.TeamID)
+	}
+	if inner.lookups != 1 {
+		t.Errorf("Expected 1 database lookup, got %d", inner.lookups)
+	}
+
+	now = now.Add(time.Minute)
+	repo.GetByID(ctx, 1)
+	if inner.lookups != 2 {
+		t.Errorf("Expected the user to be loaded again after the TTL, got %d lookups", inner.lookups)
+	}
+
+	if _, err := repo.GetByID(ctx, 2); err == nil {
+		t.Error("Expected an error for an unknown user")
+	}
+	if _, err := repo.GetByID(ctx, 2); err == nil || inner.lookups != 4 {
+		t.Errorf("Expected errors not to be cached, got %d lookups", inner.lookups)
+	}
+}
+
+func TestCachedUserRepository_InvalidatesOnWrite(t *testing.T) {
+	ctx := context.Background()
+	inner := newCountingUserRepo()
+	repo := NewCachedUserRepository(inner, time.Minute)
+
+	repo.GetByID(ctx, 1)
+	repo.SetActive(ctx, 1, false)
+	user, _ := repo.GetByID(ctx, 1)
+	if user.Active {
+		t.Error("Expected a deactivated user to be seen at once")
+	}
+
+	repo.UpdateRole(ctx, 1, domain.RoleManager)
+	user, _ = repo.GetByID(ctx, 1)
+	if user.Role != domain.RoleManager {
+		t.Errorf("Expected the new role, got %s", user.Role)
+	}
+	if inner.lookups != 3 {
+		t.Errorf("Expected 3 database lookups, got %d", inner.lookups)
+	}
+}
+
+func TestCachedUserRepository_ConcurrentMisses(t *testing.T) {
+	ctx := context.Background()
+	inner := newCountingUserRepo()
+	inner.delay = 20 * time.Millisecond
+	repo := NewCachedUserRepository(inner, time.Minute)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := repo.GetByID(ctx, 1); err != nil {
+				t.Errorf("GetByID() unexpected error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if inner.lookups != 1 {
+		t.Errorf("Expected concurrent requests to share 1 lookup, got %d", inner.lookups)
+	}
+}
+
+func TestNewCachedUserRepository_Disabled(t *testing.T) {
+	inner := newCountingUserRepo()
+	if repo := NewCachedUserRepository(inner, 0); repo != domain.UserRepository(inner) {
+		t.Error("Expected a zero TTL to return the repository unchanged")
+	}
+}
@@ -0,0 +1,220 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"expense-management-system/internal/domain"
+)
+
+type cashAdvanceRepository struct {
+	db *sql.DB
+}
+
+func NewCashAdvanceRepository(db *sql.DB) domain.CashAdvanceRepository {
+	return &cashAdvanceRepository{db: db}
+}
+
+const cashAdvanceColumns = `id, user_id, amount_idr, purpose, status, outstanding_idr, recovered_idr,
+		       approver_id, approval_notes, submitted_at, processed_at, payment_id, payment_external_id,
+		       created_at, updated_at`
+
+func scanCashAdvance(row rowScanner) (*domain.CashAdvance, error) {
+	advance := &domain.CashAdvance{}
+	err := row.Scan(
+		&advance.ID,
+		&advance.UserID,
+		&advance.AmountIDR,
+		&advance.Purpose,
+		&advance.Status,
+		&advance.OutstandingIDR,
+		&advance.RecoveredIDR,
+		&advance.ApproverID,
+		&advance.ApprovalNotes,
+		&advance.SubmittedAt,
+		&advance.ProcessedAt,
+		&advance.PaymentID,
+		&advance.PaymentExternalID,
+		&advance.CreatedAt,
+		&advance.UpdatedAt,
+	)
+	return advance, err
+}
+
+func (r *cashAdvanceRepository) Create(ctx context.Context, advance *domain.CashAdvance) error {
+	query := `
+		INSERT INTO cash_advances (user_id, amount_idr, purpose, status, payment_external_id)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, submitted_at, created_at, updated_at`
+
+	err := r.db.QueryRowContext(ctx, query,
+		advance.UserID,
+		advance.AmountIDR,
+		advance.Purpose,
+		advance.Status,
+		advance.PaymentExternalID,
+	).Scan(&advance.ID, &advance.SubmittedAt, &advance.CreatedAt, &advance.UpdatedAt)
+
+	return err
+}
+
+func (r *cashAdvanceRepository) GetByID(ctx context.Context, id int) (*domain.CashAdvance, error) {
+	query := `
+		SELECT ` + cashAdvanceColumns + `
+		FROM cash_advances
+		WHERE id = $1`
+
+	advance, err := scanCashAdvance(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, errors.New("cash advance not found")
+	}
+
+	return advance, err
+}
+
+// List returns advances for userID, or for everyone when userID is 0.
+func (r *cashAdvanceRepository) List(ctx context.Context, userID int, status string, limit, offset int) ([]*domain.CashAdvance, int, error) {
+	var advances []*domain.CashAdvance
+	var total int
+
+	whereClause := "WHERE ($1 = 0 OR user_id = $1) AND ($2 = '' OR status = $2)"
+
+	countQuery := "SELECT COUNT(*) FROM cash_advances " + whereClause
+	if err := r.db.QueryRowContext(ctx, countQuery, userID, status).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT ` + cashAdvanceColumns + `
+		FROM cash_advances
+		` + whereClause + `
+		ORDER BY submitted_at DESC
+		LIMIT $3 OFFSET $4`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, status, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		advance, err := scanCashAdvance(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		advances = append(advances, advance)
+	}
+
+	return advances, total, nil
+}
+
+func (r *cashAdvanceRepository) UpdateApproval(ctx context.Context, id int, status string, approverID int, notes *string) error {
+	query := `
+		UPDATE cash_advances
+		SET status = $1, approver_id = $2, approval_notes = $3,
+		    processed_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $4 AND status = $5`
+
+	result, err := r.db.ExecContext(ctx, query, status, approverID, notes, id, domain.AdvanceStatusAwaitingApproval)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errors.New("cash advance is not awaiting approval")
+	}
+
+	return nil
+}
+
+// MarkPaid records the payout and opens the full amount for settlement.
+func (r *cashAdvanceRepository) MarkPaid(ctx context.Context, id int, paymentID string) error {
+	query := `
+		UPDATE cash_advances
+		SET status = $1, outstanding_idr = amount_idr, payment_id = NULLIF($2, ''),
+		    processed_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $3 AND status = $4`
+
+	_, err := r.db.ExecContext(ctx, query, domain.AdvanceStatusPaid, paymentID, id, domain.AdvanceStatusApproved)
+	return err
+}
+
+// ApplySettlement draws up to amountIDR from the advance's outstanding balance
+// for an expense and returns how much was covered. The advance row is locked
+// so concurrent settlements cannot overdraw it; it becomes settled once
+// nothing is left.
+func (r *cashAdvanceRepository) ApplySettlement(ctx context.Context, id int, expenseID int, amountIDR int) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var outstanding int
+	var status string
+	err = tx.QueryRowContext(ctx, "SELECT outstanding_idr, status FROM cash_advances WHERE id = $1 FOR UPDATE", id).Scan(&outstanding, &status)
+	if err == sql.ErrNoRows {
+		return 0, errors.New("cash advance not found")
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if status != domain.AdvanceStatusPaid {
+		return 0, nil
+	}
+
+	settled := amountIDR
+	if outstanding < settled {
+		settled = outstanding
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE cash_advances
+		SET outstanding_idr = outstanding_idr - $1,
+		    status = CASE WHEN outstanding_idr - $1 = 0 THEN $2 ELSE status END,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = $3`,
+		settled, domain.AdvanceStatusSettled, id)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE expenses
+		SET advance_settled_idr = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2`,
+		settled, expenseID)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return settled, nil
+}
+
+// Close settles a paid advance by recording its remaining balance as
+// recovered from the employee, and returns the recovered amount.
+func (r *cashAdvanceRepository) Close(ctx context.Context, id int) (int, error) {
+	query := `
+		UPDATE cash_advances
+		SET recovered_idr = recovered_idr + outstanding_idr, outstanding_idr = 0,
+		    status = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2 AND status = $3
+		RETURNING recovered_idr`
+
+	var recovered int
+	err := r.db.QueryRowContext(ctx, query, domain.AdvanceStatusSettled, id, domain.AdvanceStatusPaid).Scan(&recovered)
+	if err == sql.ErrNoRows {
+		return 0, errors.New("only paid cash advances can be closed")
+	}
+
+	return recovered, err
+}
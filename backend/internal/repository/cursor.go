@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"expense-management-system/internal/domain"
+	"fmt"
+	"time"
+)
+
+// cursorTimeLayout writes TIMESTAMP values as stored, without a zone, so
+// they compare the same whatever the session time zone.
+const cursorTimeLayout = "2006-01-02 15:04:05.999999"
+
+// pageCursor is the position after the last row of a page: its value in
+// the sort column (nil for NULL) and its ID. Sort and Order tie it to the
+// order it was made for. Clients get it base64-encoded and treat it as
+// opaque.
+type pageCursor struct {
+	Sort  string  `json:"s"`
+	Order string  `json:"o"`
+	Value *string `json:"v,omitempty"`
+	ID    int     `json:"id"`
+}
+
+func encodeCursor(c pageCursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCursor also checks that the cursor was made for the same order.
+func decodeCursor(s, sort, order string) (*pageCursor, error) {
+	invalid := fmt.Errorf("%w: invalid cursor", domain.ErrInvalidFilter)
+
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, invalid
+	}
+	c := &pageCursor{}
+	if err := json.Unmarshal(b, c); err != nil || c.ID < 1 {
+		return nil, invalid
+	}
+	if c.Sort != sort || c.Order != order {
+		return nil, fmt.Errorf("%w: the cursor is for another sort order", domain.ErrInvalidFilter)
+	}
+	return c, nil
+}
+
+func cursorTime(t time.Time) *string {
+	s := t.Format(cursorTimeLayout)
+	return &s
+}
+
+// pageOffset is where a page starts in offset mode.
+func pageOffset(page domain.PageRequest) int {
+	if page.Page < 1 {
+		return 0
+	}
+	return (page.Page - 1) * page.Limit
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"errors"
+	"expense-management-system/internal/domain"
+	"testing"
+	"time"
+)
+
+func TestDecodeCursor(t *testing.T) {
+	value := cursorTime(time.Date(2024, 1, 31, 9, 30, 0, 123000, time.UTC))
+	encoded := encodeCursor(pageCursor{Sort: domain.ExpenseSortSubmittedAt, Order: domain.SortDesc, Value: value, ID: 42})
+
+	cursor, err := decodeCursor(encoded, domain.ExpenseSortSubmittedAt, domain.SortDesc)
+	if err != nil {
+		t.Fatalf("decodeCursor() unexpected error = %v", err)
+	}
+	if cursor.ID != 42 || cursor.Value == nil || *cursor.Value != "2024-01-31 09:30:00.000123" {
+		t.Errorf("decodeCursor() = %+v", cursor)
+	}
+
+	tests := []struct {
+		name   string
+		cursor string
+		sort   string
+		order  string
+	}{
+		{"other order", encoded, domain.ExpenseSortSubmittedAt, domain.SortAsc},
+		{"other sort field", encoded, domain.ExpenseSortAmount, domain.SortDesc},
+		{"not base64", "not a cursor!", domain.ExpenseSortSubmittedAt, domain.SortDesc},
+		{"not JSON", "bm90IGpzb24", domain.ExpenseSortSubmittedAt, domain.SortDesc},
+		{"no ID", encodeCursor(pageCursor{Sort: domain.ExpenseSortSubmittedAt, Order: domain.SortDesc}), domain.ExpenseSortSubmittedAt, domain.SortDesc},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeCursor(tt.cursor, tt.sort, tt.order); !errors.Is(err, domain.ErrInvalidFilter) {
+				t.Errorf("decodeCursor() error = %v, want ErrInvalidFilter", err)
+			}
+		})
+	}
+}
@@ -6,9 +6,57 @@ import (
 	"errors"
 	"expense-management-system/internal/domain"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/lib/pq"
 )
 
+const expenseColumns = `id, user_id, amount_idr, description, category, receipt_url, receipt_id, status, auto_approved,
+		       submitted_at, processed_at, payment_id, payment_external_id, payment_run_id,
+		       cash_advance_id, advance_settled_idr, journal_batch_id, possible_duplicate_of,
+		       receipt_extraction, receipt_amount_mismatch, created_at, updated_at`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanExpense(row rowScanner) (*domain.Expense, error) {
+	expense := &domain.Expense{}
+	var extraction []byte
+	err := row.Scan(
+		&expense.ID,
+		&expense.UserID,
+		&expense.AmountIDR,
+		&expense.Description,
+		&expense.Category,
+		&expense.ReceiptURL,
+		&expense.ReceiptID,
+		&expense.Status,
+		&expense.AutoApproved,
+		&expense.SubmittedAt,
+		&expense.ProcessedAt,
+		&expense.PaymentID,
+		&expense.PaymentExternalID,
+		&expense.PaymentRunID,
+		&expense.CashAdvanceID,
+		&expense.AdvanceSettledIDR,
+		&expense.JournalBatchID,
+		&expense.PossibleDuplicateOf,
+		&extraction,
+		&expense.ReceiptAmountMismatch,
+		&expense.CreatedAt,
+		&expense.UpdatedAt,
+	)
+	if err != nil {
+		return expense, err
+	}
+	expense.ReceiptExtraction, err = decodeExtraction(extraction)
+	return expense, err
+}
+
 type expenseRepository struct {
 	db *sql.DB
 }
@@ -19,18 +67,30 @@ func NewExpenseRepository(db *sql.DB) domain.ExpenseRepository {
 
 func (r *expenseRepository) Create(ctx context.Context, expense *domain.Expense) error {
 	query := `
-		INSERT INTO expenses (user_id, amount_idr, description, receipt_url, status, auto_approved, payment_external_id)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO expenses (user_id, amount_idr, description, category, receipt_url, receipt_id, status, auto_approved,
+		                      payment_external_id, cash_advance_id, possible_duplicate_of, receipt_extraction, receipt_amount_mismatch)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 		RETURNING id, submitted_at, created_at, updated_at`
 
-	err := r.db.QueryRowContext(ctx, query,
+	extraction, err := encodeExtraction(expense.ReceiptExtraction)
+	if err != nil {
+		return err
+	}
+
+	err = r.db.QueryRowContext(ctx, query,
 		expense.UserID,
 		expense.AmountIDR,
 		expense.Description,
+		expense.Category,
 		expense.ReceiptURL,
+		expense.ReceiptID,
 		expense.Status,
 		expense.AutoApproved,
 		expense.PaymentExternalID,
+		expense.CashAdvanceID,
+		expense.PossibleDuplicateOf,
+		extraction,
+		expense.ReceiptAmountMismatch,
 	).Scan(&expense.ID, &expense.SubmittedAt, &expense.CreatedAt, &expense.UpdatedAt)
 
 	return err
@@ -38,28 +98,11 @@ func (r *expenseRepository) Create(ctx context.Context, expense *domain.Expense)
 
 func (r *expenseRepository) GetByID(ctx context.Context, id int) (*domain.Expense, error) {
 	query := `
-		SELECT id, user_id, amount_idr, description, receipt_url, status, auto_approved,
-		       submitted_at, processed_at, payment_id, payment_external_id, created_at, updated_at
+		SELECT ` + expenseColumns + `
 		FROM expenses
 		WHERE id = $1`
 
-	expense := &domain.Expense{}
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&expense.ID,
-		&expense.UserID,
-		&expense.AmountIDR,
-		&expense.Description,
-		&expense.ReceiptURL,
-		&expense.Status,
-		&expense.AutoApproved,
-		&expense.SubmittedAt,
-		&expense.ProcessedAt,
-		&expense.PaymentID,
-		&expense.PaymentExternalID,
-		&expense.CreatedAt,
-		&expense.UpdatedAt,
-	)
-
+	expense, err := scanExpense(r.db.QueryRowContext(ctx, query, id))
 	if err == sql.ErrNoRows {
 		return nil, errors.New("expense not found")
 	}
@@ -67,171 +110,335 @@ func (r *expenseRepository) GetByID(ctx context.Context, id int) (*domain.Expens
 	return expense, err
 }
 
-func (r *expenseRepository) GetByUserID(ctx context.Context, userID int, status string, limit, offset int) ([]*domain.Expense, int, error) {
-	var expenses []*domain.Expense
-	var total int
+// expenseSortColumn is a column expense lists can be ordered by: how to
+// cast a cursor value back to its type and how to read it off an expense.
+// Nullable columns sort their NULLs last.
+type expenseSortColumn struct {
+	name     string
+	cast     string
+	nullable bool
+	value    func(e *domain.Expense) *string
+}
 
-	whereClause := "WHERE user_id = $1"
-	args := []interface{}{userID}
-	argCount := 1
+// expenseSortColumns maps the sort fields clients may ask for to columns;
+// only these ever reach the ORDER BY clause.
+var expenseSortColumns = map[string]expenseSortColumn{
+	domain.ExpenseSortSubmittedAt: {name: "submitted_at", cast: "timestamp", value: func(e *domain.Expense) *string {
+		return cursorTime(e.SubmittedAt)
+	}},
+	domain.ExpenseSortProcessedAt: {name: "processed_at", cast: "timestamp", nullable: true, value: func(e *domain.Expense) *string {
+		if e.ProcessedAt == nil {
+			return nil
+		}
+		return cursorTime(*e.ProcessedAt)
+	}},
+	domain.ExpenseSortAmount: {name: "amount_idr", cast: "integer", value: func(e *domain.Expense) *string {
+		s := strconv.Itoa(e.AmountIDR)
+		return &s
+	}},
+	domain.ExpenseSortStatus: {name: "status", cast: "text", value: func(e *domain.Expense) *string {
+		return &e.Status
+	}},
+	domain.ExpenseSortID: {name: "id"},
+}
 
-	if status != "" {
-		argCount++
-		whereClause += fmt.Sprintf(" AND status = $%d", argCount)
-		args = append(args, status)
+// expenseSort resolves the sort field and order of a filter, with their
+// defaults.
+func expenseSort(filter domain.ExpenseFilter) (string, expenseSortColumn, string) {
+	field := filter.SortBy
+	column, ok := expenseSortColumns[field]
+	if !ok {
+		field = domain.ExpenseSortSubmittedAt
+		column = expenseSortColumns[field]
 	}
-
-	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM expenses %s", whereClause)
-	err := r.db.QueryRowContext(ctx, countQuery, args...).Scan(&total)
-	if err != nil {
-		return nil, 0, err
+	order := domain.SortDesc
+	if filter.SortOrder == domain.SortAsc {
+		order = domain.SortAsc
 	}
+	return field, column, order
+}
 
-	query := fmt.Sprintf(`
-		SELECT id, user_id, amount_idr, description, receipt_url, status, auto_approved,
-		       submitted_at, processed_at, payment_id, payment_external_id, created_at, updated_at
-		FROM expenses
-		%s
-		ORDER BY submitted_at DESC
-		LIMIT $%d OFFSET $%d`, whereClause, argCount+1, argCount+2)
+// expenseQuery collects the conditions of an expense search. Values only
+// ever go into args and are referred to by placeholder.
+type expenseQuery struct {
+	conditions []string
+	args       []interface{}
+}
 
-	args = append(args, limit, offset)
+// arg adds a value and returns its placeholder.
+func (q *expenseQuery) arg(value interface{}) string {
+	q.args = append(q.args, value)
+	return fmt.Sprintf("$%d", len(q.args))
+}
 
-	rows, err := r.db.QueryContext(ctx, query, args...)
-	if err != nil {
-		return nil, 0, err
+func (q *expenseQuery) where(condition string) {
+	q.conditions = append(q.conditions, condition)
+}
+
+func (q *expenseQuery) whereClause() string {
+	if len(q.conditions) == 0 {
+		return ""
 	}
-	defer rows.Close()
+	return "WHERE " + strings.Join(q.conditions, " AND ")
+}
 
-	for rows.Next() {
-		expense := &domain.Expense{}
-		err := rows.Scan(
-			&expense.ID,
-			&expense.UserID,
-			&expense.AmountIDR,
-			&expense.Description,
-			&expense.ReceiptURL,
-			&expense.Status,
-			&expense.AutoApproved,
-			&expense.SubmittedAt,
-			&expense.ProcessedAt,
-			&expense.PaymentID,
-			&expense.PaymentExternalID,
-			&expense.CreatedAt,
-			&expense.UpdatedAt,
-		)
-		if err != nil {
-			return nil, 0, err
-		}
-		expenses = append(expenses, expense)
+func buildExpenseQuery(filter domain.ExpenseFilter) *expenseQuery {
+	q := &expenseQuery{}
+
+	if filter.SubmitterID != nil {
+		q.where("user_id = " + q.arg(*filter.SubmitterID))
+	}
+	if filter.ApproverID != nil {
+		q.where("EXISTS (SELECT 1 FROM approvals a WHERE a.expense_id = expenses.id AND a.approver_id = " + q.arg(*filter.ApproverID) + ")")
+	}
+	if len(filter.Statuses) > 0 {
+		q.where("status = ANY(" + q.arg(pq.Array(filter.Statuses)) + ")")
+	}
+	if filter.SubmittedFrom != nil {
+		q.where("submitted_at >= " + q.arg(*filter.SubmittedFrom))
+	}
+	if filter.SubmittedTo != nil {
+		q.where("submitted_at < " + q.arg(*filter.SubmittedTo))
+	}
+	if filter.ProcessedFrom != nil {
+		q.where("processed_at >= " + q.arg(*filter.ProcessedFrom))
+	}
+	if filter.ProcessedTo != nil {
+		q.where("processed_at < " + q.arg(*filter.ProcessedTo))
+	}
+	if filter.MinAmountIDR != nil {
+		q.where("amount_idr >= " + q.arg(*filter.MinAmountIDR))
+	}
+	if filter.MaxAmountIDR != nil {
+		q.where("amount_idr <= " + q.arg(*filter.MaxAmountIDR))
+	}
+	if filter.AutoApproved != nil {
+		q.where("auto_approved = " + q.arg(*filter.AutoApproved))
+	}
+	if filter.Search != "" {
+		// Must match the expression of idx_expenses_description_fts.
+		q.where("to_tsvector('simple', description) @@ websearch_to_tsquery('simple', " + q.arg(filter.Search) + ")")
 	}
 
-	return expenses, total, nil
+	return q
 }
 
-func (r *expenseRepository) GetAll(ctx context.Context, status string, limit, offset int) ([]*domain.Expense, int, error) {
-	var expenses []*domain.Expense
-	var total int
+// expenseOrderBy breaks ties by ID so pages do not overlap. NULLS LAST is
+// only spelled out for nullable columns: Postgres matches it against the
+// index order even for NOT NULL columns, and the (column, id) indexes read
+// backwards give DESC NULLS FIRST.
+func expenseOrderBy(filter domain.ExpenseFilter) string {
+	_, column, order := expenseSort(filter)
+	direction := "DESC"
+	if order == domain.SortAsc {
+		direction = "ASC"
+	}
+	if !column.nullable {
+		if column.name == "id" {
+			return "ORDER BY id " + direction
+		}
+		return "ORDER BY " + column.name + " " + direction + ", id " + direction
+	}
+	return "ORDER BY " + column.name + " " + direction + " NULLS LAST, id " + direction
+}
 
-	whereClause := ""
-	args := []interface{}{}
-	argCount := 0
+// after adds the condition for the rows that follow cursor in the order of
+// filter. The comparison of (column, id) as a row can use the composite
+// indexes on both.
+func (q *expenseQuery) after(filter domain.ExpenseFilter, cursor *pageCursor) {
+	_, column, order := expenseSort(filter)
+	op := "<"
+	if order == domain.SortAsc {
+		op = ">"
+	}
 
-	if status != "" {
-		argCount++
-		whereClause = "WHERE status = $1"
-		args = append(args, status)
+	switch {
+	case column.name == "id":
+		q.where("id " + op + " " + q.arg(cursor.ID))
+	case cursor.Value == nil && column.nullable:
+		// Past the last non-NULL value only NULLs are left.
+		q.where("(" + column.name + " IS NULL AND id " + op + " " + q.arg(cursor.ID) + ")")
+	case cursor.Value == nil:
+		q.where("FALSE")
+	default:
+		condition := "(" + column.name + ", id) " + op + " (" + q.arg(*cursor.Value) + "::" + column.cast + ", " + q.arg(cursor.ID) + ")"
+		if column.nullable {
+			condition = "(" + condition + " OR " + column.name + " IS NULL)"
+		}
+		q.where(condition)
 	}
+}
 
-	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM expenses %s", whereClause)
-	err := r.db.QueryRowContext(ctx, countQuery, args...).Scan(&total)
-	if err != nil {
-		return nil, 0, err
+// Search counts only when asked to, and reads one row more than the page
+// to know whether there is a next one.
+func (r *expenseRepository) Search(ctx context.Context, filter domain.ExpenseFilter, page domain.PageRequest) (*domain.ExpensePage, error) {
+	q := buildExpenseQuery(filter)
+	result := &domain.ExpensePage{}
+
+	if page.CountTotal {
+		var total int
+		countQuery := "SELECT COUNT(*) FROM expenses " + q.whereClause()
+		if err := r.db.QueryRowContext(ctx, countQuery, q.args...).Scan(&total); err != nil {
+			return nil, err
+		}
+		result.Total = &total
+	}
+
+	field, column, order := expenseSort(filter)
+	offset := pageOffset(page)
+	if page.Cursor != "" {
+		cursor, err := decodeCursor(page.Cursor, field, order)
+		if err != nil {
+			return nil, err
+		}
+		q.after(filter, cursor)
+		offset = 0
 	}
 
 	query := fmt.Sprintf(`
-		SELECT id, user_id, amount_idr, description, receipt_url, status, auto_approved,
-		       submitted_at, processed_at, payment_id, payment_external_id, created_at, updated_at
+		SELECT `+expenseColumns+`
 		FROM expenses
 		%s
-		ORDER BY submitted_at DESC
-		LIMIT $%d OFFSET $%d`, whereClause, argCount+1, argCount+2)
+		%s
+		LIMIT %s OFFSET %s`, q.whereClause(), expenseOrderBy(filter), q.arg(page.Limit+1), q.arg(offset))
+
+	rows, err := r.db.QueryContext(ctx, query, q.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		expense, err := scanExpense(rows)
+		if err != nil {
+			return nil, err
+		}
+		result.Expenses = append(result.Expenses, expense)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-	args = append(args, limit, offset)
+	if len(result.Expenses) > page.Limit {
+		result.Expenses = result.Expenses[:page.Limit]
+		last := result.Expenses[page.Limit-1]
+		cursor := pageCursor{Sort: field, Order: order, ID: last.ID}
+		if column.value != nil {
+			cursor.Value = column.value(last)
+		}
+		result.NextCursor = encodeCursor(cursor)
+	}
+
+	return result, nil
+}
 
-	rows, err := r.db.QueryContext(ctx, query, args...)
+// Export reads the submitter and the approval through LATERAL subqueries,
+// which only add the columns they name, so the filter and order of
+// buildExpenseQuery apply unchanged. Rows are passed on as they arrive
+// rather than collected first.
+func (r *expenseRepository) Export(ctx context.Context, filter domain.ExpenseFilter, fn func(*domain.ExpenseExportRow) error) error {
+	q := buildExpenseQuery(filter)
+	query := `
+		SELECT ` + expenseColumns + `,
+		       submitter.submitter_name, submitter.submitter_email,
+		       approval.approver_name, approval.approver_email, approval.approval_notes, approval.decided_at
+		FROM expenses
+		LEFT JOIN LATERAL (
+			SELECT u.name AS submitter_name, u.email AS submitter_email
+			FROM users u WHERE u.id = expenses.user_id
+		) submitter ON TRUE
+		LEFT JOIN LATERAL (
+			SELECT u.name AS approver_name, u.email AS approver_email, a.notes AS approval_notes, a.created_at AS decided_at
+			FROM approvals a
+			JOIN users u ON u.id = a.approver_id
+			WHERE a.expense_id = expenses.id
+			ORDER BY a.created_at DESC
+			LIMIT 1
+		) approval ON TRUE
+		` + q.whereClause() + `
+		` + expenseOrderBy(filter)
+
+	rows, err := r.db.QueryContext(ctx, query, q.args...)
 	if err != nil {
-		return nil, 0, err
+		return err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		expense := &domain.Expense{}
+		row := &domain.ExpenseExportRow{}
+		var submitterName, submitterEmail sql.NullString
+		var extraction []byte
 		err := rows.Scan(
-			&expense.ID,
-			&expense.UserID,
-			&expense.AmountIDR,
-			&expense.Description,
-			&expense.ReceiptURL,
-			&expense.Status,
-			&expense.AutoApproved,
-			&expense.SubmittedAt,
-			&expense.ProcessedAt,
-			&expense.PaymentID,
-			&expense.PaymentExternalID,
-			&expense.CreatedAt,
-			&expense.UpdatedAt,
+			&row.ID,
+			&row.UserID,
+			&row.AmountIDR,
+			&row.Description,
+			&row.Category,
+			&row.ReceiptURL,
+			&row.ReceiptID,
+			&row.Status,
+			&row.AutoApproved,
+			&row.SubmittedAt,
+			&row.ProcessedAt,
+			&row.PaymentID,
+			&row.PaymentExternalID,
+			&row.PaymentRunID,
+			&row.CashAdvanceID,
+			&row.AdvanceSettledIDR,
+			&row.JournalBatchID,
+			&row.PossibleDuplicateOf,
+			&extraction,
+			&row.ReceiptAmountMismatch,
+			&row.CreatedAt,
+			&row.UpdatedAt,
+			&submitterName,
+			&submitterEmail,
+			&row.ApproverName,
+			&row.ApproverEmail,
+			&row.ApprovalNotes,
+			&row.DecidedAt,
 		)
 		if err != nil {
-			return nil, 0, err
+			return err
+		}
+		row.SubmitterName = submitterName.String
+		row.SubmitterEmail = submitterEmail.String
+		if row.ReceiptExtraction, err = decodeExtraction(extraction); err != nil {
+			return err
 		}
-		expenses = append(expenses, expense)
-	}
 
-	return expenses, total, nil
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
 }
 
 func (r *expenseRepository) GetPendingApprovals(ctx context.Context, limit, offset int) ([]*domain.Expense, int, error) {
 	var expenses []*domain.Expense
 	var total int
 
-	countQuery := "SELECT COUNT(*) FROM expenses WHERE status = $1 AND amount_idr >= $2"
-	err := r.db.QueryRowContext(ctx, countQuery, domain.StatusAwaitingApproval, domain.ApprovalThreshold).Scan(&total)
+	countQuery := "SELECT COUNT(*) FROM expenses WHERE status = $1"
+	err := r.db.QueryRowContext(ctx, countQuery, domain.StatusAwaitingApproval).Scan(&total)
 	if err != nil {
 		return nil, 0, err
 	}
 
 	query := `
-		SELECT id, user_id, amount_idr, description, receipt_url, status, auto_approved,
-		       submitted_at, processed_at, payment_id, payment_external_id, created_at, updated_at
+		SELECT ` + expenseColumns + `
 		FROM expenses
-		WHERE status = $1 AND amount_idr >= $2
+		WHERE status = $1
 		ORDER BY submitted_at ASC
-		LIMIT $3 OFFSET $4`
+		LIMIT $2 OFFSET $3`
 
-	rows, err := r.db.QueryContext(ctx, query, domain.StatusAwaitingApproval, domain.ApprovalThreshold, limit, offset)
+	rows, err := r.db.QueryContext(ctx, query, domain.StatusAwaitingApproval, limit, offset)
 	if err != nil {
 		return nil, 0, err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		expense := &domain.Expense{}
-		err := rows.Scan(
-			&expense.ID,
-			&expense.UserID,
-			&expense.AmountIDR,
-			&expense.Description,
-			&expense.ReceiptURL,
-			&expense.Status,
-			&expense.AutoApproved,
-			&expense.SubmittedAt,
-			&expense.ProcessedAt,
-			&expense.PaymentID,
-			&expense.PaymentExternalID,
-			&expense.CreatedAt,
-			&expense.UpdatedAt,
-		)
+		expense, err := scanExpense(rows)
 		if err != nil {
 			return nil, 0, err
 		}
@@ -292,3 +499,97 @@ func (r *expenseRepository) UpdatePaymentInfo(ctx context.Context, id int, payme
 	_, err := r.db.ExecContext(ctx, query, args...)
 	return err
 }
+
+func (r *expenseRepository) AssignPaymentRun(ctx context.Context, id int, paymentRunID int) error {
+	query := `
+		UPDATE expenses
+		SET payment_run_id = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2`
+
+	_, err := r.db.ExecContext(ctx, query, paymentRunID, id)
+	return err
+}
+
+func (r *expenseRepository) GetByPaymentRunID(ctx context.Context, paymentRunID int) ([]*domain.Expense, error) {
+	query := `
+		SELECT ` + expenseColumns + `
+		FROM expenses
+		WHERE payment_run_id = $1
+		ORDER BY user_id, submitted_at ASC`
+
+	rows, err := r.db.QueryContext(ctx, query, paymentRunID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var expenses []*domain.Expense
+	for rows.Next() {
+		expense, err := scanExpense(rows)
+		if err != nil {
+			return nil, err
+		}
+		expenses = append(expenses, expense)
+	}
+
+	return expenses, nil
+}
+
+func (r *expenseRepository) FindDuplicateCandidates(ctx context.Context, userID, amountIDR int, since time.Time, receiptSHA256 *string) ([]*domain.DuplicateCandidate, error) {
+	query := `
+		SELECT ` + expenseColumns + `, receipt.sha256
+		FROM expenses
+		LEFT JOIN LATERAL (
+			SELECT rc.sha256 FROM receipts rc WHERE rc.id = expenses.receipt_id
+		) receipt ON TRUE
+		WHERE status <> $1
+		  AND ((user_id = $2 AND amount_idr = $3 AND submitted_at >= $4)
+		       OR ($5::text IS NOT NULL AND receipt.sha256 = $5))
+		ORDER BY submitted_at ASC, id ASC`
+
+	rows, err := r.db.QueryContext(ctx, query, domain.StatusRejected, userID, amountIDR, since, receiptSHA256)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candidates []*domain.DuplicateCandidate
+	for rows.Next() {
+		candidate := &domain.DuplicateCandidate{Expense: &domain.Expense{}}
+		expense := candidate.Expense
+		var extraction []byte
+		err := rows.Scan(
+			&expense.ID,
+			&expense.UserID,
+			&expense.AmountIDR,
+			&expense.Description,
+			&expense.Category,
+			&expense.ReceiptURL,
+			&expense.ReceiptID,
+			&expense.Status,
+			&expense.AutoApproved,
+			&expense.SubmittedAt,
+			&expense.ProcessedAt,
+			&expense.PaymentID,
+			&expense.PaymentExternalID,
+			&expense.PaymentRunID,
+			&expense.CashAdvanceID,
+			&expense.AdvanceSettledIDR,
+			&expense.JournalBatchID,
+			&expense.PossibleDuplicateOf,
+			&extraction,
+			&expense.ReceiptAmountMismatch,
+			&expense.CreatedAt,
+			&expense.UpdatedAt,
+			&candidate.ReceiptSHA256,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if expense.ReceiptExtraction, err = decodeExtraction(extraction); err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, candidate)
+	}
+	return candidates, rows.Err()
+}
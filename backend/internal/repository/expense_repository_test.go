@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"expense-management-system/internal/domain"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildExpenseQuery(t *testing.T) {
+	submitter, approver, minAmount := 1, 3, 100000
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	autoApproved := false
+	search := "taxi'); DROP TABLE expenses; --"
+
+	q := buildExpenseQuery(domain.ExpenseFilter{
+		SubmitterID:   &submitter,
+		ApproverID:    &approver,
+		Statuses:      []string{domain.StatusApproved, domain.StatusCompleted},
+		SubmittedFrom: &from,
+		MinAmountIDR:  &minAmount,
+		AutoApproved:  &autoApproved,
+		Search:        search,
+	})
+
+	want := "WHERE user_id = $1" +
+		" AND EXISTS (SELECT 1 FROM approvals a WHERE a.expense_id = expenses.id AND a.approver_id = $2)" +
+		" AND status = ANY($3)" +
+		" AND submitted_at >= $4" +
+		" AND amount_idr >= $5" +
+		" AND auto_approved = $6" +
+		" AND to_tsvector('simple', description) @@ websearch_to_tsquery('simple', $7)"
+	if got := q.whereClause(); got != want {
+		t.Errorf("whereClause() =\n%s\nwant\n%s", got, want)
+	}
+	if len(q.args) != 7 || q.args[6] != search {
+		t.Errorf("Expected the search text as the 7th argument, got %v", q.args)
+	}
+	if strings.Contains(q.whereClause(), "taxi") {
+		t.Error("Expected values to stay out of the SQL")
+	}
+
+	if got := buildExpenseQuery(domain.ExpenseFilter{}).whereClause(); got != "" {
+		t.Errorf("Expected no WHERE clause for an empty filter, got %q", got)
+	}
+}
+
+func TestExpenseOrderBy(t *testing.T) {
+	tests := []struct {
+		filter domain.ExpenseFilter
+		want   string
+	}{
+		{domain.ExpenseFilter{}, "ORDER BY submitted_at DESC, id DESC"},
+		{domain.ExpenseFilter{SortBy: domain.ExpenseSortAmount, SortOrder: domain.SortAsc}, "ORDER BY amount_idr ASC, id ASC"},
+		{domain.ExpenseFilter{SortBy: domain.ExpenseSortProcessedAt}, "ORDER BY processed_at DESC NULLS LAST, id DESC"},
+		{domain.ExpenseFilter{SortBy: domain.ExpenseSortProcessedAt, SortOrder: domain.SortAsc}, "ORDER BY processed_at ASC NULLS LAST, id ASC"},
+		{domain.ExpenseFilter{SortBy: domain.ExpenseSortID, SortOrder: domain.SortAsc}, "ORDER BY id ASC"},
+		{domain.ExpenseFilter{SortBy: "amount_idr; DROP TABLE expenses", SortOrder: "asc; --"}, "ORDER BY submitted_at DESC, id DESC"},
+	}
+
+	for _, tt := range tests {
+		if got := expenseOrderBy(tt.filter); got != tt.want {
+			t.Errorf("expenseOrderBy(%+v) = %q, want %q", tt.filter, got, tt.want)
+		}
+	}
+}
+
+func TestExpenseQueryAfter(t *testing.T) {
+	value := "2024-01-31 09:30:00"
+	amount := "500000"
+
+	tests := []struct {
+		name   string
+		filter domain.ExpenseFilter
+		cursor pageCursor
+		want   string
+		args   int
+	}{
+		{
+			name:   "submitted_at descending",
+			cursor: pageCursor{Value: &value, ID: 42},
+			want:   "WHERE (submitted_at, id) < ($1::timestamp, $2)",
+			args:   2,
+		},
+		{
+			name:   "amount ascending",
+			filter: domain.ExpenseFilter{SortBy: domain.ExpenseSortAmount, SortOrder: domain.SortAsc},
+			cursor: pageCursor{Value: &amount, ID: 42},
+			want:   "WHERE (amount_idr, id) > ($1::integer, $2)",
+			args:   2,
+		},
+		{
+			name:   "processed_at keeps the NULLs that sort last",
+			filter: domain.ExpenseFilter{SortBy: domain.ExpenseSortProcessedAt},
+			cursor: pageCursor{Value: &value, ID: 42},
+			want:   "WHERE ((processed_at, id) < ($1::timestamp, $2) OR processed_at IS NULL)",
+			args:   2,
+		},
+		{
+			name:   "processed_at past the last value",
+			filter: domain.ExpenseFilter{SortBy: domain.ExpenseSortProcessedAt},
+			cursor: pageCursor{ID: 42},
+			want:   "WHERE (processed_at IS NULL AND id < $1)",
+			args:   1,
+		},
+		{
+			name:   "id",
+			filter: domain.ExpenseFilter{SortBy: domain.ExpenseSortID, SortOrder: domain.SortAsc},
+			cursor: pageCursor{ID: 42},
+			want:   "WHERE id > $1",
+			args:   1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := &expenseQuery{}
+			q.after(tt.filter, &tt.cursor)
+			if got := q.whereClause(); got != tt.want {
+				t.Errorf("whereClause() = %q, want %q", got, tt.want)
+			}
+			if len(q.args) != tt.args {
+				t.Errorf("Expected %d arguments, got %v", tt.args, q.args)
+			}
+		})
+	}
+}
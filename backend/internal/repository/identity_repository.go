@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"expense-management-system/internal/domain"
+	"time"
+)
+
+type identityRepository struct {
+	db *sql.DB
+}
+
+func NewIdentityRepository(db *sql.DB) domain.IdentityRepository {
+	return &identityRepository{db: db}
+}
+
+func (r *identityRepository) GetByIssuerSubject(ctx context.Context, issuer, subject string) (*domain.ExternalIdentity, error) {
+	query := `
+		SELECT id, user_id, issuer, subject, email, created_at, last_login_at
+		FROM user_identities
+		WHERE issuer = $1 AND subject = $2`
+
+	identity := &domain.ExternalIdentity{}
+	err := r.db.QueryRowContext(ctx, query, issuer, subject).Scan(
+		&identity.ID,
+		&identity.UserID,
+		&identity.Issuer,
+		&identity.Subject,
+		&identity.Email,
+		&identity.CreatedAt,
+		&identity.LastLoginAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, errors.New("identity not found")
+	}
+
+	return identity, err
+}
+
+func (r *identityRepository) Create(ctx context.Context, identity *domain.ExternalIdentity) error {
+	query := `
+		INSERT INTO user_identities (user_id, issuer, subject, email)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`
+
+	return r.db.QueryRowContext(ctx, query,
+		identity.UserID,
+		identity.Issuer,
+		identity.Subject,
+		identity.Email,
+	).Scan(&identity.ID, &identity.CreatedAt)
+}
+
+func (r *identityRepository) TouchLogin(ctx context.Context, id int) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE user_identities SET last_login_at = CURRENT_TIMESTAMP WHERE id = $1", id)
+	return err
+}
+
+func (r *identityRepository) CreateAuthRequest(ctx context.Context, request *domain.SSOAuthRequest) error {
+	// Abandoned logins are never consumed; clear them out as new ones start.
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM sso_auth_requests WHERE expires_at < $1", time.Now()); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO sso_auth_requests (state_hash, nonce, code_verifier, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`
+
+	return r.db.QueryRowContext(ctx, query,
+		request.StateHash,
+		request.Nonce,
+		request.CodeVerifier,
+		request.ExpiresAt,
+	).Scan(&request.ID, &request.CreatedAt)
+}
+
+func (r *identityRepository) ConsumeAuthRequest(ctx context.Context, stateHash string) (*domain.SSOAuthRequest, error) {
+	query := `
+		DELETE FROM sso_auth_requests
+		WHERE state_hash = $1
+		RETURNING id, state_hash, nonce, code_verifier, expires_at, created_at`
+
+	request := &domain.SSOAuthRequest{}
+	err := r.db.QueryRowContext(ctx, query, stateHash).Scan(
+		&request.ID,
+		&request.StateHash,
+		&request.Nonce,
+		&request.CodeVerifier,
+		&request.ExpiresAt,
+		&request.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, errors.New("sso request not found")
+	}
+
+	return request, err
+}
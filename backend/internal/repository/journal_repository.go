@@ -0,0 +1,288 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"expense-management-system/internal/domain"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type journalRepository struct {
+	db *sql.DB
+}
+
+func NewJournalRepository(db *sql.DB) domain.JournalRepository {
+	return &journalRepository{db: db}
+}
+
+// paidStatuses are the statuses of expenses that were paid out; refunds
+// later on do not undo the payment.
+var paidStatuses = []string{domain.StatusCompleted, domain.StatusPartiallyRefunded, domain.StatusRefunded}
+
+const journalBatchColumns = `id, paid_before, expense_count, total_amount_idr, refund_count, refunded_amount_idr, created_by, created_at`
+
+func scanJournalBatch(row rowScanner) (*domain.JournalBatch, error) {
+	batch := &domain.JournalBatch{}
+	err := row.Scan(
+		&batch.ID,
+		&batch.PaidBefore,
+		&batch.ExpenseCount,
+		&batch.TotalAmountIDR,
+		&batch.RefundCount,
+		&batch.RefundedAmountIDR,
+		&batch.CreatedBy,
+		&batch.CreatedAt,
+	)
+	return batch, err
+}
+
+func (r *journalRepository) ListAccounts(ctx context.Context) ([]*domain.JournalAccount, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT key, code, name, updated_at
+		FROM journal_accounts
+		ORDER BY key`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accounts []*domain.JournalAccount
+	for rows.Next() {
+		account := &domain.JournalAccount{}
+		if err := rows.Scan(&account.Key, &account.Code, &account.Name, &account.UpdatedAt); err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	}
+
+	return accounts, rows.Err()
+}
+
+func (r *journalRepository) UpdateAccount(ctx context.Context, account *domain.JournalAccount) error {
+	query := `
+		INSERT INTO journal_accounts (key, code, name)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (key) DO UPDATE SET code = EXCLUDED.code, name = EXCLUDED.name, updated_at = CURRENT_TIMESTAMP
+		RETURNING updated_at`
+
+	return r.db.QueryRowContext(ctx, query, account.Key, account.Code, account.Name).Scan(&account.UpdatedAt)
+}
+
+func (r *journalRepository) GetUnexported(ctx context.Context, paidBefore time.Time) ([]*domain.Expense, error) {
+	query := `
+		SELECT ` + expenseColumns + `
+		FROM expenses
+		WHERE journal_batch_id IS NULL AND status = ANY($1) AND processed_at < $2
+		ORDER BY processed_at, id`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(paidStatuses), paidBefore)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var expenses []*domain.Expense
+	for rows.Next() {
+		expense, err := scanExpense(rows)
+		if err != nil {
+			return nil, err
+		}
+		expenses = append(expenses, expense)
+	}
+
+	return expenses, rows.Err()
+}
+
+// GetUnexportedRefunds takes a refund's last update as the time it
+// completed, since a completed refund is not changed again.
+func (r *journalRepository) GetUnexportedRefunds(ctx context.Context, completedBefore time.Time) ([]*domain.JournalRefund, error) {
+	query := `
+		SELECT rf.id, rf.expense_id, rf.amount_idr, rf.reason, e.category, rf.updated_at
+		FROM refunds rf
+		JOIN expenses e ON e.id = rf.expense_id
+		WHERE rf.journal_batch_id IS NULL AND rf.status = $1 AND rf.updated_at < $2
+		ORDER BY rf.updated_at, rf.id`
+
+	rows, err := r.db.QueryContext(ctx, query, domain.RefundStatusCompleted, completedBefore)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var refunds []*domain.JournalRefund
+	for rows.Next() {
+		refund := &domain.JournalRefund{}
+		err := rows.Scan(
+			&refund.ID,
+			&refund.ExpenseID,
+			&refund.AmountIDR,
+			&refund.Reason,
+			&refund.Category,
+			&refund.CompletedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		refunds = append(refunds, refund)
+	}
+
+	return refunds, rows.Err()
+}
+
+// CreateBatch only claims expenses and refunds that are still unexported;
+// if another batch took any of them first, the whole transaction is rolled
+// back.
+func (r *journalRepository) CreateBatch(ctx context.Context, batch *domain.JournalBatch, expenseIDs, refundIDs []int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO journal_batches (paid_before, expense_count, total_amount_idr, refund_count, refunded_amount_idr, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`,
+		batch.PaidBefore, batch.ExpenseCount, batch.TotalAmountIDR, batch.RefundCount, batch.RefundedAmountIDR, batch.CreatedBy,
+	).Scan(&batch.ID, &batch.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE expenses
+		SET journal_batch_id = $1
+		WHERE id = ANY($2) AND journal_batch_id IS NULL`,
+		batch.ID, pq.Array(expenseIDs))
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if int(affected) != len(expenseIDs) {
+		return domain.ErrJournalConflict
+	}
+
+	result, err = tx.ExecContext(ctx, `
+		UPDATE refunds
+		SET journal_batch_id = $1
+		WHERE id = ANY($2) AND journal_batch_id IS NULL`,
+		batch.ID, pq.Array(refundIDs))
+	if err != nil {
+		return err
+	}
+	affected, err = result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if int(affected) != len(refundIDs) {
+		return domain.ErrJournalConflict
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO journal_lines (batch_id, expense_id, refund_id, entry_date, account_code, account_name, debit_idr, credit_idr, description)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, line := range batch.Lines {
+		_, err := stmt.ExecContext(ctx,
+			batch.ID,
+			line.ExpenseID,
+			line.RefundID,
+			line.EntryDate,
+			line.AccountCode,
+			line.AccountName,
+			line.DebitIDR,
+			line.CreditIDR,
+			line.Description,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *journalRepository) List(ctx context.Context, limit, offset int) ([]*domain.JournalBatch, int, error) {
+	var total int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM journal_batches").Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT ` + journalBatchColumns + `
+		FROM journal_batches
+		ORDER BY id DESC
+		LIMIT $1 OFFSET $2`
+
+	rows, err := r.db.QueryContext(ctx, query, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var batches []*domain.JournalBatch
+	for rows.Next() {
+		batch, err := scanJournalBatch(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		batches = append(batches, batch)
+	}
+
+	return batches, total, rows.Err()
+}
+
+func (r *journalRepository) GetByID(ctx context.Context, id int) (*domain.JournalBatch, error) {
+	query := `
+		SELECT ` + journalBatchColumns + `
+		FROM journal_batches
+		WHERE id = $1`
+
+	batch, err := scanJournalBatch(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, errors.New("journal batch not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT expense_id, refund_id, entry_date, account_code, account_name, debit_idr, credit_idr, description
+		FROM journal_lines
+		WHERE batch_id = $1
+		ORDER BY id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		line := &domain.JournalLine{}
+		err := rows.Scan(
+			&line.ExpenseID,
+			&line.RefundID,
+			&line.EntryDate,
+			&line.AccountCode,
+			&line.AccountName,
+			&line.DebitIDR,
+			&line.CreditIDR,
+			&line.Description,
+		)
+		if err != nil {
+			return nil, err
+		}
+		batch.Lines = append(batch.Lines, line)
+	}
+
+	return batch, rows.Err()
+}
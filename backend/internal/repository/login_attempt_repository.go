@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"expense-management-system/internal/domain"
+	"time"
+)
+
+type loginAttemptRepository struct {
+	db *sql.DB
+}
+
+func NewLoginAttemptRepository(db *sql.DB) domain.LoginAttemptRepository {
+	return &loginAttemptRepository{db: db}
+}
+
+func (r *loginAttemptRepository) Create(ctx context.Context, attempt *domain.LoginAttempt) error {
+	query := `
+		INSERT INTO login_attempts (email, user_id, ip_address, success)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`
+
+	return r.db.QueryRowContext(ctx, query,
+		attempt.Email,
+		attempt.UserID,
+		attempt.IPAddress,
+		attempt.Success,
+	).Scan(&attempt.ID, &attempt.CreatedAt)
+}
+
+func (r *loginAttemptRepository) CountFailuresByIP(ctx context.Context, ipAddress string, since time.Time) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM login_attempts
+		WHERE ip_address = $1 AND NOT success AND created_at >= $2`, ipAddress, since).Scan(&count)
+	return count, err
+}
+
+func (r *loginAttemptRepository) ListByUser(ctx context.Context, userID int, limit int) ([]*domain.LoginAttempt, error) {
+	query := `
+		SELECT id, email, user_id, ip_address, success, created_at
+		FROM login_attempts
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attempts []*domain.LoginAttempt
+	for rows.Next() {
+		attempt := &domain.LoginAttempt{}
+		if err := rows.Scan(
+			&attempt.ID,
+			&attempt.Email,
+			&attempt.UserID,
+			&attempt.IPAddress,
+			&attempt.Success,
+			&attempt.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, attempt)
+	}
+
+	return attempts, rows.Err()
+}
@@ -0,0 +1,373 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"expense-management-system/internal/domain"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type paymentRunRepository struct {
+	db *sql.DB
+}
+
+func NewPaymentRunRepository(db *sql.DB) domain.PaymentRunRepository {
+	return &paymentRunRepository{db: db}
+}
+
+// Totals are derived from the linked expenses rather than stored on the run,
+// so they can never drift from what will actually be paid out.
+const paymentRunSelect = `
+		SELECT r.id, r.cutoff_at, r.status, COALESCE(SUM(e.amount_idr - e.advance_settled_idr), 0), COUNT(e.id),
+		       r.released_by, r.released_at, r.created_at, r.updated_at
+		FROM payment_runs r
+		LEFT JOIN expenses e ON e.payment_run_id = r.id`
+
+func scanPaymentRun(row rowScanner) (*domain.PaymentRun, error) {
+	run := &domain.PaymentRun{}
+	err := row.Scan(
+		&run.ID,
+		&run.CutoffAt,
+		&run.Status,
+		&run.TotalAmountIDR,
+		&run.ExpenseCount,
+		&run.ReleasedBy,
+		&run.ReleasedAt,
+		&run.CreatedAt,
+		&run.UpdatedAt,
+	)
+	return run, err
+}
+
+func (r *paymentRunRepository) GetOrCreateOpen(ctx context.Context, cutoffAt time.Time) (*domain.PaymentRun, error) {
+	query := `
+		INSERT INTO payment_runs (cutoff_at, status)
+		VALUES ($1, $2)
+		ON CONFLICT (cutoff_at) DO UPDATE SET updated_at = CURRENT_TIMESTAMP
+		RETURNING id`
+
+	var id int
+	if err := r.db.QueryRowContext(ctx, query, cutoffAt, domain.PaymentRunStatusOpen).Scan(&id); err != nil {
+		return nil, err
+	}
+
+	return r.GetByID(ctx, id)
+}
+
+func (r *paymentRunRepository) GetByID(ctx context.Context, id int) (*domain.PaymentRun, error) {
+	query := paymentRunSelect + `
+		WHERE r.id = $1
+		GROUP BY r.id`
+
+	run, err := scanPaymentRun(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, errors.New("payment run not found")
+	}
+
+	return run, err
+}
+
+func (r *paymentRunRepository) List(ctx context.Context, status string, limit, offset int) ([]*domain.PaymentRun, int, error) {
+	var runs []*domain.PaymentRun
+	var total int
+
+	countQuery := "SELECT COUNT(*) FROM payment_runs WHERE $1 = '' OR status = $1"
+	if err := r.db.QueryRowContext(ctx, countQuery, status).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := paymentRunSelect + `
+		WHERE $1 = '' OR r.status = $1
+		GROUP BY r.id
+		ORDER BY r.cutoff_at DESC
+		LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.QueryContext(ctx, query, status, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		run, err := scanPaymentRun(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		runs = append(runs, run)
+	}
+
+	return runs, total, nil
+}
+
+func (r *paymentRunRepository) CloseDue(ctx context.Context, now time.Time) ([]*domain.PaymentRun, error) {
+	query := `
+		UPDATE payment_runs
+		SET status = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE status = $2 AND cutoff_at <= $3
+		RETURNING id`
+
+	rows, err := r.db.QueryContext(ctx, query, domain.PaymentRunStatusPendingReview, domain.PaymentRunStatusOpen, now)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	var runs []*domain.PaymentRun
+	for _, id := range ids {
+		run, err := r.GetByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+
+	return runs, nil
+}
+
+// Release moves a reviewed run to released and creates one pending payout per
+// employee summing their approved expenses, all in a single transaction.
+func (r *paymentRunRepository) Release(ctx context.Context, id int, releasedBy int) ([]*domain.Payout, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE payment_runs
+		SET status = $1, released_by = $2, released_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $3 AND status = $4`,
+		domain.PaymentRunStatusReleased, releasedBy, id, domain.PaymentRunStatusPendingReview)
+	if err != nil {
+		return nil, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if affected == 0 {
+		return nil, errors.New("payment run is not pending review")
+	}
+
+	// Amounts already covered by a cash advance are not paid out again.
+	rows, err := tx.QueryContext(ctx, `
+		SELECT user_id, SUM(amount_idr - advance_settled_idr), COUNT(*)
+		FROM expenses
+		WHERE payment_run_id = $1 AND status = $2
+		GROUP BY user_id
+		ORDER BY user_id`,
+		id, domain.StatusApproved)
+	if err != nil {
+		return nil, err
+	}
+
+	var payouts []*domain.Payout
+	for rows.Next() {
+		payout := &domain.Payout{
+			PaymentRunID:      id,
+			Status:            domain.PayoutStatusPending,
+			PaymentExternalID: uuid.New().String(),
+		}
+		if err := rows.Scan(&payout.UserID, &payout.AmountIDR, &payout.ExpenseCount); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		payouts = append(payouts, payout)
+	}
+	rows.Close()
+
+	insertQuery := `
+		INSERT INTO payouts (payment_run_id, user_id, amount_idr, expense_count, status, payment_external_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at`
+
+	for _, payout := range payouts {
+		err := tx.QueryRowContext(ctx, insertQuery,
+			payout.PaymentRunID,
+			payout.UserID,
+			payout.AmountIDR,
+			payout.ExpenseCount,
+			payout.Status,
+			payout.PaymentExternalID,
+		).Scan(&payout.ID, &payout.CreatedAt, &payout.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return payouts, nil
+}
+
+func (r *paymentRunRepository) GetPayouts(ctx context.Context, paymentRunID int) ([]*domain.Payout, error) {
+	query := `
+		SELECT id, payment_run_id, user_id, amount_idr, expense_count, status,
+		       payment_id, payment_external_id, created_at, updated_at
+		FROM payouts
+		WHERE payment_run_id = $1
+		ORDER BY user_id`
+
+	rows, err := r.db.QueryContext(ctx, query, paymentRunID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var payouts []*domain.Payout
+	for rows.Next() {
+		payout, err := scanPayout(rows)
+		if err != nil {
+			return nil, err
+		}
+		payouts = append(payouts, payout)
+	}
+
+	return payouts, nil
+}
+
+func (r *paymentRunRepository) GetPayoutByID(ctx context.Context, id int) (*domain.Payout, error) {
+	query := `
+		SELECT id, payment_run_id, user_id, amount_idr, expense_count, status,
+		       payment_id, payment_external_id, created_at, updated_at
+		FROM payouts
+		WHERE id = $1`
+
+	payout, err := scanPayout(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, errors.New("payout not found")
+	}
+
+	return payout, err
+}
+
+func (r *paymentRunRepository) ClaimStalePayouts(ctx context.Context, staleBefore time.Time) ([]*domain.Payout, error) {
+	query := `
+		UPDATE payouts p
+		SET updated_at = CURRENT_TIMESTAMP
+		FROM payment_runs r
+		WHERE r.id = p.payment_run_id AND r.status = $1
+		  AND p.status = $2 AND p.updated_at < $3
+		RETURNING p.id, p.payment_run_id, p.user_id, p.amount_idr, p.expense_count, p.status,
+		          p.payment_id, p.payment_external_id, p.created_at, p.updated_at`
+
+	rows, err := r.db.QueryContext(ctx, query, domain.PaymentRunStatusReleased, domain.PayoutStatusPending, staleBefore)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var payouts []*domain.Payout
+	for rows.Next() {
+		payout, err := scanPayout(rows)
+		if err != nil {
+			return nil, err
+		}
+		payouts = append(payouts, payout)
+	}
+
+	return payouts, rows.Err()
+}
+
+func (r *paymentRunRepository) RetryPayout(ctx context.Context, runID, payoutID int) (*domain.Payout, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	payout, err := scanPayout(tx.QueryRowContext(ctx, `
+		UPDATE payouts
+		SET status = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2 AND payment_run_id = $3 AND status = $4
+		RETURNING id, payment_run_id, user_id, amount_idr, expense_count, status,
+		          payment_id, payment_external_id, created_at, updated_at`,
+		domain.PayoutStatusPending, payoutID, runID, domain.PayoutStatusFailed))
+	if err == sql.ErrNoRows {
+		return nil, errors.New("payout not found or not failed")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE payment_runs
+		SET status = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2 AND status = $3`,
+		domain.PaymentRunStatusReleased, runID, domain.PaymentRunStatusFailed)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return payout, nil
+}
+
+func scanPayout(row rowScanner) (*domain.Payout, error) {
+	payout := &domain.Payout{}
+	err := row.Scan(
+		&payout.ID,
+		&payout.PaymentRunID,
+		&payout.UserID,
+		&payout.AmountIDR,
+		&payout.ExpenseCount,
+		&payout.Status,
+		&payout.PaymentID,
+		&payout.PaymentExternalID,
+		&payout.CreatedAt,
+		&payout.UpdatedAt,
+	)
+	return payout, err
+}
+
+func (r *paymentRunRepository) UpdatePayoutStatus(ctx context.Context, id int, status string, paymentID *string) error {
+	query := `
+		UPDATE payouts
+		SET status = $1, payment_id = COALESCE($2, payment_id), updated_at = CURRENT_TIMESTAMP
+		WHERE id = $3`
+
+	_, err := r.db.ExecContext(ctx, query, status, paymentID, id)
+	return err
+}
+
+// RefreshStatus settles a released run once none of its payouts are pending:
+// completed if every payout succeeded, failed otherwise.
+func (r *paymentRunRepository) RefreshStatus(ctx context.Context, id int) error {
+	query := `
+		UPDATE payment_runs
+		SET status = CASE
+		        WHEN EXISTS (SELECT 1 FROM payouts WHERE payment_run_id = $1 AND status = $2) THEN $3
+		        ELSE $4
+		    END,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = $5
+		  AND NOT EXISTS (SELECT 1 FROM payouts WHERE payment_run_id = $1 AND status = $6)`
+
+	_, err := r.db.ExecContext(ctx, query,
+		id,
+		domain.PayoutStatusFailed,
+		domain.PaymentRunStatusFailed,
+		domain.PaymentRunStatusCompleted,
+		domain.PaymentRunStatusReleased,
+		domain.PayoutStatusPending,
+	)
+	return err
+}
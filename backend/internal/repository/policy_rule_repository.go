@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"expense-management-system/internal/domain"
+)
+
+type policyRuleRepository struct {
+	db *sql.DB
+}
+
+func NewPolicyRuleRepository(db *sql.DB) domain.PolicyRuleRepository {
+	return &policyRuleRepository{db: db}
+}
+
+const policyRuleColumns = `id, name, message, action, condition, enabled, created_by, created_at, updated_at`
+
+func scanPolicyRule(row rowScanner) (*domain.PolicyRule, error) {
+	rule := &domain.PolicyRule{}
+	var condition []byte
+	err := row.Scan(
+		&rule.ID,
+		&rule.Name,
+		&rule.Message,
+		&rule.Action,
+		&condition,
+		&rule.Enabled,
+		&rule.CreatedBy,
+		&rule.CreatedAt,
+		&rule.UpdatedAt,
+	)
+	rule.Condition = condition
+	return rule, err
+}
+
+func (r *policyRuleRepository) Create(ctx context.Context, rule *domain.PolicyRule) error {
+	query := `
+		INSERT INTO policy_rules (name, message, action, condition, enabled, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at`
+
+	return r.db.QueryRowContext(ctx, query,
+		rule.Name,
+		rule.Message,
+		rule.Action,
+		[]byte(rule.Condition),
+		rule.Enabled,
+		rule.CreatedBy,
+	).Scan(&rule.ID, &rule.CreatedAt, &rule.UpdatedAt)
+}
+
+func (r *policyRuleRepository) Update(ctx context.Context, rule *domain.PolicyRule) error {
+	query := `
+		UPDATE policy_rules
+		SET name = $2, message = $3, action = $4, condition = $5, enabled = $6, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING updated_at`
+
+	err := r.db.QueryRowContext(ctx, query,
+		rule.ID,
+		rule.Name,
+		rule.Message,
+		rule.Action,
+		[]byte(rule.Condition),
+		rule.Enabled,
+	).Scan(&rule.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return errors.New("policy rule not found")
+	}
+	return err
+}
+
+func (r *policyRuleRepository) Delete(ctx context.Context, id int) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM policy_rules WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errors.New("policy rule not found")
+	}
+
+	return nil
+}
+
+func (r *policyRuleRepository) GetByID(ctx context.Context, id int) (*domain.PolicyRule, error) {
+	query := `
+		SELECT ` + policyRuleColumns + `
+		FROM policy_rules
+		WHERE id = $1`
+
+	rule, err := scanPolicyRule(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, errors.New("policy rule not found")
+	}
+
+	return rule, err
+}
+
+func (r *policyRuleRepository) List(ctx context.Context, enabledOnly bool) ([]*domain.PolicyRule, error) {
+	query := `
+		SELECT ` + policyRuleColumns + `
+		FROM policy_rules
+		WHERE enabled OR NOT $1
+		ORDER BY id`
+
+	rows, err := r.db.QueryContext(ctx, query, enabledOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []*domain.PolicyRule{}
+	for rows.Next() {
+		rule, err := scanPolicyRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, rows.Err()
+}
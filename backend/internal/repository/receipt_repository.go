@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"expense-management-system/internal/domain"
+)
+
+type receiptRepository struct {
+	db *sql.DB
+}
+
+func NewReceiptRepository(db *sql.DB) domain.ReceiptRepository {
+	return &receiptRepository{db: db}
+}
+
+func (r *receiptRepository) Create(ctx context.Context, receipt *domain.Receipt) error {
+	query := `
+		INSERT INTO receipts (user_id, file_name, content_type, size_bytes, sha256, extraction)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`
+
+	extraction, err := encodeExtraction(receipt.Extraction)
+	if err != nil {
+		return err
+	}
+
+	return r.db.QueryRowContext(ctx, query,
+		receipt.UserID,
+		receipt.FileName,
+		receipt.ContentType,
+		receipt.SizeBytes,
+		receipt.SHA256,
+		extraction,
+	).Scan(&receipt.ID, &receipt.CreatedAt)
+}
+
+func (r *receiptRepository) GetByID(ctx context.Context, id int) (*domain.Receipt, error) {
+	query := `
+		SELECT id, user_id, file_name, content_type, size_bytes, sha256, created_at, extraction
+		FROM receipts
+		WHERE id = $1`
+
+	receipt := &domain.Receipt{}
+	var extraction []byte
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&receipt.ID,
+		&receipt.UserID,
+		&receipt.FileName,
+		&receipt.ContentType,
+		&receipt.SizeBytes,
+		&receipt.SHA256,
+		&receipt.CreatedAt,
+		&extraction,
+	)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("receipt not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	receipt.Extraction, err = decodeExtraction(extraction)
+	return receipt, err
+}
+
+// encodeExtraction stores a receipt extraction as JSONB, or NULL without
+// one.
+func encodeExtraction(extraction *domain.ReceiptExtraction) (interface{}, error) {
+	if extraction == nil {
+		return nil, nil
+	}
+	return json.Marshal(extraction)
+}
+
+func decodeExtraction(data []byte) (*domain.ReceiptExtraction, error) {
+	if data == nil {
+		return nil, nil
+	}
+	extraction := &domain.ReceiptExtraction{}
+	if err := json.Unmarshal(data, extraction); err != nil {
+		return nil, err
+	}
+	return extraction, nil
+}
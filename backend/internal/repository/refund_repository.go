@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"expense-management-system/internal/domain"
+)
+
+type refundRepository struct {
+	db *sql.DB
+}
+
+func NewRefundRepository(db *sql.DB) domain.RefundRepository {
+	return &refundRepository{db: db}
+}
+
+func (r *refundRepository) Create(ctx context.Context, refund *domain.Refund) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Locking the expense makes concurrent refunds of it wait here, so each
+	// sees the ones before it.
+	var amountIDR int
+	err = tx.QueryRowContext(ctx, "SELECT amount_idr FROM expenses WHERE id = $1 FOR UPDATE", refund.ExpenseID).Scan(&amountIDR)
+	if err == sql.ErrNoRows {
+		return 0, errors.New("expense not found")
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var refunded int
+	err = tx.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(amount_idr), 0)
+		FROM refunds
+		WHERE expense_id = $1 AND status <> $2`,
+		refund.ExpenseID, domain.RefundStatusFailed).Scan(&refunded)
+	if err != nil {
+		return 0, err
+	}
+
+	remaining := amountIDR - refunded
+	if refund.AmountIDR > remaining {
+		return remaining, domain.ErrRefundExceedsBalance
+	}
+
+	query := `
+		INSERT INTO refunds (expense_id, payment_id, type, amount_idr, reason, status, external_id, initiated_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at, updated_at`
+
+	err = tx.QueryRowContext(ctx, query,
+		refund.ExpenseID,
+		refund.PaymentID,
+		refund.Type,
+		refund.AmountIDR,
+		refund.Reason,
+		refund.Status,
+		refund.ExternalID,
+		refund.InitiatedBy,
+	).Scan(&refund.ID, &refund.CreatedAt, &refund.UpdatedAt)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return remaining, nil
+}
+
+func (r *refundRepository) UpdateStatus(ctx context.Context, id int, status string, providerRefundID *string) error {
+	query := `
+		UPDATE refunds
+		SET status = $1, provider_refund_id = COALESCE($2, provider_refund_id), updated_at = CURRENT_TIMESTAMP
+		WHERE id = $3`
+
+	_, err := r.db.ExecContext(ctx, query, status, providerRefundID, id)
+	return err
+}
+
+func (r *refundRepository) Complete(ctx context.Context, id int, providerRefundID *string) (string, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	// Refunds of the same expense complete one after another, so the last
+	// one sees all the others and sets the final status.
+	var expenseID, amountIDR int
+	err = tx.QueryRowContext(ctx, `
+		SELECT e.id, e.amount_idr
+		FROM expenses e
+		JOIN refunds rf ON rf.expense_id = e.id
+		WHERE rf.id = $1
+		FOR UPDATE OF e`, id).Scan(&expenseID, &amountIDR)
+	if err == sql.ErrNoRows {
+		return "", errors.New("refund not found")
+	}
+	if err != nil {
+		return "", err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE refunds
+		SET status = $1, provider_refund_id = COALESCE($2, provider_refund_id), updated_at = CURRENT_TIMESTAMP
+		WHERE id = $3`,
+		domain.RefundStatusCompleted, providerRefundID, id)
+	if err != nil {
+		return "", err
+	}
+
+	var refunded int
+	err = tx.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(amount_idr), 0)
+		FROM refunds
+		WHERE expense_id = $1 AND status = $2`,
+		expenseID, domain.RefundStatusCompleted).Scan(&refunded)
+	if err != nil {
+		return "", err
+	}
+
+	status := domain.StatusPartiallyRefunded
+	if refunded >= amountIDR {
+		status = domain.StatusRefunded
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE expenses SET status = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2", status, expenseID)
+	if err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+	return status, nil
+}
+
+func (r *refundRepository) GetByExpenseID(ctx context.Context, expenseID int) ([]*domain.Refund, error) {
+	query := `
+		SELECT id, expense_id, payment_id, type, amount_idr, reason, status, external_id,
+		       provider_refund_id, initiated_by, created_at, updated_at
+		FROM refunds
+		WHERE expense_id = $1
+		ORDER BY created_at ASC`
+
+	rows, err := r.db.QueryContext(ctx, query, expenseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var refunds []*domain.Refund
+	for rows.Next() {
+		refund := &domain.Refund{}
+		err := rows.Scan(
+			&refund.ID,
+			&refund.ExpenseID,
+			&refund.PaymentID,
+			&refund.Type,
+			&refund.AmountIDR,
+			&refund.Reason,
+			&refund.Status,
+			&refund.ExternalID,
+			&refund.ProviderRefundID,
+			&refund.InitiatedBy,
+			&refund.CreatedAt,
+			&refund.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		refunds = append(refunds, refund)
+	}
+
+	return refunds, nil
+}
@@ -0,0 +1,288 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"expense-management-system/internal/domain"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type reportRepository struct {
+	db *sql.DB
+}
+
+func NewReportRepository(db *sql.DB) domain.ReportRepository {
+	return &reportRepository{db: db}
+}
+
+const reportScheduleColumns = `id, name, cron, period, group_by, format, filters, recipients, enabled,
+		       next_run_at, last_run_at, created_by, created_at, updated_at`
+
+func scanReportSchedule(row rowScanner) (*domain.ReportSchedule, error) {
+	schedule := &domain.ReportSchedule{}
+	var filters []byte
+	err := row.Scan(
+		&schedule.ID,
+		&schedule.Name,
+		&schedule.Cron,
+		&schedule.Period,
+		&schedule.GroupBy,
+		&schedule.Format,
+		&filters,
+		pq.Array(&schedule.Recipients),
+		&schedule.Enabled,
+		&schedule.NextRunAt,
+		&schedule.LastRunAt,
+		&schedule.CreatedBy,
+		&schedule.CreatedAt,
+		&schedule.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(filters, &schedule.Filters); err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+const reportRunColumns = `id, schedule_id, report_name, trigger, triggered_by, status, format, period_start, period_end,
+		       row_count, file_name, error, started_at, finished_at`
+
+func scanReportRun(row rowScanner) (*domain.ReportRun, error) {
+	run := &domain.ReportRun{}
+	err := row.Scan(
+		&run.ID,
+		&run.ScheduleID,
+		&run.ReportName,
+		&run.Trigger,
+		&run.TriggeredBy,
+		&run.Status,
+		&run.Format,
+		&run.PeriodStart,
+		&run.PeriodEnd,
+		&run.RowCount,
+		&run.FileName,
+		&run.Error,
+		&run.StartedAt,
+		&run.FinishedAt,
+	)
+	return run, err
+}
+
+func (r *reportRepository) CreateSchedule(ctx context.Context, schedule *domain.ReportSchedule) error {
+	filters, err := json.Marshal(schedule.Filters)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO report_schedules (name, cron, period, group_by, format, filters, recipients, enabled, next_run_at, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, created_at, updated_at`
+
+	return r.db.QueryRowContext(ctx, query,
+		schedule.Name,
+		schedule.Cron,
+		schedule.Period,
+		schedule.GroupBy,
+		schedule.Format,
+		filters,
+		pq.Array(schedule.Recipients),
+		schedule.Enabled,
+		schedule.NextRunAt,
+		schedule.CreatedBy,
+	).Scan(&schedule.ID, &schedule.CreatedAt, &schedule.UpdatedAt)
+}
+
+func (r *reportRepository) UpdateSchedule(ctx context.Context, schedule *domain.ReportSchedule) error {
+	filters, err := json.Marshal(schedule.Filters)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE report_schedules
+		SET name = $2, cron = $3, period = $4, group_by = $5, format = $6, filters = $7, recipients = $8,
+		    enabled = $9, next_run_at = $10, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING updated_at`
+
+	err = r.db.QueryRowContext(ctx, query,
+		schedule.ID,
+		schedule.Name,
+		schedule.Cron,
+		schedule.Period,
+		schedule.GroupBy,
+		schedule.Format,
+		filters,
+		pq.Array(schedule.Recipients),
+		schedule.Enabled,
+		schedule.NextRunAt,
+	).Scan(&schedule.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return errors.New("report schedule not found")
+	}
+	return err
+}
+
+func (r *reportRepository) DeleteSchedule(ctx context.Context, id int) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM report_schedules WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errors.New("report schedule not found")
+	}
+
+	return nil
+}
+
+func (r *reportRepository) GetSchedule(ctx context.Context, id int) (*domain.ReportSchedule, error) {
+	query := `
+		SELECT ` + reportScheduleColumns + `
+		FROM report_schedules
+		WHERE id = $1`
+
+	schedule, err := scanReportSchedule(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, errors.New("report schedule not found")
+	}
+
+	return schedule, err
+}
+
+func (r *reportRepository) ListSchedules(ctx context.Context) ([]*domain.ReportSchedule, error) {
+	query := `
+		SELECT ` + reportScheduleColumns + `
+		FROM report_schedules
+		ORDER BY id`
+
+	return r.querySchedules(ctx, query)
+}
+
+func (r *reportRepository) GetDue(ctx context.Context, now time.Time) ([]*domain.ReportSchedule, error) {
+	query := `
+		SELECT ` + reportScheduleColumns + `
+		FROM report_schedules
+		WHERE enabled AND next_run_at <= $1
+		ORDER BY next_run_at, id`
+
+	return r.querySchedules(ctx, query, now)
+}
+
+func (r *reportRepository) querySchedules(ctx context.Context, query string, args ...interface{}) ([]*domain.ReportSchedule, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var schedules []*domain.ReportSchedule
+	for rows.Next() {
+		schedule, err := scanReportSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, schedule)
+	}
+
+	return schedules, rows.Err()
+}
+
+func (r *reportRepository) ClaimRun(ctx context.Context, id int, due time.Time, next *time.Time) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE report_schedules
+		SET next_run_at = $3, last_run_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND enabled AND next_run_at = $2`, id, due, next)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+func (r *reportRepository) CreateRun(ctx context.Context, run *domain.ReportRun) error {
+	query := `
+		INSERT INTO report_runs (schedule_id, report_name, trigger, triggered_by, status, format, period_start, period_end)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, started_at`
+
+	return r.db.QueryRowContext(ctx, query,
+		run.ScheduleID,
+		run.ReportName,
+		run.Trigger,
+		run.TriggeredBy,
+		run.Status,
+		run.Format,
+		run.PeriodStart,
+		run.PeriodEnd,
+	).Scan(&run.ID, &run.StartedAt)
+}
+
+func (r *reportRepository) FinishRun(ctx context.Context, run *domain.ReportRun) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE report_runs
+		SET status = $2, row_count = $3, file_name = $4, error = $5, finished_at = $6
+		WHERE id = $1`,
+		run.ID, run.Status, run.RowCount, run.FileName, run.Error, run.FinishedAt)
+	return err
+}
+
+func (r *reportRepository) ListRuns(ctx context.Context, scheduleID *int, limit, offset int) ([]*domain.ReportRun, int, error) {
+	var total int
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM report_runs
+		WHERE $1::int IS NULL OR schedule_id = $1`, scheduleID).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT ` + reportRunColumns + `
+		FROM report_runs
+		WHERE $1::int IS NULL OR schedule_id = $1
+		ORDER BY id DESC
+		LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.QueryContext(ctx, query, scheduleID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var runs []*domain.ReportRun
+	for rows.Next() {
+		run, err := scanReportRun(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		runs = append(runs, run)
+	}
+
+	return runs, total, rows.Err()
+}
+
+func (r *reportRepository) GetRun(ctx context.Context, id int) (*domain.ReportRun, error) {
+	query := `
+		SELECT ` + reportRunColumns + `
+		FROM report_runs
+		WHERE id = $1`
+
+	run, err := scanReportRun(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, errors.New("report run not found")
+	}
+
+	return run, err
+}
@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"expense-management-system/internal/domain"
+)
+
+type roleRepository struct {
+	db *sql.DB
+}
+
+func NewRoleRepository(db *sql.DB) domain.RoleRepository {
+	return &roleRepository{db: db}
+}
+
+func (r *roleRepository) List(ctx context.Context) ([]*domain.Role, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT name, description, built_in, created_at, updated_at
+		FROM roles
+		ORDER BY built_in DESC, name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := []*domain.Role{}
+	byName := map[string]*domain.Role{}
+	for rows.Next() {
+		role := &domain.Role{Permissions: []string{}}
+		if err := rows.Scan(&role.Name, &role.Description, &role.BuiltIn, &role.CreatedAt, &role.UpdatedAt); err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+		byName[role.Name] = role
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	permRows, err := r.db.QueryContext(ctx, `
+		SELECT role, permission
+		FROM role_permissions
+		ORDER BY role, permission`)
+	if err != nil {
+		return nil, err
+	}
+	defer permRows.Close()
+
+	for permRows.Next() {
+		var name, permission string
+		if err := permRows.Scan(&name, &permission); err != nil {
+			return nil, err
+		}
+		if role, ok := byName[name]; ok {
+			role.Permissions = append(role.Permissions, permission)
+		}
+	}
+
+	return roles, permRows.Err()
+}
+
+func (r *roleRepository) GetByName(ctx context.Context, name string) (*domain.Role, error) {
+	role := &domain.Role{Permissions: []string{}}
+	err := r.db.QueryRowContext(ctx, `
+		SELECT name, description, built_in, created_at, updated_at
+		FROM roles
+		WHERE name = $1`, name).Scan(&role.Name, &role.Description, &role.BuiltIn, &role.CreatedAt, &role.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("role not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT permission
+		FROM role_permissions
+		WHERE role = $1
+		ORDER BY permission`, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var permission string
+		if err := rows.Scan(&permission); err != nil {
+			return nil, err
+		}
+		role.Permissions = append(role.Permissions, permission)
+	}
+
+	return role, rows.Err()
+}
+
+func (r *roleRepository) Create(ctx context.Context, role *domain.Role) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO roles (name, description)
+		VALUES ($1, $2)
+		RETURNING built_in, created_at, updated_at`, role.Name, role.Description).Scan(&role.BuiltIn, &role.CreatedAt, &role.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	if err := insertPermissions(ctx, tx, role.Name, role.Permissions); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *roleRepository) Update(ctx context.Context, role *domain.Role) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `
+		UPDATE roles
+		SET description = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE name = $1
+		RETURNING updated_at`, role.Name, role.Description).Scan(&role.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return errors.New("role not found")
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM role_permissions WHERE role = $1", role.Name); err != nil {
+		return err
+	}
+	if err := insertPermissions(ctx, tx, role.Name, role.Permissions); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *roleRepository) Delete(ctx context.Context, name string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM role_permissions WHERE role = $1", name); err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM roles WHERE name = $1 AND NOT built_in", name)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return errors.New("role not found")
+	}
+
+	return tx.Commit()
+}
+
+func (r *roleRepository) CountUsers(ctx context.Context, name string) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM users WHERE role = $1", name).Scan(&count)
+	return count, err
+}
+
+func insertPermissions(ctx context.Context, tx *sql.Tx, role string, permissions []string) error {
+	for _, permission := range permissions {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO role_permissions (role, permission)
+			VALUES ($1, $2)`, role, permission); err != nil {
+			return err
+		}
+	}
+	return nil
+}
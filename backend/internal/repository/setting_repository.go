@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"expense-management-system/internal/domain"
+)
+
+type settingRepository struct {
+	db *sql.DB
+}
+
+func NewSettingRepository(db *sql.DB) domain.SettingRepository {
+	return &settingRepository{db: db}
+}
+
+func (r *settingRepository) List(ctx context.Context) ([]*domain.Setting, error) {
+	query := `SELECT key, value, updated_by, updated_at FROM settings ORDER BY key`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var settings []*domain.Setting
+	for rows.Next() {
+		setting := &domain.Setting{Source: domain.SettingSourceDatabase}
+		if err := rows.Scan(&setting.Key, &setting.Value, &setting.UpdatedBy, &setting.UpdatedAt); err != nil {
+			return nil, err
+		}
+		settings = append(settings, setting)
+	}
+	return settings, rows.Err()
+}
+
+func (r *settingRepository) Set(ctx context.Context, key string, value int, changedBy int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	old, err := lockSetting(ctx, tx, key)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO settings (key, value, updated_by, updated_at)
+		VALUES ($1, $2, $3, CURRENT_TIMESTAMP)
+		ON CONFLICT (key) DO UPDATE
+		SET value = EXCLUDED.value, updated_by = EXCLUDED.updated_by, updated_at = EXCLUDED.updated_at`,
+		key, value, changedBy,
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := recordSettingChange(ctx, tx, key, old, &value, changedBy); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *settingRepository) Reset(ctx context.Context, key string, changedBy int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	old, err := lockSetting(ctx, tx, key)
+	if err != nil {
+		return err
+	}
+	if old == nil {
+		return nil
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM settings WHERE key = $1`, key); err != nil {
+		return err
+	}
+
+	if err := recordSettingChange(ctx, tx, key, old, nil, changedBy); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// lockSetting returns the stored value of a setting, if any, and keeps
+// concurrent changes of it waiting so the history stays in order.
+func lockSetting(ctx context.Context, tx *sql.Tx, key string) (*int, error) {
+	var value int
+	err := tx.QueryRowContext(ctx, `SELECT value FROM settings WHERE key = $1 FOR UPDATE`, key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &value, nil
+}
+
+func recordSettingChange(ctx context.Context, tx *sql.Tx, key string, oldValue, newValue *int, changedBy int) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO setting_changes (key, old_value, new_value, changed_by)
+		VALUES ($1, $2, $3, $4)`,
+		key, oldValue, newValue, changedBy,
+	)
+	return err
+}
+
+func (r *settingRepository) History(ctx context.Context, key string, limit int) ([]*domain.SettingChange, error) {
+	query := `
+		SELECT id, key, old_value, new_value, changed_by, changed_at
+		FROM setting_changes
+		WHERE key = $1 OR $1 = ''
+		ORDER BY changed_at DESC, id DESC
+		LIMIT $2`
+
+	rows, err := r.db.QueryContext(ctx, query, key, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []*domain.SettingChange
+	for rows.Next() {
+		change := &domain.SettingChange{}
+		if err := rows.Scan(&change.ID, &change.Key, &change.OldValue, &change.NewValue, &change.ChangedBy, &change.ChangedAt); err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	return changes, rows.Err()
+}
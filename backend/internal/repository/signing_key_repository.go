@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"expense-management-system/internal/domain"
+	"time"
+)
+
+// Key times are stored in UTC; they are only compared with times from the
+// API, never with the database clock.
+type signingKeyRepository struct {
+	db *sql.DB
+}
+
+func NewSigningKeyRepository(db *sql.DB) domain.SigningKeyRepository {
+	return &signingKeyRepository{db: db}
+}
+
+func (r *signingKeyRepository) ListUnexpired(ctx context.Context, now time.Time) ([]*domain.SigningKey, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT kid, algorithm, private_key, activates_at, retires_at, expires_at, created_at
+		FROM jwt_signing_keys
+		WHERE expires_at > $1
+		ORDER BY activates_at, kid`, now.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []*domain.SigningKey{}
+	for rows.Next() {
+		key := &domain.SigningKey{}
+		if err := rows.Scan(&key.KID, &key.Algorithm, &key.PrivateKey, &key.ActivatesAt, &key.RetiresAt, &key.ExpiresAt, &key.CreatedAt); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+func (r *signingKeyRepository) Create(ctx context.Context, key *domain.SigningKey) error {
+	query := `
+		INSERT INTO jwt_signing_keys (kid, algorithm, private_key, activates_at, retires_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING created_at`
+
+	return r.db.QueryRowContext(ctx, query,
+		key.KID,
+		key.Algorithm,
+		key.PrivateKey,
+		key.ActivatesAt.UTC(),
+		key.RetiresAt.UTC(),
+		key.ExpiresAt.UTC(),
+	).Scan(&key.CreatedAt)
+}
+
+func (r *signingKeyRepository) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM jwt_signing_keys WHERE expires_at <= $1", now.UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := result.RowsAffected()
+	return int(rows), err
+}
@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"expense-management-system/internal/domain"
+	"time"
+)
+
+type tokenRepository struct {
+	db *sql.DB
+}
+
+func NewTokenRepository(db *sql.DB) domain.TokenRepository {
+	return &tokenRepository{db: db}
+}
+
+func (r *tokenRepository) CreateRefreshToken(ctx context.Context, token *domain.RefreshToken) error {
+	query := `
+		INSERT INTO refresh_tokens (user_id, token_hash, family_id, access_jti, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`
+
+	err := r.db.QueryRowContext(ctx, query,
+		token.UserID,
+		token.TokenHash,
+		token.FamilyID,
+		token.AccessJTI,
+		token.ExpiresAt,
+	).Scan(&token.ID, &token.CreatedAt)
+
+	return err
+}
+
+func (r *tokenRepository) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*domain.RefreshToken, error) {
+	query := `
+		SELECT id, user_id, token_hash, family_id, access_jti, expires_at, revoked_at, created_at
+		FROM refresh_tokens
+		WHERE token_hash = $1`
+
+	token := &domain.RefreshToken{}
+	err := r.db.QueryRowContext(ctx, query, tokenHash).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.TokenHash,
+		&token.FamilyID,
+		&token.AccessJTI,
+		&token.ExpiresAt,
+		&token.RevokedAt,
+		&token.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, errors.New("refresh token not found")
+	}
+
+	return token, err
+}
+
+func (r *tokenRepository) RevokeRefreshToken(ctx context.Context, id int) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE refresh_tokens
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND revoked_at IS NULL`, id)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+func (r *tokenRepository) RevokeRefreshFamily(ctx context.Context, familyID string) ([]string, error) {
+	return r.revokeMany(ctx, "family_id = $1", familyID)
+}
+
+func (r *tokenRepository) RevokeUserRefreshTokens(ctx context.Context, userID int) ([]string, error) {
+	return r.revokeMany(ctx, "user_id = $1", userID)
+}
+
+// revokeMany revokes every live refresh token matching where and returns the
+// access token JTIs those sessions were last issued.
+func (r *tokenRepository) revokeMany(ctx context.Context, where string, arg interface{}) ([]string, error) {
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE ` + where + ` AND revoked_at IS NULL
+		RETURNING access_jti`
+
+	rows, err := r.db.QueryContext(ctx, query, arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jtis []string
+	for rows.Next() {
+		var jti string
+		if err := rows.Scan(&jti); err != nil {
+			return nil, err
+		}
+		jtis = append(jtis, jti)
+	}
+
+	return jtis, rows.Err()
+}
+
+func (r *tokenRepository) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	// Expired tokens are rejected anyway; clear their revocations out as new
+	// ones come in.
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM revoked_tokens WHERE expires_at < $1", time.Now()); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO revoked_tokens (jti, expires_at)
+		VALUES ($1, $2)
+		ON CONFLICT (jti) DO NOTHING`
+
+	_, err := r.db.ExecContext(ctx, query, jti, expiresAt)
+	return err
+}
+
+func (r *tokenRepository) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	var revoked bool
+	err := r.db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $1)", jti).Scan(&revoked)
+	return revoked, err
+}
+
+func (r *tokenRepository) CreatePasswordReset(ctx context.Context, token *domain.PasswordResetToken) error {
+	query := `
+		INSERT INTO password_reset_tokens (user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at`
+
+	return r.db.QueryRowContext(ctx, query, token.UserID, token.TokenHash, token.ExpiresAt).Scan(&token.ID, &token.CreatedAt)
+}
+
+func (r *tokenRepository) GetPasswordResetByHash(ctx context.Context, tokenHash string) (*domain.PasswordResetToken, error) {
+	query := `
+		SELECT id, user_id, token_hash, expires_at, used_at, created_at
+		FROM password_reset_tokens
+		WHERE token_hash = $1`
+
+	token := &domain.PasswordResetToken{}
+	err := r.db.QueryRowContext(ctx, query, tokenHash).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.TokenHash,
+		&token.ExpiresAt,
+		&token.UsedAt,
+		&token.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, errors.New("password reset token not found")
+	}
+
+	return token, err
+}
+
+func (r *tokenRepository) UsePasswordReset(ctx context.Context, id int) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE password_reset_tokens
+		SET used_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND used_at IS NULL`, id)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+func (r *tokenRepository) InvalidatePasswordResets(ctx context.Context, userID int) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE password_reset_tokens
+		SET used_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND used_at IS NULL`, userID)
+	return err
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"expense-management-system/internal/domain"
+	"time"
 )
 
 type userRepository struct {
@@ -15,39 +16,51 @@ func NewUserRepository(db *sql.DB) domain.UserRepository {
 	return &userRepository{db: db}
 }
 
+const userColumns = `id, email, password_hash, name, role, team_id, active, service_account, created_at, updated_at,
+	failed_login_attempts, last_failed_login_at, locked_until`
+
+func scanUser(row rowScanner) (*domain.User, error) {
+	user := &domain.User{}
+	err := row.Scan(
+		&user.ID,
+		&user.Email,
+		&user.PasswordHash,
+		&user.Name,
+		&user.Role,
+		&user.TeamID,
+		&user.Active,
+		&user.ServiceAccount,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+		&user.FailedLoginAttempts,
+		&user.LastFailedLoginAt,
+		&user.LockedUntil,
+	)
+	return user, err
+}
+
 func (r *userRepository) Create(ctx context.Context, user *domain.User) error {
 	query := `
-		INSERT INTO users (email, password_hash, name, role)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id, created_at, updated_at`
+		INSERT INTO users (email, password_hash, name, role, team_id, service_account)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, active, created_at, updated_at`
 
 	err := r.db.QueryRowContext(ctx, query,
 		user.Email,
 		user.PasswordHash,
 		user.Name,
 		user.Role,
-	).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
+		user.TeamID,
+		user.ServiceAccount,
+	).Scan(&user.ID, &user.Active, &user.CreatedAt, &user.UpdatedAt)
 
 	return err
 }
 
 func (r *userRepository) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
-	query := `
-		SELECT id, email, password_hash, name, role, created_at, updated_at
-		FROM users
-		WHERE email = $1`
-
-	user := &domain.User{}
-	err := r.db.QueryRowContext(ctx, query, email).Scan(
-		&user.ID,
-		&user.Email,
-		&user.PasswordHash,
-		&user.Name,
-		&user.Role,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+	query := `SELECT ` + userColumns + ` FROM users WHERE email = $1`
 
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, email))
 	if err == sql.ErrNoRows {
 		return nil, errors.New("user not found")
 	}
@@ -56,25 +69,128 @@ func (r *userRepository) GetByEmail(ctx context.Context, email string) (*domain.
 }
 
 func (r *userRepository) GetByID(ctx context.Context, id int) (*domain.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1`
+
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, errors.New("user not found")
+	}
+
+	return user, err
+}
+
+func (r *userRepository) List(ctx context.Context, role string, includeInactive bool, limit, offset int) ([]*domain.User, int, error) {
+	var users []*domain.User
+	var total int
+
+	whereClause := "WHERE ($1 = '' OR role = $1) AND ($2 OR active)"
+
+	countQuery := "SELECT COUNT(*) FROM users " + whereClause
+	if err := r.db.QueryRowContext(ctx, countQuery, role, includeInactive).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
 	query := `
-		SELECT id, email, password_hash, name, role, created_at, updated_at
+		SELECT ` + userColumns + `
 		FROM users
-		WHERE id = $1`
+		` + whereClause + `
+		ORDER BY name ASC, id ASC
+		LIMIT $3 OFFSET $4`
 
-	user := &domain.User{}
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&user.ID,
-		&user.Email,
-		&user.PasswordHash,
-		&user.Name,
-		&user.Role,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+	rows, err := r.db.QueryContext(ctx, query, role, includeInactive, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		users = append(users, user)
+	}
+
+	return users, total, nil
+}
+
+func (r *userRepository) Update(ctx context.Context, user *domain.User) error {
+	query := `
+		UPDATE users
+		SET email = $1, name = $2, team_id = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $4
+		RETURNING updated_at`
+
+	err := r.db.QueryRowContext(ctx, query, user.Email, user.Name, user.TeamID, user.ID).Scan(&user.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return errors.New("user not found")
+	}
+
+	return err
+}
+
+func (r *userRepository) UpdateRole(ctx context.Context, id int, role string) error {
+	return r.exec(ctx, `UPDATE users SET role = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`, role, id)
+}
+
+func (r *userRepository) UpdatePassword(ctx context.Context, id int, passwordHash string) error {
+	return r.exec(ctx, `UPDATE users SET password_hash = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`, passwordHash, id)
+}
+
+func (r *userRepository) SetActive(ctx context.Context, id int, active bool) error {
+	return r.exec(ctx, `UPDATE users SET active = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`, active, id)
+}
+
+func (r *userRepository) RecordLoginFailure(ctx context.Context, id int, maxAttempts int, windowStart, lockUntil time.Time) (*domain.User, error) {
+	// SET expressions all see the row as it was before the update, so the
+	// attempt count is computed the same way in both columns.
+	query := `
+		UPDATE users
+		SET failed_login_attempts = CASE
+		        WHEN (locked_until IS NOT NULL AND locked_until <= $3)
+		          OR last_failed_login_at IS NULL OR last_failed_login_at < $2 THEN 1
+		        ELSE failed_login_attempts + 1
+		    END,
+		    locked_until = CASE
+		        WHEN (CASE
+		                  WHEN (locked_until IS NOT NULL AND locked_until <= $3)
+		                    OR last_failed_login_at IS NULL OR last_failed_login_at < $2 THEN 1
+		                  ELSE failed_login_attempts + 1
+		              END) >= $4 THEN $5
+		        ELSE NULL
+		    END,
+		    last_failed_login_at = $3
+		WHERE id = $1
+		RETURNING ` + userColumns
 
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, id, windowStart, time.Now(), maxAttempts, lockUntil))
 	if err == sql.ErrNoRows {
 		return nil, errors.New("user not found")
 	}
 
 	return user, err
 }
+
+func (r *userRepository) ResetLoginFailures(ctx context.Context, id int) error {
+	return r.exec(ctx, `
+		UPDATE users
+		SET failed_login_attempts = 0, last_failed_login_at = NULL, locked_until = NULL
+		WHERE id = $1`, id)
+}
+
+func (r *userRepository) exec(ctx context.Context, query string, args ...interface{}) error {
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return errors.New("user not found")
+	}
+
+	return nil
+}
@@ -0,0 +1,86 @@
+// Package storage keeps generated and uploaded files on the local disk.
+package storage
+
+import (
+	"errors"
+	"expense-management-system/internal/domain"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalStore keeps files in a directory. Names are plain file names, so
+// nothing outside the directory can be reached.
+type LocalStore struct {
+	dir string
+}
+
+func NewLocalStore(dir string) (domain.FileStore, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+	return &LocalStore{dir: dir}, nil
+}
+
+func (s *LocalStore) path(name string) (string, error) {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("invalid file name %q", name)
+	}
+	return filepath.Join(s.dir, name), nil
+}
+
+// Create writes to a temporary file that takes the name on Close, so a
+// file is never seen half written.
+func (s *LocalStore) Create(name string) (io.WriteCloser, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.CreateTemp(s.dir, "."+name+".*")
+	if err != nil {
+		return nil, err
+	}
+	return &pendingFile{File: f, path: path}, nil
+}
+
+func (s *LocalStore) Open(name string) (io.ReadCloser, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, errors.New("file not found")
+	}
+	return f, err
+}
+
+func (s *LocalStore) Remove(name string) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+type pendingFile struct {
+	*os.File
+	path string
+}
+
+func (f *pendingFile) Close() error {
+	if err := f.File.Close(); err != nil {
+		os.Remove(f.File.Name())
+		return err
+	}
+	if err := os.Rename(f.File.Name(), f.path); err != nil {
+		os.Remove(f.File.Name())
+		return err
+	}
+	return nil
+}
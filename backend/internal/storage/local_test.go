@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"io"
+	"os"
+	"testing"
+)
+
+func TestLocalStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewLocalStore(dir)
+	if err != nil {
+		t.Fatalf("NewLocalStore() unexpected error = %v", err)
+	}
+
+	w, err := store.Create("report-1.csv")
+	if err != nil {
+		t.Fatalf("Create() unexpected error = %v", err)
+	}
+	io.WriteString(w, "ID,Amount\n")
+	if _, err := store.Open("report-1.csv"); err == nil {
+		t.Error("Expected the file to appear only when closed")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() unexpected error = %v", err)
+	}
+
+	r, err := store.Open("report-1.csv")
+	if err != nil {
+		t.Fatalf("Open() unexpected error = %v", err)
+	}
+	content, _ := io.ReadAll(r)
+	r.Close()
+	if string(content) != "ID,Amount\n" {
+		t.Errorf("Open() read %q", content)
+	}
+
+	if err := store.Remove("report-1.csv"); err != nil {
+		t.Fatalf("Remove() unexpected error = %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Expected an empty directory, got %d entries", len(entries))
+	}
+}
+
+func TestLocalStoreNames(t *testing.T) {
+	store, _ := NewLocalStore(t.TempDir())
+	for _, name := range []string{"", ".", "..", "../secret", "a/b", `a\b`} {
+		if _, err := store.Create(name); err == nil {
+			t.Errorf("Create(%q) expected an error", name)
+		}
+		if _, err := store.Open(name); err == nil {
+			t.Errorf("Open(%q) expected an error", name)
+		}
+	}
+}
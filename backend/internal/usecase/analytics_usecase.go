@@ -0,0 +1,63 @@
+package usecase
+
+import (
+	"context"
+	"expense-management-system/internal/domain"
+	"fmt"
+)
+
+const (
+	defaultAnalyticsGroups = 100
+	maxAnalyticsGroups     = 1000
+	defaultTopSpenders     = 10
+)
+
+type analyticsUsecase struct {
+	analyticsRepo domain.AnalyticsRepository
+}
+
+func NewAnalyticsUsecase(analyticsRepo domain.AnalyticsRepository) domain.AnalyticsUsecase {
+	return &analyticsUsecase{analyticsRepo: analyticsRepo}
+}
+
+// scopeAnalyticsFilter validates filter and, as for the expense list, narrows it to the
+// user's own expenses unless they may see everyone's.
+func scopeAnalyticsFilter(userID int, filter domain.ExpenseFilter, canViewAll bool) (domain.ExpenseFilter, error) {
+	if err := validateExpenseFilter(filter); err != nil {
+		return filter, err
+	}
+	if !canViewAll {
+		filter.SubmitterID = &userID
+	}
+	return filter, nil
+}
+
+func (u *analyticsUsecase) Summary(ctx context.Context, userID int, filter domain.ExpenseFilter, canViewAll bool) (*domain.AnalyticsSummary, error) {
+	filter, err := scopeAnalyticsFilter(userID, filter, canViewAll)
+	if err != nil {
+		return nil, err
+	}
+	return u.analyticsRepo.Summary(ctx, filter)
+}
+
+func (u *analyticsUsecase) Breakdown(ctx context.Context, userID int, filter domain.ExpenseFilter, dimension string, limit int, canViewAll bool) ([]*domain.AnalyticsGroup, error) {
+	if !domain.IsValidAnalyticsDimension(dimension) {
+		return nil, fmt.Errorf("%w: cannot group by %q", domain.ErrInvalidFilter, dimension)
+	}
+	if limit < 1 || limit > maxAnalyticsGroups {
+		limit = defaultAnalyticsGroups
+	}
+
+	filter, err := scopeAnalyticsFilter(userID, filter, canViewAll)
+	if err != nil {
+		return nil, err
+	}
+	return u.analyticsRepo.Breakdown(ctx, filter, dimension, limit)
+}
+
+func (u *analyticsUsecase) TopSpenders(ctx context.Context, userID int, filter domain.ExpenseFilter, limit int, canViewAll bool) ([]*domain.AnalyticsGroup, error) {
+	if limit < 1 || limit > 100 {
+		limit = defaultTopSpenders
+	}
+	return u.Breakdown(ctx, userID, filter, domain.AnalyticsByEmployee, limit, canViewAll)
+}
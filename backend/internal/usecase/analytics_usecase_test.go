@@ -0,0 +1,76 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"expense-management-system/internal/domain"
+	"testing"
+)
+
+type mockAnalyticsRepo struct {
+	filter    domain.ExpenseFilter
+	dimension string
+	limit     int
+	calls     int
+	groups    []*domain.AnalyticsGroup
+}
+
+func (m *mockAnalyticsRepo) Summary(ctx context.Context, filter domain.ExpenseFilter) (*domain.AnalyticsSummary, error) {
+	m.filter = filter
+	m.calls++
+	return &domain.AnalyticsSummary{}, nil
+}
+
+func (m *mockAnalyticsRepo) Breakdown(ctx context.Context, filter domain.ExpenseFilter, dimension string, limit int) ([]*domain.AnalyticsGroup, error) {
+	m.filter = filter
+	m.dimension = dimension
+	m.limit = limit
+	m.calls++
+	return m.groups, nil
+}
+
+func TestAnalyticsUsecase_Scope(t *testing.T) {
+	ctx := context.Background()
+	repo := &mockAnalyticsRepo{}
+	uc := NewAnalyticsUsecase(repo)
+
+	if _, err := uc.Summary(ctx, 1, domain.ExpenseFilter{SubmitterID: intPtr(2)}, false); err != nil {
+		t.Fatalf("Summary() unexpected error = %v", err)
+	}
+	if *repo.filter.SubmitterID != 1 {
+		t.Errorf("Expected an employee to see only their own expenses, got submitter %d", *repo.filter.SubmitterID)
+	}
+
+	if _, err := uc.Breakdown(ctx, 3, domain.ExpenseFilter{}, domain.AnalyticsByApprover, 0, true); err != nil {
+		t.Fatalf("Breakdown() unexpected error = %v", err)
+	}
+	if repo.filter.SubmitterID != nil {
+		t.Errorf("Expected no submitter filter with expense:read_all, got %d", *repo.filter.SubmitterID)
+	}
+	if repo.limit != defaultAnalyticsGroups {
+		t.Errorf("Expected the default limit, got %d", repo.limit)
+	}
+
+	if _, err := uc.TopSpenders(ctx, 3, domain.ExpenseFilter{}, 500, true); err != nil {
+		t.Fatalf("TopSpenders() unexpected error = %v", err)
+	}
+	if repo.dimension != domain.AnalyticsByEmployee || repo.limit != defaultTopSpenders {
+		t.Errorf("Expected the top %d employees, got %d by %s", defaultTopSpenders, repo.limit, repo.dimension)
+	}
+}
+
+func TestAnalyticsUsecase_InvalidInput(t *testing.T) {
+	ctx := context.Background()
+	repo := &mockAnalyticsRepo{}
+	uc := NewAnalyticsUsecase(repo)
+
+	if _, err := uc.Breakdown(ctx, 3, domain.ExpenseFilter{}, "weekday", 10, true); !errors.Is(err, domain.ErrInvalidFilter) {
+		t.Errorf("Breakdown() error = %v, want ErrInvalidFilter", err)
+	}
+	if _, err := uc.Summary(ctx, 3, domain.ExpenseFilter{Statuses: []string{"paid"}}, true); !errors.Is(err, domain.ErrInvalidFilter) {
+		t.Errorf("Summary() error = %v, want ErrInvalidFilter", err)
+	}
+	if repo.calls != 0 {
+		t.Errorf("Expected invalid input to be rejected before querying, got %d calls", repo.calls)
+	}
+}
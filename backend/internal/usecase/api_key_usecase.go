@@ -0,0 +1,347 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"expense-management-system/internal/domain"
+	"expense-management-system/pkg/config"
+	"expense-management-system/pkg/logger"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// API keys look like ems_<prefix>_<secret>. The prefix is stored in clear
+// so a key can be found without scanning every hash.
+const apiKeyMarker = "ems"
+
+// serviceAccountEmailDomain is reserved (RFC 2606), so service account
+// emails never collide with real ones and cannot receive mail.
+const serviceAccountEmailDomain = "service-accounts.invalid"
+
+// lastUsedInterval bounds how often a key's last use is written.
+const lastUsedInterval = time.Minute
+
+var errInvalidAPIKey = errors.New("invalid api key")
+
+var nonSlugChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+type apiKeyUsecase struct {
+	apiKeyRepo domain.APIKeyRepository
+	userRepo   domain.UserRepository
+	auditRepo  domain.AuditLogRepository
+	cfg        *config.Config
+	now        func() time.Time
+}
+
+// NewAPIKeyUsecase manages service accounts and their API keys. Service
+// accounts are users with the service role, which grants nothing by itself:
+// a request made with a key may do what the key's scopes allow.
+func NewAPIKeyUsecase(apiKeyRepo domain.APIKeyRepository, userRepo domain.UserRepository, auditRepo domain.AuditLogRepository, cfg *config.Config) domain.APIKeyUsecase {
+	return &apiKeyUsecase{
+		apiKeyRepo: apiKeyRepo,
+		userRepo:   userRepo,
+		auditRepo:  auditRepo,
+		cfg:        cfg,
+		now:        time.Now,
+	}
+}
+
+func (u *apiKeyUsecase) CreateServiceAccount(ctx context.Context, adminID int, name string) (*domain.User, error) {
+	name = strings.TrimSpace(name)
+	slug := strings.Trim(nonSlugChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if slug == "" {
+		return nil, errors.New("name is required")
+	}
+
+	email := "svc-" + slug + "@" + serviceAccountEmailDomain
+	if _, err := u.userRepo.GetByEmail(ctx, email); err == nil {
+		return nil, errors.New("service account already exists")
+	}
+
+	account := &domain.User{
+		Email:          email,
+		Name:           name,
+		Role:           domain.RoleService,
+		ServiceAccount: true,
+	}
+	if err := u.userRepo.Create(ctx, account); err != nil {
+		return nil, err
+	}
+
+	u.audit(ctx, adminID, account.ID, domain.ActionServiceAccountCreate, map[string]interface{}{
+		"name":  account.Name,
+		"email": account.Email,
+	})
+	logger.InfoLogger.Printf("[SECURITY] Service account %d (%s) created by admin %d", account.ID, account.Email, adminID)
+
+	return account, nil
+}
+
+func (u *apiKeyUsecase) ListServiceAccounts(ctx context.Context) ([]*domain.User, error) {
+	accounts, _, err := u.userRepo.List(ctx, domain.RoleService, true, 1000, 0)
+	return accounts, err
+}
+
+func (u *apiKeyUsecase) CreateKey(ctx context.Context, adminID int, granted []string, serviceAccountID int, name string, scopes []string, expiresAt *time.Time) (*domain.APIKey, string, error) {
+	if _, err := u.getServiceAccount(ctx, serviceAccountID); err != nil {
+		return nil, "", err
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 100 {
+		return nil, "", errors.New("name is required and must be at most 100 characters")
+	}
+	scopes, err := normalizeScopes(scopes)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := checkScopes(granted, scopes); err != nil {
+		return nil, "", err
+	}
+
+	now := u.now()
+	if expiresAt == nil {
+		if u.cfg.APIKeyTTLDays > 0 {
+			defaultExpiry := now.AddDate(0, 0, u.cfg.APIKeyTTLDays)
+			expiresAt = &defaultExpiry
+		}
+	} else if !expiresAt.After(now) {
+		return nil, "", errors.New("expires_at must be in the future")
+	}
+
+	key, rawKey, err := newAPIKey(serviceAccountID, adminID, name, scopes, expiresAt)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := u.apiKeyRepo.Create(ctx, key); err != nil {
+		return nil, "", err
+	}
+
+	u.audit(ctx, adminID, serviceAccountID, domain.ActionAPIKeyCreate, map[string]interface{}{
+		"key_id":     key.ID,
+		"prefix":     key.Prefix,
+		"name":       key.Name,
+		"scopes":     key.Scopes,
+		"expires_at": key.ExpiresAt,
+	})
+	logger.InfoLogger.Printf("[SECURITY] API key %s for service account %d created by admin %d: %v", key.Prefix, serviceAccountID, adminID, key.Scopes)
+
+	return key, rawKey, nil
+}
+
+func (u *apiKeyUsecase) ListKeys(ctx context.Context, serviceAccountID int) ([]*domain.APIKey, error) {
+	if _, err := u.getServiceAccount(ctx, serviceAccountID); err != nil {
+		return nil, err
+	}
+	return u.apiKeyRepo.ListByServiceAccount(ctx, serviceAccountID)
+}
+
+// RotateKey gives the replacement the same lifetime the old key was issued
+// with. Rotating issues a new secret, so it needs the same scopes as
+// creating the key. The old key expires after APIKeyRotationGraceHours, or earlier if
+// it was due to anyway.
+func (u *apiKeyUsecase) RotateKey(ctx context.Context, adminID int, granted []string, serviceAccountID, keyID int) (*domain.APIKey, string, error) {
+	old, err := u.getKey(ctx, serviceAccountID, keyID)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := checkScopes(granted, old.Scopes); err != nil {
+		return nil, "", err
+	}
+
+	now := u.now()
+	switch {
+	case old.RevokedAt != nil:
+		return nil, "", errors.New("api key is revoked")
+	case old.ReplacedByID != nil:
+		return nil, "", errors.New("api key was already rotated")
+	case old.ExpiresAt != nil && !old.ExpiresAt.After(now):
+		return nil, "", errors.New("api key has expired")
+	}
+
+	var expiresAt *time.Time
+	if old.ExpiresAt != nil {
+		expiry := now.Add(old.ExpiresAt.Sub(old.CreatedAt))
+		expiresAt = &expiry
+	}
+
+	key, rawKey, err := newAPIKey(serviceAccountID, adminID, old.Name, old.Scopes, expiresAt)
+	if err != nil {
+		return nil, "", err
+	}
+
+	graceEnd := now.Add(time.Duration(u.cfg.APIKeyRotationGraceHours) * time.Hour)
+	if err := u.apiKeyRepo.Rotate(ctx, old.ID, key, graceEnd); err != nil {
+		return nil, "", err
+	}
+
+	u.audit(ctx, adminID, serviceAccountID, domain.ActionAPIKeyRotate, map[string]interface{}{
+		"key_id":         key.ID,
+		"prefix":         key.Prefix,
+		"old_key_id":     old.ID,
+		"old_prefix":     old.Prefix,
+		"old_expires_at": graceEnd,
+	})
+	logger.InfoLogger.Printf("[SECURITY] API key %s rotated to %s by admin %d", old.Prefix, key.Prefix, adminID)
+
+	return key, rawKey, nil
+}
+
+func (u *apiKeyUsecase) RevokeKey(ctx context.Context, adminID, serviceAccountID, keyID int) error {
+	key, err := u.getKey(ctx, serviceAccountID, keyID)
+	if err != nil {
+		return err
+	}
+
+	revoked, err := u.apiKeyRepo.Revoke(ctx, key.ID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return errors.New("api key is already revoked")
+	}
+
+	u.audit(ctx, adminID, serviceAccountID, domain.ActionAPIKeyRevoke, map[string]interface{}{
+		"key_id": key.ID,
+		"prefix": key.Prefix,
+	})
+	logger.InfoLogger.Printf("[SECURITY] API key %s revoked by admin %d", key.Prefix, adminID)
+
+	return nil
+}
+
+// Authenticate fails the same way whatever is wrong with the key; the
+// reason is only logged.
+func (u *apiKeyUsecase) Authenticate(ctx context.Context, rawKey, ipAddress string) (*domain.User, *domain.APIKey, error) {
+	parts := strings.SplitN(rawKey, "_", 3)
+	if len(parts) != 3 || parts[0] != apiKeyMarker || parts[1] == "" || parts[2] == "" {
+		return nil, nil, errInvalidAPIKey
+	}
+
+	key, err := u.apiKeyRepo.GetByPrefix(ctx, parts[1])
+	if err != nil {
+		return nil, nil, errInvalidAPIKey
+	}
+	if subtle.ConstantTimeCompare([]byte(hashToken(rawKey)), []byte(key.KeyHash)) != 1 {
+		logger.ErrorLogger.Printf("[SECURITY] Wrong secret for API key %s from %s", key.Prefix, ipAddress)
+		return nil, nil, errInvalidAPIKey
+	}
+
+	now := u.now()
+	if key.RevokedAt != nil {
+		logger.ErrorLogger.Printf("[SECURITY] Revoked API key %s used from %s", key.Prefix, ipAddress)
+		return nil, nil, errInvalidAPIKey
+	}
+	if key.ExpiresAt != nil && !key.ExpiresAt.After(now) {
+		logger.ErrorLogger.Printf("[SECURITY] Expired API key %s used from %s", key.Prefix, ipAddress)
+		return nil, nil, errInvalidAPIKey
+	}
+
+	account, err := u.userRepo.GetByID(ctx, key.ServiceAccountID)
+	if err != nil || !account.ServiceAccount || !account.Active {
+		logger.ErrorLogger.Printf("[SECURITY] API key %s used for an unavailable service account", key.Prefix)
+		return nil, nil, errInvalidAPIKey
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= lastUsedInterval || key.LastUsedIP == nil || *key.LastUsedIP != ipAddress {
+		if err := u.apiKeyRepo.TouchLastUsed(ctx, key.ID, ipAddress); err != nil {
+			logger.ErrorLogger.Printf("Failed to record use of API key %s: %v", key.Prefix, err)
+		}
+	}
+
+	return account, key, nil
+}
+
+func (u *apiKeyUsecase) getServiceAccount(ctx context.Context, id int) (*domain.User, error) {
+	account, err := u.userRepo.GetByID(ctx, id)
+	if err != nil || !account.ServiceAccount {
+		return nil, errors.New("service account not found")
+	}
+	return account, nil
+}
+
+// getKey only finds keys of the given service account, so a key cannot be
+// managed through another account's URL.
+func (u *apiKeyUsecase) getKey(ctx context.Context, serviceAccountID, keyID int) (*domain.APIKey, error) {
+	if _, err := u.getServiceAccount(ctx, serviceAccountID); err != nil {
+		return nil, err
+	}
+	key, err := u.apiKeyRepo.GetByID(ctx, keyID)
+	if err != nil || key.ServiceAccountID != serviceAccountID {
+		return nil, errors.New("api key not found")
+	}
+	return key, nil
+}
+
+func (u *apiKeyUsecase) audit(ctx context.Context, adminID, serviceAccountID int, action string, metadata map[string]interface{}) {
+	auditLog := &domain.AuditLog{
+		SubjectUserID: &serviceAccountID,
+		UserID:        &adminID,
+		Action:        action,
+		Metadata:      metadata,
+	}
+	u.auditRepo.Create(ctx, auditLog)
+}
+
+// newAPIKey generates a key and returns it with its plaintext.
+func newAPIKey(serviceAccountID, createdBy int, name string, scopes []string, expiresAt *time.Time) (*domain.APIKey, string, error) {
+	prefixBytes := make([]byte, 6)
+	if _, err := rand.Read(prefixBytes); err != nil {
+		return nil, "", err
+	}
+	secret, err := generateSecureToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	prefix := hex.EncodeToString(prefixBytes)
+	rawKey := apiKeyMarker + "_" + prefix + "_" + secret
+
+	return &domain.APIKey{
+		ServiceAccountID: serviceAccountID,
+		Name:             name,
+		Prefix:           prefix,
+		KeyHash:          hashToken(rawKey),
+		Scopes:           scopes,
+		ExpiresAt:        expiresAt,
+		CreatedBy:        createdBy,
+	}, rawKey, nil
+}
+
+// normalizeScopes is normalizePermissions for API keys, which need at least
+// one scope and cannot be given every permission.
+func normalizeScopes(scopes []string) ([]string, error) {
+	scopes, err := normalizePermissions(scopes)
+	if err != nil {
+		return nil, err
+	}
+	if len(scopes) == 0 {
+		return nil, errors.New("at least one scope is required")
+	}
+	for _, scope := range scopes {
+		if !domain.IsValidScope(scope) {
+			return nil, fmt.Errorf("%q cannot be granted to an api key", scope)
+		}
+	}
+	return scopes, nil
+}
+
+// checkScopes is checkGrant for API keys: service_account:manage alone must
+// not be a way to obtain permissions, so the caller needs every scope.
+func checkScopes(granted, scopes []string) error {
+	held := make(map[string]bool, len(granted))
+	for _, p := range granted {
+		held[p] = true
+	}
+	for _, scope := range scopes {
+		if !held[scope] {
+			return domain.ErrScopeNotGrantable
+		}
+	}
+	return nil
+}
@@ -0,0 +1,379 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"expense-management-system/internal/domain"
+	"expense-management-system/pkg/config"
+	"strings"
+	"testing"
+	"time"
+)
+
+type mockAPIKeyRepo struct {
+	keys    map[int]*domain.APIKey
+	nextID  int
+	touches int
+}
+
+func newMockAPIKeyRepo() *mockAPIKeyRepo {
+	return &mockAPIKeyRepo{keys: map[int]*domain.APIKey{}, nextID: 1}
+}
+
+func (m *mockAPIKeyRepo) Create(ctx context.Context, key *domain.APIKey) error {
+	key.ID = m.nextID
+	key.CreatedAt = time.Now()
+	m.nextID++
+	copy := *key
+	m.keys[key.ID] = &copy
+	return nil
+}
+
+func (m *mockAPIKeyRepo) GetByID(ctx context.Context, id int) (*domain.APIKey, error) {
+	key, ok := m.keys[id]
+	if !ok {
+		return nil, errors.New("api key not found")
+	}
+	copy := *key
+	return &copy, nil
+}
+
+func (m *mockAPIKeyRepo) GetByPrefix(ctx context.Context, prefix string) (*domain.APIKey, error) {
+	for _, key := range m.keys {
+		if key.Prefix == prefix {
+			copy := *key
+			return &copy, nil
+		}
+	}
+	return nil, errors.New("api key not found")
+}
+
+func (m *mockAPIKeyRepo) ListByServiceAccount(ctx context.Context, serviceAccountID int) ([]*domain.APIKey, error) {
+	keys := []*domain.APIKey{}
+	for _, key := range m.keys {
+		if key.ServiceAccountID == serviceAccountID {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (m *mockAPIKeyRepo) Rotate(ctx context.Context, oldID int, newKey *domain.APIKey, oldExpiresAt time.Time) error {
+	if err := m.Create(ctx, newKey); err != nil {
+		return err
+	}
+	old := m.keys[oldID]
+	old.ReplacedByID = &newKey.ID
+	if old.ExpiresAt == nil || old.ExpiresAt.After(oldExpiresAt) {
+		old.ExpiresAt = &oldExpiresAt
+	}
+	return nil
+}
+
+func (m *mockAPIKeyRepo) Revoke(ctx context.Context, id int) (bool, error) {
+	key := m.keys[id]
+	if key.RevokedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	key.RevokedAt = &now
+	return true, nil
+}
+
+func (m *mockAPIKeyRepo) TouchLastUsed(ctx context.Context, id int, ipAddress string) error {
+	m.touches++
+	now := time.Now()
+	m.keys[id].LastUsedAt = &now
+	m.keys[id].LastUsedIP = &ipAddress
+	return nil
+}
+
+type apiKeyTestEnv struct {
+	uc      *apiKeyUsecase
+	keyRepo *mockAPIKeyRepo
+	users   map[int]*domain.User
+	audits  []*domain.AuditLog
+	now     time.Time
+}
+
+// newAPIKeyTestEnv has service account 5 and human user 1.
+func newAPIKeyTestEnv() *apiKeyTestEnv {
+	env := &apiKeyTestEnv{
+		keyRepo: newMockAPIKeyRepo(),
+		users: map[int]*domain.User{
+			1: {ID: 1, Email: "employee1@example.com", Role: domain.RoleEmployee, Active: true},
+			5: {ID: 5, Email: "svc-hr-sync@service-accounts.invalid", Role: domain.RoleService, Active: true, ServiceAccount: true},
+		},
+		now: time.Now(),
+	}
+	userRepo := &mockUserRepo{
+		getByIDFunc: func(ctx context.Context, id int) (*domain.User, error) {
+			user, ok := env.users[id]
+			if !ok {
+				return nil, errors.New("user not found")
+			}
+			return user, nil
+		},
+		getByEmailFunc: func(ctx context.Context, email string) (*domain.User, error) {
+			for _, user := range env.users {
+				if user.Email == email {
+					return user, nil
+				}
+			}
+			return nil, errors.New("user not found")
+		},
+		createFunc: func(ctx context.Context, user *domain.User) error {
+			user.ID = 10 + len(env.users)
+			env.users[user.ID] = user
+			return nil
+		},
+	}
+	auditRepo := &mockAuditRepo{
+		createFunc: func(ctx context.Context, log *domain.AuditLog) error {
+			env.audits = append(env.audits, log)
+			return nil
+		},
+	}
+	cfg := &config.Config{APIKeyTTLDays: 90, APIKeyRotationGraceHours: 24}
+
+	env.uc = NewAPIKeyUsecase(env.keyRepo, userRepo, auditRepo, cfg).(*apiKeyUsecase)
+	env.uc.now = func() time.Time { return env.now }
+	return env
+}
+
+func TestAPIKeyUsecase_CreateServiceAccount(t *testing.T) {
+	env := newAPIKeyTestEnv()
+	ctx := context.Background()
+
+	account, err := env.uc.CreateServiceAccount(ctx, 9, "  ERP Export ")
+	if err != nil {
+		t.Fatalf("CreateServiceAccount() unexpected error = %v", err)
+	}
+	if !account.ServiceAccount || account.Role != domain.RoleService || account.PasswordHash != "" {
+		t.Errorf("CreateServiceAccount() = %+v", account)
+	}
+	if account.Email != "svc-erp-export@service-accounts.invalid" || account.Name != "ERP Export" {
+		t.Errorf("CreateServiceAccount() email = %s, name = %s", account.Email, account.Name)
+	}
+	if len(env.audits) != 1 || env.audits[0].Action != domain.ActionServiceAccountCreate || *env.audits[0].SubjectUserID != account.ID {
+		t.Errorf("Expected a service_account_create audit entry, got %+v", env.audits)
+	}
+
+	if _, err := env.uc.CreateServiceAccount(ctx, 9, "erp export"); err == nil {
+		t.Error("Expected a duplicate service account to be rejected")
+	}
+	if _, err := env.uc.CreateServiceAccount(ctx, 9, " -- "); err == nil {
+		t.Error("Expected a name without letters or digits to be rejected")
+	}
+}
+
+func TestAPIKeyUsecase_CreateKey(t *testing.T) {
+	yesterday := time.Now().Add(-24 * time.Hour)
+
+	tests := []struct {
+		name             string
+		serviceAccountID int
+		keyName          string
+		granted          []string
+		scopes           []string
+		expiresAt        *time.Time
+		wantErr          bool
+	}{
+		{
+			name:             "Valid key",
+			serviceAccountID: 5,
+			keyName:          "nightly sync",
+			scopes:           []string{domain.PermExpenseReadAll, domain.PermAuditRead, domain.PermExpenseReadAll},
+		},
+		{
+			name:             "Human user",
+			serviceAccountID: 1,
+			keyName:          "laptop",
+			scopes:           []string{domain.PermExpenseReadAll},
+			wantErr:          true,
+		},
+		{
+			name:             "No scopes",
+			serviceAccountID: 5,
+			keyName:          "nightly sync",
+			wantErr:          true,
+		},
+		{
+			name:             "Unknown scope",
+			serviceAccountID: 5,
+			keyName:          "nightly sync",
+			scopes:           []string{"expense:everything"},
+			wantErr:          true,
+		},
+		{
+			name:             "Key management scope",
+			serviceAccountID: 5,
+			keyName:          "nightly sync",
+			scopes:           []string{domain.PermServiceAccountManage},
+			wantErr:          true,
+		},
+		{
+			name:             "Scope the admin does not hold",
+			serviceAccountID: 5,
+			keyName:          "nightly sync",
+			granted:          []string{domain.PermServiceAccountManage, domain.PermAuditRead},
+			scopes:           []string{domain.PermExpenseReadAll, domain.PermAuditRead},
+			wantErr:          true,
+		},
+		{
+			name:             "Missing name",
+			serviceAccountID: 5,
+			scopes:           []string{domain.PermExpenseReadAll},
+			wantErr:          true,
+		},
+		{
+			name:             "Expiry in the past",
+			serviceAccountID: 5,
+			keyName:          "nightly sync",
+			scopes:           []string{domain.PermExpenseReadAll},
+			expiresAt:        &yesterday,
+			wantErr:          true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newAPIKeyTestEnv()
+			granted := tt.granted
+			if granted == nil {
+				granted = adminPermissions()
+			}
+
+			key, rawKey, err := env.uc.CreateKey(context.Background(), 9, granted, tt.serviceAccountID, tt.keyName, tt.scopes, tt.expiresAt)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CreateKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if !strings.HasPrefix(rawKey, "ems_"+key.Prefix+"_") {
+				t.Errorf("CreateKey() key %q does not start with its prefix %q", rawKey, key.Prefix)
+			}
+			if key.KeyHash == rawKey || key.KeyHash != hashToken(rawKey) {
+				t.Error("Expected only the hash of the key to be stored")
+			}
+			if len(key.Scopes) != 2 || key.Scopes[0] != domain.PermAuditRead {
+				t.Errorf("CreateKey() scopes = %v", key.Scopes)
+			}
+			if key.ExpiresAt == nil || !key.ExpiresAt.Equal(env.now.AddDate(0, 0, 90)) {
+				t.Errorf("CreateKey() expires_at = %v, want the default lifetime", key.ExpiresAt)
+			}
+			if len(env.audits) != 1 || env.audits[0].Action != domain.ActionAPIKeyCreate {
+				t.Errorf("Expected an api_key_create audit entry, got %+v", env.audits)
+			}
+		})
+	}
+}
+
+func TestAPIKeyUsecase_Authenticate(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Valid key records its last use", func(t *testing.T) {
+		env := newAPIKeyTestEnv()
+		_, rawKey, _ := env.uc.CreateKey(ctx, 9, adminPermissions(), 5, "sync", []string{domain.PermExpenseReadAll}, nil)
+
+		user, key, err := env.uc.Authenticate(ctx, rawKey, "10.0.0.7")
+		if err != nil {
+			t.Fatalf("Authenticate() unexpected error = %v", err)
+		}
+		if user.ID != 5 || key.Scopes[0] != domain.PermExpenseReadAll {
+			t.Errorf("Authenticate() = %+v, %+v", user, key)
+		}
+
+		// A second call straight away from the same address is not written.
+		env.uc.Authenticate(ctx, rawKey, "10.0.0.7")
+		if env.keyRepo.touches != 1 {
+			t.Errorf("Expected last use to be recorded once, got %d writes", env.keyRepo.touches)
+		}
+	})
+
+	rejected := []struct {
+		name  string
+		setup func(env *apiKeyTestEnv, rawKey string) string
+	}{
+		{"Malformed", func(env *apiKeyTestEnv, rawKey string) string { return "not-a-key" }},
+		{"Unknown prefix", func(env *apiKeyTestEnv, rawKey string) string { return "ems_000000000000_secret" }},
+		{"Wrong secret", func(env *apiKeyTestEnv, rawKey string) string { return rawKey[:len(rawKey)-4] + "AAAA" }},
+		{"Revoked", func(env *apiKeyTestEnv, rawKey string) string {
+			env.uc.RevokeKey(context.Background(), 9, 5, 1)
+			return rawKey
+		}},
+		{"Expired", func(env *apiKeyTestEnv, rawKey string) string {
+			env.now = env.now.AddDate(0, 0, 91)
+			return rawKey
+		}},
+		{"Deactivated service account", func(env *apiKeyTestEnv, rawKey string) string {
+			env.users[5].Active = false
+			return rawKey
+		}},
+	}
+	for _, tt := range rejected {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newAPIKeyTestEnv()
+			_, rawKey, _ := env.uc.CreateKey(ctx, 9, adminPermissions(), 5, "sync", []string{domain.PermExpenseReadAll}, nil)
+
+			if _, _, err := env.uc.Authenticate(ctx, tt.setup(env, rawKey), "10.0.0.7"); err == nil {
+				t.Error("Expected the key to be rejected")
+			}
+		})
+	}
+}
+
+func TestAPIKeyUsecase_RotateKey(t *testing.T) {
+	ctx := context.Background()
+	env := newAPIKeyTestEnv()
+	old, oldRawKey, _ := env.uc.CreateKey(ctx, 9, adminPermissions(), 5, "sync", []string{domain.PermExpenseReadAll}, nil)
+
+	key, rawKey, err := env.uc.RotateKey(ctx, 9, adminPermissions(), 5, old.ID)
+	if err != nil {
+		t.Fatalf("RotateKey() unexpected error = %v", err)
+	}
+	if key.Name != old.Name || len(key.Scopes) != 1 || rawKey == oldRawKey {
+		t.Errorf("RotateKey() = %+v", key)
+	}
+
+	// Both keys work during the grace period; afterwards only the new one.
+	if _, _, err := env.uc.Authenticate(ctx, oldRawKey, "10.0.0.7"); err != nil {
+		t.Errorf("Expected the old key to work during the grace period, got %v", err)
+	}
+	env.now = env.now.Add(25 * time.Hour)
+	if _, _, err := env.uc.Authenticate(ctx, oldRawKey, "10.0.0.7"); err == nil {
+		t.Error("Expected the old key to expire after the grace period")
+	}
+	if _, _, err := env.uc.Authenticate(ctx, rawKey, "10.0.0.7"); err != nil {
+		t.Errorf("Expected the new key to work, got %v", err)
+	}
+
+	if _, _, err := env.uc.RotateKey(ctx, 9, adminPermissions(), 5, old.ID); err == nil {
+		t.Error("Expected a rotated key not to be rotated again")
+	}
+	if _, _, err := env.uc.RotateKey(ctx, 9, []string{domain.PermServiceAccountManage}, 5, key.ID); !errors.Is(err, domain.ErrScopeNotGrantable) {
+		t.Errorf("RotateKey() without the key's scopes error = %v, want %v", err, domain.ErrScopeNotGrantable)
+	}
+	if env.audits[len(env.audits)-1].Action != domain.ActionAPIKeyRotate {
+		t.Errorf("Expected an api_key_rotate audit entry, got %+v", env.audits)
+	}
+}
+
+func TestAPIKeyUsecase_RevokeKey(t *testing.T) {
+	ctx := context.Background()
+	env := newAPIKeyTestEnv()
+	env.users[6] = &domain.User{ID: 6, Role: domain.RoleService, Active: true, ServiceAccount: true}
+	key, _, _ := env.uc.CreateKey(ctx, 9, adminPermissions(), 5, "sync", []string{domain.PermExpenseReadAll}, nil)
+
+	if err := env.uc.RevokeKey(ctx, 9, 6, key.ID); err == nil {
+		t.Error("Expected a key not to be revocable through another service account")
+	}
+	if err := env.uc.RevokeKey(ctx, 9, 5, key.ID); err != nil {
+		t.Fatalf("RevokeKey() unexpected error = %v", err)
+	}
+	if err := env.uc.RevokeKey(ctx, 9, 5, key.ID); err == nil {
+		t.Error("Expected revoking twice to fail")
+	}
+}
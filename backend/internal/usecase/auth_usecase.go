@@ -2,51 +2,633 @@ package usecase
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"expense-management-system/internal/domain"
 	"expense-management-system/pkg/config"
+	"expense-management-system/pkg/logger"
+	"fmt"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type authUsecase struct {
-	userRepo domain.UserRepository
-	cfg      *config.Config
+	userRepo    domain.UserRepository
+	tokenRepo   domain.TokenRepository
+	attemptRepo domain.LoginAttemptRepository
+	twoFactor   domain.TwoFactorUsecase
+	notifier    domain.Notifier
+	signer      domain.TokenSigner
+	cfg         *config.Config
 }
 
-func NewAuthUsecase(userRepo domain.UserRepository, cfg *config.Config) domain.AuthUsecase {
+// NewAuthUsecase builds the auth usecase. A nil twoFactor turns off the
+// second login step.
+func NewAuthUsecase(userRepo domain.UserRepository, tokenRepo domain.TokenRepository, attemptRepo domain.LoginAttemptRepository, twoFactor domain.TwoFactorUsecase, notifier domain.Notifier, signer domain.TokenSigner, cfg *config.Config) domain.AuthUsecase {
 	return &authUsecase{
-		userRepo: userRepo,
-		cfg:      cfg,
+		userRepo:    userRepo,
+		tokenRepo:   tokenRepo,
+		attemptRepo: attemptRepo,
+		twoFactor:   twoFactor,
+		notifier:    notifier,
+		signer:      signer,
+		cfg:         cfg,
 	}
 }
 
-func (u *authUsecase) Login(ctx context.Context, email, password string) (string, *domain.User, error) {
+// dummyPasswordHash is compared against when the email is unknown, so a
+// failed login takes as long whether or not the account exists.
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password-for-timing"), bcrypt.DefaultCost)
+
+// Login checks a password login. Failures are counted per account and per
+// client IP; an account is locked for LoginLockoutMinutes once it reaches
+// LoginMaxAttempts within the attempt window, and an IP is refused while it
+// has LoginIPMaxAttempts failures in the window. Users with two-factor
+// authentication enabled, or whose role requires it, get a challenge instead
+// of tokens and finish with CompleteLogin.
+func (u *authUsecase) Login(ctx context.Context, email, password, clientIP string) (*domain.LoginResult, error) {
+	now := time.Now()
+	windowStart := now.Add(-time.Duration(u.cfg.LoginAttemptWindowMinutes) * time.Minute)
+
+	if clientIP != "" && u.cfg.LoginIPMaxAttempts > 0 {
+		failures, err := u.attemptRepo.CountFailuresByIP(ctx, clientIP, windowStart)
+		if err != nil {
+			return nil, err
+		}
+		if failures >= u.cfg.LoginIPMaxAttempts {
+			logger.ErrorLogger.Printf("[SECURITY] Login refused for IP %s (%d failed attempts in window)", clientIP, failures)
+			return nil, domain.ErrTooManyLoginAttempts
+		}
+	}
+
 	user, err := u.userRepo.GetByEmail(ctx, email)
 	if err != nil {
-		return "", nil, errors.New("invalid credentials")
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		u.recordAttempt(ctx, email, nil, clientIP, false)
+		return nil, errors.New("invalid credentials")
+	}
+
+	// Compare before looking at the lock so locked and unlocked accounts
+	// answer in the same time.
+	passwordErr := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
+
+	if user.LockedUntil != nil && now.Before(*user.LockedUntil) {
+		u.recordAttempt(ctx, email, &user.ID, clientIP, false)
+		logger.ErrorLogger.Printf("[SECURITY] Login attempt for locked user %d from %s", user.ID, clientIP)
+		return nil, domain.ErrAccountLocked
+	}
+
+	if passwordErr != nil {
+		u.recordAttempt(ctx, email, &user.ID, clientIP, false)
+		u.recordFailure(ctx, user, windowStart, now)
+		return nil, errors.New("invalid credentials")
+	}
+
+	if !user.Active {
+		return nil, errors.New("account is deactivated")
+	}
+
+	u.recordAttempt(ctx, email, &user.ID, clientIP, true)
+	if user.FailedLoginAttempts > 0 || user.LockedUntil != nil {
+		if err := u.userRepo.ResetLoginFailures(ctx, user.ID); err != nil {
+			logger.ErrorLogger.Printf("Failed to reset login failures of user %d: %v", user.ID, err)
+		}
+	}
+
+	return u.StartSession(ctx, user)
+}
+
+func (u *authUsecase) StartSession(ctx context.Context, user *domain.User) (*domain.LoginResult, error) {
+	if !user.Active {
+		return nil, errors.New("account is deactivated")
+	}
+
+	challenge, err := u.challengeFor(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	if challenge != nil {
+		return &domain.LoginResult{User: user, Challenge: challenge}, nil
+	}
+
+	pair, err := u.issueTokens(ctx, user, uuid.New().String())
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.LoginResult{Tokens: pair, User: user}, nil
+}
+
+func (u *authUsecase) recordFailure(ctx context.Context, user *domain.User, windowStart, now time.Time) {
+	lockUntil := now.Add(time.Duration(u.cfg.LoginLockoutMinutes) * time.Minute)
+	updated, err := u.userRepo.RecordLoginFailure(ctx, user.ID, u.cfg.LoginMaxAttempts, windowStart, lockUntil)
+	if err != nil {
+		logger.ErrorLogger.Printf("Failed to record login failure of user %d: %v", user.ID, err)
+		return
+	}
+
+	if updated.LockedUntil != nil {
+		logger.ErrorLogger.Printf("[SECURITY] User %d locked until %s after %d failed login attempts", user.ID, updated.LockedUntil.Format(time.RFC3339), updated.FailedLoginAttempts)
+	}
+}
+
+func (u *authUsecase) recordAttempt(ctx context.Context, email string, userID *int, clientIP string, success bool) {
+	attempt := &domain.LoginAttempt{
+		Email:     email,
+		UserID:    userID,
+		IPAddress: clientIP,
+		Success:   success,
+	}
+	if err := u.attemptRepo.Create(ctx, attempt); err != nil {
+		logger.ErrorLogger.Printf("Failed to record login attempt for %s: %v", email, err)
+	}
+}
+
+// challengeFor returns a login challenge when the user must give a second
+// factor, or nil when a password is enough.
+func (u *authUsecase) challengeFor(ctx context.Context, user *domain.User) (*domain.MFAChallenge, error) {
+	if u.twoFactor == nil {
+		return nil, nil
+	}
+
+	enabled, err := u.twoFactor.IsEnabled(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	required := u.twoFactor.IsRequired(user)
+	if !enabled && !required {
+		return nil, nil
+	}
+
+	ttl := time.Duration(u.cfg.MFAChallengeTTLMinutes) * time.Minute
+	claims := jwt.MapClaims{
+		"jti":     uuid.New().String(),
+		"user_id": user.ID,
+		"iat":     time.Now().Unix(),
+		"exp":     time.Now().Add(ttl).Unix(),
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(u.challengeKey())
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.MFAChallenge{
+		Token:              token,
+		EnrollmentRequired: !enabled,
+		ExpiresIn:          int(ttl.Seconds()),
+	}, nil
+}
+
+// ChallengeSetup hands out a TOTP secret to a user who has to enrol before
+// their first login can complete.
+func (u *authUsecase) ChallengeSetup(ctx context.Context, challengeToken string) (*domain.TwoFactorSetup, error) {
+	user, _, err := u.checkChallenge(ctx, challengeToken)
+	if err != nil {
+		return nil, err
+	}
+
+	enabled, err := u.twoFactor.IsEnabled(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled || !u.twoFactor.IsRequired(user) {
+		return nil, errors.New("two-factor enrolment is not required")
+	}
+
+	return u.twoFactor.Setup(ctx, user)
+}
+
+// CompleteLogin checks the second factor of a challenged login. Wrong codes
+// count towards the account lockout like wrong passwords do, and each
+// challenge can be completed only once.
+func (u *authUsecase) CompleteLogin(ctx context.Context, challengeToken, code string) (*domain.TokenPair, *domain.User, []string, error) {
+	user, claims, err := u.checkChallenge(ctx, challengeToken)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	enabled, err := u.twoFactor.IsEnabled(ctx, user.ID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var recoveryCodes []string
+	if enabled {
+		err = u.twoFactor.Verify(ctx, user.ID, code, true)
+	} else {
+		recoveryCodes, err = u.twoFactor.Enable(ctx, user, code)
+	}
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidTwoFactorCode) {
+			now := time.Now()
+			u.recordFailure(ctx, user, now.Add(-time.Duration(u.cfg.LoginAttemptWindowMinutes)*time.Minute), now)
+			logger.ErrorLogger.Printf("[SECURITY] Invalid two-factor code for user %d", user.ID)
+		}
+		return nil, nil, nil, err
+	}
+
+	if err := u.revokeAccess(ctx, claims); err != nil {
+		return nil, nil, nil, err
+	}
+
+	pair, err := u.issueTokens(ctx, user, uuid.New().String())
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return pair, user, recoveryCodes, nil
+}
+
+// checkChallenge parses a challenge token and loads its user. Completed
+// challenges are on the access token revocation list.
+func (u *authUsecase) checkChallenge(ctx context.Context, challengeToken string) (*domain.User, jwt.MapClaims, error) {
+	if u.twoFactor == nil {
+		return nil, nil, errors.New("two-factor authentication is not available")
+	}
+
+	invalid := errors.New("invalid or expired challenge")
+	claims, err := parseJWT(challengeToken, u.challengeKey())
+	if err != nil {
+		return nil, nil, invalid
+	}
+
+	jti, _ := claims["jti"].(string)
+	userID, ok := claims["user_id"].(float64)
+	if jti == "" || !ok {
+		return nil, nil, invalid
+	}
+
+	revoked, err := u.tokenRepo.IsAccessTokenRevoked(ctx, jti)
+	if err != nil {
+		return nil, nil, err
+	}
+	if revoked {
+		return nil, nil, invalid
+	}
+
+	user, err := u.userRepo.GetByID(ctx, int(userID))
+	if err != nil {
+		return nil, nil, invalid
+	}
+	if !user.Active {
+		return nil, nil, errors.New("account is deactivated")
+	}
+	if user.LockedUntil != nil && time.Now().Before(*user.LockedUntil) {
+		return nil, nil, domain.ErrAccountLocked
+	}
+
+	return user, claims, nil
+}
+
+// challengeKey signs challenge tokens. It is derived from JWT_SECRET so a
+// challenge can never pass as an access token.
+func (u *authUsecase) challengeKey() []byte {
+	mac := hmac.New(sha256.New, []byte(u.cfg.JWTSecret))
+	mac.Write([]byte("mfa-challenge"))
+	return mac.Sum(nil)
+}
+
+// Refresh rotates a refresh token: the presented token is revoked and a new
+// one is issued in the same family. Presenting a token that was already
+// rotated means it was copied, so the whole family is revoked.
+func (u *authUsecase) Refresh(ctx context.Context, refreshToken string) (*domain.TokenPair, *domain.User, error) {
+	stored, err := u.tokenRepo.GetRefreshTokenByHash(ctx, hashToken(refreshToken))
+	if err != nil {
+		return nil, nil, errors.New("invalid refresh token")
+	}
+
+	if stored.RevokedAt != nil {
+		u.revokeFamily(ctx, stored.FamilyID)
+		logger.ErrorLogger.Printf("Revoked refresh token reused for user %d, ended session family %s", stored.UserID, stored.FamilyID)
+		return nil, nil, errors.New("invalid refresh token")
+	}
+
+	if time.Now().After(stored.ExpiresAt) {
+		return nil, nil, errors.New("refresh token expired")
+	}
+
+	rotated, err := u.tokenRepo.RevokeRefreshToken(ctx, stored.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !rotated {
+		// Lost a race with another refresh of the same token.
+		u.revokeFamily(ctx, stored.FamilyID)
+		return nil, nil, errors.New("invalid refresh token")
+	}
+
+	user, err := u.userRepo.GetByID(ctx, stored.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !user.Active {
+		return nil, nil, errors.New("account is deactivated")
+	}
+
+	pair, err := u.issueTokens(ctx, user, stored.FamilyID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return pair, user, nil
+}
+
+// Logout ends the current session: the access token is added to the
+// revocation list and, when given, the session's refresh tokens are revoked.
+func (u *authUsecase) Logout(ctx context.Context, accessToken, refreshToken string) error {
+	claims, err := u.parseToken(ctx, accessToken)
+	if err != nil {
+		return err
+	}
+
+	if err := u.revokeAccess(ctx, claims); err != nil {
+		return err
+	}
+
+	if refreshToken == "" {
+		return nil
+	}
+
+	stored, err := u.tokenRepo.GetRefreshTokenByHash(ctx, hashToken(refreshToken))
+	if err != nil {
+		return nil
+	}
+
+	userID, _ := claims["user_id"].(float64)
+	if stored.UserID != int(userID) {
+		return errors.New("refresh token belongs to another user")
+	}
+
+	u.revokeFamily(ctx, stored.FamilyID)
+	return nil
+}
+
+// LogoutAll ends every session of the user, including access tokens that
+// have not expired yet.
+func (u *authUsecase) LogoutAll(ctx context.Context, userID int, accessToken string) error {
+	jtis, err := u.tokenRepo.RevokeUserRefreshTokens(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().Add(u.accessTokenTTL())
+	for _, jti := range jtis {
+		if err := u.tokenRepo.RevokeAccessToken(ctx, jti, expiresAt); err != nil {
+			return err
+		}
+	}
+
+	if accessToken != "" {
+		claims, err := u.parseToken(ctx, accessToken)
+		if err == nil {
+			if err := u.revokeAccess(ctx, claims); err != nil {
+				return err
+			}
+		}
+	}
+
+	logger.InfoLogger.Printf("All sessions of user %d revoked (%d sessions)", userID, len(jtis))
+	return nil
+}
+
+func (u *authUsecase) ChangePassword(ctx context.Context, userID int, currentPassword, newPassword string) (*domain.TokenPair, error) {
+	user, err := u.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(currentPassword)); err != nil {
+		return nil, errors.New("current password is incorrect")
+	}
+	if currentPassword == newPassword {
+		return nil, errors.New("new password must differ from the current one")
+	}
+
+	if err := u.setPassword(ctx, user, newPassword); err != nil {
+		return nil, err
+	}
+
+	return u.issueTokens(ctx, user, uuid.New().String())
+}
+
+// RequestPasswordReset mails a reset link to the user. It succeeds for
+// unknown and deactivated accounts too, so the endpoint cannot be used to
+// find out which emails are registered. Accounts created by single sign-on
+// have no password and are skipped the same way.
+func (u *authUsecase) RequestPasswordReset(ctx context.Context, email string) error {
+	user, err := u.userRepo.GetByEmail(ctx, strings.TrimSpace(strings.ToLower(email)))
+	if err != nil || !user.Active || user.PasswordHash == "" {
+		return nil
+	}
+
+	// Only the most recent link works.
+	if err := u.tokenRepo.InvalidatePasswordResets(ctx, user.ID); err != nil {
+		return err
+	}
+
+	token, err := generateSecureToken()
+	if err != nil {
+		return err
+	}
+
+	ttl := time.Duration(u.cfg.PasswordResetTTLMinutes) * time.Minute
+	reset := &domain.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := u.tokenRepo.CreatePasswordReset(ctx, reset); err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("Reset your password within %d minutes: %s?token=%s", u.cfg.PasswordResetTTLMinutes, u.cfg.PasswordResetURL, url.QueryEscape(token))
+	if err := u.notifier.Send(ctx, user.Email, "Reset your password", body); err != nil {
+		logger.ErrorLogger.Printf("Failed to send password reset to user %d: %v", user.ID, err)
+		return errors.New("failed to send password reset email")
+	}
+
+	return nil
+}
+
+// ResetPassword redeems a reset token. The token is checked against the
+// password policy before it is consumed, so a rejected password does not
+// burn the link.
+func (u *authUsecase) ResetPassword(ctx context.Context, token, newPassword string) error {
+	reset, err := u.tokenRepo.GetPasswordResetByHash(ctx, hashToken(token))
+	if err != nil || reset.UsedAt != nil || time.Now().After(reset.ExpiresAt) {
+		return errors.New("invalid or expired reset token")
+	}
+
+	user, err := u.userRepo.GetByID(ctx, reset.UserID)
+	if err != nil {
+		return err
+	}
+	if !user.Active {
+		return errors.New("account is deactivated")
+	}
+
+	if err := ValidatePassword(newPassword, user.Email); err != nil {
+		return err
+	}
+
+	used, err := u.tokenRepo.UsePasswordReset(ctx, reset.ID)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return errors.New("invalid or expired reset token")
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
-		return "", nil, errors.New("invalid credentials")
+	return u.setPassword(ctx, user, newPassword)
+}
+
+// setPassword stores a new password and ends every existing session of the
+// user, since whoever held them may have known the old one.
+func (u *authUsecase) setPassword(ctx context.Context, user *domain.User, password string) error {
+	if err := ValidatePassword(password, user.Email); err != nil {
+		return err
 	}
 
-	token, err := u.generateToken(user)
+	hash, err := HashPassword(password)
 	if err != nil {
-		return "", nil, err
+		return err
+	}
+
+	if err := u.userRepo.UpdatePassword(ctx, user.ID, hash); err != nil {
+		return err
+	}
+	user.PasswordHash = hash
+
+	if err := u.tokenRepo.InvalidatePasswordResets(ctx, user.ID); err != nil {
+		return err
+	}
+	if err := u.userRepo.ResetLoginFailures(ctx, user.ID); err != nil {
+		return err
+	}
+	if err := u.LogoutAll(ctx, user.ID, ""); err != nil {
+		return err
 	}
 
-	return token, user, nil
+	logger.InfoLogger.Printf("Password changed for user %d", user.ID)
+	return nil
 }
 
 func (u *authUsecase) ValidateToken(ctx context.Context, tokenString string) (*domain.User, error) {
+	claims, err := u.verifyAccessToken(ctx, tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, ok := claims["user_id"].(float64)
+	if !ok {
+		return nil, errors.New("invalid user_id in token")
+	}
+
+	user, err := u.userRepo.GetByID(ctx, int(userID))
+	if err != nil {
+		return nil, err
+	}
+	if !user.Active {
+		return nil, errors.New("account is deactivated")
+	}
+
+	return user, nil
+}
+
+// ValidateTokenClaims is ValidateToken without the user lookup when
+// AUTH_TRUST_TOKEN_CLAIMS is set: the user is built from the token, so a
+// role change only applies once the token expires. Revoked tokens are
+// rejected as always, and with the user cache on so are deactivated users:
+// deactivation revokes the tokens of live sessions, but not those issued
+// before the session's last refresh.
+func (u *authUsecase) ValidateTokenClaims(ctx context.Context, tokenString string) (*domain.User, error) {
+	if !u.cfg.AuthTrustTokenClaims {
+		return u.ValidateToken(ctx, tokenString)
+	}
+
+	claims, err := u.verifyAccessToken(ctx, tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, ok := claims["user_id"].(float64)
+	if !ok {
+		return nil, errors.New("invalid user_id in token")
+	}
+	email, _ := claims["email"].(string)
+	name, _ := claims["name"].(string)
+	role, _ := claims["role"].(string)
+	if role == "" {
+		return u.ValidateToken(ctx, tokenString)
+	}
+	if u.cfg.UserCacheTTLSeconds > 0 {
+		user, err := u.userRepo.GetByID(ctx, int(userID))
+		if err != nil {
+			return nil, err
+		}
+		if !user.Active {
+			return nil, errors.New("account is deactivated")
+		}
+	}
+
+	return &domain.User{
+		ID:     int(userID),
+		Email:  email,
+		Name:   name,
+		Role:   role,
+		Active: true,
+	}, nil
+}
+
+// verifyAccessToken checks the signature, expiry and revocation of an
+// access token.
+func (u *authUsecase) verifyAccessToken(ctx context.Context, tokenString string) (jwt.MapClaims, error) {
+	claims, err := u.parseToken(ctx, tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	jti, ok := claims["jti"].(string)
+	if !ok || jti == "" {
+		return nil, errors.New("invalid token")
+	}
+
+	revoked, err := u.tokenRepo.IsAccessTokenRevoked(ctx, jti)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, errors.New("token has been revoked")
+	}
+
+	return claims, nil
+}
+
+func (u *authUsecase) parseToken(ctx context.Context, tokenString string) (jwt.MapClaims, error) {
+	claims, err := u.signer.Verify(ctx, tokenString)
+	if err != nil {
+		return nil, err
+	}
+	return jwt.MapClaims(claims), nil
+}
+
+// parseJWT checks tokens we sign with a secret for our own use, such as
+// two-factor challenges.
+func parseJWT(tokenString string, key []byte) (jwt.MapClaims, error) {
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, errors.New("invalid signing method")
 		}
-		return []byte(u.cfg.JWTSecret), nil
+		return key, nil
 	})
 
 	if err != nil || !token.Valid {
@@ -58,29 +640,99 @@ func (u *authUsecase) ValidateToken(ctx context.Context, tokenString string) (*d
 		return nil, errors.New("invalid token claims")
 	}
 
-	userID, ok := claims["user_id"].(float64)
-	if !ok {
-		return nil, errors.New("invalid user_id in token")
+	return claims, nil
+}
+
+// issueTokens creates a new access token and a refresh token for the session
+// identified by familyID.
+func (u *authUsecase) issueTokens(ctx context.Context, user *domain.User, familyID string) (*domain.TokenPair, error) {
+	jti := uuid.New().String()
+	accessToken, err := u.generateToken(ctx, user, jti)
+	if err != nil {
+		return nil, err
 	}
 
-	user, err := u.userRepo.GetByID(ctx, int(userID))
+	refreshToken, err := generateSecureToken()
 	if err != nil {
 		return nil, err
 	}
 
-	return user, nil
+	stored := &domain.RefreshToken{
+		UserID:    user.ID,
+		TokenHash: hashToken(refreshToken),
+		FamilyID:  familyID,
+		AccessJTI: jti,
+		ExpiresAt: time.Now().Add(time.Duration(u.cfg.RefreshTokenTTLHours) * time.Hour),
+	}
+	if err := u.tokenRepo.CreateRefreshToken(ctx, stored); err != nil {
+		return nil, err
+	}
+
+	return &domain.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(u.accessTokenTTL().Seconds()),
+	}, nil
 }
 
-func (u *authUsecase) generateToken(user *domain.User) (string, error) {
-	claims := jwt.MapClaims{
+func (u *authUsecase) revokeAccess(ctx context.Context, claims jwt.MapClaims) error {
+	jti, _ := claims["jti"].(string)
+	if jti == "" {
+		return nil
+	}
+
+	expiresAt := time.Now().Add(u.accessTokenTTL())
+	if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+		expiresAt = exp.Time
+	}
+
+	return u.tokenRepo.RevokeAccessToken(ctx, jti, expiresAt)
+}
+
+func (u *authUsecase) revokeFamily(ctx context.Context, familyID string) {
+	jtis, err := u.tokenRepo.RevokeRefreshFamily(ctx, familyID)
+	if err != nil {
+		logger.ErrorLogger.Printf("Failed to revoke session family %s: %v", familyID, err)
+		return
+	}
+
+	expiresAt := time.Now().Add(u.accessTokenTTL())
+	for _, jti := range jtis {
+		u.tokenRepo.RevokeAccessToken(ctx, jti, expiresAt)
+	}
+}
+
+func (u *authUsecase) accessTokenTTL() time.Duration {
+	return time.Duration(u.cfg.AccessTokenTTLMinutes) * time.Minute
+}
+
+func (u *authUsecase) generateToken(ctx context.Context, user *domain.User, jti string) (string, error) {
+	claims := map[string]interface{}{
+		"jti":     jti,
 		"user_id": user.ID,
 		"email":   user.Email,
+		"name":    user.Name,
 		"role":    user.Role,
-		"exp":     time.Now().Add(time.Hour * time.Duration(u.cfg.JWTExpiryHours)).Unix(),
+		"iat":     time.Now().Unix(),
+		"exp":     time.Now().Add(u.accessTokenTTL()).Unix(),
+	}
+
+	return u.signer.Sign(ctx, claims)
+}
+
+func generateSecureToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
 	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(u.cfg.JWTSecret))
+// hashToken is what refresh tokens are stored and looked up by, so a leaked
+// database does not hand out live sessions.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
 
 func HashPassword(password string) (string, error) {
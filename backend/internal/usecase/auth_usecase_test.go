@@ -4,12 +4,174 @@ import (
 	"context"
 	"errors"
 	"expense-management-system/internal/domain"
+	"expense-management-system/internal/jwtkeys"
+	"expense-management-system/internal/repository"
 	"expense-management-system/pkg/config"
+	"fmt"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
+type mockTokenRepo struct {
+	refreshTokens  map[string]*domain.RefreshToken
+	revokedJTIs    map[string]bool
+	passwordResets map[string]*domain.PasswordResetToken
+}
+
+func newMockTokenRepo() *mockTokenRepo {
+	return &mockTokenRepo{
+		refreshTokens:  map[string]*domain.RefreshToken{},
+		revokedJTIs:    map[string]bool{},
+		passwordResets: map[string]*domain.PasswordResetToken{},
+	}
+}
+
+func (m *mockTokenRepo) CreatePasswordReset(ctx context.Context, token *domain.PasswordResetToken) error {
+	token.ID = len(m.passwordResets) + 1
+	m.passwordResets[token.TokenHash] = token
+	return nil
+}
+
+func (m *mockTokenRepo) GetPasswordResetByHash(ctx context.Context, tokenHash string) (*domain.PasswordResetToken, error) {
+	token, ok := m.passwordResets[tokenHash]
+	if !ok {
+		return nil, errors.New("password reset token not found")
+	}
+	return token, nil
+}
+
+func (m *mockTokenRepo) UsePasswordReset(ctx context.Context, id int) (bool, error) {
+	for _, token := range m.passwordResets {
+		if token.ID == id {
+			if token.UsedAt != nil {
+				return false, nil
+			}
+			now := time.Now()
+			token.UsedAt = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mockTokenRepo) InvalidatePasswordResets(ctx context.Context, userID int) error {
+	now := time.Now()
+	for _, token := range m.passwordResets {
+		if token.UserID == userID && token.UsedAt == nil {
+			token.UsedAt = &now
+		}
+	}
+	return nil
+}
+
+const testClientIP = "192.0.2.10"
+
+type mockLoginAttemptRepo struct {
+	attempts []*domain.LoginAttempt
+}
+
+func newMockLoginAttemptRepo() *mockLoginAttemptRepo {
+	return &mockLoginAttemptRepo{}
+}
+
+func (m *mockLoginAttemptRepo) Create(ctx context.Context, attempt *domain.LoginAttempt) error {
+	attempt.ID = len(m.attempts) + 1
+	attempt.CreatedAt = time.Now()
+	m.attempts = append(m.attempts, attempt)
+	return nil
+}
+
+func (m *mockLoginAttemptRepo) CountFailuresByIP(ctx context.Context, ipAddress string, since time.Time) (int, error) {
+	count := 0
+	for _, attempt := range m.attempts {
+		if attempt.IPAddress == ipAddress && !attempt.Success && !attempt.CreatedAt.Before(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *mockLoginAttemptRepo) ListByUser(ctx context.Context, userID int, limit int) ([]*domain.LoginAttempt, error) {
+	var attempts []*domain.LoginAttempt
+	for i := len(m.attempts) - 1; i >= 0 && len(attempts) < limit; i-- {
+		if m.attempts[i].UserID != nil && *m.attempts[i].UserID == userID {
+			attempts = append(attempts, m.attempts[i])
+		}
+	}
+	return attempts, nil
+}
+
+type mockNotifier struct {
+	sent []string
+}
+
+func (m *mockNotifier) Send(ctx context.Context, to, subject, body string) error {
+	m.sent = append(m.sent, body)
+	return nil
+}
+
+func (m *mockTokenRepo) CreateRefreshToken(ctx context.Context, token *domain.RefreshToken) error {
+	token.ID = len(m.refreshTokens) + 1
+	m.refreshTokens[token.TokenHash] = token
+	return nil
+}
+
+func (m *mockTokenRepo) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*domain.RefreshToken, error) {
+	token, ok := m.refreshTokens[tokenHash]
+	if !ok {
+		return nil, errors.New("refresh token not found")
+	}
+	copied := *token
+	return &copied, nil
+}
+
+func (m *mockTokenRepo) RevokeRefreshToken(ctx context.Context, id int) (bool, error) {
+	for _, token := range m.refreshTokens {
+		if token.ID == id {
+			if token.RevokedAt != nil {
+				return false, nil
+			}
+			now := time.Now()
+			token.RevokedAt = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mockTokenRepo) revokeWhere(match func(*domain.RefreshToken) bool) []string {
+	var jtis []string
+	now := time.Now()
+	for _, token := range m.refreshTokens {
+		if match(token) && token.RevokedAt == nil {
+			token.RevokedAt = &now
+			jtis = append(jtis, token.AccessJTI)
+		}
+	}
+	return jtis
+}
+
+func (m *mockTokenRepo) RevokeRefreshFamily(ctx context.Context, familyID string) ([]string, error) {
+	return m.revokeWhere(func(t *domain.RefreshToken) bool { return t.FamilyID == familyID }), nil
+}
+
+func (m *mockTokenRepo) RevokeUserRefreshTokens(ctx context.Context, userID int) ([]string, error) {
+	return m.revokeWhere(func(t *domain.RefreshToken) bool { return t.UserID == userID }), nil
+}
+
+func (m *mockTokenRepo) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	m.revokedJTIs[jti] = true
+	return nil
+}
+
+func (m *mockTokenRepo) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	return m.revokedJTIs[jti], nil
+}
+
 func TestAuthUsecase_Login(t *testing.T) {
 	// Pre-hash password for testing
 	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
@@ -34,6 +196,7 @@ func TestAuthUsecase_Login(t *testing.T) {
 						Name:         "Employee One",
 						Role:         domain.RoleEmployee,
 						PasswordHash: string(hashedPassword),
+						Active:       true,
 					}, nil
 				}
 			},
@@ -52,6 +215,7 @@ func TestAuthUsecase_Login(t *testing.T) {
 						Name:         "Manager Name",
 						Role:         domain.RoleManager,
 						PasswordHash: string(hashedPassword),
+						Active:       true,
 					}, nil
 				}
 			},
@@ -68,6 +232,24 @@ func TestAuthUsecase_Login(t *testing.T) {
 						ID:           1,
 						Email:        "employee1@example.com",
 						PasswordHash: string(hashedPassword),
+						Active:       true,
+					}, nil
+				}
+			},
+			wantErr: true,
+		},
+		{
+			name:     "Deactivated user",
+			email:    "employee1@example.com",
+			password: "password123",
+			setupMock: func(repo *mockUserRepo) {
+				repo.getByEmailFunc = func(ctx context.Context, email string) (*domain.User, error) {
+					return &domain.User{
+						ID:           1,
+						Email:        "employee1@example.com",
+						Role:         domain.RoleEmployee,
+						PasswordHash: string(hashedPassword),
+						Active:       false,
 					}, nil
 				}
 			},
@@ -96,13 +278,14 @@ func TestAuthUsecase_Login(t *testing.T) {
 			}
 
 			cfg := &config.Config{
-				JWTSecret:      "test-secret-key",
-				JWTExpiryHours: 24,
+				JWTSecret:             "test-secret-key",
+				AccessTokenTTLMinutes: 15,
+				RefreshTokenTTLHours:  720,
 			}
 
-			uc := NewAuthUsecase(userRepo, cfg)
+			uc := NewAuthUsecase(userRepo, newMockTokenRepo(), newMockLoginAttemptRepo(), nil, &mockNotifier{}, jwtkeys.NewHMACSigner(cfg.JWTSecret), cfg)
 
-			token, user, err := uc.Login(ctx, tt.email, tt.password)
+			pair, user, err := login(ctx, uc, tt.email, tt.password, testClientIP)
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Login() error = %v, wantErr %v", err, tt.wantErr)
@@ -110,8 +293,8 @@ func TestAuthUsecase_Login(t *testing.T) {
 			}
 
 			if !tt.wantErr {
-				if token == "" {
-					t.Error("Expected token to be generated")
+				if pair == nil || pair.AccessToken == "" || pair.RefreshToken == "" {
+					t.Error("Expected access and refresh tokens to be generated")
 				}
 
 				if user == nil {
@@ -134,16 +317,18 @@ func TestAuthUsecase_Login(t *testing.T) {
 func TestAuthUsecase_ValidateToken(t *testing.T) {
 	ctx := context.Background()
 	cfg := &config.Config{
-		JWTSecret:      "test-secret-key",
-		JWTExpiryHours: 24,
+		JWTSecret:             "test-secret-key",
+		AccessTokenTTLMinutes: 15,
+		RefreshTokenTTLHours:  720,
 	}
 
 	userRepo := &mockUserRepo{
 		getByIDFunc: func(ctx context.Context, id int) (*domain.User, error) {
 			return &domain.User{
-				ID:    id,
-				Email: "test@example.com",
-				Role:  domain.RoleEmployee,
+				ID:     id,
+				Email:  "test@example.com",
+				Role:   domain.RoleEmployee,
+				Active: true,
 			}, nil
 		},
 		getByEmailFunc: func(ctx context.Context, email string) (*domain.User, error) {
@@ -153,17 +338,20 @@ func TestAuthUsecase_ValidateToken(t *testing.T) {
 				Email:        email,
 				Role:         domain.RoleEmployee,
 				PasswordHash: string(hashedPassword),
+				Active:       true,
 			}, nil
 		},
 	}
 
-	uc := NewAuthUsecase(userRepo, cfg)
+	tokenRepo := newMockTokenRepo()
+	uc := NewAuthUsecase(userRepo, tokenRepo, newMockLoginAttemptRepo(), nil, &mockNotifier{}, jwtkeys.NewHMACSigner(cfg.JWTSecret), cfg)
 
 	// Generate valid token
-	token, _, err := uc.Login(ctx, "test@example.com", "password123")
+	pair, _, err := login(ctx, uc, "test@example.com", "password123", testClientIP)
 	if err != nil {
 		t.Fatalf("Failed to generate token: %v", err)
 	}
+	token := pair.AccessToken
 
 	tests := []struct {
 		name       string
@@ -206,6 +394,17 @@ func TestAuthUsecase_ValidateToken(t *testing.T) {
 			wantErr:    true,
 			wantErrMsg: "user not found",
 		},
+		{
+			name:  "Deactivated user",
+			token: token,
+			setupRepo: func(repo *mockUserRepo) {
+				repo.getByIDFunc = func(ctx context.Context, id int) (*domain.User, error) {
+					return &domain.User{ID: id, Role: domain.RoleEmployee, Active: false}, nil
+				}
+			},
+			wantErr:    true,
+			wantErrMsg: "account is deactivated",
+		},
 	}
 
 	for _, tt := range tests {
@@ -219,7 +418,7 @@ func TestAuthUsecase_ValidateToken(t *testing.T) {
 				tt.setupRepo(testRepo)
 			}
 
-			testUc := NewAuthUsecase(testRepo, cfg)
+			testUc := NewAuthUsecase(testRepo, tokenRepo, newMockLoginAttemptRepo(), nil, &mockNotifier{}, jwtkeys.NewHMACSigner(cfg.JWTSecret), cfg)
 			user, err := testUc.ValidateToken(ctx, tt.token)
 
 			if (err != nil) != tt.wantErr {
@@ -268,11 +467,12 @@ func TestAuthUsecase_HashPassword(t *testing.T) {
 
 func TestAuthUsecase_GenerateToken(t *testing.T) {
 	cfg := &config.Config{
-		JWTSecret:      "test-secret-key",
-		JWTExpiryHours: 24,
+		JWTSecret:             "test-secret-key",
+		AccessTokenTTLMinutes: 15,
+		RefreshTokenTTLHours:  720,
 	}
 
-	uc := &authUsecase{cfg: cfg}
+	uc := &authUsecase{cfg: cfg, signer: jwtkeys.NewHMACSigner(cfg.JWTSecret)}
 
 	user := &domain.User{
 		ID:    1,
@@ -280,7 +480,7 @@ func TestAuthUsecase_GenerateToken(t *testing.T) {
 		Role:  domain.RoleEmployee,
 	}
 
-	token, err := uc.generateToken(user)
+	token, err := uc.generateToken(context.Background(), user, "test-jti")
 	if err != nil {
 		t.Errorf("generateToken() unexpected error = %v", err)
 	}
@@ -289,3 +489,568 @@ func TestAuthUsecase_GenerateToken(t *testing.T) {
 		t.Error("generateToken() returned empty token")
 	}
 }
+
+func newTestAuthUsecase(t *testing.T) (domain.AuthUsecase, *mockTokenRepo) {
+	t.Helper()
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	userRepo := &mockUserRepo{
+		getByEmailFunc: func(ctx context.Context, email string) (*domain.User, error) {
+			return &domain.User{ID: 1, Email: email, Role: domain.RoleEmployee, PasswordHash: string(hashedPassword), Active: true}, nil
+		},
+	}
+	cfg := &config.Config{
+		JWTSecret:             "test-secret-key",
+		AccessTokenTTLMinutes: 15,
+		RefreshTokenTTLHours:  720,
+	}
+
+	tokenRepo := newMockTokenRepo()
+	return NewAuthUsecase(userRepo, tokenRepo, newMockLoginAttemptRepo(), nil, &mockNotifier{}, jwtkeys.NewHMACSigner(cfg.JWTSecret), cfg), tokenRepo
+}
+
+func TestAuthUsecase_Refresh(t *testing.T) {
+	ctx := context.Background()
+	uc, _ := newTestAuthUsecase(t)
+
+	first, _, err := login(ctx, uc, "employee1@example.com", "password123", testClientIP)
+	if err != nil {
+		t.Fatalf("Login() unexpected error = %v", err)
+	}
+
+	second, _, err := uc.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh() unexpected error = %v", err)
+	}
+
+	if second.RefreshToken == first.RefreshToken || second.AccessToken == first.AccessToken {
+		t.Error("Expected refresh to rotate both tokens")
+	}
+
+	if _, err := uc.ValidateToken(ctx, second.AccessToken); err != nil {
+		t.Errorf("Rotated access token should be valid: %v", err)
+	}
+
+	// Replaying the old refresh token revokes the whole session.
+	if _, _, err := uc.Refresh(ctx, first.RefreshToken); err == nil {
+		t.Error("Expected reused refresh token to be rejected")
+	}
+
+	if _, _, err := uc.Refresh(ctx, second.RefreshToken); err == nil {
+		t.Error("Expected session to be revoked after refresh token reuse")
+	}
+
+	if _, err := uc.ValidateToken(ctx, second.AccessToken); err == nil {
+		t.Error("Expected session access token to be revoked after refresh token reuse")
+	}
+}
+
+func TestAuthUsecase_Logout(t *testing.T) {
+	ctx := context.Background()
+	uc, _ := newTestAuthUsecase(t)
+
+	session, _, _ := login(ctx, uc, "employee1@example.com", "password123", testClientIP)
+	other, _, _ := login(ctx, uc, "employee1@example.com", "password123", testClientIP)
+
+	if err := uc.Logout(ctx, session.AccessToken, session.RefreshToken); err != nil {
+		t.Fatalf("Logout() unexpected error = %v", err)
+	}
+
+	if _, err := uc.ValidateToken(ctx, session.AccessToken); err == nil {
+		t.Error("Expected access token to be revoked after logout")
+	}
+
+	if _, _, err := uc.Refresh(ctx, session.RefreshToken); err == nil {
+		t.Error("Expected refresh token to be revoked after logout")
+	}
+
+	if _, err := uc.ValidateToken(ctx, other.AccessToken); err != nil {
+		t.Errorf("Other sessions should stay valid after logout: %v", err)
+	}
+}
+
+func TestAuthUsecase_LogoutAll(t *testing.T) {
+	ctx := context.Background()
+	uc, _ := newTestAuthUsecase(t)
+
+	first, _, _ := login(ctx, uc, "employee1@example.com", "password123", testClientIP)
+	second, _, _ := login(ctx, uc, "employee1@example.com", "password123", testClientIP)
+
+	if err := uc.LogoutAll(ctx, 1, first.AccessToken); err != nil {
+		t.Fatalf("LogoutAll() unexpected error = %v", err)
+	}
+
+	for _, pair := range []*domain.TokenPair{first, second} {
+		if _, err := uc.ValidateToken(ctx, pair.AccessToken); err == nil {
+			t.Error("Expected every access token to be revoked")
+		}
+		if _, _, err := uc.Refresh(ctx, pair.RefreshToken); err == nil {
+			t.Error("Expected every refresh token to be revoked")
+		}
+	}
+}
+
+func newPasswordTestAuthUsecase(t *testing.T) (domain.AuthUsecase, *mockTokenRepo, *mockNotifier) {
+	t.Helper()
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	user := &domain.User{ID: 1, Email: "employee1@example.com", Role: domain.RoleEmployee, PasswordHash: string(hashedPassword), Active: true}
+	userRepo := &mockUserRepo{
+		getByIDFunc: func(ctx context.Context, id int) (*domain.User, error) {
+			copy := *user
+			return &copy, nil
+		},
+		getByEmailFunc: func(ctx context.Context, email string) (*domain.User, error) {
+			if email != user.Email {
+				return nil, errors.New("user not found")
+			}
+			copy := *user
+			return &copy, nil
+		},
+		updatePasswordFunc: func(ctx context.Context, id int, passwordHash string) error {
+			user.PasswordHash = passwordHash
+			return nil
+		},
+	}
+	cfg := &config.Config{
+		JWTSecret:               "test-secret-key",
+		AccessTokenTTLMinutes:   15,
+		RefreshTokenTTLHours:    720,
+		PasswordResetTTLMinutes: 30,
+		PasswordResetURL:        "http://localhost:3000/reset-password",
+	}
+
+	tokenRepo := newMockTokenRepo()
+	notifier := &mockNotifier{}
+	return NewAuthUsecase(userRepo, tokenRepo, newMockLoginAttemptRepo(), nil, notifier, jwtkeys.NewHMACSigner(cfg.JWTSecret), cfg), tokenRepo, notifier
+}
+
+func TestAuthUsecase_ChangePassword(t *testing.T) {
+	ctx := context.Background()
+	uc, tokenRepo, _ := newPasswordTestAuthUsecase(t)
+
+	pair, _, err := login(ctx, uc, "employee1@example.com", "password123", testClientIP)
+	if err != nil {
+		t.Fatalf("Login() unexpected error = %v", err)
+	}
+
+	if _, err := uc.ChangePassword(ctx, 1, "wrong-password", "Better-pass-2024"); err == nil {
+		t.Error("Expected error for an incorrect current password")
+	}
+	if _, err := uc.ChangePassword(ctx, 1, "password123", "short1"); err == nil {
+		t.Error("Expected password policy to reject a short password")
+	}
+
+	newPair, err := uc.ChangePassword(ctx, 1, "password123", "Better-pass-2024")
+	if err != nil {
+		t.Fatalf("ChangePassword() unexpected error = %v", err)
+	}
+	if newPair.AccessToken == "" || newPair.RefreshToken == "" {
+		t.Error("Expected a new token pair")
+	}
+
+	if _, _, err := uc.Refresh(ctx, pair.RefreshToken); err == nil {
+		t.Error("Expected sessions from before the change to be revoked")
+	}
+	if _, err := uc.ValidateToken(ctx, pair.AccessToken); err == nil {
+		t.Error("Expected the old access token to be revoked")
+	}
+	if _, err := uc.ValidateToken(ctx, newPair.AccessToken); err != nil {
+		t.Errorf("Expected the new access token to work, got %v", err)
+	}
+	if len(tokenRepo.revokedJTIs) == 0 {
+		t.Error("Expected access tokens to be added to the revocation list")
+	}
+
+	if _, _, err := login(ctx, uc, "employee1@example.com", "Better-pass-2024", testClientIP); err != nil {
+		t.Errorf("Expected login with the new password, got %v", err)
+	}
+}
+
+func TestAuthUsecase_PasswordReset(t *testing.T) {
+	ctx := context.Background()
+	uc, _, notifier := newPasswordTestAuthUsecase(t)
+
+	pair, _, err := login(ctx, uc, "employee1@example.com", "password123", testClientIP)
+	if err != nil {
+		t.Fatalf("Login() unexpected error = %v", err)
+	}
+
+	if err := uc.RequestPasswordReset(ctx, "nobody@example.com"); err != nil {
+		t.Errorf("Expected unknown emails to succeed silently, got %v", err)
+	}
+	if len(notifier.sent) != 0 {
+		t.Fatal("Expected no message for an unknown email")
+	}
+
+	if err := uc.RequestPasswordReset(ctx, "Employee1@example.com"); err != nil {
+		t.Fatalf("RequestPasswordReset() unexpected error = %v", err)
+	}
+	if err := uc.RequestPasswordReset(ctx, "employee1@example.com"); err != nil {
+		t.Fatalf("RequestPasswordReset() unexpected error = %v", err)
+	}
+	if len(notifier.sent) != 2 {
+		t.Fatalf("Expected two reset messages, got %d", len(notifier.sent))
+	}
+
+	firstToken := resetTokenFromMessage(t, notifier.sent[0])
+	token := resetTokenFromMessage(t, notifier.sent[1])
+
+	if err := uc.ResetPassword(ctx, firstToken, "Better-pass-2024"); err == nil {
+		t.Error("Expected an earlier reset link to stop working once a new one is issued")
+	}
+	if err := uc.ResetPassword(ctx, token, "password"); err == nil {
+		t.Error("Expected password policy to reject a weak password")
+	}
+
+	if err := uc.ResetPassword(ctx, token, "Better-pass-2024"); err != nil {
+		t.Fatalf("ResetPassword() unexpected error = %v", err)
+	}
+	if err := uc.ResetPassword(ctx, token, "Another-pass-2024"); err == nil {
+		t.Error("Expected the reset token to be single-use")
+	}
+
+	if _, _, err := uc.Refresh(ctx, pair.RefreshToken); err == nil {
+		t.Error("Expected existing sessions to be revoked after a reset")
+	}
+	if _, _, err := login(ctx, uc, "employee1@example.com", "Better-pass-2024", testClientIP); err != nil {
+		t.Errorf("Expected login with the new password, got %v", err)
+	}
+}
+
+func TestAuthUsecase_ResetPasswordExpired(t *testing.T) {
+	ctx := context.Background()
+	uc, tokenRepo, notifier := newPasswordTestAuthUsecase(t)
+
+	if err := uc.RequestPasswordReset(ctx, "employee1@example.com"); err != nil {
+		t.Fatalf("RequestPasswordReset() unexpected error = %v", err)
+	}
+	for _, reset := range tokenRepo.passwordResets {
+		reset.ExpiresAt = time.Now().Add(-time.Minute)
+	}
+
+	if err := uc.ResetPassword(ctx, resetTokenFromMessage(t, notifier.sent[0]), "Better-pass-2024"); err == nil {
+		t.Error("Expected an expired reset token to be rejected")
+	}
+}
+
+func resetTokenFromMessage(t *testing.T, body string) string {
+	t.Helper()
+
+	_, token, ok := strings.Cut(body, "?token=")
+	if !ok {
+		t.Fatalf("No reset token in message %q", body)
+	}
+	return token
+}
+
+func newLockoutTestAuthUsecase(t *testing.T) (domain.AuthUsecase, *domain.User, *mockLoginAttemptRepo) {
+	t.Helper()
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	user := &domain.User{ID: 1, Email: "employee1@example.com", Role: domain.RoleEmployee, PasswordHash: string(hashedPassword), Active: true}
+	userRepo := &mockUserRepo{
+		getByEmailFunc: func(ctx context.Context, email string) (*domain.User, error) {
+			if email != user.Email {
+				return nil, errors.New("user not found")
+			}
+			copy := *user
+			return &copy, nil
+		},
+		recordLoginFailureFunc: func(ctx context.Context, id int, maxAttempts int, windowStart, lockUntil time.Time) (*domain.User, error) {
+			user.FailedLoginAttempts++
+			if user.FailedLoginAttempts >= maxAttempts {
+				user.LockedUntil = &lockUntil
+			}
+			copy := *user
+			return &copy, nil
+		},
+		resetLoginFailuresFunc: func(ctx context.Context, id int) error {
+			user.FailedLoginAttempts = 0
+			user.LockedUntil = nil
+			return nil
+		},
+	}
+	cfg := &config.Config{
+		JWTSecret:                 "test-secret-key",
+		AccessTokenTTLMinutes:     15,
+		RefreshTokenTTLHours:      720,
+		LoginMaxAttempts:          3,
+		LoginIPMaxAttempts:        5,
+		LoginAttemptWindowMinutes: 15,
+		LoginLockoutMinutes:       15,
+	}
+
+	attemptRepo := newMockLoginAttemptRepo()
+	return NewAuthUsecase(userRepo, newMockTokenRepo(), attemptRepo, nil, &mockNotifier{}, jwtkeys.NewHMACSigner(cfg.JWTSecret), cfg), user, attemptRepo
+}
+
+func TestAuthUsecase_LoginLockout(t *testing.T) {
+	ctx := context.Background()
+	uc, user, attemptRepo := newLockoutTestAuthUsecase(t)
+
+	for i := 0; i < 3; i++ {
+		if _, _, err := login(ctx, uc, user.Email, "wrong-password", testClientIP); err == nil || errors.Is(err, domain.ErrAccountLocked) {
+			t.Fatalf("attempt %d: expected invalid credentials, got %v", i+1, err)
+		}
+	}
+	if user.LockedUntil == nil {
+		t.Fatal("Expected the account to be locked after 3 failed attempts")
+	}
+
+	// The right password does not get through while locked.
+	if _, _, err := login(ctx, uc, user.Email, "password123", "198.51.100.7"); !errors.Is(err, domain.ErrAccountLocked) {
+		t.Errorf("Expected ErrAccountLocked, got %v", err)
+	}
+
+	// Once the lock expires the user can log in and the count starts over.
+	expired := time.Now().Add(-time.Minute)
+	user.LockedUntil = &expired
+	if _, _, err := login(ctx, uc, user.Email, "password123", "198.51.100.7"); err != nil {
+		t.Fatalf("Expected login after the lock expired, got %v", err)
+	}
+	if user.FailedLoginAttempts != 0 || user.LockedUntil != nil {
+		t.Error("Expected a successful login to reset the failure count")
+	}
+
+	last := attemptRepo.attempts[len(attemptRepo.attempts)-1]
+	if !last.Success || last.UserID == nil || last.IPAddress != "198.51.100.7" {
+		t.Errorf("Expected the successful attempt to be recorded, got %+v", last)
+	}
+}
+
+func TestAuthUsecase_LoginIPThrottle(t *testing.T) {
+	ctx := context.Background()
+	uc, user, attemptRepo := newLockoutTestAuthUsecase(t)
+
+	// Spread failures over unknown accounts so no single account locks.
+	for i := 0; i < 5; i++ {
+		if _, _, err := login(ctx, uc, "nobody@example.com", "guess", testClientIP); err == nil {
+			t.Fatal("Expected unknown account login to fail")
+		}
+	}
+	for _, attempt := range attemptRepo.attempts {
+		if attempt.UserID != nil {
+			t.Error("Expected attempts on unknown emails to have no user")
+		}
+	}
+
+	if _, _, err := login(ctx, uc, user.Email, "password123", testClientIP); !errors.Is(err, domain.ErrTooManyLoginAttempts) {
+		t.Errorf("Expected ErrTooManyLoginAttempts for the throttled IP, got %v", err)
+	}
+	if _, _, err := login(ctx, uc, user.Email, "password123", "198.51.100.7"); err != nil {
+		t.Errorf("Expected other IPs to be unaffected, got %v", err)
+	}
+}
+
+// login runs a password login that is expected to return tokens directly.
+func login(ctx context.Context, uc domain.AuthUsecase, email, password, clientIP string) (*domain.TokenPair, *domain.User, error) {
+	result, err := uc.Login(ctx, email, password, clientIP)
+	if err != nil {
+		return nil, nil, err
+	}
+	return result.Tokens, result.User, nil
+}
+
+func newTwoFactorTestAuthUsecase(t *testing.T, requiredRoles ...string) (domain.AuthUsecase, domain.TwoFactorUsecase, *mockTwoFactorRepo, *domain.User) {
+	t.Helper()
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	user := &domain.User{ID: 3, Email: "manager@example.com", Role: domain.RoleManager, PasswordHash: string(hashedPassword), Active: true}
+	userRepo := &mockUserRepo{
+		getByIDFunc: func(ctx context.Context, id int) (*domain.User, error) {
+			copy := *user
+			return &copy, nil
+		},
+		getByEmailFunc: func(ctx context.Context, email string) (*domain.User, error) {
+			copy := *user
+			return &copy, nil
+		},
+	}
+	cfg := &config.Config{
+		JWTSecret:                 "test-secret-key",
+		AccessTokenTTLMinutes:     15,
+		RefreshTokenTTLHours:      720,
+		MFAChallengeTTLMinutes:    5,
+		LoginAttemptWindowMinutes: 15,
+		TOTPIssuer:                "Expense Management",
+		TOTPRequiredRoles:         requiredRoles,
+	}
+
+	twoFactorRepo := newMockTwoFactorRepo()
+	twoFactor := NewTwoFactorUsecase(twoFactorRepo, userRepo, cfg)
+	uc := NewAuthUsecase(userRepo, newMockTokenRepo(), newMockLoginAttemptRepo(), twoFactor, &mockNotifier{}, jwtkeys.NewHMACSigner(cfg.JWTSecret), cfg)
+	return uc, twoFactor, twoFactorRepo, user
+}
+
+func TestAuthUsecase_LoginWithTwoFactor(t *testing.T) {
+	ctx := context.Background()
+	uc, twoFactor, twoFactorRepo, user := newTwoFactorTestAuthUsecase(t)
+
+	result, err := uc.Login(ctx, user.Email, "password123", testClientIP)
+	if err != nil {
+		t.Fatalf("Login() unexpected error = %v", err)
+	}
+	if result.Challenge != nil || result.Tokens == nil {
+		t.Fatal("Expected tokens straight away without two-factor")
+	}
+
+	enrolUser(t, twoFactor, twoFactorRepo, user)
+
+	result, err = uc.Login(ctx, user.Email, "password123", testClientIP)
+	if err != nil {
+		t.Fatalf("Login() unexpected error = %v", err)
+	}
+	if result.Tokens != nil || result.Challenge == nil || result.Challenge.EnrollmentRequired {
+		t.Fatalf("Expected a challenge instead of tokens, got %+v", result)
+	}
+
+	challenge := result.Challenge.Token
+	if _, err := uc.ValidateToken(ctx, challenge); err == nil {
+		t.Error("Expected a challenge token not to work as an access token")
+	}
+	if _, _, _, err := uc.CompleteLogin(ctx, challenge, "000000"); !errors.Is(err, domain.ErrInvalidTwoFactorCode) {
+		t.Errorf("CompleteLogin() with wrong code error = %v, want ErrInvalidTwoFactorCode", err)
+	}
+
+	pair, _, _, err := uc.CompleteLogin(ctx, challenge, currentCode(t, twoFactorRepo, user.ID, 0))
+	if err != nil {
+		t.Fatalf("CompleteLogin() unexpected error = %v", err)
+	}
+	if _, err := uc.ValidateToken(ctx, pair.AccessToken); err != nil {
+		t.Errorf("Expected the completed login's token to be valid, got %v", err)
+	}
+
+	if _, _, _, err := uc.CompleteLogin(ctx, challenge, currentCode(t, twoFactorRepo, user.ID, 1)); err == nil {
+		t.Error("Expected a completed challenge not to be usable again")
+	}
+}
+
+func TestAuthUsecase_LoginRequiresEnrolment(t *testing.T) {
+	ctx := context.Background()
+	uc, twoFactor, twoFactorRepo, user := newTwoFactorTestAuthUsecase(t, domain.RoleManager)
+
+	result, err := uc.Login(ctx, user.Email, "password123", testClientIP)
+	if err != nil {
+		t.Fatalf("Login() unexpected error = %v", err)
+	}
+	if result.Challenge == nil || !result.Challenge.EnrollmentRequired {
+		t.Fatalf("Expected an enrolment challenge, got %+v", result)
+	}
+
+	setup, err := uc.ChallengeSetup(ctx, result.Challenge.Token)
+	if err != nil {
+		t.Fatalf("ChallengeSetup() unexpected error = %v", err)
+	}
+	if setup.ProvisioningURI == "" {
+		t.Error("Expected a provisioning URI")
+	}
+
+	_, _, recoveryCodes, err := uc.CompleteLogin(ctx, result.Challenge.Token, currentCode(t, twoFactorRepo, user.ID, 0))
+	if err != nil {
+		t.Fatalf("CompleteLogin() unexpected error = %v", err)
+	}
+	if len(recoveryCodes) != recoveryCodeCount {
+		t.Errorf("Expected recovery codes after enrolment, got %d", len(recoveryCodes))
+	}
+	if enabled, _ := twoFactor.IsEnabled(ctx, user.ID); !enabled {
+		t.Error("Expected two-factor to be enabled by the enrolment login")
+	}
+}
+
+func TestAuthUsecase_ValidateTokenClaims(t *testing.T) {
+	ctx := context.Background()
+	lookups := 0
+	userRepo := &mockUserRepo{
+		getByIDFunc: func(ctx context.Context, id int) (*domain.User, error) {
+			lookups++
+			return &domain.User{ID: id, Email: "test@example.com", Role: domain.RoleManager, Active: false}, nil
+		},
+	}
+	cfg := &config.Config{JWTSecret: "test-secret-key", AccessTokenTTLMinutes: 15}
+	uc := NewAuthUsecase(userRepo, newMockTokenRepo(), newMockLoginAttemptRepo(), nil, &mockNotifier{}, jwtkeys.NewHMACSigner(cfg.JWTSecret), cfg).(*authUsecase)
+
+	token, _ := uc.generateToken(ctx, &domain.User{ID: 5, Email: "test@example.com", Name: "Test", Role: domain.RoleEmployee}, "jti-claims")
+
+	// Without trusting claims the stored user decides.
+	if _, err := uc.ValidateTokenClaims(ctx, token); err == nil || lookups != 1 {
+		t.Errorf("Expected the deactivated user to be looked up and rejected, got %v after %d lookups", err, lookups)
+	}
+
+	cfg.AuthTrustTokenClaims = true
+	user, err := uc.ValidateTokenClaims(ctx, token)
+	if err != nil {
+		t.Fatalf("ValidateTokenClaims() unexpected error = %v", err)
+	}
+	if lookups != 1 {
+		t.Errorf("Expected no user lookup, got %d", lookups)
+	}
+	if user.ID != 5 || user.Role != domain.RoleEmployee || user.Name != "Test" || user.Email != "test@example.com" {
+		t.Errorf("ValidateTokenClaims() user = %+v", user)
+	}
+
+	// Revocation is still checked.
+	uc.tokenRepo.(*mockTokenRepo).revokedJTIs["jti-claims"] = true
+	if _, err := uc.ValidateTokenClaims(ctx, token); err == nil {
+		t.Error("Expected a revoked token to be rejected")
+	}
+
+	// With the user cache on, the cached user's Active flag is checked too.
+	delete(uc.tokenRepo.(*mockTokenRepo).revokedJTIs, "jti-claims")
+	cfg.UserCacheTTLSeconds = 30
+	if _, err := uc.ValidateTokenClaims(ctx, token); err == nil || lookups != 2 {
+		t.Errorf("Expected the deactivated user to be rejected, got %v after %d lookups", err, lookups)
+	}
+}
+
+// BenchmarkAuthUsecase_ValidateToken authenticates concurrent requests of a
+// few users against a database that takes 200µs per lookup and reports how
+// many lookups each request cost.
+func BenchmarkAuthUsecase_ValidateToken(b *testing.B) {
+	const users = 20
+
+	cases := []struct {
+		name       string
+		cacheTTL   time.Duration
+		trustClaim bool
+	}{
+		{"no cache", 0, false},
+		{"cache", 30 * time.Second, false},
+		{"trusted claims", 0, true},
+	}
+
+	for _, tc := range cases {
+		b.Run(tc.name, func(b *testing.B) {
+			ctx := context.Background()
+			var lookups int64
+			db := &mockUserRepo{
+				getByIDFunc: func(ctx context.Context, id int) (*domain.User, error) {
+					atomic.AddInt64(&lookups, 1)
+					time.Sleep(200 * time.Microsecond)
+					return &domain.User{ID: id, Email: "test@example.com", Role: domain.RoleEmployee, Active: true}, nil
+				},
+			}
+			userRepo := repository.NewCachedUserRepository(db, tc.cacheTTL)
+			cfg := &config.Config{JWTSecret: "test-secret-key", AccessTokenTTLMinutes: 15, AuthTrustTokenClaims: tc.trustClaim}
+			uc := NewAuthUsecase(userRepo, newMockTokenRepo(), newMockLoginAttemptRepo(), nil, &mockNotifier{}, jwtkeys.NewHMACSigner(cfg.JWTSecret), cfg).(*authUsecase)
+
+			tokens := make([]string, users)
+			for i := range tokens {
+				user := &domain.User{ID: i + 1, Email: "test@example.com", Role: domain.RoleEmployee}
+				tokens[i], _ = uc.generateToken(ctx, user, fmt.Sprintf("jti-%d", i))
+			}
+
+			var next int64
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					token := tokens[atomic.AddInt64(&next, 1)%users]
+					if _, err := uc.ValidateTokenClaims(ctx, token); err != nil {
+						b.Error(err)
+					}
+				}
+			})
+			b.ReportMetric(float64(atomic.LoadInt64(&lookups))/float64(b.N), "lookups/op")
+		})
+	}
+}
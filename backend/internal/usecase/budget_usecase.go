@@ -0,0 +1,150 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"expense-management-system/internal/domain"
+	"expense-management-system/pkg/logger"
+	"fmt"
+	"time"
+)
+
+type budgetUsecase struct {
+	budgetRepo domain.BudgetRepository
+	userRepo   domain.UserRepository
+	now        func() time.Time
+}
+
+func NewBudgetUsecase(budgetRepo domain.BudgetRepository, userRepo domain.UserRepository) domain.BudgetUsecase {
+	return &budgetUsecase{
+		budgetRepo: budgetRepo,
+		userRepo:   userRepo,
+		now:        time.Now,
+	}
+}
+
+func (u *budgetUsecase) Create(ctx context.Context, userID int, budget *domain.Budget) (*domain.Budget, error) {
+	if budget.UserID != nil && budget.TeamID != nil {
+		return nil, errors.New("a budget applies to either a user or a team, not both")
+	}
+
+	if budget.Category != nil && !domain.IsValidCategory(*budget.Category) {
+		return nil, fmt.Errorf("unknown category %q", *budget.Category)
+	}
+
+	switch budget.Period {
+	case domain.BudgetPeriodMonthly, domain.BudgetPeriodQuarterly, domain.BudgetPeriodYearly:
+	default:
+		return nil, errors.New("period must be monthly, quarterly or yearly")
+	}
+
+	if budget.SoftLimitIDR == nil && budget.HardLimitIDR == nil {
+		return nil, errors.New("at least one of soft_limit_idr or hard_limit_idr is required")
+	}
+	if (budget.SoftLimitIDR != nil && *budget.SoftLimitIDR <= 0) || (budget.HardLimitIDR != nil && *budget.HardLimitIDR <= 0) {
+		return nil, errors.New("limits must be positive")
+	}
+	if budget.SoftLimitIDR != nil && budget.HardLimitIDR != nil && *budget.SoftLimitIDR > *budget.HardLimitIDR {
+		return nil, errors.New("soft limit cannot exceed hard limit")
+	}
+
+	if budget.HardLimitAction == "" {
+		budget.HardLimitAction = domain.BudgetActionRequireApproval
+	}
+	if budget.HardLimitAction != domain.BudgetActionRequireApproval && budget.HardLimitAction != domain.BudgetActionBlock {
+		return nil, errors.New("hard_limit_action must be require_approval or block")
+	}
+
+	budget.CreatedBy = userID
+	if err := u.budgetRepo.Create(ctx, budget); err != nil {
+		return nil, err
+	}
+
+	logger.InfoLogger.Printf("Budget %d (%s) created by user %d", budget.ID, budget.Period, userID)
+	return budget, nil
+}
+
+func (u *budgetUsecase) List(ctx context.Context) ([]*domain.Budget, error) {
+	return u.budgetRepo.List(ctx)
+}
+
+func (u *budgetUsecase) Delete(ctx context.Context, id int) error {
+	return u.budgetRepo.Delete(ctx, id)
+}
+
+func (u *budgetUsecase) GetStatus(ctx context.Context, userID int) ([]*domain.BudgetStatus, error) {
+	return u.evaluate(ctx, userID, nil, 0)
+}
+
+func (u *budgetUsecase) Evaluate(ctx context.Context, userID int, category string, amountIDR int) ([]*domain.BudgetStatus, error) {
+	return u.evaluate(ctx, userID, &category, amountIDR)
+}
+
+func (u *budgetUsecase) evaluate(ctx context.Context, userID int, category *string, amountIDR int) ([]*domain.BudgetStatus, error) {
+	user, err := u.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	budgets, err := u.budgetRepo.GetApplicable(ctx, userID, user.TeamID, category)
+	if err != nil {
+		return nil, err
+	}
+
+	now := u.now()
+	statuses := make([]*domain.BudgetStatus, 0, len(budgets))
+	for _, budget := range budgets {
+		start, end := periodBounds(budget.Period, now)
+
+		consumed, err := u.budgetRepo.GetConsumption(ctx, budget, start, end)
+		if err != nil {
+			return nil, err
+		}
+		consumed += amountIDR
+
+		statuses = append(statuses, &domain.BudgetStatus{
+			Budget:            budget,
+			PeriodStart:       start,
+			PeriodEnd:         end,
+			ConsumedIDR:       consumed,
+			SoftLimitExceeded: budget.SoftLimitIDR != nil && consumed > *budget.SoftLimitIDR,
+			HardLimitExceeded: budget.HardLimitIDR != nil && consumed > *budget.HardLimitIDR,
+		})
+	}
+
+	return statuses, nil
+}
+
+// periodBounds returns the calendar month, quarter or year containing now.
+func periodBounds(period string, now time.Time) (time.Time, time.Time) {
+	year, month, _ := now.Date()
+
+	switch period {
+	case domain.BudgetPeriodYearly:
+		start := time.Date(year, time.January, 1, 0, 0, 0, 0, now.Location())
+		return start, start.AddDate(1, 0, 0)
+	case domain.BudgetPeriodQuarterly:
+		firstMonth := time.Month((int(month)-1)/3*3 + 1)
+		start := time.Date(year, firstMonth, 1, 0, 0, 0, 0, now.Location())
+		return start, start.AddDate(0, 3, 0)
+	default:
+		start := time.Date(year, month, 1, 0, 0, 0, 0, now.Location())
+		return start, start.AddDate(0, 1, 0)
+	}
+}
+
+// describeBudget is used in warnings shown to the employee.
+func describeBudget(budget *domain.Budget) string {
+	scope := "company"
+	switch {
+	case budget.UserID != nil:
+		scope = "personal"
+	case budget.TeamID != nil:
+		scope = "team"
+	}
+
+	if budget.Category != nil {
+		return fmt.Sprintf("%s %s %s budget", budget.Period, scope, *budget.Category)
+	}
+	return fmt.Sprintf("%s %s budget", budget.Period, scope)
+}
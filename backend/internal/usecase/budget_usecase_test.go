@@ -0,0 +1,208 @@
+package usecase
+
+import (
+	"context"
+	"expense-management-system/internal/domain"
+	"strings"
+	"testing"
+	"time"
+)
+
+type mockBudgetRepo struct {
+	budgets  []*domain.Budget
+	consumed map[int]int
+	created  *domain.Budget
+}
+
+func (m *mockBudgetRepo) Create(ctx context.Context, budget *domain.Budget) error {
+	budget.ID = 1
+	m.created = budget
+	return nil
+}
+
+func (m *mockBudgetRepo) GetByID(ctx context.Context, id int) (*domain.Budget, error) {
+	return nil, nil
+}
+
+func (m *mockBudgetRepo) List(ctx context.Context) ([]*domain.Budget, error) {
+	return m.budgets, nil
+}
+
+func (m *mockBudgetRepo) Delete(ctx context.Context, id int) error {
+	return nil
+}
+
+func (m *mockBudgetRepo) GetApplicable(ctx context.Context, userID int, teamID *int, category *string) ([]*domain.Budget, error) {
+	return m.budgets, nil
+}
+
+func (m *mockBudgetRepo) GetConsumption(ctx context.Context, budget *domain.Budget, from, to time.Time) (int, error) {
+	return m.consumed[budget.ID], nil
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestPeriodBounds(t *testing.T) {
+	now := time.Date(2026, 5, 17, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		period    string
+		wantStart time.Time
+		wantEnd   time.Time
+	}{
+		{domain.BudgetPeriodMonthly, time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{domain.BudgetPeriodQuarterly, time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC)},
+		{domain.BudgetPeriodYearly, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.period, func(t *testing.T) {
+			start, end := periodBounds(tt.period, now)
+			if !start.Equal(tt.wantStart) || !end.Equal(tt.wantEnd) {
+				t.Errorf("periodBounds() = [%v, %v), want [%v, %v)", start, end, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestBudgetUsecase_Create(t *testing.T) {
+	tests := []struct {
+		name    string
+		budget  *domain.Budget
+		wantErr bool
+	}{
+		{
+			name:   "Team travel budget defaults to require approval",
+			budget: &domain.Budget{TeamID: intPtr(1), Category: strPtr(domain.CategoryTravel), Period: domain.BudgetPeriodMonthly, HardLimitIDR: intPtr(10000000)},
+		},
+		{
+			name:    "User and team are mutually exclusive",
+			budget:  &domain.Budget{UserID: intPtr(1), TeamID: intPtr(1), Period: domain.BudgetPeriodMonthly, HardLimitIDR: intPtr(10000000)},
+			wantErr: true,
+		},
+		{
+			name:    "A limit is required",
+			budget:  &domain.Budget{UserID: intPtr(1), Period: domain.BudgetPeriodMonthly},
+			wantErr: true,
+		},
+		{
+			name:    "Soft limit cannot exceed hard limit",
+			budget:  &domain.Budget{UserID: intPtr(1), Period: domain.BudgetPeriodYearly, SoftLimitIDR: intPtr(5000000), HardLimitIDR: intPtr(1000000)},
+			wantErr: true,
+		},
+		{
+			name:    "Unknown period",
+			budget:  &domain.Budget{UserID: intPtr(1), Period: "weekly", HardLimitIDR: intPtr(1000000)},
+			wantErr: true,
+		},
+		{
+			name:    "Unknown category",
+			budget:  &domain.Budget{Category: strPtr("yachts"), Period: domain.BudgetPeriodMonthly, HardLimitIDR: intPtr(1000000)},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc := NewBudgetUsecase(&mockBudgetRepo{}, &mockUserRepo{})
+
+			budget, err := uc.Create(context.Background(), 4, tt.budget)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Create() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if budget.HardLimitAction != domain.BudgetActionRequireApproval || budget.CreatedBy != 4 {
+				t.Errorf("Unexpected budget %+v", budget)
+			}
+		})
+	}
+}
+
+func TestExpenseUsecase_Submit_Budgets(t *testing.T) {
+	tests := []struct {
+		name         string
+		budget       *domain.Budget
+		consumed     int
+		amountIDR    int
+		wantErr      bool
+		wantStatus   string
+		wantWarnings int
+	}{
+		{
+			name:       "Within budget is auto-approved without warnings",
+			budget:     &domain.Budget{ID: 1, UserID: intPtr(1), Period: domain.BudgetPeriodMonthly, SoftLimitIDR: intPtr(2000000), HardLimitIDR: intPtr(3000000), HardLimitAction: domain.BudgetActionRequireApproval},
+			consumed:   500000,
+			amountIDR:  500000,
+			wantStatus: domain.StatusApproved,
+		},
+		{
+			name:         "Soft limit breach warns but still auto-approves",
+			budget:       &domain.Budget{ID: 1, UserID: intPtr(1), Period: domain.BudgetPeriodMonthly, SoftLimitIDR: intPtr(2000000), HardLimitIDR: intPtr(3000000), HardLimitAction: domain.BudgetActionRequireApproval},
+			consumed:     1800000,
+			amountIDR:    500000,
+			wantStatus:   domain.StatusApproved,
+			wantWarnings: 1,
+		},
+		{
+			name:         "Hard limit breach requires approval",
+			budget:       &domain.Budget{ID: 1, UserID: intPtr(1), Period: domain.BudgetPeriodMonthly, SoftLimitIDR: intPtr(2000000), HardLimitIDR: intPtr(3000000), HardLimitAction: domain.BudgetActionRequireApproval},
+			consumed:     2800000,
+			amountIDR:    500000,
+			wantStatus:   domain.StatusAwaitingApproval,
+			wantWarnings: 1,
+		},
+		{
+			name:      "Hard limit breach on blocking budget rejects submission",
+			budget:    &domain.Budget{ID: 1, Category: strPtr(domain.CategoryMeals), Period: domain.BudgetPeriodMonthly, HardLimitIDR: intPtr(3000000), HardLimitAction: domain.BudgetActionBlock},
+			consumed:  2800000,
+			amountIDR: 500000,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paymentChan := make(chan PaymentJob, 1)
+			userRepo := &mockUserRepo{
+				getByIDFunc: func(ctx context.Context, id int) (*domain.User, error) {
+					return &domain.User{ID: id, Email: "employee1@example.com", Role: domain.RoleEmployee}, nil
+				},
+			}
+			budgetRepo := &mockBudgetRepo{
+				budgets:  []*domain.Budget{tt.budget},
+				consumed: map[int]int{tt.budget.ID: tt.consumed},
+			}
+			budgets := NewBudgetUsecase(budgetRepo, userRepo)
+
+			uc := NewExpenseUsecase(&mockExpenseRepo{}, &mockApprovalRepo{}, &mockAuditRepo{}, userRepo, &mockCashAdvanceRepo{}, paymentChan, nil, budgets, nil, nil, nil, nil)
+
+			expense, err := uc.Submit(context.Background(), 1, tt.amountIDR, "Team dinner", domain.CategoryMeals, nil, nil, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Submit() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !strings.Contains(err.Error(), "budget") {
+					t.Errorf("Expected budget error, got %v", err)
+				}
+				return
+			}
+
+			if expense.Status != tt.wantStatus {
+				t.Errorf("Submit() status = %v, want %v", expense.Status, tt.wantStatus)
+			}
+
+			if len(expense.BudgetWarnings) != tt.wantWarnings {
+				t.Errorf("Submit() warnings = %v, want %d", expense.BudgetWarnings, tt.wantWarnings)
+			}
+
+			if expense.Category != domain.CategoryMeals {
+				t.Errorf("Submit() category = %v, want %v", expense.Category, domain.CategoryMeals)
+			}
+		})
+	}
+}
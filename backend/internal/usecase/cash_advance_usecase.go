@@ -0,0 +1,280 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"expense-management-system/internal/domain"
+	"expense-management-system/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+type cashAdvanceUsecase struct {
+	advanceRepo domain.CashAdvanceRepository
+	auditRepo   domain.AuditLogRepository
+	userRepo    domain.UserRepository
+	paymentChan chan PaymentJob
+	settings    domain.SettingsUsecase
+}
+
+func NewCashAdvanceUsecase(
+	advanceRepo domain.CashAdvanceRepository,
+	auditRepo domain.AuditLogRepository,
+	userRepo domain.UserRepository,
+	paymentChan chan PaymentJob,
+	settings domain.SettingsUsecase,
+) domain.CashAdvanceUsecase {
+	return &cashAdvanceUsecase{
+		advanceRepo: advanceRepo,
+		auditRepo:   auditRepo,
+		userRepo:    userRepo,
+		paymentChan: paymentChan,
+		settings:    settings,
+	}
+}
+
+// Request follows the same rules as an expense: small advances are approved
+// and paid straight away, larger ones wait for a manager.
+func (u *cashAdvanceUsecase) Request(ctx context.Context, userID int, amountIDR int, purpose string) (*domain.CashAdvance, error) {
+	limits, err := expenseLimits(ctx, u.settings)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAmount(limits, amountIDR); err != nil {
+		return nil, err
+	}
+
+	if purpose == "" {
+		return nil, errors.New("purpose is required")
+	}
+
+	autoApproved := amountIDR < limits.ApprovalThresholdIDR
+	status := domain.AdvanceStatusAwaitingApproval
+	if autoApproved {
+		status = domain.AdvanceStatusApproved
+	}
+
+	advance := &domain.CashAdvance{
+		UserID:            userID,
+		AmountIDR:         amountIDR,
+		Purpose:           purpose,
+		Status:            status,
+		PaymentExternalID: uuid.New().String(),
+	}
+
+	if err := u.advanceRepo.Create(ctx, advance); err != nil {
+		return nil, err
+	}
+
+	auditLog := &domain.AuditLog{
+		CashAdvanceID: &advance.ID,
+		UserID:        &userID,
+		Action:        domain.ActionSubmit,
+		NewStatus:     &status,
+		Metadata: map[string]interface{}{
+			"amount_idr":             amountIDR,
+			"auto_approved":          autoApproved,
+			"approval_threshold_idr": limits.ApprovalThresholdIDR,
+		},
+	}
+	u.auditRepo.Create(ctx, auditLog)
+
+	user, _ := u.userRepo.GetByID(ctx, userID)
+	if autoApproved {
+		logger.InfoLogger.Printf("Auto-approved cash advance %d, dispatching payment", advance.ID)
+		u.sendToPaymentQueue(advance)
+
+		if user != nil {
+			logger.InfoLogger.Printf("[EMAIL] Cash advance approval notification sent to %s for advance %d (IDR %d)", user.Email, advance.ID, amountIDR)
+		}
+	} else {
+		logger.InfoLogger.Printf("Cash advance %d requires manager approval (amount: IDR %d >= threshold)", advance.ID, amountIDR)
+
+		if user != nil {
+			logger.InfoLogger.Printf("[EMAIL] Approval request notification sent to managers for cash advance %d by %s", advance.ID, user.Email)
+		}
+	}
+
+	return advance, nil
+}
+
+func (u *cashAdvanceUsecase) GetByID(ctx context.Context, userID int, advanceID int, canViewAll bool) (*domain.CashAdvance, error) {
+	advance, err := u.advanceRepo.GetByID(ctx, advanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !canViewAll && advance.UserID != userID {
+		return nil, errors.New("unauthorized access to cash advance")
+	}
+
+	return advance, nil
+}
+
+func (u *cashAdvanceUsecase) List(ctx context.Context, userID int, status string, page, limit int, canViewAll bool) ([]*domain.CashAdvance, int, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	offset := (page - 1) * limit
+
+	// Managers can see every employee's advances
+	if canViewAll {
+		userID = 0
+	}
+
+	return u.advanceRepo.List(ctx, userID, status, limit, offset)
+}
+
+func (u *cashAdvanceUsecase) Approve(ctx context.Context, managerID, advanceID int, notes *string) error {
+	advance, err := u.advanceRepo.GetByID(ctx, advanceID)
+	if err != nil {
+		return err
+	}
+
+	if advance.Status != domain.AdvanceStatusAwaitingApproval {
+		return errors.New("cash advance is not awaiting approval")
+	}
+
+	if err := u.advanceRepo.UpdateApproval(ctx, advanceID, domain.AdvanceStatusApproved, managerID, notes); err != nil {
+		return err
+	}
+
+	u.auditDecision(ctx, managerID, advance, domain.ActionApprove, domain.AdvanceStatusApproved, notes)
+
+	logger.InfoLogger.Printf("Cash advance %d approved by manager %d, dispatching payment", advanceID, managerID)
+	advance.Status = domain.AdvanceStatusApproved
+	u.sendToPaymentQueue(advance)
+
+	user, _ := u.userRepo.GetByID(ctx, advance.UserID)
+	if user != nil {
+		logger.InfoLogger.Printf("[EMAIL] Cash advance approval notification sent to %s for advance %d", user.Email, advanceID)
+	}
+
+	return nil
+}
+
+func (u *cashAdvanceUsecase) Reject(ctx context.Context, managerID, advanceID int, notes *string) error {
+	advance, err := u.advanceRepo.GetByID(ctx, advanceID)
+	if err != nil {
+		return err
+	}
+
+	if advance.Status != domain.AdvanceStatusAwaitingApproval {
+		return errors.New("cash advance is not awaiting approval")
+	}
+
+	if err := u.advanceRepo.UpdateApproval(ctx, advanceID, domain.AdvanceStatusRejected, managerID, notes); err != nil {
+		return err
+	}
+
+	u.auditDecision(ctx, managerID, advance, domain.ActionReject, domain.AdvanceStatusRejected, notes)
+
+	logger.InfoLogger.Printf("Cash advance %d rejected by manager %d", advanceID, managerID)
+
+	user, _ := u.userRepo.GetByID(ctx, advance.UserID)
+	if user != nil {
+		logger.InfoLogger.Printf("[EMAIL] Cash advance rejection notification sent to %s for advance %d", user.Email, advanceID)
+	}
+
+	return nil
+}
+
+func (u *cashAdvanceUsecase) auditDecision(ctx context.Context, managerID int, advance *domain.CashAdvance, action, newStatus string, notes *string) {
+	oldStatus := advance.Status
+	auditLog := &domain.AuditLog{
+		CashAdvanceID: &advance.ID,
+		UserID:        &managerID,
+		Action:        action,
+		OldStatus:     &oldStatus,
+		NewStatus:     &newStatus,
+		Metadata: map[string]interface{}{
+			"notes": notes,
+		},
+	}
+	u.auditRepo.Create(ctx, auditLog)
+}
+
+// GetBalance sums what the employee still owes across their paid advances,
+// i.e. money handed out that has not yet been covered by expenses.
+func (u *cashAdvanceUsecase) GetBalance(ctx context.Context, userID int) (*domain.AdvanceBalance, error) {
+	balance := &domain.AdvanceBalance{UserID: userID, Advances: []*domain.CashAdvance{}}
+
+	const pageSize = 100
+	for offset := 0; ; offset += pageSize {
+		advances, total, err := u.advanceRepo.List(ctx, userID, domain.AdvanceStatusPaid, pageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, advance := range advances {
+			balance.OutstandingIDR += advance.OutstandingIDR
+			balance.Advances = append(balance.Advances, advance)
+		}
+
+		if len(advances) == 0 || offset+pageSize >= total {
+			break
+		}
+	}
+
+	return balance, nil
+}
+
+// Close is used by finance once the employee has returned whatever part of
+// the advance was not spent; the remaining balance is recorded as recovered.
+func (u *cashAdvanceUsecase) Close(ctx context.Context, userID, advanceID int) (*domain.CashAdvance, error) {
+	advance, err := u.advanceRepo.GetByID(ctx, advanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if advance.Status != domain.AdvanceStatusPaid {
+		return nil, errors.New("only paid cash advances can be closed")
+	}
+
+	outstanding := advance.OutstandingIDR
+	if _, err := u.advanceRepo.Close(ctx, advanceID); err != nil {
+		return nil, err
+	}
+
+	oldStatus := advance.Status
+	newStatus := domain.AdvanceStatusSettled
+	auditLog := &domain.AuditLog{
+		CashAdvanceID: &advance.ID,
+		UserID:        &userID,
+		Action:        domain.ActionSettle,
+		OldStatus:     &oldStatus,
+		NewStatus:     &newStatus,
+		Metadata: map[string]interface{}{
+			"recovered_idr": outstanding,
+		},
+	}
+	u.auditRepo.Create(ctx, auditLog)
+
+	logger.InfoLogger.Printf("Cash advance %d closed by user %d, IDR %d recovered", advanceID, userID, outstanding)
+
+	user, _ := u.userRepo.GetByID(ctx, advance.UserID)
+	if user != nil && outstanding > 0 {
+		logger.InfoLogger.Printf("[EMAIL] Cash advance settlement notification sent to %s for advance %d (IDR %d recovered)", user.Email, advanceID, outstanding)
+	}
+
+	return u.advanceRepo.GetByID(ctx, advanceID)
+}
+
+// Advances are always paid immediately, even when expenses are batched, since
+// the employee needs the money before the trip.
+func (u *cashAdvanceUsecase) sendToPaymentQueue(advance *domain.CashAdvance) {
+	select {
+	case u.paymentChan <- PaymentJob{
+		AdvanceID:  advance.ID,
+		Amount:     advance.AmountIDR,
+		ExternalID: advance.PaymentExternalID,
+	}:
+		logger.InfoLogger.Printf("Payment job queued for cash advance %d", advance.ID)
+	default:
+		logger.ErrorLogger.Printf("Payment queue full, could not queue cash advance %d", advance.ID)
+	}
+}
@@ -0,0 +1,327 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"expense-management-system/internal/domain"
+	"testing"
+)
+
+type mockCashAdvanceRepo struct {
+	getByIDFunc         func(ctx context.Context, id int) (*domain.CashAdvance, error)
+	listFunc            func(ctx context.Context, userID int, status string, limit, offset int) ([]*domain.CashAdvance, int, error)
+	applySettlementFunc func(ctx context.Context, id int, expenseID int, amountIDR int) (int, error)
+	approvals           map[int]string
+	closed              []int
+}
+
+func (m *mockCashAdvanceRepo) Create(ctx context.Context, advance *domain.CashAdvance) error {
+	advance.ID = 1
+	return nil
+}
+
+func (m *mockCashAdvanceRepo) GetByID(ctx context.Context, id int) (*domain.CashAdvance, error) {
+	if m.getByIDFunc != nil {
+		return m.getByIDFunc(ctx, id)
+	}
+	return nil, errors.New("cash advance not found")
+}
+
+func (m *mockCashAdvanceRepo) List(ctx context.Context, userID int, status string, limit, offset int) ([]*domain.CashAdvance, int, error) {
+	if m.listFunc != nil {
+		return m.listFunc(ctx, userID, status, limit, offset)
+	}
+	return nil, 0, nil
+}
+
+func (m *mockCashAdvanceRepo) UpdateApproval(ctx context.Context, id int, status string, approverID int, notes *string) error {
+	if m.approvals == nil {
+		m.approvals = map[int]string{}
+	}
+	m.approvals[id] = status
+	return nil
+}
+
+func (m *mockCashAdvanceRepo) MarkPaid(ctx context.Context, id int, paymentID string) error {
+	return nil
+}
+
+func (m *mockCashAdvanceRepo) ApplySettlement(ctx context.Context, id int, expenseID int, amountIDR int) (int, error) {
+	if m.applySettlementFunc != nil {
+		return m.applySettlementFunc(ctx, id, expenseID, amountIDR)
+	}
+	return 0, nil
+}
+
+func (m *mockCashAdvanceRepo) Close(ctx context.Context, id int) (int, error) {
+	m.closed = append(m.closed, id)
+	return 0, nil
+}
+
+func TestCashAdvanceUsecase_Request(t *testing.T) {
+	tests := []struct {
+		name       string
+		amountIDR  int
+		purpose    string
+		wantErr    bool
+		wantStatus string
+		wantJob    bool
+	}{
+		{
+			name:       "Small advance is auto-approved and paid",
+			amountIDR:  500000,
+			purpose:    "Taxi fares for Surabaya trip",
+			wantStatus: domain.AdvanceStatusApproved,
+			wantJob:    true,
+		},
+		{
+			name:       "Large advance requires approval",
+			amountIDR:  3000000,
+			purpose:    "Hotel for Bali conference",
+			wantStatus: domain.AdvanceStatusAwaitingApproval,
+		},
+		{
+			name:      "Amount below minimum",
+			amountIDR: 5000,
+			purpose:   "Parking",
+			wantErr:   true,
+		},
+		{
+			name:      "Purpose is required",
+			amountIDR: 500000,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paymentChan := make(chan PaymentJob, 1)
+			uc := NewCashAdvanceUsecase(&mockCashAdvanceRepo{}, &mockAuditRepo{}, &mockUserRepo{}, paymentChan, nil)
+
+			advance, err := uc.Request(context.Background(), 1, tt.amountIDR, tt.purpose)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Request() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if advance.Status != tt.wantStatus {
+				t.Errorf("Request() status = %v, want %v", advance.Status, tt.wantStatus)
+			}
+
+			select {
+			case job := <-paymentChan:
+				if !tt.wantJob {
+					t.Error("Payment job should not be queued before approval")
+				}
+				if job.AdvanceID != advance.ID || job.Amount != tt.amountIDR || job.ExpenseID != 0 {
+					t.Errorf("Unexpected payment job %+v", job)
+				}
+			default:
+				if tt.wantJob {
+					t.Error("Expected payment job to be queued")
+				}
+			}
+		})
+	}
+}
+
+func TestCashAdvanceUsecase_Approve(t *testing.T) {
+	advanceRepo := &mockCashAdvanceRepo{
+		getByIDFunc: func(ctx context.Context, id int) (*domain.CashAdvance, error) {
+			return &domain.CashAdvance{ID: id, UserID: 1, AmountIDR: 3000000, Status: domain.AdvanceStatusAwaitingApproval, PaymentExternalID: "adv-1"}, nil
+		},
+	}
+
+	var audited *domain.AuditLog
+	auditRepo := &mockAuditRepo{
+		createFunc: func(ctx context.Context, log *domain.AuditLog) error {
+			audited = log
+			return nil
+		},
+	}
+
+	paymentChan := make(chan PaymentJob, 1)
+	uc := NewCashAdvanceUsecase(advanceRepo, auditRepo, &mockUserRepo{}, paymentChan, nil)
+
+	if err := uc.Approve(context.Background(), 2, 5, nil); err != nil {
+		t.Fatalf("Approve() unexpected error = %v", err)
+	}
+
+	if advanceRepo.approvals[5] != domain.AdvanceStatusApproved {
+		t.Errorf("Advance approval status = %v, want %v", advanceRepo.approvals[5], domain.AdvanceStatusApproved)
+	}
+
+	if audited == nil || audited.CashAdvanceID == nil || *audited.CashAdvanceID != 5 || audited.ExpenseID != 0 {
+		t.Errorf("Expected approval audit linked to the advance, got %+v", audited)
+	}
+
+	select {
+	case job := <-paymentChan:
+		if job.AdvanceID != 5 || job.ExternalID != "adv-1" {
+			t.Errorf("Unexpected payment job %+v", job)
+		}
+	default:
+		t.Error("Expected payment job to be queued")
+	}
+}
+
+func TestCashAdvanceUsecase_GetBalance(t *testing.T) {
+	advanceRepo := &mockCashAdvanceRepo{
+		listFunc: func(ctx context.Context, userID int, status string, limit, offset int) ([]*domain.CashAdvance, int, error) {
+			if status != domain.AdvanceStatusPaid {
+				t.Errorf("Balance should only consider paid advances, got status %q", status)
+			}
+			return []*domain.CashAdvance{
+				{ID: 1, UserID: userID, OutstandingIDR: 750000},
+				{ID: 2, UserID: userID, OutstandingIDR: 250000},
+			}, 2, nil
+		},
+	}
+	uc := NewCashAdvanceUsecase(advanceRepo, &mockAuditRepo{}, &mockUserRepo{}, make(chan PaymentJob, 1), nil)
+
+	balance, err := uc.GetBalance(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("GetBalance() unexpected error = %v", err)
+	}
+
+	if balance.OutstandingIDR != 1000000 || len(balance.Advances) != 2 {
+		t.Errorf("GetBalance() = %+v, want IDR 1000000 across 2 advances", balance)
+	}
+}
+
+func TestCashAdvanceUsecase_Close(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  string
+		wantErr bool
+	}{
+		{name: "Close paid advance", status: domain.AdvanceStatusPaid},
+		{name: "Cannot close unpaid advance", status: domain.AdvanceStatusApproved, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			advanceRepo := &mockCashAdvanceRepo{
+				getByIDFunc: func(ctx context.Context, id int) (*domain.CashAdvance, error) {
+					return &domain.CashAdvance{ID: id, UserID: 1, Status: tt.status, OutstandingIDR: 200000}, nil
+				},
+			}
+			uc := NewCashAdvanceUsecase(advanceRepo, &mockAuditRepo{}, &mockUserRepo{}, make(chan PaymentJob, 1), nil)
+
+			_, err := uc.Close(context.Background(), 4, 3)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Close() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !tt.wantErr && len(advanceRepo.closed) != 1 {
+				t.Error("Expected advance to be closed")
+			}
+		})
+	}
+}
+
+func TestExpenseUsecase_Submit_AgainstCashAdvance(t *testing.T) {
+	tests := []struct {
+		name       string
+		advance    *domain.CashAdvance
+		amountIDR  int
+		settled    int
+		wantErr    bool
+		wantStatus string
+		wantJobAmt int
+		wantNoJob  bool
+	}{
+		{
+			name:       "Partially covered expense pays only the difference",
+			advance:    &domain.CashAdvance{ID: 3, UserID: 1, Status: domain.AdvanceStatusPaid, OutstandingIDR: 200000},
+			amountIDR:  500000,
+			settled:    200000,
+			wantStatus: domain.StatusApproved,
+			wantJobAmt: 300000,
+		},
+		{
+			name:       "Fully covered expense completes without payment",
+			advance:    &domain.CashAdvance{ID: 3, UserID: 1, Status: domain.AdvanceStatusPaid, OutstandingIDR: 800000},
+			amountIDR:  500000,
+			settled:    500000,
+			wantStatus: domain.StatusCompleted,
+			wantNoJob:  true,
+		},
+		{
+			name:      "Cannot use another employee's advance",
+			advance:   &domain.CashAdvance{ID: 3, UserID: 2, Status: domain.AdvanceStatusPaid, OutstandingIDR: 800000},
+			amountIDR: 500000,
+			wantErr:   true,
+		},
+		{
+			name:      "Cannot use an advance that has not been paid",
+			advance:   &domain.CashAdvance{ID: 3, UserID: 1, Status: domain.AdvanceStatusApproved},
+			amountIDR: 500000,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paymentChan := make(chan PaymentJob, 1)
+
+			advanceRepo := &mockCashAdvanceRepo{
+				getByIDFunc: func(ctx context.Context, id int) (*domain.CashAdvance, error) {
+					return tt.advance, nil
+				},
+				applySettlementFunc: func(ctx context.Context, id int, expenseID int, amountIDR int) (int, error) {
+					return tt.settled, nil
+				},
+			}
+
+			var settleAudit *domain.AuditLog
+			auditRepo := &mockAuditRepo{
+				createFunc: func(ctx context.Context, log *domain.AuditLog) error {
+					if log.Action == domain.ActionSettle {
+						settleAudit = log
+					}
+					return nil
+				},
+			}
+
+			uc := NewExpenseUsecase(&mockExpenseRepo{}, &mockApprovalRepo{}, auditRepo, &mockUserRepo{}, advanceRepo, paymentChan, nil, nil, nil, nil, nil, nil)
+
+			advanceID := tt.advance.ID
+			expense, err := uc.Submit(context.Background(), 1, tt.amountIDR, "Hotel in Surabaya", domain.CategoryLodging, nil, nil, &advanceID)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Submit() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if expense.Status != tt.wantStatus {
+				t.Errorf("Submit() status = %v, want %v", expense.Status, tt.wantStatus)
+			}
+
+			if expense.AdvanceSettledIDR != tt.settled {
+				t.Errorf("AdvanceSettledIDR = %d, want %d", expense.AdvanceSettledIDR, tt.settled)
+			}
+
+			select {
+			case job := <-paymentChan:
+				if tt.wantNoJob {
+					t.Errorf("Fully settled expense should not be paid, got job %+v", job)
+				}
+				if job.Amount != tt.wantJobAmt {
+					t.Errorf("Payment job amount = %d, want %d", job.Amount, tt.wantJobAmt)
+				}
+			default:
+				if !tt.wantNoJob {
+					t.Error("Expected payment job for the net amount")
+				}
+			}
+
+			if tt.wantNoJob && settleAudit == nil {
+				t.Error("Expected settlement audit entry")
+			}
+		})
+	}
+}
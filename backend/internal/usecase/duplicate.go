@@ -0,0 +1,75 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// duplicateWindow is how far back an expense of the same amount by the same
+// employee is compared with a new one.
+const duplicateWindow = 30 * 24 * time.Hour
+
+// minDescriptionSimilarity is the share of description words two expenses
+// must have in common to look like the same claim.
+const minDescriptionSimilarity = 0.5
+
+// findDuplicate returns the earliest expense a new one likely repeats, or
+// nil: first one whose receipt has the same content, whoever submitted it,
+// else one of the employee's own with the same amount and a similar
+// description from the last duplicateWindow. Rejected expenses never count.
+func (u *expenseUsecase) findDuplicate(ctx context.Context, userID, amountIDR int, description string, receiptSHA256 *string) (*int, error) {
+	since := time.Now().Add(-duplicateWindow)
+	candidates, err := u.expenseRepo.FindDuplicateCandidates(ctx, userID, amountIDR, since, receiptSHA256)
+	if err != nil {
+		return nil, err
+	}
+
+	if receiptSHA256 != nil {
+		for _, candidate := range candidates {
+			if candidate.ReceiptSHA256 != nil && *candidate.ReceiptSHA256 == *receiptSHA256 {
+				return &candidate.Expense.ID, nil
+			}
+		}
+	}
+
+	for _, candidate := range candidates {
+		expense := candidate.Expense
+		if expense.UserID == userID && expense.AmountIDR == amountIDR && !expense.SubmittedAt.Before(since) &&
+			descriptionSimilarity(expense.Description, description) >= minDescriptionSimilarity {
+			return &expense.ID, nil
+		}
+	}
+	return nil, nil
+}
+
+// descriptionSimilarity is the Jaccard index of the words of two
+// descriptions, ignoring case, punctuation and words shorter than three
+// letters.
+func descriptionSimilarity(a, b string) float64 {
+	wordsA, wordsB := descriptionWords(a), descriptionWords(b)
+	if len(wordsA) == 0 || len(wordsB) == 0 {
+		return 0
+	}
+
+	shared := 0
+	for word := range wordsA {
+		if wordsB[word] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(wordsA)+len(wordsB)-shared)
+}
+
+func descriptionWords(description string) map[string]bool {
+	words := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(description), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len([]rune(word)) >= 3 {
+			words[word] = true
+		}
+	}
+	return words
+}
@@ -0,0 +1,188 @@
+package usecase
+
+import (
+	"context"
+	"expense-management-system/internal/domain"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDescriptionSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b    string
+		similar bool
+	}{
+		{"Taxi to airport", "taxi to the airport", true},
+		{"Team lunch at Warteg Bahari", "Team lunch - Warteg Bahari!", true},
+		{"Hotel in Surabaya", "Hotel in Jakarta", false},
+		{"Team lunch", "Client dinner", false},
+		{"", "Taxi", false},
+		{"a b", "a b", false},
+	}
+
+	for _, tt := range tests {
+		got := descriptionSimilarity(tt.a, tt.b) >= minDescriptionSimilarity
+		if got != tt.similar {
+			t.Errorf("descriptionSimilarity(%q, %q) = %v, want similar %v", tt.a, tt.b, descriptionSimilarity(tt.a, tt.b), tt.similar)
+		}
+	}
+}
+
+func TestSubmitFlagsPossibleDuplicates(t *testing.T) {
+	now := time.Now()
+	receiptContent := pngHeader + "receipt from the warung"
+
+	earlier := func(id, userID int, description string, hash *string) *domain.DuplicateCandidate {
+		return &domain.DuplicateCandidate{
+			Expense: &domain.Expense{
+				ID:          id,
+				UserID:      userID,
+				AmountIDR:   250000,
+				Description: description,
+				Status:      domain.StatusCompleted,
+				SubmittedAt: now.Add(-48 * time.Hour),
+			},
+			ReceiptSHA256: hash,
+		}
+	}
+
+	tests := []struct {
+		name        string
+		withReceipt bool
+		candidates  func(hash string) []*domain.DuplicateCandidate
+		wantDupOf   *int
+	}{
+		{
+			name: "No earlier expenses is auto-approved",
+		},
+		{
+			name: "Same amount and similar description is flagged",
+			candidates: func(string) []*domain.DuplicateCandidate {
+				return []*domain.DuplicateCandidate{earlier(3, 1, "Taxi to the airport", nil)}
+			},
+			wantDupOf: intPtr(3),
+		},
+		{
+			name: "Same amount with a different description is auto-approved",
+			candidates: func(string) []*domain.DuplicateCandidate {
+				return []*domain.DuplicateCandidate{earlier(3, 1, "Printer paper", nil)}
+			},
+		},
+		{
+			name:        "Identical receipt is flagged whoever submitted it",
+			withReceipt: true,
+			candidates: func(hash string) []*domain.DuplicateCandidate {
+				return []*domain.DuplicateCandidate{earlier(5, 2, "Printer paper", &hash)}
+			},
+			wantDupOf: intPtr(5),
+		},
+		{
+			name:        "Identical receipt wins over an earlier similar description",
+			withReceipt: true,
+			candidates: func(hash string) []*domain.DuplicateCandidate {
+				return []*domain.DuplicateCandidate{
+					earlier(3, 1, "Taxi to the airport", nil),
+					earlier(9, 2, "Printer paper", &hash),
+				}
+			},
+			wantDupOf: intPtr(9),
+		},
+		{
+			name:        "Different receipt with a different description is auto-approved",
+			withReceipt: true,
+			candidates: func(string) []*domain.DuplicateCandidate {
+				other := strings.Repeat("0", 64)
+				return []*domain.DuplicateCandidate{earlier(5, 2, "Printer paper", &other)}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			receipts, _ := newTestReceiptUsecase()
+
+			var receiptID *int
+			var hash string
+			if tt.withReceipt {
+				receipt, err := receipts.Upload(ctx, 1, strings.NewReader(receiptContent))
+				if err != nil {
+					t.Fatalf("Upload() error = %v", err)
+				}
+				receiptID = &receipt.ID
+				hash = receipt.SHA256
+			}
+
+			var gotHash *string
+			expenseRepo := &mockExpenseRepo{
+				duplicatesFunc: func(ctx context.Context, userID, amountIDR int, since time.Time, receiptSHA256 *string) ([]*domain.DuplicateCandidate, error) {
+					gotHash = receiptSHA256
+					if tt.candidates == nil {
+						return nil, nil
+					}
+					return tt.candidates(hash), nil
+				},
+			}
+			var submitAudit *domain.AuditLog
+			auditRepo := &mockAuditRepo{
+				createFunc: func(ctx context.Context, log *domain.AuditLog) error {
+					submitAudit = log
+					return nil
+				},
+			}
+			paymentChan := make(chan PaymentJob, 1)
+
+			uc := NewExpenseUsecase(expenseRepo, &mockApprovalRepo{}, auditRepo, &mockUserRepo{}, &mockCashAdvanceRepo{}, paymentChan, nil, nil, nil, receipts, nil, nil)
+
+			expense, err := uc.Submit(ctx, 1, 250000, "Taxi to airport", domain.CategoryTravel, nil, receiptID, nil)
+			if err != nil {
+				t.Fatalf("Submit() error = %v", err)
+			}
+
+			if tt.withReceipt {
+				if gotHash == nil || *gotHash != hash {
+					t.Errorf("duplicates looked up with receipt hash %v, want %s", gotHash, hash)
+				}
+				if expense.ReceiptURL == nil || *expense.ReceiptURL != "https://expenses.example.com/api/receipts/1/file" {
+					t.Errorf("ReceiptURL = %v, want the uploaded receipt", expense.ReceiptURL)
+				}
+			}
+
+			if tt.wantDupOf == nil {
+				if expense.PossibleDuplicateOf != nil {
+					t.Errorf("PossibleDuplicateOf = %d, want none", *expense.PossibleDuplicateOf)
+				}
+				if !expense.AutoApproved || len(paymentChan) != 1 {
+					t.Errorf("expense below the threshold should be auto-approved and paid")
+				}
+				return
+			}
+
+			if expense.PossibleDuplicateOf == nil || *expense.PossibleDuplicateOf != *tt.wantDupOf {
+				t.Fatalf("PossibleDuplicateOf = %v, want %d", expense.PossibleDuplicateOf, *tt.wantDupOf)
+			}
+			if expense.AutoApproved || expense.Status != domain.StatusAwaitingApproval || len(paymentChan) != 0 {
+				t.Errorf("flagged expense should wait for approval, got status %s", expense.Status)
+			}
+			if submitAudit == nil || submitAudit.Metadata["possible_duplicate_of"] != *tt.wantDupOf {
+				t.Errorf("submit audit metadata = %v, want possible_duplicate_of %d", submitAudit, *tt.wantDupOf)
+			}
+		})
+	}
+}
+
+func TestSubmitRefusesAnotherEmployeesReceipt(t *testing.T) {
+	ctx := context.Background()
+	receipts, _ := newTestReceiptUsecase()
+	receipt, err := receipts.Upload(ctx, 2, strings.NewReader(pngHeader+"someone else's receipt"))
+	if err != nil {
+		t.Fatalf("Upload() error = %v", err)
+	}
+
+	uc := NewExpenseUsecase(&mockExpenseRepo{}, &mockApprovalRepo{}, &mockAuditRepo{}, &mockUserRepo{}, &mockCashAdvanceRepo{}, make(chan PaymentJob, 1), nil, nil, nil, receipts, nil, nil)
+
+	if _, err := uc.Submit(ctx, 1, 250000, "Taxi to airport", domain.CategoryTravel, nil, &receipt.ID, nil); err == nil {
+		t.Error("Submit() with another employee's receipt should fail")
+	}
+}
@@ -6,23 +6,40 @@ import (
 	"expense-management-system/internal/domain"
 	"expense-management-system/pkg/logger"
 	"fmt"
+	"math"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// maxExpenseSearchLength bounds the free-text search of expense lists.
+const maxExpenseSearchLength = 200
+
 type expenseUsecase struct {
 	expenseRepo  domain.ExpenseRepository
 	approvalRepo domain.ApprovalRepository
 	auditRepo    domain.AuditLogRepository
 	userRepo     domain.UserRepository
+	advanceRepo  domain.CashAdvanceRepository
 	paymentChan  chan PaymentJob
+	paymentRuns  domain.PaymentRunUsecase
+	budgets      domain.BudgetUsecase
+	twoFactor    domain.TwoFactorUsecase
+	receipts     domain.ReceiptUsecase
+	policies     domain.PolicyUsecase
+	settings     domain.SettingsUsecase
 }
 
+// PaymentJob is either a single expense payment, a cash advance payment when
+// AdvanceID is set, or, when PayoutID is set, a batched payout covering
+// several expenses of one employee in a payment run.
 type PaymentJob struct {
-	ExpenseID  int
-	Amount     int
-	ExternalID string
+	ExpenseID    int
+	AdvanceID    int
+	Amount       int
+	ExternalID   string
+	PaymentRunID int
+	PayoutID     int
 }
 
 func NewExpenseUsecase(
@@ -30,41 +47,124 @@ func NewExpenseUsecase(
 	approvalRepo domain.ApprovalRepository,
 	auditRepo domain.AuditLogRepository,
 	userRepo domain.UserRepository,
+	advanceRepo domain.CashAdvanceRepository,
 	paymentChan chan PaymentJob,
+	paymentRuns domain.PaymentRunUsecase,
+	budgets domain.BudgetUsecase,
+	twoFactor domain.TwoFactorUsecase,
+	receipts domain.ReceiptUsecase,
+	policies domain.PolicyUsecase,
+	settings domain.SettingsUsecase,
 ) domain.ExpenseUsecase {
 	return &expenseUsecase{
 		expenseRepo:  expenseRepo,
 		approvalRepo: approvalRepo,
 		auditRepo:    auditRepo,
 		userRepo:     userRepo,
+		advanceRepo:  advanceRepo,
 		paymentChan:  paymentChan,
+		paymentRuns:  paymentRuns,
+		budgets:      budgets,
+		twoFactor:    twoFactor,
+		receipts:     receipts,
+		policies:     policies,
+		settings:     settings,
 	}
 }
 
-func (u *expenseUsecase) Submit(ctx context.Context, userID int, amountIDR int, description string, receiptURL *string) (*domain.Expense, error) {
-	if amountIDR < domain.MinExpenseAmount || amountIDR > domain.MaxExpenseAmount {
-		return nil, fmt.Errorf("amount must be between IDR %d and IDR %d", domain.MinExpenseAmount, domain.MaxExpenseAmount)
+func (u *expenseUsecase) Submit(ctx context.Context, userID int, amountIDR int, description string, category string, receiptURL *string, receiptID *int, cashAdvanceID *int) (*domain.Expense, error) {
+	limits, err := expenseLimits(ctx, u.settings)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAmount(limits, amountIDR); err != nil {
+		return nil, err
 	}
 
 	if description == "" {
 		return nil, errors.New("description is required")
 	}
 
+	if category == "" {
+		category = domain.CategoryGeneral
+	}
+	if !domain.IsValidCategory(category) {
+		return nil, fmt.Errorf("unknown category %q", category)
+	}
+
+	var receiptSHA256 *string
+	var extraction *domain.ReceiptExtraction
+	if receiptID != nil {
+		receipt, err := u.receipts.Get(ctx, userID, *receiptID, false)
+		if err != nil {
+			return nil, err
+		}
+		receiptURL = &receipt.URL
+		receiptSHA256 = &receipt.SHA256
+		extraction = receipt.Extraction
+	}
+	amountMismatch := receiptTotalDiffers(extraction, amountIDR)
+
+	if cashAdvanceID != nil {
+		advance, err := u.advanceRepo.GetByID(ctx, *cashAdvanceID)
+		if err != nil {
+			return nil, err
+		}
+		if advance.UserID != userID {
+			return nil, errors.New("cash advance belongs to another employee")
+		}
+		if advance.Status != domain.AdvanceStatusPaid || advance.OutstandingIDR <= 0 {
+			return nil, errors.New("cash advance has no outstanding balance to settle against")
+		}
+	}
+
+	policyViolations, policyApproval, err := u.checkPolicies(ctx, userID, &domain.Expense{
+		UserID:        userID,
+		AmountIDR:     amountIDR,
+		Description:   description,
+		Category:      category,
+		ReceiptURL:    receiptURL,
+		ReceiptID:     receiptID,
+		CashAdvanceID: cashAdvanceID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	budgetWarnings, budgetApproval, err := u.checkBudgets(ctx, userID, category, amountIDR)
+	if err != nil {
+		return nil, err
+	}
+
+	duplicateOf, err := u.findDuplicate(ctx, userID, amountIDR, description, receiptSHA256)
+	if err != nil {
+		return nil, err
+	}
+
 	externalID := uuid.New().String()
-	autoApproved := amountIDR < domain.ApprovalThreshold
+	autoApproved := amountIDR < limits.ApprovalThresholdIDR && !budgetApproval && !policyApproval && duplicateOf == nil && !amountMismatch
 	status := domain.StatusAwaitingApproval
 	if autoApproved {
 		status = domain.StatusApproved
 	}
 
 	expense := &domain.Expense{
-		UserID:            userID,
-		AmountIDR:         amountIDR,
-		Description:       description,
-		ReceiptURL:        receiptURL,
-		Status:            status,
-		AutoApproved:      autoApproved,
-		PaymentExternalID: &externalID,
+		UserID:              userID,
+		AmountIDR:           amountIDR,
+		Description:         description,
+		Category:            category,
+		ReceiptURL:          receiptURL,
+		ReceiptID:           receiptID,
+		Status:              status,
+		AutoApproved:        autoApproved,
+		PaymentExternalID:   &externalID,
+		CashAdvanceID:       cashAdvanceID,
+		PossibleDuplicateOf: duplicateOf,
+		BudgetWarnings:      budgetWarnings,
+		PolicyViolations:    policyViolations,
+
+		ReceiptExtraction:     extraction,
+		ReceiptAmountMismatch: amountMismatch,
 	}
 
 	if err := u.expenseRepo.Create(ctx, expense); err != nil {
@@ -77,22 +177,61 @@ func (u *expenseUsecase) Submit(ctx context.Context, userID int, amountIDR int,
 		Action:    domain.ActionSubmit,
 		NewStatus: &status,
 		Metadata: map[string]interface{}{
-			"amount_idr":    amountIDR,
-			"auto_approved": autoApproved,
+			"amount_idr":             amountIDR,
+			"category":               category,
+			"auto_approved":          autoApproved,
+			"approval_threshold_idr": limits.ApprovalThresholdIDR,
 		},
 	}
+	if cashAdvanceID != nil {
+		auditLog.Metadata["cash_advance_id"] = *cashAdvanceID
+	}
+	if len(budgetWarnings) > 0 {
+		auditLog.Metadata["budget_warnings"] = budgetWarnings
+	}
+	if budgetApproval {
+		auditLog.Metadata["budget_approval_required"] = true
+	}
+	if receiptID != nil {
+		auditLog.Metadata["receipt_id"] = *receiptID
+	}
+	if duplicateOf != nil {
+		auditLog.Metadata["possible_duplicate_of"] = *duplicateOf
+	}
+	if len(policyViolations) > 0 {
+		auditLog.Metadata["policy_violations"] = policyViolations
+	}
+	if amountMismatch {
+		auditLog.Metadata["receipt_total"] = *extraction.Total
+		auditLog.Metadata["receipt_amount_mismatch"] = true
+	}
 	u.auditRepo.Create(ctx, auditLog)
 
+	if amountMismatch {
+		logger.InfoLogger.Printf("Expense %d claims IDR %d but its receipt reads %.2f", expense.ID, amountIDR, *extraction.Total)
+	}
+
 	if autoApproved {
-		logger.InfoLogger.Printf("Auto-approved expense %d, sending to payment queue", expense.ID)
-		u.sendToPaymentQueue(expense.ID, amountIDR, externalID)
+		logger.InfoLogger.Printf("Auto-approved expense %d, dispatching payment", expense.ID)
+		u.dispatchPayment(ctx, expense)
 
 		user, _ := u.userRepo.GetByID(ctx, userID)
 		if user != nil {
 			logger.InfoLogger.Printf("[EMAIL] Auto-approval notification sent to %s for expense %d (IDR %d)", user.Email, expense.ID, amountIDR)
 		}
 	} else {
-		logger.InfoLogger.Printf("Expense %d requires manager approval (amount: IDR %d >= threshold)", expense.ID, amountIDR)
+		reason := fmt.Sprintf("amount: IDR %d >= threshold", amountIDR)
+		switch {
+		case duplicateOf != nil:
+			reason = fmt.Sprintf("possible duplicate of expense %d", *duplicateOf)
+		case policyApproval:
+			reason = "required by policy rule"
+		case budgetApproval && amountIDR < limits.ApprovalThresholdIDR:
+			reason = "budget hard limit exceeded"
+		case amountMismatch && amountIDR < limits.ApprovalThresholdIDR:
+			reason = "receipt total differs from the amount claimed"
+		}
+		logger.InfoLogger.Printf("Expense %d requires manager approval (%s)", expense.ID, reason)
 
 		user, _ := u.userRepo.GetByID(ctx, userID)
 		if user != nil {
@@ -103,19 +242,20 @@ func (u *expenseUsecase) Submit(ctx context.Context, userID int, amountIDR int,
 	return expense, nil
 }
 
-func (u *expenseUsecase) GetByID(ctx context.Context, userID int, expenseID int, isManager bool) (*domain.Expense, error) {
+func (u *expenseUsecase) GetByID(ctx context.Context, userID int, expenseID int, canViewAll bool) (*domain.Expense, error) {
 	expense, err := u.expenseRepo.GetByID(ctx, expenseID)
 	if err != nil {
 		return nil, err
 	}
 
-	if !isManager && expense.UserID != userID {
+	if !canViewAll && expense.UserID != userID {
 		return nil, errors.New("unauthorized access to expense")
 	}
 
 	// Populate approval data if expense has been approved/rejected/completed
-	// Completed expenses were previously approved and then payment processed
-	if expense.Status == domain.StatusApproved || expense.Status == domain.StatusRejected || expense.Status == domain.StatusCompleted {
+	// Completed (and later refunded) expenses were previously approved and then payment processed
+	switch expense.Status {
+	case domain.StatusApproved, domain.StatusRejected, domain.StatusCompleted, domain.StatusPartiallyRefunded, domain.StatusRefunded:
 		approval, err := u.approvalRepo.GetByExpenseID(ctx, expenseID)
 		if err == nil {
 			expense.Approval = approval
@@ -125,23 +265,63 @@ func (u *expenseUsecase) GetByID(ctx context.Context, userID int, expenseID int,
 	return expense, nil
 }
 
-func (u *expenseUsecase) GetUserExpenses(ctx context.Context, userID int, status string, page, limit int, isManager bool) ([]*domain.Expense, int, error) {
-	if page < 1 {
-		page = 1
+func (u *expenseUsecase) GetUserExpenses(ctx context.Context, userID int, filter domain.ExpenseFilter, page domain.PageRequest, canViewAll bool) (*domain.ExpensePage, error) {
+	if page.Page < 1 {
+		page.Page = 1
 	}
-	if limit < 1 || limit > 100 {
-		limit = 20
+	if page.Limit < 1 || page.Limit > 100 {
+		page.Limit = 20
 	}
 
-	offset := (page - 1) * limit
-
-	// Managers can see all expenses
-	if isManager {
-		return u.expenseRepo.GetAll(ctx, status, limit, offset)
+	if err := validateExpenseFilter(filter); err != nil {
+		return nil, err
 	}
 
 	// Regular users see only their own expenses
-	return u.expenseRepo.GetByUserID(ctx, userID, status, limit, offset)
+	if !canViewAll {
+		filter.SubmitterID = &userID
+	}
+
+	return u.expenseRepo.Search(ctx, filter, page)
+}
+
+func (u *expenseUsecase) ExportExpenses(ctx context.Context, userID int, filter domain.ExpenseFilter, canViewAll bool, fn func(*domain.ExpenseExportRow) error) error {
+	if err := validateExpenseFilter(filter); err != nil {
+		return err
+	}
+
+	if !canViewAll {
+		filter.SubmitterID = &userID
+	}
+
+	return u.expenseRepo.Export(ctx, filter, fn)
+}
+
+func validateExpenseFilter(filter domain.ExpenseFilter) error {
+	for _, status := range filter.Statuses {
+		if !domain.IsValidStatus(status) {
+			return fmt.Errorf("%w: unknown status %q", domain.ErrInvalidFilter, status)
+		}
+	}
+	if filter.SortBy != "" && !domain.IsValidExpenseSort(filter.SortBy) {
+		return fmt.Errorf("%w: cannot sort by %q", domain.ErrInvalidFilter, filter.SortBy)
+	}
+	if filter.SortOrder != "" && filter.SortOrder != domain.SortAsc && filter.SortOrder != domain.SortDesc {
+		return fmt.Errorf("%w: order must be asc or desc", domain.ErrInvalidFilter)
+	}
+	if filter.MinAmountIDR != nil && filter.MaxAmountIDR != nil && *filter.MinAmountIDR > *filter.MaxAmountIDR {
+		return fmt.Errorf("%w: min_amount is above max_amount", domain.ErrInvalidFilter)
+	}
+	if filter.SubmittedFrom != nil && filter.SubmittedTo != nil && !filter.SubmittedFrom.Before(*filter.SubmittedTo) {
+		return fmt.Errorf("%w: submitted_from must be before submitted_to", domain.ErrInvalidFilter)
+	}
+	if filter.ProcessedFrom != nil && filter.ProcessedTo != nil && !filter.ProcessedFrom.Before(*filter.ProcessedTo) {
+		return fmt.Errorf("%w: processed_from must be before processed_to", domain.ErrInvalidFilter)
+	}
+	if len(filter.Search) > maxExpenseSearchLength {
+		return fmt.Errorf("%w: search is longer than %d characters", domain.ErrInvalidFilter, maxExpenseSearchLength)
+	}
+	return nil
 }
 
 func (u *expenseUsecase) GetPendingApprovals(ctx context.Context, page, limit int) ([]*domain.Expense, int, error) {
@@ -156,7 +336,7 @@ func (u *expenseUsecase) GetPendingApprovals(ctx context.Context, page, limit in
 	return u.expenseRepo.GetPendingApprovals(ctx, limit, offset)
 }
 
-func (u *expenseUsecase) Approve(ctx context.Context, managerID, expenseID int, notes *string) error {
+func (u *expenseUsecase) Approve(ctx context.Context, managerID, expenseID int, notes *string, totpCode string) error {
 	expense, err := u.expenseRepo.GetByID(ctx, expenseID)
 	if err != nil {
 		return err
@@ -166,6 +346,18 @@ func (u *expenseUsecase) Approve(ctx context.Context, managerID, expenseID int,
 		return errors.New("expense is not awaiting approval")
 	}
 
+	// Large amounts need a fresh second factor, not just a session.
+	if u.twoFactor != nil {
+		if err := u.twoFactor.VerifyStepUp(ctx, managerID, expense.AmountIDR, totpCode); err != nil {
+			return err
+		}
+	}
+
+	// Budgets may have filled up since submission; blocking limits still apply.
+	if _, _, err := u.checkBudgets(ctx, expense.UserID, expense.Category, expense.AmountIDR); err != nil {
+		return err
+	}
+
 	approval := &domain.Approval{
 		ExpenseID:  expenseID,
 		ApproverID: managerID,
@@ -196,8 +388,9 @@ func (u *expenseUsecase) Approve(ctx context.Context, managerID, expenseID int,
 	}
 	u.auditRepo.Create(ctx, auditLog)
 
-	logger.InfoLogger.Printf("Expense %d approved by manager %d, sending to payment queue", expenseID, managerID)
-	u.sendToPaymentQueue(expenseID, expense.AmountIDR, *expense.PaymentExternalID)
+	logger.InfoLogger.Printf("Expense %d approved by manager %d, dispatching payment", expenseID, managerID)
+	expense.Status = newStatus
+	u.dispatchPayment(ctx, expense)
 
 	user, _ := u.userRepo.GetByID(ctx, expense.UserID)
 	if user != nil {
@@ -257,6 +450,158 @@ func (u *expenseUsecase) Reject(ctx context.Context, managerID, expenseID int, n
 	return nil
 }
 
+// checkBudgets evaluates the budgets covering a new expense. Soft-limit
+// breaches come back as warnings; a hard-limit breach either forces manager
+// approval or, for blocking budgets, fails with an error.
+func (u *expenseUsecase) checkBudgets(ctx context.Context, userID int, category string, amountIDR int) ([]string, bool, error) {
+	if u.budgets == nil {
+		return nil, false, nil
+	}
+
+	statuses, err := u.budgets.Evaluate(ctx, userID, category, amountIDR)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var warnings []string
+	requireApproval := false
+	for _, status := range statuses {
+		budget := status.Budget
+		switch {
+		case status.HardLimitExceeded && budget.HardLimitAction == domain.BudgetActionBlock:
+			return nil, false, fmt.Errorf("expense would exceed the %s (IDR %d of IDR %d)",
+				describeBudget(budget), status.ConsumedIDR, *budget.HardLimitIDR)
+		case status.HardLimitExceeded:
+			requireApproval = true
+			warnings = append(warnings, fmt.Sprintf("Exceeds the %s hard limit (IDR %d of IDR %d); manager approval required",
+				describeBudget(budget), status.ConsumedIDR, *budget.HardLimitIDR))
+		case status.SoftLimitExceeded:
+			warnings = append(warnings, fmt.Sprintf("Exceeds the %s soft limit (IDR %d of IDR %d)",
+				describeBudget(budget), status.ConsumedIDR, *budget.SoftLimitIDR))
+		}
+	}
+
+	return warnings, requireApproval, nil
+}
+
+// checkPolicies returns the policy rules an expense matches that let it
+// through, and whether one of them sends it to a manager. Rules that block
+// it fail the submission, which is audited against the submitter.
+func (u *expenseUsecase) checkPolicies(ctx context.Context, userID int, expense *domain.Expense) ([]domain.PolicyViolation, bool, error) {
+	if u.policies == nil {
+		return nil, false, nil
+	}
+
+	violations, err := u.policies.Evaluate(ctx, userID, expense)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var allowed, blocking []domain.PolicyViolation
+	requireApproval := false
+	for _, violation := range violations {
+		switch violation.Action {
+		case domain.PolicyActionBlock:
+			blocking = append(blocking, violation)
+		case domain.PolicyActionRequireApproval:
+			requireApproval = true
+			allowed = append(allowed, violation)
+		default:
+			allowed = append(allowed, violation)
+		}
+	}
+
+	if len(blocking) > 0 {
+		u.auditRepo.Create(ctx, &domain.AuditLog{
+			SubjectUserID: &userID,
+			UserID:        &userID,
+			Action:        domain.ActionSubmitBlocked,
+			Metadata: map[string]interface{}{
+				"amount_idr":        expense.AmountIDR,
+				"category":          expense.Category,
+				"policy_violations": blocking,
+			},
+		})
+		logger.InfoLogger.Printf("Expense submission by user %d blocked by %d policy rule(s)", userID, len(blocking))
+		return nil, false, &domain.PolicyViolationError{Violations: blocking}
+	}
+
+	return allowed, requireApproval, nil
+}
+
+// receiptTotalDiffers reports whether the total read off the receipt is not
+// the amount claimed. Totals in other currencies cannot be compared and
+// count as matching.
+func receiptTotalDiffers(extraction *domain.ReceiptExtraction, amountIDR int) bool {
+	if extraction == nil || extraction.Total == nil {
+		return false
+	}
+	if extraction.Currency != nil && *extraction.Currency != domain.CurrencyIDR {
+		return false
+	}
+	return math.Round(*extraction.Total) != float64(amountIDR)
+}
+
+// dispatchPayment pays an approved expense immediately through the worker
+// pool, or parks it in the current payment run when batched payouts are on.
+// Expenses raised against a cash advance are first settled from its
+// outstanding balance and only the difference is paid.
+func (u *expenseUsecase) dispatchPayment(ctx context.Context, expense *domain.Expense) {
+	if expense.CashAdvanceID != nil {
+		settled, err := u.advanceRepo.ApplySettlement(ctx, *expense.CashAdvanceID, expense.ID, expense.AmountIDR)
+		if err != nil {
+			logger.ErrorLogger.Printf("Failed to settle expense %d against cash advance %d: %v", expense.ID, *expense.CashAdvanceID, err)
+			return
+		}
+		expense.AdvanceSettledIDR = settled
+
+		if settled > 0 {
+			logger.InfoLogger.Printf("Expense %d settled IDR %d against cash advance %d", expense.ID, settled, *expense.CashAdvanceID)
+		}
+
+		if settled == expense.AmountIDR {
+			u.completeFromAdvance(ctx, expense)
+			return
+		}
+	}
+
+	if u.paymentRuns != nil {
+		if err := u.paymentRuns.AddExpense(ctx, expense); err != nil {
+			logger.ErrorLogger.Printf("Failed to add expense %d to payment run: %v", expense.ID, err)
+		}
+		return
+	}
+
+	u.sendToPaymentQueue(expense.ID, expense.AmountIDR-expense.AdvanceSettledIDR, *expense.PaymentExternalID)
+}
+
+// completeFromAdvance closes an expense that was fully covered by a cash
+// advance; nothing is owed to the employee so no payment is made.
+func (u *expenseUsecase) completeFromAdvance(ctx context.Context, expense *domain.Expense) {
+	now := time.Now().Format(time.RFC3339)
+	if err := u.expenseRepo.UpdateStatus(ctx, expense.ID, domain.StatusCompleted, &now); err != nil {
+		logger.ErrorLogger.Printf("Failed to complete expense %d settled by cash advance: %v", expense.ID, err)
+		return
+	}
+
+	oldStatus := expense.Status
+	newStatus := domain.StatusCompleted
+	auditLog := &domain.AuditLog{
+		ExpenseID:     expense.ID,
+		CashAdvanceID: expense.CashAdvanceID,
+		Action:        domain.ActionSettle,
+		OldStatus:     &oldStatus,
+		NewStatus:     &newStatus,
+		Metadata: map[string]interface{}{
+			"settled_idr": expense.AdvanceSettledIDR,
+		},
+	}
+	u.auditRepo.Create(ctx, auditLog)
+
+	expense.Status = newStatus
+	logger.InfoLogger.Printf("Expense %d fully settled against cash advance %d, no payment needed", expense.ID, *expense.CashAdvanceID)
+}
+
 func (u *expenseUsecase) sendToPaymentQueue(expenseID, amount int, externalID string) {
 	select {
 	case u.paymentChan <- PaymentJob{
@@ -2,9 +2,11 @@ package usecase
 
 import (
 	"context"
+	"errors"
 	"expense-management-system/internal/domain"
 	"expense-management-system/pkg/logger"
 	"testing"
+	"time"
 )
 
 func init() {
@@ -17,9 +19,13 @@ type mockExpenseRepo struct {
 	createFunc          func(ctx context.Context, expense *domain.Expense) error
 	getByIDFunc         func(ctx context.Context, id int) (*domain.Expense, error)
 	updateFunc          func(ctx context.Context, expense *domain.Expense) error
-	getByUserIDFunc     func(ctx context.Context, userID int, status string, limit, offset int) ([]*domain.Expense, int, error)
-	getAllFunc          func(ctx context.Context, status string, limit, offset int) ([]*domain.Expense, int, error)
+	searchFunc          func(ctx context.Context, filter domain.ExpenseFilter, page domain.PageRequest) (*domain.ExpensePage, error)
+	exportFunc          func(ctx context.Context, filter domain.ExpenseFilter, fn func(*domain.ExpenseExportRow) error) error
 	getPendingApprovals func(ctx context.Context, limit, offset int) ([]*domain.Expense, int, error)
+	assignPaymentRunFn  func(ctx context.Context, id int, paymentRunID int) error
+	getByPaymentRunFn   func(ctx context.Context, paymentRunID int) ([]*domain.Expense, error)
+	updateStatusFunc    func(ctx context.Context, id int, status string, processedAt *string) error
+	duplicatesFunc      func(ctx context.Context, userID, amountIDR int, since time.Time, receiptSHA256 *string) ([]*domain.DuplicateCandidate, error)
 }
 
 func (m *mockExpenseRepo) Create(ctx context.Context, expense *domain.Expense) error {
@@ -45,6 +51,9 @@ func (m *mockExpenseRepo) Update(ctx context.Context, expense *domain.Expense) e
 }
 
 func (m *mockExpenseRepo) UpdateStatus(ctx context.Context, id int, status string, processedAt *string) error {
+	if m.updateStatusFunc != nil {
+		return m.updateStatusFunc(ctx, id, status, processedAt)
+	}
 	return nil
 }
 
@@ -52,18 +61,18 @@ func (m *mockExpenseRepo) UpdatePaymentInfo(ctx context.Context, id int, payment
 	return nil
 }
 
-func (m *mockExpenseRepo) GetByUserID(ctx context.Context, userID int, status string, limit, offset int) ([]*domain.Expense, int, error) {
-	if m.getByUserIDFunc != nil {
-		return m.getByUserIDFunc(ctx, userID, status, limit, offset)
+func (m *mockExpenseRepo) Search(ctx context.Context, filter domain.ExpenseFilter, page domain.PageRequest) (*domain.ExpensePage, error) {
+	if m.searchFunc != nil {
+		return m.searchFunc(ctx, filter, page)
 	}
-	return nil, 0, nil
+	return &domain.ExpensePage{}, nil
 }
 
-func (m *mockExpenseRepo) GetAll(ctx context.Context, status string, limit, offset int) ([]*domain.Expense, int, error) {
-	if m.getAllFunc != nil {
-		return m.getAllFunc(ctx, status, limit, offset)
+func (m *mockExpenseRepo) Export(ctx context.Context, filter domain.ExpenseFilter, fn func(*domain.ExpenseExportRow) error) error {
+	if m.exportFunc != nil {
+		return m.exportFunc(ctx, filter, fn)
 	}
-	return nil, 0, nil
+	return nil
 }
 
 func (m *mockExpenseRepo) GetPendingApprovals(ctx context.Context, limit, offset int) ([]*domain.Expense, int, error) {
@@ -73,6 +82,27 @@ func (m *mockExpenseRepo) GetPendingApprovals(ctx context.Context, limit, offset
 	return nil, 0, nil
 }
 
+func (m *mockExpenseRepo) AssignPaymentRun(ctx context.Context, id int, paymentRunID int) error {
+	if m.assignPaymentRunFn != nil {
+		return m.assignPaymentRunFn(ctx, id, paymentRunID)
+	}
+	return nil
+}
+
+func (m *mockExpenseRepo) GetByPaymentRunID(ctx context.Context, paymentRunID int) ([]*domain.Expense, error) {
+	if m.getByPaymentRunFn != nil {
+		return m.getByPaymentRunFn(ctx, paymentRunID)
+	}
+	return nil, nil
+}
+
+func (m *mockExpenseRepo) FindDuplicateCandidates(ctx context.Context, userID, amountIDR int, since time.Time, receiptSHA256 *string) ([]*domain.DuplicateCandidate, error) {
+	if m.duplicatesFunc != nil {
+		return m.duplicatesFunc(ctx, userID, amountIDR, since, receiptSHA256)
+	}
+	return nil, nil
+}
+
 type mockApprovalRepo struct {
 	getByExpenseIDFunc func(ctx context.Context, expenseID int) (*domain.Approval, error)
 }
@@ -105,16 +135,29 @@ func (m *mockAuditRepo) GetByExpenseID(ctx context.Context, expenseID int) ([]*d
 	return nil, nil
 }
 
+func (m *mockAuditRepo) GetBySubjectUserID(ctx context.Context, userID int, page domain.PageRequest) (*domain.AuditLogPage, error) {
+	return &domain.AuditLogPage{}, nil
+}
+
 type mockUserRepo struct {
 	getByIDFunc    func(ctx context.Context, id int) (*domain.User, error)
 	getByEmailFunc func(ctx context.Context, email string) (*domain.User, error)
+	createFunc     func(ctx context.Context, user *domain.User) error
+	updateFunc     func(ctx context.Context, user *domain.User) error
+	updateRoleFunc func(ctx context.Context, id int, role string) error
+	setActiveFunc  func(ctx context.Context, id int, active bool) error
+
+	updatePasswordFunc func(ctx context.Context, id int, passwordHash string) error
+
+	recordLoginFailureFunc func(ctx context.Context, id int, maxAttempts int, windowStart, lockUntil time.Time) (*domain.User, error)
+	resetLoginFailuresFunc func(ctx context.Context, id int) error
 }
 
 func (m *mockUserRepo) GetByID(ctx context.Context, id int) (*domain.User, error) {
 	if m.getByIDFunc != nil {
 		return m.getByIDFunc(ctx, id)
 	}
-	return &domain.User{ID: id, Email: "test@example.com"}, nil
+	return &domain.User{ID: id, Email: "test@example.com", Active: true}, nil
 }
 
 func (m *mockUserRepo) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
@@ -125,6 +168,55 @@ func (m *mockUserRepo) GetByEmail(ctx context.Context, email string) (*domain.Us
 }
 
 func (m *mockUserRepo) Create(ctx context.Context, user *domain.User) error {
+	if m.createFunc != nil {
+		return m.createFunc(ctx, user)
+	}
+	return nil
+}
+
+func (m *mockUserRepo) List(ctx context.Context, role string, includeInactive bool, limit, offset int) ([]*domain.User, int, error) {
+	return nil, 0, nil
+}
+
+func (m *mockUserRepo) Update(ctx context.Context, user *domain.User) error {
+	if m.updateFunc != nil {
+		return m.updateFunc(ctx, user)
+	}
+	return nil
+}
+
+func (m *mockUserRepo) UpdateRole(ctx context.Context, id int, role string) error {
+	if m.updateRoleFunc != nil {
+		return m.updateRoleFunc(ctx, id, role)
+	}
+	return nil
+}
+
+func (m *mockUserRepo) UpdatePassword(ctx context.Context, id int, passwordHash string) error {
+	if m.updatePasswordFunc != nil {
+		return m.updatePasswordFunc(ctx, id, passwordHash)
+	}
+	return nil
+}
+
+func (m *mockUserRepo) RecordLoginFailure(ctx context.Context, id int, maxAttempts int, windowStart, lockUntil time.Time) (*domain.User, error) {
+	if m.recordLoginFailureFunc != nil {
+		return m.recordLoginFailureFunc(ctx, id, maxAttempts, windowStart, lockUntil)
+	}
+	return &domain.User{ID: id, FailedLoginAttempts: 1}, nil
+}
+
+func (m *mockUserRepo) ResetLoginFailures(ctx context.Context, id int) error {
+	if m.resetLoginFailuresFunc != nil {
+		return m.resetLoginFailuresFunc(ctx, id)
+	}
+	return nil
+}
+
+func (m *mockUserRepo) SetActive(ctx context.Context, id int, active bool) error {
+	if m.setActiveFunc != nil {
+		return m.setActiveFunc(ctx, id, active)
+	}
 	return nil
 }
 
@@ -221,9 +313,9 @@ func TestExpenseUsecase_Submit(t *testing.T) {
 			auditRepo := &mockAuditRepo{}
 			userRepo := &mockUserRepo{}
 
-			uc := NewExpenseUsecase(expenseRepo, approvalRepo, auditRepo, userRepo, paymentChan)
+			uc := NewExpenseUsecase(expenseRepo, approvalRepo, auditRepo, userRepo, &mockCashAdvanceRepo{}, paymentChan, nil, nil, nil, nil, nil, nil)
 
-			expense, err := uc.Submit(ctx, tt.userID, tt.amountIDR, tt.description, tt.receiptURL)
+			expense, err := uc.Submit(ctx, tt.userID, tt.amountIDR, tt.description, "", tt.receiptURL, nil, nil)
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Submit() error = %v, wantErr %v", err, tt.wantErr)
@@ -332,9 +424,9 @@ func TestExpenseUsecase_Approve(t *testing.T) {
 				tt.setupMock(expenseRepo, approvalRepo, auditRepo)
 			}
 
-			uc := NewExpenseUsecase(expenseRepo, approvalRepo, auditRepo, userRepo, paymentChan)
+			uc := NewExpenseUsecase(expenseRepo, approvalRepo, auditRepo, userRepo, &mockCashAdvanceRepo{}, paymentChan, nil, nil, nil, nil, nil, nil)
 
-			err := uc.Approve(ctx, tt.approverID, tt.expenseID, strPtr(tt.notes))
+			err := uc.Approve(ctx, tt.approverID, tt.expenseID, strPtr(tt.notes), "")
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Approve() error = %v, wantErr %v", err, tt.wantErr)
@@ -356,6 +448,41 @@ func TestExpenseUsecase_Approve(t *testing.T) {
 	}
 }
 
+func TestExpenseUsecase_ApproveStepUp(t *testing.T) {
+	ctx := context.Background()
+	paymentChan := make(chan PaymentJob, 10)
+
+	expenseRepo := &mockExpenseRepo{
+		getByIDFunc: func(ctx context.Context, id int) (*domain.Expense, error) {
+			return &domain.Expense{
+				ID:                1,
+				UserID:            1,
+				AmountIDR:         15000000,
+				Status:            domain.StatusAwaitingApproval,
+				PaymentExternalID: strPtr("test-external-id"),
+			}, nil
+		},
+	}
+	twoFactor, twoFactorRepo := newTestTwoFactorUsecase()
+	enrolUser(t, twoFactor, twoFactorRepo, &domain.User{ID: 3, Email: "manager@example.com", Role: domain.RoleManager})
+
+	uc := NewExpenseUsecase(expenseRepo, &mockApprovalRepo{}, &mockAuditRepo{}, &mockUserRepo{}, &mockCashAdvanceRepo{}, paymentChan, nil, nil, twoFactor, nil, nil, nil)
+
+	if err := uc.Approve(ctx, 3, 1, nil, ""); !errors.Is(err, domain.ErrStepUpRequired) {
+		t.Errorf("Approve() without code error = %v, want ErrStepUpRequired", err)
+	}
+	if len(paymentChan) != 0 {
+		t.Fatal("Expected no payment before the step-up succeeds")
+	}
+
+	if err := uc.Approve(ctx, 3, 1, nil, currentCode(t, twoFactorRepo, 3, 0)); err != nil {
+		t.Fatalf("Approve() unexpected error = %v", err)
+	}
+	if len(paymentChan) != 1 {
+		t.Error("Expected payment job to be queued")
+	}
+}
+
 func TestExpenseUsecase_Reject(t *testing.T) {
 	ctx := context.Background()
 	paymentChan := make(chan PaymentJob, 10)
@@ -374,7 +501,7 @@ func TestExpenseUsecase_Reject(t *testing.T) {
 	auditRepo := &mockAuditRepo{}
 	userRepo := &mockUserRepo{}
 
-	uc := NewExpenseUsecase(expenseRepo, approvalRepo, auditRepo, userRepo, paymentChan)
+	uc := NewExpenseUsecase(expenseRepo, approvalRepo, auditRepo, userRepo, &mockCashAdvanceRepo{}, paymentChan, nil, nil, nil, nil, nil, nil)
 
 	err := uc.Reject(ctx, 3, 1, strPtr("Receipt not clear"))
 	if err != nil {
@@ -483,7 +610,7 @@ func TestExpenseUsecase_GetByID(t *testing.T) {
 				tt.setupMock(expenseRepo, approvalRepo)
 			}
 
-			uc := NewExpenseUsecase(expenseRepo, approvalRepo, auditRepo, userRepo, paymentChan)
+			uc := NewExpenseUsecase(expenseRepo, approvalRepo, auditRepo, userRepo, &mockCashAdvanceRepo{}, paymentChan, nil, nil, nil, nil, nil, nil)
 
 			expense, err := uc.GetByID(ctx, tt.userID, tt.expenseID, tt.isManager)
 
@@ -506,13 +633,21 @@ func TestExpenseUsecase_GetByID(t *testing.T) {
 	}
 }
 
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+func expensePage(expenses ...*domain.Expense) *domain.ExpensePage {
+	total := len(expenses)
+	return &domain.ExpensePage{Expenses: expenses, Total: &total}
+}
+
 func TestExpenseUsecase_GetUserExpenses(t *testing.T) {
 	tests := []struct {
 		name      string
 		userID    int
-		status    string
-		page      int
-		limit     int
+		filter    domain.ExpenseFilter
+		page      domain.PageRequest
 		isManager bool
 		setupMock func(*mockExpenseRepo)
 		wantErr   bool
@@ -521,35 +656,55 @@ func TestExpenseUsecase_GetUserExpenses(t *testing.T) {
 		{
 			name:      "Employee gets own expenses",
 			userID:    1,
-			status:    "",
-			page:      1,
-			limit:     20,
+			page:      domain.PageRequest{Page: 1, Limit: 20},
 			isManager: false,
 			setupMock: func(repo *mockExpenseRepo) {
-				repo.getByUserIDFunc = func(ctx context.Context, userID int, status string, limit, offset int) ([]*domain.Expense, int, error) {
-					return []*domain.Expense{
-						{ID: 1, UserID: 1, AmountIDR: 500000},
-						{ID: 2, UserID: 1, AmountIDR: 750000},
-					}, 2, nil
+				repo.searchFunc = func(ctx context.Context, filter domain.ExpenseFilter, page domain.PageRequest) (*domain.ExpensePage, error) {
+					if filter.SubmitterID == nil || *filter.SubmitterID != 1 {
+						t.Errorf("Expected the search to be limited to user 1, got %v", filter.SubmitterID)
+					}
+					return expensePage(
+						&domain.Expense{ID: 1, UserID: 1, AmountIDR: 500000},
+						&domain.Expense{ID: 2, UserID: 1, AmountIDR: 750000},
+					), nil
 				}
 			},
 			wantErr:   false,
 			wantCount: 2,
 		},
+		{
+			name:      "Employee cannot search other submitters",
+			userID:    1,
+			filter:    domain.ExpenseFilter{SubmitterID: intPtr(2)},
+			page:      domain.PageRequest{Page: 1, Limit: 20},
+			isManager: false,
+			setupMock: func(repo *mockExpenseRepo) {
+				repo.searchFunc = func(ctx context.Context, filter domain.ExpenseFilter, page domain.PageRequest) (*domain.ExpensePage, error) {
+					if *filter.SubmitterID != 1 {
+						t.Errorf("Expected the search to be limited to user 1, got %d", *filter.SubmitterID)
+					}
+					return expensePage(), nil
+				}
+			},
+			wantErr:   false,
+			wantCount: 0,
+		},
 		{
 			name:      "Manager gets all expenses",
 			userID:    3,
-			status:    domain.StatusApproved,
-			page:      1,
-			limit:     20,
+			filter:    domain.ExpenseFilter{Statuses: []string{domain.StatusApproved}},
+			page:      domain.PageRequest{Page: 1, Limit: 20},
 			isManager: true,
 			setupMock: func(repo *mockExpenseRepo) {
-				repo.getAllFunc = func(ctx context.Context, status string, limit, offset int) ([]*domain.Expense, int, error) {
-					return []*domain.Expense{
-						{ID: 1, UserID: 1, AmountIDR: 500000, Status: domain.StatusApproved},
-						{ID: 2, UserID: 2, AmountIDR: 750000, Status: domain.StatusApproved},
-						{ID: 3, UserID: 1, AmountIDR: 900000, Status: domain.StatusApproved},
-					}, 3, nil
+				repo.searchFunc = func(ctx context.Context, filter domain.ExpenseFilter, page domain.PageRequest) (*domain.ExpensePage, error) {
+					if filter.SubmitterID != nil {
+						t.Errorf("Expected no submitter filter, got %d", *filter.SubmitterID)
+					}
+					return expensePage(
+						&domain.Expense{ID: 1, UserID: 1, AmountIDR: 500000, Status: domain.StatusApproved},
+						&domain.Expense{ID: 2, UserID: 2, AmountIDR: 750000, Status: domain.StatusApproved},
+						&domain.Expense{ID: 3, UserID: 1, AmountIDR: 900000, Status: domain.StatusApproved},
+					), nil
 				}
 			},
 			wantErr:   false,
@@ -558,16 +713,14 @@ func TestExpenseUsecase_GetUserExpenses(t *testing.T) {
 		{
 			name:      "Pagination with page < 1 defaults to 1",
 			userID:    1,
-			status:    "",
-			page:      0,
-			limit:     20,
+			page:      domain.PageRequest{Page: 0, Limit: 20},
 			isManager: false,
 			setupMock: func(repo *mockExpenseRepo) {
-				repo.getByUserIDFunc = func(ctx context.Context, userID int, status string, limit, offset int) ([]*domain.Expense, int, error) {
-					if offset != 0 {
-						t.Error("Expected offset to be 0 for page 1")
+				repo.searchFunc = func(ctx context.Context, filter domain.ExpenseFilter, page domain.PageRequest) (*domain.ExpensePage, error) {
+					if page.Page != 1 {
+						t.Errorf("Expected page 1, got %d", page.Page)
 					}
-					return []*domain.Expense{{ID: 1}}, 1, nil
+					return expensePage(&domain.Expense{ID: 1}), nil
 				}
 			},
 			wantErr:   false,
@@ -576,21 +729,73 @@ func TestExpenseUsecase_GetUserExpenses(t *testing.T) {
 		{
 			name:      "Limit > 100 defaults to 20",
 			userID:    1,
-			status:    "",
-			page:      1,
-			limit:     150,
+			page:      domain.PageRequest{Page: 1, Limit: 150},
 			isManager: false,
 			setupMock: func(repo *mockExpenseRepo) {
-				repo.getByUserIDFunc = func(ctx context.Context, userID int, status string, limit, offset int) ([]*domain.Expense, int, error) {
-					if limit != 20 {
-						t.Errorf("Expected limit to be 20, got %d", limit)
+				repo.searchFunc = func(ctx context.Context, filter domain.ExpenseFilter, page domain.PageRequest) (*domain.ExpensePage, error) {
+					if page.Limit != 20 {
+						t.Errorf("Expected limit to be 20, got %d", page.Limit)
 					}
-					return []*domain.Expense{{ID: 1}}, 1, nil
+					return expensePage(&domain.Expense{ID: 1}), nil
 				}
 			},
 			wantErr:   false,
 			wantCount: 1,
 		},
+		{
+			name:      "Cursor is passed to the repository",
+			userID:    1,
+			page:      domain.PageRequest{Limit: 50, Cursor: "abc"},
+			isManager: false,
+			setupMock: func(repo *mockExpenseRepo) {
+				repo.searchFunc = func(ctx context.Context, filter domain.ExpenseFilter, page domain.PageRequest) (*domain.ExpensePage, error) {
+					if page.Cursor != "abc" || page.Limit != 50 || page.CountTotal {
+						t.Errorf("Expected the cursor page unchanged, got %+v", page)
+					}
+					return &domain.ExpensePage{Expenses: []*domain.Expense{{ID: 1}}, NextCursor: "def"}, nil
+				}
+			},
+			wantErr:   false,
+			wantCount: 1,
+		},
+		{
+			name:    "Unknown status",
+			userID:  1,
+			filter:  domain.ExpenseFilter{Statuses: []string{"paid"}},
+			page:    domain.PageRequest{Page: 1, Limit: 20},
+			wantErr: true,
+		},
+		{
+			name:    "Unknown sort field",
+			userID:  1,
+			filter:  domain.ExpenseFilter{SortBy: "description; DROP TABLE expenses"},
+			page:    domain.PageRequest{Page: 1, Limit: 20},
+			wantErr: true,
+		},
+		{
+			name:    "Unknown sort order",
+			userID:  1,
+			filter:  domain.ExpenseFilter{SortBy: domain.ExpenseSortAmount, SortOrder: "sideways"},
+			page:    domain.PageRequest{Page: 1, Limit: 20},
+			wantErr: true,
+		},
+		{
+			name:    "Inverted amount range",
+			userID:  1,
+			filter:  domain.ExpenseFilter{MinAmountIDR: intPtr(500000), MaxAmountIDR: intPtr(100000)},
+			page:    domain.PageRequest{Page: 1, Limit: 20},
+			wantErr: true,
+		},
+		{
+			name:   "Inverted date range",
+			userID: 1,
+			filter: domain.ExpenseFilter{
+				SubmittedFrom: timePtr(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)),
+				SubmittedTo:   timePtr(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
+			},
+			page:    domain.PageRequest{Page: 1, Limit: 20},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -607,25 +812,76 @@ func TestExpenseUsecase_GetUserExpenses(t *testing.T) {
 				tt.setupMock(expenseRepo)
 			}
 
-			uc := NewExpenseUsecase(expenseRepo, approvalRepo, auditRepo, userRepo, paymentChan)
+			uc := NewExpenseUsecase(expenseRepo, approvalRepo, auditRepo, userRepo, &mockCashAdvanceRepo{}, paymentChan, nil, nil, nil, nil, nil, nil)
 
-			expenses, count, err := uc.GetUserExpenses(ctx, tt.userID, tt.status, tt.page, tt.limit, tt.isManager)
+			result, err := uc.GetUserExpenses(ctx, tt.userID, tt.filter, tt.page, tt.isManager)
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GetUserExpenses() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
+			if tt.wantErr && !errors.Is(err, domain.ErrInvalidFilter) {
+				t.Errorf("GetUserExpenses() error = %v, want ErrInvalidFilter", err)
+			}
 
 			if !tt.wantErr {
-				if len(expenses) != tt.wantCount {
-					t.Errorf("GetUserExpenses() count = %v, want %v", len(expenses), tt.wantCount)
+				if len(result.Expenses) != tt.wantCount {
+					t.Errorf("GetUserExpenses() count = %v, want %v", len(result.Expenses), tt.wantCount)
 				}
+			}
+		})
+	}
+}
 
-				if count != tt.wantCount {
-					t.Errorf("GetUserExpenses() total = %v, want %v", count, tt.wantCount)
+func TestExpenseUsecase_ExportExpenses(t *testing.T) {
+	ctx := context.Background()
+	var gotFilter domain.ExpenseFilter
+	repo := &mockExpenseRepo{
+		exportFunc: func(ctx context.Context, filter domain.ExpenseFilter, fn func(*domain.ExpenseExportRow) error) error {
+			gotFilter = filter
+			for id := 1; id <= 3; id++ {
+				if err := fn(&domain.ExpenseExportRow{Expense: domain.Expense{ID: id}}); err != nil {
+					return err
 				}
 			}
-		})
+			return nil
+		},
+	}
+	uc := NewExpenseUsecase(repo, &mockApprovalRepo{}, &mockAuditRepo{}, &mockUserRepo{}, &mockCashAdvanceRepo{}, make(chan PaymentJob, 1), nil, nil, nil, nil, nil, nil)
+
+	var ids []int
+	collect := func(row *domain.ExpenseExportRow) error {
+		ids = append(ids, row.ID)
+		return nil
+	}
+
+	if err := uc.ExportExpenses(ctx, 1, domain.ExpenseFilter{SubmitterID: intPtr(2)}, false, collect); err != nil {
+		t.Fatalf("ExportExpenses() unexpected error = %v", err)
+	}
+	if gotFilter.SubmitterID == nil || *gotFilter.SubmitterID != 1 {
+		t.Errorf("Expected the export to be limited to user 1, got %v", gotFilter.SubmitterID)
+	}
+	if len(ids) != 3 {
+		t.Errorf("Expected 3 rows, got %v", ids)
+	}
+
+	if err := uc.ExportExpenses(ctx, 3, domain.ExpenseFilter{SubmitterID: intPtr(2)}, true, collect); err != nil {
+		t.Fatalf("ExportExpenses() unexpected error = %v", err)
+	}
+	if *gotFilter.SubmitterID != 2 {
+		t.Errorf("Expected the submitter filter to be kept, got %d", *gotFilter.SubmitterID)
+	}
+
+	stop := errors.New("client went away")
+	err := uc.ExportExpenses(ctx, 3, domain.ExpenseFilter{}, true, func(row *domain.ExpenseExportRow) error { return stop })
+	if !errors.Is(err, stop) {
+		t.Errorf("Expected the callback error, got %v", err)
+	}
+
+	gotFilter = domain.ExpenseFilter{}
+	err = uc.ExportExpenses(ctx, 3, domain.ExpenseFilter{Statuses: []string{"paid"}}, true, collect)
+	if !errors.Is(err, domain.ErrInvalidFilter) || gotFilter.Statuses != nil {
+		t.Errorf("Expected ErrInvalidFilter before querying, got %v", err)
 	}
 }
 
@@ -695,7 +951,7 @@ func TestExpenseUsecase_GetPendingApprovals(t *testing.T) {
 				tt.setupMock(expenseRepo)
 			}
 
-			uc := NewExpenseUsecase(expenseRepo, approvalRepo, auditRepo, userRepo, paymentChan)
+			uc := NewExpenseUsecase(expenseRepo, approvalRepo, auditRepo, userRepo, &mockCashAdvanceRepo{}, paymentChan, nil, nil, nil, nil, nil, nil)
 
 			expenses, count, err := uc.GetPendingApprovals(ctx, tt.page, tt.limit)
 			if err != nil {
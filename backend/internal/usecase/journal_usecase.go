@@ -0,0 +1,200 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"expense-management-system/internal/domain"
+	"expense-management-system/pkg/logger"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type journalUsecase struct {
+	journalRepo domain.JournalRepository
+	now         func() time.Time
+}
+
+func NewJournalUsecase(journalRepo domain.JournalRepository) domain.JournalUsecase {
+	return &journalUsecase{
+		journalRepo: journalRepo,
+		now:         time.Now,
+	}
+}
+
+func (u *journalUsecase) ListAccounts(ctx context.Context) ([]*domain.JournalAccount, error) {
+	return u.journalRepo.ListAccounts(ctx)
+}
+
+func (u *journalUsecase) UpdateAccount(ctx context.Context, userID int, key, code, name string) (*domain.JournalAccount, error) {
+	if !domain.IsValidJournalAccount(key) {
+		return nil, fmt.Errorf("unknown journal account %q", key)
+	}
+
+	account := &domain.JournalAccount{
+		Key:  key,
+		Code: strings.TrimSpace(code),
+		Name: strings.TrimSpace(name),
+	}
+	if account.Code == "" || len(account.Code) > 50 {
+		return nil, errors.New("account code is required and at most 50 characters")
+	}
+	if account.Name == "" || len(account.Name) > 255 {
+		return nil, errors.New("account name is required and at most 255 characters")
+	}
+
+	if err := u.journalRepo.UpdateAccount(ctx, account); err != nil {
+		return nil, err
+	}
+
+	logger.InfoLogger.Printf("Journal account %s set to %s (%s) by user %d", key, account.Code, account.Name, userID)
+	return account, nil
+}
+
+func (u *journalUsecase) CreateBatch(ctx context.Context, userID int, paidBefore time.Time) (*domain.JournalBatch, error) {
+	if paidBefore.IsZero() {
+		paidBefore = u.now()
+	}
+
+	expenses, err := u.journalRepo.GetUnexported(ctx, paidBefore)
+	if err != nil {
+		return nil, err
+	}
+	refunds, err := u.journalRepo.GetUnexportedRefunds(ctx, paidBefore)
+	if err != nil {
+		return nil, err
+	}
+	if len(expenses) == 0 && len(refunds) == 0 {
+		return nil, domain.ErrNothingToExport
+	}
+
+	accountList, err := u.journalRepo.ListAccounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	accounts := make(map[string]*domain.JournalAccount, len(accountList))
+	for _, account := range accountList {
+		accounts[account.Key] = account
+	}
+
+	batch := &domain.JournalBatch{PaidBefore: paidBefore, CreatedBy: userID}
+	expenseIDs := make([]int, 0, len(expenses))
+	for _, expense := range expenses {
+		lines, err := journalEntry(expense, accounts)
+		if err != nil {
+			return nil, err
+		}
+		batch.Lines = append(batch.Lines, lines...)
+		batch.ExpenseCount++
+		batch.TotalAmountIDR += expense.AmountIDR
+		expenseIDs = append(expenseIDs, expense.ID)
+	}
+	refundIDs := make([]int, 0, len(refunds))
+	for _, refund := range refunds {
+		lines, err := refundJournalEntry(refund, accounts)
+		if err != nil {
+			return nil, err
+		}
+		batch.Lines = append(batch.Lines, lines...)
+		batch.RefundCount++
+		batch.RefundedAmountIDR += refund.AmountIDR
+		refundIDs = append(refundIDs, refund.ID)
+	}
+
+	if err := u.journalRepo.CreateBatch(ctx, batch, expenseIDs, refundIDs); err != nil {
+		return nil, err
+	}
+
+	logger.InfoLogger.Printf("Journal batch %d with %d expenses (IDR %d) and %d refunds (IDR %d) created by user %d", batch.ID, batch.ExpenseCount, batch.TotalAmountIDR, batch.RefundCount, batch.RefundedAmountIDR, userID)
+	return batch, nil
+}
+
+// journalEntry debits the expense account of the category with the full
+// amount and credits what the payout and any cash advance covered, dated
+// the day the expense was paid. Refunds of it are entries of their own.
+func journalEntry(expense *domain.Expense, accounts map[string]*domain.JournalAccount) ([]*domain.JournalLine, error) {
+	if expense.ProcessedAt == nil {
+		return nil, fmt.Errorf("expense %d has no payment date", expense.ID)
+	}
+	var lines []*domain.JournalLine
+	post := journalPoster(&lines, accounts, &domain.JournalLine{
+		ExpenseID:   expense.ID,
+		EntryDate:   entryDay(*expense.ProcessedAt),
+		Description: fmt.Sprintf("Expense #%d: %s", expense.ID, expense.Description),
+	})
+
+	if err := post(domain.JournalExpenseAccount(expense.Category), expense.AmountIDR, 0); err != nil {
+		return nil, err
+	}
+	if expense.AdvanceSettledIDR > 0 {
+		if err := post(domain.JournalAccountAdvances, 0, expense.AdvanceSettledIDR); err != nil {
+			return nil, err
+		}
+	}
+	if paidOut := expense.AmountIDR - expense.AdvanceSettledIDR; paidOut > 0 {
+		if err := post(domain.JournalAccountCash, 0, paidOut); err != nil {
+			return nil, err
+		}
+	}
+
+	return lines, nil
+}
+
+// refundJournalEntry reverses a refunded amount, dated the day the refund
+// completed: the bank account gets the money back and the expense account
+// of the category is credited.
+func refundJournalEntry(refund *domain.JournalRefund, accounts map[string]*domain.JournalAccount) ([]*domain.JournalLine, error) {
+	refundID := refund.ID
+	var lines []*domain.JournalLine
+	post := journalPoster(&lines, accounts, &domain.JournalLine{
+		ExpenseID:   refund.ExpenseID,
+		RefundID:    &refundID,
+		EntryDate:   entryDay(refund.CompletedAt),
+		Description: fmt.Sprintf("Refund #%d of expense #%d: %s", refund.ID, refund.ExpenseID, refund.Reason),
+	})
+
+	if err := post(domain.JournalAccountCash, refund.AmountIDR, 0); err != nil {
+		return nil, err
+	}
+	if err := post(domain.JournalExpenseAccount(refund.Category), 0, refund.AmountIDR); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+// journalPoster returns a function appending lines like entry to lines,
+// each debiting or crediting the account set for key.
+func journalPoster(lines *[]*domain.JournalLine, accounts map[string]*domain.JournalAccount, entry *domain.JournalLine) func(key string, debit, credit int) error {
+	return func(key string, debit, credit int) error {
+		account, ok := accounts[key]
+		if !ok {
+			return fmt.Errorf("no ledger account is set for %s", key)
+		}
+		line := *entry
+		line.AccountCode = account.Code
+		line.AccountName = account.Name
+		line.DebitIDR = debit
+		line.CreditIDR = credit
+		*lines = append(*lines, &line)
+		return nil
+	}
+}
+
+func entryDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func (u *journalUsecase) List(ctx context.Context, page, limit int) ([]*domain.JournalBatch, int, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	return u.journalRepo.List(ctx, limit, (page-1)*limit)
+}
+
+func (u *journalUsecase) GetByID(ctx context.Context, id int) (*domain.JournalBatch, error) {
+	return u.journalRepo.GetByID(ctx, id)
+}
@@ -0,0 +1,246 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"expense-management-system/internal/domain"
+	"testing"
+	"time"
+)
+
+type mockJournalRepo struct {
+	accounts   []*domain.JournalAccount
+	unexported []*domain.Expense
+	refunds    []*domain.JournalRefund
+	createErr  error
+
+	paidBefore time.Time
+	created    *domain.JournalBatch
+	expenseIDs []int
+	refundIDs  []int
+}
+
+func (m *mockJournalRepo) ListAccounts(ctx context.Context) ([]*domain.JournalAccount, error) {
+	return m.accounts, nil
+}
+
+func (m *mockJournalRepo) UpdateAccount(ctx context.Context, account *domain.JournalAccount) error {
+	return nil
+}
+
+func (m *mockJournalRepo) GetUnexported(ctx context.Context, paidBefore time.Time) ([]*domain.Expense, error) {
+	m.paidBefore = paidBefore
+	return m.unexported, nil
+}
+
+func (m *mockJournalRepo) GetUnexportedRefunds(ctx context.Context, completedBefore time.Time) ([]*domain.JournalRefund, error) {
+	return m.refunds, nil
+}
+
+func (m *mockJournalRepo) CreateBatch(ctx context.Context, batch *domain.JournalBatch, expenseIDs, refundIDs []int) error {
+	if m.createErr != nil {
+		return m.createErr
+	}
+	batch.ID = 1
+	m.created = batch
+	m.expenseIDs = expenseIDs
+	m.refundIDs = refundIDs
+	return nil
+}
+
+func (m *mockJournalRepo) List(ctx context.Context, limit, offset int) ([]*domain.JournalBatch, int, error) {
+	return nil, 0, nil
+}
+
+func (m *mockJournalRepo) GetByID(ctx context.Context, id int) (*domain.JournalBatch, error) {
+	return nil, nil
+}
+
+func defaultJournalAccounts() []*domain.JournalAccount {
+	return []*domain.JournalAccount{
+		{Key: domain.JournalExpenseAccount(domain.CategoryTravel), Code: "6100", Name: "Travel"},
+		{Key: domain.JournalExpenseAccount(domain.CategoryMeals), Code: "6120", Name: "Meals"},
+		{Key: domain.JournalAccountCash, Code: "1010", Name: "Bank"},
+		{Key: domain.JournalAccountAdvances, Code: "1410", Name: "Employee Advances"},
+	}
+}
+
+func TestJournalUsecase_CreateBatch(t *testing.T) {
+	paid := time.Date(2025, 1, 15, 16, 30, 0, 0, time.UTC)
+	repo := &mockJournalRepo{
+		accounts: defaultJournalAccounts(),
+		unexported: []*domain.Expense{
+			{ID: 7, AmountIDR: 1500000, Category: domain.CategoryTravel, Description: "Flight", ProcessedAt: &paid},
+			{ID: 8, AmountIDR: 400000, Category: domain.CategoryMeals, Description: "Team lunch", ProcessedAt: &paid, AdvanceSettledIDR: 100000},
+			{ID: 9, AmountIDR: 250000, Category: domain.CategoryMeals, Description: "Dinner", ProcessedAt: &paid, AdvanceSettledIDR: 250000},
+		},
+	}
+	uc := NewJournalUsecase(repo).(*journalUsecase)
+	now := time.Date(2025, 2, 1, 9, 0, 0, 0, time.UTC)
+	uc.now = func() time.Time { return now }
+
+	batch, err := uc.CreateBatch(context.Background(), 5, time.Time{})
+	if err != nil {
+		t.Fatalf("CreateBatch() unexpected error = %v", err)
+	}
+	if !repo.paidBefore.Equal(now) {
+		t.Errorf("Expected expenses paid before now, got %v", repo.paidBefore)
+	}
+	if batch.ExpenseCount != 3 || batch.TotalAmountIDR != 2150000 || batch.CreatedBy != 5 {
+		t.Errorf("Unexpected batch %+v", batch)
+	}
+	if len(repo.expenseIDs) != 3 {
+		t.Errorf("Expected all 3 expenses to be marked, got %v", repo.expenseIDs)
+	}
+
+	type posting struct {
+		expenseID     int
+		account       string
+		debit, credit int
+	}
+	want := []posting{
+		{7, "6100", 1500000, 0},
+		{7, "1010", 0, 1500000},
+		{8, "6120", 400000, 0},
+		{8, "1410", 0, 100000},
+		{8, "1010", 0, 300000},
+		{9, "6120", 250000, 0},
+		{9, "1410", 0, 250000},
+	}
+	if len(batch.Lines) != len(want) {
+		t.Fatalf("Expected %d lines, got %d", len(want), len(batch.Lines))
+	}
+
+	balance := map[int]int{}
+	for i, line := range batch.Lines {
+		got := posting{line.ExpenseID, line.AccountCode, line.DebitIDR, line.CreditIDR}
+		if got != want[i] {
+			t.Errorf("line %d = %+v, want %+v", i, got, want[i])
+		}
+		if !line.EntryDate.Equal(time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("Expected the entry on the day paid, got %v", line.EntryDate)
+		}
+		balance[line.ExpenseID] += line.DebitIDR - line.CreditIDR
+	}
+	for expenseID, diff := range balance {
+		if diff != 0 {
+			t.Errorf("Entry of expense %d is off by %d", expenseID, diff)
+		}
+	}
+}
+
+func TestJournalUsecase_CreateBatchRefunds(t *testing.T) {
+	paid := time.Date(2025, 1, 15, 16, 30, 0, 0, time.UTC)
+	refunded := time.Date(2025, 1, 20, 10, 0, 0, 0, time.UTC)
+	repo := &mockJournalRepo{
+		accounts: defaultJournalAccounts(),
+		unexported: []*domain.Expense{
+			{ID: 7, AmountIDR: 1500000, Category: domain.CategoryTravel, Description: "Flight", ProcessedAt: &paid, Status: domain.StatusPartiallyRefunded},
+		},
+		refunds: []*domain.JournalRefund{
+			{ID: 3, ExpenseID: 7, AmountIDR: 500000, Reason: "Fare difference", Category: domain.CategoryTravel, CompletedAt: refunded},
+			// A refund of an expense exported in an earlier batch.
+			{ID: 4, ExpenseID: 2, AmountIDR: 250000, Reason: "Duplicate", Category: domain.CategoryMeals, CompletedAt: refunded},
+		},
+	}
+
+	batch, err := NewJournalUsecase(repo).CreateBatch(context.Background(), 5, refunded.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatalf("CreateBatch() unexpected error = %v", err)
+	}
+	if batch.ExpenseCount != 1 || batch.TotalAmountIDR != 1500000 || batch.RefundCount != 2 || batch.RefundedAmountIDR != 750000 {
+		t.Errorf("Unexpected batch %+v", batch)
+	}
+	if len(repo.refundIDs) != 2 || repo.refundIDs[0] != 3 || repo.refundIDs[1] != 4 {
+		t.Errorf("Expected both refunds to be marked, got %v", repo.refundIDs)
+	}
+
+	type posting struct {
+		expenseID, refundID int
+		account             string
+		debit, credit       int
+	}
+	want := []posting{
+		{7, 0, "6100", 1500000, 0},
+		{7, 0, "1010", 0, 1500000},
+		{7, 3, "1010", 500000, 0},
+		{7, 3, "6100", 0, 500000},
+		{2, 4, "1010", 250000, 0},
+		{2, 4, "6120", 0, 250000},
+	}
+	if len(batch.Lines) != len(want) {
+		t.Fatalf("Expected %d lines, got %d", len(want), len(batch.Lines))
+	}
+
+	// Net of the refund, the travel account carries what was really spent.
+	spent := 0
+	for i, line := range batch.Lines {
+		got := posting{line.ExpenseID, 0, line.AccountCode, line.DebitIDR, line.CreditIDR}
+		if line.RefundID != nil {
+			got.refundID = *line.RefundID
+			if !line.EntryDate.Equal(time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC)) {
+				t.Errorf("Expected the refund entry on the day it completed, got %v", line.EntryDate)
+			}
+		}
+		if got != want[i] {
+			t.Errorf("line %d = %+v, want %+v", i, got, want[i])
+		}
+		if line.AccountCode == "6100" {
+			spent += line.DebitIDR - line.CreditIDR
+		}
+	}
+	if spent != 1000000 {
+		t.Errorf("Expected IDR 1000000 of travel spend, got %d", spent)
+	}
+}
+
+func TestJournalUsecase_CreateBatchErrors(t *testing.T) {
+	paid := time.Date(2025, 1, 15, 16, 30, 0, 0, time.UTC)
+	lodging := []*domain.Expense{{ID: 7, AmountIDR: 900000, Category: domain.CategoryLodging, ProcessedAt: &paid}}
+	travel := []*domain.Expense{{ID: 7, AmountIDR: 900000, Category: domain.CategoryTravel, ProcessedAt: &paid}}
+
+	tests := []struct {
+		name    string
+		repo    *mockJournalRepo
+		wantErr error
+	}{
+		{"nothing to export", &mockJournalRepo{accounts: defaultJournalAccounts()}, domain.ErrNothingToExport},
+		{"exported concurrently", &mockJournalRepo{accounts: defaultJournalAccounts(), unexported: travel, createErr: domain.ErrJournalConflict}, domain.ErrJournalConflict},
+		{"no account for the category", &mockJournalRepo{accounts: defaultJournalAccounts(), unexported: lodging}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewJournalUsecase(tt.repo).CreateBatch(context.Background(), 5, paid.AddDate(0, 0, 1))
+			if err == nil {
+				t.Fatal("CreateBatch() expected an error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("CreateBatch() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.repo.created != nil {
+				t.Error("Expected no batch to be stored")
+			}
+		})
+	}
+}
+
+func TestJournalUsecase_UpdateAccount(t *testing.T) {
+	uc := NewJournalUsecase(&mockJournalRepo{})
+	ctx := context.Background()
+
+	account, err := uc.UpdateAccount(ctx, 5, domain.JournalAccountCash, " 1020 ", "Operating account")
+	if err != nil {
+		t.Fatalf("UpdateAccount() unexpected error = %v", err)
+	}
+	if account.Code != "1020" {
+		t.Errorf("Expected the code to be trimmed, got %q", account.Code)
+	}
+
+	if _, err := uc.UpdateAccount(ctx, 5, "expense:yachts", "6900", "Yachts"); err == nil {
+		t.Error("Expected an error for an unknown account")
+	}
+	if _, err := uc.UpdateAccount(ctx, 5, domain.JournalAccountCash, "", "Bank"); err == nil {
+		t.Error("Expected an error for an empty code")
+	}
+}
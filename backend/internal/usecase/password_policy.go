@@ -0,0 +1,64 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+const (
+	MinPasswordLength = 10
+	// bcrypt ignores everything past 72 bytes.
+	MaxPasswordLength = 72
+)
+
+// commonPasswords are rejected outright regardless of the other rules.
+var commonPasswords = map[string]bool{
+	"password123":  true,
+	"password1234": true,
+	"qwerty12345":  true,
+	"1234567890a":  true,
+	"iloveyou123":  true,
+	"welcome1234":  true,
+	"letmein1234":  true,
+	"admin12345":   true,
+	"changeme123":  true,
+	"abc123456789": true,
+}
+
+// ValidatePassword enforces the password policy: 10 to 72 characters, at
+// least one letter and one digit, not a well-known password and not derived
+// from the user's email address.
+func ValidatePassword(password, email string) error {
+	if len(password) < MinPasswordLength {
+		return fmt.Errorf("password must be at least %d characters", MinPasswordLength)
+	}
+	if len(password) > MaxPasswordLength {
+		return fmt.Errorf("password must be at most %d bytes", MaxPasswordLength)
+	}
+
+	var hasLetter, hasDigit bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLetter(r):
+			hasLetter = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		}
+	}
+	if !hasLetter || !hasDigit {
+		return errors.New("password must contain at least one letter and one digit")
+	}
+
+	lower := strings.ToLower(password)
+	if commonPasswords[lower] {
+		return errors.New("password is too common")
+	}
+
+	if local, _, ok := strings.Cut(strings.ToLower(email), "@"); ok && len(local) >= 3 && strings.Contains(lower, local) {
+		return errors.New("password must not contain your email address")
+	}
+
+	return nil
+}
@@ -0,0 +1,32 @@
+package usecase
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidatePassword(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		email    string
+		wantErr  bool
+	}{
+		{name: "Valid password", password: "Receipts-2024", email: "employee1@example.com"},
+		{name: "Too short", password: "abc123", email: "employee1@example.com", wantErr: true},
+		{name: "Too long", password: strings.Repeat("a1", 37), email: "employee1@example.com", wantErr: true},
+		{name: "No digit", password: "onlyletterspass", email: "employee1@example.com", wantErr: true},
+		{name: "No letter", password: "12345678901", email: "employee1@example.com", wantErr: true},
+		{name: "Common password", password: "Password123", email: "employee1@example.com", wantErr: true},
+		{name: "Contains email name", password: "employee1-secret", email: "employee1@example.com", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePassword(tt.password, tt.email)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidatePassword() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
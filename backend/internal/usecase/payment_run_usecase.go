@@ -0,0 +1,253 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"expense-management-system/internal/domain"
+	"expense-management-system/pkg/logger"
+	"time"
+)
+
+// payoutRequeueAfter is how long a payout of a released run may stay
+// pending before the sweep queues it again. It is well beyond the time a
+// worker spends retrying one, so payouts in progress are left alone; one
+// queued twice is still paid once, since the gateway deduplicates by
+// external ID.
+const payoutRequeueAfter = 15 * time.Minute
+
+type paymentRunUsecase struct {
+	paymentRunRepo domain.PaymentRunRepository
+	expenseRepo    domain.ExpenseRepository
+	auditRepo      domain.AuditLogRepository
+	paymentChan    chan PaymentJob
+	cutoffHour     int
+	cutoffMinute   int
+}
+
+// NewPaymentRunUsecase creates the batched payout flow. cutoff is the daily
+// "HH:MM" time at which the open run closes for finance review; an invalid
+// value falls back to 17:00.
+func NewPaymentRunUsecase(
+	paymentRunRepo domain.PaymentRunRepository,
+	expenseRepo domain.ExpenseRepository,
+	auditRepo domain.AuditLogRepository,
+	paymentChan chan PaymentJob,
+	cutoff string,
+) domain.PaymentRunUsecase {
+	t, err := time.Parse("15:04", cutoff)
+	if err != nil {
+		logger.ErrorLogger.Printf("Invalid payment run cutoff %q, using 17:00", cutoff)
+		t, _ = time.Parse("15:04", "17:00")
+	}
+
+	return &paymentRunUsecase{
+		paymentRunRepo: paymentRunRepo,
+		expenseRepo:    expenseRepo,
+		auditRepo:      auditRepo,
+		paymentChan:    paymentChan,
+		cutoffHour:     t.Hour(),
+		cutoffMinute:   t.Minute(),
+	}
+}
+
+// nextCutoff returns the first cutoff strictly after now.
+func (u *paymentRunUsecase) nextCutoff(now time.Time) time.Time {
+	cutoff := time.Date(now.Year(), now.Month(), now.Day(), u.cutoffHour, u.cutoffMinute, 0, 0, now.Location())
+	if !cutoff.After(now) {
+		cutoff = cutoff.AddDate(0, 0, 1)
+	}
+	return cutoff
+}
+
+func (u *paymentRunUsecase) AddExpense(ctx context.Context, expense *domain.Expense) error {
+	cutoff := u.nextCutoff(time.Now())
+
+	run, err := u.paymentRunRepo.GetOrCreateOpen(ctx, cutoff)
+	if err != nil {
+		return err
+	}
+
+	// The scheduler may have closed this run between computing the cutoff
+	// and reaching the database; roll over to the following day's run.
+	if run.Status != domain.PaymentRunStatusOpen {
+		run, err = u.paymentRunRepo.GetOrCreateOpen(ctx, cutoff.AddDate(0, 0, 1))
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := u.expenseRepo.AssignPaymentRun(ctx, expense.ID, run.ID); err != nil {
+		return err
+	}
+	expense.PaymentRunID = &run.ID
+
+	logger.InfoLogger.Printf("Expense %d added to payment run %d (cutoff %s)", expense.ID, run.ID, run.CutoffAt.Format(time.RFC3339))
+	return nil
+}
+
+func (u *paymentRunUsecase) CloseDueRuns(ctx context.Context, now time.Time) error {
+	runs, err := u.paymentRunRepo.CloseDue(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	for _, run := range runs {
+		logger.InfoLogger.Printf("[EMAIL] Payment run %d ready for finance review (%d expenses, IDR %d)", run.ID, run.ExpenseCount, run.TotalAmountIDR)
+	}
+
+	return nil
+}
+
+func (u *paymentRunUsecase) List(ctx context.Context, status string, page, limit int) ([]*domain.PaymentRun, int, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	offset := (page - 1) * limit
+	return u.paymentRunRepo.List(ctx, status, limit, offset)
+}
+
+func (u *paymentRunUsecase) GetByID(ctx context.Context, id int) (*domain.PaymentRun, error) {
+	run, err := u.paymentRunRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	run.Payouts, err = u.paymentRunRepo.GetPayouts(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	run.Expenses, err = u.expenseRepo.GetByPaymentRunID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return run, nil
+}
+
+func (u *paymentRunUsecase) Release(ctx context.Context, userID, runID int) (*domain.PaymentRun, error) {
+	run, err := u.paymentRunRepo.GetByID(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+
+	if run.Status != domain.PaymentRunStatusPendingReview {
+		return nil, errors.New("payment run is not pending review")
+	}
+
+	payouts, err := u.paymentRunRepo.Release(ctx, runID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The run is released now; finish recording it even if the client goes
+	// away.
+	ctx = context.WithoutCancel(ctx)
+
+	expenses, err := u.expenseRepo.GetByPaymentRunID(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+
+	payoutByUser := make(map[int]*domain.Payout, len(payouts))
+	for _, payout := range payouts {
+		payoutByUser[payout.UserID] = payout
+	}
+
+	for _, expense := range expenses {
+		payout, ok := payoutByUser[expense.UserID]
+		if !ok || expense.Status != domain.StatusApproved {
+			continue
+		}
+
+		auditLog := &domain.AuditLog{
+			ExpenseID: expense.ID,
+			UserID:    &userID,
+			Action:    domain.ActionRelease,
+			Metadata: map[string]interface{}{
+				"payment_run_id": runID,
+				"payout_id":      payout.ID,
+				"external_id":    payout.PaymentExternalID,
+			},
+		}
+		u.auditRepo.Create(ctx, auditLog)
+	}
+
+	logger.InfoLogger.Printf("Payment run %d released by user %d, queueing %d payouts", runID, userID, len(payouts))
+
+	for _, payout := range payouts {
+		u.queuePayout(payout)
+	}
+
+	return u.GetByID(ctx, runID)
+}
+
+// queuePayout does not wait for queue space: a payout left out stays
+// pending, and RequeueStalePayouts queues it later.
+func (u *paymentRunUsecase) queuePayout(payout *domain.Payout) {
+	select {
+	case u.paymentChan <- PaymentJob{
+		Amount:       payout.AmountIDR,
+		ExternalID:   payout.PaymentExternalID,
+		PaymentRunID: payout.PaymentRunID,
+		PayoutID:     payout.ID,
+	}:
+		logger.InfoLogger.Printf("Payout job queued for payout %d (run %d, user %d)", payout.ID, payout.PaymentRunID, payout.UserID)
+	default:
+		logger.ErrorLogger.Printf("Payment queue full, payout %d (run %d) left for the next sweep", payout.ID, payout.PaymentRunID)
+	}
+}
+
+func (u *paymentRunUsecase) RequeueStalePayouts(ctx context.Context, now time.Time) error {
+	payouts, err := u.paymentRunRepo.ClaimStalePayouts(ctx, now.Add(-payoutRequeueAfter))
+	if err != nil {
+		return err
+	}
+
+	for _, payout := range payouts {
+		logger.InfoLogger.Printf("Payout %d of payment run %d still pending, queueing it again", payout.ID, payout.PaymentRunID)
+		u.queuePayout(payout)
+	}
+	return nil
+}
+
+// RetryPayout sends a failed payout again with the same external ID, so
+// that one which reached the gateway after all is not paid twice.
+func (u *paymentRunUsecase) RetryPayout(ctx context.Context, userID, runID, payoutID int) (*domain.PaymentRun, error) {
+	payout, err := u.paymentRunRepo.RetryPayout(ctx, runID, payoutID)
+	if err != nil {
+		return nil, err
+	}
+	ctx = context.WithoutCancel(ctx)
+
+	expenses, err := u.expenseRepo.GetByPaymentRunID(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	for _, expense := range expenses {
+		if expense.UserID != payout.UserID || expense.Status != domain.StatusApproved {
+			continue
+		}
+
+		auditLog := &domain.AuditLog{
+			ExpenseID: expense.ID,
+			UserID:    &userID,
+			Action:    domain.ActionPayoutRetry,
+			Metadata: map[string]interface{}{
+				"payment_run_id": runID,
+				"payout_id":      payout.ID,
+				"external_id":    payout.PaymentExternalID,
+			},
+		}
+		u.auditRepo.Create(ctx, auditLog)
+	}
+
+	logger.InfoLogger.Printf("Payout %d of payment run %d retried by user %d", payout.ID, runID, userID)
+	u.queuePayout(payout)
+
+	return u.GetByID(ctx, runID)
+}
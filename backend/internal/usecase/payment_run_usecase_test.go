@@ -0,0 +1,400 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"expense-management-system/internal/domain"
+	"testing"
+	"time"
+)
+
+type mockPaymentRunRepo struct {
+	getOrCreateOpenFunc func(ctx context.Context, cutoffAt time.Time) (*domain.PaymentRun, error)
+	getByIDFunc         func(ctx context.Context, id int) (*domain.PaymentRun, error)
+	releaseFunc         func(ctx context.Context, id int, releasedBy int) ([]*domain.Payout, error)
+	closeDueFunc        func(ctx context.Context, now time.Time) ([]*domain.PaymentRun, error)
+	claimStaleFunc      func(ctx context.Context, staleBefore time.Time) ([]*domain.Payout, error)
+	retryPayoutFunc     func(ctx context.Context, runID, payoutID int) (*domain.Payout, error)
+}
+
+func (m *mockPaymentRunRepo) GetOrCreateOpen(ctx context.Context, cutoffAt time.Time) (*domain.PaymentRun, error) {
+	if m.getOrCreateOpenFunc != nil {
+		return m.getOrCreateOpenFunc(ctx, cutoffAt)
+	}
+	return &domain.PaymentRun{ID: 1, CutoffAt: cutoffAt, Status: domain.PaymentRunStatusOpen}, nil
+}
+
+func (m *mockPaymentRunRepo) GetByID(ctx context.Context, id int) (*domain.PaymentRun, error) {
+	if m.getByIDFunc != nil {
+		return m.getByIDFunc(ctx, id)
+	}
+	return &domain.PaymentRun{ID: id, Status: domain.PaymentRunStatusOpen}, nil
+}
+
+func (m *mockPaymentRunRepo) List(ctx context.Context, status string, limit, offset int) ([]*domain.PaymentRun, int, error) {
+	return nil, 0, nil
+}
+
+func (m *mockPaymentRunRepo) CloseDue(ctx context.Context, now time.Time) ([]*domain.PaymentRun, error) {
+	if m.closeDueFunc != nil {
+		return m.closeDueFunc(ctx, now)
+	}
+	return nil, nil
+}
+
+func (m *mockPaymentRunRepo) Release(ctx context.Context, id int, releasedBy int) ([]*domain.Payout, error) {
+	if m.releaseFunc != nil {
+		return m.releaseFunc(ctx, id, releasedBy)
+	}
+	return nil, nil
+}
+
+func (m *mockPaymentRunRepo) GetPayouts(ctx context.Context, paymentRunID int) ([]*domain.Payout, error) {
+	return nil, nil
+}
+
+func (m *mockPaymentRunRepo) GetPayoutByID(ctx context.Context, id int) (*domain.Payout, error) {
+	return nil, errors.New("payout not found")
+}
+
+func (m *mockPaymentRunRepo) UpdatePayoutStatus(ctx context.Context, id int, status string, paymentID *string) error {
+	return nil
+}
+
+func (m *mockPaymentRunRepo) RefreshStatus(ctx context.Context, id int) error {
+	return nil
+}
+
+func (m *mockPaymentRunRepo) ClaimStalePayouts(ctx context.Context, staleBefore time.Time) ([]*domain.Payout, error) {
+	if m.claimStaleFunc != nil {
+		return m.claimStaleFunc(ctx, staleBefore)
+	}
+	return nil, nil
+}
+
+func (m *mockPaymentRunRepo) RetryPayout(ctx context.Context, runID, payoutID int) (*domain.Payout, error) {
+	if m.retryPayoutFunc != nil {
+		return m.retryPayoutFunc(ctx, runID, payoutID)
+	}
+	return nil, errors.New("payout not found or not failed")
+}
+
+func TestPaymentRunUsecase_NextCutoff(t *testing.T) {
+	uc := NewPaymentRunUsecase(&mockPaymentRunRepo{}, &mockExpenseRepo{}, &mockAuditRepo{}, make(chan PaymentJob, 1), "17:00").(*paymentRunUsecase)
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want time.Time
+	}{
+		{
+			name: "Before cutoff uses same day",
+			now:  time.Date(2026, 1, 8, 9, 30, 0, 0, time.UTC),
+			want: time.Date(2026, 1, 8, 17, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "Exactly at cutoff rolls to next day",
+			now:  time.Date(2026, 1, 8, 17, 0, 0, 0, time.UTC),
+			want: time.Date(2026, 1, 9, 17, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "After cutoff rolls to next day",
+			now:  time.Date(2026, 1, 31, 22, 0, 0, 0, time.UTC),
+			want: time.Date(2026, 2, 1, 17, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := uc.nextCutoff(tt.now); !got.Equal(tt.want) {
+				t.Errorf("nextCutoff() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPaymentRunUsecase_AddExpense(t *testing.T) {
+	ctx := context.Background()
+
+	var cutoffs []time.Time
+	runRepo := &mockPaymentRunRepo{
+		getOrCreateOpenFunc: func(ctx context.Context, cutoffAt time.Time) (*domain.PaymentRun, error) {
+			cutoffs = append(cutoffs, cutoffAt)
+			// First run was closed concurrently by the scheduler
+			if len(cutoffs) == 1 {
+				return &domain.PaymentRun{ID: 1, CutoffAt: cutoffAt, Status: domain.PaymentRunStatusPendingReview}, nil
+			}
+			return &domain.PaymentRun{ID: 2, CutoffAt: cutoffAt, Status: domain.PaymentRunStatusOpen}, nil
+		},
+	}
+
+	var assignedRunID int
+	expenseRepo := &mockExpenseRepo{
+		assignPaymentRunFn: func(ctx context.Context, id int, paymentRunID int) error {
+			assignedRunID = paymentRunID
+			return nil
+		},
+	}
+
+	uc := NewPaymentRunUsecase(runRepo, expenseRepo, &mockAuditRepo{}, make(chan PaymentJob, 1), "17:00")
+
+	expense := &domain.Expense{ID: 10, UserID: 1, AmountIDR: 500000}
+	if err := uc.AddExpense(ctx, expense); err != nil {
+		t.Fatalf("AddExpense() unexpected error = %v", err)
+	}
+
+	if len(cutoffs) != 2 || !cutoffs[1].Equal(cutoffs[0].AddDate(0, 0, 1)) {
+		t.Errorf("Expected rollover to next day's run, got cutoffs %v", cutoffs)
+	}
+
+	if assignedRunID != 2 {
+		t.Errorf("Expense assigned to run %d, want 2", assignedRunID)
+	}
+
+	if expense.PaymentRunID == nil || *expense.PaymentRunID != 2 {
+		t.Errorf("Expense PaymentRunID = %v, want 2", expense.PaymentRunID)
+	}
+}
+
+func TestPaymentRunUsecase_Release(t *testing.T) {
+	tests := []struct {
+		name      string
+		runStatus string
+		wantErr   bool
+		wantJobs  int
+	}{
+		{
+			name:      "Release pending review run queues one payout per employee",
+			runStatus: domain.PaymentRunStatusPendingReview,
+			wantErr:   false,
+			wantJobs:  2,
+		},
+		{
+			name:      "Cannot release open run",
+			runStatus: domain.PaymentRunStatusOpen,
+			wantErr:   true,
+		},
+		{
+			name:      "Cannot release already released run",
+			runStatus: domain.PaymentRunStatusReleased,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			paymentChan := make(chan PaymentJob, 10)
+
+			runRepo := &mockPaymentRunRepo{
+				getByIDFunc: func(ctx context.Context, id int) (*domain.PaymentRun, error) {
+					return &domain.PaymentRun{ID: id, Status: tt.runStatus}, nil
+				},
+				releaseFunc: func(ctx context.Context, id int, releasedBy int) ([]*domain.Payout, error) {
+					return []*domain.Payout{
+						{ID: 1, PaymentRunID: id, UserID: 1, AmountIDR: 750000, ExpenseCount: 2, PaymentExternalID: "payout-1"},
+						{ID: 2, PaymentRunID: id, UserID: 2, AmountIDR: 300000, ExpenseCount: 1, PaymentExternalID: "payout-2"},
+					}, nil
+				},
+			}
+
+			var audited []int
+			auditRepo := &mockAuditRepo{
+				createFunc: func(ctx context.Context, log *domain.AuditLog) error {
+					audited = append(audited, log.ExpenseID)
+					return nil
+				},
+			}
+
+			expenseRepo := &mockExpenseRepo{
+				getByPaymentRunFn: func(ctx context.Context, paymentRunID int) ([]*domain.Expense, error) {
+					return []*domain.Expense{
+						{ID: 1, UserID: 1, AmountIDR: 500000, Status: domain.StatusApproved},
+						{ID: 2, UserID: 1, AmountIDR: 250000, Status: domain.StatusApproved},
+						{ID: 3, UserID: 2, AmountIDR: 300000, Status: domain.StatusApproved},
+					}, nil
+				},
+			}
+
+			uc := NewPaymentRunUsecase(runRepo, expenseRepo, auditRepo, paymentChan, "17:00")
+
+			_, err := uc.Release(ctx, 4, 7)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Release() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if len(paymentChan) != tt.wantJobs {
+				t.Fatalf("Queued %d payout jobs, want %d", len(paymentChan), tt.wantJobs)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			job := <-paymentChan
+			if job.PayoutID != 1 || job.PaymentRunID != 7 || job.Amount != 750000 || job.ExternalID != "payout-1" {
+				t.Errorf("Unexpected payout job %+v", job)
+			}
+
+			if len(audited) != 3 {
+				t.Errorf("Expected release audit entry for each of 3 expenses, got %d", len(audited))
+			}
+		})
+	}
+}
+
+func TestPaymentRunUsecase_ReleaseDoesNotWaitForQueue(t *testing.T) {
+	// The client is gone and the queue has room for one payout only.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	paymentChan := make(chan PaymentJob, 1)
+
+	runRepo := &mockPaymentRunRepo{
+		getByIDFunc: func(ctx context.Context, id int) (*domain.PaymentRun, error) {
+			return &domain.PaymentRun{ID: id, Status: domain.PaymentRunStatusPendingReview}, nil
+		},
+		releaseFunc: func(ctx context.Context, id int, releasedBy int) ([]*domain.Payout, error) {
+			return []*domain.Payout{
+				{ID: 1, PaymentRunID: id, UserID: 1, AmountIDR: 500000, PaymentExternalID: "payout-1"},
+				{ID: 2, PaymentRunID: id, UserID: 2, AmountIDR: 300000, PaymentExternalID: "payout-2"},
+			}, nil
+		},
+	}
+	var audits int
+	auditRepo := &mockAuditRepo{
+		createFunc: func(ctx context.Context, log *domain.AuditLog) error {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			audits++
+			return nil
+		},
+	}
+	expenseRepo := &mockExpenseRepo{
+		getByPaymentRunFn: func(ctx context.Context, paymentRunID int) ([]*domain.Expense, error) {
+			return []*domain.Expense{
+				{ID: 1, UserID: 1, AmountIDR: 500000, Status: domain.StatusApproved},
+				{ID: 2, UserID: 2, AmountIDR: 300000, Status: domain.StatusApproved},
+			}, nil
+		},
+	}
+	uc := NewPaymentRunUsecase(runRepo, expenseRepo, auditRepo, paymentChan, "17:00")
+
+	if _, err := uc.Release(ctx, 4, 7); err != nil {
+		t.Fatalf("Release() unexpected error = %v", err)
+	}
+	if len(paymentChan) != 1 {
+		t.Errorf("Queued %d payout jobs, want 1", len(paymentChan))
+	}
+	if audits != 2 {
+		t.Errorf("Expected the release to be audited after the client left, got %d entries", audits)
+	}
+}
+
+func TestPaymentRunUsecase_RequeueStalePayouts(t *testing.T) {
+	now := time.Date(2026, 1, 8, 18, 0, 0, 0, time.UTC)
+	var staleBefore time.Time
+	runRepo := &mockPaymentRunRepo{
+		claimStaleFunc: func(ctx context.Context, before time.Time) ([]*domain.Payout, error) {
+			staleBefore = before
+			return []*domain.Payout{
+				{ID: 3, PaymentRunID: 7, UserID: 1, AmountIDR: 500000, PaymentExternalID: "payout-3"},
+			}, nil
+		},
+	}
+	paymentChan := make(chan PaymentJob, 10)
+	uc := NewPaymentRunUsecase(runRepo, &mockExpenseRepo{}, &mockAuditRepo{}, paymentChan, "17:00")
+
+	if err := uc.RequeueStalePayouts(context.Background(), now); err != nil {
+		t.Fatalf("RequeueStalePayouts() unexpected error = %v", err)
+	}
+	if !staleBefore.Equal(now.Add(-payoutRequeueAfter)) {
+		t.Errorf("Claimed payouts pending since %v, want %v", staleBefore, now.Add(-payoutRequeueAfter))
+	}
+	if len(paymentChan) != 1 {
+		t.Fatalf("Queued %d payout jobs, want 1", len(paymentChan))
+	}
+	if job := <-paymentChan; job.PayoutID != 3 || job.PaymentRunID != 7 || job.ExternalID != "payout-3" {
+		t.Errorf("Unexpected payout job %+v", job)
+	}
+}
+
+func TestPaymentRunUsecase_RetryPayout(t *testing.T) {
+	ctx := context.Background()
+	runRepo := &mockPaymentRunRepo{
+		retryPayoutFunc: func(ctx context.Context, runID, payoutID int) (*domain.Payout, error) {
+			if payoutID != 2 {
+				return nil, errors.New("payout not found or not failed")
+			}
+			return &domain.Payout{ID: 2, PaymentRunID: runID, UserID: 2, AmountIDR: 300000, Status: domain.PayoutStatusPending, PaymentExternalID: "payout-2"}, nil
+		},
+	}
+	var audited []int
+	auditRepo := &mockAuditRepo{
+		createFunc: func(ctx context.Context, log *domain.AuditLog) error {
+			if log.Action != domain.ActionPayoutRetry {
+				t.Errorf("Audit action = %v, want %v", log.Action, domain.ActionPayoutRetry)
+			}
+			audited = append(audited, log.ExpenseID)
+			return nil
+		},
+	}
+	expenseRepo := &mockExpenseRepo{
+		getByPaymentRunFn: func(ctx context.Context, paymentRunID int) ([]*domain.Expense, error) {
+			return []*domain.Expense{
+				{ID: 1, UserID: 1, AmountIDR: 500000, Status: domain.StatusCompleted},
+				{ID: 3, UserID: 2, AmountIDR: 300000, Status: domain.StatusApproved},
+			}, nil
+		},
+	}
+	paymentChan := make(chan PaymentJob, 10)
+	uc := NewPaymentRunUsecase(runRepo, expenseRepo, auditRepo, paymentChan, "17:00")
+
+	if _, err := uc.RetryPayout(ctx, 4, 7, 1); err == nil {
+		t.Error("Expected an error for a payout that is not failed")
+	}
+	if _, err := uc.RetryPayout(ctx, 4, 7, 2); err != nil {
+		t.Fatalf("RetryPayout() unexpected error = %v", err)
+	}
+	if len(paymentChan) != 1 {
+		t.Fatalf("Queued %d payout jobs, want 1", len(paymentChan))
+	}
+	if job := <-paymentChan; job.PayoutID != 2 || job.ExternalID != "payout-2" || job.Amount != 300000 {
+		t.Errorf("Unexpected payout job %+v", job)
+	}
+	if len(audited) != 1 || audited[0] != 3 {
+		t.Errorf("Expected a retry audit entry for expense 3 only, got %v", audited)
+	}
+}
+
+func TestExpenseUsecase_Submit_BatchedPayments(t *testing.T) {
+	ctx := context.Background()
+	paymentChan := make(chan PaymentJob, 10)
+
+	expenseRepo := &mockExpenseRepo{}
+	runUsecase := NewPaymentRunUsecase(&mockPaymentRunRepo{}, expenseRepo, &mockAuditRepo{}, paymentChan, "17:00")
+
+	uc := NewExpenseUsecase(expenseRepo, &mockApprovalRepo{}, &mockAuditRepo{}, &mockUserRepo{}, &mockCashAdvanceRepo{}, paymentChan, runUsecase, nil, nil, nil, nil, nil)
+
+	expense, err := uc.Submit(ctx, 1, 500000, "Office supplies", "", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Submit() unexpected error = %v", err)
+	}
+
+	if expense.Status != domain.StatusApproved {
+		t.Errorf("Submit() status = %v, want %v", expense.Status, domain.StatusApproved)
+	}
+
+	if expense.PaymentRunID == nil {
+		t.Error("Expected auto-approved expense to be added to a payment run")
+	}
+
+	select {
+	case <-paymentChan:
+		t.Error("Payment job should not be queued immediately in batched mode")
+	default:
+		// Expected
+	}
+}
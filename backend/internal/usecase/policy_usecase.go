@@ -0,0 +1,252 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"expense-management-system/internal/domain"
+	"expense-management-system/internal/policy"
+	"expense-management-system/pkg/logger"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// policyCacheTTL bounds how long another API instance may keep checking
+// expenses against rules that were since changed.
+const policyCacheTTL = time.Minute
+
+type compiledRule struct {
+	rule      *domain.PolicyRule
+	condition *policy.Condition
+}
+
+type policyUsecase struct {
+	ruleRepo  domain.PolicyRuleRepository
+	userRepo  domain.UserRepository
+	auditRepo domain.AuditLogRepository
+	now       func() time.Time
+
+	// Rules are checked on every submission, so the enabled ones are kept
+	// parsed.
+	mu       sync.RWMutex
+	cache    []compiledRule
+	loaded   bool
+	loadedAt time.Time
+}
+
+func NewPolicyUsecase(ruleRepo domain.PolicyRuleRepository, userRepo domain.UserRepository, auditRepo domain.AuditLogRepository) domain.PolicyUsecase {
+	return &policyUsecase{
+		ruleRepo:  ruleRepo,
+		userRepo:  userRepo,
+		auditRepo: auditRepo,
+		now:       time.Now,
+	}
+}
+
+// prepareRule validates a rule and stores its condition compacted.
+func prepareRule(rule *domain.PolicyRule) error {
+	rule.Name = strings.TrimSpace(rule.Name)
+	if rule.Name == "" || len(rule.Name) > 100 {
+		return errors.New("name is required and at most 100 characters")
+	}
+	rule.Message = strings.TrimSpace(rule.Message)
+	if rule.Message == "" || len(rule.Message) > 500 {
+		return errors.New("message is required and at most 500 characters")
+	}
+	if !domain.IsValidPolicyAction(rule.Action) {
+		return fmt.Errorf("action must be %s, %s or %s", domain.PolicyActionBlock, domain.PolicyActionWarn, domain.PolicyActionRequireApproval)
+	}
+	if len(rule.Condition) == 0 {
+		return errors.New("condition is required")
+	}
+	if _, err := policy.Parse(rule.Condition); err != nil {
+		return err
+	}
+
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, rule.Condition); err != nil {
+		return err
+	}
+	rule.Condition = compact.Bytes()
+	return nil
+}
+
+func (u *policyUsecase) Create(ctx context.Context, adminID int, rule *domain.PolicyRule) (*domain.PolicyRule, error) {
+	if err := prepareRule(rule); err != nil {
+		return nil, err
+	}
+	rule.CreatedBy = adminID
+
+	if err := u.ruleRepo.Create(ctx, rule); err != nil {
+		return nil, err
+	}
+	u.invalidate()
+
+	u.audit(ctx, adminID, domain.ActionPolicyRuleCreate, map[string]interface{}{
+		"rule_id":   rule.ID,
+		"name":      rule.Name,
+		"action":    rule.Action,
+		"condition": string(rule.Condition),
+		"enabled":   rule.Enabled,
+	})
+	logger.InfoLogger.Printf("Policy rule %d (%s) created by admin %d", rule.ID, rule.Name, adminID)
+
+	return rule, nil
+}
+
+func (u *policyUsecase) Update(ctx context.Context, adminID, id int, update *domain.PolicyRule) (*domain.PolicyRule, error) {
+	rule, err := u.ruleRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := prepareRule(update); err != nil {
+		return nil, err
+	}
+
+	old := *rule
+	rule.Name = update.Name
+	rule.Message = update.Message
+	rule.Action = update.Action
+	rule.Condition = update.Condition
+	rule.Enabled = update.Enabled
+
+	if err := u.ruleRepo.Update(ctx, rule); err != nil {
+		return nil, err
+	}
+	u.invalidate()
+
+	u.audit(ctx, adminID, domain.ActionPolicyRuleUpdate, map[string]interface{}{
+		"rule_id":       rule.ID,
+		"name":          rule.Name,
+		"old_action":    old.Action,
+		"new_action":    rule.Action,
+		"old_condition": string(old.Condition),
+		"new_condition": string(rule.Condition),
+		"old_enabled":   old.Enabled,
+		"new_enabled":   rule.Enabled,
+	})
+	logger.InfoLogger.Printf("Policy rule %d (%s) updated by admin %d", rule.ID, rule.Name, adminID)
+
+	return rule, nil
+}
+
+func (u *policyUsecase) Delete(ctx context.Context, adminID, id int) error {
+	rule, err := u.ruleRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := u.ruleRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+	u.invalidate()
+
+	u.audit(ctx, adminID, domain.ActionPolicyRuleDelete, map[string]interface{}{
+		"rule_id":   rule.ID,
+		"name":      rule.Name,
+		"action":    rule.Action,
+		"condition": string(rule.Condition),
+	})
+	logger.InfoLogger.Printf("Policy rule %d (%s) deleted by admin %d", rule.ID, rule.Name, adminID)
+
+	return nil
+}
+
+func (u *policyUsecase) GetByID(ctx context.Context, id int) (*domain.PolicyRule, error) {
+	return u.ruleRepo.GetByID(ctx, id)
+}
+
+func (u *policyUsecase) List(ctx context.Context) ([]*domain.PolicyRule, error) {
+	return u.ruleRepo.List(ctx, false)
+}
+
+func (u *policyUsecase) Evaluate(ctx context.Context, userID int, expense *domain.Expense) ([]domain.PolicyViolation, error) {
+	rules, err := u.enabledRules(ctx)
+	if err != nil || len(rules) == 0 {
+		return nil, err
+	}
+
+	user, err := u.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	facts := policy.Facts{
+		AmountIDR:   expense.AmountIDR,
+		Category:    expense.Category,
+		Description: expense.Description,
+		HasReceipt:  expense.ReceiptURL != nil || expense.ReceiptID != nil,
+		CashAdvance: expense.CashAdvanceID != nil,
+		Role:        user.Role,
+		SubmittedAt: u.now(),
+	}
+
+	var violations []domain.PolicyViolation
+	for _, compiled := range rules {
+		if compiled.condition.Match(facts) {
+			violations = append(violations, domain.PolicyViolation{
+				RuleID:   compiled.rule.ID,
+				RuleName: compiled.rule.Name,
+				Action:   compiled.rule.Action,
+				Message:  compiled.rule.Message,
+			})
+		}
+	}
+	return violations, nil
+}
+
+func (u *policyUsecase) enabledRules(ctx context.Context) ([]compiledRule, error) {
+	u.mu.RLock()
+	if u.loaded && u.now().Sub(u.loadedAt) < policyCacheTTL {
+		rules := u.cache
+		u.mu.RUnlock()
+		return rules, nil
+	}
+	u.mu.RUnlock()
+
+	stored, err := u.ruleRepo.List(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make([]compiledRule, 0, len(stored))
+	for _, rule := range stored {
+		condition, err := policy.Parse(rule.Condition)
+		if err != nil {
+			// Rules are checked when saved, so this only happens when a
+			// field a rule uses was removed; the rest still apply.
+			logger.ErrorLogger.Printf("Skipping policy rule %d (%s): %v", rule.ID, rule.Name, err)
+			continue
+		}
+		rules = append(rules, compiledRule{rule: rule, condition: condition})
+	}
+
+	u.mu.Lock()
+	u.cache = rules
+	u.loaded = true
+	u.loadedAt = u.now()
+	u.mu.Unlock()
+
+	return rules, nil
+}
+
+func (u *policyUsecase) invalidate() {
+	u.mu.Lock()
+	u.cache = nil
+	u.loaded = false
+	u.mu.Unlock()
+}
+
+// audit records rule changes against the admin who made them, since audit
+// entries need a subject and a rule is not one.
+func (u *policyUsecase) audit(ctx context.Context, adminID int, action string, metadata map[string]interface{}) {
+	auditLog := &domain.AuditLog{
+		SubjectUserID: &adminID,
+		UserID:        &adminID,
+		Action:        action,
+		Metadata:      metadata,
+	}
+	u.auditRepo.Create(ctx, auditLog)
+}
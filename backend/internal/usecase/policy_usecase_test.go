@@ -0,0 +1,247 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"expense-management-system/internal/domain"
+	"strings"
+	"testing"
+	"time"
+)
+
+type mockPolicyRuleRepo struct {
+	rules     []*domain.PolicyRule
+	listCalls int
+}
+
+func (m *mockPolicyRuleRepo) Create(ctx context.Context, rule *domain.PolicyRule) error {
+	rule.ID = len(m.rules) + 1
+	saved := *rule
+	m.rules = append(m.rules, &saved)
+	return nil
+}
+
+func (m *mockPolicyRuleRepo) Update(ctx context.Context, rule *domain.PolicyRule) error {
+	for i, existing := range m.rules {
+		if existing.ID == rule.ID {
+			saved := *rule
+			m.rules[i] = &saved
+			return nil
+		}
+	}
+	return errors.New("policy rule not found")
+}
+
+func (m *mockPolicyRuleRepo) Delete(ctx context.Context, id int) error {
+	for i, existing := range m.rules {
+		if existing.ID == id {
+			m.rules = append(m.rules[:i], m.rules[i+1:]...)
+			return nil
+		}
+	}
+	return errors.New("policy rule not found")
+}
+
+func (m *mockPolicyRuleRepo) GetByID(ctx context.Context, id int) (*domain.PolicyRule, error) {
+	for _, existing := range m.rules {
+		if existing.ID == id {
+			found := *existing
+			return &found, nil
+		}
+	}
+	return nil, errors.New("policy rule not found")
+}
+
+func (m *mockPolicyRuleRepo) List(ctx context.Context, enabledOnly bool) ([]*domain.PolicyRule, error) {
+	m.listCalls++
+	var rules []*domain.PolicyRule
+	for _, existing := range m.rules {
+		if existing.Enabled || !enabledOnly {
+			found := *existing
+			rules = append(rules, &found)
+		}
+	}
+	return rules, nil
+}
+
+const (
+	mealsWithoutReceiptRule = `{"all": [{"field": "category", "op": "eq", "value": "meals"}, {"field": "amount_idr", "op": "gt", "value": 500000}, {"field": "has_receipt", "op": "eq", "value": false}]}`
+	weekendRule             = `{"field": "weekend", "op": "eq", "value": true}`
+	missingProjectCodeRule  = `{"not": {"field": "description", "op": "matches", "value": "\\bPRJ-[0-9]{4}\\b"}}`
+)
+
+func newTestPolicyUsecase(now time.Time) (*policyUsecase, *mockPolicyRuleRepo) {
+	repo := &mockPolicyRuleRepo{}
+	uc := NewPolicyUsecase(repo, &mockUserRepo{}, &mockAuditRepo{}).(*policyUsecase)
+	uc.now = func() time.Time { return now }
+	return uc, repo
+}
+
+func TestPolicyRuleValidation(t *testing.T) {
+	valid := func() *domain.PolicyRule {
+		return &domain.PolicyRule{
+			Name:      "Meals need a receipt",
+			Message:   "Meals over Rp 500.000 need a receipt",
+			Action:    domain.PolicyActionBlock,
+			Condition: []byte(mealsWithoutReceiptRule),
+		}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(rule *domain.PolicyRule)
+		wantErr string
+	}{
+		{name: "valid", modify: func(rule *domain.PolicyRule) {}},
+		{name: "missing name", modify: func(rule *domain.PolicyRule) { rule.Name = "  " }, wantErr: "name is required"},
+		{name: "missing message", modify: func(rule *domain.PolicyRule) { rule.Message = "" }, wantErr: "message is required"},
+		{name: "unknown action", modify: func(rule *domain.PolicyRule) { rule.Action = "reject" }, wantErr: "action must be"},
+		{name: "missing condition", modify: func(rule *domain.PolicyRule) { rule.Condition = nil }, wantErr: "condition is required"},
+		{name: "invalid condition", modify: func(rule *domain.PolicyRule) {
+			rule.Condition = []byte(`{"field": "merchant", "op": "eq", "value": "x"}`)
+		}, wantErr: "unknown field"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc, repo := newTestPolicyUsecase(time.Now())
+			rule := valid()
+			tt.modify(rule)
+
+			created, err := uc.Create(context.Background(), 1, rule)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Create() error = %v, want it to mention %q", err, tt.wantErr)
+				}
+				if len(repo.rules) != 0 {
+					t.Errorf("invalid rule was stored")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			if strings.Contains(string(created.Condition), " ") || created.CreatedBy != 1 {
+				t.Errorf("created rule = %+v, want a compacted condition created by admin 1", created)
+			}
+		})
+	}
+}
+
+func TestPolicyEvaluate(t *testing.T) {
+	saturday := time.Date(2025, 3, 15, 14, 30, 0, 0, time.Local)
+	ctx := context.Background()
+	uc, repo := newTestPolicyUsecase(saturday)
+
+	for _, rule := range []*domain.PolicyRule{
+		{Name: "Meals need a receipt", Message: "Attach a receipt", Action: domain.PolicyActionBlock, Condition: []byte(mealsWithoutReceiptRule), Enabled: true},
+		{Name: "Weekend", Message: "Weekend expenses need a manager", Action: domain.PolicyActionRequireApproval, Condition: []byte(weekendRule), Enabled: true},
+		{Name: "Project code", Message: "Include a project code", Action: domain.PolicyActionWarn, Condition: []byte(missingProjectCodeRule), Enabled: false},
+	} {
+		if _, err := uc.Create(ctx, 1, rule); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	expense := &domain.Expense{AmountIDR: 750000, Category: domain.CategoryMeals, Description: "Client dinner"}
+	violations, err := uc.Evaluate(ctx, 2, expense)
+	if err != nil {
+		t.Fatalf("Evaluate() error = %v", err)
+	}
+	if len(violations) != 2 || violations[0].RuleID != 1 || violations[0].Action != domain.PolicyActionBlock || violations[1].RuleID != 2 {
+		t.Errorf("Evaluate() = %+v, want the receipt and weekend rules", violations)
+	}
+
+	receiptID := 4
+	expense.ReceiptID = &receiptID
+	if violations, _ := uc.Evaluate(ctx, 2, expense); len(violations) != 1 || violations[0].RuleID != 2 {
+		t.Errorf("Evaluate() with a receipt = %+v, want only the weekend rule", violations)
+	}
+	if repo.listCalls != 1 {
+		t.Errorf("enabled rules loaded %d times, want them cached", repo.listCalls)
+	}
+
+	// Enabling a rule applies to the next submission.
+	if _, err := uc.Update(ctx, 1, 3, &domain.PolicyRule{Name: "Project code", Message: "Include a project code", Action: domain.PolicyActionWarn, Condition: []byte(missingProjectCodeRule), Enabled: true}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if violations, _ := uc.Evaluate(ctx, 2, expense); len(violations) != 2 || violations[1].RuleID != 3 {
+		t.Errorf("Evaluate() after enabling = %+v, want the weekend and project code rules", violations)
+	}
+}
+
+func TestSubmitAppliesPolicyRules(t *testing.T) {
+	tuesday := time.Date(2025, 3, 11, 9, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name         string
+		action       string
+		wantBlocked  bool
+		wantApproval bool
+	}{
+		{name: "block rejects the expense", action: domain.PolicyActionBlock, wantBlocked: true},
+		{name: "warn lets it through", action: domain.PolicyActionWarn},
+		{name: "require_approval waits for a manager", action: domain.PolicyActionRequireApproval, wantApproval: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			policies, _ := newTestPolicyUsecase(tuesday)
+			if _, err := policies.Create(ctx, 1, &domain.PolicyRule{Name: "Project code", Message: "Include a project code", Action: tt.action, Condition: []byte(missingProjectCodeRule), Enabled: true}); err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+
+			var audits []*domain.AuditLog
+			auditRepo := &mockAuditRepo{
+				createFunc: func(ctx context.Context, log *domain.AuditLog) error {
+					audits = append(audits, log)
+					return nil
+				},
+			}
+			created := false
+			expenseRepo := &mockExpenseRepo{
+				createFunc: func(ctx context.Context, expense *domain.Expense) error {
+					created = true
+					expense.ID = 1
+					return nil
+				},
+			}
+			paymentChan := make(chan PaymentJob, 1)
+
+			uc := NewExpenseUsecase(expenseRepo, &mockApprovalRepo{}, auditRepo, &mockUserRepo{}, &mockCashAdvanceRepo{}, paymentChan, nil, nil, nil, nil, policies, nil)
+
+			expense, err := uc.Submit(ctx, 2, 250000, "Team lunch", domain.CategoryMeals, nil, nil, nil)
+			if tt.wantBlocked {
+				var policyErr *domain.PolicyViolationError
+				if !errors.As(err, &policyErr) || len(policyErr.Violations) != 1 || policyErr.Violations[0].Message != "Include a project code" {
+					t.Fatalf("Submit() error = %v, want a policy violation", err)
+				}
+				if created {
+					t.Error("blocked expense was stored")
+				}
+				if len(audits) != 1 || audits[0].Action != domain.ActionSubmitBlocked || *audits[0].SubjectUserID != 2 {
+					t.Errorf("audits = %+v, want one submit_blocked entry for the submitter", audits)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Submit() error = %v", err)
+			}
+
+			if len(expense.PolicyViolations) != 1 || expense.PolicyViolations[0].Action != tt.action {
+				t.Errorf("PolicyViolations = %+v, want the project code rule", expense.PolicyViolations)
+			}
+			if len(audits) == 0 || audits[0].Metadata["policy_violations"] == nil {
+				t.Errorf("submit audit does not record the policy violations")
+			}
+			if tt.wantApproval {
+				if expense.AutoApproved || expense.Status != domain.StatusAwaitingApproval || len(paymentChan) != 0 {
+					t.Errorf("expense should wait for approval, got status %s", expense.Status)
+				}
+			} else if !expense.AutoApproved || len(paymentChan) != 1 {
+				t.Errorf("expense below the threshold should be auto-approved and paid, got status %s", expense.Status)
+			}
+		})
+	}
+}
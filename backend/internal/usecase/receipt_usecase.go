@@ -0,0 +1,158 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"expense-management-system/internal/domain"
+	"expense-management-system/pkg/config"
+	"expense-management-system/pkg/logger"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const maxReceiptSize = 5 << 20
+
+// receiptExtensions maps the accepted receipt content types, as sniffed
+// from the file itself, to the extension the stored file gets.
+var receiptExtensions = map[string]string{
+	"image/jpeg":      "jpg",
+	"image/png":       "png",
+	"image/gif":       "gif",
+	"image/webp":      "webp",
+	"application/pdf": "pdf",
+}
+
+type receiptUsecase struct {
+	receiptRepo domain.ReceiptRepository
+	store       domain.FileStore
+	fileURL     string
+
+	// extractor reads the fields off uploaded receipts; nil when OCR is
+	// off.
+	extractor      domain.ReceiptExtractor
+	extractTimeout time.Duration
+}
+
+func NewReceiptUsecase(receiptRepo domain.ReceiptRepository, store domain.FileStore, extractor domain.ReceiptExtractor, cfg *config.Config) domain.ReceiptUsecase {
+	return &receiptUsecase{
+		receiptRepo:    receiptRepo,
+		store:          store,
+		fileURL:        strings.TrimRight(cfg.PublicAPIURL, "/") + "/receipts/%d/file",
+		extractor:      extractor,
+		extractTimeout: time.Duration(cfg.ReceiptOCRTimeoutSeconds) * time.Second,
+	}
+}
+
+func (u *receiptUsecase) Upload(ctx context.Context, userID int, file io.Reader) (*domain.Receipt, error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err == io.EOF {
+		return nil, fmt.Errorf("%w: the file is empty", domain.ErrInvalidReceipt)
+	}
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	head = head[:n]
+
+	contentType := http.DetectContentType(head)
+	extension, ok := receiptExtensions[contentType]
+	if !ok {
+		return nil, fmt.Errorf("%w: must be a JPEG, PNG, GIF, WebP or PDF file", domain.ErrInvalidReceipt)
+	}
+
+	fileName := fmt.Sprintf("receipt-%s.%s", uuid.New().String(), extension)
+	out, err := u.store.Create(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha256.New()
+	content := io.LimitReader(io.MultiReader(bytes.NewReader(head), file), maxReceiptSize+1)
+	size, err := io.Copy(io.MultiWriter(out, hash), content)
+	if err == nil && size > maxReceiptSize {
+		err = fmt.Errorf("%w: must be at most %d MB", domain.ErrInvalidReceipt, maxReceiptSize>>20)
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		u.store.Remove(fileName)
+		return nil, err
+	}
+
+	receipt := &domain.Receipt{
+		UserID:      userID,
+		FileName:    fileName,
+		ContentType: contentType,
+		SizeBytes:   size,
+		SHA256:      hex.EncodeToString(hash.Sum(nil)),
+		Extraction:  u.extract(ctx, userID, fileName, contentType),
+	}
+	if err := u.receiptRepo.Create(ctx, receipt); err != nil {
+		u.store.Remove(fileName)
+		return nil, err
+	}
+	receipt.URL = fmt.Sprintf(u.fileURL, receipt.ID)
+
+	logger.InfoLogger.Printf("User %d uploaded receipt %d (%s, %d bytes)", userID, receipt.ID, contentType, size)
+	return receipt, nil
+}
+
+// extract reads the stored file for the fields to prefill. Receipts that
+// cannot be read are kept all the same, without them.
+func (u *receiptUsecase) extract(ctx context.Context, userID int, fileName, contentType string) *domain.ReceiptExtraction {
+	if u.extractor == nil {
+		return nil
+	}
+
+	file, err := u.store.Open(fileName)
+	if err != nil {
+		logger.ErrorLogger.Printf("Failed to open receipt %s for extraction: %v", fileName, err)
+		return nil
+	}
+	defer file.Close()
+
+	if u.extractTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, u.extractTimeout)
+		defer cancel()
+	}
+	extraction, err := u.extractor.Extract(ctx, contentType, file)
+	if err != nil {
+		logger.ErrorLogger.Printf("Failed to read receipt %s uploaded by user %d: %v", fileName, userID, err)
+		return nil
+	}
+	return extraction
+}
+
+func (u *receiptUsecase) Get(ctx context.Context, userID, id int, canViewAll bool) (*domain.Receipt, error) {
+	receipt, err := u.receiptRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !canViewAll && receipt.UserID != userID {
+		return nil, errors.New("receipt not found")
+	}
+	receipt.URL = fmt.Sprintf(u.fileURL, receipt.ID)
+	return receipt, nil
+}
+
+func (u *receiptUsecase) Open(ctx context.Context, userID, id int, canViewAll bool) (*domain.Receipt, io.ReadCloser, error) {
+	receipt, err := u.Get(ctx, userID, id, canViewAll)
+	if err != nil {
+		return nil, nil, err
+	}
+	file, err := u.store.Open(receipt.FileName)
+	if err != nil {
+		return nil, nil, err
+	}
+	return receipt, file, nil
+}
@@ -0,0 +1,228 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"expense-management-system/internal/domain"
+	"expense-management-system/internal/ocr/ocrtest"
+	"expense-management-system/pkg/config"
+	"io"
+	"strings"
+	"testing"
+)
+
+type mockReceiptRepo struct {
+	receipts map[int]*domain.Receipt
+}
+
+func (m *mockReceiptRepo) Create(ctx context.Context, receipt *domain.Receipt) error {
+	receipt.ID = len(m.receipts) + 1
+	saved := *receipt
+	m.receipts[receipt.ID] = &saved
+	return nil
+}
+
+func (m *mockReceiptRepo) GetByID(ctx context.Context, id int) (*domain.Receipt, error) {
+	receipt, ok := m.receipts[id]
+	if !ok {
+		return nil, errors.New("receipt not found")
+	}
+	found := *receipt
+	return &found, nil
+}
+
+func newTestReceiptUsecase() (*receiptUsecase, *memoryStore) {
+	store := &memoryStore{files: map[string]*bytes.Buffer{}}
+	uc := NewReceiptUsecase(&mockReceiptRepo{receipts: map[int]*domain.Receipt{}}, store, nil, &config.Config{PublicAPIURL: "https://expenses.example.com/api/"})
+	return uc.(*receiptUsecase), store
+}
+
+const pngHeader = "\x89PNG\r\n\x1a\n"
+
+func TestReceiptUpload(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		wantType    string
+		wantInvalid bool
+	}{
+		{name: "png", content: pngHeader + "image data", wantType: "image/png"},
+		{name: "pdf", content: "%PDF-1.4\n" + strings.Repeat("x", 2000), wantType: "application/pdf"},
+		{name: "text is refused", content: "just some text", wantInvalid: true},
+		{name: "html is refused", content: "<html><body>receipt</body></html>", wantInvalid: true},
+		{name: "empty is refused", content: "", wantInvalid: true},
+		{name: "over 5 MB is refused", content: pngHeader + strings.Repeat("x", maxReceiptSize), wantInvalid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc, store := newTestReceiptUsecase()
+
+			receipt, err := uc.Upload(context.Background(), 7, strings.NewReader(tt.content))
+			if tt.wantInvalid {
+				if !errors.Is(err, domain.ErrInvalidReceipt) {
+					t.Fatalf("Upload() error = %v, want ErrInvalidReceipt", err)
+				}
+				if len(store.files) != 0 {
+					t.Errorf("Upload() left %d files behind", len(store.files))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Upload() error = %v", err)
+			}
+
+			sum := sha256.Sum256([]byte(tt.content))
+			if receipt.SHA256 != hex.EncodeToString(sum[:]) {
+				t.Errorf("SHA256 = %s, want the digest of the content", receipt.SHA256)
+			}
+			if receipt.ContentType != tt.wantType || receipt.SizeBytes != int64(len(tt.content)) || receipt.UserID != 7 {
+				t.Errorf("receipt = %+v", receipt)
+			}
+			if receipt.URL != "https://expenses.example.com/api/receipts/1/file" {
+				t.Errorf("URL = %s", receipt.URL)
+			}
+			if got := store.files[receipt.FileName]; got == nil || got.String() != tt.content {
+				t.Errorf("stored file does not match the upload")
+			}
+		})
+	}
+}
+
+func TestReceiptOpen(t *testing.T) {
+	uc, _ := newTestReceiptUsecase()
+	ctx := context.Background()
+
+	uploaded, err := uc.Upload(ctx, 7, strings.NewReader(pngHeader+"image data"))
+	if err != nil {
+		t.Fatalf("Upload() error = %v", err)
+	}
+
+	receipt, file, err := uc.Open(ctx, 7, uploaded.ID, false)
+	if err != nil {
+		t.Fatalf("Open() by the uploader error = %v", err)
+	}
+	content, _ := io.ReadAll(file)
+	file.Close()
+	if string(content) != pngHeader+"image data" || receipt.URL != uploaded.URL {
+		t.Errorf("Open() = %+v, %q", receipt, content)
+	}
+
+	if _, _, err := uc.Open(ctx, 8, uploaded.ID, false); err == nil || err.Error() != "receipt not found" {
+		t.Errorf("Open() by another employee error = %v, want receipt not found", err)
+	}
+	if _, file, err := uc.Open(ctx, 8, uploaded.ID, true); err != nil {
+		t.Errorf("Open() with canViewAll error = %v", err)
+	} else {
+		file.Close()
+	}
+}
+
+func TestReceiptUploadExtracts(t *testing.T) {
+	total, merchant := 185000.0, "Warteg Bahari"
+	tests := []struct {
+		name      string
+		extractor *ocrtest.Extractor
+		want      *domain.ReceiptExtraction
+	}{
+		{
+			name:      "Fields read off the receipt are kept",
+			extractor: &ocrtest.Extractor{Extraction: &domain.ReceiptExtraction{Total: &total, Merchant: &merchant}},
+			want:      &domain.ReceiptExtraction{Total: &total, Merchant: &merchant},
+		},
+		{
+			name:      "Unreadable receipt is still uploaded",
+			extractor: &ocrtest.Extractor{Err: errors.New("tesseract: exit status 1")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockReceiptRepo{receipts: map[int]*domain.Receipt{}}
+			store := &memoryStore{files: map[string]*bytes.Buffer{}}
+			uc := NewReceiptUsecase(repo, store, tt.extractor, &config.Config{ReceiptOCRTimeoutSeconds: 5})
+
+			receipt, err := uc.Upload(context.Background(), 7, strings.NewReader(pngHeader+"receipt image"))
+			if err != nil {
+				t.Fatalf("Upload() error = %v", err)
+			}
+			if len(tt.extractor.ContentTypes) != 1 || tt.extractor.ContentTypes[0] != "image/png" {
+				t.Errorf("extractor read %v, want the PNG once", tt.extractor.ContentTypes)
+			}
+
+			stored := repo.receipts[receipt.ID].Extraction
+			if tt.want == nil {
+				if stored != nil {
+					t.Errorf("Extraction = %+v, want none", stored)
+				}
+				return
+			}
+			if stored == nil || *stored.Total != *tt.want.Total || *stored.Merchant != *tt.want.Merchant {
+				t.Errorf("Extraction = %+v, want %+v", stored, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubmitComparesReceiptTotal(t *testing.T) {
+	usd := "USD"
+	idr := domain.CurrencyIDR
+	tests := []struct {
+		name         string
+		total        float64
+		currency     *string
+		amountIDR    int
+		wantMismatch bool
+	}{
+		{name: "Matching total", total: 250000, amountIDR: 250000},
+		{name: "Rounded total matches", total: 249999.60, currency: &idr, amountIDR: 250000},
+		{name: "Different total is flagged", total: 185000, amountIDR: 250000, wantMismatch: true},
+		{name: "Total in another currency is not compared", total: 16.50, currency: &usd, amountIDR: 250000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			total := tt.total
+			extractor := &ocrtest.Extractor{Extraction: &domain.ReceiptExtraction{Total: &total, Currency: tt.currency}}
+			receipts := NewReceiptUsecase(&mockReceiptRepo{receipts: map[int]*domain.Receipt{}}, &memoryStore{files: map[string]*bytes.Buffer{}}, extractor, &config.Config{})
+			receipt, err := receipts.Upload(ctx, 1, strings.NewReader(pngHeader+"taxi receipt"))
+			if err != nil {
+				t.Fatalf("Upload() error = %v", err)
+			}
+
+			var submitAudit *domain.AuditLog
+			auditRepo := &mockAuditRepo{
+				createFunc: func(ctx context.Context, log *domain.AuditLog) error {
+					submitAudit = log
+					return nil
+				},
+			}
+			uc := NewExpenseUsecase(&mockExpenseRepo{}, &mockApprovalRepo{}, auditRepo, &mockUserRepo{}, &mockCashAdvanceRepo{}, make(chan PaymentJob, 1), nil, nil, nil, receipts, nil, nil)
+
+			expense, err := uc.Submit(ctx, 1, tt.amountIDR, "Taxi to airport", domain.CategoryTravel, nil, &receipt.ID, nil)
+			if err != nil {
+				t.Fatalf("Submit() error = %v", err)
+			}
+			if expense.ReceiptExtraction == nil || *expense.ReceiptExtraction.Total != tt.total {
+				t.Errorf("ReceiptExtraction = %+v, want the receipt's", expense.ReceiptExtraction)
+			}
+			if expense.ReceiptAmountMismatch != tt.wantMismatch {
+				t.Errorf("ReceiptAmountMismatch = %v, want %v", expense.ReceiptAmountMismatch, tt.wantMismatch)
+			}
+			if _, ok := submitAudit.Metadata["receipt_amount_mismatch"]; ok != tt.wantMismatch {
+				t.Errorf("submit audit metadata = %v", submitAudit.Metadata)
+			}
+			// Below the threshold, only a mismatch holds the expense back.
+			if expense.AutoApproved == tt.wantMismatch {
+				t.Errorf("AutoApproved = %v with ReceiptAmountMismatch %v", expense.AutoApproved, tt.wantMismatch)
+			}
+			if tt.wantMismatch && expense.Status != domain.StatusAwaitingApproval {
+				t.Errorf("Status = %v, want %v", expense.Status, domain.StatusAwaitingApproval)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"expense-management-system/internal/domain"
+	"expense-management-system/pkg/logger"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+type refundUsecase struct {
+	refundRepo     domain.RefundRepository
+	expenseRepo    domain.ExpenseRepository
+	auditRepo      domain.AuditLogRepository
+	userRepo       domain.UserRepository
+	paymentService domain.PaymentService
+}
+
+func NewRefundUsecase(
+	refundRepo domain.RefundRepository,
+	expenseRepo domain.ExpenseRepository,
+	auditRepo domain.AuditLogRepository,
+	userRepo domain.UserRepository,
+	paymentService domain.PaymentService,
+) domain.RefundUsecase {
+	return &refundUsecase{
+		refundRepo:     refundRepo,
+		expenseRepo:    expenseRepo,
+		auditRepo:      auditRepo,
+		userRepo:       userRepo,
+		paymentService: paymentService,
+	}
+}
+
+// Initiate records money coming back for a paid expense. A refund is money the
+// employee returned and is recorded as completed straight away; a reversal
+// asks the payment provider to pull the original payment back.
+func (u *refundUsecase) Initiate(ctx context.Context, userID, expenseID int, refundType string, amountIDR int, reason string) (*domain.Refund, error) {
+	if refundType != domain.RefundTypeRefund && refundType != domain.RefundTypeReversal {
+		return nil, fmt.Errorf("refund type must be %q or %q", domain.RefundTypeRefund, domain.RefundTypeReversal)
+	}
+
+	if reason == "" {
+		return nil, errors.New("reason is required")
+	}
+
+	expense, err := u.expenseRepo.GetByID(ctx, expenseID)
+	if err != nil {
+		return nil, err
+	}
+
+	if expense.Status != domain.StatusCompleted && expense.Status != domain.StatusPartiallyRefunded {
+		return nil, errors.New("only completed expenses can be refunded")
+	}
+
+	if expense.PaymentID == nil || *expense.PaymentID == "" {
+		return nil, errors.New("expense has no payment to refund")
+	}
+
+	if amountIDR <= 0 {
+		return nil, errors.New("refund amount must be positive")
+	}
+
+	refund := &domain.Refund{
+		ExpenseID:   expenseID,
+		PaymentID:   *expense.PaymentID,
+		Type:        refundType,
+		AmountIDR:   amountIDR,
+		Reason:      reason,
+		Status:      domain.RefundStatusPending,
+		ExternalID:  uuid.New().String(),
+		InitiatedBy: userID,
+	}
+
+	// The repository checks the balance, counting pending refunds, under a
+	// lock of the expense so concurrent requests cannot both claim it.
+	remaining, err := u.refundRepo.Create(ctx, refund)
+	if errors.Is(err, domain.ErrRefundExceedsBalance) {
+		return nil, fmt.Errorf("refund amount must be between IDR 1 and IDR %d", remaining)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if refundType == domain.RefundTypeReversal {
+		providerRefundID, err := u.paymentService.ProcessRefund(ctx, refund.PaymentID, amountIDR, refund.ExternalID)
+		if err != nil {
+			logger.ErrorLogger.Printf("Reversal %d for expense %d failed: %v", refund.ID, expenseID, err)
+			u.refundRepo.UpdateStatus(ctx, refund.ID, domain.RefundStatusFailed, nil)
+			refund.Status = domain.RefundStatusFailed
+			u.audit(ctx, userID, expense, expense.Status, refund, err)
+			return refund, fmt.Errorf("payment provider rejected reversal: %w", err)
+		}
+		if providerRefundID != "" {
+			refund.ProviderRefundID = &providerRefundID
+		}
+	}
+
+	// Other refunds of the expense may still be in flight, so the expense
+	// status comes from the refunds completed by now, not from remaining.
+	newStatus, err := u.refundRepo.Complete(ctx, refund.ID, refund.ProviderRefundID)
+	if err != nil {
+		return nil, err
+	}
+	refund.Status = domain.RefundStatusCompleted
+
+	u.audit(ctx, userID, expense, newStatus, refund, nil)
+
+	logger.InfoLogger.Printf("%s %d of IDR %d recorded for expense %d by user %d", refundType, refund.ID, amountIDR, expenseID, userID)
+
+	user, _ := u.userRepo.GetByID(ctx, expense.UserID)
+	if user != nil {
+		logger.InfoLogger.Printf("[EMAIL] Refund notification sent to %s for expense %d (IDR %d)", user.Email, expenseID, amountIDR)
+	}
+
+	return refund, nil
+}
+
+func (u *refundUsecase) audit(ctx context.Context, userID int, expense *domain.Expense, newStatus string, refund *domain.Refund, failure error) {
+	oldStatus := expense.Status
+	metadata := map[string]interface{}{
+		"refund_id":   refund.ID,
+		"type":        refund.Type,
+		"amount_idr":  refund.AmountIDR,
+		"reason":      refund.Reason,
+		"payment_id":  refund.PaymentID,
+		"external_id": refund.ExternalID,
+		"status":      refund.Status,
+	}
+	if refund.ProviderRefundID != nil {
+		metadata["provider_refund_id"] = *refund.ProviderRefundID
+	}
+	if failure != nil {
+		metadata["error"] = failure.Error()
+	}
+
+	auditLog := &domain.AuditLog{
+		ExpenseID: expense.ID,
+		UserID:    &userID,
+		Action:    domain.ActionRefund,
+		OldStatus: &oldStatus,
+		NewStatus: &newStatus,
+		Metadata:  metadata,
+	}
+	u.auditRepo.Create(ctx, auditLog)
+}
+
+func (u *refundUsecase) GetByExpenseID(ctx context.Context, userID, expenseID int, canViewAll bool) ([]*domain.Refund, error) {
+	expense, err := u.expenseRepo.GetByID(ctx, expenseID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !canViewAll && expense.UserID != userID {
+		return nil, errors.New("unauthorized access to expense")
+	}
+
+	return u.refundRepo.GetByExpenseID(ctx, expenseID)
+}
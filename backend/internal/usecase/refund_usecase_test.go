@@ -0,0 +1,353 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"expense-management-system/internal/domain"
+	"sync"
+	"testing"
+)
+
+// mockRefundRepo checks the balance of an expense of amountIDR under a lock,
+// as the database does with the expense row.
+type mockRefundRepo struct {
+	mu        sync.Mutex
+	amountIDR int
+	refunds   []*domain.Refund
+	statuses  map[int]string
+
+	expenseStatus string
+}
+
+func (m *mockRefundRepo) Create(ctx context.Context, refund *domain.Refund) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	remaining := m.amountIDR
+	for _, r := range m.refunds {
+		if r.Status != domain.RefundStatusFailed {
+			remaining -= r.AmountIDR
+		}
+	}
+	if refund.AmountIDR > remaining {
+		return remaining, domain.ErrRefundExceedsBalance
+	}
+
+	refund.ID = len(m.refunds) + 1
+	saved := *refund
+	m.refunds = append(m.refunds, &saved)
+	return remaining, nil
+}
+
+func (m *mockRefundRepo) UpdateStatus(ctx context.Context, id int, status string, providerRefundID *string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.statuses == nil {
+		m.statuses = map[int]string{}
+	}
+	m.statuses[id] = status
+	for _, r := range m.refunds {
+		if r.ID == id {
+			r.Status = status
+		}
+	}
+	return nil
+}
+
+func (m *mockRefundRepo) Complete(ctx context.Context, id int, providerRefundID *string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	refunded := 0
+	for _, r := range m.refunds {
+		if r.ID == id {
+			r.Status = domain.RefundStatusCompleted
+		}
+		if r.Status == domain.RefundStatusCompleted {
+			refunded += r.AmountIDR
+		}
+	}
+	m.expenseStatus = domain.StatusPartiallyRefunded
+	if refunded >= m.amountIDR {
+		m.expenseStatus = domain.StatusRefunded
+	}
+	return m.expenseStatus, nil
+}
+
+func (m *mockRefundRepo) GetByExpenseID(ctx context.Context, expenseID int) ([]*domain.Refund, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.refunds, nil
+}
+
+type mockPaymentService struct {
+	refundFunc func(ctx context.Context, paymentID string, amount int, externalID string) (string, error)
+	calls      int
+}
+
+func (m *mockPaymentService) ProcessPayment(ctx context.Context, expenseID int, amount int, externalID string) (string, error) {
+	return "pay-1", nil
+}
+
+func (m *mockPaymentService) ProcessRefund(ctx context.Context, paymentID string, amount int, externalID string) (string, error) {
+	m.calls++
+	if m.refundFunc != nil {
+		return m.refundFunc(ctx, paymentID, amount, externalID)
+	}
+	return "rf-1", nil
+}
+
+func TestRefundUsecase_Initiate(t *testing.T) {
+	tests := []struct {
+		name          string
+		expenseStatus string
+		paymentID     *string
+		existing      []*domain.Refund
+		refundType    string
+		amountIDR     int
+		reason        string
+		providerErr   error
+		wantErr       bool
+		wantStatus    string
+		wantProvider  int
+	}{
+		{
+			name:          "Full employee refund marks expense refunded",
+			expenseStatus: domain.StatusCompleted,
+			paymentID:     strPtr("pay-1"),
+			refundType:    domain.RefundTypeRefund,
+			amountIDR:     1500000,
+			reason:        "Trip cancelled",
+			wantStatus:    domain.StatusRefunded,
+			wantProvider:  0,
+		},
+		{
+			name:          "Partial reversal calls provider",
+			expenseStatus: domain.StatusCompleted,
+			paymentID:     strPtr("pay-1"),
+			refundType:    domain.RefundTypeReversal,
+			amountIDR:     500000,
+			reason:        "Duplicate payout",
+			wantStatus:    domain.StatusPartiallyRefunded,
+			wantProvider:  1,
+		},
+		{
+			name:          "Earlier refunds reduce remaining balance",
+			expenseStatus: domain.StatusPartiallyRefunded,
+			paymentID:     strPtr("pay-1"),
+			existing: []*domain.Refund{
+				{ID: 1, AmountIDR: 1000000, Status: domain.RefundStatusCompleted},
+				{ID: 2, AmountIDR: 400000, Status: domain.RefundStatusFailed},
+			},
+			refundType: domain.RefundTypeRefund,
+			amountIDR:  500000,
+			reason:     "Remaining balance",
+			wantStatus: domain.StatusRefunded,
+		},
+		{
+			name:          "Refund still in flight keeps expense partially refunded",
+			expenseStatus: domain.StatusCompleted,
+			paymentID:     strPtr("pay-1"),
+			existing: []*domain.Refund{
+				{ID: 1, AmountIDR: 500000, Status: domain.RefundStatusPending},
+			},
+			refundType: domain.RefundTypeRefund,
+			amountIDR:  1000000,
+			reason:     "Remaining balance",
+			wantStatus: domain.StatusPartiallyRefunded,
+		},
+		{
+			name:          "Cannot refund more than remaining",
+			expenseStatus: domain.StatusPartiallyRefunded,
+			paymentID:     strPtr("pay-1"),
+			existing: []*domain.Refund{
+				{ID: 1, AmountIDR: 1000000, Status: domain.RefundStatusCompleted},
+			},
+			refundType: domain.RefundTypeRefund,
+			amountIDR:  600000,
+			reason:     "Too much",
+			wantErr:    true,
+		},
+		{
+			name:          "Cannot refund unpaid expense",
+			expenseStatus: domain.StatusApproved,
+			refundType:    domain.RefundTypeRefund,
+			amountIDR:     100000,
+			reason:        "Not paid yet",
+			wantErr:       true,
+		},
+		{
+			name:          "Unknown refund type",
+			expenseStatus: domain.StatusCompleted,
+			paymentID:     strPtr("pay-1"),
+			refundType:    "chargeback",
+			amountIDR:     100000,
+			reason:        "Bank dispute",
+			wantErr:       true,
+		},
+		{
+			name:          "Reason is required",
+			expenseStatus: domain.StatusCompleted,
+			paymentID:     strPtr("pay-1"),
+			refundType:    domain.RefundTypeRefund,
+			amountIDR:     100000,
+			wantErr:       true,
+		},
+		{
+			name:          "Provider failure records failed reversal",
+			expenseStatus: domain.StatusCompleted,
+			paymentID:     strPtr("pay-1"),
+			refundType:    domain.RefundTypeReversal,
+			amountIDR:     100000,
+			reason:        "Reverse payout",
+			providerErr:   errors.New("payment failed with status 422"),
+			wantErr:       true,
+			wantProvider:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			expenseRepo := &mockExpenseRepo{
+				getByIDFunc: func(ctx context.Context, id int) (*domain.Expense, error) {
+					return &domain.Expense{
+						ID:        id,
+						UserID:    1,
+						AmountIDR: 1500000,
+						Status:    tt.expenseStatus,
+						PaymentID: tt.paymentID,
+					}, nil
+				},
+			}
+
+			var audited *domain.AuditLog
+			auditRepo := &mockAuditRepo{
+				createFunc: func(ctx context.Context, log *domain.AuditLog) error {
+					audited = log
+					return nil
+				},
+			}
+
+			refundRepo := &mockRefundRepo{amountIDR: 1500000, refunds: tt.existing}
+			paymentService := &mockPaymentService{
+				refundFunc: func(ctx context.Context, paymentID string, amount int, externalID string) (string, error) {
+					return "rf-1", tt.providerErr
+				},
+			}
+
+			uc := NewRefundUsecase(refundRepo, expenseRepo, auditRepo, &mockUserRepo{}, paymentService)
+
+			refund, err := uc.Initiate(ctx, 4, 1, tt.refundType, tt.amountIDR, tt.reason)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Initiate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if paymentService.calls != tt.wantProvider {
+				t.Errorf("Provider called %d times, want %d", paymentService.calls, tt.wantProvider)
+			}
+
+			if tt.providerErr != nil {
+				if refund == nil || refund.Status != domain.RefundStatusFailed {
+					t.Errorf("Expected failed refund to be returned, got %+v", refund)
+				}
+				if audited == nil || audited.Action != domain.ActionRefund {
+					t.Error("Expected failed reversal to be audited")
+				}
+				return
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if refund.Status != domain.RefundStatusCompleted {
+				t.Errorf("Refund status = %v, want %v", refund.Status, domain.RefundStatusCompleted)
+			}
+
+			if refundRepo.expenseStatus != tt.wantStatus {
+				t.Errorf("Expense status = %v, want %v", refundRepo.expenseStatus, tt.wantStatus)
+			}
+
+			if audited == nil || audited.Action != domain.ActionRefund || *audited.NewStatus != tt.wantStatus {
+				t.Errorf("Expected refund audit log with new status %v, got %+v", tt.wantStatus, audited)
+			}
+		})
+	}
+}
+
+func TestRefundUsecase_InitiateConcurrently(t *testing.T) {
+	expenseRepo := &mockExpenseRepo{
+		getByIDFunc: func(ctx context.Context, id int) (*domain.Expense, error) {
+			return &domain.Expense{
+				ID:        id,
+				UserID:    1,
+				AmountIDR: 1500000,
+				Status:    domain.StatusCompleted,
+				PaymentID: strPtr("pay-1"),
+			}, nil
+		},
+	}
+	refundRepo := &mockRefundRepo{amountIDR: 1500000}
+	uc := NewRefundUsecase(refundRepo, expenseRepo, &mockAuditRepo{}, &mockUserRepo{}, &mockPaymentService{})
+
+	// Ten finance users refund IDR 500,000 each at once; only three fit.
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := uc.Initiate(context.Background(), 4, 1, domain.RefundTypeRefund, 500000, "Trip cancelled"); err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	refunded := 0
+	for _, r := range refundRepo.refunds {
+		refunded += r.AmountIDR
+	}
+	if succeeded != 3 || refunded != 1500000 {
+		t.Errorf("%d refunds of IDR %d in total succeeded, want 3 of the full IDR 1500000", succeeded, refunded)
+	}
+	if refundRepo.expenseStatus != domain.StatusRefunded {
+		t.Errorf("Expense status = %v, want %v", refundRepo.expenseStatus, domain.StatusRefunded)
+	}
+}
+
+func TestRefundUsecase_GetByExpenseID(t *testing.T) {
+	expenseRepo := &mockExpenseRepo{
+		getByIDFunc: func(ctx context.Context, id int) (*domain.Expense, error) {
+			return &domain.Expense{ID: id, UserID: 1}, nil
+		},
+	}
+	uc := NewRefundUsecase(&mockRefundRepo{}, expenseRepo, &mockAuditRepo{}, &mockUserRepo{}, &mockPaymentService{})
+
+	tests := []struct {
+		name       string
+		userID     int
+		canViewAll bool
+		wantErr    bool
+	}{
+		{name: "Submitter can view", userID: 1},
+		{name: "Finance can view", userID: 4, canViewAll: true},
+		{name: "Other employee cannot view", userID: 2, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := uc.GetByExpenseID(context.Background(), tt.userID, 1, tt.canViewAll)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetByExpenseID() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
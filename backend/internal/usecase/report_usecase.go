@@ -0,0 +1,377 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"expense-management-system/internal/cron"
+	"expense-management-system/internal/domain"
+	"expense-management-system/internal/export"
+	"expense-management-system/pkg/config"
+	"expense-management-system/pkg/logger"
+	"fmt"
+	"io"
+	"math"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+const maxReportRecipients = 20
+
+type reportUsecase struct {
+	reportRepo    domain.ReportRepository
+	expenseRepo   domain.ExpenseRepository
+	analyticsRepo domain.AnalyticsRepository
+	store         domain.FileStore
+	notifier      domain.Notifier
+	downloadURL   string
+	now           func() time.Time
+}
+
+func NewReportUsecase(reportRepo domain.ReportRepository, expenseRepo domain.ExpenseRepository, analyticsRepo domain.AnalyticsRepository, store domain.FileStore, notifier domain.Notifier, cfg *config.Config) domain.ReportUsecase {
+	return &reportUsecase{
+		reportRepo:    reportRepo,
+		expenseRepo:   expenseRepo,
+		analyticsRepo: analyticsRepo,
+		store:         store,
+		notifier:      notifier,
+		downloadURL:   strings.TrimRight(cfg.PublicAPIURL, "/") + "/reports/runs/%d/download",
+		now:           time.Now,
+	}
+}
+
+// prepareSchedule validates a schedule and sets when it runs next.
+func (u *reportUsecase) prepareSchedule(schedule *domain.ReportSchedule) error {
+	schedule.Name = strings.TrimSpace(schedule.Name)
+	if schedule.Name == "" || len(schedule.Name) > 100 {
+		return errors.New("name is required and at most 100 characters")
+	}
+
+	cronSchedule, err := cron.Parse(schedule.Cron)
+	if err != nil {
+		return err
+	}
+	if schedule.Period == "" {
+		schedule.Period = domain.ReportPeriodMonth
+	}
+	if !domain.IsValidReportPeriod(schedule.Period) {
+		return fmt.Errorf("unknown period %q", schedule.Period)
+	}
+	if schedule.GroupBy != "" && !domain.IsValidAnalyticsDimension(schedule.GroupBy) {
+		return fmt.Errorf("cannot group by %q", schedule.GroupBy)
+	}
+	if schedule.Format == "" {
+		schedule.Format = export.FormatCSV
+	}
+	if _, err := export.LookupDocumentFormat(schedule.Format); err != nil {
+		return err
+	}
+	if err := validateExpenseFilter(reportFilter(schedule.Filters)); err != nil {
+		return err
+	}
+
+	if len(schedule.Recipients) > maxReportRecipients {
+		return fmt.Errorf("at most %d recipients", maxReportRecipients)
+	}
+	recipients := make([]string, 0, len(schedule.Recipients))
+	for _, recipient := range schedule.Recipients {
+		address, err := mail.ParseAddress(recipient)
+		if err != nil || address.Address != strings.TrimSpace(recipient) {
+			return fmt.Errorf("invalid recipient %q", recipient)
+		}
+		recipients = append(recipients, address.Address)
+	}
+	schedule.Recipients = recipients
+
+	schedule.NextRunAt = nil
+	if schedule.Enabled {
+		next := cronSchedule.Next(u.now())
+		if next.IsZero() {
+			return fmt.Errorf("cron expression %q never fires", schedule.Cron)
+		}
+		schedule.NextRunAt = &next
+	}
+	return nil
+}
+
+func (u *reportUsecase) CreateSchedule(ctx context.Context, userID int, schedule *domain.ReportSchedule) (*domain.ReportSchedule, error) {
+	if err := u.prepareSchedule(schedule); err != nil {
+		return nil, err
+	}
+	schedule.CreatedBy = userID
+
+	if err := u.reportRepo.CreateSchedule(ctx, schedule); err != nil {
+		return nil, err
+	}
+
+	logger.InfoLogger.Printf("Report schedule %d (%s, %q) created by user %d", schedule.ID, schedule.Name, schedule.Cron, userID)
+	return schedule, nil
+}
+
+func (u *reportUsecase) UpdateSchedule(ctx context.Context, userID, id int, schedule *domain.ReportSchedule) (*domain.ReportSchedule, error) {
+	existing, err := u.reportRepo.GetSchedule(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.prepareSchedule(schedule); err != nil {
+		return nil, err
+	}
+	schedule.ID = existing.ID
+	schedule.LastRunAt = existing.LastRunAt
+	schedule.CreatedBy = existing.CreatedBy
+	schedule.CreatedAt = existing.CreatedAt
+
+	if err := u.reportRepo.UpdateSchedule(ctx, schedule); err != nil {
+		return nil, err
+	}
+
+	logger.InfoLogger.Printf("Report schedule %d (%s, %q) updated by user %d", schedule.ID, schedule.Name, schedule.Cron, userID)
+	return schedule, nil
+}
+
+func (u *reportUsecase) DeleteSchedule(ctx context.Context, userID, id int) error {
+	if err := u.reportRepo.DeleteSchedule(ctx, id); err != nil {
+		return err
+	}
+
+	logger.InfoLogger.Printf("Report schedule %d deleted by user %d", id, userID)
+	return nil
+}
+
+func (u *reportUsecase) GetSchedule(ctx context.Context, id int) (*domain.ReportSchedule, error) {
+	return u.reportRepo.GetSchedule(ctx, id)
+}
+
+func (u *reportUsecase) ListSchedules(ctx context.Context) ([]*domain.ReportSchedule, error) {
+	return u.reportRepo.ListSchedules(ctx)
+}
+
+func (u *reportUsecase) RunNow(ctx context.Context, userID, id int) (*domain.ReportRun, error) {
+	schedule, err := u.reportRepo.GetSchedule(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return u.run(ctx, schedule, domain.ReportTriggerManual, &userID)
+}
+
+// RunDue claims each due schedule before running it, so with several API
+// instances a report runs once. Runs missed while the API was down are
+// made up for with a single run.
+func (u *reportUsecase) RunDue(ctx context.Context, now time.Time) error {
+	schedules, err := u.reportRepo.GetDue(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	for _, schedule := range schedules {
+		var next *time.Time
+		if cronSchedule, err := cron.Parse(schedule.Cron); err == nil {
+			if t := cronSchedule.Next(now); !t.IsZero() {
+				next = &t
+			}
+		}
+
+		claimed, err := u.reportRepo.ClaimRun(ctx, schedule.ID, *schedule.NextRunAt, next)
+		if err != nil {
+			logger.ErrorLogger.Printf("Failed to claim run of report schedule %d: %v", schedule.ID, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		if _, err := u.run(ctx, schedule, domain.ReportTriggerSchedule, nil); err != nil {
+			logger.ErrorLogger.Printf("Failed to run report schedule %d: %v", schedule.ID, err)
+		}
+	}
+	return nil
+}
+
+// run generates a report and sends its link to the recipients. A report
+// that fails to generate is recorded as a failed run rather than returned
+// as an error.
+func (u *reportUsecase) run(ctx context.Context, schedule *domain.ReportSchedule, trigger string, triggeredBy *int) (*domain.ReportRun, error) {
+	run := &domain.ReportRun{
+		ScheduleID:  &schedule.ID,
+		ReportName:  schedule.Name,
+		Trigger:     trigger,
+		TriggeredBy: triggeredBy,
+		Status:      domain.ReportRunRunning,
+		Format:      schedule.Format,
+	}
+	run.PeriodStart, run.PeriodEnd = reportPeriod(schedule.Period, u.now())
+
+	if err := u.reportRepo.CreateRun(ctx, run); err != nil {
+		return nil, err
+	}
+
+	fileName := fmt.Sprintf("report-%d.%s", run.ID, schedule.Format)
+	err := u.render(ctx, schedule, run, fileName)
+	finishedAt := u.now()
+	run.FinishedAt = &finishedAt
+	if err != nil {
+		logger.ErrorLogger.Printf("Report run %d of schedule %d failed: %v", run.ID, schedule.ID, err)
+		u.store.Remove(fileName)
+		message := err.Error()
+		run.Status = domain.ReportRunFailed
+		run.Error = &message
+	} else {
+		run.Status = domain.ReportRunSucceeded
+		run.FileName = &fileName
+	}
+
+	if err := u.reportRepo.FinishRun(ctx, run); err != nil {
+		return nil, err
+	}
+
+	if run.Status == domain.ReportRunSucceeded {
+		u.deliver(ctx, schedule, run)
+	}
+	return run, nil
+}
+
+var reportGroupColumns = []string{"Group", "Name", "Expenses", "Total (IDR)", "Avg Approval Turnaround (hours)"}
+
+// render writes the report into the file store: every expense of the
+// period, or one row per group followed by the total.
+func (u *reportUsecase) render(ctx context.Context, schedule *domain.ReportSchedule, run *domain.ReportRun, fileName string) error {
+	format, err := export.LookupDocumentFormat(schedule.Format)
+	if err != nil {
+		return err
+	}
+
+	filter := reportFilter(schedule.Filters)
+	filter.SubmittedFrom, filter.SubmittedTo = run.PeriodStart, run.PeriodEnd
+	filter.SortBy, filter.SortOrder = domain.ExpenseSortSubmittedAt, domain.SortAsc
+
+	file, err := u.store.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	out := format.NewWriter(file)
+
+	if schedule.GroupBy == "" {
+		if err := out.WriteHeader(export.ExpenseColumns); err != nil {
+			return err
+		}
+		err = u.expenseRepo.Export(ctx, filter, func(row *domain.ExpenseExportRow) error {
+			run.RowCount++
+			return out.WriteRow(export.ExpenseCells(row))
+		})
+		if err != nil {
+			return err
+		}
+	} else {
+		groups, err := u.analyticsRepo.Breakdown(ctx, filter, schedule.GroupBy, 0)
+		if err != nil {
+			return err
+		}
+		if err := out.WriteHeader(reportGroupColumns); err != nil {
+			return err
+		}
+
+		var count, total int
+		for _, group := range groups {
+			var turnaround interface{}
+			if group.AvgTurnaroundHours != nil {
+				turnaround = math.Round(*group.AvgTurnaroundHours*10) / 10
+			}
+			if err := out.WriteRow([]interface{}{group.Key, group.Label, group.ExpenseCount, group.TotalAmountIDR, turnaround}); err != nil {
+				return err
+			}
+			count += group.ExpenseCount
+			total += group.TotalAmountIDR
+		}
+		run.RowCount = len(groups)
+		if err := out.WriteRow([]interface{}{"Total", nil, count, total, nil}); err != nil {
+			return err
+		}
+	}
+
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// deliver sends the download link of a run to the recipients. A failed
+// delivery is logged; the run stays available for download.
+func (u *reportUsecase) deliver(ctx context.Context, schedule *domain.ReportSchedule, run *domain.ReportRun) {
+	subject := "Report ready: " + schedule.Name
+
+	covering := "all expenses"
+	if run.PeriodStart != nil && run.PeriodEnd != nil {
+		covering = fmt.Sprintf("expenses submitted from %s to %s", run.PeriodStart.Format("2006-01-02"), run.PeriodEnd.AddDate(0, 0, -1).Format("2006-01-02"))
+	}
+	body := fmt.Sprintf("The %s report on %s (%d rows) is ready to download: %s",
+		schedule.Name, covering, run.RowCount, fmt.Sprintf(u.downloadURL, run.ID))
+
+	for _, recipient := range schedule.Recipients {
+		if err := u.notifier.Send(ctx, recipient, subject, body); err != nil {
+			logger.ErrorLogger.Printf("Failed to send report run %d to %s: %v", run.ID, recipient, err)
+		}
+	}
+}
+
+func (u *reportUsecase) ListRuns(ctx context.Context, scheduleID *int, page, limit int) ([]*domain.ReportRun, int, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	return u.reportRepo.ListRuns(ctx, scheduleID, limit, (page-1)*limit)
+}
+
+func (u *reportUsecase) OpenRun(ctx context.Context, id int) (*domain.ReportRun, io.ReadCloser, error) {
+	run, err := u.reportRepo.GetRun(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if run.Status != domain.ReportRunSucceeded || run.FileName == nil {
+		return nil, nil, fmt.Errorf("report run %d has no file (status %s)", id, run.Status)
+	}
+
+	file, err := u.store.Open(*run.FileName)
+	if err != nil {
+		return nil, nil, err
+	}
+	return run, file, nil
+}
+
+func reportFilter(filters domain.ReportFilters) domain.ExpenseFilter {
+	return domain.ExpenseFilter{
+		SubmitterID:  filters.SubmitterID,
+		ApproverID:   filters.ApproverID,
+		Statuses:     filters.Statuses,
+		MinAmountIDR: filters.MinAmountIDR,
+		MaxAmountIDR: filters.MaxAmountIDR,
+		AutoApproved: filters.AutoApproved,
+		Search:       strings.TrimSpace(filters.Search),
+	}
+}
+
+// reportPeriod returns the day, week or month before the one now is in;
+// nil bounds for all time.
+func reportPeriod(period string, now time.Time) (*time.Time, *time.Time) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	var start, end time.Time
+	switch period {
+	case domain.ReportPeriodDay:
+		end = today
+		start = end.AddDate(0, 0, -1)
+	case domain.ReportPeriodWeek:
+		// Weeks start on Monday.
+		end = today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+		start = end.AddDate(0, 0, -7)
+	case domain.ReportPeriodMonth:
+		end = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		start = end.AddDate(0, -1, 0)
+	default:
+		return nil, nil
+	}
+	return &start, &end
+}
@@ -0,0 +1,345 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"expense-management-system/internal/domain"
+	"expense-management-system/pkg/config"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+type mockReportRepo struct {
+	schedules map[int]*domain.ReportSchedule
+	runs      []*domain.ReportRun
+	claimed   bool
+
+	claimDue  time.Time
+	claimNext *time.Time
+}
+
+func (m *mockReportRepo) CreateSchedule(ctx context.Context, schedule *domain.ReportSchedule) error {
+	schedule.ID = len(m.schedules) + 1
+	m.schedules[schedule.ID] = schedule
+	return nil
+}
+
+func (m *mockReportRepo) UpdateSchedule(ctx context.Context, schedule *domain.ReportSchedule) error {
+	m.schedules[schedule.ID] = schedule
+	return nil
+}
+
+func (m *mockReportRepo) DeleteSchedule(ctx context.Context, id int) error {
+	delete(m.schedules, id)
+	return nil
+}
+
+func (m *mockReportRepo) GetSchedule(ctx context.Context, id int) (*domain.ReportSchedule, error) {
+	schedule, ok := m.schedules[id]
+	if !ok {
+		return nil, errors.New("report schedule not found")
+	}
+	return schedule, nil
+}
+
+func (m *mockReportRepo) ListSchedules(ctx context.Context) ([]*domain.ReportSchedule, error) {
+	return nil, nil
+}
+
+func (m *mockReportRepo) GetDue(ctx context.Context, now time.Time) ([]*domain.ReportSchedule, error) {
+	var due []*domain.ReportSchedule
+	for _, schedule := range m.schedules {
+		if schedule.Enabled && schedule.NextRunAt != nil && !schedule.NextRunAt.After(now) {
+			due = append(due, schedule)
+		}
+	}
+	return due, nil
+}
+
+func (m *mockReportRepo) ClaimRun(ctx context.Context, id int, due time.Time, next *time.Time) (bool, error) {
+	m.claimDue, m.claimNext = due, next
+	return m.claimed, nil
+}
+
+func (m *mockReportRepo) CreateRun(ctx context.Context, run *domain.ReportRun) error {
+	run.ID = len(m.runs) + 1
+	m.runs = append(m.runs, run)
+	return nil
+}
+
+func (m *mockReportRepo) FinishRun(ctx context.Context, run *domain.ReportRun) error {
+	return nil
+}
+
+func (m *mockReportRepo) ListRuns(ctx context.Context, scheduleID *int, limit, offset int) ([]*domain.ReportRun, int, error) {
+	return m.runs, len(m.runs), nil
+}
+
+func (m *mockReportRepo) GetRun(ctx context.Context, id int) (*domain.ReportRun, error) {
+	if id < 1 || id > len(m.runs) {
+		return nil, errors.New("report run not found")
+	}
+	return m.runs[id-1], nil
+}
+
+// memoryStore keeps files in memory.
+type memoryStore struct {
+	files map[string]*bytes.Buffer
+}
+
+type memoryFile struct {
+	bytes.Buffer
+	store *memoryStore
+	name  string
+}
+
+func (f *memoryFile) Close() error {
+	f.store.files[f.name] = &f.Buffer
+	return nil
+}
+
+func (s *memoryStore) Create(name string) (io.WriteCloser, error) {
+	return &memoryFile{store: s, name: name}, nil
+}
+
+func (s *memoryStore) Open(name string) (io.ReadCloser, error) {
+	file, ok := s.files[name]
+	if !ok {
+		return nil, errors.New("file not found")
+	}
+	return io.NopCloser(bytes.NewReader(file.Bytes())), nil
+}
+
+func (s *memoryStore) Remove(name string) error {
+	delete(s.files, name)
+	return nil
+}
+
+type reportFixture struct {
+	uc        *reportUsecase
+	repo      *mockReportRepo
+	expenses  *mockExpenseRepo
+	analytics *mockAnalyticsRepo
+	store     *memoryStore
+	notifier  *mockNotifier
+}
+
+func newReportFixture(now time.Time) *reportFixture {
+	f := &reportFixture{
+		repo:      &mockReportRepo{schedules: map[int]*domain.ReportSchedule{}, claimed: true},
+		expenses:  &mockExpenseRepo{},
+		analytics: &mockAnalyticsRepo{},
+		store:     &memoryStore{files: map[string]*bytes.Buffer{}},
+		notifier:  &mockNotifier{},
+	}
+	cfg := &config.Config{PublicAPIURL: "https://expenses.example.com/api/"}
+	f.uc = NewReportUsecase(f.repo, f.expenses, f.analytics, f.store, f.notifier, cfg).(*reportUsecase)
+	f.uc.now = func() time.Time { return now }
+	return f
+}
+
+func TestReportPeriod(t *testing.T) {
+	now := time.Date(2025, 3, 5, 7, 0, 0, 0, time.UTC) // a Wednesday
+	day := func(month time.Month, d int) time.Time { return time.Date(2025, month, d, 0, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		period     string
+		start, end time.Time
+	}{
+		{domain.ReportPeriodDay, day(3, 4), day(3, 5)},
+		{domain.ReportPeriodWeek, day(2, 24), day(3, 3)},
+		{domain.ReportPeriodMonth, day(2, 1), day(3, 1)},
+	}
+	for _, tt := range tests {
+		start, end := reportPeriod(tt.period, now)
+		if !start.Equal(tt.start) || !end.Equal(tt.end) {
+			t.Errorf("reportPeriod(%s) = %v to %v, want %v to %v", tt.period, start, end, tt.start, tt.end)
+		}
+	}
+
+	if start, end := reportPeriod(domain.ReportPeriodAll, now); start != nil || end != nil {
+		t.Error("Expected no bounds for all time")
+	}
+}
+
+func TestReportUsecase_CreateSchedule(t *testing.T) {
+	now := time.Date(2025, 3, 5, 7, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	invalid := []*domain.ReportSchedule{
+		{Name: "", Cron: "@monthly"},
+		{Name: "Monthly", Cron: "every month"},
+		{Name: "Monthly", Cron: "@monthly", Period: "previous_decade"},
+		{Name: "Monthly", Cron: "@monthly", GroupBy: "color"},
+		{Name: "Monthly", Cron: "@monthly", Format: "docx"},
+		{Name: "Monthly", Cron: "@monthly", Filters: domain.ReportFilters{Statuses: []string{"paid"}}},
+		{Name: "Monthly", Cron: "@monthly", Recipients: []string{"finance"}},
+		{Name: "Monthly", Cron: "0 0 30 2 *", Enabled: true},
+	}
+	for _, schedule := range invalid {
+		f := newReportFixture(now)
+		if _, err := f.uc.CreateSchedule(ctx, 4, schedule); err == nil {
+			t.Errorf("CreateSchedule(%+v) expected an error", schedule)
+		}
+	}
+
+	f := newReportFixture(now)
+	schedule, err := f.uc.CreateSchedule(ctx, 4, &domain.ReportSchedule{
+		Name:       " Monthly spend ",
+		Cron:       "0 7 1 * *",
+		Recipients: []string{"finance@example.com"},
+		Enabled:    true,
+	})
+	if err != nil {
+		t.Fatalf("CreateSchedule() unexpected error = %v", err)
+	}
+	if schedule.Name != "Monthly spend" || schedule.Period != domain.ReportPeriodMonth || schedule.Format != "csv" || schedule.CreatedBy != 4 {
+		t.Errorf("Unexpected schedule %+v", schedule)
+	}
+	if want := time.Date(2025, 4, 1, 7, 0, 0, 0, time.UTC); schedule.NextRunAt == nil || !schedule.NextRunAt.Equal(want) {
+		t.Errorf("NextRunAt = %v, want %v", schedule.NextRunAt, want)
+	}
+
+	disabled, _ := f.uc.CreateSchedule(ctx, 4, &domain.ReportSchedule{Name: "Paused", Cron: "@daily"})
+	if disabled.NextRunAt != nil {
+		t.Error("Expected no next run for a disabled schedule")
+	}
+}
+
+func TestReportUsecase_RunDue(t *testing.T) {
+	now := time.Date(2025, 3, 1, 7, 0, 30, 0, time.UTC)
+	due := time.Date(2025, 3, 1, 7, 0, 0, 0, time.UTC)
+	f := newReportFixture(now)
+	f.repo.schedules[1] = &domain.ReportSchedule{
+		ID:         1,
+		Name:       "Monthly spend",
+		Cron:       "0 7 1 * *",
+		Period:     domain.ReportPeriodMonth,
+		Format:     "csv",
+		Filters:    domain.ReportFilters{Statuses: []string{domain.StatusCompleted}},
+		Recipients: []string{"finance@example.com", "cfo@example.com"},
+		Enabled:    true,
+		NextRunAt:  &due,
+	}
+
+	var filter domain.ExpenseFilter
+	f.expenses.exportFunc = func(ctx context.Context, got domain.ExpenseFilter, fn func(*domain.ExpenseExportRow) error) error {
+		filter = got
+		for _, id := range []int{7, 8} {
+			if err := fn(&domain.ExpenseExportRow{Expense: domain.Expense{ID: id, AmountIDR: 250000}, SubmitterName: "Employee A"}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := f.uc.RunDue(context.Background(), now); err != nil {
+		t.Fatalf("RunDue() unexpected error = %v", err)
+	}
+
+	if !f.repo.claimDue.Equal(due) || f.repo.claimNext == nil || !f.repo.claimNext.Equal(time.Date(2025, 4, 1, 7, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the run claimed from %v to April, got %v", due, f.repo.claimNext)
+	}
+	if !filter.SubmittedFrom.Equal(time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)) || !filter.SubmittedTo.Equal(due.Truncate(24*time.Hour)) {
+		t.Errorf("Expected February, got %v to %v", filter.SubmittedFrom, filter.SubmittedTo)
+	}
+	if len(filter.Statuses) != 1 || filter.Statuses[0] != domain.StatusCompleted {
+		t.Errorf("Expected the schedule's filters, got %v", filter.Statuses)
+	}
+
+	if len(f.repo.runs) != 1 {
+		t.Fatalf("Expected 1 run, got %d", len(f.repo.runs))
+	}
+	run := f.repo.runs[0]
+	if run.Status != domain.ReportRunSucceeded || run.RowCount != 2 || run.Trigger != domain.ReportTriggerSchedule {
+		t.Errorf("Unexpected run %+v", run)
+	}
+
+	_, file, err := f.uc.OpenRun(context.Background(), run.ID)
+	if err != nil {
+		t.Fatalf("OpenRun() unexpected error = %v", err)
+	}
+	content, _ := io.ReadAll(file)
+	if lines := strings.Count(string(content), "\n"); lines != 3 {
+		t.Errorf("Expected a header and 2 rows, got %d lines", lines)
+	}
+
+	if len(f.notifier.sent) != 2 {
+		t.Fatalf("Expected the link sent to 2 recipients, got %d", len(f.notifier.sent))
+	}
+	for _, want := range []string{"https://expenses.example.com/api/reports/runs/1/download", "2025-02-01 to 2025-02-28", "2 rows"} {
+		if !strings.Contains(f.notifier.sent[0], want) {
+			t.Errorf("Expected the message to contain %q, got %q", want, f.notifier.sent[0])
+		}
+	}
+}
+
+func TestReportUsecase_RunDueClaimedElsewhere(t *testing.T) {
+	now := time.Date(2025, 3, 1, 7, 0, 30, 0, time.UTC)
+	f := newReportFixture(now)
+	f.repo.claimed = false
+	f.repo.schedules[1] = &domain.ReportSchedule{ID: 1, Name: "Daily", Cron: "@daily", Format: "csv", Enabled: true, NextRunAt: &now}
+
+	if err := f.uc.RunDue(context.Background(), now); err != nil {
+		t.Fatalf("RunDue() unexpected error = %v", err)
+	}
+	if len(f.repo.runs) != 0 {
+		t.Error("Expected no run of a schedule claimed by another instance")
+	}
+}
+
+func TestReportUsecase_RunNowGrouped(t *testing.T) {
+	f := newReportFixture(time.Date(2025, 3, 5, 9, 0, 0, 0, time.UTC))
+	f.repo.schedules[1] = &domain.ReportSchedule{ID: 1, Name: "By category", Cron: "@monthly", Period: domain.ReportPeriodAll, GroupBy: domain.AnalyticsByCategory, Format: "csv"}
+	turnaround := 20.04
+	f.analytics.groups = []*domain.AnalyticsGroup{
+		{Key: domain.CategoryTravel, ExpenseCount: 3, TotalAmountIDR: 4500000, AvgTurnaroundHours: &turnaround},
+		{Key: domain.CategoryMeals, ExpenseCount: 5, TotalAmountIDR: 900000},
+	}
+
+	run, err := f.uc.RunNow(context.Background(), 4, 1)
+	if err != nil {
+		t.Fatalf("RunNow() unexpected error = %v", err)
+	}
+	if run.Trigger != domain.ReportTriggerManual || *run.TriggeredBy != 4 || run.RowCount != 2 {
+		t.Errorf("Unexpected run %+v", run)
+	}
+	if f.analytics.dimension != domain.AnalyticsByCategory || f.analytics.filter.SubmittedFrom != nil {
+		t.Errorf("Expected an all-time breakdown by category, got %s from %v", f.analytics.dimension, f.analytics.filter.SubmittedFrom)
+	}
+
+	content := f.store.files["report-1.csv"].String()
+	for _, want := range []string{"travel,,3,4500000,20\n", "meals,,5,900000,\n", "Total,,8,5400000,\n"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("Expected the report to contain %q, got\n%s", want, content)
+		}
+	}
+	if len(f.notifier.sent) != 0 {
+		t.Error("Expected no message without recipients")
+	}
+}
+
+func TestReportUsecase_FailedRun(t *testing.T) {
+	f := newReportFixture(time.Date(2025, 3, 5, 9, 0, 0, 0, time.UTC))
+	f.repo.schedules[1] = &domain.ReportSchedule{ID: 1, Name: "Daily", Cron: "@daily", Period: domain.ReportPeriodDay, Format: "xlsx", Recipients: []string{"finance@example.com"}}
+	f.expenses.exportFunc = func(ctx context.Context, filter domain.ExpenseFilter, fn func(*domain.ExpenseExportRow) error) error {
+		return errors.New("connection reset")
+	}
+
+	run, err := f.uc.RunNow(context.Background(), 4, 1)
+	if err != nil {
+		t.Fatalf("RunNow() unexpected error = %v", err)
+	}
+	if run.Status != domain.ReportRunFailed || run.Error == nil || *run.Error != "connection reset" {
+		t.Errorf("Expected a failed run, got %+v", run)
+	}
+	if len(f.store.files) != 0 || len(f.notifier.sent) != 0 {
+		t.Error("Expected no file and no message for a failed run")
+	}
+	if _, _, err := f.uc.OpenRun(context.Background(), run.ID); err == nil {
+		t.Error("Expected no download of a failed run")
+	}
+}
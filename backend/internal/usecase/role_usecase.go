@@ -0,0 +1,213 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"expense-management-system/internal/domain"
+	"expense-management-system/pkg/logger"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// permissionCacheTTL bounds how long another API instance may keep using
+// role permissions after they were changed.
+const permissionCacheTTL = time.Minute
+
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{1,49}$`)
+
+type roleUsecase struct {
+	roleRepo  domain.RoleRepository
+	auditRepo domain.AuditLogRepository
+	now       func() time.Time
+
+	// Permissions are checked on every request, so they are cached per role.
+	mu       sync.RWMutex
+	cache    map[string]map[string]bool
+	loadedAt time.Time
+}
+
+func NewRoleUsecase(roleRepo domain.RoleRepository, auditRepo domain.AuditLogRepository) domain.RoleUsecase {
+	return &roleUsecase{
+		roleRepo:  roleRepo,
+		auditRepo: auditRepo,
+		now:       time.Now,
+	}
+}
+
+func (u *roleUsecase) List(ctx context.Context) ([]*domain.Role, error) {
+	return u.roleRepo.List(ctx)
+}
+
+func (u *roleUsecase) GetByName(ctx context.Context, name string) (*domain.Role, error) {
+	return u.roleRepo.GetByName(ctx, name)
+}
+
+func (u *roleUsecase) Create(ctx context.Context, adminID int, role *domain.Role) (*domain.Role, error) {
+	role.Name = strings.TrimSpace(strings.ToLower(role.Name))
+	role.Description = strings.TrimSpace(role.Description)
+
+	if !roleNamePattern.MatchString(role.Name) {
+		return nil, errors.New("role name must be 2 to 50 lowercase letters, digits, '-' or '_', starting with a letter")
+	}
+	permissions, err := normalizePermissions(role.Permissions)
+	if err != nil {
+		return nil, err
+	}
+	role.Permissions = permissions
+
+	if _, err := u.roleRepo.GetByName(ctx, role.Name); err == nil {
+		return nil, errors.New("role already exists")
+	}
+
+	if err := u.roleRepo.Create(ctx, role); err != nil {
+		return nil, err
+	}
+	u.invalidate()
+
+	u.audit(ctx, adminID, domain.ActionRoleCreate, map[string]interface{}{
+		"role":        role.Name,
+		"permissions": role.Permissions,
+	})
+	logger.InfoLogger.Printf("[SECURITY] Role %s created by admin %d", role.Name, adminID)
+
+	return role, nil
+}
+
+// Update changes a role's description and permissions. The admin role is
+// fixed so that nobody can lock every admin out of role management.
+func (u *roleUsecase) Update(ctx context.Context, adminID int, name string, description *string, permissions []string) (*domain.Role, error) {
+	role, err := u.roleRepo.GetByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	oldPermissions := role.Permissions
+	if description != nil {
+		role.Description = strings.TrimSpace(*description)
+	}
+	if permissions != nil {
+		if role.Name == domain.RoleAdmin {
+			return nil, errors.New("the permissions of the admin role cannot be changed")
+		}
+		if role.Permissions, err = normalizePermissions(permissions); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := u.roleRepo.Update(ctx, role); err != nil {
+		return nil, err
+	}
+	u.invalidate()
+
+	u.audit(ctx, adminID, domain.ActionRoleUpdate, map[string]interface{}{
+		"role":            role.Name,
+		"old_permissions": oldPermissions,
+		"new_permissions": role.Permissions,
+	})
+	logger.InfoLogger.Printf("[SECURITY] Role %s updated by admin %d: %v", role.Name, adminID, role.Permissions)
+
+	return role, nil
+}
+
+func (u *roleUsecase) Delete(ctx context.Context, adminID int, name string) error {
+	role, err := u.roleRepo.GetByName(ctx, name)
+	if err != nil {
+		return err
+	}
+	if role.BuiltIn {
+		return errors.New("built-in roles cannot be deleted")
+	}
+
+	users, err := u.roleRepo.CountUsers(ctx, name)
+	if err != nil {
+		return err
+	}
+	if users > 0 {
+		return fmt.Errorf("role is assigned to %d user(s)", users)
+	}
+
+	if err := u.roleRepo.Delete(ctx, name); err != nil {
+		return err
+	}
+	u.invalidate()
+
+	u.audit(ctx, adminID, domain.ActionRoleDelete, map[string]interface{}{
+		"role":        role.Name,
+		"permissions": role.Permissions,
+	})
+	logger.InfoLogger.Printf("[SECURITY] Role %s deleted by admin %d", role.Name, adminID)
+
+	return nil
+}
+
+func (u *roleUsecase) Permissions(ctx context.Context, role string) (map[string]bool, error) {
+	u.mu.RLock()
+	if u.cache != nil && u.now().Sub(u.loadedAt) < permissionCacheTTL {
+		permissions := u.cache[role]
+		u.mu.RUnlock()
+		return permissions, nil
+	}
+	u.mu.RUnlock()
+
+	roles, err := u.roleRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cache := make(map[string]map[string]bool, len(roles))
+	for _, r := range roles {
+		set := make(map[string]bool, len(r.Permissions))
+		for _, permission := range r.Permissions {
+			set[permission] = true
+		}
+		cache[r.Name] = set
+	}
+
+	u.mu.Lock()
+	u.cache = cache
+	u.loadedAt = u.now()
+	u.mu.Unlock()
+
+	return cache[role], nil
+}
+
+func (u *roleUsecase) invalidate() {
+	u.mu.Lock()
+	u.cache = nil
+	u.mu.Unlock()
+}
+
+// audit records role changes against the admin who made them, since audit
+// entries need a subject and a role is not one.
+func (u *roleUsecase) audit(ctx context.Context, adminID int, action string, metadata map[string]interface{}) {
+	auditLog := &domain.AuditLog{
+		SubjectUserID: &adminID,
+		UserID:        &adminID,
+		Action:        action,
+		Metadata:      metadata,
+	}
+	u.auditRepo.Create(ctx, auditLog)
+}
+
+// normalizePermissions rejects unknown permissions and returns the rest
+// sorted and without duplicates.
+func normalizePermissions(permissions []string) ([]string, error) {
+	seen := map[string]bool{}
+	result := []string{}
+	for _, permission := range permissions {
+		permission = strings.TrimSpace(permission)
+		if !domain.IsValidPermission(permission) {
+			return nil, fmt.Errorf("unknown permission %q", permission)
+		}
+		if !seen[permission] {
+			seen[permission] = true
+			result = append(result, permission)
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
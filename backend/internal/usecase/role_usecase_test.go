@@ -0,0 +1,244 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"expense-management-system/internal/domain"
+	"testing"
+	"time"
+)
+
+type mockRoleRepo struct {
+	roles     map[string]*domain.Role
+	userCount map[string]int
+	listCalls int
+}
+
+// newMockRoleRepo holds the built-in roles with a few of their permissions.
+func newMockRoleRepo() *mockRoleRepo {
+	repo := &mockRoleRepo{roles: map[string]*domain.Role{}, userCount: map[string]int{}}
+	for name, permissions := range map[string][]string{
+		domain.RoleEmployee: {domain.PermExpenseSubmit},
+		domain.RoleManager:  {domain.PermExpenseSubmit, domain.PermExpenseApprove, domain.PermExpenseReadAll},
+		domain.RoleFinance:  {domain.PermPaymentRead, domain.PermPaymentRelease, domain.PermRefundCreate},
+		domain.RoleAdmin:    {domain.PermUserManage, domain.PermRoleManage},
+		domain.RoleAuditor:  {domain.PermAuditRead, domain.PermPaymentRead},
+	} {
+		repo.roles[name] = &domain.Role{Name: name, Permissions: permissions, BuiltIn: true}
+	}
+	return repo
+}
+
+func (m *mockRoleRepo) List(ctx context.Context) ([]*domain.Role, error) {
+	m.listCalls++
+	roles := []*domain.Role{}
+	for _, role := range m.roles {
+		copy := *role
+		roles = append(roles, &copy)
+	}
+	return roles, nil
+}
+
+func (m *mockRoleRepo) GetByName(ctx context.Context, name string) (*domain.Role, error) {
+	role, ok := m.roles[name]
+	if !ok {
+		return nil, errors.New("role not found")
+	}
+	copy := *role
+	return &copy, nil
+}
+
+func (m *mockRoleRepo) Create(ctx context.Context, role *domain.Role) error {
+	copy := *role
+	m.roles[role.Name] = &copy
+	return nil
+}
+
+func (m *mockRoleRepo) Update(ctx context.Context, role *domain.Role) error {
+	copy := *role
+	m.roles[role.Name] = &copy
+	return nil
+}
+
+func (m *mockRoleRepo) Delete(ctx context.Context, name string) error {
+	delete(m.roles, name)
+	return nil
+}
+
+func (m *mockRoleRepo) CountUsers(ctx context.Context, name string) (int, error) {
+	return m.userCount[name], nil
+}
+
+func TestRoleUsecase_Create(t *testing.T) {
+	tests := []struct {
+		name            string
+		role            *domain.Role
+		wantErr         bool
+		wantPermissions []string
+	}{
+		{
+			name:            "Valid role sorts and dedupes permissions",
+			role:            &domain.Role{Name: " Payments-Viewer ", Permissions: []string{domain.PermPaymentRead, domain.PermAuditRead, domain.PermPaymentRead}},
+			wantPermissions: []string{domain.PermAuditRead, domain.PermPaymentRead},
+		},
+		{
+			name:            "Role without permissions",
+			role:            &domain.Role{Name: "guest"},
+			wantPermissions: []string{},
+		},
+		{
+			name:    "Unknown permission",
+			role:    &domain.Role{Name: "viewer", Permissions: []string{"payment:everything"}},
+			wantErr: true,
+		},
+		{
+			name:    "Invalid name",
+			role:    &domain.Role{Name: "1 bad name"},
+			wantErr: true,
+		},
+		{
+			name:    "Existing role",
+			role:    &domain.Role{Name: domain.RoleManager},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var audits []*domain.AuditLog
+			auditRepo := &mockAuditRepo{
+				createFunc: func(ctx context.Context, log *domain.AuditLog) error {
+					audits = append(audits, log)
+					return nil
+				},
+			}
+			uc := NewRoleUsecase(newMockRoleRepo(), auditRepo)
+
+			role, err := uc.Create(context.Background(), 9, tt.role)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Create() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if len(role.Permissions) != len(tt.wantPermissions) {
+				t.Fatalf("Create() permissions = %v, want %v", role.Permissions, tt.wantPermissions)
+			}
+			for i := range role.Permissions {
+				if role.Permissions[i] != tt.wantPermissions[i] {
+					t.Errorf("Create() permissions = %v, want %v", role.Permissions, tt.wantPermissions)
+				}
+			}
+			if len(audits) != 1 || audits[0].Action != domain.ActionRoleCreate {
+				t.Errorf("Expected a role_create audit entry, got %+v", audits)
+			}
+		})
+	}
+}
+
+func TestRoleUsecase_Update(t *testing.T) {
+	ctx := context.Background()
+	repo := newMockRoleRepo()
+	uc := NewRoleUsecase(repo, &mockAuditRepo{})
+
+	// Managers lose approval rights: the cached permissions must follow.
+	if perms, _ := uc.Permissions(ctx, domain.RoleManager); !perms[domain.PermExpenseApprove] {
+		t.Fatal("Expected managers to start with expense:approve")
+	}
+
+	role, err := uc.Update(ctx, 9, domain.RoleManager, nil, []string{domain.PermExpenseReadAll})
+	if err != nil {
+		t.Fatalf("Update() unexpected error = %v", err)
+	}
+	if len(role.Permissions) != 1 {
+		t.Errorf("Update() permissions = %v", role.Permissions)
+	}
+	if perms, _ := uc.Permissions(ctx, domain.RoleManager); perms[domain.PermExpenseApprove] {
+		t.Error("Expected the permission cache to be refreshed after an update")
+	}
+
+	description := "Team leads"
+	role, err = uc.Update(ctx, 9, domain.RoleManager, &description, nil)
+	if err != nil || role.Description != description || len(role.Permissions) != 1 {
+		t.Errorf("Update() description only = %+v, %v", role, err)
+	}
+
+	if _, err := uc.Update(ctx, 9, domain.RoleAdmin, nil, []string{}); err == nil {
+		t.Error("Expected the admin role's permissions to be fixed")
+	}
+	if _, err := uc.Update(ctx, 9, domain.RoleManager, nil, []string{"expense:everything"}); err == nil {
+		t.Error("Expected an unknown permission to be rejected")
+	}
+	if _, err := uc.Update(ctx, 9, "ghost", &description, nil); err == nil {
+		t.Error("Expected an unknown role to be rejected")
+	}
+}
+
+func TestRoleUsecase_Delete(t *testing.T) {
+	ctx := context.Background()
+	repo := newMockRoleRepo()
+	repo.roles["contractor"] = &domain.Role{Name: "contractor", Permissions: []string{domain.PermExpenseSubmit}}
+	repo.roles["intern"] = &domain.Role{Name: "intern"}
+	repo.userCount["contractor"] = 2
+	uc := NewRoleUsecase(repo, &mockAuditRepo{})
+
+	if err := uc.Delete(ctx, 9, domain.RoleEmployee); err == nil {
+		t.Error("Expected built-in roles not to be deletable")
+	}
+	if err := uc.Delete(ctx, 9, "contractor"); err == nil {
+		t.Error("Expected a role with users not to be deletable")
+	}
+	if err := uc.Delete(ctx, 9, "intern"); err != nil {
+		t.Errorf("Delete() unexpected error = %v", err)
+	}
+	if _, ok := repo.roles["intern"]; ok {
+		t.Error("Expected the role to be deleted")
+	}
+}
+
+func TestRoleUsecase_PermissionsCache(t *testing.T) {
+	ctx := context.Background()
+	repo := newMockRoleRepo()
+	uc := NewRoleUsecase(repo, &mockAuditRepo{}).(*roleUsecase)
+
+	now := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	uc.now = func() time.Time { return now }
+
+	perms, err := uc.Permissions(ctx, domain.RoleAuditor)
+	if err != nil {
+		t.Fatalf("Permissions() unexpected error = %v", err)
+	}
+	if !perms[domain.PermAuditRead] || perms[domain.PermExpenseApprove] {
+		t.Errorf("Permissions(auditor) = %v", perms)
+	}
+	if perms, _ := uc.Permissions(ctx, "ghost"); len(perms) != 0 {
+		t.Errorf("Expected an unknown role to grant nothing, got %v", perms)
+	}
+	if repo.listCalls != 1 {
+		t.Errorf("Expected permissions to be cached, loaded %d times", repo.listCalls)
+	}
+
+	// A change made through another instance shows up once the cache expires.
+	repo.roles[domain.RoleAuditor].Permissions = []string{domain.PermExpenseReadAll}
+	now = now.Add(permissionCacheTTL)
+	if perms, _ := uc.Permissions(ctx, domain.RoleAuditor); perms[domain.PermAuditRead] || !perms[domain.PermExpenseReadAll] {
+		t.Errorf("Expected expired cache to be reloaded, got %v", perms)
+	}
+}
+
+func TestPermissionCatalogue(t *testing.T) {
+	seen := map[string]bool{}
+	for _, p := range domain.Permissions {
+		if seen[p.Name] {
+			t.Errorf("Permission %s listed twice", p.Name)
+		}
+		seen[p.Name] = true
+		if p.Description == "" {
+			t.Errorf("Permission %s has no description", p.Name)
+		}
+	}
+	if domain.IsValidPermission("expense:delete_everything") {
+		t.Error("Expected unknown permissions to be invalid")
+	}
+}
@@ -0,0 +1,240 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"expense-management-system/internal/domain"
+	"expense-management-system/pkg/config"
+	"expense-management-system/pkg/logger"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// settingsCacheTTL bounds how long another API instance may keep applying
+// limits after an admin changed them.
+const settingsCacheTTL = time.Minute
+
+type settingsUsecase struct {
+	settingRepo domain.SettingRepository
+	defaults    map[string]int
+	now         func() time.Time
+
+	// Limits are read on every submission, so stored settings are cached.
+	mu       sync.RWMutex
+	cache    map[string]*domain.Setting
+	loaded   bool
+	loadedAt time.Time
+}
+
+func NewSettingsUsecase(settingRepo domain.SettingRepository, cfg *config.Config) domain.SettingsUsecase {
+	return &settingsUsecase{
+		settingRepo: settingRepo,
+		defaults: map[string]int{
+			domain.SettingMinExpenseAmount:  cfg.MinExpenseAmountIDR,
+			domain.SettingMaxExpenseAmount:  cfg.MaxExpenseAmountIDR,
+			domain.SettingApprovalThreshold: cfg.ApprovalThresholdIDR,
+		},
+		now: time.Now,
+	}
+}
+
+func (u *settingsUsecase) ExpenseLimits(ctx context.Context) (domain.ExpenseLimits, error) {
+	values, err := u.values(ctx, false)
+	if err != nil {
+		return domain.ExpenseLimits{}, err
+	}
+	return limitsFrom(values), nil
+}
+
+func (u *settingsUsecase) List(ctx context.Context) ([]*domain.Setting, error) {
+	stored, err := u.stored(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := make([]*domain.Setting, 0, len(domain.SettingDefinitions))
+	for _, definition := range domain.SettingDefinitions {
+		settings = append(settings, u.effective(definition, stored))
+	}
+	return settings, nil
+}
+
+func (u *settingsUsecase) Update(ctx context.Context, adminID int, key string, value int) (*domain.Setting, error) {
+	return u.change(ctx, adminID, key, &value)
+}
+
+func (u *settingsUsecase) Reset(ctx context.Context, adminID int, key string) (*domain.Setting, error) {
+	return u.change(ctx, adminID, key, nil)
+}
+
+// change stores value, or goes back to the default when it is nil, if the
+// limits still make sense together afterwards.
+func (u *settingsUsecase) change(ctx context.Context, adminID int, key string, value *int) (*domain.Setting, error) {
+	if !domain.IsValidSetting(key) {
+		return nil, errors.New("setting not found")
+	}
+
+	// Checked against the stored values rather than the cache, which may
+	// predate a change made on another instance.
+	values, err := u.values(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	old := values[key]
+	values[key] = u.defaults[key]
+	if value != nil {
+		values[key] = *value
+	}
+	if err := validateLimits(limitsFrom(values)); err != nil {
+		return nil, err
+	}
+
+	if value != nil {
+		err = u.settingRepo.Set(ctx, key, *value, adminID)
+	} else {
+		err = u.settingRepo.Reset(ctx, key, adminID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	u.invalidate()
+
+	logger.InfoLogger.Printf("Setting %s changed from %d to %d by admin %d", key, old, values[key], adminID)
+
+	stored, err := u.stored(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	for _, definition := range domain.SettingDefinitions {
+		if definition.Key == key {
+			return u.effective(definition, stored), nil
+		}
+	}
+	return nil, errors.New("setting not found")
+}
+
+func (u *settingsUsecase) History(ctx context.Context, key string, limit int) ([]*domain.SettingChange, error) {
+	if key != "" && !domain.IsValidSetting(key) {
+		return nil, errors.New("setting not found")
+	}
+	if limit <= 0 {
+		limit = 100
+	}
+	if limit > 500 {
+		limit = 500
+	}
+	return u.settingRepo.History(ctx, key, limit)
+}
+
+func (u *settingsUsecase) effective(definition domain.SettingDefinition, stored map[string]*domain.Setting) *domain.Setting {
+	setting := &domain.Setting{
+		Key:          definition.Key,
+		Description:  definition.Description,
+		Value:        u.defaults[definition.Key],
+		DefaultValue: u.defaults[definition.Key],
+		Source:       domain.SettingSourceEnvironment,
+	}
+	if s, ok := stored[definition.Key]; ok {
+		setting.Value = s.Value
+		setting.Source = domain.SettingSourceDatabase
+		setting.UpdatedBy = s.UpdatedBy
+		setting.UpdatedAt = s.UpdatedAt
+	}
+	return setting
+}
+
+// values returns the value in effect for every setting.
+func (u *settingsUsecase) values(ctx context.Context, fresh bool) (map[string]int, error) {
+	stored, err := u.stored(ctx, fresh)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]int, len(u.defaults))
+	for key, value := range u.defaults {
+		values[key] = value
+	}
+	for key, setting := range stored {
+		if domain.IsValidSetting(key) {
+			values[key] = setting.Value
+		}
+	}
+	return values, nil
+}
+
+func (u *settingsUsecase) stored(ctx context.Context, fresh bool) (map[string]*domain.Setting, error) {
+	if !fresh {
+		u.mu.RLock()
+		if u.loaded && u.now().Sub(u.loadedAt) < settingsCacheTTL {
+			cache := u.cache
+			u.mu.RUnlock()
+			return cache, nil
+		}
+		u.mu.RUnlock()
+	}
+
+	settings, err := u.settingRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stored := make(map[string]*domain.Setting, len(settings))
+	for _, setting := range settings {
+		stored[setting.Key] = setting
+	}
+
+	u.mu.Lock()
+	u.cache = stored
+	u.loaded = true
+	u.loadedAt = u.now()
+	u.mu.Unlock()
+
+	return stored, nil
+}
+
+func (u *settingsUsecase) invalidate() {
+	u.mu.Lock()
+	u.cache = nil
+	u.loaded = false
+	u.mu.Unlock()
+}
+
+func limitsFrom(values map[string]int) domain.ExpenseLimits {
+	return domain.ExpenseLimits{
+		MinAmountIDR:         values[domain.SettingMinExpenseAmount],
+		MaxAmountIDR:         values[domain.SettingMaxExpenseAmount],
+		ApprovalThresholdIDR: values[domain.SettingApprovalThreshold],
+	}
+}
+
+func validateLimits(limits domain.ExpenseLimits) error {
+	if limits.MinAmountIDR <= 0 {
+		return errors.New("minimum expense amount must be positive")
+	}
+	if limits.MaxAmountIDR < limits.MinAmountIDR {
+		return errors.New("maximum expense amount must not be below the minimum")
+	}
+	if limits.MaxAmountIDR > domain.MaxAmountIDR {
+		return fmt.Errorf("maximum expense amount must be at most IDR %d", domain.MaxAmountIDR)
+	}
+	if limits.ApprovalThresholdIDR < 0 {
+		return errors.New("approval threshold must not be negative")
+	}
+	return nil
+}
+
+// expenseLimits reads the limits in effect; without a settings store the
+// built-in ones apply.
+func expenseLimits(ctx context.Context, settings domain.SettingsUsecase) (domain.ExpenseLimits, error) {
+	if settings == nil {
+		return domain.DefaultExpenseLimits, nil
+	}
+	return settings.ExpenseLimits(ctx)
+}
+
+func checkAmount(limits domain.ExpenseLimits, amountIDR int) error {
+	if amountIDR < limits.MinAmountIDR || amountIDR > limits.MaxAmountIDR {
+		return fmt.Errorf("amount must be between IDR %d and IDR %d", limits.MinAmountIDR, limits.MaxAmountIDR)
+	}
+	return nil
+}
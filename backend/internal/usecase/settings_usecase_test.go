@@ -0,0 +1,230 @@
+package usecase
+
+import (
+	"context"
+	"expense-management-system/internal/domain"
+	"expense-management-system/pkg/config"
+	"strings"
+	"testing"
+	"time"
+)
+
+type mockSettingRepo struct {
+	values    map[string]int
+	changes   []*domain.SettingChange
+	listCalls int
+}
+
+func (m *mockSettingRepo) List(ctx context.Context) ([]*domain.Setting, error) {
+	m.listCalls++
+	var settings []*domain.Setting
+	for key, value := range m.values {
+		settings = append(settings, &domain.Setting{Key: key, Value: value, Source: domain.SettingSourceDatabase})
+	}
+	return settings, nil
+}
+
+func (m *mockSettingRepo) Set(ctx context.Context, key string, value int, changedBy int) error {
+	m.record(key, &value, changedBy)
+	m.values[key] = value
+	return nil
+}
+
+func (m *mockSettingRepo) Reset(ctx context.Context, key string, changedBy int) error {
+	if _, ok := m.values[key]; !ok {
+		return nil
+	}
+	m.record(key, nil, changedBy)
+	delete(m.values, key)
+	return nil
+}
+
+func (m *mockSettingRepo) record(key string, newValue *int, changedBy int) {
+	var oldValue *int
+	if value, ok := m.values[key]; ok {
+		oldValue = &value
+	}
+	m.changes = append([]*domain.SettingChange{{
+		ID:        len(m.changes) + 1,
+		Key:       key,
+		OldValue:  oldValue,
+		NewValue:  newValue,
+		ChangedBy: changedBy,
+		ChangedAt: time.Now(),
+	}}, m.changes...)
+}
+
+func (m *mockSettingRepo) History(ctx context.Context, key string, limit int) ([]*domain.SettingChange, error) {
+	var changes []*domain.SettingChange
+	for _, change := range m.changes {
+		if (key == "" || change.Key == key) && len(changes) < limit {
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
+
+func newTestSettingsUsecase() (*settingsUsecase, *mockSettingRepo) {
+	repo := &mockSettingRepo{values: map[string]int{}}
+	uc := NewSettingsUsecase(repo, &config.Config{
+		MinExpenseAmountIDR:  10000,
+		MaxExpenseAmountIDR:  50000000,
+		ApprovalThresholdIDR: 1000000,
+	})
+	return uc.(*settingsUsecase), repo
+}
+
+func TestSettingsUpdate(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		value   int
+		wantErr string
+	}{
+		{name: "lower threshold", key: domain.SettingApprovalThreshold, value: 500000},
+		{name: "threshold of zero sends everything for approval", key: domain.SettingApprovalThreshold, value: 0},
+		{name: "raise maximum", key: domain.SettingMaxExpenseAmount, value: 100000000},
+		{name: "unknown setting", key: "payment_mode", value: 1, wantErr: "setting not found"},
+		{name: "minimum of zero", key: domain.SettingMinExpenseAmount, value: 0, wantErr: "must be positive"},
+		{name: "minimum above maximum", key: domain.SettingMinExpenseAmount, value: 60000000, wantErr: "below the minimum"},
+		{name: "maximum beyond the column", key: domain.SettingMaxExpenseAmount, value: domain.MaxAmountIDR + 1, wantErr: "at most"},
+		{name: "negative threshold", key: domain.SettingApprovalThreshold, value: -1, wantErr: "must not be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc, repo := newTestSettingsUsecase()
+
+			setting, err := uc.Update(context.Background(), 1, tt.key, tt.value)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Update() error = %v, want it to mention %q", err, tt.wantErr)
+				}
+				if len(repo.changes) != 0 {
+					t.Errorf("rejected value was stored")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Update() error = %v", err)
+			}
+			if setting.Value != tt.value || setting.Source != domain.SettingSourceDatabase {
+				t.Errorf("Update() = %+v, want %d from the database", setting, tt.value)
+			}
+			if len(repo.changes) != 1 || repo.changes[0].OldValue != nil || *repo.changes[0].NewValue != tt.value {
+				t.Errorf("history = %+v, want one change from the default", repo.changes)
+			}
+		})
+	}
+}
+
+func TestSettingsExpenseLimits(t *testing.T) {
+	ctx := context.Background()
+	uc, repo := newTestSettingsUsecase()
+
+	limits, err := uc.ExpenseLimits(ctx)
+	if err != nil {
+		t.Fatalf("ExpenseLimits() error = %v", err)
+	}
+	if limits != domain.DefaultExpenseLimits {
+		t.Errorf("ExpenseLimits() = %+v, want the configured defaults", limits)
+	}
+
+	if _, err := uc.Update(ctx, 1, domain.SettingApprovalThreshold, 250000); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	listed := repo.listCalls
+	for i := 0; i < 3; i++ {
+		limits, _ = uc.ExpenseLimits(ctx)
+	}
+	if limits.ApprovalThresholdIDR != 250000 {
+		t.Errorf("threshold = %d after the update, want 250000", limits.ApprovalThresholdIDR)
+	}
+	if repo.listCalls != listed {
+		t.Errorf("settings loaded %d more times, want them cached", repo.listCalls-listed)
+	}
+
+	// Once the cache expires, values changed elsewhere are picked up.
+	repo.values[domain.SettingApprovalThreshold] = 750000
+	uc.now = func() time.Time { return time.Now().Add(settingsCacheTTL) }
+	if limits, _ = uc.ExpenseLimits(ctx); limits.ApprovalThresholdIDR != 750000 {
+		t.Errorf("threshold = %d after the cache expired, want 750000", limits.ApprovalThresholdIDR)
+	}
+}
+
+func TestSettingsReset(t *testing.T) {
+	ctx := context.Background()
+	uc, repo := newTestSettingsUsecase()
+
+	if _, err := uc.Update(ctx, 1, domain.SettingMaxExpenseAmount, 20000000); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	// The minimum may not pass the stored maximum, only the default one.
+	if _, err := uc.Update(ctx, 1, domain.SettingMinExpenseAmount, 30000000); err == nil {
+		t.Fatal("Update() above the stored maximum should fail")
+	}
+
+	setting, err := uc.Reset(ctx, 2, domain.SettingMaxExpenseAmount)
+	if err != nil {
+		t.Fatalf("Reset() error = %v", err)
+	}
+	if setting.Value != 50000000 || setting.Source != domain.SettingSourceEnvironment {
+		t.Errorf("Reset() = %+v, want the environment value", setting)
+	}
+
+	history, _ := uc.History(ctx, domain.SettingMaxExpenseAmount, 0)
+	if len(history) != 2 || history[0].NewValue != nil || *history[0].OldValue != 20000000 || history[0].ChangedBy != 2 {
+		t.Errorf("History() = %+v, want the reset first", history)
+	}
+	if _, err := uc.History(ctx, "payment_mode", 0); err == nil {
+		t.Error("History() of an unknown setting should fail")
+	}
+	if len(repo.values) != 0 {
+		t.Errorf("stored values = %v, want none after the reset", repo.values)
+	}
+}
+
+func TestSubmitUsesRuntimeLimits(t *testing.T) {
+	ctx := context.Background()
+	settings, _ := newTestSettingsUsecase()
+	if _, err := settings.Update(ctx, 1, domain.SettingApprovalThreshold, 100000); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if _, err := settings.Update(ctx, 1, domain.SettingMinExpenseAmount, 50000); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	var submitAudit *domain.AuditLog
+	auditRepo := &mockAuditRepo{
+		createFunc: func(ctx context.Context, log *domain.AuditLog) error {
+			submitAudit = log
+			return nil
+		},
+	}
+	paymentChan := make(chan PaymentJob, 1)
+	uc := NewExpenseUsecase(&mockExpenseRepo{}, &mockApprovalRepo{}, auditRepo, &mockUserRepo{}, &mockCashAdvanceRepo{}, paymentChan, nil, nil, nil, nil, nil, settings)
+
+	if _, err := uc.Submit(ctx, 1, 20000, "Parking", domain.CategoryTransport, nil, nil, nil); err == nil || !strings.Contains(err.Error(), "IDR 50000") {
+		t.Errorf("Submit() below the raised minimum error = %v", err)
+	}
+
+	expense, err := uc.Submit(ctx, 1, 250000, "Taxi to airport", domain.CategoryTravel, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	if expense.AutoApproved || expense.Status != domain.StatusAwaitingApproval || len(paymentChan) != 0 {
+		t.Errorf("expense above the lowered threshold should wait for approval, got status %s", expense.Status)
+	}
+	if submitAudit == nil || submitAudit.Metadata["approval_threshold_idr"] != 100000 {
+		t.Errorf("submit audit metadata = %v, want the threshold applied", submitAudit)
+	}
+
+	advances := NewCashAdvanceUsecase(&mockCashAdvanceRepo{}, &mockAuditRepo{}, &mockUserRepo{}, make(chan PaymentJob, 1), settings)
+	advance, err := advances.Request(ctx, 1, 250000, "Trip to Surabaya")
+	if err != nil {
+		t.Fatalf("Request() error = %v", err)
+	}
+	if advance.Status != domain.AdvanceStatusAwaitingApproval {
+		t.Errorf("advance above the lowered threshold has status %s, want awaiting approval", advance.Status)
+	}
+}
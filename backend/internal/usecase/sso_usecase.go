@@ -0,0 +1,255 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"expense-management-system/internal/domain"
+	"expense-management-system/pkg/config"
+	"expense-management-system/pkg/logger"
+	"strings"
+	"time"
+)
+
+// ssoRequestTTL is how long a user has to finish logging in at the identity
+// provider.
+const ssoRequestTTL = 10 * time.Minute
+
+// rolePrecedence decides the role of a user whose groups map to several.
+var rolePrecedence = []string{domain.RoleAdmin, domain.RoleFinance, domain.RoleManager, domain.RoleAuditor, domain.RoleEmployee}
+
+type ssoUsecase struct {
+	provider     domain.IdentityProvider
+	identityRepo domain.IdentityRepository
+	userRepo     domain.UserRepository
+	auditRepo    domain.AuditLogRepository
+	auth         domain.AuthUsecase
+	cfg          *config.Config
+	// roleByGroup is OIDC_ROLE_MAPPING parsed, IdP group to role.
+	roleByGroup map[string]string
+}
+
+// NewSSOUsecase builds the single sign-on usecase. A nil provider means SSO
+// is not configured and every call returns domain.ErrSSONotConfigured.
+func NewSSOUsecase(provider domain.IdentityProvider, identityRepo domain.IdentityRepository, userRepo domain.UserRepository, auditRepo domain.AuditLogRepository, auth domain.AuthUsecase, cfg *config.Config) domain.SSOUsecase {
+	return &ssoUsecase{
+		provider:     provider,
+		identityRepo: identityRepo,
+		userRepo:     userRepo,
+		auditRepo:    auditRepo,
+		auth:         auth,
+		cfg:          cfg,
+		roleByGroup:  parseRoleMapping(cfg.OIDCRoleMapping),
+	}
+}
+
+func (u *ssoUsecase) Begin(ctx context.Context) (string, error) {
+	if u.provider == nil {
+		return "", domain.ErrSSONotConfigured
+	}
+
+	state, err := generateSecureToken()
+	if err != nil {
+		return "", err
+	}
+	nonce, err := generateSecureToken()
+	if err != nil {
+		return "", err
+	}
+	verifier, err := generateSecureToken()
+	if err != nil {
+		return "", err
+	}
+
+	request := &domain.SSOAuthRequest{
+		StateHash:    hashToken(state),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    time.Now().Add(ssoRequestTTL),
+	}
+	if err := u.identityRepo.CreateAuthRequest(ctx, request); err != nil {
+		return "", err
+	}
+
+	return u.provider.AuthCodeURL(ctx, state, nonce, verifier)
+}
+
+// Complete redeems the provider's callback. The user is found by the linked
+// identity, then by verified email (linking the identity), and otherwise
+// provisioned when OIDC_AUTO_PROVISION is on.
+func (u *ssoUsecase) Complete(ctx context.Context, code, state string) (*domain.LoginResult, error) {
+	if u.provider == nil {
+		return nil, domain.ErrSSONotConfigured
+	}
+
+	request, err := u.identityRepo.ConsumeAuthRequest(ctx, hashToken(state))
+	if err != nil || time.Now().After(request.ExpiresAt) {
+		return nil, errors.New("invalid or expired sign-in request")
+	}
+
+	claims, err := u.provider.Exchange(ctx, code, request.CodeVerifier, request.Nonce)
+	if err != nil {
+		logger.ErrorLogger.Printf("[SECURITY] SSO login failed: %v", err)
+		return nil, errors.New("single sign-on failed")
+	}
+
+	user, identity, err := u.resolveUser(ctx, claims)
+	if err != nil {
+		logger.ErrorLogger.Printf("[SECURITY] SSO login refused for %s (%s): %v", claims.Subject, claims.Email, err)
+		return nil, err
+	}
+	if !user.Active {
+		return nil, errors.New("account is deactivated")
+	}
+
+	if u.cfg.OIDCSyncRoles {
+		if err := u.syncRole(ctx, user, claims.Groups); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := u.identityRepo.TouchLogin(ctx, identity.ID); err != nil {
+		logger.ErrorLogger.Printf("Failed to record SSO login of user %d: %v", user.ID, err)
+	}
+
+	return u.auth.StartSession(ctx, user)
+}
+
+func (u *ssoUsecase) resolveUser(ctx context.Context, claims *domain.IdentityClaims) (*domain.User, *domain.ExternalIdentity, error) {
+	identity, err := u.identityRepo.GetByIssuerSubject(ctx, claims.Issuer, claims.Subject)
+	if err == nil {
+		user, err := u.userRepo.GetByID(ctx, identity.UserID)
+		if err != nil {
+			return nil, nil, err
+		}
+		return user, identity, nil
+	}
+
+	// Matching by email hands over an account, so the provider must vouch
+	// for the address.
+	email := strings.TrimSpace(strings.ToLower(claims.Email))
+	if err := validateEmail(email); err != nil {
+		return nil, nil, errors.New("identity provider did not return a valid email")
+	}
+	if u.cfg.OIDCRequireVerifiedEmail && !claims.EmailVerified {
+		return nil, nil, errors.New("email is not verified by the identity provider")
+	}
+
+	user, err := u.userRepo.GetByEmail(ctx, email)
+	action := domain.ActionIdentityLink
+	if err != nil {
+		if !u.cfg.OIDCAutoProvision {
+			return nil, nil, errors.New("no account exists for this email")
+		}
+		if user, err = u.provision(ctx, email, claims); err != nil {
+			return nil, nil, err
+		}
+		action = domain.ActionUserProvision
+	}
+	if user.ServiceAccount {
+		return nil, nil, errors.New("service accounts cannot sign in")
+	}
+
+	identity = &domain.ExternalIdentity{
+		UserID:  user.ID,
+		Issuer:  claims.Issuer,
+		Subject: claims.Subject,
+		Email:   email,
+	}
+	if err := u.identityRepo.Create(ctx, identity); err != nil {
+		return nil, nil, err
+	}
+
+	u.audit(ctx, user.ID, action, map[string]interface{}{
+		"issuer":  claims.Issuer,
+		"subject": claims.Subject,
+		"role":    user.Role,
+	})
+	logger.InfoLogger.Printf("[SECURITY] SSO identity %s linked to user %d (%s)", claims.Subject, user.ID, action)
+
+	return user, identity, nil
+}
+
+// provision creates an account for a first-time SSO user. It has no
+// password, so it can only log in through the identity provider.
+func (u *ssoUsecase) provision(ctx context.Context, email string, claims *domain.IdentityClaims) (*domain.User, error) {
+	name := strings.TrimSpace(claims.Name)
+	if name == "" {
+		name = email
+	}
+
+	user := &domain.User{
+		Email: email,
+		Name:  name,
+		Role:  u.mapRole(claims.Groups),
+	}
+	if err := u.userRepo.Create(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// syncRole makes the user's role follow their groups at the identity
+// provider (OIDC_SYNC_ROLES).
+func (u *ssoUsecase) syncRole(ctx context.Context, user *domain.User, groups []string) error {
+	role := u.mapRole(groups)
+	if role == user.Role {
+		return nil
+	}
+
+	if err := u.userRepo.UpdateRole(ctx, user.ID, role); err != nil {
+		return err
+	}
+
+	u.audit(ctx, user.ID, domain.ActionRoleChange, map[string]interface{}{
+		"old_role": user.Role,
+		"new_role": role,
+		"source":   "sso",
+	})
+	logger.InfoLogger.Printf("User %d role changed from %s to %s by identity provider groups", user.ID, user.Role, role)
+
+	user.Role = role
+	return nil
+}
+
+func (u *ssoUsecase) mapRole(groups []string) string {
+	matched := map[string]bool{}
+	for _, group := range groups {
+		if role, ok := u.roleByGroup[group]; ok {
+			matched[role] = true
+		}
+	}
+
+	for _, role := range rolePrecedence {
+		if matched[role] {
+			return role
+		}
+	}
+	return domain.RoleEmployee
+}
+
+func (u *ssoUsecase) audit(ctx context.Context, userID int, action string, metadata map[string]interface{}) {
+	auditLog := &domain.AuditLog{
+		SubjectUserID: &userID,
+		UserID:        &userID,
+		Action:        action,
+		Metadata:      metadata,
+	}
+	u.auditRepo.Create(ctx, auditLog)
+}
+
+// parseRoleMapping reads "group=role" entries; entries that are not a
+// built-in role, or are the service role, are skipped.
+func parseRoleMapping(entries []string) map[string]string {
+	mapping := map[string]string{}
+	for _, entry := range entries {
+		group, role, ok := strings.Cut(entry, "=")
+		group, role = strings.TrimSpace(group), strings.TrimSpace(role)
+		if !ok || group == "" || !domain.IsBuiltInRole(role) || role == domain.RoleService {
+			logger.ErrorLogger.Printf("Ignoring invalid OIDC_ROLE_MAPPING entry %q", entry)
+			continue
+		}
+		mapping[group] = role
+	}
+	return mapping
+}
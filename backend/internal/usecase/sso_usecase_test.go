@@ -0,0 +1,279 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"expense-management-system/internal/domain"
+	"expense-management-system/internal/jwtkeys"
+	"expense-management-system/internal/oidc"
+	"expense-management-system/internal/oidc/oidctest"
+	"expense-management-system/pkg/config"
+	"testing"
+	"time"
+)
+
+type mockIdentityRepo struct {
+	identities []*domain.ExternalIdentity
+	requests   map[string]*domain.SSOAuthRequest
+}
+
+func newMockIdentityRepo() *mockIdentityRepo {
+	return &mockIdentityRepo{requests: map[string]*domain.SSOAuthRequest{}}
+}
+
+func (m *mockIdentityRepo) GetByIssuerSubject(ctx context.Context, issuer, subject string) (*domain.ExternalIdentity, error) {
+	for _, identity := range m.identities {
+		if identity.Issuer == issuer && identity.Subject == subject {
+			return identity, nil
+		}
+	}
+	return nil, errors.New("identity not found")
+}
+
+func (m *mockIdentityRepo) Create(ctx context.Context, identity *domain.ExternalIdentity) error {
+	identity.ID = len(m.identities) + 1
+	m.identities = append(m.identities, identity)
+	return nil
+}
+
+func (m *mockIdentityRepo) TouchLogin(ctx context.Context, id int) error {
+	now := time.Now()
+	m.identities[id-1].LastLoginAt = &now
+	return nil
+}
+
+func (m *mockIdentityRepo) CreateAuthRequest(ctx context.Context, request *domain.SSOAuthRequest) error {
+	m.requests[request.StateHash] = request
+	return nil
+}
+
+func (m *mockIdentityRepo) ConsumeAuthRequest(ctx context.Context, stateHash string) (*domain.SSOAuthRequest, error) {
+	request, ok := m.requests[stateHash]
+	if !ok {
+		return nil, errors.New("sso request not found")
+	}
+	delete(m.requests, stateHash)
+	return request, nil
+}
+
+type ssoTestEnv struct {
+	uc           domain.SSOUsecase
+	issuer       *oidctest.Issuer
+	identityRepo *mockIdentityRepo
+	users        map[int]*domain.User
+	audits       []*domain.AuditLog
+}
+
+func newSSOTestEnv(t *testing.T, cfg *config.Config) *ssoTestEnv {
+	t.Helper()
+
+	issuer, server, err := oidctest.NewTestServer("expense-app", "expense-secret")
+	if err != nil {
+		t.Fatalf("NewTestServer() unexpected error = %v", err)
+	}
+	t.Cleanup(server.Close)
+
+	env := &ssoTestEnv{
+		issuer:       issuer,
+		identityRepo: newMockIdentityRepo(),
+		users: map[int]*domain.User{
+			2: {ID: 2, Email: "manager@example.com", Name: "Manager", Role: domain.RoleManager, Active: true},
+		},
+	}
+
+	userRepo := &mockUserRepo{
+		getByIDFunc: func(ctx context.Context, id int) (*domain.User, error) {
+			user, ok := env.users[id]
+			if !ok {
+				return nil, errors.New("user not found")
+			}
+			copy := *user
+			return &copy, nil
+		},
+		getByEmailFunc: func(ctx context.Context, email string) (*domain.User, error) {
+			for _, user := range env.users {
+				if user.Email == email {
+					copy := *user
+					return &copy, nil
+				}
+			}
+			return nil, errors.New("user not found")
+		},
+		createFunc: func(ctx context.Context, user *domain.User) error {
+			user.ID = 100 + len(env.users)
+			user.Active = true
+			copy := *user
+			env.users[user.ID] = &copy
+			return nil
+		},
+		updateRoleFunc: func(ctx context.Context, id int, role string) error {
+			env.users[id].Role = role
+			return nil
+		},
+	}
+	auditRepo := &mockAuditRepo{
+		createFunc: func(ctx context.Context, log *domain.AuditLog) error {
+			env.audits = append(env.audits, log)
+			return nil
+		},
+	}
+
+	cfg.JWTSecret = "test-secret-key"
+	cfg.AccessTokenTTLMinutes = 15
+	cfg.RefreshTokenTTLHours = 720
+
+	provider := oidc.NewProvider(oidc.Config{
+		IssuerURL:    issuer.URL,
+		ClientID:     "expense-app",
+		ClientSecret: "expense-secret",
+		RedirectURL:  "http://localhost:3000/auth/callback",
+	})
+	auth := NewAuthUsecase(userRepo, newMockTokenRepo(), newMockLoginAttemptRepo(), nil, &mockNotifier{}, jwtkeys.NewHMACSigner(cfg.JWTSecret), cfg)
+	env.uc = NewSSOUsecase(provider, env.identityRepo, userRepo, auditRepo, auth, cfg)
+
+	return env
+}
+
+// login runs the whole browser round trip as identity.
+func (env *ssoTestEnv) login(t *testing.T, identity *oidctest.Identity) (*domain.LoginResult, error) {
+	t.Helper()
+
+	authURL, err := env.uc.Begin(context.Background())
+	if err != nil {
+		t.Fatalf("Begin() unexpected error = %v", err)
+	}
+
+	env.issuer.SetIdentity(identity)
+	code, state, err := env.issuer.Authorize(authURL)
+	if err != nil {
+		t.Fatalf("Authorize() unexpected error = %v", err)
+	}
+
+	return env.uc.Complete(context.Background(), code, state)
+}
+
+func TestSSOUsecase_ProvisionsNewUser(t *testing.T) {
+	env := newSSOTestEnv(t, &config.Config{
+		OIDCAutoProvision:        true,
+		OIDCRequireVerifiedEmail: true,
+		OIDCRoleMapping:          []string{"expense-finance=finance", "expense-managers=manager", "bogus=owner"},
+	})
+	identity := &oidctest.Identity{
+		Subject:       "idp-42",
+		Email:         "New.Hire@Example.com",
+		EmailVerified: true,
+		Name:          "New Hire",
+		Groups:        []string{"staff", "expense-managers", "expense-finance"},
+	}
+
+	result, err := env.login(t, identity)
+	if err != nil {
+		t.Fatalf("Complete() unexpected error = %v", err)
+	}
+	if result.Tokens == nil {
+		t.Fatal("Expected tokens for an SSO login")
+	}
+
+	user := result.User
+	if user.Email != "new.hire@example.com" || user.Name != "New Hire" || user.PasswordHash != "" {
+		t.Errorf("Unexpected provisioned user %+v", user)
+	}
+	if user.Role != domain.RoleFinance {
+		t.Errorf("Provisioned role = %v, want finance (highest mapped group)", user.Role)
+	}
+	if len(env.audits) != 1 || env.audits[0].Action != domain.ActionUserProvision {
+		t.Errorf("Expected a user_provision audit entry, got %+v", env.audits)
+	}
+
+	again, err := env.login(t, identity)
+	if err != nil {
+		t.Fatalf("Second Complete() unexpected error = %v", err)
+	}
+	if again.User.ID != user.ID || len(env.users) != 2 || len(env.identityRepo.identities) != 1 {
+		t.Error("Expected the second login to reuse the linked account")
+	}
+	if env.identityRepo.identities[0].LastLoginAt == nil {
+		t.Error("Expected the identity's last login to be recorded")
+	}
+}
+
+func TestSSOUsecase_LinksExistingAccountByEmail(t *testing.T) {
+	env := newSSOTestEnv(t, &config.Config{OIDCRequireVerifiedEmail: true})
+
+	_, err := env.login(t, &oidctest.Identity{Subject: "idp-7", Email: "manager@example.com", EmailVerified: false})
+	if err == nil {
+		t.Fatal("Expected an unverified email not to be linked")
+	}
+
+	result, err := env.login(t, &oidctest.Identity{Subject: "idp-7", Email: "manager@example.com", EmailVerified: true, Groups: []string{"nobody"}})
+	if err != nil {
+		t.Fatalf("Complete() unexpected error = %v", err)
+	}
+	if result.User.ID != 2 || result.User.Role != domain.RoleManager {
+		t.Errorf("Expected the existing manager account unchanged, got %+v", result.User)
+	}
+	if len(env.audits) != 1 || env.audits[0].Action != domain.ActionIdentityLink {
+		t.Errorf("Expected an identity_link audit entry, got %+v", env.audits)
+	}
+}
+
+func TestSSOUsecase_AutoProvisionDisabled(t *testing.T) {
+	env := newSSOTestEnv(t, &config.Config{OIDCAutoProvision: false})
+
+	if _, err := env.login(t, &oidctest.Identity{Subject: "idp-9", Email: "stranger@example.com", EmailVerified: true}); err == nil {
+		t.Error("Expected an unknown email to be refused without auto-provisioning")
+	}
+	if len(env.users) != 1 {
+		t.Error("Expected no user to be created")
+	}
+}
+
+func TestSSOUsecase_SyncRoles(t *testing.T) {
+	env := newSSOTestEnv(t, &config.Config{
+		OIDCSyncRoles:   true,
+		OIDCRoleMapping: []string{"expense-admins=admin"},
+	})
+
+	result, err := env.login(t, &oidctest.Identity{Subject: "idp-7", Email: "manager@example.com", EmailVerified: true, Groups: []string{"expense-admins"}})
+	if err != nil {
+		t.Fatalf("Complete() unexpected error = %v", err)
+	}
+	if result.User.Role != domain.RoleAdmin || env.users[2].Role != domain.RoleAdmin {
+		t.Errorf("Expected the role to follow IdP groups, got %v", result.User.Role)
+	}
+
+	last := env.audits[len(env.audits)-1]
+	if last.Action != domain.ActionRoleChange || last.Metadata["source"] != "sso" {
+		t.Errorf("Expected an SSO role_change audit entry, got %+v", last)
+	}
+}
+
+func TestSSOUsecase_RejectsReusedState(t *testing.T) {
+	env := newSSOTestEnv(t, &config.Config{OIDCAutoProvision: true})
+	ctx := context.Background()
+
+	authURL, err := env.uc.Begin(ctx)
+	if err != nil {
+		t.Fatalf("Begin() unexpected error = %v", err)
+	}
+	env.issuer.SetIdentity(&oidctest.Identity{Subject: "idp-1", Email: "manager@example.com", EmailVerified: true})
+	code, state, _ := env.issuer.Authorize(authURL)
+
+	if _, err := env.uc.Complete(ctx, code, "forged-state"); err == nil {
+		t.Error("Expected an unknown state to be rejected")
+	}
+	if _, err := env.uc.Complete(ctx, code, state); err != nil {
+		t.Fatalf("Complete() unexpected error = %v", err)
+	}
+	if _, err := env.uc.Complete(ctx, code, state); err == nil {
+		t.Error("Expected a used state to be rejected")
+	}
+}
+
+func TestSSOUsecase_NotConfigured(t *testing.T) {
+	uc := NewSSOUsecase(nil, newMockIdentityRepo(), &mockUserRepo{}, &mockAuditRepo{}, nil, &config.Config{})
+
+	if _, err := uc.Begin(context.Background()); !errors.Is(err, domain.ErrSSONotConfigured) {
+		t.Errorf("Begin() error = %v, want ErrSSONotConfigured", err)
+	}
+}
@@ -0,0 +1,64 @@
+package usecase
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA1 test key from RFC 6238 appendix B, base32 encoded.
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode(t *testing.T) {
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{unix: 59, want: "287082"},
+		{unix: 1111111109, want: "081804"},
+		{unix: 1234567890, want: "005924"},
+		{unix: 2000000000, want: "279037"},
+	}
+
+	for _, tt := range tests {
+		got, err := totpCode(rfc6238Secret, totpStep(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("totpCode() unexpected error = %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("totpCode(%d) = %v, want %v", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestVerifyTOTP(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	current := totpStep(now)
+
+	previous, _ := totpCode(rfc6238Secret, current-1)
+	if step, ok := verifyTOTP(rfc6238Secret, previous, now); !ok || step != current-1 {
+		t.Errorf("Expected the previous step's code to be accepted, got step %d ok %v", step, ok)
+	}
+
+	stale, _ := totpCode(rfc6238Secret, current-2)
+	if _, ok := verifyTOTP(rfc6238Secret, stale, now); ok {
+		t.Error("Expected a code two steps old to be rejected")
+	}
+
+	if _, ok := verifyTOTP(rfc6238Secret, "12345", now); ok {
+		t.Error("Expected a short code to be rejected")
+	}
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := totpProvisioningURI("Expense Management", "manager@example.com", "ABC")
+
+	if !strings.HasPrefix(uri, "otpauth://totp/Expense%20Management:manager@example.com?") {
+		t.Errorf("Unexpected provisioning URI label: %s", uri)
+	}
+	for _, param := range []string{"secret=ABC", "issuer=Expense+Management", "digits=6", "period=30"} {
+		if !strings.Contains(uri, param) {
+			t.Errorf("Expected %s in provisioning URI %s", param, uri)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"expense-management-system/internal/domain"
+	"expense-management-system/pkg/config"
+	"expense-management-system/pkg/logger"
+	"strings"
+	"time"
+)
+
+// recoveryCodeCount is how many single-use recovery codes a user gets.
+const recoveryCodeCount = 10
+
+type twoFactorUsecase struct {
+	twoFactorRepo domain.TwoFactorRepository
+	userRepo      domain.UserRepository
+	cfg           *config.Config
+}
+
+func NewTwoFactorUsecase(twoFactorRepo domain.TwoFactorRepository, userRepo domain.UserRepository, cfg *config.Config) domain.TwoFactorUsecase {
+	return &twoFactorUsecase{
+		twoFactorRepo: twoFactorRepo,
+		userRepo:      userRepo,
+		cfg:           cfg,
+	}
+}
+
+func (u *twoFactorUsecase) Status(ctx context.Context, user *domain.User) (*domain.TwoFactorStatus, error) {
+	enabled, err := u.IsEnabled(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &domain.TwoFactorStatus{
+		Enabled:  enabled,
+		Required: u.IsRequired(user),
+	}
+	if enabled {
+		remaining, err := u.twoFactorRepo.CountRecoveryCodes(ctx, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		status.RecoveryCodesRemaining = remaining
+	}
+
+	return status, nil
+}
+
+func (u *twoFactorUsecase) IsEnabled(ctx context.Context, userID int) (bool, error) {
+	secret, err := u.twoFactorRepo.Get(ctx, userID)
+	if err != nil {
+		return false, nil
+	}
+	return secret.EnabledAt != nil, nil
+}
+
+// IsRequired reports whether the user's role must use two-factor
+// authentication (TOTP_REQUIRED_ROLES).
+func (u *twoFactorUsecase) IsRequired(user *domain.User) bool {
+	for _, role := range u.cfg.TOTPRequiredRoles {
+		if role == user.Role {
+			return true
+		}
+	}
+	return false
+}
+
+// Setup creates a new pending secret. It only takes effect once Enable is
+// called with a code from it, so an abandoned setup changes nothing.
+func (u *twoFactorUsecase) Setup(ctx context.Context, user *domain.User) (*domain.TwoFactorSetup, error) {
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.twoFactorRepo.SavePending(ctx, user.ID, secret); err != nil {
+		return nil, err
+	}
+
+	return &domain.TwoFactorSetup{
+		Secret:          secret,
+		ProvisioningURI: totpProvisioningURI(u.cfg.TOTPIssuer, user.Email, secret),
+	}, nil
+}
+
+func (u *twoFactorUsecase) Enable(ctx context.Context, user *domain.User, code string) ([]string, error) {
+	secret, err := u.twoFactorRepo.Get(ctx, user.ID)
+	if err != nil {
+		return nil, errors.New("two-factor setup has not been started")
+	}
+	if secret.EnabledAt != nil {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+
+	if err := u.verifyTOTP(ctx, secret, code); err != nil {
+		return nil, err
+	}
+
+	if err := u.twoFactorRepo.Enable(ctx, user.ID); err != nil {
+		return nil, err
+	}
+
+	codes, err := u.newRecoveryCodes(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.InfoLogger.Printf("[SECURITY] Two-factor authentication enabled for user %d", user.ID)
+	return codes, nil
+}
+
+func (u *twoFactorUsecase) Disable(ctx context.Context, user *domain.User, code string) error {
+	if u.IsRequired(user) {
+		return errors.New("two-factor authentication is required for your role")
+	}
+
+	if err := u.Verify(ctx, user.ID, code, true); err != nil {
+		return err
+	}
+
+	if err := u.twoFactorRepo.Delete(ctx, user.ID); err != nil {
+		return err
+	}
+
+	logger.InfoLogger.Printf("[SECURITY] Two-factor authentication disabled for user %d", user.ID)
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes; the old ones stop
+// working. It needs a TOTP code, not a recovery code.
+func (u *twoFactorUsecase) RegenerateRecoveryCodes(ctx context.Context, user *domain.User, code string) ([]string, error) {
+	if err := u.Verify(ctx, user.ID, code, false); err != nil {
+		return nil, err
+	}
+
+	return u.newRecoveryCodes(ctx, user.ID)
+}
+
+func (u *twoFactorUsecase) Verify(ctx context.Context, userID int, code string, allowRecovery bool) error {
+	secret, err := u.twoFactorRepo.Get(ctx, userID)
+	if err != nil || secret.EnabledAt == nil {
+		return errors.New("two-factor authentication is not enabled")
+	}
+
+	code = strings.TrimSpace(code)
+	if allowRecovery && len(code) != totpDigits {
+		used, err := u.twoFactorRepo.UseRecoveryCode(ctx, userID, hashToken(normalizeRecoveryCode(code)))
+		if err != nil {
+			return err
+		}
+		if !used {
+			return domain.ErrInvalidTwoFactorCode
+		}
+		logger.InfoLogger.Printf("[SECURITY] Recovery code used by user %d", userID)
+		return nil
+	}
+
+	return u.verifyTOTP(ctx, secret, code)
+}
+
+// VerifyStepUp is a no-op for amounts up to APPROVAL_STEP_UP_AMOUNT_IDR (or
+// when the threshold is 0). Above it the user must have two-factor enabled and
+// give a current TOTP code; recovery codes are not accepted. Wrong codes count
+// towards the account lockout like wrong passwords do, so a stolen access
+// token cannot be used to guess them.
+func (u *twoFactorUsecase) VerifyStepUp(ctx context.Context, userID int, amountIDR int, code string) error {
+	if u.cfg.StepUpAmountIDR <= 0 || amountIDR <= u.cfg.StepUpAmountIDR {
+		return nil
+	}
+
+	enabled, err := u.IsEnabled(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return errors.New("two-factor authentication must be enabled to approve this amount")
+	}
+	if strings.TrimSpace(code) == "" {
+		return domain.ErrStepUpRequired
+	}
+
+	user, err := u.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if user.LockedUntil != nil && now.Before(*user.LockedUntil) {
+		return domain.ErrAccountLocked
+	}
+
+	err = u.Verify(ctx, userID, code, false)
+	if errors.Is(err, domain.ErrInvalidTwoFactorCode) {
+		u.recordFailure(ctx, userID, now)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	if user.FailedLoginAttempts > 0 {
+		if err := u.userRepo.ResetLoginFailures(ctx, userID); err != nil {
+			logger.ErrorLogger.Printf("Failed to reset login failures of user %d: %v", userID, err)
+		}
+	}
+	return nil
+}
+
+func (u *twoFactorUsecase) recordFailure(ctx context.Context, userID int, now time.Time) {
+	windowStart := now.Add(-time.Duration(u.cfg.LoginAttemptWindowMinutes) * time.Minute)
+	lockUntil := now.Add(time.Duration(u.cfg.LoginLockoutMinutes) * time.Minute)
+	updated, err := u.userRepo.RecordLoginFailure(ctx, userID, u.cfg.LoginMaxAttempts, windowStart, lockUntil)
+	if err != nil {
+		logger.ErrorLogger.Printf("Failed to record step-up failure of user %d: %v", userID, err)
+		return
+	}
+
+	logger.ErrorLogger.Printf("[SECURITY] Invalid step-up code for user %d", userID)
+	if updated.LockedUntil != nil {
+		logger.ErrorLogger.Printf("[SECURITY] User %d locked until %s after %d failed attempts", userID, updated.LockedUntil.Format(time.RFC3339), updated.FailedLoginAttempts)
+	}
+}
+
+// verifyTOTP checks the code and records its time step so the same code
+// cannot be used twice.
+func (u *twoFactorUsecase) verifyTOTP(ctx context.Context, secret *domain.TwoFactorSecret, code string) error {
+	step, ok := verifyTOTP(secret.Secret, code, time.Now())
+	if !ok {
+		return domain.ErrInvalidTwoFactorCode
+	}
+
+	fresh, err := u.twoFactorRepo.UseStep(ctx, secret.UserID, step)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return domain.ErrInvalidTwoFactorCode
+	}
+
+	return nil
+}
+
+func (u *twoFactorUsecase) newRecoveryCodes(ctx context.Context, userID int) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 4)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		raw := hex.EncodeToString(b)
+		codes[i] = raw[:4] + "-" + raw[4:]
+		hashes[i] = hashToken(raw)
+	}
+
+	if err := u.twoFactorRepo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// normalizeRecoveryCode accepts codes with or without the dash and in any
+// case.
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(code, "-", ""))
+}
@@ -0,0 +1,299 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"expense-management-system/internal/domain"
+	"expense-management-system/pkg/config"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type mockTwoFactorRepo struct {
+	secrets       map[int]*domain.TwoFactorSecret
+	recoveryCodes map[int]map[string]bool
+}
+
+func newMockTwoFactorRepo() *mockTwoFactorRepo {
+	return &mockTwoFactorRepo{
+		secrets:       map[int]*domain.TwoFactorSecret{},
+		recoveryCodes: map[int]map[string]bool{},
+	}
+}
+
+func (m *mockTwoFactorRepo) Get(ctx context.Context, userID int) (*domain.TwoFactorSecret, error) {
+	secret, ok := m.secrets[userID]
+	if !ok {
+		return nil, errors.New("two-factor authentication not set up")
+	}
+	copy := *secret
+	return &copy, nil
+}
+
+func (m *mockTwoFactorRepo) SavePending(ctx context.Context, userID int, secret string) error {
+	if existing, ok := m.secrets[userID]; ok && existing.EnabledAt != nil {
+		return errors.New("two-factor authentication is already enabled")
+	}
+	m.secrets[userID] = &domain.TwoFactorSecret{UserID: userID, Secret: secret, CreatedAt: time.Now()}
+	return nil
+}
+
+func (m *mockTwoFactorRepo) Enable(ctx context.Context, userID int) error {
+	now := time.Now()
+	m.secrets[userID].EnabledAt = &now
+	return nil
+}
+
+func (m *mockTwoFactorRepo) Delete(ctx context.Context, userID int) error {
+	delete(m.secrets, userID)
+	delete(m.recoveryCodes, userID)
+	return nil
+}
+
+func (m *mockTwoFactorRepo) UseStep(ctx context.Context, userID int, step int64) (bool, error) {
+	secret := m.secrets[userID]
+	if secret.LastUsedStep >= step {
+		return false, nil
+	}
+	secret.LastUsedStep = step
+	return true, nil
+}
+
+func (m *mockTwoFactorRepo) ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error {
+	m.recoveryCodes[userID] = map[string]bool{}
+	for _, hash := range codeHashes {
+		m.recoveryCodes[userID][hash] = false
+	}
+	return nil
+}
+
+func (m *mockTwoFactorRepo) UseRecoveryCode(ctx context.Context, userID int, codeHash string) (bool, error) {
+	used, ok := m.recoveryCodes[userID][codeHash]
+	if !ok || used {
+		return false, nil
+	}
+	m.recoveryCodes[userID][codeHash] = true
+	return true, nil
+}
+
+func (m *mockTwoFactorRepo) CountRecoveryCodes(ctx context.Context, userID int) (int, error) {
+	count := 0
+	for _, used := range m.recoveryCodes[userID] {
+		if !used {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func newTestTwoFactorUsecase(requiredRoles ...string) (domain.TwoFactorUsecase, *mockTwoFactorRepo) {
+	repo := newMockTwoFactorRepo()
+	cfg := &config.Config{
+		TOTPIssuer:        "Expense Management",
+		TOTPRequiredRoles: requiredRoles,
+		StepUpAmountIDR:   10000000,
+	}
+	return NewTwoFactorUsecase(repo, &mockUserRepo{}, cfg), repo
+}
+
+// currentCode returns the code for the user's secret at the given offset in
+// time steps from now.
+func currentCode(t *testing.T, repo *mockTwoFactorRepo, userID int, offset int64) string {
+	t.Helper()
+	code, err := totpCode(repo.secrets[userID].Secret, totpStep(time.Now())+offset)
+	if err != nil {
+		t.Fatalf("totpCode() unexpected error = %v", err)
+	}
+	return code
+}
+
+// enrolUser sets up and enables two-factor authentication and returns the
+// recovery codes.
+func enrolUser(t *testing.T, uc domain.TwoFactorUsecase, repo *mockTwoFactorRepo, user *domain.User) []string {
+	t.Helper()
+	if _, err := uc.Setup(context.Background(), user); err != nil {
+		t.Fatalf("Setup() unexpected error = %v", err)
+	}
+	codes, err := uc.Enable(context.Background(), user, currentCode(t, repo, user.ID, -1))
+	if err != nil {
+		t.Fatalf("Enable() unexpected error = %v", err)
+	}
+	return codes
+}
+
+func TestTwoFactorUsecase_Enrolment(t *testing.T) {
+	ctx := context.Background()
+	uc, repo := newTestTwoFactorUsecase()
+	user := &domain.User{ID: 3, Email: "manager@example.com", Role: domain.RoleManager}
+
+	setup, err := uc.Setup(ctx, user)
+	if err != nil {
+		t.Fatalf("Setup() unexpected error = %v", err)
+	}
+	if setup.Secret == "" || setup.ProvisioningURI == "" {
+		t.Errorf("Expected a secret and provisioning URI, got %+v", setup)
+	}
+	if enabled, _ := uc.IsEnabled(ctx, user.ID); enabled {
+		t.Error("Expected a pending setup not to be enabled")
+	}
+
+	if _, err := uc.Enable(ctx, user, "000000"); !errors.Is(err, domain.ErrInvalidTwoFactorCode) {
+		t.Errorf("Enable() with a wrong code error = %v, want ErrInvalidTwoFactorCode", err)
+	}
+
+	codes, err := uc.Enable(ctx, user, currentCode(t, repo, user.ID, 0))
+	if err != nil {
+		t.Fatalf("Enable() unexpected error = %v", err)
+	}
+	if len(codes) != recoveryCodeCount {
+		t.Errorf("Expected %d recovery codes, got %d", recoveryCodeCount, len(codes))
+	}
+
+	status, err := uc.Status(ctx, user)
+	if err != nil {
+		t.Fatalf("Status() unexpected error = %v", err)
+	}
+	if !status.Enabled || status.RecoveryCodesRemaining != recoveryCodeCount {
+		t.Errorf("Unexpected status %+v", status)
+	}
+
+	if _, err := uc.Setup(ctx, user); err == nil {
+		t.Error("Expected Setup() to fail once two-factor is enabled")
+	}
+}
+
+func TestTwoFactorUsecase_Verify(t *testing.T) {
+	ctx := context.Background()
+	uc, repo := newTestTwoFactorUsecase()
+	user := &domain.User{ID: 3, Email: "manager@example.com", Role: domain.RoleManager}
+	codes := enrolUser(t, uc, repo, user)
+
+	code := currentCode(t, repo, user.ID, 0)
+	if err := uc.Verify(ctx, user.ID, code, false); err != nil {
+		t.Fatalf("Verify() unexpected error = %v", err)
+	}
+	if err := uc.Verify(ctx, user.ID, code, false); !errors.Is(err, domain.ErrInvalidTwoFactorCode) {
+		t.Errorf("Expected a replayed code to be rejected, got %v", err)
+	}
+
+	if err := uc.Verify(ctx, user.ID, codes[0], false); err == nil {
+		t.Error("Expected recovery codes to be refused when not allowed")
+	}
+	if err := uc.Verify(ctx, user.ID, codes[0], true); err != nil {
+		t.Fatalf("Verify() with recovery code unexpected error = %v", err)
+	}
+	if err := uc.Verify(ctx, user.ID, codes[0], true); !errors.Is(err, domain.ErrInvalidTwoFactorCode) {
+		t.Errorf("Expected a used recovery code to be rejected, got %v", err)
+	}
+}
+
+func TestTwoFactorUsecase_Disable(t *testing.T) {
+	ctx := context.Background()
+	uc, repo := newTestTwoFactorUsecase(domain.RoleFinance)
+
+	finance := &domain.User{ID: 4, Email: "finance@example.com", Role: domain.RoleFinance}
+	codes := enrolUser(t, uc, repo, finance)
+	if err := uc.Disable(ctx, finance, codes[0]); err == nil {
+		t.Error("Expected a required role to be unable to disable two-factor")
+	}
+
+	employee := &domain.User{ID: 1, Email: "employee1@example.com", Role: domain.RoleEmployee}
+	codes = enrolUser(t, uc, repo, employee)
+	if err := uc.Disable(ctx, employee, codes[0]); err != nil {
+		t.Fatalf("Disable() unexpected error = %v", err)
+	}
+	if enabled, _ := uc.IsEnabled(ctx, employee.ID); enabled {
+		t.Error("Expected two-factor to be disabled")
+	}
+}
+
+func TestTwoFactorUsecase_VerifyStepUp(t *testing.T) {
+	ctx := context.Background()
+	uc, repo := newTestTwoFactorUsecase()
+	user := &domain.User{ID: 3, Email: "manager@example.com", Role: domain.RoleManager}
+
+	if err := uc.VerifyStepUp(ctx, user.ID, 10000000, ""); err != nil {
+		t.Errorf("Expected no step-up at the threshold, got %v", err)
+	}
+	if err := uc.VerifyStepUp(ctx, user.ID, 10000001, ""); err == nil {
+		t.Error("Expected step-up to fail without two-factor enabled")
+	}
+
+	codes := enrolUser(t, uc, repo, user)
+
+	if err := uc.VerifyStepUp(ctx, user.ID, 10000001, ""); !errors.Is(err, domain.ErrStepUpRequired) {
+		t.Errorf("VerifyStepUp() without code error = %v, want ErrStepUpRequired", err)
+	}
+	if err := uc.VerifyStepUp(ctx, user.ID, 10000001, codes[0]); err == nil {
+		t.Error("Expected recovery codes to be refused for step-up")
+	}
+	if err := uc.VerifyStepUp(ctx, user.ID, 10000001, currentCode(t, repo, user.ID, 0)); err != nil {
+		t.Errorf("VerifyStepUp() unexpected error = %v", err)
+	}
+}
+
+func TestTwoFactorUsecase_StepUpLockout(t *testing.T) {
+	ctx := context.Background()
+	manager := &domain.User{ID: 3, Email: "manager@example.com", Role: domain.RoleManager, Active: true}
+	userRepo := &mockUserRepo{
+		getByIDFunc: func(ctx context.Context, id int) (*domain.User, error) {
+			copy := *manager
+			return &copy, nil
+		},
+		recordLoginFailureFunc: func(ctx context.Context, id int, maxAttempts int, windowStart, lockUntil time.Time) (*domain.User, error) {
+			manager.FailedLoginAttempts++
+			if manager.FailedLoginAttempts >= maxAttempts {
+				manager.LockedUntil = &lockUntil
+			}
+			copy := *manager
+			return &copy, nil
+		},
+		resetLoginFailuresFunc: func(ctx context.Context, id int) error {
+			manager.FailedLoginAttempts = 0
+			manager.LockedUntil = nil
+			return nil
+		},
+	}
+	repo := newMockTwoFactorRepo()
+	uc := NewTwoFactorUsecase(repo, userRepo, &config.Config{
+		TOTPIssuer:                "Expense Management",
+		StepUpAmountIDR:           10000000,
+		LoginMaxAttempts:          5,
+		LoginAttemptWindowMinutes: 15,
+		LoginLockoutMinutes:       15,
+	})
+	enrolUser(t, uc, repo, manager)
+
+	// A correct code clears earlier mistakes.
+	if err := uc.VerifyStepUp(ctx, manager.ID, 20000000, wrongCode(t, repo, manager.ID)); !errors.Is(err, domain.ErrInvalidTwoFactorCode) {
+		t.Fatalf("VerifyStepUp() with a wrong code error = %v, want ErrInvalidTwoFactorCode", err)
+	}
+	if err := uc.VerifyStepUp(ctx, manager.ID, 20000000, currentCode(t, repo, manager.ID, 0)); err != nil {
+		t.Fatalf("VerifyStepUp() unexpected error = %v", err)
+	}
+	if manager.FailedLoginAttempts != 0 {
+		t.Errorf("failed attempts = %d after a correct code, want 0", manager.FailedLoginAttempts)
+	}
+
+	for i := 0; i < 5; i++ {
+		if err := uc.VerifyStepUp(ctx, manager.ID, 20000000, wrongCode(t, repo, manager.ID)); !errors.Is(err, domain.ErrInvalidTwoFactorCode) {
+			t.Fatalf("attempt %d: VerifyStepUp() error = %v, want ErrInvalidTwoFactorCode", i+1, err)
+		}
+	}
+	if manager.LockedUntil == nil {
+		t.Fatal("Expected the account to be locked after 5 wrong codes")
+	}
+
+	// Once locked, not even the right code is checked.
+	if err := uc.VerifyStepUp(ctx, manager.ID, 20000000, currentCode(t, repo, manager.ID, 1)); !errors.Is(err, domain.ErrAccountLocked) {
+		t.Errorf("VerifyStepUp() while locked error = %v, want ErrAccountLocked", err)
+	}
+}
+
+// wrongCode returns a well-formed code that is not the current one.
+func wrongCode(t *testing.T, repo *mockTwoFactorRepo, userID int) string {
+	code, _ := strconv.Atoi(currentCode(t, repo, userID, 0))
+	return fmt.Sprintf("%06d", (code+500000)%1000000)
+}
@@ -0,0 +1,359 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"expense-management-system/internal/domain"
+	"expense-management-system/pkg/config"
+	"expense-management-system/pkg/logger"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// recentLoginAttempts is how many login attempts the lockout status lists.
+const recentLoginAttempts = 20
+
+// maxAuditLogPage caps the page size of a user's audit logs.
+const maxAuditLogPage = 500
+
+type userAdminUsecase struct {
+	userRepo       domain.UserRepository
+	auditRepo      domain.AuditLogRepository
+	roleRepo       domain.RoleRepository
+	tokenRepo      domain.TokenRepository
+	attemptRepo    domain.LoginAttemptRepository
+	accessTokenTTL time.Duration
+}
+
+func NewUserAdminUsecase(userRepo domain.UserRepository, auditRepo domain.AuditLogRepository, roleRepo domain.RoleRepository, tokenRepo domain.TokenRepository, attemptRepo domain.LoginAttemptRepository, cfg *config.Config) domain.UserAdminUsecase {
+	return &userAdminUsecase{
+		userRepo:       userRepo,
+		auditRepo:      auditRepo,
+		roleRepo:       roleRepo,
+		tokenRepo:      tokenRepo,
+		attemptRepo:    attemptRepo,
+		accessTokenTTL: time.Duration(cfg.AccessTokenTTLMinutes) * time.Minute,
+	}
+}
+
+func (u *userAdminUsecase) Create(ctx context.Context, adminID int, granted []string, user *domain.User, password string) (*domain.User, error) {
+	user.Email = strings.TrimSpace(strings.ToLower(user.Email))
+	user.Name = strings.TrimSpace(user.Name)
+
+	if err := validateEmail(user.Email); err != nil {
+		return nil, err
+	}
+	if user.Name == "" {
+		return nil, errors.New("name is required")
+	}
+	if user.Role == "" {
+		user.Role = domain.RoleEmployee
+	}
+	if user.Role == domain.RoleService {
+		return nil, errServiceRoleReserved
+	}
+	if err := u.checkGrant(ctx, granted, user.Role); err != nil {
+		return nil, err
+	}
+	if err := ValidatePassword(password, user.Email); err != nil {
+		return nil, err
+	}
+
+	if _, err := u.userRepo.GetByEmail(ctx, user.Email); err == nil {
+		return nil, errors.New("email is already in use")
+	}
+
+	hash, err := HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	user.PasswordHash = hash
+
+	if err := u.userRepo.Create(ctx, user); err != nil {
+		return nil, err
+	}
+
+	u.audit(ctx, adminID, user.ID, domain.ActionUserCreate, map[string]interface{}{
+		"email":   user.Email,
+		"role":    user.Role,
+		"team_id": user.TeamID,
+	})
+
+	logger.InfoLogger.Printf("User %d (%s) created by admin %d", user.ID, user.Role, adminID)
+	return user, nil
+}
+
+func (u *userAdminUsecase) List(ctx context.Context, role string, includeInactive bool, page, limit int) ([]*domain.User, int, error) {
+	if role != "" {
+		if err := u.checkRole(ctx, role); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	offset := (page - 1) * limit
+	return u.userRepo.List(ctx, role, includeInactive, limit, offset)
+}
+
+func (u *userAdminUsecase) GetByID(ctx context.Context, id int) (*domain.User, error) {
+	return u.userRepo.GetByID(ctx, id)
+}
+
+func (u *userAdminUsecase) Update(ctx context.Context, adminID, id int, update *domain.UserUpdate) (*domain.User, error) {
+	user, err := u.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]interface{}{}
+
+	if update.Email != nil {
+		email := strings.TrimSpace(strings.ToLower(*update.Email))
+		if err := validateEmail(email); err != nil {
+			return nil, err
+		}
+		if email != user.Email {
+			if _, err := u.userRepo.GetByEmail(ctx, email); err == nil {
+				return nil, errors.New("email is already in use")
+			}
+			changes["email"] = map[string]interface{}{"old": user.Email, "new": email}
+			user.Email = email
+		}
+	}
+
+	if update.Name != nil {
+		name := strings.TrimSpace(*update.Name)
+		if name == "" {
+			return nil, errors.New("name is required")
+		}
+		if name != user.Name {
+			changes["name"] = map[string]interface{}{"old": user.Name, "new": name}
+			user.Name = name
+		}
+	}
+
+	if update.TeamID != nil && (user.TeamID == nil || *user.TeamID != *update.TeamID) {
+		changes["team_id"] = map[string]interface{}{"old": user.TeamID, "new": *update.TeamID}
+		user.TeamID = update.TeamID
+	}
+
+	if len(changes) == 0 {
+		return user, nil
+	}
+
+	if err := u.userRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	u.audit(ctx, adminID, user.ID, domain.ActionUserUpdate, changes)
+	return user, nil
+}
+
+func (u *userAdminUsecase) ChangeRole(ctx context.Context, adminID int, granted []string, id int, role string) (*domain.User, error) {
+	if err := u.checkGrant(ctx, granted, role); err != nil {
+		return nil, err
+	}
+	if adminID == id {
+		return nil, errors.New("admins cannot change their own role")
+	}
+
+	user, err := u.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if user.ServiceAccount || role == domain.RoleService {
+		return nil, errServiceRoleReserved
+	}
+	if user.Role == role {
+		return user, nil
+	}
+
+	if err := u.userRepo.UpdateRole(ctx, id, role); err != nil {
+		return nil, err
+	}
+
+	oldRole := user.Role
+	user.Role = role
+
+	u.audit(ctx, adminID, id, domain.ActionRoleChange, map[string]interface{}{
+		"old_role": oldRole,
+		"new_role": role,
+	})
+
+	logger.InfoLogger.Printf("User %d role changed from %s to %s by admin %d", id, oldRole, role, adminID)
+	return user, nil
+}
+
+func (u *userAdminUsecase) Deactivate(ctx context.Context, adminID, id int) (*domain.User, error) {
+	if adminID == id {
+		return nil, errors.New("admins cannot deactivate themselves")
+	}
+
+	user, err := u.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !user.Active {
+		return nil, errors.New("user is already deactivated")
+	}
+
+	if err := u.userRepo.SetActive(ctx, id, false); err != nil {
+		return nil, err
+	}
+	user.Active = false
+
+	u.revokeSessions(ctx, id)
+
+	u.audit(ctx, adminID, id, domain.ActionUserDeactivate, nil)
+
+	logger.InfoLogger.Printf("User %d deactivated by admin %d", id, adminID)
+	return user, nil
+}
+
+func (u *userAdminUsecase) Reactivate(ctx context.Context, adminID, id int) (*domain.User, error) {
+	user, err := u.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if user.Active {
+		return nil, errors.New("user is already active")
+	}
+
+	if err := u.userRepo.SetActive(ctx, id, true); err != nil {
+		return nil, err
+	}
+	user.Active = true
+
+	u.audit(ctx, adminID, id, domain.ActionUserReactivate, nil)
+
+	logger.InfoLogger.Printf("User %d reactivated by admin %d", id, adminID)
+	return user, nil
+}
+
+func (u *userAdminUsecase) GetAuditLogs(ctx context.Context, id int, page domain.PageRequest) (*domain.AuditLogPage, error) {
+	if _, err := u.userRepo.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	if page.Limit > maxAuditLogPage {
+		page.Limit = maxAuditLogPage
+	}
+	return u.auditRepo.GetBySubjectUserID(ctx, id, page)
+}
+
+func (u *userAdminUsecase) GetLockoutStatus(ctx context.Context, id int) (*domain.LockoutStatus, error) {
+	user, err := u.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	attempts, err := u.attemptRepo.ListByUser(ctx, id, recentLoginAttempts)
+	if err != nil {
+		return nil, err
+	}
+
+	return newLockoutStatus(user, attempts), nil
+}
+
+// Unlock clears the failed attempt count and any lock so the user can log in
+// again straight away.
+func (u *userAdminUsecase) Unlock(ctx context.Context, adminID, id int) (*domain.LockoutStatus, error) {
+	user, err := u.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.userRepo.ResetLoginFailures(ctx, id); err != nil {
+		return nil, err
+	}
+
+	u.audit(ctx, adminID, id, domain.ActionUserUnlock, map[string]interface{}{
+		"failed_attempts": user.FailedLoginAttempts,
+		"locked_until":    user.LockedUntil,
+	})
+	logger.InfoLogger.Printf("[SECURITY] User %d unlocked by admin %d", id, adminID)
+
+	return u.GetLockoutStatus(ctx, id)
+}
+
+// errServiceRoleReserved keeps the service role and service accounts
+// together; see NewAPIKeyUsecase.
+var errServiceRoleReserved = errors.New("the service role is reserved for service accounts")
+
+// checkRole makes sure a role is defined before users are given it.
+func (u *userAdminUsecase) checkRole(ctx context.Context, role string) error {
+	if _, err := u.roleRepo.GetByName(ctx, role); err != nil {
+		return fmt.Errorf("unknown role %q", role)
+	}
+	return nil
+}
+
+// checkGrant is checkRole for giving a user the role: user:manage alone
+// must not be a way to hand out admin, so the caller needs every
+// permission of the role.
+func (u *userAdminUsecase) checkGrant(ctx context.Context, granted []string, role string) error {
+	r, err := u.roleRepo.GetByName(ctx, role)
+	if err != nil {
+		return fmt.Errorf("unknown role %q", role)
+	}
+
+	held := make(map[string]bool, len(granted))
+	for _, p := range granted {
+		held[p] = true
+	}
+	for _, p := range r.Permissions {
+		if !held[p] {
+			return domain.ErrRoleNotGrantable
+		}
+	}
+	return nil
+}
+
+func newLockoutStatus(user *domain.User, attempts []*domain.LoginAttempt) *domain.LockoutStatus {
+	status := &domain.LockoutStatus{
+		UserID:            user.ID,
+		FailedAttempts:    user.FailedLoginAttempts,
+		LastFailedLoginAt: user.LastFailedLoginAt,
+		RecentAttempts:    attempts,
+	}
+	if user.LockedUntil != nil && time.Now().Before(*user.LockedUntil) {
+		status.Locked = true
+		status.LockedUntil = user.LockedUntil
+	}
+	return status
+}
+
+// revokeSessions ends every refresh token of the user so reactivating the
+// account does not bring old sessions back, and revokes the access tokens
+// issued with them, as LogoutAll does.
+func (u *userAdminUsecase) revokeSessions(ctx context.Context, userID int) {
+	jtis, err := u.tokenRepo.RevokeUserRefreshTokens(ctx, userID)
+	if err != nil {
+		logger.ErrorLogger.Printf("Failed to revoke sessions of user %d: %v", userID, err)
+		return
+	}
+
+	expiresAt := time.Now().Add(u.accessTokenTTL)
+	for _, jti := range jtis {
+		if err := u.tokenRepo.RevokeAccessToken(ctx, jti, expiresAt); err != nil {
+			logger.ErrorLogger.Printf("Failed to revoke access token of user %d: %v", userID, err)
+		}
+	}
+}
+
+func (u *userAdminUsecase) audit(ctx context.Context, adminID, subjectID int, action string, metadata map[string]interface{}) {
+	auditLog := &domain.AuditLog{
+		SubjectUserID: &subjectID,
+		UserID:        &adminID,
+		Action:        action,
+		Metadata:      metadata,
+	}
+	u.auditRepo.Create(ctx, auditLog)
+}
+
+func validateEmail(email string) error {
+	at := strings.Index(email, "@")
+	if at < 1 || at == len(email)-1 || strings.ContainsAny(email, " \t") {
+		return errors.New("a valid email is required")
+	}
+	return nil
+}
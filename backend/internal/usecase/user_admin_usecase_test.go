@@ -0,0 +1,383 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"expense-management-system/internal/domain"
+	"expense-management-system/pkg/config"
+	"testing"
+	"time"
+)
+
+func TestUserAdminUsecase_Create(t *testing.T) {
+	tests := []struct {
+		name     string
+		user     *domain.User
+		password string
+		existing bool
+		wantErr  bool
+		wantRole string
+	}{
+		{
+			name:     "Valid employee defaults role",
+			user:     &domain.User{Email: " New@Example.com ", Name: "New Hire"},
+			password: "Welcome-2024x",
+			wantRole: domain.RoleEmployee,
+		},
+		{
+			name:     "Valid finance user",
+			user:     &domain.User{Email: "fin2@example.com", Name: "Finance Two", Role: domain.RoleFinance},
+			password: "Welcome-2024x",
+			wantRole: domain.RoleFinance,
+		},
+		{
+			name:     "Invalid email",
+			user:     &domain.User{Email: "not-an-email", Name: "X"},
+			password: "Welcome-2024x",
+			wantErr:  true,
+		},
+		{
+			name:     "Unknown role",
+			user:     &domain.User{Email: "x@example.com", Name: "X", Role: "superuser"},
+			password: "Welcome-2024x",
+			wantErr:  true,
+		},
+		{
+			name:     "Short password",
+			user:     &domain.User{Email: "x@example.com", Name: "X"},
+			password: "short",
+			wantErr:  true,
+		},
+		{
+			name:     "Duplicate email",
+			user:     &domain.User{Email: "employee1@example.com", Name: "X"},
+			password: "Welcome-2024x",
+			existing: true,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var audits []*domain.AuditLog
+			userRepo := &mockUserRepo{
+				getByEmailFunc: func(ctx context.Context, email string) (*domain.User, error) {
+					if tt.existing {
+						return &domain.User{ID: 1, Email: email}, nil
+					}
+					return nil, errors.New("user not found")
+				},
+				createFunc: func(ctx context.Context, user *domain.User) error {
+					user.ID = 10
+					user.Active = true
+					return nil
+				},
+			}
+			auditRepo := &mockAuditRepo{
+				createFunc: func(ctx context.Context, log *domain.AuditLog) error {
+					audits = append(audits, log)
+					return nil
+				},
+			}
+			uc := NewUserAdminUsecase(userRepo, auditRepo, newMockRoleRepo(), newMockTokenRepo(), newMockLoginAttemptRepo(), &config.Config{AccessTokenTTLMinutes: 15})
+
+			user, err := uc.Create(context.Background(), 99, adminPermissions(), tt.user, tt.password)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Create() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if len(audits) != 0 {
+					t.Error("Expected no audit entry for a rejected create")
+				}
+				return
+			}
+
+			if user.Role != tt.wantRole {
+				t.Errorf("Create() role = %v, want %v", user.Role, tt.wantRole)
+			}
+			if user.PasswordHash == "" || user.PasswordHash == tt.password {
+				t.Error("Expected password to be hashed")
+			}
+			if len(audits) != 1 || audits[0].Action != domain.ActionUserCreate || *audits[0].SubjectUserID != 10 || *audits[0].UserID != 99 {
+				t.Errorf("Expected one user_create audit entry, got %+v", audits)
+			}
+		})
+	}
+}
+
+func TestUserAdminUsecase_Deactivate(t *testing.T) {
+	ctx := context.Background()
+	active := true
+	var audits []*domain.AuditLog
+
+	userRepo := &mockUserRepo{
+		getByIDFunc: func(ctx context.Context, id int) (*domain.User, error) {
+			return &domain.User{ID: id, Role: domain.RoleEmployee, Active: active}, nil
+		},
+		setActiveFunc: func(ctx context.Context, id int, a bool) error {
+			active = a
+			return nil
+		},
+	}
+	auditRepo := &mockAuditRepo{
+		createFunc: func(ctx context.Context, log *domain.AuditLog) error {
+			audits = append(audits, log)
+			return nil
+		},
+	}
+	tokenRepo := newMockTokenRepo()
+	tokenRepo.CreateRefreshToken(ctx, &domain.RefreshToken{UserID: 5, TokenHash: "h", FamilyID: "f", AccessJTI: "j", ExpiresAt: time.Now().Add(time.Hour)})
+
+	uc := NewUserAdminUsecase(userRepo, auditRepo, newMockRoleRepo(), tokenRepo, newMockLoginAttemptRepo(), &config.Config{AccessTokenTTLMinutes: 15})
+
+	if _, err := uc.Deactivate(ctx, 5, 5); err == nil {
+		t.Error("Expected admins to be unable to deactivate themselves")
+	}
+
+	user, err := uc.Deactivate(ctx, 1, 5)
+	if err != nil {
+		t.Fatalf("Deactivate() unexpected error = %v", err)
+	}
+	if user.Active || active {
+		t.Error("Expected user to be deactivated")
+	}
+	if tokenRepo.refreshTokens["h"].RevokedAt == nil {
+		t.Error("Expected refresh tokens of the deactivated user to be revoked")
+	}
+	if !tokenRepo.revokedJTIs["j"] {
+		t.Error("Expected access tokens of the deactivated user to be revoked")
+	}
+
+	if _, err := uc.Deactivate(ctx, 1, 5); err == nil {
+		t.Error("Expected error deactivating an inactive user")
+	}
+
+	if _, err := uc.Reactivate(ctx, 1, 5); err != nil {
+		t.Fatalf("Reactivate() unexpected error = %v", err)
+	}
+	if !active {
+		t.Error("Expected user to be reactivated")
+	}
+
+	if len(audits) != 2 || audits[0].Action != domain.ActionUserDeactivate || audits[1].Action != domain.ActionUserReactivate {
+		t.Errorf("Expected deactivate and reactivate audit entries, got %d", len(audits))
+	}
+}
+
+func TestUserAdminUsecase_ChangeRole(t *testing.T) {
+	ctx := context.Background()
+	var updatedRole string
+	var audits []*domain.AuditLog
+
+	userRepo := &mockUserRepo{
+		updateRoleFunc: func(ctx context.Context, id int, role string) error {
+			updatedRole = role
+			return nil
+		},
+	}
+	auditRepo := &mockAuditRepo{
+		createFunc: func(ctx context.Context, log *domain.AuditLog) error {
+			audits = append(audits, log)
+			return nil
+		},
+	}
+	uc := NewUserAdminUsecase(userRepo, auditRepo, newMockRoleRepo(), newMockTokenRepo(), newMockLoginAttemptRepo(), &config.Config{AccessTokenTTLMinutes: 15})
+
+	if _, err := uc.ChangeRole(ctx, 1, adminPermissions(), 5, "owner"); err == nil {
+		t.Error("Expected error for unknown role")
+	}
+	if _, err := uc.ChangeRole(ctx, 1, adminPermissions(), 1, domain.RoleEmployee); err == nil {
+		t.Error("Expected admins to be unable to change their own role")
+	}
+
+	user, err := uc.ChangeRole(ctx, 1, adminPermissions(), 5, domain.RoleManager)
+	if err != nil {
+		t.Fatalf("ChangeRole() unexpected error = %v", err)
+	}
+	if user.Role != domain.RoleManager || updatedRole != domain.RoleManager {
+		t.Errorf("ChangeRole() role = %v, stored %v", user.Role, updatedRole)
+	}
+	if len(audits) != 1 || audits[0].Metadata["old_role"] != "" || audits[0].Metadata["new_role"] != domain.RoleManager {
+		t.Errorf("Expected role_change audit entry, got %+v", audits)
+	}
+}
+
+func TestUserAdminUsecase_GrantOnlyHeldPermissions(t *testing.T) {
+	ctx := context.Background()
+	roleRepo := newMockRoleRepo()
+	roleRepo.roles["helpdesk"] = &domain.Role{Name: "helpdesk", Permissions: []string{domain.PermUserManage, domain.PermExpenseSubmit}}
+
+	tests := []struct {
+		name    string
+		granted []string
+		role    string
+		wantErr error
+	}{
+		{
+			name:    "Custom role with user:manage cannot grant admin",
+			granted: roleRepo.roles["helpdesk"].Permissions,
+			role:    domain.RoleAdmin,
+			wantErr: domain.ErrRoleNotGrantable,
+		},
+		{
+			name:    "Custom role can grant a role within its permissions",
+			granted: roleRepo.roles["helpdesk"].Permissions,
+			role:    domain.RoleEmployee,
+		},
+		{
+			name:    "API key scoped to user:manage cannot grant admin",
+			granted: []string{domain.PermUserManage},
+			role:    domain.RoleAdmin,
+			wantErr: domain.ErrRoleNotGrantable,
+		},
+		{
+			name:    "API key scoped to user:manage cannot grant employee",
+			granted: []string{domain.PermUserManage},
+			role:    domain.RoleEmployee,
+			wantErr: domain.ErrRoleNotGrantable,
+		},
+		{
+			name:    "Admin can grant admin",
+			granted: adminPermissions(),
+			role:    domain.RoleAdmin,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var audits []*domain.AuditLog
+			userRepo := &mockUserRepo{
+				getByEmailFunc: func(ctx context.Context, email string) (*domain.User, error) {
+					return nil, errors.New("user not found")
+				},
+				createFunc: func(ctx context.Context, user *domain.User) error {
+					user.ID = 10
+					return nil
+				},
+				updateRoleFunc: func(ctx context.Context, id int, role string) error {
+					return nil
+				},
+			}
+			auditRepo := &mockAuditRepo{
+				createFunc: func(ctx context.Context, log *domain.AuditLog) error {
+					audits = append(audits, log)
+					return nil
+				},
+			}
+			uc := NewUserAdminUsecase(userRepo, auditRepo, roleRepo, newMockTokenRepo(), newMockLoginAttemptRepo(), &config.Config{AccessTokenTTLMinutes: 15})
+
+			_, err := uc.Create(ctx, 99, tt.granted, &domain.User{Email: "new@example.com", Name: "New", Role: tt.role}, "Welcome-2024x")
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Create() error = %v, want %v", err, tt.wantErr)
+			}
+			_, err = uc.ChangeRole(ctx, 99, tt.granted, 5, tt.role)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("ChangeRole() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil && len(audits) != 0 {
+				t.Errorf("Expected no audit entry for a refused grant, got %d", len(audits))
+			}
+		})
+	}
+}
+
+func TestUserAdminUsecase_Update(t *testing.T) {
+	ctx := context.Background()
+	var audits []*domain.AuditLog
+
+	userRepo := &mockUserRepo{
+		getByIDFunc: func(ctx context.Context, id int) (*domain.User, error) {
+			return &domain.User{ID: id, Email: "old@example.com", Name: "Old Name", Active: true}, nil
+		},
+		getByEmailFunc: func(ctx context.Context, email string) (*domain.User, error) {
+			if email == "taken@example.com" {
+				return &domain.User{ID: 2, Email: email}, nil
+			}
+			return nil, errors.New("user not found")
+		},
+	}
+	auditRepo := &mockAuditRepo{
+		createFunc: func(ctx context.Context, log *domain.AuditLog) error {
+			audits = append(audits, log)
+			return nil
+		},
+	}
+	uc := NewUserAdminUsecase(userRepo, auditRepo, newMockRoleRepo(), newMockTokenRepo(), newMockLoginAttemptRepo(), &config.Config{AccessTokenTTLMinutes: 15})
+
+	if _, err := uc.Update(ctx, 1, 5, &domain.UserUpdate{Email: strPtr("taken@example.com")}); err == nil {
+		t.Error("Expected error for an email already in use")
+	}
+
+	if _, err := uc.Update(ctx, 1, 5, &domain.UserUpdate{Name: strPtr("Old Name")}); err != nil {
+		t.Fatalf("Update() unexpected error = %v", err)
+	}
+	if len(audits) != 0 {
+		t.Error("Expected no audit entry when nothing changed")
+	}
+
+	user, err := uc.Update(ctx, 1, 5, &domain.UserUpdate{Name: strPtr("New Name")})
+	if err != nil {
+		t.Fatalf("Update() unexpected error = %v", err)
+	}
+	if user.Name != "New Name" {
+		t.Errorf("Update() name = %v, want New Name", user.Name)
+	}
+	if len(audits) != 1 || audits[0].Action != domain.ActionUserUpdate || audits[0].Metadata["name"] == nil {
+		t.Errorf("Expected user_update audit entry with name change, got %+v", audits)
+	}
+}
+
+func TestUserAdminUsecase_Unlock(t *testing.T) {
+	ctx := context.Background()
+	lockedUntil := time.Now().Add(10 * time.Minute)
+	user := &domain.User{ID: 5, Active: true, FailedLoginAttempts: 5, LockedUntil: &lockedUntil}
+	var audits []*domain.AuditLog
+
+	userRepo := &mockUserRepo{
+		getByIDFunc: func(ctx context.Context, id int) (*domain.User, error) {
+			copy := *user
+			return &copy, nil
+		},
+		resetLoginFailuresFunc: func(ctx context.Context, id int) error {
+			user.FailedLoginAttempts = 0
+			user.LockedUntil = nil
+			return nil
+		},
+	}
+	auditRepo := &mockAuditRepo{
+		createFunc: func(ctx context.Context, log *domain.AuditLog) error {
+			audits = append(audits, log)
+			return nil
+		},
+	}
+	uc := NewUserAdminUsecase(userRepo, auditRepo, newMockRoleRepo(), newMockTokenRepo(), newMockLoginAttemptRepo(), &config.Config{AccessTokenTTLMinutes: 15})
+
+	status, err := uc.GetLockoutStatus(ctx, 5)
+	if err != nil {
+		t.Fatalf("GetLockoutStatus() unexpected error = %v", err)
+	}
+	if !status.Locked || status.FailedAttempts != 5 {
+		t.Errorf("Expected a locked status with 5 failures, got %+v", status)
+	}
+
+	status, err = uc.Unlock(ctx, 1, 5)
+	if err != nil {
+		t.Fatalf("Unlock() unexpected error = %v", err)
+	}
+	if status.Locked || status.FailedAttempts != 0 {
+		t.Errorf("Expected the user to be unlocked, got %+v", status)
+	}
+	if len(audits) != 1 || audits[0].Action != domain.ActionUserUnlock {
+		t.Errorf("Expected a user_unlock audit entry, got %+v", audits)
+	}
+}
+
+// adminPermissions is what the admin role holds in a real deployment:
+// every permission there is.
+func adminPermissions() []string {
+	permissions := []string{}
+	for _, p := range domain.Permissions {
+		permissions = append(permissions, p.Name)
+	}
+	return permissions
+}
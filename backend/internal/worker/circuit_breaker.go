@@ -0,0 +1,132 @@
+package worker
+
+import (
+	"errors"
+	"expense-management-system/pkg/logger"
+	"expvar"
+	"sync"
+	"time"
+)
+
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half_open"
+)
+
+var ErrCircuitOpen = errors.New("payment gateway circuit breaker is open")
+
+// breakerMetrics is published under /debug/vars as "payment_gateway_breaker".
+var breakerMetrics = expvar.NewMap("payment_gateway_breaker")
+
+// CircuitBreaker stops every worker from hammering the payment gateway once it
+// is failing. After failureThreshold consecutive failures it opens and rejects
+// calls for openTimeout, then lets a single probe through (half-open); the
+// probe's outcome closes or re-opens it.
+type CircuitBreaker struct {
+	mu               sync.Mutex
+	state            string
+	failures         int
+	openedAt         time.Time
+	probeInFlight    bool
+	failureThreshold int
+	openTimeout      time.Duration
+	now              func() time.Time
+}
+
+func NewCircuitBreaker(failureThreshold int, openTimeout time.Duration) *CircuitBreaker {
+	if failureThreshold < 1 {
+		failureThreshold = 1
+	}
+
+	cb := &CircuitBreaker{
+		state:            BreakerClosed,
+		failureThreshold: failureThreshold,
+		openTimeout:      openTimeout,
+		now:              time.Now,
+	}
+	cb.publish()
+	return cb
+}
+
+// Allow reports whether a call may proceed. When it returns false, retryAfter
+// is how long until the breaker will admit a probe.
+func (cb *CircuitBreaker) Allow() (ok bool, retryAfter time.Duration) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch cb.state {
+	case BreakerOpen:
+		elapsed := cb.now().Sub(cb.openedAt)
+		if elapsed < cb.openTimeout {
+			breakerMetrics.Add("rejected", 1)
+			return false, cb.openTimeout - elapsed
+		}
+		cb.setState(BreakerHalfOpen)
+		cb.probeInFlight = true
+		return true, 0
+	case BreakerHalfOpen:
+		if cb.probeInFlight {
+			breakerMetrics.Add("rejected", 1)
+			return false, cb.openTimeout
+		}
+		cb.probeInFlight = true
+		return true, 0
+	default:
+		return true, 0
+	}
+}
+
+func (cb *CircuitBreaker) RecordSuccess() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	breakerMetrics.Add("successes", 1)
+	cb.failures = 0
+	cb.probeInFlight = false
+	if cb.state != BreakerClosed {
+		cb.setState(BreakerClosed)
+	}
+}
+
+func (cb *CircuitBreaker) RecordFailure() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	breakerMetrics.Add("failures", 1)
+	cb.failures++
+	cb.probeInFlight = false
+
+	if cb.state == BreakerHalfOpen || (cb.state == BreakerClosed && cb.failures >= cb.failureThreshold) {
+		cb.openedAt = cb.now()
+		breakerMetrics.Add("trips", 1)
+		cb.setState(BreakerOpen)
+	}
+}
+
+// Abandon releases a half-open probe whose call was cancelled before the
+// gateway answered, without counting it either way.
+func (cb *CircuitBreaker) Abandon() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.probeInFlight = false
+}
+
+func (cb *CircuitBreaker) State() string {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return cb.state
+}
+
+// setState must be called with mu held.
+func (cb *CircuitBreaker) setState(state string) {
+	logger.InfoLogger.Printf("Payment gateway circuit breaker %s -> %s (consecutive failures: %d)", cb.state, state, cb.failures)
+	cb.state = state
+	cb.publish()
+}
+
+func (cb *CircuitBreaker) publish() {
+	state := new(expvar.String)
+	state.Set(cb.state)
+	breakerMetrics.Set("state", state)
+}
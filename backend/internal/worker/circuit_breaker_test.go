@@ -0,0 +1,83 @@
+package worker
+
+import (
+	"expense-management-system/pkg/logger"
+	"testing"
+	"time"
+)
+
+func init() {
+	logger.Init()
+}
+
+func TestCircuitBreaker_OpensAfterThreshold(t *testing.T) {
+	cb := NewCircuitBreaker(3, 30*time.Second)
+
+	for i := 0; i < 2; i++ {
+		cb.RecordFailure()
+	}
+	if cb.State() != BreakerClosed {
+		t.Fatalf("State after 2 failures = %v, want %v", cb.State(), BreakerClosed)
+	}
+
+	cb.RecordFailure()
+	if cb.State() != BreakerOpen {
+		t.Fatalf("State after 3 failures = %v, want %v", cb.State(), BreakerOpen)
+	}
+
+	ok, retryAfter := cb.Allow()
+	if ok {
+		t.Error("Open breaker should reject calls")
+	}
+	if retryAfter <= 0 || retryAfter > 30*time.Second {
+		t.Errorf("retryAfter = %v, want within open timeout", retryAfter)
+	}
+}
+
+func TestCircuitBreaker_SuccessResetsFailures(t *testing.T) {
+	cb := NewCircuitBreaker(2, 30*time.Second)
+
+	cb.RecordFailure()
+	cb.RecordSuccess()
+	cb.RecordFailure()
+
+	if cb.State() != BreakerClosed {
+		t.Errorf("State = %v, want %v (failures are consecutive only)", cb.State(), BreakerClosed)
+	}
+}
+
+func TestCircuitBreaker_HalfOpenProbe(t *testing.T) {
+	now := time.Date(2026, 1, 8, 10, 0, 0, 0, time.UTC)
+	cb := NewCircuitBreaker(1, 30*time.Second)
+	cb.now = func() time.Time { return now }
+
+	cb.RecordFailure()
+	if ok, _ := cb.Allow(); ok {
+		t.Fatal("Breaker should be open immediately after tripping")
+	}
+
+	now = now.Add(31 * time.Second)
+
+	if ok, _ := cb.Allow(); !ok {
+		t.Fatal("Breaker should admit a probe after the open timeout")
+	}
+	if cb.State() != BreakerHalfOpen {
+		t.Fatalf("State = %v, want %v", cb.State(), BreakerHalfOpen)
+	}
+	if ok, _ := cb.Allow(); ok {
+		t.Error("Only one probe should be admitted while half-open")
+	}
+
+	// Failed probe re-opens the breaker
+	cb.RecordFailure()
+	if cb.State() != BreakerOpen {
+		t.Fatalf("State after failed probe = %v, want %v", cb.State(), BreakerOpen)
+	}
+
+	now = now.Add(31 * time.Second)
+	cb.Allow()
+	cb.RecordSuccess()
+	if cb.State() != BreakerClosed {
+		t.Errorf("State after successful probe = %v, want %v", cb.State(), BreakerClosed)
+	}
+}
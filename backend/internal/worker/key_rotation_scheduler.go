@@ -0,0 +1,64 @@
+package worker
+
+import (
+	"context"
+	"expense-management-system/pkg/logger"
+	"sync"
+	"time"
+)
+
+// KeyRotator adds and retires token signing keys; see jwtkeys.KeyRing.
+type KeyRotator interface {
+	Rotate(ctx context.Context) error
+}
+
+// KeyRotationScheduler periodically lets the key ring publish the next
+// signing key ahead of time and drop expired ones.
+type KeyRotationScheduler struct {
+	rotator  KeyRotator
+	interval time.Duration
+	wg       sync.WaitGroup
+	ctx      context.Context
+	cancel   context.CancelFunc
+}
+
+func NewKeyRotationScheduler(rotator KeyRotator, interval time.Duration) *KeyRotationScheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &KeyRotationScheduler{
+		rotator:  rotator,
+		interval: interval,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+func (s *KeyRotationScheduler) Start() {
+	logger.InfoLogger.Printf("Starting signing key rotation scheduler (interval %v)", s.interval)
+
+	s.wg.Add(1)
+	go s.run()
+}
+
+func (s *KeyRotationScheduler) run() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.rotator.Rotate(s.ctx); err != nil {
+				logger.ErrorLogger.Printf("Failed to rotate signing keys: %v", err)
+			}
+		}
+	}
+}
+
+func (s *KeyRotationScheduler) Stop() {
+	logger.InfoLogger.Println("Stopping signing key rotation scheduler...")
+	s.cancel()
+	s.wg.Wait()
+}
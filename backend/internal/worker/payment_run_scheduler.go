@@ -0,0 +1,64 @@
+package worker
+
+import (
+	"context"
+	"expense-management-system/internal/domain"
+	"expense-management-system/pkg/logger"
+	"sync"
+	"time"
+)
+
+// PaymentRunScheduler periodically closes open payment runs whose cutoff has
+// passed so finance can review and release them, and queues again the
+// payouts of released runs that were never finished.
+type PaymentRunScheduler struct {
+	paymentRunUsecase domain.PaymentRunUsecase
+	interval          time.Duration
+	wg                sync.WaitGroup
+	ctx               context.Context
+	cancel            context.CancelFunc
+}
+
+func NewPaymentRunScheduler(paymentRunUsecase domain.PaymentRunUsecase, interval time.Duration) *PaymentRunScheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &PaymentRunScheduler{
+		paymentRunUsecase: paymentRunUsecase,
+		interval:          interval,
+		ctx:               ctx,
+		cancel:            cancel,
+	}
+}
+
+func (s *PaymentRunScheduler) Start() {
+	logger.InfoLogger.Printf("Starting payment run scheduler (interval %v)", s.interval)
+
+	s.wg.Add(1)
+	go s.run()
+}
+
+func (s *PaymentRunScheduler) run() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := s.paymentRunUsecase.CloseDueRuns(s.ctx, now); err != nil {
+				logger.ErrorLogger.Printf("Failed to close due payment runs: %v", err)
+			}
+			if err := s.paymentRunUsecase.RequeueStalePayouts(s.ctx, now); err != nil {
+				logger.ErrorLogger.Printf("Failed to requeue pending payouts: %v", err)
+			}
+		}
+	}
+}
+
+func (s *PaymentRunScheduler) Stop() {
+	logger.InfoLogger.Println("Stopping payment run scheduler...")
+	s.cancel()
+	s.wg.Wait()
+}
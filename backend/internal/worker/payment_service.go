@@ -11,15 +11,22 @@ import (
 	"expense-management-system/pkg/logger"
 	"fmt"
 	"io"
+	"math/rand"
 	"net/http"
+	"net/url"
 	"time"
 )
 
 type PaymentService struct {
-	client      *http.Client
-	cfg         *config.Config
-	expenseRepo domain.ExpenseRepository
-	auditRepo   domain.AuditLogRepository
+	client         *http.Client
+	cfg            *config.Config
+	expenseRepo    domain.ExpenseRepository
+	auditRepo      domain.AuditLogRepository
+	paymentRunRepo domain.PaymentRunRepository
+	advanceRepo    domain.CashAdvanceRepository
+	breaker        *CircuitBreaker
+	retryBaseDelay time.Duration
+	retryMaxDelay  time.Duration
 }
 
 type PaymentRequest struct {
@@ -27,6 +34,12 @@ type PaymentRequest struct {
 	ExternalID string `json:"external_id"`
 }
 
+type RefundRequest struct {
+	PaymentID  string `json:"payment_id"`
+	Amount     int    `json:"amount"`
+	ExternalID string `json:"external_id"`
+}
+
 type PaymentResponse struct {
 	Data struct {
 		ID         string `json:"id"`
@@ -36,35 +49,137 @@ type PaymentResponse struct {
 	Message string `json:"message,omitempty"`
 }
 
-func NewPaymentService(cfg *config.Config, expenseRepo domain.ExpenseRepository, auditRepo domain.AuditLogRepository) *PaymentService {
+func NewPaymentService(cfg *config.Config, expenseRepo domain.ExpenseRepository, auditRepo domain.AuditLogRepository, paymentRunRepo domain.PaymentRunRepository, advanceRepo domain.CashAdvanceRepository) *PaymentService {
 	return &PaymentService{
 		client: &http.Client{
 			Timeout: 30 * time.Second,
 		},
-		cfg:         cfg,
-		expenseRepo: expenseRepo,
-		auditRepo:   auditRepo,
+		cfg:            cfg,
+		expenseRepo:    expenseRepo,
+		auditRepo:      auditRepo,
+		paymentRunRepo: paymentRunRepo,
+		advanceRepo:    advanceRepo,
+		breaker:        NewCircuitBreaker(cfg.PaymentBreakerThreshold, time.Duration(cfg.PaymentBreakerOpenSeconds)*time.Second),
+		retryBaseDelay: time.Duration(cfg.PaymentRetryBaseMs) * time.Millisecond,
+		retryMaxDelay:  time.Duration(cfg.PaymentRetryMaxMs) * time.Millisecond,
+	}
+}
+
+// PaymentError describes a failed gateway call. Retryable is false for
+// permanent failures (4xx, declined payments) that must not be retried.
+type PaymentError struct {
+	StatusCode int
+	Retryable  bool
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *PaymentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PaymentError) Unwrap() error {
+	return e.Err
+}
+
+var ErrPaymentDuplicate = errors.New("idempotency_error: external_id already exists")
+
+// isRetryable treats network errors and timeouts as transient unless the
+// caller's own context was cancelled.
+func isRetryable(ctx context.Context, err error) bool {
+	if errors.Is(err, ErrPaymentDuplicate) || ctx.Err() != nil {
+		return false
+	}
+
+	var paymentErr *PaymentError
+	if errors.As(err, &paymentErr) {
+		return paymentErr.Retryable
 	}
+
+	return true
 }
 
+// ProcessPayment sends a single payment through the shared circuit breaker.
 func (s *PaymentService) ProcessPayment(ctx context.Context, expenseID int, amount int, externalID string) (string, error) {
-	reqBody := PaymentRequest{
-		Amount:     amount,
-		ExternalID: externalID,
+	return s.withBreaker(ctx, func() (string, error) {
+		return s.callGateway(ctx, http.MethodPost, "/v1/payments", PaymentRequest{
+			Amount:     amount,
+			ExternalID: externalID,
+		})
+	})
+}
+
+// FindPayment returns the ID of the successful payment made with
+// externalID, for when the gateway reports a payment as a duplicate.
+func (s *PaymentService) FindPayment(ctx context.Context, externalID string) (string, error) {
+	return s.withBreaker(ctx, func() (string, error) {
+		return s.callGateway(ctx, http.MethodGet, "/v1/payments?external_id="+url.QueryEscape(externalID), nil)
+	})
+}
+
+// ProcessRefund reverses (part of) a completed payment at the provider and
+// returns the provider's refund ID.
+func (s *PaymentService) ProcessRefund(ctx context.Context, paymentID string, amount int, externalID string) (string, error) {
+	refundID, err := s.withBreaker(ctx, func() (string, error) {
+		return s.callGateway(ctx, http.MethodPost, "/v1/refunds", RefundRequest{
+			PaymentID:  paymentID,
+			Amount:     amount,
+			ExternalID: externalID,
+		})
+	})
+
+	// A duplicate external_id means this reversal already went through.
+	if errors.Is(err, ErrPaymentDuplicate) {
+		return "", nil
 	}
+	return refundID, err
+}
 
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return "", err
+// withBreaker runs a gateway call through the circuit breaker. Only transient
+// gateway failures count against it; a 4xx means the gateway is up and
+// answering.
+func (s *PaymentService) withBreaker(ctx context.Context, call func() (string, error)) (string, error) {
+	if ok, retryAfter := s.breaker.Allow(); !ok {
+		return "", &PaymentError{Retryable: true, RetryAfter: retryAfter, Err: ErrCircuitOpen}
 	}
 
-	url := fmt.Sprintf("%s/v1/payments", s.cfg.PaymentAPIURL)
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	id, err := call()
+
+	switch {
+	case err == nil:
+		s.breaker.RecordSuccess()
+	case ctx.Err() != nil:
+		s.breaker.Abandon()
+	case isRetryable(ctx, err):
+		s.breaker.RecordFailure()
+	default:
+		s.breaker.RecordSuccess()
+	}
+
+	return id, err
+}
+
+// callGateway sends reqBody (none if nil) to the provider and returns the ID
+// of the payment or refund in the response, classifying failures into
+// retryable and permanent.
+func (s *PaymentService) callGateway(ctx context.Context, method, path string, reqBody interface{}) (string, error) {
+	var body io.Reader
+	if reqBody != nil {
+		jsonData, err := json.Marshal(reqBody)
+		if err != nil {
+			return "", err
+		}
+		body = bytes.NewBuffer(jsonData)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("%s%s", s.cfg.PaymentAPIURL, path), body)
 	if err != nil {
 		return "", err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
+	if reqBody != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
 
 	resp, err := s.client.Do(req)
 	if err != nil {
@@ -72,37 +187,101 @@ func (s *PaymentService) ProcessPayment(ctx context.Context, expenseID int, amou
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return "", err
 	}
 
+	// Error responses from proxies may not be JSON, so the status code decides
+	// the outcome and the body is only consulted for the gateway's message.
 	var paymentResp PaymentResponse
-	if err := json.Unmarshal(body, &paymentResp); err != nil {
-		return "", err
+	jsonErr := json.Unmarshal(respBody, &paymentResp)
+
+	if resp.StatusCode == http.StatusBadRequest && paymentResp.Message == "external id already exists" {
+		return "", ErrPaymentDuplicate
 	}
 
-	if resp.StatusCode == 400 && paymentResp.Message == "external id already exists" {
-		return "", errors.New("idempotency_error: external_id already exists")
+	if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusRequestTimeout {
+		return "", &PaymentError{
+			StatusCode: resp.StatusCode,
+			Retryable:  true,
+			Err:        fmt.Errorf("payment failed with status %d", resp.StatusCode),
+		}
 	}
 
-	if resp.StatusCode != 200 {
-		return "", fmt.Errorf("payment failed with status %d", resp.StatusCode)
+	if resp.StatusCode != http.StatusOK {
+		return "", &PaymentError{
+			StatusCode: resp.StatusCode,
+			Retryable:  false,
+			Err:        fmt.Errorf("payment failed with status %d", resp.StatusCode),
+		}
+	}
+
+	if jsonErr != nil {
+		return "", jsonErr
 	}
 
 	if paymentResp.Data.Status != "success" {
-		return "", fmt.Errorf("payment status: %s", paymentResp.Data.Status)
+		return "", &PaymentError{
+			StatusCode: resp.StatusCode,
+			Retryable:  false,
+			Err:        fmt.Errorf("payment status: %s", paymentResp.Data.Status),
+		}
 	}
 
 	return paymentResp.Data.ID, nil
 }
 
+// backoff returns an exponentially growing delay for the given attempt with
+// "equal jitter": half fixed, half random, so workers retrying the same outage
+// spread out instead of hitting the gateway in lockstep.
+func (s *PaymentService) backoff(attempt int) time.Duration {
+	delay := s.retryMaxDelay
+	if shift := attempt - 1; shift < 30 && s.retryBaseDelay<<shift < s.retryMaxDelay {
+		delay = s.retryBaseDelay << shift
+	}
+
+	half := delay / 2
+	if half <= 0 {
+		return delay
+	}
+	return half + time.Duration(rand.Int63n(int64(half)))
+}
+
+// sleepContext waits for d or until ctx is done, whichever comes first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
 func (s *PaymentService) ProcessPaymentWithRetry(ctx context.Context, job usecase.PaymentJob, maxRetries int) error {
 	var lastErr error
 
 	for attempt := 1; attempt <= maxRetries; attempt++ {
 		paymentID, err := s.ProcessPayment(ctx, job.ExpenseID, job.Amount, job.ExternalID)
 
+		// An earlier attempt went through. Its payment ID is looked up so
+		// that what it paid for can still be refunded.
+		if errors.Is(err, ErrPaymentDuplicate) {
+			logger.InfoLogger.Printf("Payment %s already processed (idempotency check), looking it up", job.ExternalID)
+			paymentID, err = s.FindPayment(ctx, job.ExternalID)
+		}
+
+		if err == nil && job.PayoutID != 0 {
+			return s.completePayout(ctx, job, paymentID)
+		}
+
+		if err == nil && job.AdvanceID != 0 {
+			return s.completeAdvance(ctx, job, paymentID)
+		}
+
 		if err == nil {
 			if err := s.expenseRepo.UpdatePaymentInfo(ctx, job.ExpenseID, paymentID, job.ExternalID); err != nil {
 				logger.ErrorLogger.Printf("Failed to update payment info for expense %d: %v", job.ExpenseID, err)
@@ -134,22 +313,156 @@ func (s *PaymentService) ProcessPaymentWithRetry(ctx context.Context, job usecas
 
 		lastErr = err
 
-		if err.Error() == "idempotency_error: external_id already exists" {
-			logger.InfoLogger.Printf("Expense %d already processed (idempotency check), marking as completed", job.ExpenseID)
+		if ctx.Err() != nil {
+			return s.interrupted(job, ctx.Err())
+		}
 
-			now := time.Now().Format(time.RFC3339)
-			s.expenseRepo.UpdateStatus(ctx, job.ExpenseID, domain.StatusCompleted, &now)
-			return nil
+		if !isRetryable(ctx, err) {
+			logger.ErrorLogger.Printf("Payment attempt %d/%d failed permanently for expense %d (payout %d): %v",
+				attempt, maxRetries, job.ExpenseID, job.PayoutID, err)
+			break
 		}
 
 		if attempt < maxRetries {
-			backoff := time.Duration(attempt*2) * time.Second
-			logger.InfoLogger.Printf("Payment attempt %d/%d failed for expense %d, retrying in %v: %v",
-				attempt, maxRetries, job.ExpenseID, backoff, err)
-			time.Sleep(backoff)
+			wait := s.backoff(attempt)
+
+			// No point probing before the breaker is willing to let us through.
+			var paymentErr *PaymentError
+			if errors.As(err, &paymentErr) && paymentErr.RetryAfter > wait {
+				wait = paymentErr.RetryAfter
+			}
+
+			logger.InfoLogger.Printf("Payment attempt %d/%d failed for expense %d (payout %d), retrying in %v: %v",
+				attempt, maxRetries, job.ExpenseID, job.PayoutID, wait, err)
+
+			if err := sleepContext(ctx, wait); err != nil {
+				return s.interrupted(job, err)
+			}
 		}
 	}
 
+	if job.PayoutID != 0 {
+		logger.ErrorLogger.Printf("Payout %d failed after %d attempts: %v", job.PayoutID, maxRetries, lastErr)
+		s.failPayout(ctx, job)
+		return lastErr
+	}
+
 	logger.ErrorLogger.Printf("Payment failed for expense %d after %d attempts: %v", job.ExpenseID, maxRetries, lastErr)
 	return lastErr
 }
+
+// interrupted gives up on a job because the worker is stopping. A payout is
+// not failed, as the gateway may have paid it after all: it stays pending,
+// and PaymentRunUsecase.RequeueStalePayouts sends it again.
+func (s *PaymentService) interrupted(job usecase.PaymentJob, err error) error {
+	if job.PayoutID != 0 {
+		logger.ErrorLogger.Printf("Payout %d interrupted, left pending for the next sweep: %v", job.PayoutID, err)
+		return err
+	}
+	logger.ErrorLogger.Printf("Payment retry for expense %d cancelled: %v", job.ExpenseID, err)
+	return err
+}
+
+// completePayout marks a batched payout as paid and completes every approved
+// expense it covered.
+func (s *PaymentService) completePayout(ctx context.Context, job usecase.PaymentJob, paymentID string) error {
+	payout, err := s.paymentRunRepo.GetPayoutByID(ctx, job.PayoutID)
+	if err != nil {
+		logger.ErrorLogger.Printf("Failed to load payout %d: %v", job.PayoutID, err)
+		return err
+	}
+
+	if err := s.paymentRunRepo.UpdatePayoutStatus(ctx, payout.ID, domain.PayoutStatusCompleted, &paymentID); err != nil {
+		logger.ErrorLogger.Printf("Failed to update status for payout %d: %v", payout.ID, err)
+		return err
+	}
+
+	expenses, err := s.expenseRepo.GetByPaymentRunID(ctx, payout.PaymentRunID)
+	if err != nil {
+		logger.ErrorLogger.Printf("Failed to load expenses for payment run %d: %v", payout.PaymentRunID, err)
+		return err
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	for _, expense := range expenses {
+		if expense.UserID != payout.UserID || expense.Status != domain.StatusApproved {
+			continue
+		}
+
+		// The payout's external_id is shared by all of its expenses, so only
+		// the payment_id is copied; each expense keeps its own unique key.
+		if err := s.expenseRepo.UpdatePaymentInfo(ctx, expense.ID, paymentID, ""); err != nil {
+			logger.ErrorLogger.Printf("Failed to update payment info for expense %d: %v", expense.ID, err)
+			return err
+		}
+
+		if err := s.expenseRepo.UpdateStatus(ctx, expense.ID, domain.StatusCompleted, &now); err != nil {
+			logger.ErrorLogger.Printf("Failed to update status for expense %d: %v", expense.ID, err)
+			return err
+		}
+
+		oldStatus := expense.Status
+		newStatus := domain.StatusCompleted
+		auditLog := &domain.AuditLog{
+			ExpenseID: expense.ID,
+			Action:    domain.ActionComplete,
+			OldStatus: &oldStatus,
+			NewStatus: &newStatus,
+			Metadata: map[string]interface{}{
+				"payment_id":     paymentID,
+				"external_id":    payout.PaymentExternalID,
+				"amount":         expense.AmountIDR,
+				"payment_run_id": payout.PaymentRunID,
+				"payout_id":      payout.ID,
+			},
+		}
+		s.auditRepo.Create(ctx, auditLog)
+	}
+
+	if err := s.paymentRunRepo.RefreshStatus(ctx, payout.PaymentRunID); err != nil {
+		logger.ErrorLogger.Printf("Failed to refresh status for payment run %d: %v", payout.PaymentRunID, err)
+		return err
+	}
+
+	logger.InfoLogger.Printf("Payout %d successful for user %d (IDR %d, %d expenses), payment_id: %s",
+		payout.ID, payout.UserID, payout.AmountIDR, payout.ExpenseCount, paymentID)
+	return nil
+}
+
+// completeAdvance marks a cash advance as paid, which opens its full amount
+// for settlement against later expenses.
+func (s *PaymentService) completeAdvance(ctx context.Context, job usecase.PaymentJob, paymentID string) error {
+	if err := s.advanceRepo.MarkPaid(ctx, job.AdvanceID, paymentID); err != nil {
+		logger.ErrorLogger.Printf("Failed to mark cash advance %d as paid: %v", job.AdvanceID, err)
+		return err
+	}
+
+	oldStatus := domain.AdvanceStatusApproved
+	newStatus := domain.AdvanceStatusPaid
+	auditLog := &domain.AuditLog{
+		CashAdvanceID: &job.AdvanceID,
+		Action:        domain.ActionComplete,
+		OldStatus:     &oldStatus,
+		NewStatus:     &newStatus,
+		Metadata: map[string]interface{}{
+			"payment_id":  paymentID,
+			"external_id": job.ExternalID,
+			"amount":      job.Amount,
+		},
+	}
+	s.auditRepo.Create(ctx, auditLog)
+
+	logger.InfoLogger.Printf("Payment successful for cash advance %d, payment_id: %s", job.AdvanceID, paymentID)
+	return nil
+}
+
+func (s *PaymentService) failPayout(ctx context.Context, job usecase.PaymentJob) {
+	if err := s.paymentRunRepo.UpdatePayoutStatus(ctx, job.PayoutID, domain.PayoutStatusFailed, nil); err != nil {
+		logger.ErrorLogger.Printf("Failed to update status for payout %d: %v", job.PayoutID, err)
+		return
+	}
+
+	if err := s.paymentRunRepo.RefreshStatus(ctx, job.PaymentRunID); err != nil {
+		logger.ErrorLogger.Printf("Failed to refresh status for payment run %d: %v", job.PaymentRunID, err)
+	}
+}
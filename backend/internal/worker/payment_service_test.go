@@ -0,0 +1,247 @@
+package worker
+
+import (
+	"context"
+	"expense-management-system/internal/domain"
+	"expense-management-system/internal/usecase"
+	"expense-management-system/pkg/config"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// stubExpenseRepo records status updates; the remaining methods are unused.
+type stubExpenseRepo struct {
+	domain.ExpenseRepository
+	completed  int32
+	paymentIDs map[int]string
+	runExpense []*domain.Expense
+}
+
+func (r *stubExpenseRepo) UpdatePaymentInfo(ctx context.Context, id int, paymentID, externalID string) error {
+	if r.paymentIDs == nil {
+		r.paymentIDs = map[int]string{}
+	}
+	r.paymentIDs[id] = paymentID
+	return nil
+}
+
+func (r *stubExpenseRepo) GetByPaymentRunID(ctx context.Context, paymentRunID int) ([]*domain.Expense, error) {
+	return r.runExpense, nil
+}
+
+func (r *stubExpenseRepo) UpdateStatus(ctx context.Context, id int, status string, processedAt *string) error {
+	if status == domain.StatusCompleted {
+		atomic.AddInt32(&r.completed, 1)
+	}
+	return nil
+}
+
+// stubPaymentRunRepo holds a single payout and records what happens to it.
+type stubPaymentRunRepo struct {
+	domain.PaymentRunRepository
+	payout *domain.Payout
+}
+
+func (r *stubPaymentRunRepo) GetPayoutByID(ctx context.Context, id int) (*domain.Payout, error) {
+	copy := *r.payout
+	return &copy, nil
+}
+
+func (r *stubPaymentRunRepo) UpdatePayoutStatus(ctx context.Context, id int, status string, paymentID *string) error {
+	r.payout.Status = status
+	if paymentID != nil {
+		r.payout.PaymentID = paymentID
+	}
+	return nil
+}
+
+func (r *stubPaymentRunRepo) RefreshStatus(ctx context.Context, id int) error {
+	return nil
+}
+
+type stubAuditRepo struct {
+	domain.AuditLogRepository
+}
+
+func (r *stubAuditRepo) Create(ctx context.Context, log *domain.AuditLog) error {
+	return nil
+}
+
+func newTestPaymentService(url string, breakerThreshold int) (*PaymentService, *stubExpenseRepo) {
+	expenseRepo := &stubExpenseRepo{}
+	cfg := &config.Config{
+		PaymentAPIURL:             url,
+		PaymentBreakerThreshold:   breakerThreshold,
+		PaymentBreakerOpenSeconds: 60,
+		PaymentRetryBaseMs:        1,
+		PaymentRetryMaxMs:         5,
+	}
+	return NewPaymentService(cfg, expenseRepo, &stubAuditRepo{}, nil, nil), expenseRepo
+}
+
+func TestPaymentService_RetriesServerErrors(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte("<html>bad gateway</html>"))
+			return
+		}
+		w.Write([]byte(`{"data":{"id":"pay-1","external_id":"ext-1","status":"success"}}`))
+	}))
+	defer server.Close()
+
+	svc, expenseRepo := newTestPaymentService(server.URL, 10)
+
+	err := svc.ProcessPaymentWithRetry(context.Background(), usecase.PaymentJob{ExpenseID: 1, Amount: 500000, ExternalID: "ext-1"}, 3)
+	if err != nil {
+		t.Fatalf("ProcessPaymentWithRetry() unexpected error = %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("Gateway called %d times, want 3", calls)
+	}
+	if expenseRepo.completed != 1 {
+		t.Error("Expected expense to be marked completed")
+	}
+}
+
+func TestPaymentService_DoesNotRetryClientErrors(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(`{"message":"invalid amount"}`))
+	}))
+	defer server.Close()
+
+	svc, _ := newTestPaymentService(server.URL, 10)
+
+	err := svc.ProcessPaymentWithRetry(context.Background(), usecase.PaymentJob{ExpenseID: 1, Amount: 500000, ExternalID: "ext-1"}, 3)
+	if err == nil {
+		t.Fatal("Expected permanent error")
+	}
+	if calls != 1 {
+		t.Errorf("Gateway called %d times, want 1 (4xx is not retryable)", calls)
+	}
+	if svc.breaker.State() != BreakerClosed {
+		t.Errorf("4xx responses should not trip the breaker, state = %v", svc.breaker.State())
+	}
+}
+
+// duplicateGateway reports every payment as a duplicate and returns the
+// earlier one when it is looked up by external ID.
+func duplicateGateway(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			if r.URL.Query().Get("external_id") != "ext-1" {
+				t.Errorf("Looked up external_id %q", r.URL.Query().Get("external_id"))
+			}
+			w.Write([]byte(`{"data":{"id":"pay-1","external_id":"ext-1","status":"success"}}`))
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"message":"external id already exists"}`))
+	}))
+}
+
+func TestPaymentService_DuplicateMarksCompleted(t *testing.T) {
+	server := duplicateGateway(t)
+	defer server.Close()
+
+	svc, expenseRepo := newTestPaymentService(server.URL, 10)
+
+	err := svc.ProcessPaymentWithRetry(context.Background(), usecase.PaymentJob{ExpenseID: 1, Amount: 500000, ExternalID: "ext-1"}, 3)
+	if err != nil {
+		t.Fatalf("ProcessPaymentWithRetry() unexpected error = %v", err)
+	}
+	if expenseRepo.completed != 1 {
+		t.Error("Expected duplicate payment to be marked completed")
+	}
+	if expenseRepo.paymentIDs[1] != "pay-1" {
+		t.Errorf("Expected the earlier payment's ID to be stored, got %q", expenseRepo.paymentIDs[1])
+	}
+}
+
+func TestPaymentService_DuplicatePayoutKeepsPaymentID(t *testing.T) {
+	server := duplicateGateway(t)
+	defer server.Close()
+
+	svc, expenseRepo := newTestPaymentService(server.URL, 10)
+	runRepo := &stubPaymentRunRepo{payout: &domain.Payout{ID: 3, PaymentRunID: 7, UserID: 1, Status: domain.PayoutStatusPending, PaymentExternalID: "ext-1"}}
+	svc.paymentRunRepo = runRepo
+	expenseRepo.runExpense = []*domain.Expense{
+		{ID: 1, UserID: 1, Status: domain.StatusApproved},
+		{ID: 2, UserID: 1, Status: domain.StatusApproved},
+	}
+
+	err := svc.ProcessPaymentWithRetry(context.Background(), usecase.PaymentJob{Amount: 500000, ExternalID: "ext-1", PaymentRunID: 7, PayoutID: 3}, 3)
+	if err != nil {
+		t.Fatalf("ProcessPaymentWithRetry() unexpected error = %v", err)
+	}
+	if runRepo.payout.Status != domain.PayoutStatusCompleted || runRepo.payout.PaymentID == nil || *runRepo.payout.PaymentID != "pay-1" {
+		t.Errorf("Unexpected payout %+v", runRepo.payout)
+	}
+	// Refunds need the payment of each expense.
+	if expenseRepo.paymentIDs[1] != "pay-1" || expenseRepo.paymentIDs[2] != "pay-1" {
+		t.Errorf("Expected both expenses to keep the payment ID, got %v", expenseRepo.paymentIDs)
+	}
+}
+
+func TestPaymentService_OpenBreakerSkipsGateway(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	svc, _ := newTestPaymentService(server.URL, 2)
+
+	// The breaker opens after two failures; the remaining wait respects the
+	// context deadline instead of sleeping through the open timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := svc.ProcessPaymentWithRetry(ctx, usecase.PaymentJob{ExpenseID: 1, Amount: 500000, ExternalID: "ext-1"}, 5)
+	if err == nil {
+		t.Fatal("Expected error while gateway is down")
+	}
+	if calls != 2 {
+		t.Errorf("Gateway called %d times, want 2 before the breaker opened", calls)
+	}
+	if svc.breaker.State() != BreakerOpen {
+		t.Errorf("Breaker state = %v, want %v", svc.breaker.State(), BreakerOpen)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Retry loop ignored context cancellation, took %v", elapsed)
+	}
+}
+
+func TestPaymentService_CancelledPayoutStaysPending(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	svc, _ := newTestPaymentService(server.URL, 10)
+	svc.retryBaseDelay = time.Minute
+	svc.retryMaxDelay = time.Minute
+	runRepo := &stubPaymentRunRepo{payout: &domain.Payout{ID: 3, PaymentRunID: 7, Status: domain.PayoutStatusPending}}
+	svc.paymentRunRepo = runRepo
+
+	// The worker stops while waiting to retry.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := svc.ProcessPaymentWithRetry(ctx, usecase.PaymentJob{Amount: 500000, ExternalID: "ext-1", PaymentRunID: 7, PayoutID: 3}, 3)
+	if err == nil {
+		t.Fatal("Expected the cancellation to be returned")
+	}
+	if runRepo.payout.Status != domain.PayoutStatusPending {
+		t.Errorf("Payout status = %v, want it left %v for the recovery sweep", runRepo.payout.Status, domain.PayoutStatusPending)
+	}
+}
@@ -0,0 +1,59 @@
+package worker
+
+import (
+	"context"
+	"expense-management-system/internal/domain"
+	"expense-management-system/pkg/logger"
+	"sync"
+	"time"
+)
+
+// ReportScheduler periodically runs the scheduled reports that are due.
+type ReportScheduler struct {
+	reportUsecase domain.ReportUsecase
+	interval      time.Duration
+	wg            sync.WaitGroup
+	ctx           context.Context
+	cancel        context.CancelFunc
+}
+
+func NewReportScheduler(reportUsecase domain.ReportUsecase, interval time.Duration) *ReportScheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &ReportScheduler{
+		reportUsecase: reportUsecase,
+		interval:      interval,
+		ctx:           ctx,
+		cancel:        cancel,
+	}
+}
+
+func (s *ReportScheduler) Start() {
+	logger.InfoLogger.Printf("Starting report scheduler (interval %v)", s.interval)
+
+	s.wg.Add(1)
+	go s.run()
+}
+
+func (s *ReportScheduler) run() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := s.reportUsecase.RunDue(s.ctx, now); err != nil {
+				logger.ErrorLogger.Printf("Failed to run due reports: %v", err)
+			}
+		}
+	}
+}
+
+func (s *ReportScheduler) Stop() {
+	logger.InfoLogger.Println("Stopping report scheduler...")
+	s.cancel()
+	s.wg.Wait()
+}
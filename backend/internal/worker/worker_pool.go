@@ -54,10 +54,16 @@ func (wp *WorkerPool) worker(id int) {
 				return
 			}
 
-			logger.InfoLogger.Printf("Worker %d processing payment for expense %d", id, job.ExpenseID)
+			if job.PayoutID != 0 {
+				logger.InfoLogger.Printf("Worker %d processing payout %d for payment run %d", id, job.PayoutID, job.PaymentRunID)
+			} else if job.AdvanceID != 0 {
+				logger.InfoLogger.Printf("Worker %d processing payment for cash advance %d", id, job.AdvanceID)
+			} else {
+				logger.InfoLogger.Printf("Worker %d processing payment for expense %d", id, job.ExpenseID)
+			}
 
 			if err := wp.paymentService.ProcessPaymentWithRetry(wp.ctx, job, wp.maxRetries); err != nil {
-				logger.ErrorLogger.Printf("Worker %d failed to process payment for expense %d: %v", id, job.ExpenseID, err)
+				logger.ErrorLogger.Printf("Worker %d failed to process payment job (expense %d, payout %d, advance %d): %v", id, job.ExpenseID, job.PayoutID, job.AdvanceID, err)
 			}
 		}
 	}
@@ -0,0 +1,10 @@
+DELETE FROM users WHERE role = 'finance';
+
+DROP INDEX IF EXISTS idx_expenses_payment_run_id;
+ALTER TABLE expenses DROP COLUMN IF EXISTS payment_run_id;
+
+DROP TABLE IF EXISTS payouts;
+DROP TABLE IF EXISTS payment_runs;
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+ALTER TABLE users ADD CONSTRAINT users_role_check CHECK (role IN ('employee', 'manager'));
@@ -0,0 +1,41 @@
+-- Allow finance staff who review and release payment runs
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+ALTER TABLE users ADD CONSTRAINT users_role_check CHECK (role IN ('employee', 'manager', 'finance'));
+
+-- Create payment_runs table (one batch per cutoff)
+CREATE TABLE IF NOT EXISTS payment_runs (
+    id SERIAL PRIMARY KEY,
+    cutoff_at TIMESTAMP NOT NULL UNIQUE,
+    status VARCHAR(50) NOT NULL CHECK (status IN ('open', 'pending_review', 'released', 'completed', 'failed')),
+    released_by INTEGER REFERENCES users(id),
+    released_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create payouts table (one gateway transfer per employee per run)
+CREATE TABLE IF NOT EXISTS payouts (
+    id SERIAL PRIMARY KEY,
+    payment_run_id INTEGER NOT NULL REFERENCES payment_runs(id),
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    amount_idr BIGINT NOT NULL CHECK (amount_idr > 0),
+    expense_count INTEGER NOT NULL,
+    status VARCHAR(50) NOT NULL CHECK (status IN ('pending', 'completed', 'failed')),
+    payment_id VARCHAR(255),
+    payment_external_id VARCHAR(255) UNIQUE NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (payment_run_id, user_id)
+);
+
+-- Link expenses to the run they are paid in
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS payment_run_id INTEGER REFERENCES payment_runs(id);
+
+CREATE INDEX IF NOT EXISTS idx_expenses_payment_run_id ON expenses(payment_run_id);
+CREATE INDEX IF NOT EXISTS idx_payment_runs_status ON payment_runs(status);
+CREATE INDEX IF NOT EXISTS idx_payouts_payment_run_id ON payouts(payment_run_id);
+
+-- Seed finance user (password: password123)
+INSERT INTO users (email, password_hash, name, role) VALUES
+('finance@example.com', '$2a$10$ArfoA5Y.NYwKkh/e61P5kutQB7u0zC2coCvmTD7qv9kwJ.GhgHZ1y', 'Finance F', 'finance')
+ON CONFLICT (email) DO NOTHING;
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS refunds;
+
+UPDATE expenses SET status = 'completed' WHERE status IN ('partially_refunded', 'refunded');
+ALTER TABLE expenses DROP CONSTRAINT IF EXISTS expenses_status_check;
+ALTER TABLE expenses ADD CONSTRAINT expenses_status_check
+    CHECK (status IN ('awaiting_approval', 'approved', 'rejected', 'completed'));
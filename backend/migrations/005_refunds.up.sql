@@ -0,0 +1,22 @@
+-- Allow refunded statuses on expenses
+ALTER TABLE expenses DROP CONSTRAINT IF EXISTS expenses_status_check;
+ALTER TABLE expenses ADD CONSTRAINT expenses_status_check
+    CHECK (status IN ('awaiting_approval', 'approved', 'rejected', 'completed', 'partially_refunded', 'refunded'));
+
+-- Create refunds table (money coming back for a paid expense)
+CREATE TABLE IF NOT EXISTS refunds (
+    id SERIAL PRIMARY KEY,
+    expense_id INTEGER NOT NULL REFERENCES expenses(id),
+    payment_id VARCHAR(255) NOT NULL,
+    type VARCHAR(50) NOT NULL CHECK (type IN ('refund', 'reversal')),
+    amount_idr INTEGER NOT NULL CHECK (amount_idr > 0),
+    reason TEXT NOT NULL,
+    status VARCHAR(50) NOT NULL CHECK (status IN ('pending', 'completed', 'failed')),
+    external_id VARCHAR(255) UNIQUE NOT NULL,
+    provider_refund_id VARCHAR(255),
+    initiated_by INTEGER NOT NULL REFERENCES users(id),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_refunds_expense_id ON refunds(expense_id);
@@ -0,0 +1,9 @@
+DELETE FROM audit_logs WHERE expense_id IS NULL;
+ALTER TABLE audit_logs DROP CONSTRAINT IF EXISTS audit_logs_subject_check;
+ALTER TABLE audit_logs DROP COLUMN IF EXISTS cash_advance_id;
+ALTER TABLE audit_logs ALTER COLUMN expense_id SET NOT NULL;
+
+ALTER TABLE expenses DROP COLUMN IF EXISTS advance_settled_idr;
+ALTER TABLE expenses DROP COLUMN IF EXISTS cash_advance_id;
+
+DROP TABLE IF EXISTS cash_advances;
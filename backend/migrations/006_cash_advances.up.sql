@@ -0,0 +1,34 @@
+-- Create cash_advances table (money paid to an employee up front)
+CREATE TABLE IF NOT EXISTS cash_advances (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    amount_idr INTEGER NOT NULL CHECK (amount_idr > 0),
+    purpose TEXT NOT NULL,
+    status VARCHAR(50) NOT NULL CHECK (status IN ('awaiting_approval', 'approved', 'rejected', 'paid', 'settled')),
+    outstanding_idr INTEGER NOT NULL DEFAULT 0 CHECK (outstanding_idr >= 0),
+    recovered_idr INTEGER NOT NULL DEFAULT 0 CHECK (recovered_idr >= 0),
+    approver_id INTEGER REFERENCES users(id),
+    approval_notes TEXT,
+    submitted_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    processed_at TIMESTAMP,
+    payment_id VARCHAR(255),
+    payment_external_id VARCHAR(255) UNIQUE NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_cash_advances_user_id ON cash_advances(user_id);
+CREATE INDEX IF NOT EXISTS idx_cash_advances_status ON cash_advances(status);
+
+-- Expenses can be settled against an advance; only the remainder is paid out
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS cash_advance_id INTEGER REFERENCES cash_advances(id);
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS advance_settled_idr INTEGER NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS idx_expenses_cash_advance_id ON expenses(cash_advance_id);
+
+-- Audit entries now belong to either an expense or a cash advance
+ALTER TABLE audit_logs ALTER COLUMN expense_id DROP NOT NULL;
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS cash_advance_id INTEGER REFERENCES cash_advances(id);
+ALTER TABLE audit_logs DROP CONSTRAINT IF EXISTS audit_logs_subject_check;
+ALTER TABLE audit_logs ADD CONSTRAINT audit_logs_subject_check
+    CHECK (expense_id IS NOT NULL OR cash_advance_id IS NOT NULL);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_cash_advance_id ON audit_logs(cash_advance_id);
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS budgets;
+
+ALTER TABLE expenses DROP CONSTRAINT IF EXISTS expenses_category_check;
+ALTER TABLE expenses DROP COLUMN IF EXISTS category;
+
+ALTER TABLE users DROP COLUMN IF EXISTS team_id;
+DROP TABLE IF EXISTS teams;
@@ -0,0 +1,42 @@
+-- Create teams table and assign employees to a team
+CREATE TABLE IF NOT EXISTS teams (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) UNIQUE NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS team_id INTEGER REFERENCES teams(id);
+CREATE INDEX IF NOT EXISTS idx_users_team_id ON users(team_id);
+
+-- Expense categories for category budgets
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS category VARCHAR(50) NOT NULL DEFAULT 'general';
+ALTER TABLE expenses DROP CONSTRAINT IF EXISTS expenses_category_check;
+ALTER TABLE expenses ADD CONSTRAINT expenses_category_check
+    CHECK (category IN ('general', 'travel', 'lodging', 'meals', 'transport', 'office', 'training'));
+CREATE INDEX IF NOT EXISTS idx_expenses_category ON expenses(category);
+
+-- Create budgets table (per user, team and/or category, per period)
+CREATE TABLE IF NOT EXISTS budgets (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id),
+    team_id INTEGER REFERENCES teams(id),
+    category VARCHAR(50),
+    period VARCHAR(20) NOT NULL CHECK (period IN ('monthly', 'quarterly', 'yearly')),
+    soft_limit_idr BIGINT CHECK (soft_limit_idr > 0),
+    hard_limit_idr BIGINT CHECK (hard_limit_idr > 0),
+    hard_limit_action VARCHAR(50) NOT NULL DEFAULT 'require_approval'
+        CHECK (hard_limit_action IN ('require_approval', 'block')),
+    created_by INTEGER NOT NULL REFERENCES users(id),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK (user_id IS NULL OR team_id IS NULL),
+    CHECK (soft_limit_idr IS NOT NULL OR hard_limit_idr IS NOT NULL)
+);
+
+CREATE INDEX IF NOT EXISTS idx_budgets_user_id ON budgets(user_id);
+CREATE INDEX IF NOT EXISTS idx_budgets_team_id ON budgets(team_id);
+
+-- Seed a team for the demo users
+INSERT INTO teams (name) VALUES ('Operations') ON CONFLICT (name) DO NOTHING;
+UPDATE users SET team_id = (SELECT id FROM teams WHERE name = 'Operations')
+WHERE team_id IS NULL AND email IN ('employee1@example.com', 'employee2@example.com', 'manager@example.com');
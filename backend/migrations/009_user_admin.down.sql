@@ -0,0 +1,12 @@
+DELETE FROM audit_logs WHERE expense_id IS NULL AND cash_advance_id IS NULL;
+ALTER TABLE audit_logs DROP CONSTRAINT IF EXISTS audit_logs_subject_check;
+DROP INDEX IF EXISTS idx_audit_logs_subject_user_id;
+ALTER TABLE audit_logs DROP COLUMN IF EXISTS subject_user_id;
+ALTER TABLE audit_logs ADD CONSTRAINT audit_logs_subject_check
+    CHECK (expense_id IS NOT NULL OR cash_advance_id IS NOT NULL);
+
+ALTER TABLE users DROP COLUMN IF EXISTS active;
+
+UPDATE users SET role = 'employee' WHERE role = 'admin';
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+ALTER TABLE users ADD CONSTRAINT users_role_check CHECK (role IN ('employee', 'manager', 'finance'));
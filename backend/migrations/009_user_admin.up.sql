@@ -0,0 +1,18 @@
+-- Admins manage user accounts
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+ALTER TABLE users ADD CONSTRAINT users_role_check CHECK (role IN ('employee', 'manager', 'finance', 'admin'));
+
+-- Deactivated users keep their history but can no longer sign in
+ALTER TABLE users ADD COLUMN IF NOT EXISTS active BOOLEAN NOT NULL DEFAULT TRUE;
+
+-- Audit entries for changes to user accounts
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS subject_user_id INTEGER REFERENCES users(id);
+ALTER TABLE audit_logs DROP CONSTRAINT IF EXISTS audit_logs_subject_check;
+ALTER TABLE audit_logs ADD CONSTRAINT audit_logs_subject_check
+    CHECK (expense_id IS NOT NULL OR cash_advance_id IS NOT NULL OR subject_user_id IS NOT NULL);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_subject_user_id ON audit_logs(subject_user_id);
+
+-- Seed admin user (password: password123)
+INSERT INTO users (email, password_hash, name, role) VALUES
+('admin@example.com', '$2a$10$ArfoA5Y.NYwKkh/e61P5kutQB7u0zC2coCvmTD7qv9kwJ.GhgHZ1y', 'Admin A', 'admin')
+ON CONFLICT (email) DO NOTHING;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS sso_auth_requests;
+DROP TABLE IF EXISTS user_identities;
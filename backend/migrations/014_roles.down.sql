@@ -0,0 +1,9 @@
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_fkey;
+
+DELETE FROM users WHERE email = 'auditor@example.com';
+UPDATE users SET role = 'employee' WHERE role NOT IN ('employee', 'manager', 'finance', 'admin');
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+ALTER TABLE users ADD CONSTRAINT users_role_check CHECK (role IN ('employee', 'manager', 'finance', 'admin'));
+
+DROP TABLE IF EXISTS role_permissions;
+DROP TABLE IF EXISTS roles;
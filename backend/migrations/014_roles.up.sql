@@ -0,0 +1,76 @@
+-- Roles are named sets of permissions. Built-in roles cannot be deleted;
+-- admins can add more and change what each role may do.
+CREATE TABLE IF NOT EXISTS roles (
+    name VARCHAR(50) PRIMARY KEY,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    built_in BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role VARCHAR(50) NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+    permission VARCHAR(100) NOT NULL,
+    PRIMARY KEY (role, permission)
+);
+
+INSERT INTO roles (name, description, built_in) VALUES
+('employee', 'Submits expenses and requests cash advances', TRUE),
+('manager', 'Approves expenses and cash advances', TRUE),
+('finance', 'Manages payments, refunds, advances and budgets', TRUE),
+('admin', 'Manages user accounts and roles', TRUE),
+('auditor', 'Read-only access to expenses, payments and audit logs', TRUE)
+ON CONFLICT (name) DO NOTHING;
+
+-- Seed permissions only for roles that have none yet, so re-running the
+-- migration keeps changes made by admins.
+INSERT INTO role_permissions (role, permission)
+SELECT seed.role, seed.permission
+FROM (VALUES
+    ('employee', 'expense:submit'),
+    ('employee', 'advance:request'),
+
+    ('manager', 'expense:submit'),
+    ('manager', 'expense:read_all'),
+    ('manager', 'expense:approve'),
+    ('manager', 'advance:request'),
+    ('manager', 'advance:read_all'),
+    ('manager', 'advance:approve'),
+    ('manager', 'budget:read_all'),
+
+    ('finance', 'expense:submit'),
+    ('finance', 'expense:read_all'),
+    ('finance', 'refund:create'),
+    ('finance', 'payment:read'),
+    ('finance', 'payment:release'),
+    ('finance', 'advance:request'),
+    ('finance', 'advance:read_all'),
+    ('finance', 'advance:close'),
+    ('finance', 'budget:read_all'),
+    ('finance', 'budget:manage'),
+
+    ('admin', 'expense:submit'),
+    ('admin', 'advance:request'),
+    ('admin', 'user:read'),
+    ('admin', 'user:manage'),
+    ('admin', 'role:manage'),
+    ('admin', 'audit:read'),
+
+    ('auditor', 'expense:read_all'),
+    ('auditor', 'advance:read_all'),
+    ('auditor', 'payment:read'),
+    ('auditor', 'budget:read_all'),
+    ('auditor', 'user:read'),
+    ('auditor', 'audit:read')
+) AS seed(role, permission)
+WHERE NOT EXISTS (SELECT 1 FROM role_permissions rp WHERE rp.role = seed.role);
+
+-- Users now reference a role definition instead of a fixed list
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_fkey;
+ALTER TABLE users ADD CONSTRAINT users_role_fkey FOREIGN KEY (role) REFERENCES roles(name);
+
+-- Seed auditor user (password: password123)
+INSERT INTO users (email, password_hash, name, role) VALUES
+('auditor@example.com', '$2a$10$ArfoA5Y.NYwKkh/e61P5kutQB7u0zC2coCvmTD7qv9kwJ.GhgHZ1y', 'Auditor A', 'auditor')
+ON CONFLICT (email) DO NOTHING;
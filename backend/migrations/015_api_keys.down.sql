@@ -0,0 +1,8 @@
+ALTER TABLE audit_logs DROP COLUMN IF EXISTS api_key_id;
+DROP TABLE IF EXISTS api_keys;
+
+DELETE FROM role_permissions WHERE permission = 'service_account:manage';
+DELETE FROM audit_logs WHERE subject_user_id IN (SELECT id FROM users WHERE service_account);
+DELETE FROM users WHERE service_account;
+DELETE FROM roles WHERE name = 'service';
+ALTER TABLE users DROP COLUMN IF EXISTS service_account;
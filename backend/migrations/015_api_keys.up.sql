@@ -0,0 +1,36 @@
+-- Service accounts are users that integrations act as. They have no
+-- password and authenticate with API keys only.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS service_account BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- The service role grants nothing; a service account can do what the
+-- scopes of the key it calls with allow.
+INSERT INTO roles (name, description, built_in) VALUES
+('service', 'Service accounts; API key scopes decide what they may do', TRUE)
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role, permission) VALUES
+('admin', 'service_account:manage')
+ON CONFLICT DO NOTHING;
+
+-- Keys are stored as a SHA-256 hash. The prefix is the part of the key
+-- before the secret and is used to look it up.
+CREATE TABLE IF NOT EXISTS api_keys (
+    id SERIAL PRIMARY KEY,
+    service_account_id INTEGER NOT NULL REFERENCES users(id),
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(20) NOT NULL UNIQUE,
+    key_hash VARCHAR(64) NOT NULL,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    last_used_ip VARCHAR(45),
+    created_by INTEGER NOT NULL REFERENCES users(id),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    revoked_at TIMESTAMP,
+    replaced_by_id INTEGER REFERENCES api_keys(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_service_account_id ON api_keys(service_account_id);
+
+-- Changes made with an API key record which key was used.
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS api_key_id INTEGER REFERENCES api_keys(id);
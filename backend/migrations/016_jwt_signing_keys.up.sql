@@ -0,0 +1,14 @@
+-- Key pairs access tokens are signed with when JWT_SIGNING_ALGORITHM is
+-- RS256 or EdDSA. Private keys are PKCS #8, encrypted with a key derived
+-- from JWT_SECRET. The API adds and removes keys itself.
+CREATE TABLE IF NOT EXISTS jwt_signing_keys (
+    kid VARCHAR(32) PRIMARY KEY,
+    algorithm VARCHAR(10) NOT NULL,
+    private_key BYTEA NOT NULL,
+    activates_at TIMESTAMP NOT NULL,
+    retires_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_jwt_signing_keys_expires_at ON jwt_signing_keys(expires_at);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_approvals_approver_id;
+DROP INDEX IF EXISTS idx_expenses_amount_idr;
+DROP INDEX IF EXISTS idx_expenses_processed_at;
+DROP INDEX IF EXISTS idx_expenses_submitted_at;
+DROP INDEX IF EXISTS idx_expenses_description_fts;
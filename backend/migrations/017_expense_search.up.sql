@@ -0,0 +1,10 @@
+-- Indexes for the expense list filters. The full-text index uses the
+-- 'simple' configuration, which matches words as written in any language;
+-- queries must use the same expression to be able to use it.
+CREATE INDEX IF NOT EXISTS idx_expenses_description_fts
+    ON expenses USING GIN (to_tsvector('simple', description));
+
+CREATE INDEX IF NOT EXISTS idx_expenses_submitted_at ON expenses(submitted_at);
+CREATE INDEX IF NOT EXISTS idx_expenses_processed_at ON expenses(processed_at);
+CREATE INDEX IF NOT EXISTS idx_expenses_amount_idr ON expenses(amount_idr);
+CREATE INDEX IF NOT EXISTS idx_approvals_approver_id ON approvals(approver_id);
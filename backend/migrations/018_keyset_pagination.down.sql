@@ -0,0 +1,10 @@
+CREATE INDEX IF NOT EXISTS idx_audit_logs_subject_user_id ON audit_logs(subject_user_id);
+CREATE INDEX IF NOT EXISTS idx_expenses_amount_idr ON expenses(amount_idr);
+CREATE INDEX IF NOT EXISTS idx_expenses_processed_at ON expenses(processed_at);
+CREATE INDEX IF NOT EXISTS idx_expenses_submitted_at ON expenses(submitted_at);
+
+DROP INDEX IF EXISTS idx_audit_logs_subject_user_created_at_id;
+DROP INDEX IF EXISTS idx_expenses_amount_idr_id;
+DROP INDEX IF EXISTS idx_expenses_processed_at_id;
+DROP INDEX IF EXISTS idx_expenses_user_submitted_at_id;
+DROP INDEX IF EXISTS idx_expenses_submitted_at_id;
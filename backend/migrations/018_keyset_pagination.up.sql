@@ -0,0 +1,13 @@
+-- Composite indexes for cursor pagination: each sort column together with
+-- the id tiebreaker, so a page is an index range scan from the cursor.
+-- They replace the single-column sort indexes of 017.
+CREATE INDEX IF NOT EXISTS idx_expenses_submitted_at_id ON expenses(submitted_at, id);
+CREATE INDEX IF NOT EXISTS idx_expenses_user_submitted_at_id ON expenses(user_id, submitted_at, id);
+CREATE INDEX IF NOT EXISTS idx_expenses_processed_at_id ON expenses(processed_at, id);
+CREATE INDEX IF NOT EXISTS idx_expenses_amount_idr_id ON expenses(amount_idr, id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_subject_user_created_at_id ON audit_logs(subject_user_id, created_at, id);
+
+DROP INDEX IF EXISTS idx_expenses_submitted_at;
+DROP INDEX IF EXISTS idx_expenses_processed_at;
+DROP INDEX IF EXISTS idx_expenses_amount_idr;
+DROP INDEX IF EXISTS idx_audit_logs_subject_user_id;
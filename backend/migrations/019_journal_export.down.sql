@@ -0,0 +1,8 @@
+DELETE FROM role_permissions WHERE permission IN ('journal:export', 'journal:manage');
+
+DROP INDEX IF EXISTS idx_expenses_unexported;
+ALTER TABLE expenses DROP COLUMN IF EXISTS journal_batch_id;
+
+DROP TABLE IF EXISTS journal_lines;
+DROP TABLE IF EXISTS journal_batches;
+DROP TABLE IF EXISTS journal_accounts;
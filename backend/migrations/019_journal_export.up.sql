@@ -0,0 +1,57 @@
+-- Ledger accounts journal lines post to: one expense account per category,
+-- the bank account payouts are made from and the account cash advances are
+-- held in. Finance changes the codes to match their chart of accounts.
+CREATE TABLE IF NOT EXISTS journal_accounts (
+    key VARCHAR(50) PRIMARY KEY,
+    code VARCHAR(50) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO journal_accounts (key, code, name) VALUES
+('expense:general', '6000', 'General Expenses'),
+('expense:travel', '6100', 'Travel'),
+('expense:lodging', '6110', 'Lodging'),
+('expense:meals', '6120', 'Meals and Entertainment'),
+('expense:transport', '6130', 'Local Transport'),
+('expense:office', '6200', 'Office Supplies'),
+('expense:training', '6300', 'Training'),
+('cash', '1010', 'Bank'),
+('employee_advances', '1410', 'Employee Advances')
+ON CONFLICT (key) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS journal_batches (
+    id SERIAL PRIMARY KEY,
+    paid_before TIMESTAMP NOT NULL,
+    expense_count INTEGER NOT NULL,
+    total_amount_idr BIGINT NOT NULL,
+    created_by INTEGER NOT NULL REFERENCES users(id),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Lines keep the account code and name they were exported with, so later
+-- changes to journal_accounts do not alter past batches.
+CREATE TABLE IF NOT EXISTS journal_lines (
+    id SERIAL PRIMARY KEY,
+    batch_id INTEGER NOT NULL REFERENCES journal_batches(id),
+    expense_id INTEGER NOT NULL REFERENCES expenses(id),
+    entry_date DATE NOT NULL,
+    account_code VARCHAR(50) NOT NULL,
+    account_name VARCHAR(255) NOT NULL,
+    debit_idr INTEGER NOT NULL DEFAULT 0,
+    credit_idr INTEGER NOT NULL DEFAULT 0,
+    description TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_journal_lines_batch_id ON journal_lines(batch_id);
+
+-- An expense is exported once; the batch it went out in is kept with it.
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS journal_batch_id INTEGER REFERENCES journal_batches(id);
+
+CREATE INDEX IF NOT EXISTS idx_expenses_unexported ON expenses(processed_at)
+    WHERE journal_batch_id IS NULL AND status IN ('completed', 'partially_refunded', 'refunded');
+
+INSERT INTO role_permissions (role, permission) VALUES
+('finance', 'journal:export'),
+('finance', 'journal:manage')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,4 @@
+DELETE FROM role_permissions WHERE permission IN ('report:read', 'report:manage');
+
+DROP TABLE IF EXISTS report_runs;
+DROP TABLE IF EXISTS report_schedules;
@@ -0,0 +1,46 @@
+-- Report definitions run on a cron schedule. Filters hold the expense list
+-- filters as JSON; the submission dates come from the period of each run.
+CREATE TABLE IF NOT EXISTS report_schedules (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    cron VARCHAR(100) NOT NULL,
+    period VARCHAR(20) NOT NULL,
+    group_by VARCHAR(20) NOT NULL DEFAULT '',
+    format VARCHAR(10) NOT NULL,
+    filters JSONB NOT NULL DEFAULT '{}',
+    recipients TEXT[] NOT NULL DEFAULT '{}',
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    next_run_at TIMESTAMP,
+    last_run_at TIMESTAMP,
+    created_by INTEGER NOT NULL REFERENCES users(id),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_schedules_due ON report_schedules(next_run_at) WHERE enabled;
+
+-- Runs outlive their schedule so past files stay downloadable.
+CREATE TABLE IF NOT EXISTS report_runs (
+    id SERIAL PRIMARY KEY,
+    schedule_id INTEGER REFERENCES report_schedules(id) ON DELETE SET NULL,
+    report_name VARCHAR(100) NOT NULL,
+    trigger VARCHAR(20) NOT NULL,
+    triggered_by INTEGER REFERENCES users(id),
+    status VARCHAR(20) NOT NULL,
+    format VARCHAR(10) NOT NULL,
+    period_start TIMESTAMP,
+    period_end TIMESTAMP,
+    row_count INTEGER NOT NULL DEFAULT 0,
+    file_name VARCHAR(255),
+    error TEXT,
+    started_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    finished_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_runs_schedule_id ON report_runs(schedule_id, id);
+
+INSERT INTO role_permissions (role, permission) VALUES
+('finance', 'report:read'),
+('finance', 'report:manage'),
+('auditor', 'report:read')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_expenses_user_amount_submitted_at;
+ALTER TABLE expenses DROP COLUMN IF EXISTS possible_duplicate_of;
+ALTER TABLE expenses DROP COLUMN IF EXISTS receipt_id;
+
+DROP TABLE IF EXISTS receipts;
@@ -0,0 +1,20 @@
+-- Uploaded receipt files. The content hash finds the same receipt
+-- submitted again, by anyone.
+CREATE TABLE IF NOT EXISTS receipts (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    file_name VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    sha256 CHAR(64) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_receipts_sha256 ON receipts(sha256);
+
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS receipt_id INTEGER REFERENCES receipts(id);
+CREATE INDEX IF NOT EXISTS idx_expenses_receipt_id ON expenses(receipt_id);
+
+-- Likely duplicates point at the earlier expense they repeat
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS possible_duplicate_of INTEGER REFERENCES expenses(id);
+CREATE INDEX IF NOT EXISTS idx_expenses_user_amount_submitted_at ON expenses(user_id, amount_idr, submitted_at);
@@ -0,0 +1,3 @@
+DELETE FROM role_permissions WHERE permission = 'policy:manage';
+
+DROP TABLE IF EXISTS policy_rules;
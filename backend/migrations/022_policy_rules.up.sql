@@ -0,0 +1,18 @@
+-- Admin-defined checks of submitted expenses. The condition is a JSON
+-- expression over the expense; matching expenses are blocked, warned about
+-- or sent for manager approval.
+CREATE TABLE IF NOT EXISTS policy_rules (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    message VARCHAR(500) NOT NULL,
+    action VARCHAR(20) NOT NULL CHECK (action IN ('block', 'warn', 'require_approval')),
+    condition JSONB NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by INTEGER NOT NULL REFERENCES users(id),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO role_permissions (role, permission) VALUES
+('admin', 'policy:manage')
+ON CONFLICT DO NOTHING;
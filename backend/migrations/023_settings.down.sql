@@ -0,0 +1,9 @@
+DELETE FROM role_permissions WHERE permission = 'settings:manage';
+
+-- NOT VALID keeps expenses submitted under wider limits.
+ALTER TABLE expenses DROP CONSTRAINT IF EXISTS expenses_amount_idr_positive;
+ALTER TABLE expenses DROP CONSTRAINT IF EXISTS expenses_amount_idr_check;
+ALTER TABLE expenses ADD CONSTRAINT expenses_amount_idr_check CHECK (amount_idr >= 10000 AND amount_idr <= 50000000) NOT VALID;
+
+DROP TABLE IF EXISTS setting_changes;
+DROP TABLE IF EXISTS settings;
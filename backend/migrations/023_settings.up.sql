@@ -0,0 +1,30 @@
+-- Runtime settings admins change without a deploy. Settings that are not
+-- stored take their value from the environment.
+CREATE TABLE IF NOT EXISTS settings (
+    key VARCHAR(100) PRIMARY KEY,
+    value BIGINT NOT NULL,
+    updated_by INTEGER REFERENCES users(id),
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Every change, including resets to the environment value (new_value NULL).
+CREATE TABLE IF NOT EXISTS setting_changes (
+    id SERIAL PRIMARY KEY,
+    key VARCHAR(100) NOT NULL,
+    old_value BIGINT,
+    new_value BIGINT,
+    changed_by INTEGER NOT NULL REFERENCES users(id),
+    changed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_setting_changes_key_changed_at ON setting_changes(key, changed_at DESC);
+
+-- The expense amount limits are settings now and checked by the API, so the
+-- database only insists on a positive amount.
+ALTER TABLE expenses DROP CONSTRAINT IF EXISTS expenses_amount_idr_check;
+ALTER TABLE expenses DROP CONSTRAINT IF EXISTS expenses_amount_idr_positive;
+ALTER TABLE expenses ADD CONSTRAINT expenses_amount_idr_positive CHECK (amount_idr > 0);
+
+INSERT INTO role_permissions (role, permission) VALUES
+('admin', 'settings:manage')
+ON CONFLICT DO NOTHING;
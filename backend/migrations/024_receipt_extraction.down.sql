@@ -0,0 +1,4 @@
+ALTER TABLE expenses DROP COLUMN IF EXISTS receipt_amount_mismatch;
+ALTER TABLE expenses DROP COLUMN IF EXISTS receipt_extraction;
+
+ALTER TABLE receipts DROP COLUMN IF EXISTS extraction;
@@ -0,0 +1,7 @@
+-- Fields read off receipts by OCR: {"total", "currency", "date",
+-- "merchant"}, each present only when it could be read. Expenses keep a copy
+-- of their receipt's, and whether its total differs from the amount claimed.
+ALTER TABLE receipts ADD COLUMN IF NOT EXISTS extraction JSONB;
+
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS receipt_extraction JSONB;
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS receipt_amount_mismatch BOOLEAN NOT NULL DEFAULT FALSE;
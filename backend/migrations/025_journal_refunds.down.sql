@@ -0,0 +1,7 @@
+ALTER TABLE journal_batches DROP COLUMN IF EXISTS refunded_amount_idr;
+ALTER TABLE journal_batches DROP COLUMN IF EXISTS refund_count;
+
+ALTER TABLE journal_lines DROP COLUMN IF EXISTS refund_id;
+
+DROP INDEX IF EXISTS idx_refunds_unexported;
+ALTER TABLE refunds DROP COLUMN IF EXISTS journal_batch_id;
@@ -0,0 +1,12 @@
+-- Completed refunds are journaled as entries of their own, reversing what
+-- the payout of the expense posted. Refunds that completed before this
+-- migration go out with the next batch.
+ALTER TABLE refunds ADD COLUMN IF NOT EXISTS journal_batch_id INTEGER REFERENCES journal_batches(id);
+
+CREATE INDEX IF NOT EXISTS idx_refunds_unexported ON refunds(updated_at)
+    WHERE journal_batch_id IS NULL AND status = 'completed';
+
+ALTER TABLE journal_lines ADD COLUMN IF NOT EXISTS refund_id INTEGER REFERENCES refunds(id);
+
+ALTER TABLE journal_batches ADD COLUMN IF NOT EXISTS refund_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE journal_batches ADD COLUMN IF NOT EXISTS refunded_amount_idr BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,5 @@
+-- Retrying a failed payout sends money again, so it is a permission of its
+-- own rather than part of releasing a run.
+INSERT INTO role_permissions (role, permission) VALUES
+('finance', 'payment:retry')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_expenses_processed_at_desc_id;
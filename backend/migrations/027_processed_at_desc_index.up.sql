@@ -0,0 +1,4 @@
+-- Newest payments first sorts processed_at DESC NULLS LAST. Reading
+-- idx_expenses_processed_at_id backwards gives DESC NULLS FIRST, which
+-- Postgres cannot use for that order, so it gets an index of its own.
+CREATE INDEX IF NOT EXISTS idx_expenses_processed_at_desc_id ON expenses(processed_at DESC NULLS LAST, id DESC);
@@ -1,11 +1,18 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"strconv"
+	"strings"
 )
 
 type Config struct {
+	// AppEnv is "development" for local setups; anything else is treated
+	// as production.
+	AppEnv string
+
 	DBHost     string
 	DBPort     string
 	DBUser     string
@@ -13,8 +20,52 @@ type Config struct {
 	DBName     string
 	DBSSLMode  string
 
-	JWTSecret      string
-	JWTExpiryHours int
+	// JWTSecret signs access tokens when JWTSigningAlgorithm is HS256, and
+	// in every case two-factor challenge tokens and the encryption of the
+	// stored signing keys.
+	JWTSecret                string
+	JWTSigningAlgorithm      string
+	JWTKeyRotationDays       int
+	JWTKeyPublishLeadMinutes int
+	AccessTokenTTLMinutes    int
+	RefreshTokenTTLHours     int
+
+	// UserCacheTTLSeconds is how long authenticated users are cached (0 to
+	// always load them). With AuthTrustTokenClaims, read-only requests take
+	// the user from the access token's claims instead, and only check with
+	// the cache that the user is still active.
+	UserCacheTTLSeconds  int
+	AuthTrustTokenClaims bool
+
+	PasswordResetTTLMinutes int
+	PasswordResetURL        string
+
+	LoginMaxAttempts          int
+	LoginIPMaxAttempts        int
+	LoginAttemptWindowMinutes int
+	LoginLockoutMinutes       int
+
+	TOTPIssuer             string
+	TOTPRequiredRoles      []string
+	MFAChallengeTTLMinutes int
+	StepUpAmountIDR        int
+
+	// Single sign-on is enabled when OIDCIssuerURL is set.
+	OIDCIssuerURL            string
+	OIDCClientID             string
+	OIDCClientSecret         string
+	OIDCRedirectURL          string
+	OIDCScopes               []string
+	OIDCGroupsClaim          string
+	OIDCRoleMapping          []string
+	OIDCAutoProvision        bool
+	OIDCSyncRoles            bool
+	OIDCRequireVerifiedEmail bool
+
+	// API keys without an explicit expiry last APIKeyTTLDays (0 for no
+	// expiry); rotated keys keep working for APIKeyRotationGraceHours.
+	APIKeyTTLDays            int
+	APIKeyRotationGraceHours int
 
 	ServerPort string
 
@@ -22,14 +73,77 @@ type Config struct {
 
 	WorkerPoolSize   int
 	WorkerMaxRetries int
+
+	PaymentBreakerThreshold   int
+	PaymentBreakerOpenSeconds int
+	PaymentRetryBaseMs        int
+	PaymentRetryMaxMs         int
+
+	PaymentMode      string
+	PaymentRunCutoff string
+
+	// Scheduled report files are kept in ReportStorageDir; recipients get
+	// links to them under PublicAPIURL.
+	ReportStorageDir string
+	PublicAPIURL     string
+
+	// Uploaded receipt files are kept in ReceiptStorageDir. With
+	// ReceiptOCREnabled, images are read with Tesseract on upload to
+	// prefill the expense.
+	ReceiptStorageDir        string
+	ReceiptOCREnabled        bool
+	TesseractPath            string
+	TesseractLanguages       string
+	ReceiptOCRTimeoutSeconds int
+
+	// Expense limits apply until an admin changes them in the settings.
+	MinExpenseAmountIDR  int
+	MaxExpenseAmountIDR  int
+	ApprovalThresholdIDR int
 }
 
+const AppEnvDevelopment = "development"
+
+// placeholderJWTSecrets are the secrets shipped in the defaults and examples.
+var placeholderJWTSecrets = []string{"your-secret-key", "your-secret-key-change-in-production"}
+
+// minJWTSecretLength is the HS256 key size.
+const minJWTSecretLength = 32
+
+const (
+	PaymentModeImmediate = "immediate"
+	PaymentModeBatched   = "batched"
+)
+
 func Load() *Config {
-	jwtExpiry, _ := strconv.Atoi(getEnv("JWT_EXPIRY_HOURS", "24"))
+	accessTokenTTL, _ := strconv.Atoi(getEnv("ACCESS_TOKEN_TTL_MINUTES", "15"))
+	refreshTokenTTL, _ := strconv.Atoi(getEnv("REFRESH_TOKEN_TTL_HOURS", "720"))
+	keyRotationDays, _ := strconv.Atoi(getEnv("JWT_KEY_ROTATION_DAYS", "30"))
+	keyPublishLead, _ := strconv.Atoi(getEnv("JWT_KEY_PUBLISH_LEAD_MINUTES", "60"))
+	userCacheTTL, _ := strconv.Atoi(getEnv("USER_CACHE_TTL_SECONDS", "30"))
+	passwordResetTTL, _ := strconv.Atoi(getEnv("PASSWORD_RESET_TTL_MINUTES", "30"))
+	loginMaxAttempts, _ := strconv.Atoi(getEnv("LOGIN_MAX_ATTEMPTS", "5"))
+	loginIPMaxAttempts, _ := strconv.Atoi(getEnv("LOGIN_IP_MAX_ATTEMPTS", "20"))
+	loginAttemptWindow, _ := strconv.Atoi(getEnv("LOGIN_ATTEMPT_WINDOW_MINUTES", "15"))
+	loginLockout, _ := strconv.Atoi(getEnv("LOGIN_LOCKOUT_MINUTES", "15"))
+	mfaChallengeTTL, _ := strconv.Atoi(getEnv("MFA_CHALLENGE_TTL_MINUTES", "5"))
+	stepUpAmount, _ := strconv.Atoi(getEnv("APPROVAL_STEP_UP_AMOUNT_IDR", "10000000"))
+	apiKeyTTL, _ := strconv.Atoi(getEnv("API_KEY_TTL_DAYS", "365"))
+	apiKeyRotationGrace, _ := strconv.Atoi(getEnv("API_KEY_ROTATION_GRACE_HOURS", "24"))
 	workerPoolSize, _ := strconv.Atoi(getEnv("WORKER_POOL_SIZE", "5"))
 	workerMaxRetries, _ := strconv.Atoi(getEnv("WORKER_MAX_RETRIES", "3"))
+	breakerThreshold, _ := strconv.Atoi(getEnv("PAYMENT_BREAKER_THRESHOLD", "5"))
+	breakerOpenSeconds, _ := strconv.Atoi(getEnv("PAYMENT_BREAKER_OPEN_SECONDS", "30"))
+	retryBaseMs, _ := strconv.Atoi(getEnv("PAYMENT_RETRY_BASE_MS", "1000"))
+	retryMaxMs, _ := strconv.Atoi(getEnv("PAYMENT_RETRY_MAX_MS", "30000"))
+	receiptOCRTimeout, _ := strconv.Atoi(getEnv("RECEIPT_OCR_TIMEOUT_SECONDS", "20"))
+	minExpenseAmount, _ := strconv.Atoi(getEnv("MIN_EXPENSE_AMOUNT_IDR", "10000"))
+	maxExpenseAmount, _ := strconv.Atoi(getEnv("MAX_EXPENSE_AMOUNT_IDR", "50000000"))
+	approvalThreshold, _ := strconv.Atoi(getEnv("APPROVAL_THRESHOLD_IDR", "1000000"))
 
 	return &Config{
+		AppEnv: getEnv("APP_ENV", "production"),
+
 		DBHost:     getEnv("DB_HOST", "localhost"),
 		DBPort:     getEnv("DB_PORT", "5432"),
 		DBUser:     getEnv("DB_USER", "expense_user"),
@@ -37,8 +151,42 @@ func Load() *Config {
 		DBName:     getEnv("DB_NAME", "expense_db"),
 		DBSSLMode:  getEnv("DB_SSLMODE", "disable"),
 
-		JWTSecret:      getEnv("JWT_SECRET", "your-secret-key"),
-		JWTExpiryHours: jwtExpiry,
+		JWTSecret:                getEnv("JWT_SECRET", "your-secret-key"),
+		JWTSigningAlgorithm:      getEnv("JWT_SIGNING_ALGORITHM", "RS256"),
+		JWTKeyRotationDays:       keyRotationDays,
+		JWTKeyPublishLeadMinutes: keyPublishLead,
+		AccessTokenTTLMinutes:    accessTokenTTL,
+		RefreshTokenTTLHours:     refreshTokenTTL,
+
+		UserCacheTTLSeconds:  userCacheTTL,
+		AuthTrustTokenClaims: getEnvBool("AUTH_TRUST_TOKEN_CLAIMS", false),
+
+		PasswordResetTTLMinutes: passwordResetTTL,
+		PasswordResetURL:        getEnv("PASSWORD_RESET_URL", "http://localhost:3000/reset-password"),
+
+		LoginMaxAttempts:          loginMaxAttempts,
+		LoginIPMaxAttempts:        loginIPMaxAttempts,
+		LoginAttemptWindowMinutes: loginAttemptWindow,
+		LoginLockoutMinutes:       loginLockout,
+
+		TOTPIssuer:             getEnv("TOTP_ISSUER", "Expense Management"),
+		TOTPRequiredRoles:      splitList(getEnv("TOTP_REQUIRED_ROLES", "")),
+		MFAChallengeTTLMinutes: mfaChallengeTTL,
+		StepUpAmountIDR:        stepUpAmount,
+
+		OIDCIssuerURL:            getEnv("OIDC_ISSUER_URL", ""),
+		OIDCClientID:             getEnv("OIDC_CLIENT_ID", ""),
+		OIDCClientSecret:         getEnv("OIDC_CLIENT_SECRET", ""),
+		OIDCRedirectURL:          getEnv("OIDC_REDIRECT_URL", "http://localhost:3000/auth/callback"),
+		OIDCScopes:               splitList(getEnv("OIDC_SCOPES", "openid,email,profile")),
+		OIDCGroupsClaim:          getEnv("OIDC_GROUPS_CLAIM", "groups"),
+		OIDCRoleMapping:          splitList(getEnv("OIDC_ROLE_MAPPING", "")),
+		OIDCAutoProvision:        getEnvBool("OIDC_AUTO_PROVISION", true),
+		OIDCSyncRoles:            getEnvBool("OIDC_SYNC_ROLES", false),
+		OIDCRequireVerifiedEmail: getEnvBool("OIDC_REQUIRE_VERIFIED_EMAIL", true),
+
+		APIKeyTTLDays:            apiKeyTTL,
+		APIKeyRotationGraceHours: apiKeyRotationGrace,
 
 		ServerPort: getEnv("SERVER_PORT", "8080"),
 
@@ -46,12 +194,90 @@ func Load() *Config {
 
 		WorkerPoolSize:   workerPoolSize,
 		WorkerMaxRetries: workerMaxRetries,
+
+		PaymentBreakerThreshold:   breakerThreshold,
+		PaymentBreakerOpenSeconds: breakerOpenSeconds,
+		PaymentRetryBaseMs:        retryBaseMs,
+		PaymentRetryMaxMs:         retryMaxMs,
+
+		PaymentMode:      getEnv("PAYMENT_MODE", PaymentModeImmediate),
+		PaymentRunCutoff: getEnv("PAYMENT_RUN_CUTOFF", "17:00"),
+
+		ReportStorageDir: getEnv("REPORT_STORAGE_DIR", "./data/reports"),
+		PublicAPIURL:     getEnv("PUBLIC_API_URL", "http://localhost:8080/api"),
+
+		ReceiptStorageDir:        getEnv("RECEIPT_STORAGE_DIR", "./data/receipts"),
+		ReceiptOCREnabled:        getEnvBool("RECEIPT_OCR_ENABLED", false),
+		TesseractPath:            getEnv("TESSERACT_PATH", "tesseract"),
+		TesseractLanguages:       getEnv("TESSERACT_LANGUAGES", "eng+ind"),
+		ReceiptOCRTimeoutSeconds: receiptOCRTimeout,
+
+		MinExpenseAmountIDR:  minExpenseAmount,
+		MaxExpenseAmountIDR:  maxExpenseAmount,
+		ApprovalThresholdIDR: approvalThreshold,
 	}
 }
 
+// Validate reports settings the API must not start with. Placeholder and
+// short JWT secrets are only allowed in development.
+func (c *Config) Validate() error {
+	switch c.JWTSigningAlgorithm {
+	case "HS256", "RS256", "EdDSA":
+	default:
+		return fmt.Errorf("JWT_SIGNING_ALGORITHM must be HS256, RS256 or EdDSA, not %q", c.JWTSigningAlgorithm)
+	}
+	if c.JWTKeyRotationDays <= 0 {
+		return errors.New("JWT_KEY_ROTATION_DAYS must be positive")
+	}
+	if c.JWTKeyPublishLeadMinutes < 10 {
+		return errors.New("JWT_KEY_PUBLISH_LEAD_MINUTES must be at least 10")
+	}
+	if c.MinExpenseAmountIDR <= 0 || c.MaxExpenseAmountIDR < c.MinExpenseAmountIDR {
+		return errors.New("MIN_EXPENSE_AMOUNT_IDR must be positive and at most MAX_EXPENSE_AMOUNT_IDR")
+	}
+	if c.ApprovalThresholdIDR < 0 {
+		return errors.New("APPROVAL_THRESHOLD_IDR must not be negative")
+	}
+	if c.ReceiptOCREnabled && c.ReceiptOCRTimeoutSeconds <= 0 {
+		return errors.New("RECEIPT_OCR_TIMEOUT_SECONDS must be positive")
+	}
+
+	if c.AppEnv == AppEnvDevelopment {
+		return nil
+	}
+	for _, placeholder := range placeholderJWTSecrets {
+		if c.JWTSecret == placeholder {
+			return errors.New("JWT_SECRET is still the example value; set a random secret or APP_ENV=development")
+		}
+	}
+	if len(c.JWTSecret) < minJWTSecretLength {
+		return fmt.Errorf("JWT_SECRET must be at least %d characters", minJWTSecretLength)
+	}
+	return nil
+}
+
 func getEnv(key, fallback string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
 	}
 	return fallback
 }
+
+func getEnvBool(key string, fallback bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// splitList parses a comma separated env value, dropping empty entries.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
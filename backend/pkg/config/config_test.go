@@ -0,0 +1,50 @@
+package config
+
+import "testing"
+
+func TestConfig_Validate(t *testing.T) {
+	valid := func() *Config {
+		return &Config{
+			AppEnv:                   "production",
+			JWTSecret:                "0123456789abcdef0123456789abcdef",
+			JWTSigningAlgorithm:      "RS256",
+			JWTKeyRotationDays:       30,
+			JWTKeyPublishLeadMinutes: 60,
+			MinExpenseAmountIDR:      10000,
+			MaxExpenseAmountIDR:      50000000,
+			ApprovalThresholdIDR:     1000000,
+		}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(c *Config)
+		wantErr bool
+	}{
+		{"valid", func(c *Config) {}, false},
+		{"example secret in production", func(c *Config) { c.JWTSecret = "your-secret-key-change-in-production" }, true},
+		{"default secret in production", func(c *Config) { c.JWTSecret = "your-secret-key" }, true},
+		{"short secret in production", func(c *Config) { c.JWTSecret = "too-short" }, true},
+		{"example secret in development", func(c *Config) {
+			c.AppEnv = AppEnvDevelopment
+			c.JWTSecret = "your-secret-key"
+		}, false},
+		{"unknown algorithm", func(c *Config) { c.JWTSigningAlgorithm = "none" }, true},
+		{"no rotation", func(c *Config) { c.JWTKeyRotationDays = 0 }, true},
+		{"short publish lead", func(c *Config) { c.JWTKeyPublishLeadMinutes = 1 }, true},
+		{"no minimum amount", func(c *Config) { c.MinExpenseAmountIDR = 0 }, true},
+		{"maximum below minimum", func(c *Config) { c.MaxExpenseAmountIDR = 5000 }, true},
+		{"negative threshold", func(c *Config) { c.ApprovalThresholdIDR = -1 }, true},
+		{"OCR without timeout", func(c *Config) { c.ReceiptOCREnabled = true }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := valid()
+			tt.modify(c)
+			if err := c.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
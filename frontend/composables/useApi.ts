@@ -3,11 +3,15 @@ export const useApi = () => {
   const config = useRuntimeConfig()
   const authStore = useAuthStore()
 
-  const apiFetch = async (endpoint: string, options: any = {}) => {
+  const request = async (endpoint: string, options: any = {}, retried = false): Promise<Response> => {
     const headers: any = {
       'Content-Type': 'application/json',
       ...options.headers
     }
+    // The browser sets the multipart boundary of form uploads itself
+    if (options.body instanceof FormData) {
+      delete headers['Content-Type']
+    }
 
     if (authStore.token) {
       headers.Authorization = `Bearer ${authStore.token}`
@@ -19,7 +23,10 @@ export const useApi = () => {
     })
 
     if (response.status === 401) {
-      authStore.logout()
+      if (!retried && await authStore.refresh()) {
+        return request(endpoint, options, true)
+      }
+      authStore.clearSession()
       navigateTo('/login')
       throw new Error('Unauthorized')
     }
@@ -29,8 +36,34 @@ export const useApi = () => {
       throw new Error(error || 'Request failed')
     }
 
+    return response
+  }
+
+  const apiFetch = async (endpoint: string, options: any = {}): Promise<any> => {
+    const response = await request(endpoint, options)
     return response.json()
   }
 
-  return { apiFetch }
+  // Saves a file the API sends, under the name it suggests.
+  const apiDownload = async (endpoint: string, fallbackName: string) => {
+    const response = await request(endpoint)
+    const disposition = response.headers.get('Content-Disposition') || ''
+    const match = disposition.match(/filename="([^"]+)"/)
+
+    const url = URL.createObjectURL(await response.blob())
+    const link = document.createElement('a')
+    link.href = url
+    link.download = match ? match[1] : fallbackName
+    link.click()
+    URL.revokeObjectURL(url)
+  }
+
+  // Loads a file the API sends into an object URL, for showing files that
+  // need the Authorization header; revoke it when done.
+  const apiObjectURL = async (endpoint: string): Promise<string> => {
+    const response = await request(endpoint)
+    return URL.createObjectURL(await response.blob())
+  }
+
+  return { apiFetch, apiDownload, apiObjectURL }
 }
@@ -5,7 +5,7 @@ export default defineNuxtRouteMiddleware((to, from) => {
     authStore.loadFromStorage()
   }
 
-  if (!authStore.isManager) {
+  if (!authStore.can('expense:approve')) {
     return navigateTo('/dashboard')
   }
 })
@@ -6,7 +6,8 @@ export default defineNuxtConfig({
 
   runtimeConfig: {
     public: {
-      apiBase: process.env.API_BASE_URL || 'http://localhost:8080/api'
+      apiBase: process.env.API_BASE_URL || 'http://localhost:8080/api',
+      ssoEnabled: process.env.SSO_ENABLED === 'true'
     }
   },
 
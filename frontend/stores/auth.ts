@@ -9,18 +9,26 @@ interface User {
 interface AuthState {
   user: User | null
   token: string | null
+  refreshToken: string | null
+  // What the user's role may do, e.g. 'expense:approve'.
+  permissions: string[]
+  // A two-factor challenge from single sign-on, for the login page to finish.
+  pendingChallenge: any | null
 }
 
 // @ts-ignore
 export const useAuthStore = defineStore('auth', {
   state: (): AuthState => ({
     user: null,
-    token: null
+    token: null,
+    refreshToken: null,
+    permissions: [],
+    pendingChallenge: null
   }),
 
   getters: {
     isAuthenticated: (state: AuthState) => !!state.token,
-    isManager: (state: AuthState) => state.user?.role === 'manager'
+    can: (state: AuthState) => (permission: string) => state.permissions.includes(permission)
   },
 
   actions: {
@@ -41,23 +49,188 @@ export const useAuthStore = defineStore('auth', {
       }
 
       const data = await response.json()
-      
+      if (data.mfa_required) {
+        // The caller finishes the login with verifyChallenge.
+        return data
+      }
+      await this.setSession(data)
+      return null
+    },
+
+    // startSSO sends the browser to the identity provider.
+    async startSSO() {
+      const config = useRuntimeConfig()
+
+      const response = await fetch(`${config.public.apiBase}/auth/oidc/authorize`)
+      if (!response.ok) {
+        const error = await response.text()
+        throw new Error(error || 'Single sign-on is unavailable')
+      }
+
+      const data = await response.json()
+      window.location.href = data.authorization_url
+    },
+
+    // completeSSO redeems the identity provider's redirect. Like login, it
+    // returns a challenge when a second factor is still needed.
+    async completeSSO(code: string, state: string) {
+      const config = useRuntimeConfig()
+
+      const response = await fetch(`${config.public.apiBase}/auth/oidc/callback`, {
+        method: 'POST',
+        headers: {
+          'Content-Type': 'application/json'
+        },
+        body: JSON.stringify({ code, state })
+      })
+
+      if (!response.ok) {
+        const error = await response.text()
+        throw new Error(error || 'Single sign-on failed')
+      }
+
+      const data = await response.json()
+      if (data.mfa_required) {
+        this.pendingChallenge = data
+        return data
+      }
+      await this.setSession(data)
+      return null
+    },
+
+    // challengeSetup returns a TOTP secret for users who must enrol before
+    // their login can complete.
+    async challengeSetup(challengeToken: string) {
+      const config = useRuntimeConfig()
+
+      const response = await fetch(`${config.public.apiBase}/auth/2fa/challenge/setup`, {
+        method: 'POST',
+        headers: {
+          'Content-Type': 'application/json'
+        },
+        body: JSON.stringify({ challenge_token: challengeToken })
+      })
+
+      if (!response.ok) {
+        const error = await response.text()
+        throw new Error(error || 'Two-factor setup failed')
+      }
+
+      return response.json()
+    },
+
+    // verifyChallenge completes a challenged login with a TOTP or recovery
+    // code. Returns the recovery codes when the login also enrolled the user.
+    async verifyChallenge(challengeToken: string, code: string): Promise<string[]> {
+      const config = useRuntimeConfig()
+
+      const response = await fetch(`${config.public.apiBase}/auth/2fa/challenge/verify`, {
+        method: 'POST',
+        headers: {
+          'Content-Type': 'application/json'
+        },
+        body: JSON.stringify({ challenge_token: challengeToken, code })
+      })
+
+      if (!response.ok) {
+        const error = await response.text()
+        throw new Error(error || 'Verification failed')
+      }
+
+      const data = await response.json()
+      await this.setSession(data)
+      return data.recovery_codes || []
+    },
+
+    // refresh exchanges the stored refresh token for a new token pair.
+    // Returns false when the session can no longer be renewed.
+    async refresh(): Promise<boolean> {
+      if (!this.refreshToken) {
+        return false
+      }
+
+      const config = useRuntimeConfig()
+
+      const response = await fetch(`${config.public.apiBase}/auth/refresh`, {
+        method: 'POST',
+        headers: {
+          'Content-Type': 'application/json'
+        },
+        body: JSON.stringify({ refresh_token: this.refreshToken })
+      })
+
+      if (!response.ok) {
+        return false
+      }
+
+      await this.setSession(await response.json())
+      return true
+    },
+
+    async setSession(data: any) {
       this.token = data.token
+      this.refreshToken = data.refresh_token
       this.user = data.user
 
       if (typeof window !== 'undefined') {
         localStorage.setItem('token', data.token)
+        localStorage.setItem('refreshToken', data.refresh_token)
         localStorage.setItem('user', JSON.stringify(data.user))
       }
+
+      await this.loadPermissions()
+    },
+
+    // loadPermissions fetches what the user's role may do. Admins can change
+    // roles at any time, so this runs with every new token.
+    async loadPermissions() {
+      const config = useRuntimeConfig()
+
+      const response = await fetch(`${config.public.apiBase}/auth/me`, {
+        headers: {
+          Authorization: `Bearer ${this.token}`
+        }
+      }).catch(() => null)
+
+      if (!response || !response.ok) {
+        return
+      }
+
+      const data = await response.json()
+      this.permissions = data.permissions || []
+      if (typeof window !== 'undefined') {
+        localStorage.setItem('permissions', JSON.stringify(this.permissions))
+      }
+    },
+
+    async logout() {
+      if (this.token) {
+        const config = useRuntimeConfig()
+        // Best effort: the local session is cleared even if revocation fails.
+        await fetch(`${config.public.apiBase}/auth/logout`, {
+          method: 'POST',
+          headers: {
+            'Content-Type': 'application/json',
+            Authorization: `Bearer ${this.token}`
+          },
+          body: JSON.stringify({ refresh_token: this.refreshToken })
+        }).catch(() => {})
+      }
+
+      this.clearSession()
     },
 
-    logout() {
+    clearSession() {
       this.token = null
+      this.refreshToken = null
       this.user = null
+      this.permissions = []
       
       if (typeof window !== 'undefined') {
         localStorage.removeItem('token')
+        localStorage.removeItem('refreshToken')
         localStorage.removeItem('user')
+        localStorage.removeItem('permissions')
       }
     },
 
@@ -68,7 +241,9 @@ export const useAuthStore = defineStore('auth', {
         
         if (token && user) {
           this.token = token
+          this.refreshToken = localStorage.getItem('refreshToken')
           this.user = JSON.parse(user)
+          this.permissions = JSON.parse(localStorage.getItem('permissions') || '[]')
         }
       }
     }